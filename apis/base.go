@@ -45,6 +45,7 @@ func NewRouter(app core.App) (*router.Router[*core.RequestEvent], error) {
 	bindFileApi(app, apiGroup)
 	bindBatchApi(app, apiGroup)
 	bindRealtimeApi(app, apiGroup)
+	bindGraphQLApi(app, apiGroup)
 	bindHealthApi(app, apiGroup)
 
 	return pbRouter, nil
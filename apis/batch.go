@@ -284,6 +284,19 @@ func processInternalRequest(
 		return nil, errors.New("unknown batch request action")
 	}
 
+	return executeInternalRequest(activeApp, baseEvent, ir, params, infoContext, handle)
+}
+
+// executeInternalRequest runs handle with a new request event constructed
+// from the internal request and the base event auth state and headers.
+func executeInternalRequest(
+	activeApp core.App,
+	baseEvent *core.RequestEvent,
+	ir *core.InternalRequest,
+	params map[string]string,
+	infoContext string,
+	handle HandleFunc,
+) (*BatchRequestResult, error) {
 	// construct a new http.Request
 	// ---------------------------------------------------------------
 	buf, mw, err := multipartDataFromInternalRequest(ir)
@@ -0,0 +1,141 @@
+package cluster_admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/auth"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// adminActor identifies the admin token a request was made with
+func (api *API) adminActor(r *http.Request) string {
+	if token, ok := auth.GetAdminToken(r.Context()); ok {
+		if adminToken, exists := api.adminTokens[token]; exists {
+			return "admin:" + adminToken.Name
+		}
+	}
+	return "admin"
+}
+
+// audit records an admin action in the cluster audit log. The action already
+// happened, so failing to record it is logged rather than returned to the admin.
+func (api *API) audit(r *http.Request, action, target string, changes map[string]*enterprise.AuditChange) {
+	entry := &enterprise.AuditEntry{
+		Actor:     api.adminActor(r),
+		Action:    action,
+		Target:    target,
+		SourceIP:  auth.ClientIP(r),
+		RequestID: auth.GetRequestID(r.Context()),
+		Changes:   changes,
+	}
+
+	if err := api.cp.RecordAudit(entry); err != nil {
+		api.logger.Error("Failed to record audit entry", "action", action, "target", target, "error", err)
+	}
+}
+
+// parseAuditFilter reads an audit filter from the query string
+func parseAuditFilter(r *http.Request) (*enterprise.AuditFilter, error) {
+	query := r.URL.Query()
+
+	filter := &enterprise.AuditFilter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Target: query.Get("target"),
+	}
+
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return nil, fmt.Errorf("since must be an RFC 3339 time")
+		}
+		filter.Since = t
+	}
+
+	if until := query.Get("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("until must be an RFC 3339 time")
+		}
+		filter.Until = t
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("limit must be a positive number")
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+// HandleListAuditLog lists audit entries, newest first
+func (api *API) HandleListAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+
+	entries, err := api.cp.ListAuditEntries(filter)
+	if err != nil {
+		api.logger.Error("Failed to list audit entries", "error", err)
+		http.Error(w, "Failed to list audit entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// HandleExportAuditLog streams the matching audit entries as JSON lines, oldest first
+func (api *API) HandleExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=audit-%s.jsonl", time.Now().UTC().Format("20060102-150405")))
+
+	encoder := json.NewEncoder(w)
+	err = api.cp.ExportAuditEntries(filter, func(entry *enterprise.AuditEntry) error {
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		// Headers are already sent, so the export just ends early
+		api.logger.Error("Failed to export audit entries", "error", err)
+	}
+}
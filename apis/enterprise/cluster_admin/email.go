@@ -0,0 +1,278 @@
+package cluster_admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/email"
+)
+
+// maxWebhookBodySize bounds the body of a provider webhook, SendGrid batches events
+const maxWebhookBodySize = 1 << 20
+
+// HandleListEmailTemplates lists the built-in email templates and the admin overrides
+func (api *API) HandleListEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	overrides, err := api.cp.ListEmailTemplates()
+	if err != nil {
+		api.logger.Error("Failed to list email templates", "error", err)
+		http.Error(w, "Failed to list email templates", http.StatusInternalServerError)
+		return
+	}
+
+	defaults := make([]*enterprise.EmailTemplate, 0)
+	for _, name := range email.TemplateNames() {
+		tmpl, _ := email.DefaultTemplate(name)
+		defaults = append(defaults, tmpl)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"defaults":  defaults,
+		"overrides": overrides,
+	})
+}
+
+// HandleSaveEmailTemplate creates or replaces the override of a template for a locale
+func (api *API) HandleSaveEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var tmpl enterprise.EmailTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := api.cp.SaveEmailTemplate(&tmpl); err != nil {
+		if errors.Is(err, enterprise.ErrInvalidEmailTemplate) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		api.logger.Error("Failed to save email template", "error", err)
+		http.Error(w, "Failed to save email template", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, enterprise.AuditActionEmailTemplateSave, templateTarget(tmpl.Name, tmpl.Locale), map[string]*enterprise.AuditChange{
+		"subject": {After: tmpl.Subject},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"template": tmpl,
+	})
+}
+
+// HandleDeleteEmailTemplate removes the override of a template for a locale
+func (api *API) HandleDeleteEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	locale := email.NormalizeLocale(r.URL.Query().Get("locale"))
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := api.cp.DeleteEmailTemplate(name, locale); err != nil {
+		if errors.Is(err, enterprise.ErrEmailTemplateNotFound) {
+			http.Error(w, "Email template not found", http.StatusNotFound)
+			return
+		}
+		api.logger.Error("Failed to delete email template", "error", err)
+		http.Error(w, "Failed to delete email template", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, enterprise.AuditActionEmailTemplateDelete, templateTarget(name, locale), nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// templateTarget is the audit target of a template override
+func templateTarget(name, locale string) string {
+	if locale == "" {
+		return name
+	}
+	return name + ":" + locale
+}
+
+// HandleListOutboundEmails lists the emails waiting for delivery and the failed ones
+func (api *API) HandleListOutboundEmails(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	queued, err := api.cp.ListOutboundEmails()
+	if err != nil {
+		api.logger.Error("Failed to list outbound emails", "error", err)
+		http.Error(w, "Failed to list outbound emails", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"emails": queued,
+		"count":  len(queued),
+	})
+}
+
+// HandleRetryOutboundEmail queues a failed email again
+func (api *API) HandleRetryOutboundEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := api.cp.RetryOutboundEmail(id); err != nil {
+		if errors.Is(err, enterprise.ErrOutboundEmailNotFound) {
+			http.Error(w, "Outbound email not found", http.StatusNotFound)
+			return
+		}
+		api.logger.Error("Failed to retry outbound email", "id", id, "error", err)
+		http.Error(w, "Failed to retry outbound email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// HandleDeleteOutboundEmail removes an email from the outbound queue
+func (api *API) HandleDeleteOutboundEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := api.cp.DeleteOutboundEmail(id); err != nil {
+		if errors.Is(err, enterprise.ErrOutboundEmailNotFound) {
+			http.Error(w, "Outbound email not found", http.StatusNotFound)
+			return
+		}
+		api.logger.Error("Failed to delete outbound email", "id", id, "error", err)
+		http.Error(w, "Failed to delete outbound email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// HandleClearEmailSuppression makes a bounced or complained address deliverable again
+func (api *API) HandleClearEmailSuppression(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	address := r.URL.Query().Get("email")
+	if address == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := api.cp.GetUserByEmail(address)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	previous := user.EmailStatus
+
+	if err := api.cp.ClearEmailStatus(address); err != nil {
+		api.logger.Error("Failed to clear email status", "userId", user.ID, "error", err)
+		http.Error(w, "Failed to clear email status", http.StatusInternalServerError)
+		return
+	}
+
+	if previous != enterprise.EmailStatusOK {
+		api.audit(r, enterprise.AuditActionUserEmailStatusClear, user.ID, map[string]*enterprise.AuditChange{
+			"emailStatus": {Before: previous},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// HandleEmailWebhook receives the bounce and complaint webhooks of the email
+// provider and flags the reported addresses. The provider is configured with
+// ?provider=<name>&token=<email.webhookSecret>.
+func (api *API) HandleEmailWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !api.cp.ValidEmailWebhookToken(r.URL.Query().Get("token")) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	provider := r.URL.Query().Get("provider")
+	suppressions, err := email.ParseWebhook(provider, body)
+	if err != nil {
+		var confirmation *email.SubscriptionConfirmationError
+		if errors.As(err, &confirmation) {
+			// Confirming is left to the admin so that the URL isn't fetched blindly
+			api.logger.Warn("SES webhook subscription needs confirmation", "url", confirmation.URL)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, s := range suppressions {
+		if err := api.cp.FlagEmailAddress(s.Address, s.Status); err != nil {
+			// Providers retry failed webhooks
+			api.logger.Error("Failed to flag email address", "status", s.Status, "error", err)
+			http.Error(w, "Failed to process webhook", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -2,8 +2,9 @@ package cluster_admin
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -17,7 +18,7 @@ type API struct {
 	cp         *control_plane.ControlPlane
 	jwtManager *auth.JWTManager
 	adminTokens map[string]*enterprise.AdminToken // In-memory admin token storage
-	logger     *log.Logger
+	logger     *slog.Logger
 }
 
 // NewAPI creates a new cluster admin API handler
@@ -26,7 +27,7 @@ func NewAPI(cp *control_plane.ControlPlane, jwtManager *auth.JWTManager) *API {
 		cp:          cp,
 		jwtManager:  jwtManager,
 		adminTokens: make(map[string]*enterprise.AdminToken),
-		logger:      log.Default(),
+		logger:      enterprise.ComponentLogger(enterprise.LogComponentAPI),
 	}
 }
 
@@ -127,7 +128,7 @@ func (api *API) HandleListUsers(w http.ResponseWriter, r *http.Request) {
 	// Get users from control plane
 	users, total, err := api.cp.ListUsers(limit, offset)
 	if err != nil {
-		api.logger.Printf("Failed to list users: %v", err)
+		api.logger.Error("Failed to list users", "error", err)
 		http.Error(w, "Failed to list users", http.StatusInternalServerError)
 		return
 	}
@@ -192,6 +193,8 @@ func (api *API) HandleUpdateUserQuota(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *user
+
 	// Update quotas
 	if req.MaxTenants != nil {
 		user.MaxTenants = *req.MaxTenants
@@ -207,11 +210,13 @@ func (api *API) HandleUpdateUserQuota(w http.ResponseWriter, r *http.Request) {
 
 	// Save user
 	if err := api.cp.UpdateUser(user); err != nil {
-		api.logger.Printf("Failed to update user: %v", err)
+		api.logger.Error("Failed to update user", "error", err)
 		http.Error(w, "Failed to update user", http.StatusInternalServerError)
 		return
 	}
 
+	api.audit(r, enterprise.AuditActionUserQuotaUpdate, user.ID, enterprise.AuditDiff(before, user))
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"user":    user,
@@ -244,11 +249,13 @@ func (api *API) HandleImpersonateUser(w http.ResponseWriter, r *http.Request) {
 	// Generate a short-lived impersonation token (1 hour)
 	token, err := api.jwtManager.GenerateUserToken(user, 1)
 	if err != nil {
-		api.logger.Printf("Failed to generate impersonation token: %v", err)
+		api.logger.Error("Failed to generate impersonation token", "error", err)
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
+	api.audit(r, enterprise.AuditActionUserImpersonate, user.ID, nil)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"token":     token,
@@ -297,7 +304,7 @@ func (api *API) HandleListTenants(w http.ResponseWriter, r *http.Request) {
 	// Get tenants from control plane
 	tenants, total, err := api.cp.ListTenants(limit, offset, ownerUserID)
 	if err != nil {
-		api.logger.Printf("Failed to list tenants: %v", err)
+		api.logger.Error("Failed to list tenants", "error", err)
 		http.Error(w, "Failed to list tenants", http.StatusInternalServerError)
 		return
 	}
@@ -392,6 +399,62 @@ func (api *API) HandleListNodes(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleDrainNode puts a tenant node in maintenance mode: it gets no new tenants
+// and hands back the ones it has, then exits
+func (api *API) HandleDrainNode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		NodeID string `json:"nodeId"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.NodeID == "" {
+		http.Error(w, "nodeId is required", http.StatusBadRequest)
+		return
+	}
+
+	before := ""
+	for _, node := range api.cp.GetNodes() {
+		if node.ID == req.NodeID {
+			before = node.Status
+		}
+	}
+
+	if err := api.cp.MarkNodeDraining(req.NodeID); err != nil {
+		switch err {
+		case enterprise.ErrNodeNotFound:
+			http.Error(w, "Node not found", http.StatusNotFound)
+		case enterprise.ErrNodeOffline:
+			http.Error(w, "Node is offline", http.StatusConflict)
+		default:
+			api.logger.Error("Failed to drain node", "nodeId", req.NodeID, "error", err)
+			http.Error(w, "Failed to drain node", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if before != enterprise.NodeStatusDraining {
+		api.audit(r, enterprise.AuditActionNodeDrain, req.NodeID, map[string]*enterprise.AuditChange{
+			"status": {Before: before, After: enterprise.NodeStatusDraining},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"nodeId":  req.NodeID,
+		"status":  enterprise.NodeStatusDraining,
+	})
+}
+
 // HandleGetDiskStats returns disk usage statistics
 func (api *API) HandleGetDiskStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -503,12 +566,25 @@ func (api *API) HandleArchiveTenant(w http.ResponseWriter, r *http.Request) {
 		tier = enterprise.StorageTierCold
 	}
 
+	beforeTier := enterprise.StorageTierHot
+	if activity, err := api.cp.GetTenantActivity(req.TenantID); err == nil && activity != nil {
+		beforeTier = activity.StorageTier
+	}
+
 	if err := api.cp.ArchiveTenant(req.TenantID, tier); err != nil {
-		api.logger.Printf("Failed to archive tenant %s: %v", req.TenantID, err)
+		if errors.Is(err, enterprise.ErrTenantPreview) {
+			http.Error(w, "Preview tenants expire instead of being archived", http.StatusBadRequest)
+			return
+		}
+		api.logger.Error("Failed to archive tenant", "tenantId", req.TenantID, "error", err)
 		http.Error(w, "Failed to archive tenant", http.StatusInternalServerError)
 		return
 	}
 
+	api.audit(r, enterprise.AuditActionTenantArchive, req.TenantID, map[string]*enterprise.AuditChange{
+		"storageTier": {Before: beforeTier, After: tier},
+	})
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"tenantId": req.TenantID,
@@ -517,7 +593,8 @@ func (api *API) HandleArchiveTenant(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// HandleRestoreTenant manually restores an archived tenant
+// HandleRestoreTenant manually restores an archived tenant.
+// Cold tenants are restored asynchronously; poll HandleGetRestoreStatus for progress.
 func (api *API) HandleRestoreTenant(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -525,7 +602,8 @@ func (api *API) HandleRestoreTenant(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		TenantID string `json:"tenantId"`
+		TenantID  string `json:"tenantId"`
+		Expedited bool   `json:"expedited"` // Use Glacier expedited retrieval (not available for Deep Archive)
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -539,16 +617,59 @@ func (api *API) HandleRestoreTenant(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Restore the tenant
-	if err := api.cp.RestoreTenant(req.TenantID); err != nil {
-		api.logger.Printf("Failed to restore tenant %s: %v", req.TenantID, err)
+	job, err := api.cp.RequestTenantRestore(req.TenantID, req.Expedited, api.adminActor(r))
+	if err != nil {
+		if err == enterprise.ErrTenantNotFound {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return
+		}
+		api.logger.Error("Failed to restore tenant", "tenantId", req.TenantID, "error", err)
 		http.Error(w, "Failed to restore tenant", http.StatusInternalServerError)
 		return
 	}
 
+	api.audit(r, enterprise.AuditActionTenantRestore, req.TenantID, enterprise.AuditDiff(nil, job))
+
+	message := "Tenant restored"
+	if job.IsActive() {
+		message = fmt.Sprintf("Tenant restore initiated, estimated ready at %s", job.EstimatedReady.Format(time.RFC3339))
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"tenantId": req.TenantID,
-		"message":  "Tenant restore initiated. This may take several minutes for cold storage.",
+		"job":      job,
+		"message":  message,
+	})
+}
+
+// HandleGetRestoreStatus returns the latest restore job for a tenant
+func (api *API) HandleGetRestoreStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID := r.URL.Query().Get("tenantId")
+	if tenantID == "" {
+		http.Error(w, "tenantId parameter required", http.StatusBadRequest)
+		return
+	}
+
+	job, err := api.cp.GetRestoreJob(tenantID)
+	if err != nil {
+		if err == enterprise.ErrRestoreJobNotFound {
+			http.Error(w, "No restore job for tenant", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get restore job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job": job,
 	})
 }
 
@@ -589,3 +710,108 @@ func (api *API) HandleGetArchiveStats(w http.ResponseWriter, r *http.Request) {
 		"timestamp": time.Now(),
 	})
 }
+
+// HandleDeleteTenant deletes a tenant and crypto-shreds its data keys
+func (api *API) HandleDeleteTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID := r.URL.Query().Get("tenantId")
+	if tenantID == "" {
+		http.Error(w, "tenantId parameter required", http.StatusBadRequest)
+		return
+	}
+
+	before, err := api.cp.GetTenant(tenantID)
+	if err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if err := api.cp.DeleteTenant(tenantID); err != nil {
+		if err == enterprise.ErrTenantNotFound {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return
+		}
+		api.logger.Error("Failed to delete tenant", "tenantId", tenantID, "error", err)
+		http.Error(w, "Failed to delete tenant", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, enterprise.AuditActionTenantDelete, tenantID, map[string]*enterprise.AuditChange{
+		"status": {Before: before.Status, After: enterprise.TenantStatusDeleted},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Tenant %s deleted", tenantID),
+	})
+}
+
+// HandleRotateTenantKey rotates a tenant's data key
+func (api *API) HandleRotateTenantKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		TenantID string `json:"tenantId"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.TenantID == "" {
+		http.Error(w, "tenantId is required", http.StatusBadRequest)
+		return
+	}
+
+	keyring, err := api.cp.RotateTenantKey(req.TenantID)
+	if err != nil {
+		api.logger.Error("Failed to rotate data key of tenant", "tenantId", req.TenantID, "error", err)
+		http.Error(w, "Failed to rotate tenant key", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, enterprise.AuditActionTenantKeyRotate, req.TenantID, map[string]*enterprise.AuditChange{
+		"activeVersion": {Before: keyring.ActiveVersion - 1, After: keyring.ActiveVersion},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"tenantId":      req.TenantID,
+		"activeVersion": keyring.ActiveVersion,
+	})
+}
+
+// HandleRewrapTenantKeys rewraps all tenant data keys with the current master key
+func (api *API) HandleRewrapTenantKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rewrapped, err := api.cp.RewrapTenantKeys()
+	if err != nil {
+		api.logger.Error("Failed to rewrap tenant keys", "error", err)
+		http.Error(w, "Failed to rewrap tenant keys", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, enterprise.AuditActionKeysRewrap, "", map[string]*enterprise.AuditChange{
+		"rewrapped": {Before: 0, After: rewrapped},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"rewrapped": rewrapped,
+	})
+}
@@ -0,0 +1,35 @@
+package cluster_admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// HandleListLogs lists the entries of the cluster log store, newest first
+func (api *API) HandleListLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := enterprise.ParseLogFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := api.cp.ListLogs(filter)
+	if err != nil {
+		api.logger.Error("Failed to list log entries", "error", err)
+		http.Error(w, "Failed to list log entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
@@ -0,0 +1,178 @@
+package cluster_admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// CreateFleetMigrationRequest represents a request to roll a migration out to every tenant
+type CreateFleetMigrationRequest struct {
+	Name          string                          `json:"name"`
+	Format        enterprise.FleetMigrationFormat `json:"format"` // js (default) or json
+	Script        string                          `json:"script"`
+	Mode          enterprise.FleetMigrationMode   `json:"mode"`          // lazy (default) or eager
+	CanaryPercent int                             `json:"canaryPercent"` // Defaults to 100
+	BatchSize     int                             `json:"batchSize"`
+}
+
+// UpdateFleetMigrationRequest represents a request to steer a running rollout
+type UpdateFleetMigrationRequest struct {
+	MigrationID   string `json:"migrationId"`
+	Action        string `json:"action"` // pause, resume, abort or canary
+	CanaryPercent int    `json:"canaryPercent"`
+}
+
+// HandleListFleetMigrations lists all fleet migrations
+func (api *API) HandleListFleetMigrations(w http.ResponseWriter, r *http.Request) {
+	migrations, err := api.cp.ListFleetMigrations()
+	if err != nil {
+		api.logger.Error("Failed to list fleet migrations", "error", err)
+		http.Error(w, "Failed to list fleet migrations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"migrations": migrations,
+		"total":      len(migrations),
+	})
+}
+
+// HandleGetFleetMigration returns a fleet migration with its rollout progress and failed tenants
+func (api *API) HandleGetFleetMigration(w http.ResponseWriter, r *http.Request) {
+	migrationID := r.URL.Query().Get("migrationId")
+	if migrationID == "" {
+		http.Error(w, "migrationId is required", http.StatusBadRequest)
+		return
+	}
+
+	migration, err := api.cp.GetFleetMigration(migrationID)
+	if err != nil {
+		if err == enterprise.ErrFleetMigrationNotFound {
+			http.Error(w, "Fleet migration not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get fleet migration", http.StatusInternalServerError)
+		return
+	}
+
+	progress, failures, err := api.cp.GetFleetMigrationProgress(migration)
+	if err != nil {
+		api.logger.Error("Failed to get progress of fleet migration", "migrationId", migrationID, "error", err)
+		http.Error(w, "Failed to get fleet migration progress", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"migration": migration,
+		"progress":  progress,
+		"failures":  failures,
+	})
+}
+
+// HandleCreateFleetMigration starts rolling a migration out to every tenant
+func (api *API) HandleCreateFleetMigration(w http.ResponseWriter, r *http.Request) {
+	var req CreateFleetMigrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	migration := &enterprise.FleetMigration{
+		Name:          req.Name,
+		Format:        req.Format,
+		Script:        req.Script,
+		Mode:          req.Mode,
+		CanaryPercent: req.CanaryPercent,
+		BatchSize:     req.BatchSize,
+		CreatedBy:     api.adminActor(r),
+	}
+
+	if err := api.cp.CreateFleetMigration(migration); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	api.audit(r, enterprise.AuditActionMigrationCreate, migration.ID, map[string]*enterprise.AuditChange{
+		"name":          {After: migration.Name},
+		"format":        {After: migration.Format},
+		"mode":          {After: migration.Mode},
+		"canaryPercent": {After: migration.CanaryPercent},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"migration": migration,
+	})
+}
+
+// HandleUpdateFleetMigration pauses, resumes or aborts a rollout, or changes its canary percentage
+func (api *API) HandleUpdateFleetMigration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UpdateFleetMigrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.MigrationID == "" {
+		http.Error(w, "migrationId is required", http.StatusBadRequest)
+		return
+	}
+
+	before, err := api.cp.GetFleetMigration(req.MigrationID)
+	if err != nil {
+		if err == enterprise.ErrFleetMigrationNotFound {
+			http.Error(w, "Fleet migration not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get fleet migration", http.StatusInternalServerError)
+		return
+	}
+
+	var migration *enterprise.FleetMigration
+	switch req.Action {
+	case "pause":
+		migration, err = api.cp.SetFleetMigrationStatus(req.MigrationID, enterprise.FleetMigrationPaused)
+	case "resume":
+		migration, err = api.cp.SetFleetMigrationStatus(req.MigrationID, enterprise.FleetMigrationRunning)
+	case "abort":
+		migration, err = api.cp.SetFleetMigrationStatus(req.MigrationID, enterprise.FleetMigrationAborted)
+	case "canary":
+		migration, err = api.cp.SetFleetMigrationCanary(req.MigrationID, req.CanaryPercent)
+	default:
+		http.Error(w, "action must be pause, resume, abort or canary", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		if err == enterprise.ErrFleetMigrationFinished {
+			http.Error(w, "Fleet migration is aborted or completed", http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if changes := enterprise.AuditDiff(
+		map[string]interface{}{"status": before.Status, "canaryPercent": before.CanaryPercent},
+		map[string]interface{}{"status": migration.Status, "canaryPercent": migration.CanaryPercent},
+	); len(changes) > 0 {
+		api.audit(r, enterprise.AuditActionMigrationUpdate, migration.ID, changes)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"migration": migration,
+	})
+}
@@ -0,0 +1,166 @@
+package cluster_admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// nodeStatusTimeout bounds how long a node gets to report its status
+const nodeStatusTimeout = 3 * time.Second
+
+// NodeStatus is what a tenant node reports about itself on /_status
+type NodeStatus struct {
+	NodeID          string                              `json:"nodeId"`
+	Draining        bool                                `json:"draining"`
+	LoadedTenants   int                                 `json:"loadedTenants"`
+	Capacity        int                                 `json:"capacity"`
+	Hotspots        []*enterprise.TenantResourceMetrics `json:"hotspots"`
+	CircuitBreakers []map[string]interface{}            `json:"circuitBreakers"`
+	Error           string                              `json:"error,omitempty"` // Set when the node couldn't be reached
+}
+
+// MoveTenantRequest represents a request to move a tenant to another node
+type MoveTenantRequest struct {
+	TenantID string `json:"tenantId"`
+	NodeID   string `json:"nodeId"`
+}
+
+// HandleGetNodeStatus asks every node that isn't offline for its hotspot tenants
+// and circuit breaker states
+func (api *API) HandleGetNodeStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	nodes := api.cp.GetNodes()
+	statuses := make([]*NodeStatus, len(nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		if node.Status == enterprise.NodeStatusOffline {
+			statuses[i] = &NodeStatus{NodeID: node.ID, Error: enterprise.ErrNodeOffline.Error()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, node *enterprise.NodeInfo) {
+			defer wg.Done()
+
+			status, err := fetchNodeStatus(r.Context(), node.Address)
+			if err != nil {
+				status = &NodeStatus{Error: err.Error()}
+			}
+			status.NodeID = node.ID
+			statuses[i] = status
+		}(i, node)
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"nodes":     statuses,
+		"timestamp": time.Now(),
+	})
+}
+
+// fetchNodeStatus reads the /_status endpoint of a tenant node
+func fetchNodeStatus(ctx context.Context, address string) (*NodeStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, nodeStatusTimeout)
+	defer cancel()
+
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + address
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address+"/_status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("node returned status %d", resp.StatusCode)
+	}
+
+	var status NodeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("invalid node status: %w", err)
+	}
+
+	return &status, nil
+}
+
+// HandleMoveTenant moves a tenant to another node. The move completes once the
+// tenant's current node has handed it off.
+func (api *API) HandleMoveTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MoveTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.TenantID == "" || req.NodeID == "" {
+		http.Error(w, "tenantId and nodeId are required", http.StatusBadRequest)
+		return
+	}
+
+	before := ""
+	if tenant, err := api.cp.GetTenant(req.TenantID); err == nil {
+		before = tenant.AssignedNodeID
+	}
+
+	tenant, err := api.cp.MoveTenant(req.TenantID, req.NodeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, enterprise.ErrTenantNotFound):
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+		case errors.Is(err, enterprise.ErrNodeNotFound):
+			http.Error(w, "Node not found", http.StatusNotFound)
+		case errors.Is(err, enterprise.ErrTenantArchived),
+			errors.Is(err, enterprise.ErrNodeOffline),
+			errors.Is(err, enterprise.ErrNodeDraining),
+			errors.Is(err, enterprise.ErrRegionMismatch):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			api.logger.Error("Failed to move tenant", "tenantId", req.TenantID, "error", err)
+			http.Error(w, "Failed to move tenant", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if before != req.NodeID {
+		api.audit(r, enterprise.AuditActionTenantMove, req.TenantID, map[string]*enterprise.AuditChange{
+			"node": {Before: before, After: req.NodeID},
+		})
+	}
+
+	message := "Tenant moved"
+	if tenant.MovingToNodeID != "" {
+		message = fmt.Sprintf("Tenant will move once node %s hands it off", tenant.AssignedNodeID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenant":  tenant,
+		"message": message,
+	})
+}
@@ -0,0 +1,109 @@
+package cluster_admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// SuspendTenantRequest is the request body for suspending a tenant
+type SuspendTenantRequest struct {
+	TenantID string                      `json:"tenantId"`
+	Reason   enterprise.SuspensionReason `json:"reason"`  // abuse or non_payment
+	Message  string                      `json:"message"` // Shown to the owner and on the gateway page
+}
+
+// HandleSuspendTenant blocks a tenant while keeping its data. The gateway
+// refuses its requests right away and its node unloads it.
+func (api *API) HandleSuspendTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SuspendTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.TenantID == "" {
+		http.Error(w, "tenantId is required", http.StatusBadRequest)
+		return
+	}
+
+	var before *enterprise.TenantSuspension
+	if tenant, err := api.cp.GetTenant(req.TenantID); err == nil {
+		before = tenant.Suspension
+	}
+
+	tenant, err := api.cp.SuspendTenant(req.TenantID, req.Reason, req.Message, api.adminActor(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, enterprise.ErrTenantNotFound):
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+		case errors.Is(err, enterprise.ErrInvalidSuspension):
+			http.Error(w, "reason must be abuse or non_payment", http.StatusBadRequest)
+		default:
+			api.logger.Error("Failed to suspend tenant", "tenantId", req.TenantID, "error", err)
+			http.Error(w, "Failed to suspend tenant", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	api.audit(r, enterprise.AuditActionTenantSuspend, tenant.ID, map[string]*enterprise.AuditChange{
+		"suspension": {Before: before, After: tenant.Suspension},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenant":  tenant,
+		"message": "Tenant suspended",
+	})
+}
+
+// HandleUnsuspendTenant lifts the suspension of a tenant, which is routed
+// normally again from the next request
+func (api *API) HandleUnsuspendTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID := r.URL.Query().Get("tenantId")
+	if tenantID == "" {
+		http.Error(w, "tenantId parameter required", http.StatusBadRequest)
+		return
+	}
+
+	var before *enterprise.TenantSuspension
+	if tenant, err := api.cp.GetTenant(tenantID); err == nil {
+		before = tenant.Suspension
+	}
+
+	tenant, err := api.cp.UnsuspendTenant(tenantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, enterprise.ErrTenantNotFound):
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+		case errors.Is(err, enterprise.ErrTenantNotSuspended):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			api.logger.Error("Failed to unsuspend tenant", "tenantId", tenantID, "error", err)
+			http.Error(w, "Failed to unsuspend tenant", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	api.audit(r, enterprise.AuditActionTenantUnsuspend, tenant.ID, map[string]*enterprise.AuditChange{
+		"suspension": {Before: before},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenant":  tenant,
+		"message": "Tenant unsuspended",
+	})
+}
@@ -0,0 +1,191 @@
+package cluster_user
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/auth"
+)
+
+// CreateAPIKeyRequest represents an API key creation request
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int      `json:"expiresIn,omitempty"` // Lifetime in days, never expires if 0
+}
+
+// ValidateAPIKey authenticates an API key for auth.RequireUserAuth
+func (api *API) ValidateAPIKey(secret string) (*auth.ClusterUserClaims, error) {
+	key, user, err := api.cp.AuthenticateAPIKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.ClusterUserClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Name:     user.Name,
+		Verified: user.Verified,
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	}, nil
+}
+
+// apiKeyResponse returns the public fields of an API key, leaving out its hash
+func apiKeyResponse(key *enterprise.APIKey) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        key.ID,
+		"name":      key.Name,
+		"hint":      key.Hint,
+		"scopes":    key.Scopes,
+		"expiresAt": key.ExpiresAt,
+		"created":   key.Created,
+	}
+}
+
+// loginClaims returns the claims of a request authenticated with a login
+// token. API keys can't manage API keys, so a leaked key can't be used to
+// mint longer-lived or wider ones.
+func loginClaims(w http.ResponseWriter, r *http.Request) (*auth.ClusterUserClaims, bool) {
+	claims, ok := auth.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if claims.APIKeyID != "" {
+		http.Error(w, "API keys can only be managed after a password login", http.StatusForbidden)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// HandleCreateAPIKey creates an API key for the user. The key is only
+// returned by this response.
+func (api *API) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := loginClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ExpiresIn < 0 {
+		http.Error(w, "expiresIn must be a positive number of days", http.StatusBadRequest)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresIn > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresIn) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	key, secret, err := api.cp.CreateAPIKey(claims.UserID, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		if errors.Is(err, enterprise.ErrInvalidAPIKey) || errors.Is(err, enterprise.ErrTooManyAPIKeys) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		api.logger.Error("Failed to create API key", "userId", claims.UserID, "error", err)
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, claims, enterprise.AuditActionAPIKeyCreate, key.ID, map[string]*enterprise.AuditChange{
+		"name":   {After: key.Name},
+		"scopes": {After: key.Scopes},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"apiKey":  apiKeyResponse(key),
+		"key":     secret,
+		"message": "Store the key now, it can't be shown again",
+	})
+}
+
+// HandleListAPIKeys lists the user's API keys
+func (api *API) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := loginClaims(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := api.cp.ListAPIKeys(claims.UserID)
+	if err != nil {
+		api.logger.Error("Failed to list API keys", "userId", claims.UserID, "error", err)
+		http.Error(w, "Failed to list API keys", http.StatusInternalServerError)
+		return
+	}
+
+	apiKeys := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		apiKeys = append(apiKeys, apiKeyResponse(key))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"apiKeys": apiKeys,
+		"total":   len(apiKeys),
+	})
+}
+
+// HandleRevokeAPIKey revokes one of the user's API keys
+func (api *API) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := loginClaims(w, r)
+	if !ok {
+		return
+	}
+
+	keyID := r.URL.Query().Get("keyId")
+	if keyID == "" {
+		http.Error(w, "keyId parameter required", http.StatusBadRequest)
+		return
+	}
+
+	key, err := api.cp.RevokeAPIKey(claims.UserID, keyID)
+	if err != nil {
+		if errors.Is(err, enterprise.ErrAPIKeyNotFound) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		api.logger.Error("Failed to revoke API key", "keyId", keyID, "error", err)
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, claims, enterprise.AuditActionAPIKeyRevoke, key.ID, map[string]*enterprise.AuditChange{
+		"name": {Before: key.Name},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "API key " + key.Name + " revoked",
+	})
+}
@@ -0,0 +1,25 @@
+package cluster_user
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/auth"
+)
+
+// audit records a tenant owner action in the cluster audit log. The action already
+// happened, so failing to record it is logged rather than returned to the user.
+func (api *API) audit(r *http.Request, claims *auth.ClusterUserClaims, action, target string, changes map[string]*enterprise.AuditChange) {
+	entry := &enterprise.AuditEntry{
+		Actor:     "user:" + claims.UserID,
+		Action:    action,
+		Target:    target,
+		SourceIP:  auth.ClientIP(r),
+		RequestID: auth.GetRequestID(r.Context()),
+		Changes:   changes,
+	}
+
+	if err := api.cp.RecordAudit(entry); err != nil {
+		api.logger.Error("Failed to record audit entry", "action", action, "target", target, "error", err)
+	}
+}
@@ -2,8 +2,12 @@ package cluster_user
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/pocketbase/pocketbase/core/enterprise"
@@ -17,7 +21,7 @@ import (
 type API struct {
 	cp         *control_plane.ControlPlane
 	jwtManager *auth.JWTManager
-	logger     *log.Logger
+	logger     *slog.Logger
 }
 
 // NewAPI creates a new cluster user API handler
@@ -25,7 +29,7 @@ func NewAPI(cp *control_plane.ControlPlane, jwtManager *auth.JWTManager) *API {
 	return &API{
 		cp:         cp,
 		jwtManager: jwtManager,
-		logger:     log.Default(),
+		logger:     enterprise.ComponentLogger(enterprise.LogComponentAPI),
 	}
 }
 
@@ -34,6 +38,7 @@ type SignupRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Name     string `json:"name"`
+	Locale   string `json:"locale,omitempty"` // Language of cluster emails (default: Accept-Language)
 }
 
 // LoginRequest represents a user login request
@@ -44,8 +49,11 @@ type LoginRequest struct {
 
 // CreateTenantRequest represents a tenant creation request
 type CreateTenantRequest struct {
-	ID     string `json:"id"`     // Desired tenant ID (e.g., "myapp")
-	Domain string `json:"domain"` // Full domain (e.g., "myapp.platform.com")
+	ID       string `json:"id"`                 // Desired tenant ID (e.g., "myapp")
+	Domain   string `json:"domain"`             // Full domain (e.g., "myapp.platform.com")
+	Region   string `json:"region,omitempty"`   // Data residency requirement (defaults to the cluster's region)
+	Preview  bool   `json:"preview,omitempty"`  // Ephemeral tenant with the preview quotas
+	TTLHours int    `json:"ttlHours,omitempty"` // Lifetime, the tenant is deleted afterwards (previews default to 7 days)
 }
 
 // QuotaIncreaseRequest represents a quota increase request
@@ -79,7 +87,7 @@ func (api *API) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	existingUser, _ := api.cp.GetUserByEmail(req.Email)
 	if existingUser != nil {
 		// Log for debugging but don't reveal to user
-		api.logger.Printf("[Signup] Attempted registration with existing email: %s", req.Email)
+		api.logger.Info("Attempted registration with existing email", "email", req.Email)
 
 		// Return same response as success to prevent email enumeration
 		w.Header().Set("Content-Type", "application/json")
@@ -93,7 +101,7 @@ func (api *API) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		api.logger.Printf("Failed to hash password: %v", err)
+		api.logger.Error("Failed to hash password", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -107,6 +115,7 @@ func (api *API) HandleSignup(w http.ResponseWriter, r *http.Request) {
 		Name:                req.Name,
 		PasswordHash:        string(hashedPassword),
 		Verified:            false, // Will be set to true after email verification
+		Locale:              signupLocale(r, req.Locale),
 		MaxTenants:          maxTenants,
 		MaxStoragePerTenant: maxStoragePerTenant,
 		MaxAPIRequestsDaily: maxAPIRequestsDaily,
@@ -116,7 +125,7 @@ func (api *API) HandleSignup(w http.ResponseWriter, r *http.Request) {
 
 	// Save user
 	if err := api.cp.CreateUser(user); err != nil {
-		api.logger.Printf("Failed to create user: %v", err)
+		api.logger.Error("Failed to create user", "error", err)
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
@@ -124,7 +133,7 @@ func (api *API) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	// Generate verification token
 	verificationTokenStr, err := email.GenerateToken()
 	if err != nil {
-		api.logger.Printf("Failed to generate verification token: %v", err)
+		api.logger.Error("Failed to generate verification token", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -140,19 +149,20 @@ func (api *API) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := api.cp.SaveVerificationToken(verificationToken); err != nil {
-		api.logger.Printf("Failed to save verification token: %v", err)
+		api.logger.Error("Failed to save verification token", "error", err)
 		// Don't fail user creation if token save fails
 	}
 
-	// Log verification link for development (in production, this would be sent via email)
-	verificationURL := "http://localhost:8095/api/enterprise/users/verify?token=" + verificationTokenStr
-	api.logger.Printf("[Verification] New user registered: %s", user.Email)
-	api.logger.Printf("[Verification] Verification URL: %s", verificationURL)
+	api.logger.Info("New user registered", "email", user.Email)
+	if err := api.sendVerificationEmail(r, user, verificationTokenStr); err != nil {
+		api.logger.Error("Failed to send verification email", "userId", user.ID, "error", err)
+		// Don't fail user creation, the user can ask for another email
+	}
 
 	// Generate JWT token
 	token, err := api.jwtManager.GenerateUserToken(user, 24)
 	if err != nil {
-		api.logger.Printf("Failed to generate token: %v", err)
+		api.logger.Error("Failed to generate token", "error", err)
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
@@ -206,7 +216,7 @@ func (api *API) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	// Generate JWT token
 	token, err := api.jwtManager.GenerateUserToken(user, 24)
 	if err != nil {
-		api.logger.Printf("Failed to generate token: %v", err)
+		api.logger.Error("Failed to generate token", "error", err)
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
@@ -288,18 +298,32 @@ func (api *API) HandleCreateTenant(w http.ResponseWriter, r *http.Request) {
 		tenantID = "tenant_" + req.ID
 	}
 
+	if req.TTLHours < 0 {
+		http.Error(w, "ttlHours must be a positive number of hours", http.StatusBadRequest)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.TTLHours > 0 {
+		t := time.Now().Add(time.Duration(req.TTLHours) * time.Hour)
+		expiresAt = &t
+	}
+
 	// Create tenant
 	tenant := &enterprise.Tenant{
 		ID:          tenantID,
 		Domain:      req.Domain,
 		OwnerUserID: claims.UserID,
+		Region:      req.Region,
+		Preview:     req.Preview,
+		ExpiresAt:   expiresAt,
 		Status:      enterprise.TenantStatusCreated,
 		Created:     time.Now(),
 		Updated:     time.Now(),
 	}
 
 	if err := api.cp.CreateTenant(tenant); err != nil {
-		api.logger.Printf("Failed to create tenant: %v", err)
+		api.logger.Error("Failed to create tenant", "error", err)
 
 		// Check if it's a quota error
 		if _, ok := err.(*enterprise.QuotaError); ok {
@@ -307,10 +331,22 @@ func (api *API) HandleCreateTenant(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, enterprise.ErrUnknownRegion) {
+			http.Error(w, fmt.Sprintf("Unknown region %q, available regions: %s", req.Region, strings.Join(api.cp.Regions(), ", ")), http.StatusBadRequest)
+			return
+		}
+
+		if errors.Is(err, enterprise.ErrInvalidTenantExpiry) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		http.Error(w, "Failed to create tenant", http.StatusInternalServerError)
 		return
 	}
 
+	api.audit(r, claims, enterprise.AuditActionTenantCreate, tenant.ID, enterprise.AuditDiff(nil, tenant))
+
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -336,15 +372,70 @@ func (api *API) HandleListTenants(w http.ResponseWriter, r *http.Request) {
 	// List all tenants owned by this user
 	tenants, total, err := api.cp.ListTenants(0, 0, claims.UserID)
 	if err != nil {
-		api.logger.Printf("Failed to list tenants: %v", err)
+		api.logger.Error("Failed to list tenants", "error", err)
 		http.Error(w, "Failed to list tenants", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	response := map[string]interface{}{
 		"tenants": tenants,
 		"total":   total,
+	}
+
+	// Owners of suspended tenants can appeal through the API or the configured page
+	if appealURL := api.cp.SuspensionAppealURL(); appealURL != "" {
+		response["appealUrl"] = appealURL
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleDeleteTenant deletes one of the user's tenants, shredding its data keys
+func (api *API) HandleDeleteTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := auth.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID := r.URL.Query().Get("tenantId")
+	if tenantID == "" {
+		http.Error(w, "tenantId parameter required", http.StatusBadRequest)
+		return
+	}
+
+	// Verify user owns this tenant
+	tenant, err := api.cp.GetTenant(tenantID)
+	if err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if tenant.OwnerUserID != claims.UserID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	if err := api.cp.DeleteTenant(tenantID); err != nil {
+		api.logger.Error("Failed to delete tenant", "tenantId", tenantID, "error", err)
+		http.Error(w, "Failed to delete tenant", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, claims, enterprise.AuditActionTenantDelete, tenantID, map[string]*enterprise.AuditChange{
+		"status": {Before: tenant.Status, After: enterprise.TenantStatusDeleted},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Tenant %s deleted", tenantID),
 	})
 }
 
@@ -392,19 +483,364 @@ func (api *API) HandleGenerateTenantSSO(w http.ResponseWriter, r *http.Request)
 	// Generate SSO token
 	ssoToken, err := api.jwtManager.GenerateTenantAdminToken(user, req.TenantID)
 	if err != nil {
-		api.logger.Printf("Failed to generate SSO token: %v", err)
+		api.logger.Error("Failed to generate SSO token", "error", err)
 		http.Error(w, "Failed to generate SSO token", http.StatusInternalServerError)
 		return
 	}
 
+	api.audit(r, claims, enterprise.AuditActionTenantSSO, tenant.ID, nil)
+
+	// The token is exchanged once for a superuser session by POSTing it to
+	// ssoUrl. Opening tenantUrl does that in the browser and opens the admin UI,
+	// the token is in the fragment so it isn't sent along or logged.
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"ssoToken":  ssoToken,
-		"tenantUrl": "https://" + tenant.Domain + "/_/",
+		"ssoUrl":    "https://" + tenant.Domain + "/api/enterprise/sso",
+		"tenantUrl": "https://" + tenant.Domain + "/api/enterprise/sso#token=" + ssoToken,
 		"expiresIn": 3600, // 1 hour
 	})
 }
 
+// HandleRestoreTenant starts restoring one of the user's archived tenants.
+// Owners always get a standard retrieval; expedited restores are admin-only.
+func (api *API) HandleRestoreTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := auth.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TenantID string `json:"tenantId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Verify user owns this tenant
+	tenant, err := api.cp.GetTenant(req.TenantID)
+	if err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if tenant.OwnerUserID != claims.UserID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	job, err := api.cp.RequestTenantRestore(tenant.ID, false, claims.UserID)
+	if err != nil {
+		api.logger.Error("Failed to restore tenant", "tenantId", tenant.ID, "error", err)
+		http.Error(w, "Failed to restore tenant", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, claims, enterprise.AuditActionTenantRestore, tenant.ID, enterprise.AuditDiff(nil, job))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job": job,
+	})
+}
+
+// HandleUpdateResponseCache enables or disables the gateway response cache for
+// one of the user's tenants. Only unauthenticated GET requests are ever cached.
+func (api *API) HandleUpdateResponseCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := auth.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TenantID   string `json:"tenantId"`
+		Enabled    bool   `json:"enabled"`
+		TTLSeconds int    `json:"ttlSeconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.TTLSeconds < 0 || req.TTLSeconds > 86400 {
+		http.Error(w, "ttlSeconds must be between 0 and 86400", http.StatusBadRequest)
+		return
+	}
+
+	// Verify user owns this tenant
+	tenant, err := api.cp.GetTenant(req.TenantID)
+	if err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if tenant.OwnerUserID != claims.UserID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	before := *tenant
+
+	tenant, err = api.cp.SetTenantResponseCache(tenant.ID, req.Enabled, req.TTLSeconds)
+	if err != nil {
+		api.logger.Error("Failed to update response cache for tenant", "tenantId", req.TenantID, "error", err)
+		http.Error(w, "Failed to update response cache", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, claims, enterprise.AuditActionTenantCacheUpdate, tenant.ID, enterprise.AuditDiff(before, tenant))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenant": tenant,
+	})
+}
+
+// HandleUpdateAccessPolicy replaces the gateway access policy of one of the
+// user's tenants, an empty policy removes it
+func (api *API) HandleUpdateAccessPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := auth.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TenantID string `json:"tenantId"`
+		enterprise.TenantAccessPolicy
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Verify user owns this tenant
+	tenant, err := api.cp.GetTenant(req.TenantID)
+	if err != nil || tenant.Status == enterprise.TenantStatusDeleted {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if tenant.OwnerUserID != claims.UserID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	before := *tenant
+
+	tenant, err = api.cp.SetTenantAccessPolicy(tenant.ID, &req.TenantAccessPolicy)
+	if err != nil {
+		if errors.Is(err, enterprise.ErrInvalidAccessPolicy) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		api.logger.Error("Failed to update access policy for tenant", "tenantId", req.TenantID, "error", err)
+		http.Error(w, "Failed to update access policy", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, claims, enterprise.AuditActionTenantAccessPolicy, tenant.ID, enterprise.AuditDiff(before, tenant))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenant": tenant,
+	})
+}
+
+// HandleExtendTenant pushes back the expiry of one of the user's tenants, e.g.
+// to keep a preview tenant while its pull request is still open
+func (api *API) HandleExtendTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := auth.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TenantID    string `json:"tenantId"`
+		ExtendHours int    `json:"extendHours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ExtendHours <= 0 {
+		http.Error(w, "extendHours must be a positive number of hours", http.StatusBadRequest)
+		return
+	}
+
+	// Verify user owns this tenant
+	tenant, err := api.cp.GetTenant(req.TenantID)
+	if err != nil || tenant.Status == enterprise.TenantStatusDeleted {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if tenant.OwnerUserID != claims.UserID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	before := *tenant
+
+	tenant, err = api.cp.ExtendTenantExpiry(tenant.ID, time.Duration(req.ExtendHours)*time.Hour)
+	if err != nil {
+		if errors.Is(err, enterprise.ErrInvalidTenantExpiry) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		api.logger.Error("Failed to extend tenant expiry", "tenantId", req.TenantID, "error", err)
+		http.Error(w, "Failed to extend tenant expiry", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, claims, enterprise.AuditActionTenantExtend, tenant.ID, enterprise.AuditDiff(before, tenant))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenant": tenant,
+	})
+}
+
+// HandleAppealSuspension records the user's appeal of the suspension of one of
+// their tenants for the cluster admins to review
+func (api *API) HandleAppealSuspension(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := auth.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TenantID string `json:"tenantId"`
+		Message  string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(req.Message) == "" {
+		http.Error(w, "message is required", http.StatusBadRequest)
+		return
+	}
+
+	// Verify user owns this tenant
+	tenant, err := api.cp.GetTenant(req.TenantID)
+	if err != nil || tenant.Status == enterprise.TenantStatusDeleted {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if tenant.OwnerUserID != claims.UserID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	tenant, err = api.cp.AppealTenantSuspension(tenant.ID, req.Message)
+	if err != nil {
+		if errors.Is(err, enterprise.ErrTenantNotSuspended) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		api.logger.Error("Failed to appeal tenant suspension", "tenantId", req.TenantID, "error", err)
+		http.Error(w, "Failed to appeal tenant suspension", http.StatusInternalServerError)
+		return
+	}
+
+	api.audit(r, claims, enterprise.AuditActionTenantAppeal, tenant.ID, map[string]*enterprise.AuditChange{
+		"appeal": {After: req.Message},
+	})
+
+	response := map[string]interface{}{
+		"tenant":  tenant,
+		"message": "Appeal submitted, the cluster admins will review it",
+	}
+	if appealURL := api.cp.SuspensionAppealURL(); appealURL != "" {
+		response["appealUrl"] = appealURL
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleGetRestoreStatus returns restore progress for one of the user's tenants
+func (api *API) HandleGetRestoreStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := auth.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID := r.URL.Query().Get("tenantId")
+	if tenantID == "" {
+		http.Error(w, "tenantId parameter required", http.StatusBadRequest)
+		return
+	}
+
+	// Verify user owns this tenant
+	tenant, err := api.cp.GetTenant(tenantID)
+	if err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if tenant.OwnerUserID != claims.UserID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	job, err := api.cp.GetRestoreJob(tenant.ID)
+	if err != nil {
+		if err == enterprise.ErrRestoreJobNotFound {
+			http.Error(w, "No restore job for tenant", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get restore job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job": job,
+	})
+}
+
 // HandleVerifyEmail handles email verification
 func (api *API) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet && r.Method != http.MethodPost {
@@ -421,7 +857,7 @@ func (api *API) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
 	// Atomically validate and mark token as used (prevents double-use race condition)
 	verificationToken, err := api.cp.UseVerificationTokenAtomically(tokenStr)
 	if err != nil {
-		api.logger.Printf("[Verification] Invalid token: %v", err)
+		api.logger.Warn("Invalid token", "error", err)
 		http.Error(w, "Invalid or expired verification token", http.StatusBadRequest)
 		return
 	}
@@ -429,7 +865,7 @@ func (api *API) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
 	// Get user
 	user, err := api.cp.GetUser(verificationToken.UserID)
 	if err != nil {
-		api.logger.Printf("[Verification] User not found: %v", err)
+		api.logger.Warn("User not found", "error", err)
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
@@ -449,14 +885,14 @@ func (api *API) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
 	user.Updated = time.Now()
 
 	if err := api.cp.UpdateUser(user); err != nil {
-		api.logger.Printf("[Verification] Failed to update user: %v", err)
+		api.logger.Error("Failed to update user", "error", err)
 		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
 		return
 	}
 
 	// Token is already marked as used by UseVerificationTokenAtomically
 
-	api.logger.Printf("[Verification] Email verified for user: %s", user.Email)
+	api.logger.Info("Email verified for user", "email", user.Email)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -493,7 +929,7 @@ func (api *API) HandleResendVerification(w http.ResponseWriter, r *http.Request)
 
 	// If already verified, return same generic message to prevent enumeration
 	if user.Verified {
-		api.logger.Printf("[Verification] Resend attempted for already verified email: %s", req.Email)
+		api.logger.Info("Resend attempted for already verified email", "email", req.Email)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"message": "If the email exists and is not yet verified, a verification link has been sent.",
@@ -504,7 +940,7 @@ func (api *API) HandleResendVerification(w http.ResponseWriter, r *http.Request)
 	// Generate new verification token
 	verificationTokenStr, err := email.GenerateToken()
 	if err != nil {
-		api.logger.Printf("Failed to generate token: %v", err)
+		api.logger.Error("Failed to generate token", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -520,18 +956,56 @@ func (api *API) HandleResendVerification(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := api.cp.SaveVerificationToken(verificationToken); err != nil {
-		api.logger.Printf("Failed to save verification token: %v", err)
+		api.logger.Error("Failed to save verification token", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Log verification link for development (in production, this would be sent via email)
-	verificationURL := "http://localhost:8095/api/enterprise/users/verify?token=" + verificationTokenStr
-	api.logger.Printf("[Verification] Resending verification for %s", user.Email)
-	api.logger.Printf("[Verification] Verification URL: %s", verificationURL)
+	api.logger.Info("Resending verification", "email", user.Email)
+	if err := api.sendVerificationEmail(r, user, verificationTokenStr); err != nil {
+		// A flagged address gets the same response to prevent enumeration
+		if !errors.Is(err, enterprise.ErrEmailSuppressed) {
+			api.logger.Error("Failed to send verification email", "userId", user.ID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		api.logger.Info("Verification not sent to flagged address", "userId", user.ID, "status", user.EmailStatus)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Verification email sent. Please check your inbox.",
 	})
 }
+
+// sendVerificationEmail queues the email with the link verifying a user's address
+func (api *API) sendVerificationEmail(r *http.Request, user *enterprise.ClusterUser, token string) error {
+	verificationURL := api.publicURL(r) + "/api/enterprise/users/verify?token=" + url.QueryEscape(token)
+
+	return api.cp.SendEmail(email.TemplateVerification, user, map[string]interface{}{
+		"VerificationURL": verificationURL,
+	})
+}
+
+// publicURL returns the base URL of links in emails, the configured one or
+// the URL the request was made to
+func (api *API) publicURL(r *http.Request) string {
+	if base := api.cp.EmailBaseURL(); base != "" {
+		return base
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// signupLocale returns the email language of a new user, the requested one or
+// the preferred language of the browser
+func signupLocale(r *http.Request, requested string) string {
+	if locale := email.NormalizeLocale(requested); locale != "" {
+		return locale
+	}
+	return email.PreferredLocale(r.Header.Get("Accept-Language"))
+}
@@ -0,0 +1,60 @@
+package cluster_user
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/auth"
+)
+
+// HandleListTenantLogs lists the log store entries of one of the user's
+// tenants, newest first. It takes the filters of the admin log API.
+func (api *API) HandleListTenantLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := auth.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := enterprise.ParseLogFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if filter.TenantID == "" {
+		http.Error(w, "tenantId parameter required", http.StatusBadRequest)
+		return
+	}
+
+	// Verify user owns this tenant
+	tenant, err := api.cp.GetTenant(filter.TenantID)
+	if err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if tenant.OwnerUserID != claims.UserID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	entries, err := api.cp.ListLogs(filter)
+	if err != nil {
+		api.logger.Error("Failed to list log entries", "tenantId", tenant.ID, "error", err)
+		http.Error(w, "Failed to list log entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
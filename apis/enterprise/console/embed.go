@@ -0,0 +1,16 @@
+// Package console handles the cluster admin console embedding.
+//
+// The console is a single static page served by the control plane. It talks to
+// the /api/enterprise/admin endpoints with the admin token entered on login.
+package console
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var distDir embed.FS
+
+// DistDirFS contains the embedded dist directory files (without the "dist" prefix)
+var DistDirFS, _ = fs.Sub(distDir, "dist")
@@ -2,16 +2,17 @@ package enterprise
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/pocketbase/pocketbase/apis/enterprise/cluster_admin"
 	"github.com/pocketbase/pocketbase/apis/enterprise/cluster_user"
+	"github.com/pocketbase/pocketbase/apis/enterprise/console"
+	"github.com/pocketbase/pocketbase/core/enterprise"
 	"github.com/pocketbase/pocketbase/core/enterprise/auth"
 	"github.com/pocketbase/pocketbase/core/enterprise/control_plane"
 	"github.com/pocketbase/pocketbase/core/enterprise/health"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Router handles routing for enterprise APIs
@@ -22,17 +23,24 @@ type Router struct {
 	adminAPI        *cluster_admin.API
 	jwtManager      *auth.JWTManager
 	authRateLimiter *auth.RateLimiter
-	logger          *log.Logger
+	trustedProxies  enterprise.TrustedProxies
+	logger          *slog.Logger
 }
 
 // NewRouter creates a new enterprise API router
 // jwtSecret should be loaded from config or environment variable (POCKETBASE_JWT_SECRET)
-func NewRouter(cp *control_plane.ControlPlane, jwtSecret string) (*Router, error) {
+// trustedProxies are the load balancers whose X-Forwarded-For names the client
+func NewRouter(cp *control_plane.ControlPlane, jwtSecret string, trustedProxies []string) (*Router, error) {
 	jwtManager, err := auth.NewJWTManager(jwtSecret)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize JWT manager: %w", err)
 	}
 
+	proxies, err := enterprise.ParseTrustedProxies(trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
 	userAPI := cluster_user.NewAPI(cp, jwtManager)
 	adminAPI := cluster_admin.NewAPI(cp, jwtManager)
 
@@ -46,7 +54,8 @@ func NewRouter(cp *control_plane.ControlPlane, jwtSecret string) (*Router, error
 		adminAPI:        adminAPI,
 		jwtManager:      jwtManager,
 		authRateLimiter: authRateLimiter,
-		logger:          log.Default(),
+		trustedProxies:  proxies,
+		logger:          enterprise.ComponentLogger(enterprise.LogComponentAPI),
 	}
 
 	router.setupRoutes()
@@ -58,14 +67,15 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// Add CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Admin-Token")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Admin-Token, X-Request-ID")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 	if req.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	r.mux.ServeHTTP(w, req)
+	r.mux.ServeHTTP(w, auth.WithClientIP(auth.WithRequestID(w, req), r.trustedProxies))
 }
 
 // setupRoutes configures all API routes
@@ -79,10 +89,19 @@ func (r *Router) setupRoutes() {
 	r.mux.Handle("/api/enterprise/users/verify", rateLimitedAuth(http.HandlerFunc(r.userAPI.HandleVerifyEmail)))
 	r.mux.Handle("/api/enterprise/users/resend-verification", rateLimitedAuth(http.HandlerFunc(r.userAPI.HandleResendVerification)))
 
-	// Protected cluster user routes (require user JWT)
-	r.mux.Handle("/api/enterprise/users/profile", auth.RequireUserAuth(r.jwtManager)(http.HandlerFunc(r.userAPI.HandleGetProfile)))
+	// Protected cluster user routes (require user JWT or an API key with the route's scope)
+	r.mux.Handle("/api/enterprise/users/profile", r.requireUser(scoped(enterprise.APIKeyScopeTenantsRead, r.userAPI.HandleGetProfile)))
 	r.mux.Handle("/api/enterprise/users/tenants", r.handleUserTenants())
-	r.mux.Handle("/api/enterprise/users/tenants/sso", auth.RequireUserAuth(r.jwtManager)(http.HandlerFunc(r.userAPI.HandleGenerateTenantSSO)))
+	r.mux.Handle("/api/enterprise/users/tenants/sso", r.requireUser(scoped(enterprise.APIKeyScopeTenantsSSO, r.userAPI.HandleGenerateTenantSSO)))
+	r.mux.Handle("/api/enterprise/users/tenants/restore", r.handleUserTenantRestore())
+	r.mux.Handle("/api/enterprise/users/tenants/cache", r.requireUser(scoped(enterprise.APIKeyScopeTenantsWrite, r.userAPI.HandleUpdateResponseCache)))
+	r.mux.Handle("/api/enterprise/users/tenants/access-policy", r.requireUser(scoped(enterprise.APIKeyScopeTenantsWrite, r.userAPI.HandleUpdateAccessPolicy)))
+	r.mux.Handle("/api/enterprise/users/tenants/extend", r.requireUser(scoped(enterprise.APIKeyScopeTenantsWrite, r.userAPI.HandleExtendTenant)))
+	r.mux.Handle("/api/enterprise/users/tenants/appeal", r.requireUser(scoped(enterprise.APIKeyScopeTenantsWrite, r.userAPI.HandleAppealSuspension)))
+	r.mux.Handle("/api/enterprise/users/tenants/logs", r.requireUser(scoped(enterprise.APIKeyScopeLogsRead, r.userAPI.HandleListTenantLogs)))
+
+	// API key management (login tokens only, the handlers reject API keys)
+	r.mux.Handle("/api/enterprise/users/api-keys", r.requireUser(r.handleUserAPIKeys()))
 
 	// Admin routes (require admin token)
 	r.mux.HandleFunc("/api/enterprise/admin/tokens/generate", r.adminAPI.HandleGenerateAdminToken) // Bootstrap endpoint
@@ -90,7 +109,13 @@ func (r *Router) setupRoutes() {
 	r.mux.Handle("/api/enterprise/admin/users/quota", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleUpdateUserQuota)))
 	r.mux.Handle("/api/enterprise/admin/users/impersonate", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleImpersonateUser)))
 	r.mux.Handle("/api/enterprise/admin/tenants", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.handleAdminTenants())))
+	r.mux.Handle("/api/enterprise/admin/tenants/move", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleMoveTenant)))
+	r.mux.Handle("/api/enterprise/admin/tenants/suspension", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.handleAdminSuspension())))
+	r.mux.Handle("/api/enterprise/admin/tenants/rotate-key", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleRotateTenantKey)))
+	r.mux.Handle("/api/enterprise/admin/keys/rewrap", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleRewrapTenantKeys)))
 	r.mux.Handle("/api/enterprise/admin/nodes", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleListNodes)))
+	r.mux.Handle("/api/enterprise/admin/nodes/drain", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleDrainNode)))
+	r.mux.Handle("/api/enterprise/admin/nodes/status", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleGetNodeStatus)))
 	r.mux.Handle("/api/enterprise/admin/stats", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleGetSystemStats)))
 	r.mux.Handle("/api/enterprise/admin/disk", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleGetDiskStats)))
 
@@ -98,32 +123,114 @@ func (r *Router) setupRoutes() {
 	r.mux.Handle("/api/enterprise/admin/archive/activity", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleGetTenantActivity)))
 	r.mux.Handle("/api/enterprise/admin/archive/inactive", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleListInactiveTenants)))
 	r.mux.Handle("/api/enterprise/admin/archive/tenant", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleArchiveTenant)))
-	r.mux.Handle("/api/enterprise/admin/archive/restore", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleRestoreTenant)))
+	r.mux.Handle("/api/enterprise/admin/archive/restore", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.handleAdminRestore())))
 	r.mux.Handle("/api/enterprise/admin/archive/stats", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleGetArchiveStats)))
 
+	// Admin fleet migration routes
+	r.mux.Handle("/api/enterprise/admin/migrations", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.handleAdminMigrations())))
+	r.mux.Handle("/api/enterprise/admin/migrations/control", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleUpdateFleetMigration)))
+
+	// Admin audit log routes
+	r.mux.Handle("/api/enterprise/admin/audit", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleListAuditLog)))
+	r.mux.Handle("/api/enterprise/admin/audit/export", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleExportAuditLog)))
+
+	// Admin log store routes
+	r.mux.Handle("/api/enterprise/admin/logs", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleListLogs)))
+
+	// Admin email routes
+	r.mux.Handle("/api/enterprise/admin/email/templates", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.handleAdminEmailTemplates())))
+	r.mux.Handle("/api/enterprise/admin/email/queue", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.handleAdminEmailQueue())))
+	r.mux.Handle("/api/enterprise/admin/email/queue/retry", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleRetryOutboundEmail)))
+	r.mux.Handle("/api/enterprise/admin/email/suppressions", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(http.HandlerFunc(r.adminAPI.HandleClearEmailSuppression)))
+
+	// Email provider bounce and complaint webhooks, authenticated by the webhook secret
+	r.mux.HandleFunc("/api/enterprise/email/webhooks", r.adminAPI.HandleEmailWebhook)
+
+	// Cluster admin console (static, its API calls carry the admin token)
+	r.mux.Handle("/_/", http.StripPrefix("/_/", http.FileServer(http.FS(console.DistDirFS))))
+	r.mux.Handle("/_", http.RedirectHandler("/_/", http.StatusMovedPermanently))
+
 	// Health check endpoints
 	r.mux.HandleFunc("/health/live", health.LivenessHandler())
 	r.mux.HandleFunc("/health/ready", health.ReadinessHandler(r.cp.GetHealthChecker()))
 	r.mux.HandleFunc("/api/enterprise/health", r.cp.GetHealthChecker().HTTPHandler())
 
-	// Prometheus metrics endpoint
-	r.mux.Handle("/metrics", promhttp.Handler())
+	// Prometheus metrics endpoint, federating the metrics of the tenant nodes.
+	// Each scrape reaches every node and the series name tenants, so it takes
+	// an admin token.
+	r.mux.Handle("/metrics", auth.RequireAdminAuth(r.adminAPI.ValidateAdminToken)(r.cp.MetricsFederator().Handler()))
+}
+
+// requireUser requires a cluster user login token or API key
+func (r *Router) requireUser(handler http.Handler) http.Handler {
+	return auth.RequireUserAuth(r.jwtManager, r.userAPI.ValidateAPIKey)(handler)
+}
+
+// scoped wraps a user handler so that API keys need scope to call it
+func scoped(scope string, handler http.HandlerFunc) http.Handler {
+	return auth.RequireScope(scope)(handler)
 }
 
 // handleUserTenants handles tenant-related requests for users
 func (r *Router) handleUserTenants() http.Handler {
-	return auth.RequireUserAuth(r.jwtManager)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+	return r.requireUser(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		switch req.Method {
 		case http.MethodGet:
-			r.userAPI.HandleListTenants(w, req)
+			scoped(enterprise.APIKeyScopeTenantsRead, r.userAPI.HandleListTenants).ServeHTTP(w, req)
 		case http.MethodPost:
-			r.userAPI.HandleCreateTenant(w, req)
+			scoped(enterprise.APIKeyScopeTenantsWrite, r.userAPI.HandleCreateTenant).ServeHTTP(w, req)
+		case http.MethodDelete:
+			scoped(enterprise.APIKeyScopeTenantsWrite, r.userAPI.HandleDeleteTenant).ServeHTTP(w, req)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))
 }
 
+// handleUserTenantRestore handles archive restore requests for a user's own tenants
+func (r *Router) handleUserTenantRestore() http.Handler {
+	return r.requireUser(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			scoped(enterprise.APIKeyScopeTenantsRead, r.userAPI.HandleGetRestoreStatus).ServeHTTP(w, req)
+		case http.MethodPost:
+			scoped(enterprise.APIKeyScopeTenantsWrite, r.userAPI.HandleRestoreTenant).ServeHTTP(w, req)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+// handleUserAPIKeys handles API key management requests for users
+func (r *Router) handleUserAPIKeys() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			r.userAPI.HandleListAPIKeys(w, req)
+		case http.MethodPost:
+			r.userAPI.HandleCreateAPIKey(w, req)
+		case http.MethodDelete:
+			r.userAPI.HandleRevokeAPIKey(w, req)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleAdminRestore handles archive restore requests for admins
+func (r *Router) handleAdminRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			r.adminAPI.HandleGetRestoreStatus(w, req)
+		case http.MethodPost:
+			r.adminAPI.HandleRestoreTenant(w, req)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
 // handleAdminUsers handles user-related requests for admins
 func (r *Router) handleAdminUsers() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
@@ -152,6 +259,71 @@ func (r *Router) handleAdminTenants() http.HandlerFunc {
 			} else {
 				r.adminAPI.HandleListTenants(w, req)
 			}
+		case http.MethodDelete:
+			r.adminAPI.HandleDeleteTenant(w, req)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleAdminSuspension handles tenant suspension requests for admins
+func (r *Router) handleAdminSuspension() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPost:
+			r.adminAPI.HandleSuspendTenant(w, req)
+		case http.MethodDelete:
+			r.adminAPI.HandleUnsuspendTenant(w, req)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleAdminEmailTemplates handles email template requests for admins
+func (r *Router) handleAdminEmailTemplates() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			r.adminAPI.HandleListEmailTemplates(w, req)
+		case http.MethodPut:
+			r.adminAPI.HandleSaveEmailTemplate(w, req)
+		case http.MethodDelete:
+			r.adminAPI.HandleDeleteEmailTemplate(w, req)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleAdminEmailQueue handles outbound email queue requests for admins
+func (r *Router) handleAdminEmailQueue() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			r.adminAPI.HandleListOutboundEmails(w, req)
+		case http.MethodDelete:
+			r.adminAPI.HandleDeleteOutboundEmail(w, req)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleAdminMigrations handles fleet migration requests for admins
+func (r *Router) handleAdminMigrations() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			// Check if getting specific migration
+			if req.URL.Query().Get("migrationId") != "" {
+				r.adminAPI.HandleGetFleetMigration(w, req)
+			} else {
+				r.adminAPI.HandleListFleetMigrations(w, req)
+			}
+		case http.MethodPost:
+			r.adminAPI.HandleCreateFleetMigration(w, req)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -0,0 +1,916 @@
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+	"github.com/pocketbase/pocketbase/tools/inflector"
+	"github.com/pocketbase/pocketbase/tools/list"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/pocketbase/pocketbase/tools/search"
+	"github.com/pocketbase/pocketbase/tools/subscriptions"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/spf13/cast"
+)
+
+// note: same as the max nested relations supported by the record expand
+const graphqlMaxExpandDepth = 6
+
+// graphqlKeepAliveInterval is the interval of the SSE comments keeping idle
+// subscription connections open behind proxies.
+const graphqlKeepAliveInterval = 30 * time.Second
+
+var errGraphQLSubscriptionTransport = errors.New("subscriptions require an SSE request (Accept: text/event-stream)")
+
+var graphqlNameRegex = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+
+// graphqlReservedTypeNames are the type names not available for collections.
+var graphqlReservedTypeNames = []string{
+	"Query", "Mutation", "Subscription", "JSON",
+	"String", "Int", "Float", "Boolean", "ID",
+}
+
+type graphqlEventKey struct{}
+
+type graphqlClientKey struct{}
+
+// bindGraphQLApi registers the GraphQL api endpoint.
+func bindGraphQLApi(app core.App, rg *router.RouterGroup[*core.RequestEvent]) {
+	api := &graphqlApi{app: app}
+
+	rg.POST("/graphql", api.handle)
+
+	// regenerate the schema on the next request after a collection change
+	invalidate := func() *hook.Handler[*core.CollectionEvent] {
+		return &hook.Handler[*core.CollectionEvent]{
+			Func: func(e *core.CollectionEvent) error {
+				api.invalidate()
+				return e.Next()
+			},
+			Priority: -99,
+		}
+	}
+	app.OnCollectionAfterCreateSuccess().Bind(invalidate())
+	app.OnCollectionAfterUpdateSuccess().Bind(invalidate())
+	app.OnCollectionAfterDeleteSuccess().Bind(invalidate())
+}
+
+type graphqlApi struct {
+	app core.App
+
+	mu      sync.Mutex
+	schema  *graphql.Schema
+	version int
+}
+
+type graphqlRequestForm struct {
+	Query         string         `form:"query" json:"query"`
+	OperationName string         `form:"operationName" json:"operationName"`
+	Variables     map[string]any `form:"variables" json:"variables"`
+}
+
+func (api *graphqlApi) handle(e *core.RequestEvent) error {
+	form := new(graphqlRequestForm)
+
+	err := e.BindBody(form)
+	if err != nil {
+		return e.BadRequestError("Failed to read the GraphQL request.", err)
+	}
+
+	if form.Query == "" {
+		return e.BadRequestError("Missing GraphQL query.", nil)
+	}
+
+	schema, err := api.loadSchema()
+	if err != nil {
+		return e.InternalServerError("Failed to build the GraphQL schema.", err)
+	}
+
+	params := graphql.Params{
+		Schema:         *schema,
+		RequestString:  form.Query,
+		OperationName:  form.OperationName,
+		VariableValues: form.Variables,
+		Context:        context.WithValue(e.Request.Context(), graphqlEventKey{}, e),
+	}
+
+	if strings.Contains(e.Request.Header.Get("Accept"), "text/event-stream") {
+		return graphqlSubscribe(e, params)
+	}
+
+	return e.JSON(http.StatusOK, graphql.Do(params))
+}
+
+// invalidate discards the current schema so that it is rebuilt on the next request.
+func (api *graphqlApi) invalidate() {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.schema = nil
+	api.version++
+}
+
+// loadSchema returns the current schema, building it from the app collections if necessary.
+func (api *graphqlApi) loadSchema() (*graphql.Schema, error) {
+	api.mu.Lock()
+	schema, version := api.schema, api.version
+	api.mu.Unlock()
+
+	if schema != nil {
+		return schema, nil
+	}
+
+	collections, err := api.app.FindAllCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	built, err := newGraphQLSchema(api.app, collections)
+	if err != nil {
+		return nil, err
+	}
+
+	// don't cache a schema built before a concurrent collection change
+	api.mu.Lock()
+	if api.version == version {
+		api.schema = &built
+	}
+	api.mu.Unlock()
+
+	return &built, nil
+}
+
+// graphqlSubscribe streams the results of a subscription operation as
+// "next" SSE events, followed by a "complete" event once it ends.
+func graphqlSubscribe(e *core.RequestEvent, params graphql.Params) error {
+	// disable global write deadline for the SSE connection
+	rc := http.NewResponseController(e.Response)
+	writeDeadlineErr := rc.SetWriteDeadline(time.Time{})
+	if writeDeadlineErr != nil {
+		if !errors.Is(writeDeadlineErr, http.ErrNotSupported) {
+			return e.InternalServerError("Failed to initialize SSE connection.", writeDeadlineErr)
+		}
+
+		// only log since there are valid cases where it may not be implement (e.g. httptest.ResponseRecorder)
+		e.App.Logger().Warn("SetWriteDeadline is not supported, fallback to the default server WriteTimeout")
+	}
+
+	ctx, cancel := context.WithCancel(params.Context)
+	defer cancel()
+
+	// the subscriptions are served by a regular realtime client so that
+	// the records are broadcasted with the same access checks
+	client := subscriptions.NewDefaultClient()
+	client.Set(RealtimeClientAuthKey, e.Auth)
+	e.App.SubscriptionsBroker().Register(client)
+	defer func() {
+		e.App.SubscriptionsBroker().Unregister(client.Id())
+	}()
+
+	e.Response.Header().Set("Content-Type", "text/event-stream")
+	e.Response.Header().Set("Cache-Control", "no-store")
+	e.Response.Header().Set("X-Accel-Buffering", "no")
+
+	params.Context = context.WithValue(ctx, graphqlClientKey{}, client)
+	results := graphql.Subscribe(params)
+	defer func() {
+		cancel()
+
+		// unblock the executor in case it is in the middle of sending a result
+		go func() {
+			for range results {
+			}
+		}()
+	}()
+
+	keepAlive := time.NewTicker(graphqlKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				graphqlWriteSSE(e, "complete", "")
+				return nil
+			}
+
+			data, err := json.Marshal(result)
+			if err != nil {
+				return e.InternalServerError("Failed to serialize the subscription result.", err)
+			}
+
+			if err := graphqlWriteSSE(e, "next", string(data)); err != nil {
+				e.App.Logger().Debug(
+					"GraphQL subscription closed (failed to deliver result)",
+					slog.String("clientId", client.Id()),
+					slog.String("error", err.Error()),
+				)
+				return nil
+			}
+		case <-keepAlive.C:
+			if err := graphqlWriteSSE(e, "", ""); err != nil {
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// graphqlWriteSSE writes a single SSE event (or a keep-alive comment if event is empty).
+func graphqlWriteSSE(e *core.RequestEvent, event string, data string) error {
+	var err error
+	if event == "" {
+		_, err = e.Response.Write([]byte(":\n\n"))
+	} else {
+		_, err = fmt.Fprintf(e.Response, "event: %s\ndata: %s\n\n", event, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	return e.Flush()
+}
+
+// graphqlError exposes the status and data of an API error as GraphQL error extensions.
+type graphqlError struct {
+	*router.ApiError
+}
+
+// Extensions implements the graphql-go gqlerrors.ExtendedError interface.
+func (err graphqlError) Extensions() map[string]any {
+	return map[string]any{
+		"status": err.Status,
+		"data":   err.Data,
+	}
+}
+
+// graphqlExecute runs a record api handler as an internal request of the
+// GraphQL request so that the same rules, hooks and response fields apply.
+func graphqlExecute(
+	p graphql.ResolveParams,
+	handle HandleFunc,
+	method string,
+	url string,
+	params map[string]string,
+	body map[string]any,
+) (any, error) {
+	e, _ := p.Context.Value(graphqlEventKey{}).(*core.RequestEvent)
+	if e == nil {
+		return nil, errors.New("missing GraphQL request event")
+	}
+
+	ir := &core.InternalRequest{
+		Method: method,
+		URL:    url,
+		Body:   body,
+	}
+
+	result, err := executeInternalRequest(e.App, e, ir, params, core.RequestInfoContextGraphQL, handle)
+	if err != nil {
+		return nil, graphqlError{router.ToApiError(err)}
+	}
+
+	raw, _ := result.Body.(types.JSONRaw)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var data any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// -------------------------------------------------------------------
+
+// graphqlCollectionNames holds the GraphQL names generated for a collection,
+// e.g. for "blog_posts":
+//
+//	Type: "BlogPost" (+ "BlogPostList", "BlogPostInput", "BlogPostEvent")
+//	List: "blogPosts" (list query and subscription)
+//	View: "blogPost" (single record query)
+type graphqlCollectionNames struct {
+	Type string
+	List string
+	View string
+}
+
+func newGraphQLCollectionNames(collection *core.Collection) graphqlCollectionNames {
+	camelized := inflector.Camelize(collection.Name)
+	if camelized == "" {
+		return graphqlCollectionNames{}
+	}
+
+	list := strings.ToLower(camelized[:1]) + camelized[1:]
+	singular := inflector.Singularize(list)
+
+	names := graphqlCollectionNames{
+		Type: inflector.UcFirst(singular),
+		List: list,
+		View: singular,
+	}
+
+	// uncountable names (e.g. "news")
+	if names.View == names.List {
+		names.View += "One"
+	}
+
+	return names
+}
+
+func (names graphqlCollectionNames) typeNames() []string {
+	return []string{names.Type, names.Type + "List", names.Type + "Input", names.Type + "Event"}
+}
+
+// graphqlSchemaBuilder generates a GraphQL schema from the app collections.
+type graphqlSchemaBuilder struct {
+	app          core.App
+	json         *graphql.Scalar
+	collections  map[string]*core.Collection // collection id -> collection
+	types        map[string]*graphql.Object  // collection id -> record type
+	typeNames    map[string]bool             // used type names
+	query        graphql.Fields
+	mutation     graphql.Fields
+	subscription graphql.Fields
+}
+
+// newGraphQLSchema builds a GraphQL schema with list/view queries, create/update/delete
+// mutations and a record events subscription for each of the provided collections.
+//
+// System collections and collections whose generated names are invalid or
+// conflict with another collection are skipped.
+func newGraphQLSchema(app core.App, collections []*core.Collection) (graphql.Schema, error) {
+	b := &graphqlSchemaBuilder{
+		app:          app,
+		json:         newGraphQLJSONScalar(),
+		collections:  map[string]*core.Collection{},
+		types:        map[string]*graphql.Object{},
+		typeNames:    map[string]bool{},
+		query:        graphql.Fields{},
+		mutation:     graphql.Fields{},
+		subscription: graphql.Fields{},
+	}
+
+	for _, name := range graphqlReservedTypeNames {
+		b.typeNames[name] = true
+	}
+
+	sorted := slices.Clone(collections)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	for _, collection := range sorted {
+		if collection.System {
+			continue
+		}
+
+		names := newGraphQLCollectionNames(collection)
+		if !b.available(names) {
+			app.Logger().Warn(
+				"Collection skipped from the GraphQL schema because of invalid or conflicting names",
+				slog.String("collectionName", collection.Name),
+			)
+			continue
+		}
+
+		b.addCollection(collection, names)
+	}
+
+	// an object type must have at least one field
+	if len(b.query) == 0 {
+		b.query["_empty"] = &graphql.Field{Type: graphql.Boolean}
+	}
+
+	config := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: b.query}),
+	}
+	if len(b.mutation) > 0 {
+		config.Mutation = graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: b.mutation})
+	}
+	if len(b.subscription) > 0 {
+		config.Subscription = graphql.NewObject(graphql.ObjectConfig{Name: "Subscription", Fields: b.subscription})
+	}
+
+	return graphql.NewSchema(config)
+}
+
+// available checks whether the collection names are valid and not used yet.
+func (b *graphqlSchemaBuilder) available(names graphqlCollectionNames) bool {
+	for _, name := range append(names.typeNames(), names.List, names.View) {
+		if !graphqlNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+			return false
+		}
+	}
+
+	for _, name := range names.typeNames() {
+		if b.typeNames[name] {
+			return false
+		}
+	}
+
+	_, listExists := b.query[names.List]
+	_, viewExists := b.query[names.View]
+
+	return !listExists && !viewExists
+}
+
+func (b *graphqlSchemaBuilder) addCollection(collection *core.Collection, names graphqlCollectionNames) {
+	for _, name := range names.typeNames() {
+		b.typeNames[name] = true
+	}
+
+	recordType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        names.Type,
+		Description: "A record of the " + collection.Name + " collection.",
+		// resolved lazily because the relation fields may reference collections registered later
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return b.recordFields(collection)
+		}),
+	})
+
+	b.collections[collection.Id] = collection
+	b.types[collection.Id] = recordType
+
+	collectionPath := "/api/collections/" + url.PathEscape(collection.Name) + "/records"
+	collectionParams := func(id string) map[string]string {
+		params := map[string]string{"collection": collection.Name}
+		if id != "" {
+			params["id"] = id
+		}
+		return params
+	}
+
+	// queries
+	// ---
+	b.query[names.List] = &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+			Name: names.Type + "List",
+			Fields: graphql.Fields{
+				"page":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"perPage":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"totalItems": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"totalPages": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"items":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(recordType)))},
+			},
+		})),
+		Description: "Returns a paginated " + collection.Name + " records list.",
+		Args: graphql.FieldConfigArgument{
+			search.PageQueryParam:      &graphql.ArgumentConfig{Type: graphql.Int},
+			search.PerPageQueryParam:   &graphql.ArgumentConfig{Type: graphql.Int},
+			search.SortQueryParam:      &graphql.ArgumentConfig{Type: graphql.String},
+			search.FilterQueryParam:    &graphql.ArgumentConfig{Type: graphql.String},
+			search.SkipTotalQueryParam: &graphql.ArgumentConfig{Type: graphql.Boolean},
+		},
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			query := url.Values{}
+			for name, value := range p.Args {
+				if value != nil {
+					query.Set(name, cast.ToString(value))
+				}
+			}
+			b.setExpand(query, collection, p.Info, "items")
+
+			return graphqlExecute(p, recordsList, http.MethodGet, collectionPath+"?"+query.Encode(), collectionParams(""), nil)
+		},
+	}
+
+	b.query[names.View] = &graphql.Field{
+		Type:        recordType,
+		Description: "Returns a single " + collection.Name + " record.",
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+		},
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			id := cast.ToString(p.Args["id"])
+
+			query := url.Values{}
+			b.setExpand(query, collection, p.Info, "")
+
+			return graphqlExecute(p, recordView, http.MethodGet, collectionPath+"/"+url.PathEscape(id)+"?"+query.Encode(), collectionParams(id), nil)
+		},
+	}
+
+	// subscription
+	// ---
+	b.subscription[names.List] = &graphql.Field{
+		Type: graphql.NewObject(graphql.ObjectConfig{
+			Name: names.Type + "Event",
+			Fields: graphql.Fields{
+				"action": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"record": &graphql.Field{Type: recordType},
+			},
+		}),
+		Description: "Streams the create, update and delete events of the " + collection.Name + " records (or of a single record if id is set).",
+		Args: graphql.FieldConfigArgument{
+			"id":                    &graphql.ArgumentConfig{Type: graphql.ID},
+			search.FilterQueryParam: &graphql.ArgumentConfig{Type: graphql.String},
+		},
+		Subscribe: func(p graphql.ResolveParams) (any, error) {
+			return b.subscribe(p, collection)
+		},
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			// the source is the event decoded by subscribe
+			if p.Context.Value(graphqlClientKey{}) == nil {
+				return nil, errGraphQLSubscriptionTransport
+			}
+			return p.Source, nil
+		},
+	}
+
+	// mutations
+	// ---
+	if collection.IsView() {
+		return
+	}
+
+	mutationArgs := func(withId bool) graphql.FieldConfigArgument {
+		args := graphql.FieldConfigArgument{}
+		if withId {
+			args["id"] = &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}
+		}
+		return args
+	}
+	createArgs := mutationArgs(false)
+	updateArgs := mutationArgs(true)
+
+	// note: input objects must have at least one field
+	inputFields := b.inputFields(collection)
+	if len(inputFields) > 0 {
+		input := graphql.NewInputObject(graphql.InputObjectConfig{
+			Name:   names.Type + "Input",
+			Fields: inputFields,
+		})
+		createArgs["data"] = &graphql.ArgumentConfig{Type: graphql.NewNonNull(input)}
+		updateArgs["data"] = &graphql.ArgumentConfig{Type: graphql.NewNonNull(input)}
+	}
+
+	b.mutation["create"+names.Type] = &graphql.Field{
+		Type:        recordType,
+		Description: "Creates a new " + collection.Name + " record.",
+		Args:        createArgs,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			data, _ := p.Args["data"].(map[string]any)
+
+			query := url.Values{}
+			b.setExpand(query, collection, p.Info, "")
+
+			return graphqlExecute(p, recordCreate(false, nil), http.MethodPost, collectionPath+"?"+query.Encode(), collectionParams(""), data)
+		},
+	}
+
+	b.mutation["update"+names.Type] = &graphql.Field{
+		Type:        recordType,
+		Description: "Updates a single " + collection.Name + " record.",
+		Args:        updateArgs,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			id := cast.ToString(p.Args["id"])
+			data, _ := p.Args["data"].(map[string]any)
+
+			query := url.Values{}
+			b.setExpand(query, collection, p.Info, "")
+
+			return graphqlExecute(p, recordUpdate(false, nil), http.MethodPatch, collectionPath+"/"+url.PathEscape(id)+"?"+query.Encode(), collectionParams(id), data)
+		},
+	}
+
+	b.mutation["delete"+names.Type] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Boolean),
+		Description: "Deletes a single " + collection.Name + " record.",
+		Args:        mutationArgs(true),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			id := cast.ToString(p.Args["id"])
+
+			_, err := graphqlExecute(p, recordDelete(false, nil), http.MethodDelete, collectionPath+"/"+url.PathEscape(id), collectionParams(id), nil)
+			if err != nil {
+				return false, err
+			}
+
+			return true, nil
+		},
+	}
+}
+
+// recordFields returns the output fields of a collection record type.
+func (b *graphqlSchemaBuilder) recordFields(collection *core.Collection) graphql.Fields {
+	fields := graphql.Fields{
+		"collectionId":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"collectionName": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	}
+
+	for _, field := range collection.Fields {
+		name := field.GetName()
+		if !graphqlNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		if name == core.FieldNameId {
+			fields[name] = &graphql.Field{Type: graphql.NewNonNull(graphql.ID)}
+			continue
+		}
+
+		switch f := field.(type) {
+		case *core.PasswordField:
+			// never returned
+		case *core.NumberField:
+			fields[name] = &graphql.Field{Type: graphql.Float}
+		case *core.BoolField:
+			fields[name] = &graphql.Field{Type: graphql.Boolean}
+		case *core.JSONField, *core.GeoPointField:
+			fields[name] = &graphql.Field{Type: b.json}
+		case *core.SelectField:
+			fields[name] = &graphql.Field{Type: graphqlStringType(f.IsMultiple())}
+		case *core.FileField:
+			fields[name] = &graphql.Field{Type: graphqlStringType(f.IsMultiple())}
+		case *core.RelationField:
+			target := b.types[f.CollectionId]
+			if target == nil {
+				// the related collection is not part of the schema, return the ids
+				fields[name] = &graphql.Field{Type: graphqlStringType(f.IsMultiple())}
+				continue
+			}
+
+			var fieldType graphql.Output = target
+			if f.IsMultiple() {
+				fieldType = graphql.NewList(graphql.NewNonNull(target))
+			}
+
+			// resolved from the expanded relations (see setExpand)
+			fields[name] = &graphql.Field{
+				Type: fieldType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					record, _ := p.Source.(map[string]any)
+					expand, _ := record["expand"].(map[string]any)
+					return expand[name], nil
+				},
+			}
+		default:
+			fields[name] = &graphql.Field{Type: graphql.String}
+		}
+	}
+
+	return fields
+}
+
+// inputFields returns the create/update input fields of a collection.
+//
+// File fields are not part of the input because uploads are not supported.
+func (b *graphqlSchemaBuilder) inputFields(collection *core.Collection) graphql.InputObjectConfigFieldMap {
+	fields := graphql.InputObjectConfigFieldMap{}
+
+	for _, field := range collection.Fields {
+		name := field.GetName()
+		if name == core.FieldNameId || !graphqlNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		switch f := field.(type) {
+		case *core.AutodateField, *core.FileField:
+			// not submittable
+		case *core.NumberField:
+			fields[name] = &graphql.InputObjectFieldConfig{Type: graphql.Float}
+		case *core.BoolField:
+			fields[name] = &graphql.InputObjectFieldConfig{Type: graphql.Boolean}
+		case *core.JSONField, *core.GeoPointField:
+			fields[name] = &graphql.InputObjectFieldConfig{Type: b.json}
+		case *core.SelectField:
+			fields[name] = &graphql.InputObjectFieldConfig{Type: graphqlStringType(f.IsMultiple())}
+		case *core.RelationField:
+			fields[name] = &graphql.InputObjectFieldConfig{Type: graphqlStringType(f.IsMultiple())}
+		default:
+			fields[name] = &graphql.InputObjectFieldConfig{Type: graphql.String}
+		}
+	}
+
+	if collection.IsAuth() {
+		fields[core.FieldNamePassword+"Confirm"] = &graphql.InputObjectFieldConfig{Type: graphql.String}
+		fields["oldPassword"] = &graphql.InputObjectFieldConfig{Type: graphql.String}
+	}
+
+	return fields
+}
+
+// setExpand sets the expand query param to the relation fields selected in the
+// resolved field (or in its subField selection, e.g. "items" of a list), so that
+// the related records are fetched in batches by [core.App.ExpandRecords].
+func (b *graphqlSchemaBuilder) setExpand(query url.Values, collection *core.Collection, info graphql.ResolveInfo, subField string) {
+	var paths []string
+
+	for _, field := range info.FieldASTs {
+		selected := []*ast.Field{field}
+		if subField != "" {
+			selected = nil
+			for _, f := range graphqlSelectedFields(info, field.SelectionSet) {
+				if f.Name.Value == subField {
+					selected = append(selected, f)
+				}
+			}
+		}
+
+		for _, f := range selected {
+			b.collectExpand(&paths, collection, info, f.SelectionSet, "", 1)
+		}
+	}
+
+	if len(paths) > 0 {
+		query.Set(expandQueryParam, strings.Join(list.ToUniqueStringSlice(paths), ","))
+	}
+}
+
+func (b *graphqlSchemaBuilder) collectExpand(
+	paths *[]string,
+	collection *core.Collection,
+	info graphql.ResolveInfo,
+	selectionSet *ast.SelectionSet,
+	prefix string,
+	depth int,
+) {
+	if depth > graphqlMaxExpandDepth {
+		return
+	}
+
+	for _, f := range graphqlSelectedFields(info, selectionSet) {
+		relField, ok := collection.Fields.GetByName(f.Name.Value).(*core.RelationField)
+		if !ok {
+			continue
+		}
+
+		target := b.collections[relField.CollectionId]
+		if target == nil {
+			continue
+		}
+
+		path := prefix + relField.Name
+		*paths = append(*paths, path)
+
+		b.collectExpand(paths, target, info, f.SelectionSet, path+".", depth+1)
+	}
+}
+
+// graphqlSelectedFields returns the fields of a selection set, including the ones of its fragments.
+func graphqlSelectedFields(info graphql.ResolveInfo, selectionSet *ast.SelectionSet) []*ast.Field {
+	if selectionSet == nil {
+		return nil
+	}
+
+	var fields []*ast.Field
+
+	for _, selection := range selectionSet.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			fields = append(fields, s)
+		case *ast.InlineFragment:
+			fields = append(fields, graphqlSelectedFields(info, s.SelectionSet)...)
+		case *ast.FragmentSpread:
+			if fragment, ok := info.Fragments[s.Name.Value].(*ast.FragmentDefinition); ok {
+				fields = append(fields, graphqlSelectedFields(info, fragment.SelectionSet)...)
+			}
+		}
+	}
+
+	return fields
+}
+
+// subscribe subscribes the SSE realtime client of the request to the collection
+// records and returns a channel with the decoded broadcasted messages.
+func (b *graphqlSchemaBuilder) subscribe(p graphql.ResolveParams, collection *core.Collection) (any, error) {
+	client, _ := p.Context.Value(graphqlClientKey{}).(subscriptions.Client)
+	if client == nil {
+		return nil, errGraphQLSubscriptionTransport
+	}
+
+	topic := collection.Name + "/*"
+	if id := cast.ToString(p.Args["id"]); id != "" {
+		topic = collection.Name + "/" + id
+	}
+
+	query := url.Values{}
+	if filter := cast.ToString(p.Args[search.FilterQueryParam]); filter != "" {
+		query.Set(search.FilterQueryParam, filter)
+	}
+	b.setExpand(query, collection, p.Info, "record")
+
+	options := map[string]any{"query": map[string]string{}}
+	for name := range query {
+		options["query"].(map[string]string)[name] = query.Get(name)
+	}
+	rawOptions, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	topic += "?options=" + url.QueryEscape(string(rawOptions))
+
+	client.Subscribe(topic)
+
+	events := make(chan any)
+
+	go func() {
+		defer close(events)
+
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case msg, ok := <-client.Channel():
+				if !ok {
+					return
+				}
+
+				if msg.Name != topic {
+					continue
+				}
+
+				event := map[string]any{}
+				if err := json.Unmarshal(msg.Data, &event); err != nil {
+					b.app.Logger().Debug(
+						"Failed to decode GraphQL subscription message",
+						slog.String("clientId", client.Id()),
+						slog.String("error", err.Error()),
+					)
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func graphqlStringType(multiple bool) graphql.Type {
+	if multiple {
+		return graphql.NewList(graphql.NewNonNull(graphql.String))
+	}
+	return graphql.String
+}
+
+// newGraphQLJSONScalar creates the scalar of the json field values.
+func newGraphQLJSONScalar() *graphql.Scalar {
+	return graphql.NewScalar(graphql.ScalarConfig{
+		Name:        "JSON",
+		Description: "Arbitrary JSON value.",
+		Serialize: func(value any) any {
+			return value
+		},
+		ParseValue: func(value any) any {
+			return value
+		},
+		ParseLiteral: graphqlLiteralValue,
+	})
+}
+
+// graphqlLiteralValue converts an inline JSON scalar value to its Go representation.
+func graphqlLiteralValue(value ast.Value) any {
+	switch v := value.(type) {
+	case *ast.StringValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.IntValue:
+		n, _ := strconv.ParseInt(v.Value, 10, 64)
+		return n
+	case *ast.FloatValue:
+		n, _ := strconv.ParseFloat(v.Value, 64)
+		return n
+	case *ast.ListValue:
+		result := make([]any, 0, len(v.Values))
+		for _, item := range v.Values {
+			result = append(result, graphqlLiteralValue(item))
+		}
+		return result
+	case *ast.ObjectValue:
+		result := make(map[string]any, len(v.Fields))
+		for _, field := range v.Fields {
+			result[field.Name.Value] = graphqlLiteralValue(field.Value)
+		}
+		return result
+	default:
+		return nil
+	}
+}
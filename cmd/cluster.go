@@ -0,0 +1,849 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/tenant_node"
+	"github.com/spf13/cobra"
+)
+
+// NewClusterCommand creates and returns new command group for managing the
+// tenants of a cluster user through a control plane (login, tenants, sso,
+// export, logs and API keys).
+func NewClusterCommand() *cobra.Command {
+	opts := &clusterOptions{}
+
+	command := &cobra.Command{
+		Use:   "cluster",
+		Short: "Manage your tenants on a PocketBase cluster",
+	}
+
+	command.PersistentFlags().StringVar(&opts.url, "url", "", "the control plane URL (default "+clusterURLEnv+" or the saved login)")
+	command.PersistentFlags().StringVar(&opts.token, "token", "", "a login token or API key (default "+clusterTokenEnv+" or the saved login)")
+	command.PersistentFlags().StringVar(&opts.credentials, "credentials", defaultClusterCredentialsPath(), "the file the login is saved to")
+	command.PersistentFlags().BoolVar(&opts.json, "json", false, "print the API responses as JSON")
+
+	command.AddCommand(clusterLoginCommand(opts))
+	command.AddCommand(clusterLogoutCommand(opts))
+	command.AddCommand(clusterTenantsCommand(opts))
+	command.AddCommand(clusterSSOCommand(opts))
+	command.AddCommand(clusterExportCommand(opts))
+	command.AddCommand(clusterLogsCommand(opts))
+	command.AddCommand(clusterKeysCommand(opts))
+
+	return command
+}
+
+func clusterLoginCommand(opts *clusterOptions) *cobra.Command {
+	var email string
+	var password string
+	var apiKey string
+
+	command := &cobra.Command{
+		Use:          "login",
+		Example:      "cluster login --url https://cp.example.com --email test@example.com",
+		Short:        "Logs in to a control plane with a password or an API key and saves the login",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			baseURL := strings.TrimRight(firstNonEmpty(opts.url, os.Getenv(clusterURLEnv)), "/")
+			if baseURL == "" {
+				return fmt.Errorf("missing control plane URL, set --url or %s", clusterURLEnv)
+			}
+
+			creds := &clusterCredentials{URL: baseURL}
+
+			if apiKey != "" {
+				if !strings.HasPrefix(apiKey, enterprise.APIKeyPrefix) {
+					return errors.New("invalid API key")
+				}
+
+				var profile struct {
+					Email string `json:"email"`
+				}
+				client := newClusterClient(baseURL, apiKey)
+				if err := client.call(command.Context(), http.MethodGet, "/api/enterprise/users/profile", nil, nil, &profile); err != nil {
+					return fmt.Errorf("failed to verify the API key: %w", err)
+				}
+
+				creds.Token = apiKey
+				creds.Email = profile.Email
+			} else {
+				if email == "" {
+					return errors.New("missing --email or --api-key")
+				}
+
+				password = firstNonEmpty(password, os.Getenv(clusterPasswordEnv))
+				if password == "" {
+					var err error
+					if password, err = promptPassword(command); err != nil {
+						return err
+					}
+				}
+
+				var resp struct {
+					Token string `json:"token"`
+				}
+				client := newClusterClient(baseURL, "")
+				err := client.call(command.Context(), http.MethodPost, "/api/enterprise/users/login", nil, map[string]string{
+					"email":    email,
+					"password": password,
+				}, &resp)
+				if err != nil {
+					return fmt.Errorf("failed to log in: %w", err)
+				}
+
+				creds.Token = resp.Token
+				creds.Email = email
+			}
+
+			if err := saveClusterCredentials(opts.credentials, creds); err != nil {
+				return fmt.Errorf("failed to save the login: %w", err)
+			}
+
+			color.Green("Successfully logged in to %s as %s!", baseURL, creds.Email)
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&email, "email", "", "the cluster user email")
+	command.Flags().StringVar(&password, "password", "", "the cluster user password (default "+clusterPasswordEnv+" or prompted)")
+	command.Flags().StringVar(&apiKey, "api-key", "", "log in with an API key instead of a password")
+
+	return command
+}
+
+// promptPassword reads the password from the command input
+func promptPassword(command *cobra.Command) (string, error) {
+	fmt.Fprint(command.ErrOrStderr(), "Password: ")
+
+	line, err := bufio.NewReader(command.InOrStdin()).ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", errors.New("missing password")
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func clusterLogoutCommand(opts *clusterOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:          "logout",
+		Short:        "Removes the saved login",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if err := os.Remove(opts.credentials); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove the saved login: %w", err)
+			}
+
+			color.Green("Successfully logged out!")
+			return nil
+		},
+	}
+
+	return command
+}
+
+func clusterTenantsCommand(opts *clusterOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "tenants",
+		Short: "Manage your tenants",
+	}
+
+	command.AddCommand(clusterTenantsListCommand(opts))
+	command.AddCommand(clusterTenantsCreateCommand(opts))
+	command.AddCommand(clusterTenantsDeleteCommand(opts))
+	command.AddCommand(clusterTenantsExtendCommand(opts))
+	command.AddCommand(clusterTenantsAppealCommand(opts))
+	command.AddCommand(clusterTenantsAccessPolicyCommand(opts))
+
+	return command
+}
+
+func clusterTenantsListCommand(opts *clusterOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:          "list",
+		Short:        "Lists your tenants",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			var resp struct {
+				Tenants   []*enterprise.Tenant `json:"tenants"`
+				Total     int                  `json:"total"`
+				AppealURL string               `json:"appealUrl,omitempty"`
+			}
+			if err := client.call(command.Context(), http.MethodGet, "/api/enterprise/users/tenants", nil, nil, &resp); err != nil {
+				return err
+			}
+
+			if opts.json {
+				return printClusterJSON(command.OutOrStdout(), resp)
+			}
+
+			w := tabwriter.NewWriter(command.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tDOMAIN\tSTATUS\tREGION\tCREATED\tEXPIRES")
+			for _, tenant := range resp.Tenants {
+				expires := "never"
+				if tenant.ExpiresAt != nil {
+					expires = tenant.ExpiresAt.Format(time.RFC3339)
+				}
+				status := string(tenant.Status)
+				if tenant.Suspended() {
+					status = "suspended"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", tenant.ID, tenant.Domain, status, tenant.Region, tenant.Created.Format(time.RFC3339), expires)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			for _, tenant := range resp.Tenants {
+				if !tenant.Suspended() {
+					continue
+				}
+				printClusterSuspension(command.OutOrStdout(), tenant, resp.AppealURL)
+			}
+			return nil
+		},
+	}
+
+	return command
+}
+
+func clusterTenantsCreateCommand(opts *clusterOptions) *cobra.Command {
+	var domain string
+	var region string
+	var preview bool
+	var ttl time.Duration
+
+	command := &cobra.Command{
+		Use:          "create <id>",
+		Example:      "cluster tenants create pr-123 --domain pr-123.preview.example.com --preview --ttl 72h",
+		Short:        "Creates a new tenant (with id tenant_<id>)",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if domain == "" {
+				return errors.New("missing --domain")
+			}
+
+			if ttl < 0 {
+				return errors.New("--ttl must be positive")
+			}
+
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			body := map[string]any{
+				"id":     args[0],
+				"domain": domain,
+				"region": region,
+			}
+			if preview {
+				body["preview"] = true
+			}
+			if ttl > 0 {
+				body["ttlHours"] = clusterTTLHours(ttl)
+			}
+
+			var resp struct {
+				Tenant *enterprise.Tenant `json:"tenant"`
+			}
+			err = client.call(command.Context(), http.MethodPost, "/api/enterprise/users/tenants", nil, body, &resp)
+			if err != nil {
+				return fmt.Errorf("failed to create tenant: %w", err)
+			}
+
+			if opts.json {
+				return printClusterJSON(command.OutOrStdout(), resp.Tenant)
+			}
+
+			color.Green("Successfully created tenant %s (%s)!", resp.Tenant.ID, resp.Tenant.Domain)
+			if resp.Tenant.ExpiresAt != nil {
+				fmt.Fprintf(command.OutOrStdout(), "It will be deleted at %s.\n", resp.Tenant.ExpiresAt.Format(time.RFC3339))
+			}
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&domain, "domain", "", "the tenant domain")
+	command.Flags().StringVar(&region, "region", "", "the region the tenant data resides in (default the cluster's region)")
+	command.Flags().BoolVar(&preview, "preview", false, "create an ephemeral tenant with the preview quotas (expires after 7 days unless --ttl is set)")
+	command.Flags().DurationVar(&ttl, "ttl", 0, "delete the tenant after this long, rounded up to hours, e.g. 72h (default never, or 7 days for previews)")
+
+	return command
+}
+
+func clusterTenantsDeleteCommand(opts *clusterOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:          "delete <tenantId>",
+		Example:      "cluster tenants delete tenant_pr-123",
+		Short:        "Deletes a tenant and shreds its data keys",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			query := url.Values{"tenantId": {args[0]}}
+			if err := client.call(command.Context(), http.MethodDelete, "/api/enterprise/users/tenants", query, nil, nil); err != nil {
+				return fmt.Errorf("failed to delete tenant: %w", err)
+			}
+
+			color.Green("Successfully deleted tenant %s!", args[0])
+			return nil
+		},
+	}
+
+	return command
+}
+
+func clusterTenantsExtendCommand(opts *clusterOptions) *cobra.Command {
+	var ttl time.Duration
+
+	command := &cobra.Command{
+		Use:          "extend <tenantId>",
+		Example:      "cluster tenants extend tenant_pr-123 --ttl 72h",
+		Short:        "Pushes back the expiry of a tenant",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if ttl <= 0 {
+				return errors.New("missing --ttl")
+			}
+
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			var resp struct {
+				Tenant *enterprise.Tenant `json:"tenant"`
+			}
+			err = client.call(command.Context(), http.MethodPost, "/api/enterprise/users/tenants/extend", nil, map[string]any{
+				"tenantId":    args[0],
+				"extendHours": clusterTTLHours(ttl),
+			}, &resp)
+			if err != nil {
+				return fmt.Errorf("failed to extend tenant: %w", err)
+			}
+
+			if opts.json {
+				return printClusterJSON(command.OutOrStdout(), resp.Tenant)
+			}
+
+			color.Green("Tenant %s now expires at %s.", resp.Tenant.ID, resp.Tenant.ExpiresAt.Format(time.RFC3339))
+			return nil
+		},
+	}
+
+	command.Flags().DurationVar(&ttl, "ttl", 0, "how much longer to keep the tenant, rounded up to hours, e.g. 72h")
+
+	return command
+}
+
+// printClusterSuspension tells the owner of a suspended tenant why it was
+// suspended and how to appeal
+func printClusterSuspension(out io.Writer, tenant *enterprise.Tenant, appealURL string) {
+	suspension := tenant.Suspension
+
+	fmt.Fprintln(out)
+	color.New(color.FgYellow).Fprintf(out, "Tenant %s is suspended (%s) since %s.\n", tenant.ID, suspension.Reason, suspension.SuspendedAt.Format(time.RFC3339))
+	if suspension.Message != "" {
+		fmt.Fprintf(out, "  %s\n", suspension.Message)
+	}
+	if suspension.AppealedAt != nil {
+		fmt.Fprintf(out, "  Appealed at %s, waiting for review.\n", suspension.AppealedAt.Format(time.RFC3339))
+		return
+	}
+	fmt.Fprintf(out, "  Appeal with: pocketbase cluster tenants appeal %s --message \"...\"\n", tenant.ID)
+	if appealURL != "" {
+		fmt.Fprintf(out, "  or at %s\n", appealURL)
+	}
+}
+
+func clusterTenantsAppealCommand(opts *clusterOptions) *cobra.Command {
+	var message string
+
+	command := &cobra.Command{
+		Use:          "appeal <tenantId>",
+		Example:      `cluster tenants appeal tenant_shop --message "The invoice was paid on March 3rd"`,
+		Short:        "Appeals the suspension of a tenant",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if message == "" {
+				return errors.New("missing --message")
+			}
+
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			var resp struct {
+				Tenant *enterprise.Tenant `json:"tenant"`
+			}
+			err = client.call(command.Context(), http.MethodPost, "/api/enterprise/users/tenants/appeal", nil, map[string]any{
+				"tenantId": args[0],
+				"message":  message,
+			}, &resp)
+			if err != nil {
+				return fmt.Errorf("failed to appeal suspension: %w", err)
+			}
+
+			if opts.json {
+				return printClusterJSON(command.OutOrStdout(), resp.Tenant)
+			}
+
+			color.Green("Appealed the suspension of tenant %s, the cluster admins will review it.", resp.Tenant.ID)
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&message, "message", "", "why the suspension should be lifted")
+
+	return command
+}
+
+func clusterTenantsAccessPolicyCommand(opts *clusterOptions) *cobra.Command {
+	var policy enterprise.TenantAccessPolicy
+	var remove bool
+
+	command := &cobra.Command{
+		Use:          "access-policy <tenantId>",
+		Example:      "cluster tenants access-policy tenant_shop --admin-allow 203.0.113.0/24 --block-country KP --max-body-bytes 10485760",
+		Short:        "Replaces the gateway access policy of a tenant",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if remove == !policy.IsEmpty() {
+				return errors.New("set the policy with its flags, or remove it with --clear")
+			}
+
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			body := map[string]any{
+				"tenantId":         args[0],
+				"adminAllowlist":   policy.AdminAllowlist,
+				"blockedCidrs":     policy.BlockedCIDRs,
+				"blockedCountries": policy.BlockedCountries,
+				"maxBodyBytes":     policy.MaxBodyBytes,
+			}
+
+			var resp struct {
+				Tenant *enterprise.Tenant `json:"tenant"`
+			}
+			err = client.call(command.Context(), http.MethodPost, "/api/enterprise/users/tenants/access-policy", nil, body, &resp)
+			if err != nil {
+				return fmt.Errorf("failed to update access policy: %w", err)
+			}
+
+			if opts.json {
+				return printClusterJSON(command.OutOrStdout(), resp.Tenant)
+			}
+
+			if resp.Tenant.AccessPolicy.IsEmpty() {
+				color.Green("Removed the access policy of tenant %s.", resp.Tenant.ID)
+				return nil
+			}
+			color.Green("Updated the access policy of tenant %s:", resp.Tenant.ID)
+			return printClusterJSON(command.OutOrStdout(), resp.Tenant.AccessPolicy)
+		},
+	}
+
+	command.Flags().StringSliceVar(&policy.AdminAllowlist, "admin-allow", nil, "CIDRs or IPs allowed to reach the admin UI and collection API (comma separated or repeated)")
+	command.Flags().StringSliceVar(&policy.BlockedCIDRs, "block-cidr", nil, "CIDRs or IPs refused for every path")
+	command.Flags().StringSliceVar(&policy.BlockedCountries, "block-country", nil, "two-letter country codes refused for every path")
+	command.Flags().Int64Var(&policy.MaxBodyBytes, "max-body-bytes", 0, "largest request body accepted (default no limit)")
+	command.Flags().BoolVar(&remove, "clear", false, "remove the access policy")
+
+	return command
+}
+
+// clusterTTLHours converts a --ttl flag to the whole hours the API expects
+func clusterTTLHours(ttl time.Duration) int {
+	return int((ttl + time.Hour - 1) / time.Hour)
+}
+
+// clusterSSOResponse is the tenant admin SSO response of the control plane
+type clusterSSOResponse struct {
+	SSOToken  string `json:"ssoToken"`
+	SSOURL    string `json:"ssoUrl"`
+	TenantURL string `json:"tenantUrl"`
+	ExpiresIn int    `json:"expiresIn"`
+}
+
+func clusterSSOCommand(opts *clusterOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:          "sso <tenantId>",
+		Example:      "cluster sso tenant_pr-123",
+		Short:        "Generates a single use token to log in to a tenant as superuser",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			var resp clusterSSOResponse
+			err = client.call(command.Context(), http.MethodPost, "/api/enterprise/users/tenants/sso", nil, map[string]string{
+				"tenantId": args[0],
+			}, &resp)
+			if err != nil {
+				return fmt.Errorf("failed to generate SSO token: %w", err)
+			}
+
+			if opts.json {
+				return printClusterJSON(command.OutOrStdout(), resp)
+			}
+
+			out := command.OutOrStdout()
+			fmt.Fprintf(out, "Dashboard login:  %s\n", resp.TenantURL)
+			fmt.Fprintf(out, "SSO URL:          %s\n", resp.SSOURL)
+			fmt.Fprintf(out, "SSO token:        %s\n", resp.SSOToken)
+			fmt.Fprintf(out, "\nPOST {\"token\": \"<SSO token>\"} to the SSO URL within %s for a superuser auth token.\n", time.Duration(resp.ExpiresIn)*time.Second)
+			return nil
+		},
+	}
+
+	return command
+}
+
+func clusterExportCommand(opts *clusterOptions) *cobra.Command {
+	var output string
+	var keepBackup bool
+
+	command := &cobra.Command{
+		Use:          "export <tenantId>",
+		Example:      "cluster export tenant_pr-123 -o pr-123.zip",
+		Short:        "Downloads a backup of a tenant (its data and files)",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			name := fmt.Sprintf("export_%s.zip", time.Now().UTC().Format("20060102150405"))
+			if output == "" {
+				output = args[0] + "_" + name
+			}
+
+			if err := exportClusterTenant(command, client, args[0], name, output, keepBackup); err != nil {
+				return fmt.Errorf("failed to export tenant %s: %w", args[0], err)
+			}
+
+			color.Green("Successfully exported tenant %s to %s!", args[0], output)
+			return nil
+		},
+	}
+
+	command.Flags().StringVarP(&output, "output", "o", "", "the file to save the export to (default <tenantId>_export_<time>.zip)")
+	command.Flags().BoolVar(&keepBackup, "keep-backup", false, "keep the export in the tenant backups")
+
+	return command
+}
+
+// exportClusterTenant logs in to the tenant with a SSO token, creates a
+// backup with the tenant's backup API and downloads it to output
+func exportClusterTenant(command *cobra.Command, client *clusterClient, tenantID, name, output string, keepBackup bool) error {
+	ctx := command.Context()
+
+	var sso clusterSSOResponse
+	if err := client.call(ctx, http.MethodPost, "/api/enterprise/users/tenants/sso", nil, map[string]string{"tenantId": tenantID}, &sso); err != nil {
+		return err
+	}
+	tenantURL := strings.TrimSuffix(sso.SSOURL, tenant_node.SSOPath)
+
+	var superuser struct {
+		Token string `json:"token"`
+	}
+	if err := client.send(ctx, http.MethodPost, sso.SSOURL, "", nil, map[string]string{"token": sso.SSOToken}, &superuser); err != nil {
+		return err
+	}
+
+	if err := client.send(ctx, http.MethodPost, tenantURL+"/api/backups", superuser.Token, nil, map[string]string{"name": name}, nil); err != nil {
+		return err
+	}
+
+	if !keepBackup {
+		defer func() {
+			err := client.send(ctx, http.MethodDelete, tenantURL+"/api/backups/"+name, superuser.Token, nil, nil, nil)
+			if err != nil {
+				color.Yellow("Failed to delete the tenant backup %s: %v", name, err)
+			}
+		}()
+	}
+
+	// backup downloads are authorized with a file token
+	var fileToken struct {
+		Token string `json:"token"`
+	}
+	if err := client.send(ctx, http.MethodPost, tenantURL+"/api/files/token", superuser.Token, nil, nil, &fileToken); err != nil {
+		return err
+	}
+
+	resp, err := client.do(ctx, http.MethodGet, tenantURL+"/api/backups/"+name, "", url.Values{"token": {fileToken.Token}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(output)
+		return err
+	}
+
+	return file.Close()
+}
+
+func clusterLogsCommand(opts *clusterOptions) *cobra.Command {
+	var level string
+	var since string
+	var until string
+	var search string
+	var limit int
+
+	command := &cobra.Command{
+		Use:          "logs <tenantId>",
+		Example:      "cluster logs tenant_pr-123 --level warn --since 1h",
+		Short:        "Prints the cluster log entries of a tenant",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			query := url.Values{"tenantId": {args[0]}}
+			if level != "" {
+				query.Set("level", level)
+			}
+			if search != "" {
+				query.Set("search", search)
+			}
+			if limit > 0 {
+				query.Set("limit", strconv.Itoa(limit))
+			}
+			for name, value := range map[string]string{"since": since, "until": until} {
+				if value == "" {
+					continue
+				}
+				t, err := parseLogTime(value)
+				if err != nil {
+					return fmt.Errorf("invalid --%s: %w", name, err)
+				}
+				query.Set(name, t.Format(time.RFC3339))
+			}
+
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			var resp struct {
+				Entries []*enterprise.LogEntry `json:"entries"`
+				Count   int                    `json:"count"`
+			}
+			if err := client.call(command.Context(), http.MethodGet, "/api/enterprise/users/tenants/logs", query, nil, &resp); err != nil {
+				return err
+			}
+
+			if opts.json {
+				return printClusterJSON(command.OutOrStdout(), resp)
+			}
+
+			// the newest entries are returned first, print them like a log file
+			slices.Reverse(resp.Entries)
+			out := command.OutOrStdout()
+			for _, entry := range resp.Entries {
+				fmt.Fprintf(out, "%s %-5s [%s] %s\n", entry.Time.Format(time.RFC3339), slog.Level(entry.Level), entry.Component, entry.Message)
+			}
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&level, "level", "", "the minimum level (debug, info, warn or error)")
+	command.Flags().StringVar(&since, "since", "", "only entries since a RFC 3339 time or a duration ago (e.g. 1h)")
+	command.Flags().StringVar(&until, "until", "", "only entries before a RFC 3339 time or a duration ago")
+	command.Flags().StringVar(&search, "search", "", "only entries with this text in their message")
+	command.Flags().IntVar(&limit, "limit", 0, "the max number of entries (default 200)")
+
+	return command
+}
+
+// parseLogTime parses a RFC 3339 time or a duration before now
+func parseLogTime(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func clusterKeysCommand(opts *clusterOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage your API keys (requires a password login)",
+	}
+
+	command.AddCommand(clusterKeysListCommand(opts))
+	command.AddCommand(clusterKeysCreateCommand(opts))
+	command.AddCommand(clusterKeysRevokeCommand(opts))
+
+	return command
+}
+
+// clusterAPIKey is an API key as listed by the control plane
+type clusterAPIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hint      string     `json:"hint"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Created   time.Time  `json:"created"`
+}
+
+func clusterKeysListCommand(opts *clusterOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:          "list",
+		Short:        "Lists your API keys",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			var resp struct {
+				APIKeys []*clusterAPIKey `json:"apiKeys"`
+				Total   int              `json:"total"`
+			}
+			if err := client.call(command.Context(), http.MethodGet, "/api/enterprise/users/api-keys", nil, nil, &resp); err != nil {
+				return err
+			}
+
+			if opts.json {
+				return printClusterJSON(command.OutOrStdout(), resp)
+			}
+
+			w := tabwriter.NewWriter(command.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tKEY\tSCOPES\tEXPIRES")
+			for _, key := range resp.APIKeys {
+				expires := "never"
+				if key.ExpiresAt != nil {
+					expires = key.ExpiresAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s...\t%s\t%s\n", key.ID, key.Name, key.Hint, strings.Join(key.Scopes, ","), expires)
+			}
+			return w.Flush()
+		},
+	}
+
+	return command
+}
+
+func clusterKeysCreateCommand(opts *clusterOptions) *cobra.Command {
+	var scopes []string
+	var expiresIn int
+
+	command := &cobra.Command{
+		Use:          "create <name>",
+		Example:      "cluster keys create ci-previews --scope tenants:read,tenants:write --expires-in 90",
+		Short:        "Creates an API key, printed only once",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			var resp struct {
+				APIKey *clusterAPIKey `json:"apiKey"`
+				Key    string         `json:"key"`
+			}
+			err = client.call(command.Context(), http.MethodPost, "/api/enterprise/users/api-keys", nil, map[string]any{
+				"name":      args[0],
+				"scopes":    scopes,
+				"expiresIn": expiresIn,
+			}, &resp)
+			if err != nil {
+				return fmt.Errorf("failed to create API key: %w", err)
+			}
+
+			if opts.json {
+				return printClusterJSON(command.OutOrStdout(), resp)
+			}
+
+			color.Green("Successfully created API key %s (%s)! Store it now, it can't be shown again:", resp.APIKey.Name, resp.APIKey.ID)
+			fmt.Fprintln(command.OutOrStdout(), resp.Key)
+			return nil
+		},
+	}
+
+	command.Flags().StringSliceVar(&scopes, "scope", nil, "the scopes of the key: "+strings.Join(enterprise.APIKeyScopes, ", "))
+	command.Flags().IntVar(&expiresIn, "expires-in", 0, "the lifetime of the key in days (default never expires)")
+
+	return command
+}
+
+func clusterKeysRevokeCommand(opts *clusterOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:          "revoke <keyId>",
+		Short:        "Revokes an API key",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			client, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			query := url.Values{"keyId": {args[0]}}
+			if err := client.call(command.Context(), http.MethodDelete, "/api/enterprise/users/api-keys", query, nil, nil); err != nil {
+				return fmt.Errorf("failed to revoke API key: %w", err)
+			}
+
+			color.Green("Successfully revoked API key %s!", args[0])
+			return nil
+		},
+	}
+
+	return command
+}
+
+// printClusterJSON prints an API response for scripts
+func printClusterJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Environment variables read by the cluster commands, for CI pipelines that
+// don't keep a saved login
+const (
+	clusterURLEnv      = "POCKETBASE_CLUSTER_URL"
+	clusterTokenEnv    = "POCKETBASE_CLUSTER_TOKEN" // Login token or API key
+	clusterPasswordEnv = "POCKETBASE_CLUSTER_PASSWORD"
+)
+
+// clusterCredentials is the login saved by `cluster login`
+type clusterCredentials struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+	Email string `json:"email,omitempty"`
+}
+
+// defaultClusterCredentialsPath returns where `cluster login` saves the login
+func defaultClusterCredentialsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".pocketbase_cluster.json"
+	}
+	return filepath.Join(dir, "pocketbase", "cluster.json")
+}
+
+// loadClusterCredentials reads a saved login, returning empty credentials if
+// there is none
+func loadClusterCredentials(path string) (*clusterCredentials, error) {
+	creds := &clusterCredentials{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return creds, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, creds); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+	return creds, nil
+}
+
+// saveClusterCredentials saves a login, readable by the current user only
+func saveClusterCredentials(path string, creds *clusterCredentials) error {
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// clusterOptions are the flags shared by the cluster commands
+type clusterOptions struct {
+	url         string
+	token       string
+	credentials string
+	json        bool
+}
+
+// resolve returns the control plane URL and token, from the flags, the
+// environment or the saved login, in that order
+func (o *clusterOptions) resolve() (string, string, error) {
+	baseURL := firstNonEmpty(o.url, os.Getenv(clusterURLEnv))
+	token := firstNonEmpty(o.token, os.Getenv(clusterTokenEnv))
+
+	if baseURL == "" || token == "" {
+		creds, err := loadClusterCredentials(o.credentials)
+		if err != nil {
+			return "", "", err
+		}
+		baseURL = firstNonEmpty(baseURL, creds.URL)
+		token = firstNonEmpty(token, creds.Token)
+	}
+
+	return strings.TrimRight(baseURL, "/"), token, nil
+}
+
+// client returns an authenticated control plane client
+func (o *clusterOptions) client() (*clusterClient, error) {
+	baseURL, token, err := o.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	if baseURL == "" {
+		return nil, fmt.Errorf("missing control plane URL, set --url or %s, or run `cluster login`", clusterURLEnv)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("not logged in, run `cluster login` or set --token or %s", clusterTokenEnv)
+	}
+
+	return newClusterClient(baseURL, token), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clusterClient calls the cluster user API of a control plane
+type clusterClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+func newClusterClient(baseURL, token string) *clusterClient {
+	return &clusterClient{
+		baseURL:    baseURL,
+		token:      token,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// call sends a JSON request to the control plane and decodes the JSON
+// response into out, if set
+func (c *clusterClient) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	return c.send(ctx, method, c.baseURL+path, c.token, query, body, out)
+}
+
+// send sends a JSON request to rawURL, which can also be a tenant URL
+func (c *clusterClient) send(ctx context.Context, method, rawURL, token string, query url.Values, body, out any) error {
+	resp, err := c.do(ctx, method, rawURL, token, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+// do sends a request and returns the response, or an error with the
+// server's message for error statuses
+func (c *clusterClient) do(ctx context.Context, method, rawURL, token string, query url.Values, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+
+	return resp, nil
+}
+
+// responseError reads the message of an error response, sent as plain text
+// by the cluster API and as JSON by the tenant (PocketBase) API
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	message := strings.TrimSpace(string(data))
+
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+		message = apiErr.Message
+	}
+
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+
+	return fmt.Errorf("%s %s: %s (%d)", resp.Request.Method, resp.Request.URL.Path, message, resp.StatusCode)
+}
@@ -0,0 +1,418 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/cmd"
+)
+
+// fakeControlPlane records the requests of the cluster commands
+type fakeControlPlane struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakeControlPlane) record(r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+}
+
+func (f *fakeControlPlane) has(request string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.requests {
+		if r == request {
+			return true
+		}
+	}
+	return false
+}
+
+func runClusterCommand(t *testing.T, args ...string) (string, error) {
+	command := cmd.NewClusterCommand()
+	command.SetArgs(args)
+
+	var out bytes.Buffer
+	command.SetOut(&out)
+	command.SetErr(&out)
+
+	err := command.Execute()
+	return out.String(), err
+}
+
+func TestClusterLoginAndTenants(t *testing.T) {
+	t.Parallel()
+
+	cp := &fakeControlPlane{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cp.record(r)
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/enterprise/users/login":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["email"] != "test@example.com" || body["password"] != "secret" {
+				http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"token": "jwt"})
+		case "GET /api/enterprise/users/tenants":
+			json.NewEncoder(w).Encode(map[string]any{
+				"tenants": []map[string]any{{"id": "tenant_pr-1", "domain": "pr-1.example.com", "status": "active"}},
+				"total":   1,
+			})
+		case "POST /api/enterprise/users/tenants":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]any{
+				"tenant": map[string]any{"id": "tenant_" + body["id"], "domain": body["domain"]},
+			})
+		case "DELETE /api/enterprise/users/tenants":
+			if r.URL.Query().Get("tenantId") != "tenant_pr-1" {
+				http.Error(w, "Tenant not found", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"success": true})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	credentials := filepath.Join(t.TempDir(), "cluster.json")
+
+	if _, err := runClusterCommand(t, "tenants", "list", "--credentials", credentials); err == nil || !strings.Contains(err.Error(), "missing control plane URL") {
+		t.Fatalf("Expected an error without login, got %v", err)
+	}
+
+	if _, err := runClusterCommand(t, "login", "--url", server.URL, "--credentials", credentials, "--email", "test@example.com", "--password", "invalid"); err == nil || !strings.Contains(err.Error(), "Invalid credentials (401)") {
+		t.Fatalf("Expected the login to fail, got %v", err)
+	}
+
+	if _, err := runClusterCommand(t, "login", "--url", server.URL, "--credentials", credentials, "--email", "test@example.com", "--password", "secret"); err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	info, err := os.Stat(credentials)
+	if err != nil {
+		t.Fatalf("Expected the login to be saved: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the credentials file to be private, got %v", info.Mode().Perm())
+	}
+
+	out, err := runClusterCommand(t, "tenants", "list", "--credentials", credentials)
+	if err != nil {
+		t.Fatalf("Failed to list tenants: %v", err)
+	}
+	if !strings.Contains(out, "tenant_pr-1") || !strings.Contains(out, "pr-1.example.com") {
+		t.Errorf("Expected the tenant in the list, got %q", out)
+	}
+	if !cp.has("GET /api/enterprise/users/tenants Bearer jwt") {
+		t.Errorf("Expected the saved token to be sent, got %v", cp.requests)
+	}
+
+	out, err = runClusterCommand(t, "tenants", "create", "pr-2", "--domain", "pr-2.example.com", "--json", "--credentials", credentials)
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	if !strings.Contains(out, `"id": "tenant_pr-2"`) {
+		t.Errorf("Expected the created tenant as JSON, got %q", out)
+	}
+
+	if _, err := runClusterCommand(t, "tenants", "delete", "tenant_missing", "--credentials", credentials); err == nil {
+		t.Error("Expected deleting a missing tenant to fail")
+	}
+	if _, err := runClusterCommand(t, "tenants", "delete", "tenant_pr-1", "--credentials", credentials); err != nil {
+		t.Errorf("Failed to delete tenant: %v", err)
+	}
+
+	// an explicit token (e.g. an API key in CI) wins over the saved login
+	if _, err := runClusterCommand(t, "tenants", "list", "--credentials", credentials, "--token", "pbk_ci"); err != nil {
+		t.Fatalf("Failed to list tenants: %v", err)
+	}
+	if !cp.has("GET /api/enterprise/users/tenants Bearer pbk_ci") {
+		t.Errorf("Expected the API key to be sent, got %v", cp.requests)
+	}
+
+	if _, err := runClusterCommand(t, "logout", "--credentials", credentials); err != nil {
+		t.Fatalf("Failed to log out: %v", err)
+	}
+	if _, err := os.Stat(credentials); !os.IsNotExist(err) {
+		t.Errorf("Expected the saved login to be removed, got %v", err)
+	}
+}
+
+func TestClusterPreviewTenants(t *testing.T) {
+	t.Parallel()
+
+	var bodies []map[string]any
+	expiresAt := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/enterprise/users/tenants":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]any{
+				"tenant": map[string]any{"id": "tenant_" + body["id"].(string), "domain": body["domain"], "preview": true, "expiresAt": expiresAt},
+			})
+		case "POST /api/enterprise/users/tenants/extend":
+			json.NewEncoder(w).Encode(map[string]any{
+				"tenant": map[string]any{"id": body["tenantId"], "expiresAt": expiresAt},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	out, err := runClusterCommand(t, "tenants", "create", "pr-1", "--domain", "pr-1.example.com", "--preview", "--ttl", "90m", "--url", server.URL, "--token", "pbk_ci")
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	if bodies[0]["preview"] != true || bodies[0]["ttlHours"] != float64(2) {
+		t.Errorf("Expected a preview tenant with a TTL rounded up to 2 hours, got %v", bodies[0])
+	}
+	if !strings.Contains(out, expiresAt.Format(time.RFC3339)) {
+		t.Errorf("Expected the expiry to be printed, got %q", out)
+	}
+
+	if _, err := runClusterCommand(t, "tenants", "extend", "tenant_pr-1", "--url", server.URL, "--token", "pbk_ci"); err == nil || !strings.Contains(err.Error(), "missing --ttl") {
+		t.Fatalf("Expected the extension to require --ttl, got %v", err)
+	}
+
+	if _, err := runClusterCommand(t, "tenants", "extend", "tenant_pr-1", "--ttl", "72h", "--url", server.URL, "--token", "pbk_ci"); err != nil {
+		t.Fatalf("Failed to extend tenant: %v", err)
+	}
+	if last := bodies[len(bodies)-1]; last["tenantId"] != "tenant_pr-1" || last["extendHours"] != float64(72) {
+		t.Errorf("Unexpected extension request %v", last)
+	}
+}
+
+func TestClusterSuspendedTenants(t *testing.T) {
+	t.Parallel()
+
+	var bodies []map[string]any
+	suspendedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/enterprise/users/tenants":
+			json.NewEncoder(w).Encode(map[string]any{
+				"tenants": []map[string]any{
+					{"id": "tenant_main", "domain": "main.example.com", "status": "active"},
+					{"id": "tenant_shop", "domain": "shop.example.com", "status": "active", "suspension": map[string]any{
+						"reason": "non_payment", "message": "Invoice 42 is overdue", "suspendedAt": suspendedAt,
+					}},
+				},
+				"total":     2,
+				"appealUrl": "https://example.com/appeal",
+			})
+		case "POST /api/enterprise/users/tenants/appeal":
+			json.NewEncoder(w).Encode(map[string]any{
+				"tenant": map[string]any{"id": body["tenantId"]},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	out, err := runClusterCommand(t, "tenants", "list", "--url", server.URL, "--token", "pbk_ci")
+	if err != nil {
+		t.Fatalf("Failed to list tenants: %v", err)
+	}
+	for _, expected := range []string{"suspended", "Invoice 42 is overdue", "tenants appeal tenant_shop", "https://example.com/appeal"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in the output, got %q", expected, out)
+		}
+	}
+	if strings.Contains(out, "Tenant tenant_main is suspended") {
+		t.Errorf("Expected only tenant_shop to be suspended, got %q", out)
+	}
+
+	if _, err := runClusterCommand(t, "tenants", "appeal", "tenant_shop", "--url", server.URL, "--token", "pbk_ci"); err == nil || !strings.Contains(err.Error(), "missing --message") {
+		t.Fatalf("Expected the appeal to require --message, got %v", err)
+	}
+
+	if _, err := runClusterCommand(t, "tenants", "appeal", "tenant_shop", "--message", "Paid today", "--url", server.URL, "--token", "pbk_ci"); err != nil {
+		t.Fatalf("Failed to appeal: %v", err)
+	}
+	if last := bodies[len(bodies)-1]; last["tenantId"] != "tenant_shop" || last["message"] != "Paid today" {
+		t.Errorf("Unexpected appeal request %v", last)
+	}
+}
+
+func TestClusterTenantsAccessPolicy(t *testing.T) {
+	t.Parallel()
+
+	var bodies []map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		tenant := map[string]any{"id": body["tenantId"]}
+		if body["maxBodyBytes"] != float64(0) {
+			tenant["accessPolicy"] = map[string]any{"blockedCountries": body["blockedCountries"], "maxBodyBytes": body["maxBodyBytes"]}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"tenant": tenant})
+	}))
+	defer server.Close()
+
+	if _, err := runClusterCommand(t, "tenants", "access-policy", "tenant_shop", "--url", server.URL, "--token", "pbk_ci"); err == nil {
+		t.Fatal("Expected an empty policy without --clear to be refused")
+	}
+
+	out, err := runClusterCommand(t, "tenants", "access-policy", "tenant_shop", "--block-country", "KP,IR", "--max-body-bytes", "1024", "--url", server.URL, "--token", "pbk_ci")
+	if err != nil {
+		t.Fatalf("Failed to update the access policy: %v", err)
+	}
+	if countries, _ := bodies[0]["blockedCountries"].([]any); len(countries) != 2 || bodies[0]["maxBodyBytes"] != float64(1024) {
+		t.Errorf("Unexpected policy request %v", bodies[0])
+	}
+	if !strings.Contains(out, `"maxBodyBytes": 1024`) {
+		t.Errorf("Expected the policy to be printed, got %q", out)
+	}
+
+	if _, err := runClusterCommand(t, "tenants", "access-policy", "tenant_shop", "--clear", "--url", server.URL, "--token", "pbk_ci"); err != nil {
+		t.Fatalf("Failed to remove the access policy: %v", err)
+	}
+	if last := bodies[len(bodies)-1]; last["blockedCountries"] != nil || last["maxBodyBytes"] != float64(0) {
+		t.Errorf("Expected an empty policy, got %v", last)
+	}
+}
+
+func TestClusterExport(t *testing.T) {
+	t.Parallel()
+
+	cp := &fakeControlPlane{}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cp.record(r)
+
+		switch {
+		case r.URL.Path == "/api/enterprise/users/tenants/sso":
+			json.NewEncoder(w).Encode(map[string]any{
+				"ssoToken":  "sso",
+				"ssoUrl":    server.URL + "/api/enterprise/sso",
+				"tenantUrl": server.URL + "/api/enterprise/sso#token=sso",
+				"expiresIn": 3600,
+			})
+		case r.URL.Path == "/api/enterprise/sso":
+			json.NewEncoder(w).Encode(map[string]any{"token": "superuser"})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/backups":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/api/files/token":
+			json.NewEncoder(w).Encode(map[string]any{"token": "file"})
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/backups/export_"):
+			if r.URL.Query().Get("token") != "file" {
+				http.Error(w, `{"message":"Missing file token."}`, http.StatusForbidden)
+				return
+			}
+			w.Write([]byte("zip"))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/backups/export_"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "export.zip")
+
+	if _, err := runClusterCommand(t, "export", "tenant_pr-1", "-o", output, "--url", server.URL, "--token", "pbk_ci"); err != nil {
+		t.Fatalf("Failed to export tenant: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil || string(data) != "zip" {
+		t.Fatalf("Expected the backup to be downloaded, got %q (%v)", data, err)
+	}
+
+	for _, expected := range []string{
+		"POST /api/enterprise/users/tenants/sso Bearer pbk_ci",
+		"POST /api/enterprise/sso ",
+		"POST /api/backups Bearer superuser",
+		"POST /api/files/token Bearer superuser",
+	} {
+		if !cp.has(expected) {
+			t.Errorf("Expected request %q, got %v", expected, cp.requests)
+		}
+	}
+
+	deleted := false
+	for _, r := range cp.requests {
+		deleted = deleted || strings.HasPrefix(r, "DELETE /api/backups/export_")
+	}
+	if !deleted {
+		t.Errorf("Expected the tenant backup to be deleted after the download, got %v", cp.requests)
+	}
+}
+
+func TestClusterLogs(t *testing.T) {
+	t.Parallel()
+
+	var query map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/enterprise/users/tenants/logs" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query()
+
+		now := time.Now()
+		json.NewEncoder(w).Encode(map[string]any{
+			"entries": []map[string]any{
+				{"time": now, "level": 8, "message": "newest", "component": "tenant-node"},
+				{"time": now.Add(-time.Minute), "level": 4, "message": "oldest", "component": "gateway"},
+			},
+			"count": 2,
+		})
+	}))
+	defer server.Close()
+
+	out, err := runClusterCommand(t, "logs", "tenant_pr-1", "--level", "warn", "--since", "1h", "--url", server.URL, "--token", "pbk_ci")
+	if err != nil {
+		t.Fatalf("Failed to list logs: %v", err)
+	}
+
+	if query["tenantId"][0] != "tenant_pr-1" || query["level"][0] != "warn" {
+		t.Errorf("Unexpected query %v", query)
+	}
+	since, err := time.Parse(time.RFC3339, query["since"][0])
+	if err != nil || time.Since(since) < 59*time.Minute || time.Since(since) > 61*time.Minute {
+		t.Errorf("Expected since to be an hour ago, got %v (%v)", query["since"], err)
+	}
+
+	oldest := strings.Index(out, "WARN  [gateway] oldest")
+	newest := strings.Index(out, "ERROR [tenant-node] newest")
+	if oldest < 0 || newest < oldest {
+		t.Errorf("Expected the entries oldest first, got %q", out)
+	}
+
+	if _, err := runClusterCommand(t, "logs", "tenant_pr-1", "--since", "yesterday", "--url", server.URL, "--token", "pbk_ci"); err == nil {
+		t.Error("Expected an invalid --since to fail")
+	}
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// enterpriseFlags holds the enterprise mode command line flags. Flags that are
+// set explicitly override the config file and the environment.
+type enterpriseFlags struct {
+	mode                   string
+	nodeID                 string
+	nodeAddress            string
+	raftPeers              []string
+	raftBindAddr           string
+	controlPlaneAddrs      []string
+	region                 string
+	maxTenants             int
+	s3Endpoint             string
+	s3Region               string
+	s3Bucket               string
+	s3AccessKeyID          string
+	s3SecretAccessKey      string
+	gatewayCacheMB         int
+	gatewayCacheDir        string
+	gatewayPeerAddr        string
+	masterKeyFile          string
+	previousMasterKeyFiles []string
+	auditRetention         string
+}
+
+// apply copies the explicitly set flags into config
+func (f *enterpriseFlags) apply(config *enterprise.ClusterConfig, changed func(name string) bool) {
+	if changed("mode") {
+		config.Mode = enterprise.Mode(f.mode)
+	}
+	if changed("node-id") {
+		config.NodeID = f.nodeID
+	}
+	if changed("node-addr") {
+		config.NodeAddress = f.nodeAddress
+	}
+	if changed("raft-peers") {
+		config.RaftPeers = f.raftPeers
+	}
+	if changed("raft-bind") {
+		config.RaftBindAddr = f.raftBindAddr
+	}
+	if changed("control-plane") {
+		config.ControlPlaneAddrs = f.controlPlaneAddrs
+		config.GatewayControlPlaneAddrs = f.controlPlaneAddrs
+	}
+	if changed("region") {
+		config.Region = f.region
+	}
+	if changed("max-tenants") {
+		config.MaxTenants = f.maxTenants
+	}
+	if changed("s3-endpoint") {
+		config.S3Endpoint = f.s3Endpoint
+	}
+	if changed("s3-region") {
+		config.S3Region = f.s3Region
+	}
+	if changed("s3-bucket") {
+		config.S3Bucket = f.s3Bucket
+	}
+	if changed("s3-access-key") {
+		config.S3AccessKeyID = f.s3AccessKeyID
+	}
+	if changed("s3-secret-key") {
+		config.S3SecretAccessKey = f.s3SecretAccessKey
+	}
+	if changed("gateway-cache-mb") {
+		config.GatewayCacheMemoryMB = f.gatewayCacheMB
+	}
+	if changed("gateway-cache-dir") {
+		config.GatewayCacheDir = f.gatewayCacheDir
+	}
+	if changed("gateway-peer-addr") {
+		config.GatewayPeerAddr = f.gatewayPeerAddr
+	}
+	if changed("master-key-file") {
+		config.MasterKeyFile = f.masterKeyFile
+	}
+	if changed("previous-master-key-files") {
+		config.PreviousMasterKeyFiles = f.previousMasterKeyFiles
+	}
+	if changed("audit-retention") {
+		config.AuditRetention = f.auditRetention
+	}
+}
+
+// loadEnterpriseConfig builds the enterprise config from, in increasing order of
+// precedence: the defaults, the config file, POCKETBASE_* env vars and explicit flags
+func loadEnterpriseConfig(configFile string, flags *enterpriseFlags, changed func(name string) bool, dataDir string) (*enterprise.ClusterConfig, error) {
+	config := enterprise.DefaultClusterConfig()
+
+	if configFile != "" {
+		var err error
+		if config, err = enterprise.LoadClusterConfig(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	flags.apply(config, changed)
+
+	// Standard AWS credential env vars
+	if config.S3AccessKeyID == "" {
+		config.S3AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if config.S3SecretAccessKey == "" {
+		config.S3SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+
+	if config.DataDir == "" {
+		config.DataDir = dataDir
+	}
+	if len(config.GatewayControlPlaneAddrs) == 0 {
+		config.GatewayControlPlaneAddrs = config.ControlPlaneAddrs
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// configReloader re-reads the enterprise config on SIGHUP and hands the
+// reloadable settings to the running services
+type configReloader struct {
+	load     func() (*enterprise.ClusterConfig, error)
+	current  enterprise.ClusterConfig
+	appliers []func(*enterprise.ClusterConfig)
+	mu       sync.Mutex
+}
+
+func newConfigReloader(load func() (*enterprise.ClusterConfig, error), config *enterprise.ClusterConfig) *configReloader {
+	return &configReloader{
+		load:    load,
+		current: *config,
+	}
+}
+
+// onReload registers a service to receive reloaded configs
+func (r *configReloader) onReload(apply func(*enterprise.ClusterConfig)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.appliers = append(r.appliers, apply)
+}
+
+// watch reloads the config on every SIGHUP
+func (r *configReloader) watch() {
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	go func() {
+		for range hupChan {
+			r.reload()
+		}
+	}()
+}
+
+// reload applies the reloadable settings of the current config file. An
+// invalid file is rejected as a whole and the running config is kept.
+func (r *configReloader) reload() {
+	next, err := r.load()
+	if err != nil {
+		enterprise.Logger().Error("config reload failed, keeping the current config", "error", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reloadable, restart := r.current.ReloadChanges(next)
+	if len(restart) > 0 {
+		enterprise.Logger().Warn("config changes take effect after a restart", "fields", strings.Join(restart, ", "))
+	}
+	if len(reloadable) == 0 {
+		enterprise.Logger().Info("config reloaded, no runtime settings changed")
+		return
+	}
+
+	if err := enterprise.ConfigureLogging(next); err != nil {
+		enterprise.Logger().Error("invalid logging settings", "error", err)
+	}
+
+	r.current.ApplyReloadable(next)
+	for _, apply := range r.appliers {
+		apply(next)
+	}
+
+	enterprise.Logger().Info("config reloaded", "fields", strings.Join(reloadable, ", "))
+}
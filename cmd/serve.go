@@ -4,11 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/pocketbase/pocketbase/apis"
 	enterpriseapis "github.com/pocketbase/pocketbase/apis/enterprise"
@@ -29,18 +29,8 @@ func NewServeCommand(app core.App, showStartBanner bool) *cobra.Command {
 	var httpsAddr string
 
 	// Enterprise mode flags
-	var mode string
-	var nodeID string
-	var nodeAddress string
-	var raftPeers []string
-	var raftBindAddr string
-	var controlPlaneAddrs []string
-	var maxTenants int
-	var s3Endpoint string
-	var s3Region string
-	var s3Bucket string
-	var s3AccessKeyID string
-	var s3SecretAccessKey string
+	var configFile string
+	flags := &enterpriseFlags{}
 
 	command := &cobra.Command{
 		Use:          "serve [domain(s)]",
@@ -49,9 +39,8 @@ func NewServeCommand(app core.App, showStartBanner bool) *cobra.Command {
 		SilenceUsage: true,
 		RunE: func(command *cobra.Command, args []string) error {
 			// Check if running in enterprise mode
-			if mode != "" && mode != "standard" {
-				return runEnterpriseMode(mode, nodeID, nodeAddress, raftPeers, raftBindAddr, controlPlaneAddrs, maxTenants,
-					s3Endpoint, s3Region, s3Bucket, s3AccessKeyID, s3SecretAccessKey, app)
+			if (flags.mode != "" && flags.mode != "standard") || configFile != "" {
+				return runEnterpriseMode(command, configFile, flags, app)
 			}
 
 			// Standard PocketBase mode (existing behavior)
@@ -108,168 +97,206 @@ func NewServeCommand(app core.App, showStartBanner bool) *cobra.Command {
 
 	// Enterprise mode flags
 	command.PersistentFlags().StringVar(
-		&mode,
+		&configFile,
+		"config",
+		"",
+		"YAML or JSON enterprise config file, overridden by POCKETBASE_* env vars and explicit flags (reloaded on SIGHUP)",
+	)
+
+	command.PersistentFlags().StringVar(
+		&flags.mode,
 		"mode",
 		"",
 		"Enterprise mode: control-plane, tenant-node, gateway, all-in-one (leave empty for standard mode)",
 	)
 
 	command.PersistentFlags().StringVar(
-		&nodeID,
+		&flags.nodeID,
 		"node-id",
 		"",
 		"Node ID for control-plane mode (e.g., cp-1)",
 	)
 
 	command.PersistentFlags().StringVar(
-		&nodeAddress,
+		&flags.nodeAddress,
 		"node-addr",
 		"",
 		"This node's advertised address for tenant-node mode (e.g., node1.internal:8091, defaults to localhost:8091)",
 	)
 
 	command.PersistentFlags().StringSliceVar(
-		&raftPeers,
+		&flags.raftPeers,
 		"raft-peers",
 		[]string{},
 		"Raft peer addresses for control-plane mode (e.g., cp-1:7000,cp-2:7000,cp-3:7000)",
 	)
 
 	command.PersistentFlags().StringVar(
-		&raftBindAddr,
+		&flags.raftBindAddr,
 		"raft-bind",
 		"127.0.0.1:7000",
 		"Raft bind address for control-plane mode",
 	)
 
 	command.PersistentFlags().StringSliceVar(
-		&controlPlaneAddrs,
+		&flags.controlPlaneAddrs,
 		"control-plane",
 		[]string{},
 		"Control plane addresses for tenant-node/gateway modes (e.g., cp-1:8090,cp-2:8090)",
 	)
 
+	command.PersistentFlags().StringVar(
+		&flags.region,
+		"region",
+		"",
+		"Region of this node; the default residency of new tenants on the control plane (e.g., eu-west)",
+	)
+
 	command.PersistentFlags().IntVar(
-		&maxTenants,
+		&flags.maxTenants,
 		"max-tenants",
 		200,
 		"Maximum number of tenants for tenant-node mode",
 	)
 
 	command.PersistentFlags().StringVar(
-		&s3Endpoint,
+		&flags.s3Endpoint,
 		"s3-endpoint",
 		"",
 		"S3 endpoint URL (leave empty for AWS S3)",
 	)
 
 	command.PersistentFlags().StringVar(
-		&s3Region,
+		&flags.s3Region,
 		"s3-region",
 		"us-east-1",
 		"S3 region",
 	)
 
 	command.PersistentFlags().StringVar(
-		&s3Bucket,
+		&flags.s3Bucket,
 		"s3-bucket",
 		"",
 		"S3 bucket name for tenant data",
 	)
 
 	command.PersistentFlags().StringVar(
-		&s3AccessKeyID,
+		&flags.s3AccessKeyID,
 		"s3-access-key",
 		"",
 		"S3 access key ID (or set AWS_ACCESS_KEY_ID env var)",
 	)
 
 	command.PersistentFlags().StringVar(
-		&s3SecretAccessKey,
+		&flags.s3SecretAccessKey,
 		"s3-secret-key",
 		"",
 		"S3 secret access key (or set AWS_SECRET_ACCESS_KEY env var)",
 	)
 
+	command.PersistentFlags().IntVar(
+		&flags.gatewayCacheMB,
+		"gateway-cache-mb",
+		256,
+		"In-memory response cache size in MB for gateway mode",
+	)
+
+	command.PersistentFlags().StringVar(
+		&flags.gatewayCacheDir,
+		"gateway-cache-dir",
+		"",
+		"Directory for the on-disk response cache tier in gateway mode, used through a pb-response-cache subdirectory (leave empty for memory only)",
+	)
+
+	command.PersistentFlags().StringVar(
+		&flags.gatewayPeerAddr,
+		"gateway-peer-addr",
+		"",
+		"Address (host:port) other gateway replicas use to share quota state (leave empty to enforce quotas per replica)",
+	)
+
+	command.PersistentFlags().StringVar(
+		&flags.masterKeyFile,
+		"master-key-file",
+		"",
+		"age identity file wrapping tenant data keys in control-plane mode, generated if missing (leave empty to store tenant data unencrypted)",
+	)
+
+	command.PersistentFlags().StringSliceVar(
+		&flags.previousMasterKeyFiles,
+		"previous-master-key-files",
+		[]string{},
+		"Previous master key files still needed to unwrap tenant data keys during a master key rotation",
+	)
+
+	command.PersistentFlags().StringVar(
+		&flags.auditRetention,
+		"audit-retention",
+		"8760h",
+		"How long the control plane keeps audit log entries",
+	)
+
 	return command
 }
 
 // runEnterpriseMode starts PocketBase in enterprise mode
-func runEnterpriseMode(mode, nodeID, nodeAddress string, raftPeers []string, raftBindAddr string,
-	controlPlaneAddrs []string, maxTenants int, s3Endpoint, s3Region, s3Bucket,
-	s3AccessKeyID, s3SecretAccessKey string, app core.App) error {
-
-	log.Printf("Starting PocketBase Enterprise in %s mode", mode)
+func runEnterpriseMode(command *cobra.Command, configFile string, flags *enterpriseFlags, app core.App) error {
+	load := func() (*enterprise.ClusterConfig, error) {
+		return loadEnterpriseConfig(configFile, flags, command.Flags().Changed, app.DataDir())
+	}
 
-	// Parse mode
-	enterpriseMode, err := enterprise.ParseMode(mode)
+	config, err := load()
 	if err != nil {
-		return fmt.Errorf("invalid mode: %w", err)
+		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Get S3 credentials from env if not provided
-	if s3AccessKeyID == "" {
-		s3AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
-	}
-	if s3SecretAccessKey == "" {
-		s3SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if err := enterprise.ConfigureLogging(config); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Get JWT secret from environment variable
-	jwtSecret := os.Getenv("POCKETBASE_JWT_SECRET")
-
-	// Build enterprise config
-	config := &enterprise.ClusterConfig{
-		Mode:         enterpriseMode,
-		NodeID:       nodeID,
-		RaftPeers:    raftPeers,
-		RaftBindAddr: raftBindAddr,
-		DataDir:      app.DataDir(),
+	enterprise.Logger().Info("Starting PocketBase Enterprise", "mode", config.Mode, enterprise.LogKeyNodeID, config.NodeID)
 
-		ControlPlaneAddrs:        controlPlaneAddrs,
-		MaxTenants:              maxTenants,
-		NodeAddress:             nodeAddress,
-		GatewayControlPlaneAddrs: controlPlaneAddrs,
-
-		S3Endpoint:        s3Endpoint,
-		S3Region:          s3Region,
-		S3Bucket:          s3Bucket,
-		S3AccessKeyID:     s3AccessKeyID,
-		S3SecretAccessKey: s3SecretAccessKey,
-
-		LitestreamEnabled:   true,
-		LitestreamRetention: "72h",
-
-		JWTSecret: jwtSecret,
+	shutdownTracing, err := enterprise.StartTracing(config.Tracing, "pocketbase-"+string(config.Mode))
+	if err != nil {
+		return fmt.Errorf("failed to start tracing: %w", err)
 	}
+	defer func() {
+		// Flush the spans still buffered by the exporter
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			enterprise.Logger().Error("Failed to flush traces", "error", err)
+		}
+	}()
 
-	// Validate config based on mode
-	if err := validateEnterpriseConfig(config); err != nil {
-		return fmt.Errorf("invalid configuration: %w", err)
+	// Reloadable settings are re-read on SIGHUP when running from a config file
+	reloader := newConfigReloader(load, config)
+	if configFile != "" {
+		reloader.watch()
 	}
 
 	// Start the appropriate service based on mode
-	switch enterpriseMode {
+	switch config.Mode {
 	case enterprise.ModeControlPlane:
-		return runControlPlane(config)
+		return runControlPlane(config, reloader)
 
 	case enterprise.ModeTenantNode:
-		return runTenantNode(config)
+		return runTenantNode(config, reloader)
 
 	case enterprise.ModeGateway:
-		return runGateway(config)
+		return runGateway(config, reloader)
 
 	case enterprise.ModeAllInOne:
-		return runAllInOne(config)
+		return runAllInOne(config, reloader)
 
 	default:
-		return fmt.Errorf("unknown mode: %s", mode)
+		return fmt.Errorf("unknown mode: %s", config.Mode)
 	}
 }
 
 // runControlPlane starts the control plane service
-func runControlPlane(config *enterprise.ClusterConfig) error {
-	log.Printf("[ControlPlane] Starting control plane node: %s", config.NodeID)
+func runControlPlane(config *enterprise.ClusterConfig, reloader *configReloader) error {
+	logger := enterprise.ComponentLogger(enterprise.LogComponentControlPlane)
 
 	// Create control plane
 	cp, err := control_plane.NewControlPlane(config)
@@ -281,9 +308,13 @@ func runControlPlane(config *enterprise.ClusterConfig) error {
 	if err := cp.Start(); err != nil {
 		return fmt.Errorf("failed to start control plane: %w", err)
 	}
+	reloader.onReload(cp.ApplyConfig)
+
+	stopLogShipping := enterprise.StartLogShipping(config.NodeID, cp.LogStoreWriter())
+	defer stopLogShipping()
 
 	// Create and start HTTP API server
-	router, err := enterpriseapis.NewRouter(cp, config.JWTSecret)
+	router, err := enterpriseapis.NewRouter(cp, config.JWTSecret, config.APITrustedProxies)
 	if err != nil {
 		return fmt.Errorf("failed to create router: %w", err)
 	}
@@ -295,34 +326,35 @@ func runControlPlane(config *enterprise.ClusterConfig) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	log.Printf("[ControlPlane] Control plane running.")
-	log.Printf("[ControlPlane] - IPC server: :8090")
-	log.Printf("[ControlPlane] - HTTP API: :8095")
-	log.Printf("[ControlPlane] Press Ctrl+C to stop.")
+	logger.Info("Control plane running, press Ctrl+C to stop", "ipcAddr", config.IPCBindAddr, "httpAddr", ":8095")
 	<-sigChan
 
-	log.Printf("[ControlPlane] Shutting down...")
+	logger.Info("Shutting down")
 	return cp.Stop()
 }
 
+// tenantNodeDrainTimeout bounds how long a SIGTERM waits for tenants to be handed back
+const tenantNodeDrainTimeout = 5 * time.Minute
+
 // runTenantNode starts the tenant node service
-func runTenantNode(config *enterprise.ClusterConfig) error {
-	log.Printf("[TenantNode] Starting tenant node")
+func runTenantNode(config *enterprise.ClusterConfig, reloader *configReloader) error {
+	logger := enterprise.ComponentLogger(enterprise.LogComponentTenantNode)
 
 	ctx := context.Background()
 
-	// Create S3 storage backend
-	s3Backend, err := storage.NewS3Backend(ctx, config.S3Endpoint, config.S3Region, config.S3Bucket, config.S3AccessKeyID, config.S3SecretAccessKey)
+	// Create S3 storage backend for the node's region
+	s3Backend, err := newRegionS3Backend(ctx, config)
 	if err != nil {
 		return fmt.Errorf("failed to create S3 backend: %w", err)
 	}
 
 	// Create control plane client
-	cpClient, err := tenant_node.NewControlPlaneClient(config.ControlPlaneAddrs)
+	cpClient, err := tenant_node.NewControlPlaneClient(config.ControlPlaneAddrs, config.CircuitBreaker)
 	if err != nil {
 		return fmt.Errorf("failed to create control plane client: %w", err)
 	}
 	defer cpClient.Close()
+	cpClient.SetIPCSecret(config.IPCSecret)
 
 	// Create tenant manager
 	manager, err := tenant_node.NewManager(config, s3Backend, cpClient)
@@ -330,10 +362,14 @@ func runTenantNode(config *enterprise.ClusterConfig) error {
 		return fmt.Errorf("failed to create tenant manager: %w", err)
 	}
 
+	stopLogShipping := enterprise.StartLogShipping(manager.GetNodeID(), cpClient.AppendLogs)
+	defer stopLogShipping()
+
 	// Start tenant manager
 	if err := manager.Start(); err != nil {
 		return fmt.Errorf("failed to start tenant manager: %w", err)
 	}
+	reloader.onReload(manager.ApplyConfig)
 
 	// Create and start HTTP server to handle tenant requests
 	httpServer := tenant_node.NewHTTPServer(manager)
@@ -341,7 +377,6 @@ func runTenantNode(config *enterprise.ClusterConfig) error {
 	// Start HTTP server in background
 	errChan := make(chan error, 1)
 	go func() {
-		log.Printf("[TenantNode] Starting HTTP server on :8091")
 		errChan <- httpServer.Start(":8091")
 	}()
 
@@ -349,40 +384,67 @@ func runTenantNode(config *enterprise.ClusterConfig) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	log.Printf("[TenantNode] Tenant node running on :8091. Press Ctrl+C to stop.")
+	logger.Info("Tenant node running, press Ctrl+C to stop", "addr", ":8091")
 
 	select {
 	case err := <-errChan:
 		return fmt.Errorf("HTTP server error: %w", err)
-	case <-sigChan:
-		log.Printf("[TenantNode] Shutting down...")
+	case <-manager.Drained():
+		// Drained through the admin API
+		logger.Info("Node drained, shutting down")
+	case sig := <-sigChan:
+		// SIGTERM (e.g. a rolling deploy) hands the tenants to other nodes first;
+		// a second signal skips the rest of the drain
+		if sig == syscall.SIGTERM {
+			logger.Info("Draining before shutdown (send the signal again to stop right away)")
+
+			drainCtx, cancel := context.WithTimeout(ctx, tenantNodeDrainTimeout)
+			go func() {
+				select {
+				case <-sigChan:
+					cancel()
+				case <-drainCtx.Done():
+				}
+			}()
 
-		// Stop HTTP server first
-		if err := httpServer.Stop(); err != nil {
-			log.Printf("[TenantNode] Error stopping HTTP server: %v", err)
+			if err := manager.Drain(drainCtx); err != nil {
+				logger.Warn("Drain did not finish", "error", err)
+			}
+			cancel()
 		}
 
-		// Stop tenant manager
-		return manager.Stop()
+		logger.Info("Shutting down")
+	}
+
+	// Stop HTTP server first
+	if err := httpServer.Stop(); err != nil {
+		logger.Error("Error stopping HTTP server", "error", err)
 	}
+
+	// Stop tenant manager
+	return manager.Stop()
 }
 
 // runGateway starts the gateway service
-func runGateway(config *enterprise.ClusterConfig) error {
-	log.Printf("[Gateway] Starting gateway")
+func runGateway(config *enterprise.ClusterConfig, reloader *configReloader) error {
+	logger := enterprise.ComponentLogger(enterprise.LogComponentGateway)
 
 	// Create control plane client
-	cpClient, err := tenant_node.NewControlPlaneClient(config.GatewayControlPlaneAddrs)
+	cpClient, err := tenant_node.NewControlPlaneClient(config.GatewayControlPlaneAddrs, config.CircuitBreaker)
 	if err != nil {
 		return fmt.Errorf("failed to create control plane client: %w", err)
 	}
 	defer cpClient.Close()
 
+	stopLogShipping := enterprise.StartLogShipping(gatewayNodeID(config), cpClient.AppendLogs)
+	defer stopLogShipping()
+
 	// Create gateway
 	gw, err := gateway.NewGateway(config, cpClient)
 	if err != nil {
 		return fmt.Errorf("failed to create gateway: %w", err)
 	}
+	reloader.onReload(gw.ApplyConfig)
 
 	// Start gateway in background
 	errChan := make(chan error, 1)
@@ -394,20 +456,40 @@ func runGateway(config *enterprise.ClusterConfig) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	log.Printf("[Gateway] Gateway running on :8080. Press Ctrl+C to stop.")
+	logger.Info("Gateway running, press Ctrl+C to stop", "addr", ":8080")
 
 	select {
 	case err := <-errChan:
 		return fmt.Errorf("gateway error: %w", err)
 	case <-sigChan:
-		log.Printf("[Gateway] Shutting down...")
+		logger.Info("Shutting down")
 		return gw.Stop()
 	}
 }
 
+// gatewayNodeID names a gateway in the log store. Gateways don't register with
+// the control plane, so this is the node ID if set, else the host name.
+func gatewayNodeID(config *enterprise.ClusterConfig) string {
+	if config.NodeID != "" {
+		return config.NodeID
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return "gateway-" + hostname
+	}
+	return "gateway"
+}
+
+// newRegionS3Backend creates the S3 backend of the bucket of the node's region
+func newRegionS3Backend(ctx context.Context, config *enterprise.ClusterConfig) (*storage.S3Backend, error) {
+	regionStorage, _ := config.RegionStorage(config.Region)
+	target := regionStorage.S3
+
+	return storage.NewS3Backend(ctx, target.Endpoint, target.Region, target.Bucket, target.AccessKeyID, target.SecretAccessKey)
+}
+
 // runAllInOne starts all services in a single process
-func runAllInOne(config *enterprise.ClusterConfig) error {
-	log.Printf("[AllInOne] Starting all-in-one mode")
+func runAllInOne(config *enterprise.ClusterConfig, reloader *configReloader) error {
+	logger := enterprise.Logger()
 
 	ctx := context.Background()
 
@@ -421,19 +503,25 @@ func runAllInOne(config *enterprise.ClusterConfig) error {
 		return fmt.Errorf("failed to start control plane: %w", err)
 	}
 	defer cp.Stop()
+	reloader.onReload(cp.ApplyConfig)
+
+	// Shipped straight to the local log store, stopped before the control plane
+	stopLogShipping := enterprise.StartLogShipping(config.NodeID, cp.LogStoreWriter())
+	defer stopLogShipping()
 
 	// 2. Start tenant node
-	s3Backend, err := storage.NewS3Backend(ctx, config.S3Endpoint, config.S3Region, config.S3Bucket, config.S3AccessKeyID, config.S3SecretAccessKey)
+	s3Backend, err := newRegionS3Backend(ctx, config)
 	if err != nil {
 		return fmt.Errorf("failed to create S3 backend: %w", err)
 	}
 
 	// Use localhost for control plane in all-in-one mode
-	cpClient, err := tenant_node.NewControlPlaneClient([]string{"localhost:8090"})
+	cpClient, err := tenant_node.NewControlPlaneClient([]string{"localhost:8090"}, config.CircuitBreaker)
 	if err != nil {
 		return fmt.Errorf("failed to create control plane client: %w", err)
 	}
 	defer cpClient.Close()
+	cpClient.SetIPCSecret(config.IPCSecret)
 
 	manager, err := tenant_node.NewManager(config, s3Backend, cpClient)
 	if err != nil {
@@ -444,13 +532,13 @@ func runAllInOne(config *enterprise.ClusterConfig) error {
 		return fmt.Errorf("failed to start tenant manager: %w", err)
 	}
 	defer manager.Stop()
+	reloader.onReload(manager.ApplyConfig)
 
 	// Start tenant node HTTP server
 	tenantHTTPServer := tenant_node.NewHTTPServer(manager)
 	go func() {
-		log.Printf("[AllInOne] Starting tenant node HTTP server on :8091")
 		if err := tenantHTTPServer.Start(":8091"); err != nil {
-			log.Printf("[AllInOne] Tenant HTTP server error: %v", err)
+			logger.Error("Tenant HTTP server error", "error", err)
 		}
 	}()
 	defer tenantHTTPServer.Stop()
@@ -460,6 +548,7 @@ func runAllInOne(config *enterprise.ClusterConfig) error {
 	if err != nil {
 		return fmt.Errorf("failed to create gateway: %w", err)
 	}
+	reloader.onReload(gw.ApplyConfig)
 
 	errChan := make(chan error, 1)
 	go func() {
@@ -470,50 +559,14 @@ func runAllInOne(config *enterprise.ClusterConfig) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	log.Printf("[AllInOne] All services running. Gateway on :8080. Press Ctrl+C to stop.")
+	logger.Info("All services running, press Ctrl+C to stop", "gatewayAddr", ":8080")
 
 	select {
 	case err := <-errChan:
 		return fmt.Errorf("gateway error: %w", err)
 	case <-sigChan:
-		log.Printf("[AllInOne] Shutting down...")
+		logger.Info("Shutting down")
 		gw.Stop()
 		return nil
 	}
 }
-
-// validateEnterpriseConfig validates the enterprise configuration
-func validateEnterpriseConfig(config *enterprise.ClusterConfig) error {
-	switch config.Mode {
-	case enterprise.ModeControlPlane:
-		if config.NodeID == "" {
-			return fmt.Errorf("--node-id required for control-plane mode")
-		}
-		if len(config.RaftPeers) == 0 {
-			return fmt.Errorf("--raft-peers required for control-plane mode")
-		}
-		if config.S3Bucket == "" {
-			return fmt.Errorf("--s3-bucket required")
-		}
-
-	case enterprise.ModeTenantNode:
-		if len(config.ControlPlaneAddrs) == 0 {
-			return fmt.Errorf("--control-plane required for tenant-node mode")
-		}
-		if config.S3Bucket == "" {
-			return fmt.Errorf("--s3-bucket required")
-		}
-
-	case enterprise.ModeGateway:
-		if len(config.GatewayControlPlaneAddrs) == 0 {
-			return fmt.Errorf("--control-plane required for gateway mode")
-		}
-
-	case enterprise.ModeAllInOne:
-		if config.S3Bucket == "" {
-			return fmt.Errorf("--s3-bucket required")
-		}
-	}
-
-	return nil
-}
@@ -59,6 +59,7 @@ type BaseAppConfig struct {
 	DBConnect        DBConnectFunc
 	DataDir          string
 	EncryptionEnv    string
+	EncryptionKey    string // Used instead of the EncryptionEnv value if set
 	QueryTimeout     time.Duration
 	DataMaxOpenConns int
 	DataMaxIdleConns int
@@ -587,6 +588,12 @@ func (app *BaseApp) EncryptionEnv() string {
 	return app.config.EncryptionEnv
 }
 
+// EncryptionKey returns the app secret set in the config, if any. It takes
+// precedence over the EncryptionEnv value, e.g. for several apps in one process.
+func (app *BaseApp) EncryptionKey() string {
+	return app.config.EncryptionKey
+}
+
 // IsDev returns whether the app is in dev mode.
 //
 // When enabled logs, executed sql statements, etc. are printed to the stderr.
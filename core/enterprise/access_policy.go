@@ -0,0 +1,89 @@
+package enterprise
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// TenantAccessPolicy restricts who can reach a tenant through the gateway.
+// Requests are checked against it before they are proxied to a node.
+type TenantAccessPolicy struct {
+	AdminAllowlist   []string `json:"adminAllowlist,omitempty"`   // CIDRs or IPs allowed to reach the admin UI and collection API (everyone if empty)
+	BlockedCIDRs     []string `json:"blockedCidrs,omitempty"`     // CIDRs or IPs refused for every path
+	BlockedCountries []string `json:"blockedCountries,omitempty"` // ISO 3166-1 alpha-2 codes, from the gateway's country header
+	MaxBodyBytes     int64    `json:"maxBodyBytes,omitempty"`     // Request body limit (none if zero)
+}
+
+// IsEmpty reports whether the policy allows every request
+func (p *TenantAccessPolicy) IsEmpty() bool {
+	return p == nil || (len(p.AdminAllowlist) == 0 && len(p.BlockedCIDRs) == 0 && len(p.BlockedCountries) == 0 && p.MaxBodyBytes == 0)
+}
+
+// Normalize checks the policy and canonicalizes its entries: prefixes are
+// masked and country codes upper-cased
+func (p *TenantAccessPolicy) Normalize() error {
+	var err error
+	if p.AdminAllowlist, err = normalizePrefixes(p.AdminAllowlist); err != nil {
+		return fmt.Errorf("%w: adminAllowlist: %v", ErrInvalidAccessPolicy, err)
+	}
+	if p.BlockedCIDRs, err = normalizePrefixes(p.BlockedCIDRs); err != nil {
+		return fmt.Errorf("%w: blockedCidrs: %v", ErrInvalidAccessPolicy, err)
+	}
+
+	for i, code := range p.BlockedCountries {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+			return fmt.Errorf("%w: blockedCountries: %q is not a two-letter country code", ErrInvalidAccessPolicy, p.BlockedCountries[i])
+		}
+		p.BlockedCountries[i] = code
+	}
+
+	if p.MaxBodyBytes < 0 {
+		return fmt.Errorf("%w: maxBodyBytes must not be negative", ErrInvalidAccessPolicy)
+	}
+	return nil
+}
+
+// normalizePrefixes parses and masks a list of CIDRs or IPs
+func normalizePrefixes(entries []string) ([]string, error) {
+	prefixes, err := ParsePrefixes(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		normalized[i] = prefix.String()
+	}
+	return normalized, nil
+}
+
+// ParsePrefixes parses CIDRs, where a bare IP is a single address prefix
+func ParsePrefixes(entries []string) ([]netip.Prefix, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP %q", entry)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,35 @@
+package enterprise
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTenantAccessPolicyNormalize(t *testing.T) {
+	policy := &TenantAccessPolicy{
+		AdminAllowlist:   []string{"10.1.2.3/8", " 192.168.1.7 ", "2001:db8::1/32"},
+		BlockedCountries: []string{"ru", "KP"},
+	}
+	if err := policy.Normalize(); err != nil {
+		t.Fatalf("expected a valid policy, got %v", err)
+	}
+
+	if expected := []string{"10.0.0.0/8", "192.168.1.7/32", "2001:db8::/32"}; !reflect.DeepEqual(policy.AdminAllowlist, expected) {
+		t.Errorf("expected %v, got %v", expected, policy.AdminAllowlist)
+	}
+	if expected := []string{"RU", "KP"}; !reflect.DeepEqual(policy.BlockedCountries, expected) {
+		t.Errorf("expected %v, got %v", expected, policy.BlockedCountries)
+	}
+
+	for _, invalid := range []*TenantAccessPolicy{
+		{AdminAllowlist: []string{"10.0.0.0/33"}},
+		{BlockedCIDRs: []string{"example.com"}},
+		{BlockedCountries: []string{"USA"}},
+		{MaxBodyBytes: -1},
+	} {
+		if err := invalid.Normalize(); !errors.Is(err, ErrInvalidAccessPolicy) {
+			t.Errorf("expected %+v to be rejected, got %v", invalid, err)
+		}
+	}
+}
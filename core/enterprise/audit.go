@@ -0,0 +1,96 @@
+package enterprise
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Audited actions
+const (
+	AuditActionUserImpersonate      = "user.impersonate"
+	AuditActionUserQuotaUpdate      = "user.quota_update"
+	AuditActionTenantCreate         = "tenant.create"
+	AuditActionTenantArchive        = "tenant.archive"
+	AuditActionTenantRestore        = "tenant.restore"
+	AuditActionTenantDelete         = "tenant.delete"
+	AuditActionTenantSSO            = "tenant.sso"
+	AuditActionTenantCacheUpdate    = "tenant.cache_update"
+	AuditActionTenantKeyRotate      = "tenant.key_rotate"
+	AuditActionTenantMove           = "tenant.move"
+	AuditActionTenantExtend         = "tenant.extend"
+	AuditActionTenantExpire         = "tenant.expire"
+	AuditActionTenantSuspend        = "tenant.suspend"
+	AuditActionTenantUnsuspend      = "tenant.unsuspend"
+	AuditActionTenantAppeal         = "tenant.appeal"
+	AuditActionTenantAccessPolicy   = "tenant.access_policy_update"
+	AuditActionKeysRewrap           = "keys.rewrap"
+	AuditActionNodeDrain            = "node.drain"
+	AuditActionMigrationCreate      = "migration.create"
+	AuditActionMigrationUpdate      = "migration.update"
+	AuditActionEmailTemplateSave    = "email_template.save"
+	AuditActionEmailTemplateDelete  = "email_template.delete"
+	AuditActionUserEmailStatusClear = "user.email_status_clear"
+	AuditActionAPIKeyCreate         = "api_key.create"
+	AuditActionAPIKeyRevoke         = "api_key.revoke"
+)
+
+// Matches reports whether an entry passes the filter (Limit is ignored)
+func (f *AuditFilter) Matches(entry *AuditEntry) bool {
+	if f.Actor != "" && entry.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action {
+		return false
+	}
+	if f.Target != "" && entry.Target != f.Target {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// AuditDiff returns the top-level JSON fields that differ between before and after.
+// Either side may be nil, e.g. for created or deleted objects.
+func AuditDiff(before, after interface{}) map[string]*AuditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	changes := make(map[string]*AuditChange)
+	for name, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[name]) {
+			changes[name] = &AuditChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, exists := beforeFields[name]; !exists {
+			changes[name] = &AuditChange{After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// auditFields flattens a value into its top-level JSON fields
+func auditFields(v interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if v == nil {
+		return fields
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return map[string]interface{}{"value": v}
+	}
+	return fields
+}
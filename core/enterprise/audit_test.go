@@ -0,0 +1,65 @@
+package enterprise
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuditDiff(t *testing.T) {
+	before := &ClusterUser{ID: "user-1", PasswordHash: "hash", MaxTenants: 5}
+	after := *before
+	after.MaxTenants = 10
+
+	changes := AuditDiff(before, &after)
+	if len(changes) != 1 {
+		t.Fatalf("expected only maxTenants to change, got %v", changes)
+	}
+
+	change := changes["maxTenants"]
+	if change == nil || change.Before != float64(5) || change.After != float64(10) {
+		t.Errorf("expected maxTenants 5 -> 10, got %+v", change)
+	}
+
+	if AuditDiff(before, before) != nil {
+		t.Error("expected no changes for identical values")
+	}
+
+	// Created objects only have after values
+	created := AuditDiff(nil, &Tenant{ID: "tenant-1"})
+	if created["id"] == nil || created["id"].Before != nil || created["id"].After != "tenant-1" {
+		t.Errorf("expected id to be created, got %+v", created["id"])
+	}
+}
+
+func TestAuditFilterMatches(t *testing.T) {
+	now := time.Now()
+	entry := &AuditEntry{
+		Time:   now,
+		Actor:  "admin:ops",
+		Action: AuditActionTenantArchive,
+		Target: "tenant-1",
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   bool
+	}{
+		{"empty", AuditFilter{}, true},
+		{"actor", AuditFilter{Actor: "admin:ops"}, true},
+		{"other actor", AuditFilter{Actor: "user:user-1"}, false},
+		{"action and target", AuditFilter{Action: AuditActionTenantArchive, Target: "tenant-1"}, true},
+		{"other target", AuditFilter{Target: "tenant-2"}, false},
+		{"since", AuditFilter{Since: now}, true},
+		{"after since", AuditFilter{Since: now.Add(time.Second)}, false},
+		{"until is exclusive", AuditFilter{Until: now}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(entry); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
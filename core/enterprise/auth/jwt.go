@@ -4,7 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
-	"log"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -28,9 +28,10 @@ func NewJWTManager(secretKey string) (*JWTManager, error) {
 			return nil, fmt.Errorf("failed to generate JWT secret: %w", err)
 		}
 		secretKey = base64.StdEncoding.EncodeToString(randomKey)
-		log.Printf("[JWT] WARNING: No JWT secret provided, generated random key")
-		log.Printf("[JWT] For production, set POCKETBASE_JWT_SECRET environment variable or jwtSecret in config")
-		log.Printf("[JWT] Random keys are NOT persistent across restarts - user sessions will be invalidated!")
+		logger := enterprise.ComponentLogger(enterprise.LogComponentAuth)
+		logger.Warn("No JWT secret provided, generated random key")
+		logger.Warn("For production, set POCKETBASE_JWT_SECRET environment variable or jwtSecret in config")
+		logger.Warn("Random keys are NOT persistent across restarts - user sessions will be invalidated!")
 	}
 
 	return &JWTManager{
@@ -44,9 +45,20 @@ type ClusterUserClaims struct {
 	Email    string `json:"email"`
 	Name     string `json:"name"`
 	Verified bool   `json:"verified"`
+
+	// Set when the request is authenticated with an API key instead of a login token
+	APIKeyID string   `json:"apiKeyId,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
+// HasScope reports whether the claims grant an API key scope. Login tokens
+// grant every scope.
+func (c *ClusterUserClaims) HasScope(scope string) bool {
+	return c.APIKeyID == "" || slices.Contains(c.Scopes, scope)
+}
+
 // TenantAdminClaims represents JWT claims for SSO to tenant admin
 type TenantAdminClaims struct {
 	UserID   string `json:"userId"`
@@ -55,6 +67,9 @@ type TenantAdminClaims struct {
 	jwt.RegisteredClaims
 }
 
+// UserTokenIssuer is the issuer of cluster user login tokens
+const UserTokenIssuer = "pocketbase-enterprise"
+
 // GenerateUserToken generates a JWT token for a cluster user
 func (j *JWTManager) GenerateUserToken(user *enterprise.ClusterUser, expirationHours int) (string, error) {
 	if expirationHours <= 0 {
@@ -69,7 +84,7 @@ func (j *JWTManager) GenerateUserToken(user *enterprise.ClusterUser, expirationH
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expirationHours) * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "pocketbase-enterprise",
+			Issuer:    UserTokenIssuer,
 			Subject:   user.ID,
 		},
 	}
@@ -78,14 +93,15 @@ func (j *JWTManager) GenerateUserToken(user *enterprise.ClusterUser, expirationH
 	return token.SignedString([]byte(j.secretKey))
 }
 
-// ValidateUserToken validates a cluster user JWT token
+// ValidateUserToken validates a cluster user JWT token. Tenant admin SSO
+// tokens are signed with the same key and are rejected by their issuer.
 func (j *JWTManager) ValidateUserToken(tokenString string) (*ClusterUserClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &ClusterUserClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(j.secretKey), nil
-	})
+	}, jwt.WithIssuer(UserTokenIssuer))
 
 	if err != nil {
 		return nil, err
@@ -98,16 +114,21 @@ func (j *JWTManager) ValidateUserToken(tokenString string) (*ClusterUserClaims,
 	return nil, fmt.Errorf("invalid token")
 }
 
-// GenerateTenantAdminToken generates a SSO token for accessing tenant admin
+// TenantAdminTokenIssuer is the issuer of tenant admin SSO tokens
+const TenantAdminTokenIssuer = "pocketbase-enterprise-sso"
+
+// GenerateTenantAdminToken generates a SSO token for accessing tenant admin.
+// The token ID lets the tenant node accept each token only once.
 func (j *JWTManager) GenerateTenantAdminToken(user *enterprise.ClusterUser, tenantID string) (string, error) {
 	claims := TenantAdminClaims{
 		UserID:   user.ID,
 		TenantID: tenantID,
 		Email:    user.Email,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        enterprise.GenerateID("sso"),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)), // 1 hour SSO token
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "pocketbase-enterprise-sso",
+			Issuer:    TenantAdminTokenIssuer,
 			Subject:   user.ID,
 		},
 	}
@@ -116,14 +137,15 @@ func (j *JWTManager) GenerateTenantAdminToken(user *enterprise.ClusterUser, tena
 	return token.SignedString([]byte(j.secretKey))
 }
 
-// ValidateTenantAdminToken validates a tenant admin SSO token
+// ValidateTenantAdminToken validates a tenant admin SSO token. Cluster user
+// tokens are signed with the same key and are rejected by their issuer.
 func (j *JWTManager) ValidateTenantAdminToken(tokenString string) (*TenantAdminClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &TenantAdminClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(j.secretKey), nil
-	})
+	}, jwt.WithIssuer(TenantAdminTokenIssuer), jwt.WithExpirationRequired())
 
 	if err != nil {
 		return nil, err
@@ -202,6 +202,48 @@ func TestGenerateTenantAdminToken(t *testing.T) {
 	if claims.TenantID != "tenant_456" {
 		t.Errorf("expected tenantID tenant_456, got %s", claims.TenantID)
 	}
+
+	if claims.ID == "" {
+		t.Error("expected a token ID for single use")
+	}
+}
+
+func TestValidateTenantAdminTokenRejectsUserToken(t *testing.T) {
+	manager, _ := NewJWTManager("test-secret-key-32-bytes-long!!")
+
+	user := &enterprise.ClusterUser{
+		ID:       "user_123",
+		Email:    "test@example.com",
+		Verified: true,
+	}
+
+	token, err := manager.GenerateUserToken(user, 1)
+	if err != nil {
+		t.Fatalf("failed to generate user token: %v", err)
+	}
+
+	if _, err := manager.ValidateTenantAdminToken(token); err == nil {
+		t.Error("user token should not validate as a tenant admin token")
+	}
+}
+
+func TestValidateUserTokenRejectsTenantAdminToken(t *testing.T) {
+	manager, _ := NewJWTManager("test-secret-key-32-bytes-long!!")
+
+	user := &enterprise.ClusterUser{
+		ID:       "user_123",
+		Email:    "test@example.com",
+		Verified: true,
+	}
+
+	token, err := manager.GenerateTenantAdminToken(user, "tenant_456")
+	if err != nil {
+		t.Fatalf("failed to generate tenant admin token: %v", err)
+	}
+
+	if _, err := manager.ValidateUserToken(token); err == nil {
+		t.Error("tenant admin token should not validate as a user token")
+	}
 }
 
 func TestTenantAdminTokenExpiration(t *testing.T) {
@@ -2,12 +2,15 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"net"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
 )
 
 type contextKey string
@@ -15,6 +18,8 @@ type contextKey string
 const (
 	UserClaimsKey  contextKey = "user_claims"
 	AdminTokenKey  contextKey = "admin_token"
+	RequestIDKey   contextKey = "request_id"
+	ClientIPKey    contextKey = "client_ip"
 )
 
 // RateLimiter implements IP-based rate limiting for auth endpoints
@@ -154,37 +159,62 @@ func RateLimitMiddleware(limiter *RateLimiter) func(http.Handler) http.Handler {
 	}
 }
 
-// getClientIP extracts the client IP from the request
+// getClientIP returns the client IP resolved by WithClientIP, or the peer
+// address of requests that didn't go through it
 func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (for proxied requests)
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		// Take the first IP in the chain
-		parts := strings.Split(xff, ",")
-		if len(parts) > 0 {
-			ip := strings.TrimSpace(parts[0])
-			if ip != "" {
-				return ip
-			}
-		}
+	if ip, ok := r.Context().Value(ClientIPKey).(string); ok {
+		return ip
 	}
+	return peerIP(r)
+}
+
+// peerIP returns the address of the peer of a request
+func peerIP(r *http.Request) string {
+	if addr := enterprise.RemoteAddr(r); addr.IsValid() {
+		return addr.String()
+	}
+	return r.RemoteAddr
+}
 
-	// Check X-Real-IP header
-	xri := r.Header.Get("X-Real-IP")
-	if xri != "" {
-		return xri
+// ClientIP returns the client IP of a request, honoring the forwarding headers
+// of trusted proxies only
+func ClientIP(r *http.Request) string {
+	return getClientIP(r)
+}
+
+// WithClientIP resolves the client IP of a request once, for the rate limiter
+// and the audit log. Forwarding headers are only trusted from the proxies.
+func WithClientIP(r *http.Request, proxies enterprise.TrustedProxies) *http.Request {
+	ip := peerIP(r)
+	if addr := proxies.ClientAddr(r); addr.IsValid() {
+		ip = addr.String()
 	}
+	return r.WithContext(context.WithValue(r.Context(), ClientIPKey, ip))
+}
 
-	// Fall back to RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
+// WithRequestID tags a request with the caller's X-Request-ID, or a new one if
+// missing, and echoes it in the response so both sides can refer to it
+func WithRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	requestID := r.Header.Get(enterprise.RequestIDHeader)
+	if requestID == "" || len(requestID) > 128 {
+		b := make([]byte, 8)
+		rand.Read(b)
+		requestID = hex.EncodeToString(b)
 	}
-	return ip
+
+	w.Header().Set(enterprise.RequestIDHeader, requestID)
+	return r.WithContext(context.WithValue(r.Context(), RequestIDKey, requestID))
 }
 
-// RequireUserAuth is middleware that requires cluster user authentication
-func RequireUserAuth(jwtManager *JWTManager) func(http.Handler) http.Handler {
+// GetRequestID retrieves the request ID from context
+func GetRequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(RequestIDKey).(string)
+	return requestID
+}
+
+// RequireUserAuth is middleware that requires cluster user authentication,
+// with a login token or, if validateAPIKey is set, an API key
+func RequireUserAuth(jwtManager *JWTManager, validateAPIKey func(string) (*ClusterUserClaims, error)) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract token from Authorization header
@@ -204,7 +234,13 @@ func RequireUserAuth(jwtManager *JWTManager) func(http.Handler) http.Handler {
 			tokenString := parts[1]
 
 			// Validate token
-			claims, err := jwtManager.ValidateUserToken(tokenString)
+			var claims *ClusterUserClaims
+			var err error
+			if validateAPIKey != nil && strings.HasPrefix(tokenString, enterprise.APIKeyPrefix) {
+				claims, err = validateAPIKey(tokenString)
+			} else {
+				claims, err = jwtManager.ValidateUserToken(tokenString)
+			}
 			if err != nil {
 				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 				return
@@ -223,6 +259,27 @@ func RequireUserAuth(jwtManager *JWTManager) func(http.Handler) http.Handler {
 	}
 }
 
+// RequireScope is middleware that rejects API keys without scope. It runs
+// after RequireUserAuth.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetUserClaims(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !claims.HasScope(scope) {
+				http.Error(w, fmt.Sprintf("API key is missing the %s scope", scope), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RequireAdminAuth is middleware that requires cluster admin authentication
 func RequireAdminAuth(validateAdminToken func(string) bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -181,12 +181,17 @@ func TestRateLimitMiddleware(t *testing.T) {
 }
 
 func TestGetClientIP(t *testing.T) {
+	proxies, err := enterprise.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("failed to parse proxies: %v", err)
+	}
+
 	tests := []struct {
-		name       string
-		remoteAddr string
+		name          string
+		remoteAddr    string
 		xForwardedFor string
-		xRealIP    string
-		expected   string
+		xRealIP       string
+		expected      string
 	}{
 		{
 			name:       "RemoteAddr only",
@@ -194,29 +199,28 @@ func TestGetClientIP(t *testing.T) {
 			expected:   "192.168.1.1",
 		},
 		{
-			name:          "X-Forwarded-For single",
+			name:          "X-Forwarded-For from a trusted proxy",
 			remoteAddr:    "10.0.0.1:12345",
 			xForwardedFor: "192.168.1.1",
 			expected:      "192.168.1.1",
 		},
 		{
-			name:          "X-Forwarded-For chain",
+			name:          "X-Forwarded-For chain read from the right",
 			remoteAddr:    "10.0.0.1:12345",
-			xForwardedFor: "192.168.1.1, 10.0.0.2, 10.0.0.3",
-			expected:      "192.168.1.1",
+			xForwardedFor: "192.168.1.1, 203.0.113.9, 10.0.0.3",
+			expected:      "203.0.113.9",
 		},
 		{
-			name:       "X-Real-IP",
-			remoteAddr: "10.0.0.1:12345",
-			xRealIP:    "192.168.1.1",
-			expected:   "192.168.1.1",
+			name:          "X-Forwarded-For from an untrusted peer",
+			remoteAddr:    "203.0.113.7:12345",
+			xForwardedFor: "192.168.1.1",
+			expected:      "203.0.113.7",
 		},
 		{
-			name:          "X-Forwarded-For takes precedence over X-Real-IP",
-			remoteAddr:    "10.0.0.1:12345",
-			xForwardedFor: "192.168.1.1",
-			xRealIP:       "192.168.1.2",
-			expected:      "192.168.1.1",
+			name:       "X-Real-IP is ignored",
+			remoteAddr: "10.0.0.1:12345",
+			xRealIP:    "192.168.1.1",
+			expected:   "10.0.0.1",
 		},
 		{
 			name:       "RemoteAddr without port",
@@ -236,12 +240,20 @@ func TestGetClientIP(t *testing.T) {
 				req.Header.Set("X-Real-IP", tt.xRealIP)
 			}
 
-			ip := getClientIP(req)
+			ip := getClientIP(WithClientIP(req, proxies))
 			if ip != tt.expected {
 				t.Errorf("expected %s, got %s", tt.expected, ip)
 			}
 		})
 	}
+
+	// requests that weren't resolved only get their peer address
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Header.Set("X-Forwarded-For", "192.168.1.1")
+	if ip := ClientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected the peer address, got %s", ip)
+	}
 }
 
 func TestRequireUserAuthMiddleware(t *testing.T) {
@@ -262,7 +274,7 @@ func TestRequireUserAuthMiddleware(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	wrapped := RequireUserAuth(jwtManager)(handler)
+	wrapped := RequireUserAuth(jwtManager, nil)(handler)
 
 	// Test without token
 	t.Run("no token", func(t *testing.T) {
@@ -336,6 +348,77 @@ func TestRequireUserAuthMiddleware(t *testing.T) {
 	})
 }
 
+func TestRequireUserAuthWithAPIKey(t *testing.T) {
+	jwtManager, err := NewJWTManager("test-secret-key-32-bytes-long!!")
+	if err != nil {
+		t.Fatalf("failed to create JWT manager: %v", err)
+	}
+
+	validKey := enterprise.APIKeyPrefix + "valid"
+	validateAPIKey := func(key string) (*ClusterUserClaims, error) {
+		if key != validKey {
+			return nil, enterprise.ErrInvalidToken
+		}
+		return &ClusterUserClaims{
+			UserID:   "user_123",
+			Verified: true,
+			APIKeyID: "apikey_1",
+			Scopes:   []string{enterprise.APIKeyScopeTenantsRead},
+		}, nil
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	readOnly := RequireUserAuth(jwtManager, validateAPIKey)(RequireScope(enterprise.APIKeyScopeTenantsRead)(handler))
+	writeOnly := RequireUserAuth(jwtManager, validateAPIKey)(RequireScope(enterprise.APIKeyScopeTenantsWrite)(handler))
+
+	loginToken, err := jwtManager.GenerateUserToken(&enterprise.ClusterUser{ID: "user_123", Verified: true}, 24)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	scenarios := []struct {
+		name     string
+		wrapped  http.Handler
+		token    string
+		expected int
+	}{
+		{"valid key with scope", readOnly, validKey, http.StatusOK},
+		{"valid key without scope", writeOnly, validKey, http.StatusForbidden},
+		{"invalid key", readOnly, enterprise.APIKeyPrefix + "invalid", http.StatusUnauthorized},
+		{"login token has every scope", writeOnly, loginToken, http.StatusOK},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+s.token)
+			rr := httptest.NewRecorder()
+
+			s.wrapped.ServeHTTP(rr, req)
+
+			if rr.Code != s.expected {
+				t.Errorf("expected %d, got %d", s.expected, rr.Code)
+			}
+		})
+	}
+
+	// Without a validator API keys are treated as (invalid) login tokens
+	t.Run("api keys disabled", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+validKey)
+		rr := httptest.NewRecorder()
+
+		RequireUserAuth(jwtManager, nil)(handler).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", rr.Code)
+		}
+	})
+}
+
 func TestRequireAdminAuthMiddleware(t *testing.T) {
 	validToken := "admin_test_token_12345"
 
@@ -408,3 +491,33 @@ func TestRequireAdminAuthMiddleware(t *testing.T) {
 		}
 	})
 }
+
+func TestWithRequestID(t *testing.T) {
+	// A caller-provided ID is kept so logs on both sides line up
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	rr := httptest.NewRecorder()
+
+	req = WithRequestID(rr, req)
+
+	if got := GetRequestID(req.Context()); got != "req-123" {
+		t.Errorf("expected request ID req-123, got %q", got)
+	}
+	if got := rr.Header().Get("X-Request-ID"); got != "req-123" {
+		t.Errorf("expected response header req-123, got %q", got)
+	}
+
+	// Otherwise a new one is generated
+	req = httptest.NewRequest("GET", "/test", nil)
+	rr = httptest.NewRecorder()
+
+	req = WithRequestID(rr, req)
+
+	requestID := GetRequestID(req.Context())
+	if requestID == "" {
+		t.Fatal("expected a generated request ID")
+	}
+	if rr.Header().Get("X-Request-ID") != requestID {
+		t.Error("expected generated request ID in response header")
+	}
+}
@@ -0,0 +1,616 @@
+// Package chaos runs a whole enterprise cluster in one process - a Raft control
+// plane, tenant nodes and a gateway talking over mangos and HTTP, backed by a
+// fake S3 - and injects failures into it: node crashes, network partitions and
+// slow or unavailable S3.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/control_plane"
+	"github.com/pocketbase/pocketbase/core/enterprise/gateway"
+	"github.com/pocketbase/pocketbase/core/enterprise/storage"
+	"github.com/pocketbase/pocketbase/core/enterprise/tenant_node"
+
+	// Tenant apps are bootstrapped with the PocketBase migrations
+	_ "github.com/pocketbase/pocketbase/migrations"
+)
+
+// Bucket is the fake S3 bucket of the cluster
+const Bucket = "chaos"
+
+// gatewayName is the network name of the gateway
+const gatewayName = "gateway"
+
+// Options configure a cluster
+type Options struct {
+	DataDir       string // Root of the nodes' data directories (required)
+	ControlPlanes int    // Control plane nodes (default 3)
+	TenantNodes   int    // Tenant nodes (default 2)
+
+	// Configure is applied to every node's config before it starts
+	Configure func(config *enterprise.ClusterConfig)
+}
+
+// Cluster is a running in-process cluster
+type Cluster struct {
+	S3      *FakeS3
+	Network *Network
+
+	ControlPlanes []*ControlPlaneNode
+	TenantNodes   []*TenantNode
+	Gateway       *GatewayNode
+
+	options Options
+	ownerID string // Cluster user owning the tenants created by the harness
+}
+
+// ControlPlaneNode is a control plane process of the cluster
+type ControlPlaneNode struct {
+	Name   string // Also the Raft server ID
+	config *enterprise.ClusterConfig
+
+	mu sync.Mutex
+	cp *control_plane.ControlPlane // nil while crashed
+}
+
+// TenantNode is a tenant node process of the cluster
+type TenantNode struct {
+	Name       string
+	cluster    *Cluster
+	listenAddr string
+
+	mu       sync.Mutex
+	config   *enterprise.ClusterConfig
+	manager  *tenant_node.Manager
+	server   *tenant_node.HTTPServer
+	cpClient *tenant_node.ControlPlaneClient
+	crashed  bool
+	stopped  chan struct{} // Closed once a crashed node has finished stopping
+}
+
+// GatewayNode is the gateway process of the cluster
+type GatewayNode struct {
+	URL string
+
+	gateway  *gateway.Gateway
+	cpClient *tenant_node.ControlPlaneClient
+}
+
+// Start launches a cluster and waits until its control plane has a leader
+func Start(options Options) (*Cluster, error) {
+	if options.DataDir == "" {
+		return nil, fmt.Errorf("data directory required")
+	}
+	if options.ControlPlanes == 0 {
+		options.ControlPlanes = 3
+	}
+	if options.TenantNodes == 0 {
+		options.TenantNodes = 2
+	}
+
+	c := &Cluster{
+		S3:      NewFakeS3(),
+		Network: NewNetwork(),
+		options: options,
+	}
+
+	if err := c.start(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Cluster) start() error {
+	// Control planes reach each other's Raft transport through the network
+	raftAddrs := make([]string, c.options.ControlPlanes)
+	ipcAddrs := make([]string, c.options.ControlPlanes)
+	for i := range raftAddrs {
+		var err error
+		if raftAddrs[i], err = freeAddr(); err != nil {
+			return err
+		}
+		if ipcAddrs[i], err = freeAddr(); err != nil {
+			return err
+		}
+	}
+
+	for i := range raftAddrs {
+		name := fmt.Sprintf("node%d", i+1)
+
+		// Raft names peers after their position in RaftPeers, and skips its own address
+		peers := make([]string, len(raftAddrs))
+		for j := range raftAddrs {
+			if j == i {
+				peers[j] = raftAddrs[i]
+				continue
+			}
+			addr, err := c.Network.Link(name, fmt.Sprintf("node%d", j+1), raftAddrs[j])
+			if err != nil {
+				return err
+			}
+			peers[j] = addr
+		}
+
+		config, err := c.newConfig(enterprise.ModeControlPlane, name)
+		if err != nil {
+			return err
+		}
+		config.NodeID = name
+		config.RaftBindAddr = raftAddrs[i]
+		config.RaftPeers = peers
+		config.IPCBindAddr = ipcAddrs[i]
+		config.DataDir = filepath.Join(c.options.DataDir, name)
+
+		c.ControlPlanes = append(c.ControlPlanes, &ControlPlaneNode{Name: name, config: config})
+	}
+
+	// Raft elections need a quorum, so all control planes start together
+	errs := make(chan error, len(c.ControlPlanes))
+	for _, node := range c.ControlPlanes {
+		go func(node *ControlPlaneNode) {
+			errs <- node.start()
+		}(node)
+	}
+	for range c.ControlPlanes {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+
+	leader, err := c.WaitForLeader(30 * time.Second)
+	if err != nil {
+		return err
+	}
+
+	owner := &enterprise.ClusterUser{
+		ID:       enterprise.GenerateUserID(),
+		Email:    "chaos@example.com",
+		Name:     "Chaos",
+		Verified: true,
+		Created:  time.Now(),
+		Updated:  time.Now(),
+	}
+	owner.MaxTenants = 1000
+	_, owner.MaxStoragePerTenant, owner.MaxAPIRequestsDaily = enterprise.DefaultUserQuotas()
+	if err := leader.ControlPlane().CreateUser(owner); err != nil {
+		return fmt.Errorf("failed to create tenant owner: %w", err)
+	}
+	c.ownerID = owner.ID
+
+	for i := 0; i < c.options.TenantNodes; i++ {
+		name := fmt.Sprintf("tenant%d", i+1)
+
+		listenAddr, err := freeAddr()
+		if err != nil {
+			return err
+		}
+
+		node := &TenantNode{Name: name, cluster: c, listenAddr: listenAddr}
+		if err := node.start(); err != nil {
+			return err
+		}
+		c.TenantNodes = append(c.TenantNodes, node)
+	}
+
+	return c.startGateway()
+}
+
+// newConfig returns the config of a node named name
+func (c *Cluster) newConfig(mode enterprise.Mode, name string) (*enterprise.ClusterConfig, error) {
+	endpoint, err := c.S3.Endpoint(name)
+	if err != nil {
+		return nil, err
+	}
+
+	config := enterprise.DefaultClusterConfig()
+	config.Mode = mode
+	config.S3Endpoint = endpoint
+	config.S3Bucket = Bucket
+	config.S3AccessKeyID = "chaos"
+	config.S3SecretAccessKey = "chaos"
+	config.LitestreamReplicateSync = true
+	config.JWTSecret = "chaos-secret"
+
+	if c.options.Configure != nil {
+		c.options.Configure(config)
+	}
+	return config, nil
+}
+
+// controlPlaneAddrs returns the IPC addresses that node reaches the control planes on
+func (c *Cluster) controlPlaneAddrs(node string) ([]string, error) {
+	addrs := make([]string, 0, len(c.ControlPlanes))
+	for _, cp := range c.ControlPlanes {
+		addr, err := c.Network.Link(node, cp.Name, cp.config.IPCBindAddr)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+func (c *Cluster) startGateway() error {
+	config, err := c.newConfig(enterprise.ModeGateway, gatewayName)
+	if err != nil {
+		return err
+	}
+	if config.GatewayControlPlaneAddrs, err = c.controlPlaneAddrs(gatewayName); err != nil {
+		return err
+	}
+
+	cpClient, err := tenant_node.NewControlPlaneClient(config.GatewayControlPlaneAddrs, config.CircuitBreaker)
+	if err != nil {
+		return fmt.Errorf("failed to create gateway control plane client: %w", err)
+	}
+
+	gw, err := gateway.NewGateway(config, cpClient)
+	if err != nil {
+		cpClient.Close()
+		return fmt.Errorf("failed to create gateway: %w", err)
+	}
+
+	addr, err := freeAddr()
+	if err != nil {
+		cpClient.Close()
+		return err
+	}
+
+	go gw.Start(addr)
+
+	c.Gateway = &GatewayNode{URL: "http://" + addr, gateway: gw, cpClient: cpClient}
+	return waitForHTTP(c.Gateway.URL+"/health/live", 10*time.Second)
+}
+
+// Leader returns the control plane that is currently the Raft leader, if any
+func (c *Cluster) Leader() *ControlPlaneNode {
+	for _, node := range c.ControlPlanes {
+		if cp := node.ControlPlane(); cp != nil && cp.IsLeader() {
+			return node
+		}
+	}
+	return nil
+}
+
+// WaitForLeader waits until one of the running control planes is the leader
+func (c *Cluster) WaitForLeader(timeout time.Duration) (*ControlPlaneNode, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if leader := c.Leader(); leader != nil {
+			return leader, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no Raft leader elected within %s", timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// CreateTenant creates a tenant through the leader. Its domain starts with the
+// tenant ID, like the domains the gateway routes.
+func (c *Cluster) CreateTenant() (*enterprise.Tenant, error) {
+	leader := c.Leader()
+	if leader == nil {
+		return nil, enterprise.ErrNotLeader
+	}
+
+	id := enterprise.GenerateTenantID()
+	tenant := &enterprise.Tenant{
+		ID:          id,
+		Domain:      id + ".chaos.local",
+		OwnerUserID: c.ownerID,
+	}
+	if err := leader.ControlPlane().CreateTenant(tenant); err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// NodesServing returns the running tenant nodes that serve tenantID under a
+// valid lease. Crashed nodes don't count, even while they are still shutting
+// down.
+func (c *Cluster) NodesServing(tenantID string) []*TenantNode {
+	var nodes []*TenantNode
+	for _, node := range c.TenantNodes {
+		if node.Serving(tenantID) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Partition cuts the network between the named processes and all others. S3
+// stays reachable from both sides.
+func (c *Cluster) Partition(names ...string) {
+	c.Network.Partition(names...)
+}
+
+// IsolateFromControlPlanes cuts a tenant node off from the control planes
+// only. The gateway and S3 still reach it, so it could keep serving and
+// replicating if nothing fenced it.
+func (c *Cluster) IsolateFromControlPlanes(node *TenantNode) {
+	names := make([]string, len(c.ControlPlanes))
+	for i, cp := range c.ControlPlanes {
+		names[i] = cp.Name
+	}
+	c.Network.Cut(node.Name, names...)
+}
+
+// Heal restores the network between all running processes
+func (c *Cluster) Heal() {
+	c.Network.Heal()
+	for _, node := range c.ControlPlanes {
+		if node.ControlPlane() == nil {
+			c.Network.Isolate(node.Name)
+		}
+	}
+	for _, node := range c.TenantNodes {
+		if node.Crashed() {
+			c.Network.Isolate(node.Name)
+		}
+	}
+}
+
+// SetS3Latency delays every S3 request by d
+func (c *Cluster) SetS3Latency(d time.Duration) {
+	c.S3.SetLatency(d)
+}
+
+// SetS3Unavailable makes every S3 request fail with a retryable error
+func (c *Cluster) SetS3Unavailable(unavailable bool) {
+	c.S3.SetUnavailable(unavailable)
+}
+
+// CrashControlPlane stops a control plane without a chance to talk to its peers
+func (c *Cluster) CrashControlPlane(node *ControlPlaneNode) error {
+	c.Network.Isolate(node.Name)
+	return node.stop()
+}
+
+// RestartControlPlane starts a crashed control plane on its old data directory
+func (c *Cluster) RestartControlPlane(node *ControlPlaneNode) error {
+	c.Network.Reconnect(node.Name)
+	return node.start()
+}
+
+// CrashTenantNode cuts a tenant node off from the cluster and S3, then shuts it
+// down in the background. The replicas it didn't sync yet are lost, like on a
+// real crash.
+func (c *Cluster) CrashTenantNode(node *TenantNode) {
+	c.Network.Isolate(node.Name)
+	c.S3.Block(node.Name, true)
+	node.crash()
+}
+
+// RestartTenantNode starts a crashed tenant node again. It registers with a new
+// node ID and an empty tenant cache, like a restarted process.
+func (c *Cluster) RestartTenantNode(node *TenantNode) error {
+	node.waitStopped()
+	c.Network.Reconnect(node.Name)
+	c.S3.Block(node.Name, false)
+	return node.start()
+}
+
+// Close stops all processes
+func (c *Cluster) Close() {
+	if c.Gateway != nil {
+		c.Gateway.gateway.Stop()
+		c.Gateway.cpClient.Close()
+	}
+
+	for _, node := range c.TenantNodes {
+		if !node.Crashed() {
+			node.stop()
+		}
+		node.waitStopped()
+	}
+
+	for _, node := range c.ControlPlanes {
+		node.stop()
+	}
+
+	c.Network.Close()
+	c.S3.Close()
+}
+
+// ControlPlane returns the running control plane, or nil while it is crashed
+func (n *ControlPlaneNode) ControlPlane() *control_plane.ControlPlane {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.cp
+}
+
+func (n *ControlPlaneNode) start() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.cp != nil {
+		return nil
+	}
+
+	cp, err := control_plane.NewControlPlane(n.config)
+	if err != nil {
+		return fmt.Errorf("failed to create control plane %s: %w", n.Name, err)
+	}
+	if err := cp.Start(); err != nil {
+		return fmt.Errorf("failed to start control plane %s: %w", n.Name, err)
+	}
+
+	n.cp = cp
+	return nil
+}
+
+func (n *ControlPlaneNode) stop() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.cp == nil {
+		return nil
+	}
+
+	err := n.cp.Stop()
+	n.cp = nil
+	return err
+}
+
+// Manager returns the tenant manager of the node's current process
+func (n *TenantNode) Manager() *tenant_node.Manager {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.manager
+}
+
+// Crashed returns true between a crash and the next restart
+func (n *TenantNode) Crashed() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.crashed
+}
+
+// Serving returns true if the node is running and has tenantID loaded under
+// a valid lease. A loaded tenant whose lease ran out refuses requests and is
+// unloaded on the next heartbeat.
+func (n *TenantNode) Serving(tenantID string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.crashed || n.manager == nil {
+		return false
+	}
+	return n.manager.HoldsLease(tenantID)
+}
+
+func (n *TenantNode) start() error {
+	c := n.cluster
+
+	config, err := c.newConfig(enterprise.ModeTenantNode, n.Name)
+	if err != nil {
+		return err
+	}
+	config.DataDir = filepath.Join(c.options.DataDir, n.Name)
+	if config.ControlPlaneAddrs, err = c.controlPlaneAddrs(n.Name); err != nil {
+		return err
+	}
+
+	// Gateways reach the node through the network
+	if config.NodeAddress, err = c.Network.Link(gatewayName, n.Name, n.listenAddr); err != nil {
+		return err
+	}
+
+	s3Backend, err := storage.NewS3Backend(context.Background(), config.S3Endpoint, config.S3Region, config.S3Bucket, config.S3AccessKeyID, config.S3SecretAccessKey)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 backend of %s: %w", n.Name, err)
+	}
+
+	cpClient, err := tenant_node.NewControlPlaneClient(config.ControlPlaneAddrs, config.CircuitBreaker)
+	if err != nil {
+		return fmt.Errorf("failed to create control plane client of %s: %w", n.Name, err)
+	}
+	cpClient.SetIPCSecret(config.IPCSecret)
+
+	manager, err := tenant_node.NewManager(config, s3Backend, cpClient)
+	if err != nil {
+		cpClient.Close()
+		return fmt.Errorf("failed to create tenant manager of %s: %w", n.Name, err)
+	}
+	if err := manager.Start(); err != nil {
+		cpClient.Close()
+		return fmt.Errorf("failed to start tenant node %s: %w", n.Name, err)
+	}
+
+	server := tenant_node.NewHTTPServer(manager)
+	go server.Start(n.listenAddr)
+
+	n.mu.Lock()
+	n.config = config
+	n.manager = manager
+	n.server = server
+	n.cpClient = cpClient
+	n.crashed = false
+	n.stopped = nil
+	n.mu.Unlock()
+
+	return waitForHTTP("http://"+n.listenAddr+"/_health", 10*time.Second)
+}
+
+func (n *TenantNode) crash() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.crashed || n.manager == nil {
+		return
+	}
+
+	n.crashed = true
+	n.stopped = make(chan struct{})
+
+	manager, server, cpClient, stopped := n.manager, n.server, n.cpClient, n.stopped
+	go func() {
+		defer close(stopped)
+		server.Stop()
+		manager.Stop()
+		cpClient.Close()
+	}()
+}
+
+func (n *TenantNode) stop() {
+	n.mu.Lock()
+	manager, server, cpClient := n.manager, n.server, n.cpClient
+	n.manager = nil
+	n.mu.Unlock()
+
+	if manager == nil {
+		return
+	}
+	server.Stop()
+	manager.Stop()
+	cpClient.Close()
+}
+
+func (n *TenantNode) waitStopped() {
+	n.mu.Lock()
+	stopped := n.stopped
+	n.mu.Unlock()
+
+	if stopped != nil {
+		<-stopped
+	}
+}
+
+// freeAddr returns a local address that is free to listen on
+func freeAddr() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String(), nil
+}
+
+// waitForHTTP waits until url responds
+func waitForHTTP(url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: time.Second}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Join(fmt.Errorf("%s did not come up within %s", url, timeout), err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
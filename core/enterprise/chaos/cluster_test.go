@@ -0,0 +1,315 @@
+package chaos
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// startCluster starts a cluster that is closed when the test ends
+func startCluster(t *testing.T) *Cluster {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("starts a whole cluster")
+	}
+
+	// Litestream can't add a CA bundle to its HTTP client, and the fake S3 is plain HTTP
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	config := enterprise.DefaultClusterConfig()
+	config.LogLevel = enterprise.LogLevelWarn
+	if err := enterprise.ConfigureLogging(config); err != nil {
+		t.Fatalf("ConfigureLogging: %v", err)
+	}
+	t.Cleanup(func() {
+		enterprise.ConfigureLogging(enterprise.DefaultClusterConfig())
+	})
+
+	c, err := Start(Options{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to start cluster: %v", err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestClusterServesWrites(t *testing.T) {
+	c := startCluster(t)
+
+	tenant, err := c.CreateTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := c.WaitForTenant(ctx, tenant, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(tenant)
+	for i := 0; i < 5; i++ {
+		if err := w.Write(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lost, err := c.LostWrites(ctx, tenant, w.Acked(), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) > 0 {
+		t.Fatalf("lost %d writes", len(lost))
+	}
+}
+
+func TestLeaderFailoverWithinSLA(t *testing.T) {
+	c := startCluster(t)
+
+	crashed := c.Leader()
+	leader, took, err := c.MeasureFailover(DefaultFailoverSLA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leader == crashed {
+		t.Fatalf("crashed leader %s is still the leader", crashed.Name)
+	}
+	t.Logf("failover from %s to %s took %s", crashed.Name, leader.Name, took)
+
+	// The old leader rejoins as a follower
+	if err := c.RestartControlPlane(crashed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.WaitForLeader(DefaultFailoverSLA); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateTenant(); err != nil {
+		t.Fatalf("cluster stopped accepting writes after the restart: %v", err)
+	}
+}
+
+func TestLeaderPartitionKeepsServingTenants(t *testing.T) {
+	c := startCluster(t)
+	ctx := context.Background()
+
+	tenant, err := c.CreateTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.WaitForTenant(ctx, tenant, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	monitor := c.StartMonitor(50*time.Millisecond, tenant.ID)
+
+	// The leader ends up in the minority and has to step down
+	old := c.Leader()
+	c.Partition(old.Name)
+
+	w := c.NewWriter(tenant)
+	for i := 0; i < 5; i++ {
+		if err := w.Write(ctx); err != nil {
+			t.Fatalf("write during partition: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(DefaultFailoverSLA)
+	for {
+		if !old.ControlPlane().IsLeader() {
+			if leader := c.Leader(); leader != nil {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("majority didn't elect a leader within %s", DefaultFailoverSLA)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	c.Heal()
+	if _, err := c.CreateTenant(); err != nil {
+		t.Fatalf("cluster stopped accepting writes after healing: %v", err)
+	}
+
+	for _, v := range monitor.Stop() {
+		t.Error(v)
+	}
+	lost, err := c.LostWrites(ctx, tenant, w.Acked(), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) > 0 {
+		t.Fatalf("lost %d writes", len(lost))
+	}
+}
+
+func TestTenantNodeCrashKeepsReplicatedWrites(t *testing.T) {
+	c := startCluster(t)
+	ctx := context.Background()
+
+	tenant, err := c.CreateTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := c.WaitForTenant(ctx, tenant, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor := c.StartMonitor(50*time.Millisecond, tenant.ID)
+
+	writeCtx, stopWriting := context.WithCancel(ctx)
+	defer stopWriting()
+	w := c.NewWriter(tenant)
+	go w.Run(writeCtx, 50*time.Millisecond)
+
+	// Replication catches up after an S3 outage
+	time.Sleep(time.Second)
+	c.SetS3Unavailable(true)
+	time.Sleep(2 * time.Second)
+	c.SetS3Unavailable(false)
+	time.Sleep(ReplicationRPO)
+
+	epochsPrefix := "tenants/" + tenant.ID + "/litestream/epochs/"
+	replicated := c.S3.Keys(Bucket, epochsPrefix)
+	if len(replicated) == 0 {
+		t.Fatal("nothing replicated before the crash")
+	}
+
+	crashed := time.Now()
+	c.CrashTenantNode(node)
+	stopWriting()
+
+	survivor, err := c.WaitForTenant(ctx, tenant, NodeFailoverTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if survivor == node {
+		t.Fatalf("tenant still served by crashed node %s", node.Name)
+	}
+
+	for _, v := range monitor.Stop() {
+		t.Error(v)
+	}
+
+	writes := w.AckedBefore(crashed.Add(-ReplicationRPO))
+	if len(writes) == 0 {
+		t.Fatal("no writes acknowledged before the crash")
+	}
+	lost, err := c.LostWrites(ctx, tenant, writes, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) > 0 {
+		t.Fatalf("lost %d of %d replicated writes, first %s acked at %s", len(lost), len(writes), lost[0].Email, lost[0].AckedAt)
+	}
+
+	// The crashed node's epoch is deleted once the survivor's has a snapshot
+	crashedEpoch := epochsPrefix + strings.SplitN(strings.TrimPrefix(replicated[0], epochsPrefix), "/", 2)[0] + "/"
+	deadline := time.Now().Add(30 * time.Second)
+	for len(c.S3.Keys(Bucket, crashedEpoch)) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("replica of the crashed node's epoch %s wasn't deleted", crashedEpoch)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func TestSlowS3DoesNotBlockWrites(t *testing.T) {
+	c := startCluster(t)
+	ctx := context.Background()
+
+	c.SetS3Latency(300 * time.Millisecond)
+
+	tenant, err := c.CreateTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.WaitForTenant(ctx, tenant, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(tenant)
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		if err := w.Write(ctx); err != nil {
+			t.Fatal(err)
+		}
+		// Replication is asynchronous, so S3 latency stays off the write path
+		if took := time.Since(start); took > time.Second {
+			t.Errorf("write took %s with slow S3", took)
+		}
+	}
+
+	lost, err := c.LostWrites(ctx, tenant, w.Acked(), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) > 0 {
+		t.Fatalf("lost %d writes", len(lost))
+	}
+}
+
+func TestTenantNodeCutOffFromControlPlanesIsFenced(t *testing.T) {
+	c := startCluster(t)
+	ctx := context.Background()
+
+	tenant, err := c.CreateTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := c.WaitForTenant(ctx, tenant, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor := c.StartMonitor(50*time.Millisecond, tenant.ID)
+
+	w := c.NewWriter(tenant)
+	for i := 0; i < 5; i++ {
+		if err := w.Write(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(ReplicationRPO)
+
+	// The gateway and S3 still reach the node, only its lease can stop it
+	c.IsolateFromControlPlanes(node)
+
+	deadline := time.Now().Add(enterprise.TenantLeaseTTL + 15*time.Second)
+	for node.Serving(tenant.ID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("fenced node %s kept serving after its lease ran out", node.Name)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	survivor, err := c.WaitForTenant(ctx, tenant, NodeFailoverTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if survivor == node {
+		t.Fatalf("tenant still served by fenced node %s", node.Name)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := w.Write(ctx); err != nil {
+			t.Fatalf("write after takeover: %v", err)
+		}
+	}
+
+	c.Heal()
+	for _, v := range monitor.Stop() {
+		t.Error(v)
+	}
+	lost, err := c.LostWrites(ctx, tenant, w.Acked(), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) > 0 {
+		t.Fatalf("lost %d writes", len(lost))
+	}
+}
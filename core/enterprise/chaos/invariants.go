@@ -0,0 +1,312 @@
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// DefaultFailoverSLA is how long the control plane may go without a leader
+// after the leader crashes
+const DefaultFailoverSLA = 10 * time.Second
+
+// ReplicationRPO bounds the writes a crashed tenant node may lose: Litestream
+// ships the WAL to S3 in the background, so only writes acknowledged more than
+// this before a crash are guaranteed to survive it
+const ReplicationRPO = 5 * time.Second
+
+// NodeFailoverTimeout is how long a tenant takes to come back on another node
+// after its node crashes. The control plane only gives up on a node once it
+// misses heartbeats for 30s.
+const NodeFailoverTimeout = 60 * time.Second
+
+// Write is a record created through the gateway
+type Write struct {
+	Email   string
+	AckedAt time.Time // When the gateway acknowledged the write
+}
+
+// Writer creates user records in a tenant through the gateway and keeps the
+// ones the gateway acknowledged, to check later that none of them got lost
+type Writer struct {
+	cluster *Cluster
+	tenant  *enterprise.Tenant
+	client  *http.Client
+
+	mu     sync.Mutex
+	next   int
+	acked  []Write
+	failed int
+}
+
+// NewWriter returns a writer for tenant
+func (c *Cluster) NewWriter(tenant *enterprise.Tenant) *Writer {
+	return &Writer{
+		cluster: c,
+		tenant:  tenant,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Write creates one record and returns an error unless it was acknowledged
+func (w *Writer) Write(ctx context.Context) error {
+	w.mu.Lock()
+	w.next++
+	email := fmt.Sprintf("writer%d@chaos.local", w.next)
+	w.mu.Unlock()
+
+	body, _ := json.Marshal(map[string]string{
+		"email":           email,
+		"password":        "chaos-password",
+		"passwordConfirm": "chaos-password",
+	})
+
+	status, respBody, err := w.cluster.Request(ctx, w.tenant, http.MethodPost, "/api/collections/users/records", body)
+	if err == nil && status != http.StatusOK {
+		err = fmt.Errorf("write %s failed with status %d: %s", email, status, strings.TrimSpace(string(respBody)))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err != nil {
+		w.failed++
+		return err
+	}
+	w.acked = append(w.acked, Write{Email: email, AckedAt: time.Now()})
+	return nil
+}
+
+// Run writes every interval until ctx is done. Failed writes are counted and
+// not retried.
+func (w *Writer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		w.Write(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Acked returns the acknowledged writes
+func (w *Writer) Acked() []Write {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Write(nil), w.acked...)
+}
+
+// AckedBefore returns the writes acknowledged before t
+func (w *Writer) AckedBefore(t time.Time) []Write {
+	var writes []Write
+	for _, write := range w.Acked() {
+		if write.AckedAt.Before(t) {
+			writes = append(writes, write)
+		}
+	}
+	return writes
+}
+
+// Failed returns the number of writes that weren't acknowledged
+func (w *Writer) Failed() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.failed
+}
+
+// Request sends a request to tenant through the gateway
+func (c *Cluster) Request(ctx context.Context, tenant *enterprise.Tenant, method, path string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.Gateway.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Host = tenant.Domain
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, respBody, err
+}
+
+// WaitForTenant sends requests to tenant through the gateway until one succeeds,
+// which loads it on a node, and returns that node
+func (c *Cluster) WaitForTenant(ctx context.Context, tenant *enterprise.Tenant, timeout time.Duration) (*TenantNode, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastErr error
+	for {
+		status, body, err := c.Request(ctx, tenant, http.MethodGet, "/api/health", nil)
+		if err == nil && status == http.StatusOK {
+			if nodes := c.NodesServing(tenant.ID); len(nodes) == 1 {
+				return nodes[0], nil
+			}
+		}
+		if err == nil {
+			err = fmt.Errorf("status %d: %s", status, strings.TrimSpace(string(body)))
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("tenant %s not served within %s: %w", tenant.ID, timeout, lastErr)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// LostWrites loads tenant through the gateway and returns the writes that
+// aren't in its database
+func (c *Cluster) LostWrites(ctx context.Context, tenant *enterprise.Tenant, writes []Write, timeout time.Duration) ([]Write, error) {
+	node, err := c.WaitForTenant(ctx, tenant, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := node.Manager().GetTenant(tenant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s unloaded from %s: %w", tenant.ID, node.Name, err)
+	}
+
+	var lost []Write
+	for _, write := range writes {
+		if _, err := instance.App.FindAuthRecordByEmail("users", write.Email); err != nil {
+			lost = append(lost, write)
+		}
+	}
+	return lost, nil
+}
+
+// MeasureFailover crashes the current leader and returns how long it took until
+// another control plane was elected and committed a write
+func (c *Cluster) MeasureFailover(timeout time.Duration) (*ControlPlaneNode, time.Duration, error) {
+	leader := c.Leader()
+	if leader == nil {
+		return nil, 0, enterprise.ErrNotLeader
+	}
+
+	crashed := time.Now()
+	if err := c.CrashControlPlane(leader); err != nil {
+		return nil, 0, err
+	}
+
+	deadline := crashed.Add(timeout)
+	for {
+		if next := c.Leader(); next != nil {
+			// Leadership counts once the new leader commits
+			if _, err := c.CreateTenant(); err == nil {
+				return next, time.Since(crashed), nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, time.Since(crashed), fmt.Errorf("no leader committed a write within %s of the crash", timeout)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Violation is an invariant found broken by a Monitor
+type Violation struct {
+	Time     time.Time
+	TenantID string
+	Detail   string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: tenant %s: %s", v.Time.Format(time.RFC3339Nano), v.TenantID, v.Detail)
+}
+
+// Monitor samples the cluster for tenants loaded on more than one running node
+type Monitor struct {
+	cluster *Cluster
+	tenants []string
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu         sync.Mutex
+	violations []Violation
+}
+
+// StartMonitor checks every interval that none of tenantIDs is loaded on two
+// nodes at once
+func (c *Cluster) StartMonitor(interval time.Duration, tenantIDs ...string) *Monitor {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := &Monitor{
+		cluster: c,
+		tenants: tenantIDs,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go m.run(ctx, interval)
+	return m
+}
+
+func (m *Monitor) run(ctx context.Context, interval time.Duration) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) check() {
+	for _, tenantID := range m.tenants {
+		nodes := m.cluster.NodesServing(tenantID)
+		if len(nodes) < 2 {
+			continue
+		}
+
+		names := make([]string, len(nodes))
+		for i, node := range nodes {
+			names[i] = node.Name
+		}
+
+		m.mu.Lock()
+		m.violations = append(m.violations, Violation{
+			Time:     time.Now(),
+			TenantID: tenantID,
+			Detail:   "loaded on " + strings.Join(names, ", "),
+		})
+		m.mu.Unlock()
+	}
+}
+
+// Stop stops sampling and returns the violations found
+func (m *Monitor) Stop() []Violation {
+	m.cancel()
+	<-m.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.violations
+}
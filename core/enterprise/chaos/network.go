@@ -0,0 +1,204 @@
+package chaos
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// Network routes the TCP traffic between the processes of a cluster through
+// proxies, one per direction of every link, so that links can be cut to
+// simulate partitions and crashed nodes
+type Network struct {
+	mu    sync.Mutex
+	links map[linkKey]*link
+}
+
+type linkKey struct {
+	from, to string
+}
+
+// link forwards the connections from one process to an address of another
+type link struct {
+	key      linkKey
+	target   string
+	listener net.Listener
+
+	mu      sync.Mutex
+	blocked bool
+	conns   map[net.Conn]struct{}
+}
+
+// NewNetwork creates a network without links
+func NewNetwork() *Network {
+	return &Network{links: make(map[linkKey]*link)}
+}
+
+// Link returns the address that from reaches target of to on. The address stays
+// the same for the lifetime of the network, so restarted processes get their
+// old links back.
+func (n *Network) Link(from, to, target string) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := linkKey{from: from, to: to}
+	if l, ok := n.links[key]; ok {
+		if l.target != target {
+			return "", fmt.Errorf("link %s -> %s already targets %s", from, to, l.target)
+		}
+		return l.listener.Addr().String(), nil
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for link %s -> %s: %w", from, to, err)
+	}
+
+	l := &link{
+		key:      key,
+		target:   target,
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	n.links[key] = l
+	go l.accept()
+
+	return listener.Addr().String(), nil
+}
+
+// Isolate cuts all links from and to node
+func (n *Network) Isolate(node string) {
+	n.setBlocked(func(key linkKey) bool {
+		return key.from == node || key.to == node
+	}, true)
+}
+
+// Partition cuts the links between the nodes of side and all other nodes
+func (n *Network) Partition(side ...string) {
+	inside := make(map[string]bool, len(side))
+	for _, node := range side {
+		inside[node] = true
+	}
+
+	n.setBlocked(func(key linkKey) bool {
+		return inside[key.from] != inside[key.to]
+	}, true)
+}
+
+// Cut cuts the links between node and each of peers
+func (n *Network) Cut(node string, peers ...string) {
+	cut := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		cut[peer] = true
+	}
+
+	n.setBlocked(func(key linkKey) bool {
+		return (key.from == node && cut[key.to]) || (key.to == node && cut[key.from])
+	}, true)
+}
+
+// Reconnect restores the links from and to node
+func (n *Network) Reconnect(node string) {
+	n.setBlocked(func(key linkKey) bool {
+		return key.from == node || key.to == node
+	}, false)
+}
+
+// Heal restores all links
+func (n *Network) Heal() {
+	n.setBlocked(func(linkKey) bool { return true }, false)
+}
+
+// Close closes all links and their connections
+func (n *Network) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, l := range n.links {
+		l.listener.Close()
+		l.setBlocked(true)
+	}
+}
+
+func (n *Network) setBlocked(match func(linkKey) bool, blocked bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for key, l := range n.links {
+		if match(key) {
+			l.setBlocked(blocked)
+		}
+	}
+}
+
+// setBlocked blocks or unblocks the link. Blocking drops open connections.
+func (l *link) setBlocked(blocked bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.blocked = blocked
+	if blocked {
+		for conn := range l.conns {
+			conn.Close()
+		}
+		l.conns = make(map[net.Conn]struct{})
+	}
+}
+
+func (l *link) accept() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		go l.forward(conn)
+	}
+}
+
+// forward proxies conn to the target until either side closes or the link is cut
+func (l *link) forward(conn net.Conn) {
+	l.mu.Lock()
+	if l.blocked {
+		l.mu.Unlock()
+		conn.Close()
+		return
+	}
+	l.mu.Unlock()
+
+	upstream, err := net.Dial("tcp", l.target)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	l.mu.Lock()
+	if l.blocked {
+		l.mu.Unlock()
+		conn.Close()
+		upstream.Close()
+		return
+	}
+	l.conns[conn] = struct{}{}
+	l.conns[upstream] = struct{}{}
+	l.mu.Unlock()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+
+	conn.Close()
+	upstream.Close()
+
+	l.mu.Lock()
+	delete(l.conns, conn)
+	delete(l.conns, upstream)
+	l.mu.Unlock()
+}
@@ -0,0 +1,554 @@
+package chaos
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeS3 is an in-memory, path-style S3 server with the subset of the API used
+// by Litestream and the storage backend. Every client gets its own endpoint so
+// that S3 can be cut off for a single node.
+type FakeS3 struct {
+	mu          sync.Mutex
+	buckets     map[string]map[string]*s3Object // bucket -> key -> object
+	uploads     map[string]*s3Upload            // upload ID -> multipart upload
+	nextUpload  int
+	latency     time.Duration
+	unavailable bool
+	blocked     map[string]bool // client -> requests are denied
+
+	servers map[string]*http.Server // client -> endpoint
+	addrs   map[string]string
+}
+
+type s3Object struct {
+	data     []byte
+	etag     string
+	modified time.Time
+}
+
+type s3Upload struct {
+	bucket string
+	key    string
+	parts  map[int][]byte
+}
+
+// NewFakeS3 creates an empty fake S3. Buckets are created on first write.
+func NewFakeS3() *FakeS3 {
+	return &FakeS3{
+		buckets: make(map[string]map[string]*s3Object),
+		uploads: make(map[string]*s3Upload),
+		blocked: make(map[string]bool),
+		servers: make(map[string]*http.Server),
+		addrs:   make(map[string]string),
+	}
+}
+
+// Endpoint returns the URL that client reaches S3 on, starting it on first use
+func (s *FakeS3) Endpoint(client string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if addr, ok := s.addrs[client]; ok {
+		return "http://" + addr, nil
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for S3 client %s: %w", client, err)
+	}
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.serve(client, w, r)
+	})}
+	go server.Serve(listener)
+
+	s.servers[client] = server
+	s.addrs[client] = listener.Addr().String()
+	return "http://" + listener.Addr().String(), nil
+}
+
+// SetLatency delays every request by d
+func (s *FakeS3) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetUnavailable makes every request fail with a retryable 503
+func (s *FakeS3) SetUnavailable(unavailable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unavailable = unavailable
+}
+
+// Block denies the requests of client, as if its network was cut off. Requests
+// fail right away so that a crashed node doesn't spend its retries on them.
+func (s *FakeS3) Block(client string, blocked bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocked[client] = blocked
+}
+
+// Keys lists the keys of a bucket starting with prefix
+func (s *FakeS3) Keys(bucket, prefix string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0)
+	for key := range s.buckets[bucket] {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Close stops all endpoints
+func (s *FakeS3) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, server := range s.servers {
+		server.Close()
+	}
+}
+
+func (s *FakeS3) serve(client string, w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	latency, unavailable, blocked := s.latency, s.unavailable, s.blocked[client]
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if blocked {
+		writeS3Error(w, http.StatusForbidden, "AccessDenied", "Access denied for "+client)
+		return
+	}
+	if unavailable {
+		writeS3Error(w, http.StatusServiceUnavailable, "ServiceUnavailable", "Please reduce your request rate")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket == "" {
+		writeS3Error(w, http.StatusBadRequest, "InvalidBucketName", "Bucket name is required")
+		return
+	}
+
+	query := r.URL.Query()
+
+	if key == "" {
+		switch {
+		case query.Has("location"):
+			writeS3XML(w, http.StatusOK, struct {
+				XMLName xml.Name `xml:"LocationConstraint"`
+			}{})
+		case query.Has("lifecycle"):
+			s.serveLifecycle(w, r)
+		case r.Method == http.MethodPost && query.Has("delete"):
+			s.deleteObjects(w, r, bucket)
+		case r.Method == http.MethodGet:
+			s.listObjects(w, r, bucket)
+		case r.Method == http.MethodHead, r.Method == http.MethodPut:
+			w.WriteHeader(http.StatusOK)
+		default:
+			writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not supported on buckets")
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.getObject(w, r, bucket, key)
+	case http.MethodPut:
+		switch {
+		case query.Has("uploadId"):
+			s.uploadPart(w, r, query.Get("uploadId"))
+		case r.Header.Get("X-Amz-Copy-Source") != "":
+			s.copyObject(w, r, bucket, key)
+		default:
+			s.putObject(w, r, bucket, key)
+		}
+	case http.MethodPost:
+		switch {
+		case query.Has("uploads"):
+			s.createUpload(w, bucket, key)
+		case query.Has("uploadId"):
+			s.completeUpload(w, bucket, key, query.Get("uploadId"))
+		default:
+			// Glacier restores complete right away
+			w.WriteHeader(http.StatusOK)
+		}
+	case http.MethodDelete:
+		s.mu.Lock()
+		if query.Has("uploadId") {
+			delete(s.uploads, query.Get("uploadId"))
+		} else {
+			delete(s.buckets[bucket], key)
+		}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not supported on objects")
+	}
+}
+
+func (s *FakeS3) getObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	s.mu.Lock()
+	object := s.buckets[bucket][key]
+	s.mu.Unlock()
+
+	if object == nil {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+		return
+	}
+
+	w.Header().Set("ETag", object.etag)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", object.modified, bytes.NewReader(object.data))
+}
+
+func (s *FakeS3) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	data, err := readS3Body(r)
+	if err != nil {
+		writeS3Error(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	etag := s.store(bucket, key, data)
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *FakeS3) copyObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	source := strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/")
+	sourceBucket, sourceKey, _ := strings.Cut(source, "/")
+
+	s.mu.Lock()
+	object := s.buckets[sourceBucket][sourceKey]
+	s.mu.Unlock()
+
+	if object == nil {
+		writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The copy source does not exist")
+		return
+	}
+
+	etag := s.store(bucket, key, object.data)
+	writeS3XML(w, http.StatusOK, struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		ETag         string   `xml:"ETag"`
+		LastModified string   `xml:"LastModified"`
+	}{ETag: etag, LastModified: time.Now().UTC().Format(time.RFC3339)})
+}
+
+func (s *FakeS3) store(bucket, key string, data []byte) string {
+	sum := md5.Sum(data)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string]*s3Object)
+	}
+	s.buckets[bucket][key] = &s3Object{data: data, etag: etag, modified: time.Now()}
+	return etag
+}
+
+func (s *FakeS3) listObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+
+	maxKeys := 1000
+	if n, err := strconv.Atoi(query.Get("max-keys")); err == nil && n > 0 && n < maxKeys {
+		maxKeys = n
+	}
+
+	after := query.Get("start-after")
+	if token := query.Get("continuation-token"); token != "" {
+		after = token
+	}
+
+	type content struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int    `xml:"Size"`
+		StorageClass string `xml:"StorageClass"`
+	}
+	type commonPrefix struct {
+		Prefix string `xml:"Prefix"`
+	}
+	result := struct {
+		XMLName               xml.Name       `xml:"ListBucketResult"`
+		Name                  string         `xml:"Name"`
+		Prefix                string         `xml:"Prefix"`
+		Delimiter             string         `xml:"Delimiter,omitempty"`
+		KeyCount              int            `xml:"KeyCount"`
+		MaxKeys               int            `xml:"MaxKeys"`
+		IsTruncated           bool           `xml:"IsTruncated"`
+		ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+		NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+		Contents              []content      `xml:"Contents"`
+		CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+	}{
+		Name:              bucket,
+		Prefix:            prefix,
+		Delimiter:         delimiter,
+		MaxKeys:           maxKeys,
+		ContinuationToken: query.Get("continuation-token"),
+	}
+
+	s.mu.Lock()
+	keys := make([]string, 0)
+	for key := range s.buckets[bucket] {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	seenPrefixes := make(map[string]bool)
+	for _, key := range keys {
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common := key[:len(prefix)+i+len(delimiter)]
+				if !seenPrefixes[common] {
+					seenPrefixes[common] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: common})
+					result.KeyCount++
+					result.NextContinuationToken = key
+				}
+				continue
+			}
+		}
+
+		object := s.buckets[bucket][key]
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			LastModified: object.modified.UTC().Format("2006-01-02T15:04:05.000Z"),
+			ETag:         object.etag,
+			Size:         len(object.data),
+			StorageClass: "STANDARD",
+		})
+		result.KeyCount++
+		result.NextContinuationToken = key
+	}
+	s.mu.Unlock()
+
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+
+	writeS3XML(w, http.StatusOK, result)
+}
+
+func (s *FakeS3) deleteObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	var request struct {
+		Quiet   bool `xml:"Quiet"`
+		Objects []struct {
+			Key string `xml:"Key"`
+		} `xml:"Object"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeS3Error(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+
+	type deleted struct {
+		Key string `xml:"Key"`
+	}
+	result := struct {
+		XMLName xml.Name  `xml:"DeleteResult"`
+		Deleted []deleted `xml:"Deleted"`
+	}{}
+
+	s.mu.Lock()
+	for _, object := range request.Objects {
+		delete(s.buckets[bucket], object.Key)
+		if !request.Quiet {
+			result.Deleted = append(result.Deleted, deleted{Key: object.Key})
+		}
+	}
+	s.mu.Unlock()
+
+	writeS3XML(w, http.StatusOK, result)
+}
+
+func (s *FakeS3) createUpload(w http.ResponseWriter, bucket, key string) {
+	s.mu.Lock()
+	s.nextUpload++
+	uploadID := fmt.Sprintf("upload-%d", s.nextUpload)
+	s.uploads[uploadID] = &s3Upload{bucket: bucket, key: key, parts: make(map[int][]byte)}
+	s.mu.Unlock()
+
+	writeS3XML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}{Bucket: bucket, Key: key, UploadID: uploadID})
+}
+
+func (s *FakeS3) uploadPart(w http.ResponseWriter, r *http.Request, uploadID string) {
+	partNumber, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil {
+		writeS3Error(w, http.StatusBadRequest, "InvalidArgument", "Invalid part number")
+		return
+	}
+
+	data, err := readS3Body(r)
+	if err != nil {
+		writeS3Error(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	upload := s.uploads[uploadID]
+	if upload != nil {
+		upload.parts[partNumber] = data
+	}
+	s.mu.Unlock()
+
+	if upload == nil {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist")
+		return
+	}
+
+	sum := md5.Sum(data)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *FakeS3) completeUpload(w http.ResponseWriter, bucket, key, uploadID string) {
+	s.mu.Lock()
+	upload := s.uploads[uploadID]
+	delete(s.uploads, uploadID)
+	s.mu.Unlock()
+
+	if upload == nil {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist")
+		return
+	}
+
+	numbers := make([]int, 0, len(upload.parts))
+	for number := range upload.parts {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	var data []byte
+	for _, number := range numbers {
+		data = append(data, upload.parts[number]...)
+	}
+
+	etag := s.store(bucket, key, data)
+	writeS3XML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string   `xml:"Bucket"`
+		Key     string   `xml:"Key"`
+		ETag    string   `xml:"ETag"`
+	}{Bucket: bucket, Key: key, ETag: etag})
+}
+
+// serveLifecycle accepts lifecycle rules without applying them
+func (s *FakeS3) serveLifecycle(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeS3Error(w, http.StatusNotFound, "NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist")
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// readS3Body reads an object upload, decoding aws-chunked (streaming) bodies
+func readS3Body(r *http.Request) ([]byte, error) {
+	streaming := strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") ||
+		strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-")
+	if !streaming {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk header: %w", err)
+		}
+
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk size %q", sizeHex)
+		}
+
+		// The last chunk is only followed by trailers (checksums), which are ignored
+		if size == 0 {
+			return data, nil
+		}
+
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, fmt.Errorf("short chunk: %w", err)
+		}
+		data = append(data, chunk...)
+
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, fmt.Errorf("unterminated chunk: %w", err)
+		}
+	}
+}
+
+func writeS3XML(w http.ResponseWriter, status int, v interface{}) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code, message string) {
+	writeS3XML(w, status, struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: code, Message: message})
+}
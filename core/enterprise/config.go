@@ -0,0 +1,757 @@
+package enterprise
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnvPrefix prefixes the environment variables overriding config file fields,
+// e.g. POCKETBASE_S3_BUCKET for s3Bucket or POCKETBASE_DISK_MAX_USAGE_BYTES for disk.maxUsageBytes
+const ConfigEnvPrefix = "POCKETBASE_"
+
+// Log levels
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// reloadableConfigFields are the top-level config fields that take effect on SIGHUP
+// without restarting the node; everything else needs a restart
+var reloadableConfigFields = map[string]bool{
+	"logLevel":       true,
+	"quotas":         true,
+	"disk":           true,
+	"archive":        true,
+	"auditRetention": true,
+	"logs":           true,
+	"metrics":        true,
+
+	"gatewayCacheMemoryMb":  true,
+	"gatewayTrustedProxies": true,
+	"gatewayCountryHeader":  true,
+}
+
+// DiskSettings are the control plane's BadgerDB disk usage thresholds (zero keeps the default)
+type DiskSettings struct {
+	MaxUsageBytes        int64   `json:"maxUsageBytes,omitempty"`
+	WarningThresholdPct  float64 `json:"warningThresholdPct,omitempty"`
+	CriticalThresholdPct float64 `json:"criticalThresholdPct,omitempty"`
+}
+
+// ArchiveSettings are the inactivity thresholds of the tenant archiver (empty keeps the default)
+type ArchiveSettings struct {
+	LitestreamStopAfter string `json:"litestreamStopAfter,omitempty"` // Stop replicating idle tenants (e.g., "72h")
+	WarmAfter           string `json:"warmAfter,omitempty"`           // Unload idle tenants to S3 Standard (e.g., "168h")
+	ColdAfter           string `json:"coldAfter,omitempty"`           // Move idle tenants to Glacier (e.g., "2160h")
+	MaxPerRun           int    `json:"maxPerRun,omitempty"`           // Max tenants archived per check
+	GlacierStorageClass string `json:"glacierStorageClass,omitempty"` // GLACIER or DEEP_ARCHIVE
+}
+
+// CircuitBreakerSettings configure the circuit breaker in front of control plane calls
+// (zero keeps the default)
+type CircuitBreakerSettings struct {
+	MaxFailures     int    `json:"maxFailures,omitempty"`
+	ResetTimeout    string `json:"resetTimeout,omitempty"` // e.g., "30s"
+	HalfOpenMaxReqs int    `json:"halfOpenMaxReqs,omitempty"`
+}
+
+// BreakerConfig returns the circuit breaker config for the given breaker name
+func (s CircuitBreakerSettings) BreakerConfig(name string) CircuitBreakerConfig {
+	resetTimeout, _ := time.ParseDuration(s.ResetTimeout)
+
+	return CircuitBreakerConfig{
+		Name:            name,
+		MaxFailures:     s.MaxFailures,
+		ResetTimeout:    resetTimeout,
+		HalfOpenMaxReqs: s.HalfOpenMaxReqs,
+	}
+}
+
+// LogSettings configure the per-component log levels and the control plane log store
+type LogSettings struct {
+	Levels       map[string]string `json:"levels,omitempty"`       // Per-component levels overriding logLevel, e.g. {"raft": "warn"}
+	Persist      bool              `json:"persist,omitempty"`      // Ship logs to the control plane log store
+	PersistLevel string            `json:"persistLevel,omitempty"` // Minimum level shipped (default warn)
+	Retention    string            `json:"retention,omitempty"`    // How long the control plane keeps logs (default 168h)
+}
+
+// MetricsSettings bound the cardinality of the per-tenant Prometheus series.
+// Tenants outside the top N and the allowlist are reported as "_other".
+type MetricsSettings struct {
+	TenantTopN      int      `json:"tenantTopN,omitempty"`      // Busiest tenants per node with their own series (default 20, negative for none)
+	TenantAllowlist []string `json:"tenantAllowlist,omitempty"` // Tenants that always have their own series
+}
+
+// TracingSettings configure the export of request traces (disabled if no exporter)
+type TracingSettings struct {
+	Exporter    string  `json:"exporter,omitempty"`    // otlp or file
+	Endpoint    string  `json:"endpoint,omitempty"`    // OTLP/HTTP collector host:port or URL (default localhost:4318)
+	Insecure    bool    `json:"insecure,omitempty"`    // Plain HTTP to the collector
+	File        string  `json:"file,omitempty"`        // Path the file exporter appends to
+	SampleRatio float64 `json:"sampleRatio,omitempty"` // Share of new traces recorded (0 records all)
+
+	// Records the full SQL of query spans, with the bound parameter values
+	// (emails, password hashes, tokens...). Otherwise only the operation and table.
+	FullStatements bool `json:"fullStatements,omitempty"`
+}
+
+// Email providers of the cluster email service
+const (
+	EmailProviderLog      = "log"      // Logs emails instead of sending them (development)
+	EmailProviderSMTP     = "smtp"     // Any SMTP server
+	EmailProviderSendmail = "sendmail" // Local sendmail binary
+	EmailProviderSES      = "ses"      // Amazon SES v2 HTTP API
+	EmailProviderPostmark = "postmark" // Postmark HTTP API
+	EmailProviderSendGrid = "sendgrid" // SendGrid v3 HTTP API
+)
+
+// EmailSettings configure how the control plane sends cluster emails
+// (verification links and the like). Emails are only logged without a provider.
+type EmailSettings struct {
+	Provider    string `json:"provider,omitempty"`    // log, smtp, sendmail, ses, postmark or sendgrid
+	FromAddress string `json:"fromAddress,omitempty"` // Sender address
+	FromName    string `json:"fromName,omitempty"`    // Sender display name
+	BaseURL     string `json:"baseUrl,omitempty"`     // Public URL of the cluster API used in links (default: the request's host)
+
+	// SMTP provider
+	SMTPHost     string `json:"smtpHost,omitempty"`
+	SMTPPort     int    `json:"smtpPort,omitempty"` // Default 587
+	SMTPUsername string `json:"smtpUsername,omitempty"`
+	SMTPPassword string `json:"smtpPassword,omitempty"`
+	SMTPTLS      bool   `json:"smtpTls,omitempty"` // Implicit TLS (port 465), STARTTLS is used when offered otherwise
+
+	// HTTP API providers
+	APIKey          string `json:"apiKey,omitempty"`          // Postmark server token or SendGrid API key
+	Endpoint        string `json:"endpoint,omitempty"`        // API base URL override (stubs, EU regions)
+	Region          string `json:"region,omitempty"`          // SES region (default: s3Region)
+	AccessKeyID     string `json:"accessKeyId,omitempty"`     // SES credentials (default: the S3 credentials)
+	SecretAccessKey string `json:"secretAccessKey,omitempty"` // SES credentials (default: the S3 credentials)
+
+	// Delivery
+	MaxAttempts   int    `json:"maxAttempts,omitempty"`   // Attempts before a queued email fails (default 8)
+	WebhookSecret string `json:"webhookSecret,omitempty"` // Token of the bounce and complaint webhooks (disabled if empty)
+}
+
+// ReadSettings configure the tenant lookups of gateways and tenant nodes, which
+// are served by any control plane node rather than only the leader
+type ReadSettings struct {
+	MaxStaleness   string `json:"maxStaleness,omitempty"`   // How far behind the leader a control plane node may answer lookups (default 5s)
+	TenantCacheTTL string `json:"tenantCacheTtl,omitempty"` // How long gateways cache tenant lookups (default 2s)
+}
+
+// MaxStalenessDuration returns the max staleness of tenant lookups
+func (s ReadSettings) MaxStalenessDuration() time.Duration {
+	if d, err := time.ParseDuration(s.MaxStaleness); err == nil && d > 0 {
+		return d
+	}
+	return DefaultMaxStaleness
+}
+
+// TenantCacheTTLDuration returns how long gateways cache tenant lookups
+func (s ReadSettings) TenantCacheTTLDuration() time.Duration {
+	if d, err := time.ParseDuration(s.TenantCacheTTL); err == nil && d > 0 {
+		return d
+	}
+	return DefaultTenantCacheTTL
+}
+
+// SuspensionSettings configure what the gateway serves for suspended tenants
+type SuspensionSettings struct {
+	PageTemplate string `json:"pageTemplate,omitempty"` // html/template file served to browsers (default: a built-in page)
+	AppealURL    string `json:"appealUrl,omitempty"`    // Where owners appeal, linked from the page and the suspension email
+}
+
+// S3Target is an S3 bucket and how to reach it
+type S3Target struct {
+	Endpoint        string `json:"endpoint,omitempty"` // Custom endpoint (MinIO, LocalStack)
+	Region          string `json:"region,omitempty"`   // AWS region of the bucket
+	Bucket          string `json:"bucket,omitempty"`
+	AccessKeyID     string `json:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+}
+
+// withDefaults fills the empty settings of a target from defaults. A target
+// without a bucket is disabled and stays empty.
+func (t S3Target) withDefaults(defaults S3Target) S3Target {
+	if t.Bucket == "" {
+		return S3Target{}
+	}
+	if t.Endpoint == "" {
+		t.Endpoint = defaults.Endpoint
+	}
+	if t.Region == "" {
+		t.Region = defaults.Region
+	}
+	if t.AccessKeyID == "" && t.SecretAccessKey == "" {
+		t.AccessKeyID = defaults.AccessKeyID
+		t.SecretAccessKey = defaults.SecretAccessKey
+	}
+	return t
+}
+
+// RegionConfig is where the data of a region's tenants is stored
+type RegionConfig struct {
+	S3           S3Target `json:"s3"`
+	LitestreamDR S3Target `json:"litestreamDr"` // Second replica bucket for disaster recovery (disabled if no bucket)
+}
+
+// DefaultClusterConfig returns the config that a config file, the environment and
+// command line flags are applied on top of
+func DefaultClusterConfig() *ClusterConfig {
+	return &ClusterConfig{
+		RaftBindAddr:         "127.0.0.1:7000",
+		IPCBindAddr:          "0.0.0.0:8090",
+		MaxTenants:           200,
+		GatewayCacheMemoryMB: 256,
+		AuditRetention:       "8760h",
+		S3Region:             "us-east-1",
+		LitestreamEnabled:    true,
+		LitestreamRetention:  "72h",
+		LogLevel:             LogLevelInfo,
+	}
+}
+
+// LoadClusterConfig reads a YAML or JSON config file on top of DefaultClusterConfig.
+// Unknown fields and values of the wrong type are reported as *ConfigError.
+func LoadClusterConfig(path string) (*ClusterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// YAML files are converted to JSON so that both use the json field names
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid YAML in %s: %w", path, err)
+		}
+		if raw == nil {
+			raw = map[string]interface{}{}
+		}
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("invalid YAML in %s: %w", path, err)
+		}
+	}
+
+	config := DefaultClusterConfig()
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, configDecodeError(err)
+	}
+
+	return config, nil
+}
+
+// configDecodeError names the offending field of a JSON decoding error
+func configDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return NewConfigError(typeErr.Field, fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value))
+	}
+
+	// encoding/json has no typed error for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return NewConfigError(strings.Trim(field, `"`), "unknown field")
+	}
+
+	return fmt.Errorf("invalid config: %w", err)
+}
+
+// ApplyEnv overrides config fields from POCKETBASE_* environment variables.
+// Lists are comma separated; the per-tier quotas and the regions can only be set in
+// the config file.
+func (c *ClusterConfig) ApplyEnv(lookup func(key string) (string, bool)) error {
+	return applyConfigEnv(reflect.ValueOf(c).Elem(), "", lookup)
+}
+
+func applyConfigEnv(v reflect.Value, prefix string, lookup func(key string) (string, bool)) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := configFieldName(t.Field(i))
+		if name == "" {
+			continue
+		}
+
+		field := v.Field(i)
+		path := prefix + name
+
+		if field.Kind() == reflect.Struct {
+			if err := applyConfigEnv(field, path+".", lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := ConfigEnvKey(path)
+		value, ok := lookup(key)
+		if !ok {
+			continue
+		}
+
+		if err := setConfigField(field, value); err != nil {
+			return NewConfigError(path, fmt.Sprintf("invalid %s: %v", key, err))
+		}
+	}
+
+	return nil
+}
+
+// ConfigEnvKey returns the environment variable overriding a config field,
+// e.g. "disk.maxUsageBytes" -> "POCKETBASE_DISK_MAX_USAGE_BYTES"
+func ConfigEnvKey(path string) string {
+	var b strings.Builder
+	b.WriteString(ConfigEnvPrefix)
+
+	var prev rune
+	for _, r := range path {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+		case unicode.IsUpper(r) && prev != '.' && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		prev = r
+	}
+
+	return b.String()
+}
+
+// configFieldName returns the json name of a struct field ("" if not serialized)
+func configFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// setConfigField parses an environment value into a config field
+func setConfigField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("not settable from the environment")
+		}
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("not settable from the environment")
+	}
+	return nil
+}
+
+// Validate checks the config for the selected mode. The first problem found
+// is returned as *ConfigError naming the field.
+func (c *ClusterConfig) Validate() error {
+	if !IsValidMode(string(c.Mode)) {
+		return NewConfigError("mode", fmt.Sprintf("unknown mode %q", c.Mode))
+	}
+
+	switch c.Mode {
+	case ModeControlPlane:
+		if c.NodeID == "" {
+			return NewConfigError("nodeId", "required for control-plane mode")
+		}
+		if len(c.RaftPeers) == 0 {
+			return NewConfigError("raftPeers", "required for control-plane mode")
+		}
+		if c.bucket() == "" {
+			return NewConfigError("s3Bucket", "required")
+		}
+
+	case ModeTenantNode:
+		if len(c.ControlPlaneAddrs) == 0 {
+			return NewConfigError("controlPlaneAddrs", "required for tenant-node mode")
+		}
+		if c.bucket() == "" {
+			return NewConfigError("s3Bucket", "required")
+		}
+
+	case ModeGateway:
+		if len(c.GatewayControlPlaneAddrs) == 0 {
+			return NewConfigError("gatewayControlPlaneAddrs", "required for gateway mode")
+		}
+
+	case ModeAllInOne:
+		if c.bucket() == "" {
+			return NewConfigError("s3Bucket", "required")
+		}
+	}
+
+	if c.MaxTenants < 0 {
+		return NewConfigError("maxTenants", "must not be negative")
+	}
+
+	if c.MasterKeyFile != "" && c.IPCSecret == "" {
+		return NewConfigError("ipcSecret", "required with masterKeyFile")
+	}
+
+	if c.GatewayPeerAddr != "" && c.GatewayPeerSecret == "" {
+		return NewConfigError("gatewayPeerSecret", "required with gatewayPeerAddr")
+	}
+
+	if _, err := ParsePrefixes(c.GatewayTrustedProxies); err != nil {
+		return NewConfigError("gatewayTrustedProxies", err.Error())
+	}
+
+	if _, err := ParsePrefixes(c.APITrustedProxies); err != nil {
+		return NewConfigError("apiTrustedProxies", err.Error())
+	}
+
+	if err := c.validateRegions(); err != nil {
+		return err
+	}
+
+	if _, err := ParseLogLevel(c.LogLevel); err != nil {
+		return NewConfigError("logLevel", err.Error())
+	}
+	for component, level := range c.Logs.Levels {
+		if !IsLogComponent(component) {
+			return NewConfigError("logs.levels."+component, "unknown component")
+		}
+		if _, err := ParseLogLevel(level); err != nil {
+			return NewConfigError("logs.levels."+component, err.Error())
+		}
+	}
+	if _, err := ParseLogLevel(c.Logs.PersistLevel); err != nil {
+		return NewConfigError("logs.persistLevel", err.Error())
+	}
+
+	durations := map[string]string{
+		"auditRetention":              c.AuditRetention,
+		"litestreamRetention":         c.LitestreamRetention,
+		"archive.litestreamStopAfter": c.Archive.LitestreamStopAfter,
+		"archive.warmAfter":           c.Archive.WarmAfter,
+		"archive.coldAfter":           c.Archive.ColdAfter,
+		"circuitBreaker.resetTimeout": c.CircuitBreaker.ResetTimeout,
+		"logs.retention":              c.Logs.Retention,
+		"reads.maxStaleness":          c.Reads.MaxStaleness,
+		"reads.tenantCacheTtl":        c.Reads.TenantCacheTTL,
+	}
+	for field, value := range durations {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return NewConfigError(field, fmt.Sprintf("invalid duration %q", value))
+		}
+	}
+
+	if c.Disk.MaxUsageBytes < 0 {
+		return NewConfigError("disk.maxUsageBytes", "must not be negative")
+	}
+	for field, pct := range map[string]float64{
+		"disk.warningThresholdPct":  c.Disk.WarningThresholdPct,
+		"disk.criticalThresholdPct": c.Disk.CriticalThresholdPct,
+	} {
+		if pct < 0 || pct > 100 {
+			return NewConfigError(field, "must be between 0 and 100")
+		}
+	}
+	if c.Disk.WarningThresholdPct > 0 && c.Disk.CriticalThresholdPct > 0 && c.Disk.WarningThresholdPct >= c.Disk.CriticalThresholdPct {
+		return NewConfigError("disk.warningThresholdPct", "must be below disk.criticalThresholdPct")
+	}
+
+	if c.Archive.MaxPerRun < 0 {
+		return NewConfigError("archive.maxPerRun", "must not be negative")
+	}
+	switch c.Tracing.Exporter {
+	case "", TracingExporterOTLP:
+	case TracingExporterFile:
+		if c.Tracing.File == "" {
+			return NewConfigError("tracing.file", "required by the file exporter")
+		}
+	default:
+		return NewConfigError("tracing.exporter", "must be otlp or file")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return NewConfigError("tracing.sampleRatio", "must be between 0 and 1")
+	}
+
+	if err := c.validateEmail(); err != nil {
+		return err
+	}
+
+	switch c.Archive.GlacierStorageClass {
+	case "", "GLACIER", "DEEP_ARCHIVE":
+	default:
+		return NewConfigError("archive.glacierStorageClass", "must be GLACIER or DEEP_ARCHIVE")
+	}
+
+	if c.CircuitBreaker.MaxFailures < 0 {
+		return NewConfigError("circuitBreaker.maxFailures", "must not be negative")
+	}
+	if c.CircuitBreaker.HalfOpenMaxReqs < 0 {
+		return NewConfigError("circuitBreaker.halfOpenMaxReqs", "must not be negative")
+	}
+
+	for tier, quota := range c.Quotas {
+		field := "quotas." + string(tier)
+		if _, known := DefaultResourceQuotas[tier]; !known {
+			return NewConfigError(field, "unknown tier")
+		}
+		if quota == nil {
+			continue
+		}
+		if quota.MaxDatabaseMB < 0 || quota.MaxRequestsDaily < 0 || quota.MaxConcurrentConns < 0 ||
+			quota.MaxMemoryMB < 0 || quota.MaxCPUPercent < 0 || quota.MaxQueryTimeMs < 0 {
+			return NewConfigError(field, "limits must not be negative")
+		}
+	}
+
+	return nil
+}
+
+// validateEmail checks that the email provider has what it needs to send
+func (c *ClusterConfig) validateEmail() error {
+	e := c.Email
+
+	switch e.Provider {
+	case "", EmailProviderLog:
+		return nil
+	case EmailProviderSMTP:
+		if e.SMTPHost == "" {
+			return NewConfigError("email.smtpHost", "required by the smtp provider")
+		}
+	case EmailProviderPostmark, EmailProviderSendGrid:
+		if e.APIKey == "" {
+			return NewConfigError("email.apiKey", "required by the "+e.Provider+" provider")
+		}
+	case EmailProviderSendmail, EmailProviderSES:
+	default:
+		return NewConfigError("email.provider", "must be log, smtp, sendmail, ses, postmark or sendgrid")
+	}
+
+	if e.FromAddress == "" {
+		return NewConfigError("email.fromAddress", "required to send emails")
+	}
+	if e.MaxAttempts < 0 {
+		return NewConfigError("email.maxAttempts", "must not be negative")
+	}
+
+	return nil
+}
+
+// validateRegions checks that every region has a bucket and that DR replicas
+// don't point back at the primary bucket
+func (c *ClusterConfig) validateRegions() error {
+	if len(c.Regions) > 0 && c.Region == "" && c.Mode != ModeGateway {
+		return NewConfigError("region", "required when regions are configured")
+	}
+
+	if dr := c.LitestreamDR; dr.Bucket != "" && dr.Bucket == c.S3Bucket && dr.Endpoint == c.S3Endpoint {
+		return NewConfigError("litestreamDr.bucket", "must differ from s3Bucket")
+	}
+
+	if _, configured := c.Regions[""]; configured {
+		return NewConfigError("regions", "region names must not be empty")
+	}
+
+	for _, name := range c.RegionNames() {
+		region, configured := c.Regions[name]
+		if !configured {
+			continue
+		}
+
+		field := "regions." + name
+		if region == nil || region.S3.Bucket == "" {
+			return NewConfigError(field+".s3.bucket", "required")
+		}
+
+		storage, _ := c.RegionStorage(name)
+		if dr := storage.LitestreamDR; dr.Bucket == storage.S3.Bucket && dr.Endpoint == storage.S3.Endpoint {
+			return NewConfigError(field+".litestreamDr.bucket", "must differ from the region's bucket")
+		}
+	}
+
+	return nil
+}
+
+// RegionNames returns the regions tenants can be placed in, sorted by name
+func (c *ClusterConfig) RegionNames() []string {
+	names := make([]string, 0, len(c.Regions)+1)
+	for name := range c.Regions {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if _, configured := c.Regions[c.Region]; c.Region != "" && !configured {
+		names = append(names, c.Region)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// RegionStorage returns the storage of a region. Settings the region leaves empty
+// are taken from the top-level S3 settings, which are also the storage of c.Region
+// and of tenants without a region. ok is false for regions that aren't configured.
+func (c *ClusterConfig) RegionStorage(name string) (storage RegionConfig, ok bool) {
+	defaults := S3Target{
+		Endpoint:        c.S3Endpoint,
+		Region:          c.S3Region,
+		Bucket:          c.S3Bucket,
+		AccessKeyID:     c.S3AccessKeyID,
+		SecretAccessKey: c.S3SecretAccessKey,
+	}
+
+	region, configured := c.Regions[name]
+	if !configured || region == nil {
+		if configured || (name != "" && name != c.Region) {
+			return RegionConfig{}, false
+		}
+		return RegionConfig{
+			S3:           defaults,
+			LitestreamDR: c.LitestreamDR.withDefaults(defaults),
+		}, true
+	}
+
+	return RegionConfig{
+		S3:           region.S3.withDefaults(defaults),
+		LitestreamDR: region.LitestreamDR.withDefaults(defaults),
+	}, true
+}
+
+// bucket returns the bucket of this node's region
+func (c *ClusterConfig) bucket() string {
+	storage, _ := c.RegionStorage(c.Region)
+	return storage.S3.Bucket
+}
+
+// ReloadChanges compares a reloaded config with the current one and returns the
+// changed top-level fields, split into those applied at runtime and those that
+// only take effect after a restart
+func (c *ClusterConfig) ReloadChanges(next *ClusterConfig) (reloadable, restart []string) {
+	current := reflect.ValueOf(c).Elem()
+	updated := reflect.ValueOf(next).Elem()
+	t := current.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := configFieldName(t.Field(i))
+		if name == "" || reflect.DeepEqual(current.Field(i).Interface(), updated.Field(i).Interface()) {
+			continue
+		}
+
+		if reloadableConfigFields[name] {
+			reloadable = append(reloadable, name)
+		} else {
+			restart = append(restart, name)
+		}
+	}
+
+	return reloadable, restart
+}
+
+// ApplyReloadable copies the settings that can change at runtime from next
+func (c *ClusterConfig) ApplyReloadable(next *ClusterConfig) {
+	c.LogLevel = next.LogLevel
+	c.Quotas = next.Quotas
+	c.Disk = next.Disk
+	c.Archive = next.Archive
+	c.AuditRetention = next.AuditRetention
+	c.Logs = next.Logs
+	c.Metrics = next.Metrics
+	c.GatewayCacheMemoryMB = next.GatewayCacheMemoryMB
+	c.GatewayTrustedProxies = next.GatewayTrustedProxies
+	c.GatewayCountryHeader = next.GatewayCountryHeader
+}
+
+// ParseLogLevel parses a config log level ("" means info)
+func ParseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case LogLevelDebug:
+		return slog.LevelDebug, nil
+	case "", LogLevelInfo:
+		return slog.LevelInfo, nil
+	case LogLevelWarn:
+		return slog.LevelWarn, nil
+	case LogLevelError:
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q", level)
+}
+
+// MergeResourceQuotas overlays the non-zero limits of the configured per-tier
+// quotas on DefaultResourceQuotas
+func MergeResourceQuotas(overrides map[TenantTier]*ResourceQuota) map[TenantTier]*ResourceQuota {
+	quotas := make(map[TenantTier]*ResourceQuota, len(DefaultResourceQuotas))
+
+	for tier, defaults := range DefaultResourceQuotas {
+		quota := *defaults
+
+		if override := overrides[tier]; override != nil {
+			if override.MaxDatabaseMB > 0 {
+				quota.MaxDatabaseMB = override.MaxDatabaseMB
+			}
+			if override.MaxRequestsDaily > 0 {
+				quota.MaxRequestsDaily = override.MaxRequestsDaily
+			}
+			if override.MaxConcurrentConns > 0 {
+				quota.MaxConcurrentConns = override.MaxConcurrentConns
+			}
+			if override.MaxMemoryMB > 0 {
+				quota.MaxMemoryMB = override.MaxMemoryMB
+			}
+			if override.MaxCPUPercent > 0 {
+				quota.MaxCPUPercent = override.MaxCPUPercent
+			}
+			if override.MaxQueryTimeMs > 0 {
+				quota.MaxQueryTimeMs = override.MaxQueryTimeMs
+			}
+		}
+
+		quotas[tier] = &quota
+	}
+
+	return quotas
+}
@@ -0,0 +1,248 @@
+package enterprise
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadClusterConfigYAML(t *testing.T) {
+	path := writeConfigFile(t, "cluster.yaml", `
+mode: tenant-node
+controlPlaneAddrs: [cp-1:8090, cp-2:8090]
+s3Bucket: tenants
+litestreamRetention: 24h
+disk:
+  warningThresholdPct: 70
+archive:
+  warmAfter: 48h
+quotas:
+  small:
+    maxRequestsDaily: 20000
+`)
+
+	config, err := LoadClusterConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if config.Mode != ModeTenantNode || config.S3Bucket != "tenants" || len(config.ControlPlaneAddrs) != 2 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+	if config.LitestreamRetention != "24h" || config.Disk.WarningThresholdPct != 70 || config.Archive.WarmAfter != "48h" {
+		t.Errorf("expected file values, got %+v", config)
+	}
+
+	// Fields missing from the file keep their defaults
+	if config.MaxTenants != 200 || !config.LitestreamEnabled || config.S3Region != "us-east-1" {
+		t.Errorf("expected defaults for unset fields, got %+v", config)
+	}
+
+	quotas := MergeResourceQuotas(config.Quotas)
+	if quotas[TenantTierSmall].MaxRequestsDaily != 20000 {
+		t.Errorf("expected small tier override, got %d", quotas[TenantTierSmall].MaxRequestsDaily)
+	}
+	if quotas[TenantTierSmall].MaxDatabaseMB != DefaultResourceQuotas[TenantTierSmall].MaxDatabaseMB {
+		t.Error("expected unset quota limits to keep their defaults")
+	}
+}
+
+func TestLoadClusterConfigNamesBadField(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		field   string
+	}{
+		{"unknown field", "cluster.json", `{"mode": "gateway", "s3Bucekt": "x"}`, "s3Bucekt"},
+		{"wrong type", "cluster.json", `{"disk": {"maxUsageBytes": "lots"}}`, "disk.maxUsageBytes"},
+		{"wrong type in yaml", "cluster.yml", "maxTenants: many\n", "maxTenants"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadClusterConfig(writeConfigFile(t, tt.file, tt.content))
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("expected ConfigError, got %v", err)
+			}
+			if configErr.Field != tt.field {
+				t.Errorf("expected field %s, got %s", tt.field, configErr.Field)
+			}
+		})
+	}
+}
+
+func TestClusterConfigApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"POCKETBASE_S3_BUCKET":                 "from-env",
+		"POCKETBASE_CONTROL_PLANE_ADDRS":       "cp-1:8090, cp-2:8090",
+		"POCKETBASE_DISK_MAX_USAGE_BYTES":      "1024",
+		"POCKETBASE_LITESTREAM_REPLICATE_SYNC": "true",
+		"POCKETBASE_JWT_SECRET":                "secret",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	config := DefaultClusterConfig()
+	if err := config.ApplyEnv(lookup); err != nil {
+		t.Fatalf("failed to apply env: %v", err)
+	}
+
+	if config.S3Bucket != "from-env" || config.Disk.MaxUsageBytes != 1024 || !config.LitestreamReplicateSync || config.JWTSecret != "secret" {
+		t.Errorf("expected env overrides, got %+v", config)
+	}
+	if !reflect.DeepEqual(config.ControlPlaneAddrs, []string{"cp-1:8090", "cp-2:8090"}) {
+		t.Errorf("expected comma separated addresses, got %v", config.ControlPlaneAddrs)
+	}
+
+	env["POCKETBASE_MAX_TENANTS"] = "lots"
+	var configErr *ConfigError
+	if err := config.ApplyEnv(lookup); !errors.As(err, &configErr) || configErr.Field != "maxTenants" {
+		t.Errorf("expected maxTenants config error, got %v", err)
+	}
+}
+
+func TestConfigEnvKey(t *testing.T) {
+	tests := map[string]string{
+		"s3AccessKeyId":               "POCKETBASE_S3_ACCESS_KEY_ID",
+		"gatewayCacheMemoryMb":        "POCKETBASE_GATEWAY_CACHE_MEMORY_MB",
+		"jwtSecret":                   "POCKETBASE_JWT_SECRET",
+		"circuitBreaker.resetTimeout": "POCKETBASE_CIRCUIT_BREAKER_RESET_TIMEOUT",
+		"litestreamDr.bucket":         "POCKETBASE_LITESTREAM_DR_BUCKET",
+	}
+
+	for path, expected := range tests {
+		if got := ConfigEnvKey(path); got != expected {
+			t.Errorf("ConfigEnvKey(%s) = %s, expected %s", path, got, expected)
+		}
+	}
+}
+
+func TestClusterConfigValidate(t *testing.T) {
+	valid := func() *ClusterConfig {
+		config := DefaultClusterConfig()
+		config.Mode = ModeAllInOne
+		config.S3Bucket = "tenants"
+		return config
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	tests := []struct {
+		field  string
+		modify func(c *ClusterConfig)
+	}{
+		{"mode", func(c *ClusterConfig) { c.Mode = "cluster" }},
+		{"s3Bucket", func(c *ClusterConfig) { c.S3Bucket = "" }},
+		{"nodeId", func(c *ClusterConfig) { c.Mode = ModeControlPlane }},
+		{"logLevel", func(c *ClusterConfig) { c.LogLevel = "verbose" }},
+		{"auditRetention", func(c *ClusterConfig) { c.AuditRetention = "forever" }},
+		{"archive.coldAfter", func(c *ClusterConfig) { c.Archive.ColdAfter = "-1h" }},
+		{"disk.warningThresholdPct", func(c *ClusterConfig) { c.Disk.WarningThresholdPct, c.Disk.CriticalThresholdPct = 95, 90 }},
+		{"quotas.huge", func(c *ClusterConfig) { c.Quotas = map[TenantTier]*ResourceQuota{"huge": {}} }},
+		{"region", func(c *ClusterConfig) { c.Regions = map[string]*RegionConfig{"eu-west": {S3: S3Target{Bucket: "eu"}}} }},
+		{"regions.eu-west.s3.bucket", func(c *ClusterConfig) { c.Region, c.Regions = "us-east", map[string]*RegionConfig{"eu-west": {}} }},
+		{"litestreamDr.bucket", func(c *ClusterConfig) { c.LitestreamDR.Bucket = c.S3Bucket }},
+		{"tracing.exporter", func(c *ClusterConfig) { c.Tracing.Exporter = "jaeger" }},
+		{"tracing.file", func(c *ClusterConfig) { c.Tracing.Exporter = TracingExporterFile }},
+		{"tracing.sampleRatio", func(c *ClusterConfig) { c.Tracing.SampleRatio = 1.5 }},
+		{"email.provider", func(c *ClusterConfig) { c.Email.Provider = "mailgun" }},
+		{"email.apiKey", func(c *ClusterConfig) { c.Email.Provider = EmailProviderPostmark }},
+		{"email.fromAddress", func(c *ClusterConfig) { c.Email.Provider = EmailProviderSendmail }},
+		{"logs.levels.scheduler", func(c *ClusterConfig) { c.Logs.Levels = map[string]string{"scheduler": LogLevelDebug} }},
+		{"logs.levels.raft", func(c *ClusterConfig) { c.Logs.Levels = map[string]string{LogComponentRaft: "trace"} }},
+		{"logs.persistLevel", func(c *ClusterConfig) { c.Logs.PersistLevel = "all" }},
+		{"logs.retention", func(c *ClusterConfig) { c.Logs.Retention = "a week" }},
+		{"gatewayTrustedProxies", func(c *ClusterConfig) { c.GatewayTrustedProxies = []string{"10.0.0.0/33"} }},
+		{"apiTrustedProxies", func(c *ClusterConfig) { c.APITrustedProxies = []string{"localhost"} }},
+		{"gatewayPeerSecret", func(c *ClusterConfig) { c.GatewayPeerAddr = "10.0.1.5:8091" }},
+		{"ipcSecret", func(c *ClusterConfig) { c.MasterKeyFile = "/etc/pocketbase/master.key" }},
+		{"reads.maxStaleness", func(c *ClusterConfig) { c.Reads.MaxStaleness = "-5s" }},
+	}
+
+	for _, tt := range tests {
+		config := valid()
+		tt.modify(config)
+
+		var configErr *ConfigError
+		if err := config.Validate(); !errors.As(err, &configErr) || configErr.Field != tt.field {
+			t.Errorf("expected error for %s, got %v", tt.field, err)
+		}
+	}
+}
+
+func TestClusterConfigReloadChanges(t *testing.T) {
+	current := DefaultClusterConfig()
+	next := DefaultClusterConfig()
+	next.LogLevel = LogLevelDebug
+	next.Archive.WarmAfter = "24h"
+	next.S3Bucket = "other"
+
+	reloadable, restart := current.ReloadChanges(next)
+	if !reflect.DeepEqual(reloadable, []string{"logLevel", "archive"}) {
+		t.Errorf("expected logLevel and archive to reload, got %v", reloadable)
+	}
+	if !reflect.DeepEqual(restart, []string{"s3Bucket"}) {
+		t.Errorf("expected s3Bucket to need a restart, got %v", restart)
+	}
+
+	current.ApplyReloadable(next)
+	if current.LogLevel != LogLevelDebug || current.Archive.WarmAfter != "24h" || current.S3Bucket == "other" {
+		t.Errorf("expected only reloadable fields applied, got %+v", current)
+	}
+}
+
+func TestClusterConfigRegionStorage(t *testing.T) {
+	config := DefaultClusterConfig()
+	config.Region = "us-east"
+	config.S3Bucket = "tenants-us"
+	config.S3AccessKeyID = "key"
+	config.S3SecretAccessKey = "secret"
+	config.Regions = map[string]*RegionConfig{
+		"eu-west": {
+			S3:           S3Target{Region: "eu-west-1", Bucket: "tenants-eu"},
+			LitestreamDR: S3Target{Region: "eu-central-1", Bucket: "tenants-eu-dr"},
+		},
+	}
+
+	if names := config.RegionNames(); !reflect.DeepEqual(names, []string{"eu-west", "us-east"}) {
+		t.Errorf("expected both regions, got %v", names)
+	}
+
+	// The node's own region uses the top-level settings
+	us, ok := config.RegionStorage("us-east")
+	if !ok || us.S3.Bucket != "tenants-us" || us.LitestreamDR.Bucket != "" {
+		t.Errorf("expected top-level bucket without DR for us-east, got %+v", us)
+	}
+
+	// Unset settings of a region fall back to the top-level ones
+	eu, ok := config.RegionStorage("eu-west")
+	if !ok || eu.S3.Bucket != "tenants-eu" || eu.S3.Region != "eu-west-1" || eu.S3.AccessKeyID != "key" {
+		t.Errorf("expected eu-west bucket with top-level credentials, got %+v", eu.S3)
+	}
+	if eu.LitestreamDR.Bucket != "tenants-eu-dr" || eu.LitestreamDR.SecretAccessKey != "secret" {
+		t.Errorf("expected eu-west DR bucket, got %+v", eu.LitestreamDR)
+	}
+
+	if _, ok := config.RegionStorage("ap-south"); ok {
+		t.Error("expected unknown region to be rejected")
+	}
+}
@@ -0,0 +1,72 @@
+package enterprise
+
+import (
+	"context"
+	"time"
+)
+
+// ReadConsistency is how fresh a control plane read must be
+type ReadConsistency string
+
+const (
+	// ReadLinearizable reads go through the Raft leader, which confirms its
+	// leadership with a quorum (or holds a read lease) and has applied every
+	// committed write before answering
+	ReadLinearizable ReadConsistency = "linearizable"
+
+	// ReadStale reads are answered from the local store of any control plane
+	// node that heard from the leader within the max staleness
+	ReadStale ReadConsistency = "stale"
+)
+
+// Default read settings
+const (
+	DefaultMaxStaleness   = 5 * time.Second
+	DefaultTenantCacheTTL = 2 * time.Second
+)
+
+// ReadOptions select the consistency of a control plane read
+type ReadOptions struct {
+	Consistency  ReadConsistency
+	MaxStaleness time.Duration // How far behind the leader a stale read may be (DefaultMaxStaleness if zero)
+}
+
+// Valid reports whether c is a known consistency level
+func (c ReadConsistency) Valid() bool {
+	return c == ReadLinearizable || c == ReadStale
+}
+
+type readOptionsKey struct{}
+
+// WithReadOptions returns ctx with the consistency of the control plane reads made with it
+func WithReadOptions(ctx context.Context, opts ReadOptions) context.Context {
+	return context.WithValue(ctx, readOptionsKey{}, opts)
+}
+
+// WithStaleReads returns ctx for reads that may be served by any control plane
+// node up to maxStaleness behind the leader
+func WithStaleReads(ctx context.Context, maxStaleness time.Duration) context.Context {
+	return WithReadOptions(ctx, ReadOptions{Consistency: ReadStale, MaxStaleness: maxStaleness})
+}
+
+// ReadOptionsFromContext returns the read options of ctx. Reads are
+// linearizable unless the caller asked for less.
+func ReadOptionsFromContext(ctx context.Context) ReadOptions {
+	opts, _ := ctx.Value(readOptionsKey{}).(ReadOptions)
+	return opts.WithDefaults()
+}
+
+// WithDefaults returns the options with unknown levels read linearizably and
+// the default max staleness of stale reads filled in
+func (o ReadOptions) WithDefaults() ReadOptions {
+	if !o.Consistency.Valid() {
+		o.Consistency = ReadLinearizable
+	}
+	switch {
+	case o.Consistency == ReadLinearizable:
+		o.MaxStaleness = 0
+	case o.MaxStaleness <= 0:
+		o.MaxStaleness = DefaultMaxStaleness
+	}
+	return o
+}
@@ -0,0 +1,137 @@
+package control_plane
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// MaxAPIKeysPerUser caps the API keys of a cluster user
+const MaxAPIKeysPerUser = 20
+
+// CreateAPIKey creates an API key for a user. It returns the key itself
+// along with the stored key: only its hash is kept, so it can't be shown again.
+func (cp *ControlPlane) CreateAPIKey(userID, name string, scopes []string, expiresAt *time.Time) (*enterprise.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return nil, "", fmt.Errorf("%w: the name must be 1 to 100 characters", enterprise.ErrInvalidAPIKey)
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", enterprise.ErrInvalidAPIKey)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(enterprise.APIKeyScopes, scope) {
+			return nil, "", fmt.Errorf("%w: unknown scope %q, available scopes: %s", enterprise.ErrInvalidAPIKey, scope, strings.Join(enterprise.APIKeyScopes, ", "))
+		}
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: the expiry date must be in the future", enterprise.ErrInvalidAPIKey)
+	}
+
+	cp.apiKeysMu.Lock()
+	defer cp.apiKeysMu.Unlock()
+
+	if _, err := cp.storage.GetUser(userID); err != nil {
+		return nil, "", err
+	}
+
+	keys, err := cp.storage.ListAPIKeys(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(keys) >= MaxAPIKeysPerUser {
+		return nil, "", fmt.Errorf("%w: a user can have at most %d", enterprise.ErrTooManyAPIKeys, MaxAPIKeysPerUser)
+	}
+	for _, key := range keys {
+		if key.Name == name {
+			return nil, "", fmt.Errorf("%w: an api key named %q already exists", enterprise.ErrInvalidAPIKey, name)
+		}
+	}
+
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+
+	secret := enterprise.GenerateAPIKey()
+	key := &enterprise.APIKey{
+		ID:        enterprise.GenerateID("apikey"),
+		UserID:    userID,
+		Name:      name,
+		Hash:      enterprise.HashAPIKey(secret),
+		Hint:      secret[:len(enterprise.APIKeyPrefix)+6],
+		Scopes:    slices.Compact(scopes),
+		ExpiresAt: expiresAt,
+		Created:   time.Now(),
+	}
+
+	if err := cp.storage.SaveAPIKey(key); err != nil {
+		return nil, "", fmt.Errorf("failed to save api key: %w", err)
+	}
+
+	cp.logger.Info("Created API key", "userId", userID, "keyId", key.ID, "scopes", key.Scopes)
+	return key, secret, nil
+}
+
+// ListAPIKeys lists the API keys of a user
+func (cp *ControlPlane) ListAPIKeys(userID string) ([]*enterprise.APIKey, error) {
+	keys, err := cp.storage.ListAPIKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(keys, func(a, b *enterprise.APIKey) int {
+		return a.Created.Compare(b.Created)
+	})
+	return keys, nil
+}
+
+// RevokeAPIKey deletes an API key of a user, which is rejected from then on.
+// Keys of other users are reported as not found.
+func (cp *ControlPlane) RevokeAPIKey(userID, keyID string) (*enterprise.APIKey, error) {
+	key, err := cp.storage.GetAPIKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.UserID != userID {
+		return nil, enterprise.ErrAPIKeyNotFound
+	}
+
+	if err := cp.storage.DeleteAPIKey(keyID); err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	cp.logger.Info("Revoked API key", "userId", userID, "keyId", keyID)
+	return key, nil
+}
+
+// AuthenticateAPIKey returns the API key and its user for a key presented
+// by a client. Unknown keys fail with ErrInvalidToken, expired ones with
+// ErrTokenExpired.
+func (cp *ControlPlane) AuthenticateAPIKey(secret string) (*enterprise.APIKey, *enterprise.ClusterUser, error) {
+	if !strings.HasPrefix(secret, enterprise.APIKeyPrefix) {
+		return nil, nil, enterprise.ErrInvalidToken
+	}
+
+	key, err := cp.storage.GetAPIKeyByHash(enterprise.HashAPIKey(secret))
+	if err == enterprise.ErrAPIKeyNotFound {
+		return nil, nil, enterprise.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, nil, enterprise.ErrTokenExpired
+	}
+
+	user, err := cp.storage.GetUser(key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, user, nil
+}
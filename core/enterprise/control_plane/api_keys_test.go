@@ -0,0 +1,205 @@
+package control_plane
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestCreateAPIKeyStoresOnlyTheHash(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	key, secret, err := cp.CreateAPIKey("user-1", " ci ", []string{enterprise.APIKeyScopeTenantsWrite, enterprise.APIKeyScopeTenantsRead}, nil)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+
+	if !strings.HasPrefix(secret, enterprise.APIKeyPrefix) || !strings.HasPrefix(secret, key.Hint) {
+		t.Errorf("unexpected key %q with hint %q", secret, key.Hint)
+	}
+	if key.Name != "ci" {
+		t.Errorf("expected the trimmed name, got %q", key.Name)
+	}
+	if strings.Join(key.Scopes, ",") != "tenants:read,tenants:write" {
+		t.Errorf("expected sorted scopes, got %v", key.Scopes)
+	}
+
+	stored, err := cp.storage.GetAPIKey(key.ID)
+	if err != nil {
+		t.Fatalf("failed to get api key: %v", err)
+	}
+	if stored.Hash != enterprise.HashAPIKey(secret) || strings.Contains(stored.Hash, secret) {
+		t.Errorf("expected only the key hash to be stored, got %q", stored.Hash)
+	}
+
+	authKey, user, err := cp.AuthenticateAPIKey(secret)
+	if err != nil {
+		t.Fatalf("failed to authenticate api key: %v", err)
+	}
+	if authKey.ID != key.ID || user.ID != "user-1" {
+		t.Errorf("expected key %s of user-1, got %s of %s", key.ID, authKey.ID, user.ID)
+	}
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	past := time.Now().Add(-time.Minute)
+
+	scenarios := []struct {
+		name      string
+		keyName   string
+		scopes    []string
+		expiresAt *time.Time
+	}{
+		{"empty name", " ", []string{enterprise.APIKeyScopeTenantsRead}, nil},
+		{"no scopes", "ci", nil, nil},
+		{"unknown scope", "ci", []string{"tenants:*"}, nil},
+		{"expired", "ci", []string{enterprise.APIKeyScopeTenantsRead}, &past},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			_, _, err := cp.CreateAPIKey("user-1", s.keyName, s.scopes, s.expiresAt)
+			if !errors.Is(err, enterprise.ErrInvalidAPIKey) {
+				t.Errorf("expected ErrInvalidAPIKey, got %v", err)
+			}
+		})
+	}
+
+	if _, _, err := cp.CreateAPIKey("user-1", "ci", []string{enterprise.APIKeyScopeLogsRead}, nil); err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	if _, _, err := cp.CreateAPIKey("user-1", "ci", []string{enterprise.APIKeyScopeLogsRead}, nil); !errors.Is(err, enterprise.ErrInvalidAPIKey) {
+		t.Errorf("expected a duplicate name to be rejected, got %v", err)
+	}
+	if _, _, err := cp.CreateAPIKey("missing", "ci", []string{enterprise.APIKeyScopeLogsRead}, nil); !errors.Is(err, enterprise.ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestCreateAPIKeyLimit(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	for i := 0; i < MaxAPIKeysPerUser; i++ {
+		name := "key-" + string(rune('a'+i))
+		if _, _, err := cp.CreateAPIKey("user-1", name, []string{enterprise.APIKeyScopeTenantsRead}, nil); err != nil {
+			t.Fatalf("failed to create api key %d: %v", i, err)
+		}
+	}
+
+	if _, _, err := cp.CreateAPIKey("user-1", "one-too-many", []string{enterprise.APIKeyScopeTenantsRead}, nil); !errors.Is(err, enterprise.ErrTooManyAPIKeys) {
+		t.Errorf("expected ErrTooManyAPIKeys, got %v", err)
+	}
+}
+
+func TestCreateAPIKeyConcurrently(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	// Twice the limit, every name requested twice
+	var wg sync.WaitGroup
+	for i := 0; i < 2*MaxAPIKeysPerUser; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := "key-" + string(rune('a'+i%(MaxAPIKeysPerUser+5)))
+			cp.CreateAPIKey("user-1", name, []string{enterprise.APIKeyScopeTenantsRead}, nil)
+		}()
+	}
+	wg.Wait()
+
+	keys, err := cp.ListAPIKeys("user-1")
+	if err != nil {
+		t.Fatalf("failed to list api keys: %v", err)
+	}
+	if len(keys) != MaxAPIKeysPerUser {
+		t.Errorf("expected %d api keys, got %d", MaxAPIKeysPerUser, len(keys))
+	}
+
+	names := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if names[key.Name] {
+			t.Errorf("expected unique names, %q was created twice", key.Name)
+		}
+		names[key.Name] = true
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+	createTestUser(t, cp, "user-2")
+
+	key, secret, err := cp.CreateAPIKey("user-1", "ci", []string{enterprise.APIKeyScopeTenantsRead}, nil)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	if _, _, err := cp.CreateAPIKey("user-2", "ci", []string{enterprise.APIKeyScopeTenantsRead}, nil); err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+
+	if _, err := cp.RevokeAPIKey("user-2", key.ID); !errors.Is(err, enterprise.ErrAPIKeyNotFound) {
+		t.Fatalf("expected another user's key to be not found, got %v", err)
+	}
+
+	if _, err := cp.RevokeAPIKey("user-1", key.ID); err != nil {
+		t.Fatalf("failed to revoke api key: %v", err)
+	}
+
+	if _, _, err := cp.AuthenticateAPIKey(secret); !errors.Is(err, enterprise.ErrInvalidToken) {
+		t.Errorf("expected a revoked key to be rejected, got %v", err)
+	}
+
+	keys, err := cp.ListAPIKeys("user-1")
+	if err != nil {
+		t.Fatalf("failed to list api keys: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("expected no keys left, got %d", len(keys))
+	}
+
+	keys, err = cp.ListAPIKeys("user-2")
+	if err != nil {
+		t.Fatalf("failed to list api keys: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Errorf("expected the key of user-2 to be kept, got %d", len(keys))
+	}
+}
+
+func TestAuthenticateAPIKeyRejectsInvalidKeys(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	expiresAt := time.Now().Add(time.Hour)
+	key, secret, err := cp.CreateAPIKey("user-1", "ci", []string{enterprise.APIKeyScopeTenantsRead}, &expiresAt)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+
+	if _, _, err := cp.AuthenticateAPIKey(secret + "x"); !errors.Is(err, enterprise.ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for an unknown key, got %v", err)
+	}
+	if _, _, err := cp.AuthenticateAPIKey(strings.TrimPrefix(secret, enterprise.APIKeyPrefix)); !errors.Is(err, enterprise.ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken without the key prefix, got %v", err)
+	}
+
+	// expire the key
+	expired := time.Now().Add(-time.Second)
+	key.ExpiresAt = &expired
+	if err := cp.storage.SaveAPIKey(key); err != nil {
+		t.Fatalf("failed to save api key: %v", err)
+	}
+
+	if _, _, err := cp.AuthenticateAPIKey(secret); !errors.Is(err, enterprise.ErrTokenExpired) {
+		t.Errorf("expected ErrTokenExpired, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package control_plane
+
+import (
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+const (
+	// defaultAuditRetention is how long audit entries are kept when no retention is configured
+	defaultAuditRetention = 365 * 24 * time.Hour
+
+	// auditPruneInterval is how often entries past the retention are removed
+	auditPruneInterval = 1 * time.Hour
+)
+
+// RecordAudit appends an entry to the cluster-wide audit log
+func (cp *ControlPlane) RecordAudit(entry *enterprise.AuditEntry) error {
+	if entry.ID == "" {
+		entry.ID = enterprise.GenerateID("audit")
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	return cp.storage.AppendAuditEntry(entry)
+}
+
+// ListAuditEntries returns the audit entries matching the filter, newest first
+func (cp *ControlPlane) ListAuditEntries(filter *enterprise.AuditFilter) ([]*enterprise.AuditEntry, error) {
+	return cp.storage.ListAuditEntries(filter)
+}
+
+// ExportAuditEntries calls fn for every audit entry matching the filter, oldest first
+func (cp *ControlPlane) ExportAuditEntries(filter *enterprise.AuditFilter, fn func(*enterprise.AuditEntry) error) error {
+	return cp.storage.ExportAuditEntries(filter, fn)
+}
+
+// auditRetention returns the configured audit retention
+func (cp *ControlPlane) auditRetention() time.Duration {
+	cp.configMu.RLock()
+	configured := cp.config.AuditRetention
+	cp.configMu.RUnlock()
+
+	if configured == "" {
+		return defaultAuditRetention
+	}
+
+	retention, err := time.ParseDuration(configured)
+	if err != nil || retention <= 0 {
+		cp.logger.Warn("Invalid audit retention, using the default", "configured", configured, "default", defaultAuditRetention)
+		return defaultAuditRetention
+	}
+
+	return retention
+}
+
+// pruneAuditLog periodically removes audit entries older than the retention
+func (cp *ControlPlane) pruneAuditLog() {
+	defer cp.wg.Done()
+
+	ticker := time.NewTicker(auditPruneInterval)
+	defer ticker.Stop()
+
+	retention := cp.auditRetention()
+
+	for {
+		select {
+		case <-cp.ctx.Done():
+			return
+		case <-ticker.C:
+			// Only leader should prune, the cutoff is replicated to followers
+			if cp.raft != nil && !cp.raft.IsLeader() {
+				continue
+			}
+
+			if err := cp.storage.PruneAuditLog(time.Now().Add(-retention)); err != nil {
+				cp.logger.Error("Failed to prune audit log", "error", err)
+			}
+		}
+	}
+}
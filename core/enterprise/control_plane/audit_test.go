@@ -0,0 +1,97 @@
+package control_plane
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestAuditLogListExportAndPrune(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	actions := []string{
+		enterprise.AuditActionUserImpersonate,
+		enterprise.AuditActionTenantArchive,
+		enterprise.AuditActionTenantRestore,
+		enterprise.AuditActionTenantArchive,
+	}
+
+	for i, action := range actions {
+		err := cp.RecordAudit(&enterprise.AuditEntry{
+			Time:      start.Add(time.Duration(i) * time.Hour),
+			Actor:     "admin:ops",
+			Action:    action,
+			Target:    "tenant-1",
+			SourceIP:  "10.0.0.1",
+			RequestID: "req-1",
+		})
+		if err != nil {
+			t.Fatalf("failed to record audit entry: %v", err)
+		}
+	}
+
+	// Listing is newest first
+	entries, err := cp.ListAuditEntries(&enterprise.AuditFilter{Action: enterprise.AuditActionTenantArchive})
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %v", err)
+	}
+	if len(entries) != 2 || !entries[0].Time.Equal(start.Add(3*time.Hour)) {
+		t.Fatalf("expected the 2 archive entries newest first, got %+v", entries)
+	}
+	if entries[0].ID == "" {
+		t.Error("expected entry ID to be generated")
+	}
+
+	entries, err = cp.ListAuditEntries(&enterprise.AuditFilter{Until: start.Add(2 * time.Hour), Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != enterprise.AuditActionTenantArchive {
+		t.Fatalf("expected the latest entry before the until time, got %+v", entries)
+	}
+
+	// Exports are oldest first
+	var exported []*enterprise.AuditEntry
+	err = cp.ExportAuditEntries(&enterprise.AuditFilter{Since: start.Add(time.Hour)}, func(entry *enterprise.AuditEntry) error {
+		exported = append(exported, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to export audit entries: %v", err)
+	}
+	if len(exported) != 3 || !exported[0].Time.Equal(start.Add(time.Hour)) {
+		t.Fatalf("expected 3 entries oldest first, got %+v", exported)
+	}
+
+	if err := cp.storage.PruneAuditLog(start.Add(2 * time.Hour)); err != nil {
+		t.Fatalf("failed to prune audit log: %v", err)
+	}
+
+	entries, err = cp.ListAuditEntries(&enterprise.AuditFilter{})
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 entries left after pruning, got %d", len(entries))
+	}
+}
+
+func TestAuditRetention(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	if got := cp.auditRetention(); got != defaultAuditRetention {
+		t.Errorf("expected default retention, got %s", got)
+	}
+
+	cp.config.AuditRetention = "720h"
+	if got := cp.auditRetention(); got != 720*time.Hour {
+		t.Errorf("expected 720h retention, got %s", got)
+	}
+
+	cp.config.AuditRetention = "forever"
+	if got := cp.auditRetention(); got != defaultAuditRetention {
+		t.Errorf("expected invalid retention to fall back to default, got %s", got)
+	}
+}
@@ -2,20 +2,21 @@ package badger
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
 	badger "github.com/dgraph-io/badger/v4"
+	"github.com/pocketbase/pocketbase/core/enterprise"
 )
 
 // DiskManager handles disk space monitoring and management for BadgerDB
 type DiskManager struct {
 	db     *badger.DB
 	config *DiskConfig
-	logger *log.Logger
+	logger *slog.Logger
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -74,7 +75,7 @@ func NewDiskManager(db *badger.DB, config *DiskConfig) *DiskManager {
 	return &DiskManager{
 		db:     db,
 		config: config,
-		logger: log.Default(),
+		logger: enterprise.ComponentLogger(enterprise.LogComponentStorage),
 		ctx:    ctx,
 		cancel: cancel,
 	}
@@ -82,10 +83,8 @@ func NewDiskManager(db *badger.DB, config *DiskConfig) *DiskManager {
 
 // Start starts the disk manager background tasks
 func (dm *DiskManager) Start() {
-	dm.logger.Printf("[DiskManager] Starting disk manager")
-	dm.logger.Printf("[DiskManager] Max disk usage: %d bytes (%.2f GB)",
-		dm.config.MaxDiskUsageBytes,
-		float64(dm.config.MaxDiskUsageBytes)/(1024*1024*1024))
+	dm.logger.Info("Starting disk manager")
+	dm.logger.Info("Max disk usage", "bytes", dm.config.MaxDiskUsageBytes)
 
 	// Start background tasks
 	dm.wg.Add(3)
@@ -96,7 +95,7 @@ func (dm *DiskManager) Start() {
 
 // Stop stops the disk manager
 func (dm *DiskManager) Stop() {
-	dm.logger.Printf("[DiskManager] Stopping disk manager")
+	dm.logger.Info("Stopping disk manager")
 	dm.cancel()
 	dm.wg.Wait()
 }
@@ -121,7 +120,7 @@ func (dm *DiskManager) runGarbageCollection() {
 // runGC executes garbage collection
 func (dm *DiskManager) runGC() {
 	start := time.Now()
-	dm.logger.Printf("[DiskManager] Starting garbage collection")
+	dm.logger.Info("Starting garbage collection")
 
 	// Run GC until no more rewriting is possible
 	var err error
@@ -136,9 +135,9 @@ func (dm *DiskManager) runGC() {
 
 	// ErrNoRewrite is expected when GC is complete
 	if err != nil && err != badger.ErrNoRewrite {
-		dm.logger.Printf("[DiskManager] GC error: %v", err)
+		dm.logger.Error("GC error", "error", err)
 	} else {
-		dm.logger.Printf("[DiskManager] GC completed in %v (%d rewrites)", time.Since(start), gcCount)
+		dm.logger.Info("GC completed", "duration", time.Since(start), "gcCount", gcCount)
 	}
 
 	dm.mu.Lock()
@@ -174,48 +173,47 @@ func (dm *DiskManager) checkDiskUsage() {
 	dm.mu.Unlock()
 
 	// Calculate usage percentage
-	if dm.config.MaxDiskUsageBytes > 0 {
-		usagePct := (float64(totalSize) / float64(dm.config.MaxDiskUsageBytes)) * 100
+	maxBytes, warningPct, criticalPct := dm.thresholds()
+	if maxBytes > 0 {
+		usagePct := (float64(totalSize) / float64(maxBytes)) * 100
 
-		if usagePct >= dm.config.CriticalThresholdPct {
-			dm.logger.Printf("[DiskManager] CRITICAL: Disk usage at %.2f%% (%d bytes / %d bytes)",
-				usagePct, totalSize, dm.config.MaxDiskUsageBytes)
+		if usagePct >= criticalPct {
+			dm.logger.Error("Disk usage critical", "usagePct", usagePct, "totalSize", totalSize, "maxBytes", maxBytes)
 			dm.handleCriticalDiskUsage()
-		} else if usagePct >= dm.config.WarningThresholdPct {
-			dm.logger.Printf("[DiskManager] WARNING: Disk usage at %.2f%% (%d bytes / %d bytes)",
-				usagePct, totalSize, dm.config.MaxDiskUsageBytes)
+		} else if usagePct >= warningPct {
+			dm.logger.Warn("Disk usage high", "usagePct", usagePct, "totalSize", totalSize, "maxBytes", maxBytes)
 		}
 	}
 
 	// Log disk usage periodically
-	dm.logger.Printf("[DiskManager] Disk usage: LSM=%d bytes, VLog=%d bytes, Total=%d bytes",
-		lsm, vlog, totalSize)
+	dm.logger.Debug("Disk usage", "lsm", lsm, "vlog", vlog, "totalSize", totalSize)
 }
 
 // handleCriticalDiskUsage handles critical disk usage scenarios
 func (dm *DiskManager) handleCriticalDiskUsage() {
-	dm.logger.Printf("[DiskManager] Attempting emergency cleanup")
+	dm.logger.Warn("Attempting emergency cleanup")
 
 	// Run aggressive GC
-	dm.logger.Printf("[DiskManager] Running emergency GC")
+	dm.logger.Info("Running emergency GC")
 	dm.runGC()
 
 	// Force compaction
-	dm.logger.Printf("[DiskManager] Running emergency compaction")
+	dm.logger.Info("Running emergency compaction")
 	dm.runCompactionNow()
 
 	// Re-check usage
 	lsm, vlog := dm.db.Size()
 	totalSize := lsm + vlog
 
-	if dm.config.MaxDiskUsageBytes > 0 {
-		usagePct := (float64(totalSize) / float64(dm.config.MaxDiskUsageBytes)) * 100
+	maxBytes, _, criticalPct := dm.thresholds()
+	if maxBytes > 0 {
+		usagePct := (float64(totalSize) / float64(maxBytes)) * 100
 
-		if usagePct >= dm.config.CriticalThresholdPct {
-			dm.logger.Printf("[DiskManager] ALERT: Disk usage still critical after cleanup: %.2f%%", usagePct)
+		if usagePct >= criticalPct {
+			dm.logger.Error("Disk usage still critical after cleanup", "usagePct", usagePct)
 			// In production, this should trigger alerts (PagerDuty, Slack, etc.)
 		} else {
-			dm.logger.Printf("[DiskManager] Emergency cleanup successful. Usage now at %.2f%%", usagePct)
+			dm.logger.Info("Emergency cleanup successful", "usagePct", usagePct)
 		}
 	}
 }
@@ -240,14 +238,39 @@ func (dm *DiskManager) runCompaction() {
 // runCompactionNow triggers immediate compaction
 func (dm *DiskManager) runCompactionNow() {
 	start := time.Now()
-	dm.logger.Printf("[DiskManager] Starting database compaction")
+	dm.logger.Info("Starting database compaction")
 
 	// Flatten compacts the LSM tree
 	if err := dm.db.Flatten(4); err != nil {
-		dm.logger.Printf("[DiskManager] Compaction error: %v", err)
+		dm.logger.Error("Compaction error", "error", err)
 	} else {
-		dm.logger.Printf("[DiskManager] Compaction completed in %v", time.Since(start))
+		dm.logger.Info("Compaction completed", "duration", time.Since(start))
+	}
+}
+
+// SetThresholds changes the disk usage limit and alert thresholds at runtime
+// (zero values keep the current setting)
+func (dm *DiskManager) SetThresholds(maxDiskUsageBytes int64, warningPct, criticalPct float64) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if maxDiskUsageBytes > 0 {
+		dm.config.MaxDiskUsageBytes = maxDiskUsageBytes
 	}
+	if warningPct > 0 {
+		dm.config.WarningThresholdPct = warningPct
+	}
+	if criticalPct > 0 {
+		dm.config.CriticalThresholdPct = criticalPct
+	}
+}
+
+// thresholds returns the disk usage limit and alert thresholds
+func (dm *DiskManager) thresholds() (maxDiskUsageBytes int64, warningPct, criticalPct float64) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	return dm.config.MaxDiskUsageBytes, dm.config.WarningThresholdPct, dm.config.CriticalThresholdPct
 }
 
 // GetDiskUsage returns current disk usage statistics
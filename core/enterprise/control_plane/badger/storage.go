@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"time"
 
 	badger "github.com/dgraph-io/badger/v4"
@@ -82,6 +83,20 @@ const (
 	keyPrefixActivity          = "activity:"            // Tenant activity tracking
 	keyPrefixAccessPattern     = "access_pattern:"      // Tenant access patterns
 	keyPrefixVerificationToken = "verification_token:" // Email verification tokens
+	keyPrefixRestoreJob        = "restore_job:"        // Archive restore jobs, one per tenant
+	keyPrefixGateway           = "gateway:"            // Gateway replicas
+	keyPrefixUsage             = "usage:"              // Daily API request checkpoints, one per tenant
+	keyPrefixTenantKeys        = "tenantkeys:"         // Wrapped tenant data keys
+	keyPrefixAudit             = "audit:"              // Audit log, ordered by time
+	keyPrefixFleetMigration    = "fleet_migration:"    // Fleet migrations
+	keyPrefixFleetTenant       = "fleet_tenant:"       // Per-tenant fleet migration state, by migration
+	keyPrefixLog               = "log:"                // Cluster log store, ordered by time
+	keyPrefixLease             = "lease:"              // Tenant leases, one per tenant
+	keyPrefixSSOToken          = "sso_token:"          // Used tenant SSO token IDs, kept until they expire
+	keyPrefixEmailTemplate     = "email_template:"     // Admin email templates, by name and locale
+	keyPrefixEmailQueue        = "email_queue:"        // Outbound email queue
+	keyPrefixAPIKey            = "api_key:"            // Cluster user API keys
+	keyPrefixAPIKeyHash        = "api_key_hash:"       // API key IDs, by key hash
 )
 
 // Tenant operations
@@ -214,6 +229,48 @@ func (s *Storage) UpdateTenantStatus(tenantID string, status enterprise.TenantSt
 	})
 }
 
+// SetTenantSuspension suspends a tenant, or lifts its suspension if suspension is nil
+func (s *Storage) SetTenantSuspension(tenantID string, suspension *enterprise.TenantSuspension) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyPrefixTenant + tenantID))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrTenantNotFound
+			}
+			return err
+		}
+
+		var tenant enterprise.Tenant
+		err = item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &tenant)
+		})
+		if err != nil {
+			return err
+		}
+
+		tenant.Suspension = suspension
+		tenantJSON, err := json.Marshal(&tenant)
+		if err != nil {
+			return err
+		}
+
+		return txn.Set([]byte(keyPrefixTenant+tenant.ID), tenantJSON)
+	})
+}
+
+// tenantStatePrefixes are the per-tenant entries removed with a tenant, keyed
+// by tenant ID
+var tenantStatePrefixes = []string{
+	keyPrefixPlacement,
+	keyPrefixActivity,
+	keyPrefixAccessPattern,
+	keyPrefixRestoreJob,
+	keyPrefixUsage,
+	keyPrefixTenantKeys,
+	keyPrefixLease,
+}
+
+// DeleteTenant removes a tenant, its domain mapping and its per-tenant state
 func (s *Storage) DeleteTenant(tenantID string) error {
 	return s.db.Update(func(txn *badger.Txn) error {
 		// Get tenant first to get domain
@@ -238,6 +295,12 @@ func (s *Storage) DeleteTenant(tenantID string) error {
 			return err
 		}
 
+		for _, prefix := range tenantStatePrefixes {
+			if err := txn.Delete([]byte(prefix + tenantID)); err != nil {
+				return err
+			}
+		}
+
 		// Delete tenant
 		return txn.Delete([]byte(keyPrefixTenant + tenantID))
 	})
@@ -564,6 +627,43 @@ func (s *Storage) ListNodes() ([]*enterprise.NodeInfo, error) {
 	return nodes, err
 }
 
+// ListExpiringTenants returns the tenants, deleted ones included, whose
+// expiry is before the given time
+func (s *Storage) ListExpiringTenants(before time.Time) ([]*enterprise.Tenant, error) {
+	tenants := make([]*enterprise.Tenant, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(keyPrefixTenant)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var tenant enterprise.Tenant
+				if err := json.Unmarshal(val, &tenant); err != nil {
+					return err
+				}
+
+				if tenant.ExpiresAt != nil && tenant.ExpiresAt.Before(before) {
+					tenants = append(tenants, &tenant)
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return tenants, err
+}
+
 // ListTenantsByNode returns all tenants assigned to a specific node
 func (s *Storage) ListTenantsByNode(nodeID string) ([]*enterprise.Tenant, error) {
 	tenants := make([]*enterprise.Tenant, 0)
@@ -991,25 +1091,67 @@ func (s *Storage) UseVerificationTokenAtomically(token string) (*enterprise.Veri
 	return &verificationToken, nil
 }
 
-// ExportData exports all key-value pairs from BadgerDB
-// The visitor function is called for each key-value pair
-func (s *Storage) ExportData(visitor func(key, value []byte) error) error {
-	return s.db.View(func(txn *badger.Txn) error {
+// Restore job operations
+
+func (s *Storage) SaveRestoreJob(job *enterprise.RestoreJob) error {
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(keyPrefixRestoreJob+job.TenantID), jobJSON)
+	})
+}
+
+func (s *Storage) GetRestoreJob(tenantID string) (*enterprise.RestoreJob, error) {
+	var job enterprise.RestoreJob
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyPrefixRestoreJob + tenantID))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrRestoreJobNotFound
+			}
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &job)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// ListActiveRestoreJobs returns all restore jobs that are still pending or in progress
+func (s *Storage) ListActiveRestoreJobs() ([]*enterprise.RestoreJob, error) {
+	jobs := make([]*enterprise.RestoreJob, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
-		opts.PrefetchSize = 100 // Prefetch for better performance
+		opts.Prefix = []byte(keyPrefixRestoreJob)
 
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
-			key := item.Key()
-
 			err := item.Value(func(val []byte) error {
-				// Call visitor with copies of key and value
-				keyCopy := append([]byte(nil), key...)
-				valCopy := append([]byte(nil), val...)
-				return visitor(keyCopy, valCopy)
+				var job enterprise.RestoreJob
+				if err := json.Unmarshal(val, &job); err != nil {
+					return err
+				}
+
+				if job.IsActive() {
+					jobs = append(jobs, &job)
+				}
+
+				return nil
 			})
 
 			if err != nil {
@@ -1019,47 +1161,902 @@ func (s *Storage) ExportData(visitor func(key, value []byte) error) error {
 
 		return nil
 	})
+
+	return jobs, err
 }
 
-// ImportData imports key-value pairs into BadgerDB
-// This clears existing data and restores from the provided entries
-func (s *Storage) ImportData(entries []SnapshotEntry) error {
+// Gateway operations
+
+func (s *Storage) SaveGateway(gateway *enterprise.GatewayInfo) error {
+	gatewayJSON, err := json.Marshal(gateway)
+	if err != nil {
+		return err
+	}
+
 	return s.db.Update(func(txn *badger.Txn) error {
-		// First, delete all existing keys
-		// We need to iterate and collect keys to delete
-		keysToDelete := make([][]byte, 0)
+		return txn.Set([]byte(keyPrefixGateway+gateway.ID), gatewayJSON)
+	})
+}
 
+func (s *Storage) ListGateways() ([]*enterprise.GatewayInfo, error) {
+	gateways := make([]*enterprise.GatewayInfo, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
-		opts.PrefetchSize = 100
+		opts.Prefix = []byte(keyPrefixGateway)
 
 		it := txn.NewIterator(opts)
+		defer it.Close()
+
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
-			key := item.KeyCopy(nil)
-			keysToDelete = append(keysToDelete, key)
+			err := item.Value(func(val []byte) error {
+				var gateway enterprise.GatewayInfo
+				if err := json.Unmarshal(val, &gateway); err != nil {
+					return err
+				}
+				gateways = append(gateways, &gateway)
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
 		}
-		it.Close()
 
-		// Delete all keys
-		for _, key := range keysToDelete {
-			if err := txn.Delete(key); err != nil {
-				return fmt.Errorf("failed to delete key during restore: %w", err)
+		return nil
+	})
+
+	return gateways, err
+}
+
+// Tenant lease operations
+
+func (s *Storage) SaveTenantLease(lease *enterprise.TenantLease) error {
+	leaseJSON, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(keyPrefixLease+lease.TenantID), leaseJSON)
+	})
+}
+
+func (s *Storage) GetTenantLease(tenantID string) (*enterprise.TenantLease, error) {
+	var lease enterprise.TenantLease
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyPrefixLease + tenantID))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrTenantLeaseNotFound
 			}
+			return err
 		}
 
-		// Import new data
-		for _, entry := range entries {
-			if err := txn.Set(entry.Key, entry.Value); err != nil {
-				return fmt.Errorf("failed to set key during restore: %w", err)
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &lease)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &lease, nil
+}
+
+// SSO token operations
+
+// MarkSSOTokenUsed records a tenant SSO token as used until it expires
+func (s *Storage) MarkSSOTokenUsed(tokenID string, expiresAt time.Time) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry([]byte(keyPrefixSSOToken+tokenID), []byte{1}).
+			WithTTL(time.Until(expiresAt))
+		return txn.SetEntry(entry)
+	})
+}
+
+// IsSSOTokenUsed returns true if a tenant SSO token was used and hasn't expired yet
+func (s *Storage) IsSSOTokenUsed(tokenID string) (bool, error) {
+	err := s.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(keyPrefixSSOToken + tokenID))
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Email template operations
+
+func emailTemplateKey(name, locale string) []byte {
+	return []byte(keyPrefixEmailTemplate + name + ":" + locale)
+}
+
+func (s *Storage) SaveEmailTemplate(tmpl *enterprise.EmailTemplate) error {
+	tmplJSON, err := json.Marshal(tmpl)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(emailTemplateKey(tmpl.Name, tmpl.Locale), tmplJSON)
+	})
+}
+
+func (s *Storage) GetEmailTemplate(name, locale string) (*enterprise.EmailTemplate, error) {
+	var tmpl enterprise.EmailTemplate
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(emailTemplateKey(name, locale))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrEmailTemplateNotFound
+			}
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &tmpl)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &tmpl, nil
+}
+
+func (s *Storage) ListEmailTemplates() ([]*enterprise.EmailTemplate, error) {
+	templates := make([]*enterprise.EmailTemplate, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(keyPrefixEmailTemplate)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var tmpl enterprise.EmailTemplate
+				if err := json.Unmarshal(val, &tmpl); err != nil {
+					return err
+				}
+				templates = append(templates, &tmpl)
+				return nil
+			})
+
+			if err != nil {
+				return err
 			}
 		}
 
 		return nil
 	})
+
+	return templates, err
 }
 
-// SnapshotEntry represents a key-value pair for import/export
-type SnapshotEntry struct {
-	Key   []byte
-	Value []byte
+func (s *Storage) DeleteEmailTemplate(name, locale string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		key := emailTemplateKey(name, locale)
+		if _, err := txn.Get(key); err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrEmailTemplateNotFound
+			}
+			return err
+		}
+		return txn.Delete(key)
+	})
+}
+
+// Outbound email queue operations
+
+func (s *Storage) SaveOutboundEmail(email *enterprise.OutboundEmail) error {
+	emailJSON, err := json.Marshal(email)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(keyPrefixEmailQueue+email.ID), emailJSON)
+	})
+}
+
+func (s *Storage) ListOutboundEmails() ([]*enterprise.OutboundEmail, error) {
+	emails := make([]*enterprise.OutboundEmail, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(keyPrefixEmailQueue)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var email enterprise.OutboundEmail
+				if err := json.Unmarshal(val, &email); err != nil {
+					return err
+				}
+				emails = append(emails, &email)
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return emails, err
+}
+
+func (s *Storage) DeleteOutboundEmail(id string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		key := []byte(keyPrefixEmailQueue + id)
+		if _, err := txn.Get(key); err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrOutboundEmailNotFound
+			}
+			return err
+		}
+		return txn.Delete(key)
+	})
+}
+
+// API key operations
+
+func (s *Storage) SaveAPIKey(key *enterprise.APIKey) error {
+	keyJSON, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set([]byte(keyPrefixAPIKey+key.ID), keyJSON); err != nil {
+			return err
+		}
+		return txn.Set([]byte(keyPrefixAPIKeyHash+key.Hash), []byte(key.ID))
+	})
+}
+
+func (s *Storage) GetAPIKey(keyID string) (*enterprise.APIKey, error) {
+	var key enterprise.APIKey
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyPrefixAPIKey + keyID))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrAPIKeyNotFound
+			}
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &key)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (s *Storage) GetAPIKeyByHash(hash string) (*enterprise.APIKey, error) {
+	var keyID string
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyPrefixAPIKeyHash + hash))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrAPIKeyNotFound
+			}
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			keyID = string(val)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetAPIKey(keyID)
+}
+
+// ListAPIKeys lists the API keys of a user
+func (s *Storage) ListAPIKeys(userID string) ([]*enterprise.APIKey, error) {
+	keys := make([]*enterprise.APIKey, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(keyPrefixAPIKey)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var key enterprise.APIKey
+				if err := json.Unmarshal(val, &key); err != nil {
+					return err
+				}
+				if key.UserID == userID {
+					keys = append(keys, &key)
+				}
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return keys, err
+}
+
+func (s *Storage) DeleteAPIKey(keyID string) error {
+	key, err := s.GetAPIKey(keyID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Delete([]byte(keyPrefixAPIKeyHash + key.Hash)); err != nil {
+			return err
+		}
+		return txn.Delete([]byte(keyPrefixAPIKey + keyID))
+	})
+}
+
+// Usage checkpoint operations
+
+func (s *Storage) SaveUsageCheckpoint(checkpoint *enterprise.UsageCheckpoint) error {
+	checkpointJSON, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(keyPrefixUsage+checkpoint.TenantID), checkpointJSON)
+	})
+}
+
+func (s *Storage) GetUsageCheckpoint(tenantID string) (*enterprise.UsageCheckpoint, error) {
+	var checkpoint enterprise.UsageCheckpoint
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyPrefixUsage + tenantID))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrUsageCheckpointNotFound
+			}
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &checkpoint)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// ExportData exports all key-value pairs from BadgerDB
+// The visitor function is called for each key-value pair
+func (s *Storage) ExportData(visitor func(key, value []byte) error) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchSize = 100 // Prefetch for better performance
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			key := item.Key()
+
+			err := item.Value(func(val []byte) error {
+				// Call visitor with copies of key and value
+				keyCopy := append([]byte(nil), key...)
+				valCopy := append([]byte(nil), val...)
+				return visitor(keyCopy, valCopy)
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ImportData imports key-value pairs into BadgerDB
+// This clears existing data and restores from the provided entries
+func (s *Storage) ImportData(entries []SnapshotEntry) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		// First, delete all existing keys
+		// We need to iterate and collect keys to delete
+		keysToDelete := make([][]byte, 0)
+
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchSize = 100
+
+		it := txn.NewIterator(opts)
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			key := item.KeyCopy(nil)
+			keysToDelete = append(keysToDelete, key)
+		}
+		it.Close()
+
+		// Delete all keys
+		for _, key := range keysToDelete {
+			if err := txn.Delete(key); err != nil {
+				return fmt.Errorf("failed to delete key during restore: %w", err)
+			}
+		}
+
+		// Import new data
+		for _, entry := range entries {
+			if err := txn.Set(entry.Key, entry.Value); err != nil {
+				return fmt.Errorf("failed to set key during restore: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// SnapshotEntry represents a key-value pair for import/export
+type SnapshotEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// Tenant key operations
+
+func (s *Storage) SaveTenantKeyring(keyring *enterprise.TenantKeyring) error {
+	keyringJSON, err := json.Marshal(keyring)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(keyPrefixTenantKeys+keyring.TenantID), keyringJSON)
+	})
+}
+
+func (s *Storage) GetTenantKeyring(tenantID string) (*enterprise.TenantKeyring, error) {
+	var keyring enterprise.TenantKeyring
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyPrefixTenantKeys + tenantID))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrTenantKeysNotFound
+			}
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &keyring)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyring, nil
+}
+
+func (s *Storage) ListTenantKeyrings() ([]*enterprise.TenantKeyring, error) {
+	keyrings := make([]*enterprise.TenantKeyring, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(keyPrefixTenantKeys)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var keyring enterprise.TenantKeyring
+				if err := json.Unmarshal(val, &keyring); err != nil {
+					return err
+				}
+				keyrings = append(keyrings, &keyring)
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return keyrings, err
+}
+
+// Audit log operations
+
+// errStopIteration ends an audit log scan early without an error
+var errStopIteration = fmt.Errorf("stop iteration")
+
+// auditTimeKey is the key prefix of audit entries recorded at t. Zero padding keeps
+// the keys in chronological order.
+func auditTimeKey(t time.Time) string {
+	return fmt.Sprintf("%s%020d", keyPrefixAudit, t.UnixNano())
+}
+
+func (s *Storage) AppendAuditEntry(entry *enterprise.AuditEntry) error {
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(auditTimeKey(entry.Time)+":"+entry.ID), entryJSON)
+	})
+}
+
+// ListAuditEntries returns the entries matching the filter, newest first
+func (s *Storage) ListAuditEntries(filter *enterprise.AuditFilter) ([]*enterprise.AuditEntry, error) {
+	entries := make([]*enterprise.AuditEntry, 0)
+
+	err := s.scanAuditEntries(filter, true, func(entry *enterprise.AuditEntry) error {
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			return errStopIteration
+		}
+		return nil
+	})
+
+	return entries, err
+}
+
+// ExportAuditEntries calls fn for every entry matching the filter, oldest first
+func (s *Storage) ExportAuditEntries(filter *enterprise.AuditFilter, fn func(*enterprise.AuditEntry) error) error {
+	exported := 0
+
+	return s.scanAuditEntries(filter, false, func(entry *enterprise.AuditEntry) error {
+		if err := fn(entry); err != nil {
+			return err
+		}
+		exported++
+		if filter.Limit > 0 && exported >= filter.Limit {
+			return errStopIteration
+		}
+		return nil
+	})
+}
+
+func (s *Storage) scanAuditEntries(filter *enterprise.AuditFilter, reverse bool, fn func(*enterprise.AuditEntry) error) error {
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(keyPrefixAudit)
+		opts.Reverse = reverse
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		// Skip straight to the requested time range
+		start := keyPrefixAudit
+		if reverse {
+			start = keyPrefixAudit + "~"
+			if !filter.Until.IsZero() {
+				start = auditTimeKey(filter.Until)
+			}
+		} else if !filter.Since.IsZero() {
+			start = auditTimeKey(filter.Since)
+		}
+
+		for it.Seek([]byte(start)); it.Valid(); it.Next() {
+			var entry enterprise.AuditEntry
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &entry)
+			})
+			if err != nil {
+				return err
+			}
+
+			// Entries are ordered by time, so nothing further can match
+			if reverse && !filter.Since.IsZero() && entry.Time.Before(filter.Since) {
+				return nil
+			}
+			if !reverse && !filter.Until.IsZero() && !entry.Time.Before(filter.Until) {
+				return nil
+			}
+
+			if !filter.Matches(&entry) {
+				continue
+			}
+
+			if err := fn(&entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err == errStopIteration {
+		return nil
+	}
+	return err
+}
+
+// DeleteAuditEntriesBefore prunes audit entries recorded before cutoff
+func (s *Storage) DeleteAuditEntriesBefore(cutoff time.Time) (int, error) {
+	return s.deleteKeysBefore(keyPrefixAudit, auditTimeKey(cutoff))
+}
+
+// deleteKeysBefore deletes the keys with prefix that sort before end
+func (s *Storage) deleteKeysBefore(prefix, end string) (int, error) {
+	keys := make([][]byte, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(prefix)
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			key := it.Item().KeyCopy(nil)
+			if string(key) >= end {
+				break
+			}
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+
+	// A write batch splits large deletes across transactions
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, key := range keys {
+		if err := wb.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := wb.Flush(); err != nil {
+		return 0, err
+	}
+
+	return len(keys), nil
+}
+
+// Log store operations
+
+// logTimeKey is the key prefix of log entries logged at t
+func logTimeKey(t time.Time) string {
+	return fmt.Sprintf("%s%020d", keyPrefixLog, t.UnixNano())
+}
+
+// AppendLogEntries stores a batch of log entries
+func (s *Storage) AppendLogEntries(entries []*enterprise.LogEntry) error {
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, entry := range entries {
+		entryJSON, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if err := wb.Set([]byte(logTimeKey(entry.Time)+":"+entry.ID), entryJSON); err != nil {
+			return err
+		}
+	}
+
+	return wb.Flush()
+}
+
+// ListLogEntries returns the log entries matching the filter, newest first
+func (s *Storage) ListLogEntries(filter *enterprise.LogFilter) ([]*enterprise.LogEntry, error) {
+	entries := make([]*enterprise.LogEntry, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(keyPrefixLog)
+		opts.Reverse = true
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		start := keyPrefixLog + "~"
+		if !filter.Until.IsZero() {
+			start = logTimeKey(filter.Until)
+		}
+
+		for it.Seek([]byte(start)); it.Valid(); it.Next() {
+			var entry enterprise.LogEntry
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &entry)
+			})
+			if err != nil {
+				return err
+			}
+
+			// Entries are ordered by time, so nothing further can match
+			if !filter.Since.IsZero() && entry.Time.Before(filter.Since) {
+				return nil
+			}
+
+			if !filter.Matches(&entry) {
+				continue
+			}
+
+			entries = append(entries, &entry)
+			if filter.Limit > 0 && len(entries) >= filter.Limit {
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// DeleteLogEntriesBefore prunes log entries logged before cutoff
+func (s *Storage) DeleteLogEntriesBefore(cutoff time.Time) (int, error) {
+	return s.deleteKeysBefore(keyPrefixLog, logTimeKey(cutoff))
+}
+
+// Fleet migration operations
+
+func (s *Storage) SaveFleetMigration(migration *enterprise.FleetMigration) error {
+	migrationJSON, err := json.Marshal(migration)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(keyPrefixFleetMigration+migration.ID), migrationJSON)
+	})
+}
+
+func (s *Storage) GetFleetMigration(migrationID string) (*enterprise.FleetMigration, error) {
+	var migration enterprise.FleetMigration
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyPrefixFleetMigration + migrationID))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return enterprise.ErrFleetMigrationNotFound
+			}
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &migration)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &migration, nil
+}
+
+// ListFleetMigrations returns all fleet migrations, oldest first
+func (s *Storage) ListFleetMigrations() ([]*enterprise.FleetMigration, error) {
+	migrations := make([]*enterprise.FleetMigration, 0)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(keyPrefixFleetMigration)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var migration enterprise.FleetMigration
+				if err := json.Unmarshal(val, &migration); err != nil {
+					return err
+				}
+				migrations = append(migrations, &migration)
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Created.Before(migrations[j].Created)
+	})
+
+	return migrations, err
+}
+
+// fleetTenantKey is the key of a tenant's state for a fleet migration
+func fleetTenantKey(migrationID, tenantID string) string {
+	return keyPrefixFleetTenant + migrationID + ":" + tenantID
+}
+
+func (s *Storage) SaveFleetMigrationTenant(state *enterprise.FleetMigrationTenant) error {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(fleetTenantKey(state.MigrationID, state.TenantID)), stateJSON)
+	})
+}
+
+// ListFleetMigrationTenants returns the tenant states of a fleet migration,
+// keyed by tenant ID. Pending tenants have no state.
+func (s *Storage) ListFleetMigrationTenants(migrationID string) (map[string]*enterprise.FleetMigrationTenant, error) {
+	states := make(map[string]*enterprise.FleetMigrationTenant)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(keyPrefixFleetTenant + migrationID + ":")
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var state enterprise.FleetMigrationTenant
+				if err := json.Unmarshal(val, &state); err != nil {
+					return err
+				}
+				states[state.TenantID] = &state
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return states, err
 }
@@ -231,6 +231,7 @@ func TestDeleteTenant(t *testing.T) {
 	}
 
 	storage.CreateTenant(tenant)
+	storage.SaveTenantLease(&enterprise.TenantLease{TenantID: "tenant-1", NodeID: "node-1", Epoch: 1})
 
 	// Delete tenant
 	err := storage.DeleteTenant("tenant-1")
@@ -249,6 +250,34 @@ func TestDeleteTenant(t *testing.T) {
 	if err != enterprise.ErrTenantNotFound {
 		t.Error("expected domain mapping to be deleted")
 	}
+
+	// Verify the tenant state is also deleted
+	if _, err := storage.GetTenantLease("tenant-1"); err != enterprise.ErrTenantLeaseNotFound {
+		t.Errorf("expected lease to be deleted, got %v", err)
+	}
+}
+
+func TestListExpiringTenants(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	now := time.Now()
+	soon := now.Add(time.Hour)
+	later := now.Add(48 * time.Hour)
+
+	storage.CreateTenant(&enterprise.Tenant{ID: "tenant-1", Domain: "t1.example.com", ExpiresAt: &soon})
+	storage.CreateTenant(&enterprise.Tenant{ID: "tenant-2", Domain: "t2.example.com", ExpiresAt: &later})
+	storage.CreateTenant(&enterprise.Tenant{ID: "tenant-3", Domain: "t3.example.com"})
+	storage.CreateTenant(&enterprise.Tenant{ID: "tenant-4", Domain: "t4.example.com", ExpiresAt: &soon, Status: enterprise.TenantStatusDeleted})
+
+	tenants, err := storage.ListExpiringTenants(now.Add(24 * time.Hour))
+	if err != nil {
+		t.Fatalf("failed to list expiring tenants: %v", err)
+	}
+
+	if len(tenants) != 2 || tenants[0].ID != "tenant-1" || tenants[1].ID != "tenant-4" {
+		t.Errorf("expected tenant-1 and the deleted tenant-4, got %v", tenants)
+	}
 }
 
 func TestDeleteTenantNotFound(t *testing.T) {
@@ -1101,6 +1130,140 @@ func TestUseVerificationTokenAtomicallyExpired(t *testing.T) {
 	}
 }
 
+// Restore job tests
+
+func TestSaveAndGetRestoreJob(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	now := time.Now()
+	job := &enterprise.RestoreJob{
+		TenantID:       "tenant-1",
+		Status:         enterprise.RestoreJobPending,
+		FromTier:       enterprise.StorageTierCold,
+		RequestedBy:    "gateway",
+		EstimatedReady: now.Add(12 * time.Hour),
+		Created:        now,
+		Updated:        now,
+	}
+
+	if err := storage.SaveRestoreJob(job); err != nil {
+		t.Fatalf("failed to save restore job: %v", err)
+	}
+
+	retrieved, err := storage.GetRestoreJob("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to get restore job: %v", err)
+	}
+
+	if retrieved.Status != enterprise.RestoreJobPending {
+		t.Errorf("expected status pending, got %s", retrieved.Status)
+	}
+	if retrieved.FromTier != enterprise.StorageTierCold {
+		t.Errorf("expected tier cold, got %s", retrieved.FromTier)
+	}
+	if retrieved.RequestedBy != "gateway" {
+		t.Errorf("expected requestedBy gateway, got %s", retrieved.RequestedBy)
+	}
+}
+
+func TestGetRestoreJobNotFound(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	_, err := storage.GetRestoreJob("nonexistent")
+	if err != enterprise.ErrRestoreJobNotFound {
+		t.Errorf("expected ErrRestoreJobNotFound, got %v", err)
+	}
+}
+
+func TestListActiveRestoreJobs(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	statuses := map[string]enterprise.RestoreJobStatus{
+		"tenant-1": enterprise.RestoreJobPending,
+		"tenant-2": enterprise.RestoreJobInProgress,
+		"tenant-3": enterprise.RestoreJobReady,
+		"tenant-4": enterprise.RestoreJobFailed,
+	}
+
+	for tenantID, status := range statuses {
+		if err := storage.SaveRestoreJob(&enterprise.RestoreJob{TenantID: tenantID, Status: status}); err != nil {
+			t.Fatalf("failed to save restore job: %v", err)
+		}
+	}
+
+	jobs, err := storage.ListActiveRestoreJobs()
+	if err != nil {
+		t.Fatalf("failed to list restore jobs: %v", err)
+	}
+
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 active jobs, got %d", len(jobs))
+	}
+
+	for _, job := range jobs {
+		if !job.IsActive() {
+			t.Errorf("expected only active jobs, got %s for %s", job.Status, job.TenantID)
+		}
+	}
+}
+
+// Fleet migration tests
+
+func TestListFleetMigrationTenants(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	now := time.Now()
+	for i, id := range []string{"fleet_b", "fleet_a"} {
+		migration := &enterprise.FleetMigration{
+			ID:      id,
+			Name:    id,
+			Status:  enterprise.FleetMigrationRunning,
+			Created: now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := storage.SaveFleetMigration(migration); err != nil {
+			t.Fatalf("failed to save fleet migration: %v", err)
+		}
+	}
+
+	migrations, err := storage.ListFleetMigrations()
+	if err != nil {
+		t.Fatalf("failed to list fleet migrations: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].ID != "fleet_b" {
+		t.Fatalf("expected fleet_b first (oldest), got %v", migrations)
+	}
+
+	states := []*enterprise.FleetMigrationTenant{
+		{MigrationID: "fleet_a", TenantID: "tenant-1", Status: enterprise.FleetMigrationTenantApplied},
+		{MigrationID: "fleet_a", TenantID: "tenant-2", Status: enterprise.FleetMigrationTenantFailed, Error: "boom"},
+		{MigrationID: "fleet_b", TenantID: "tenant-1", Status: enterprise.FleetMigrationTenantScheduled},
+	}
+	for _, state := range states {
+		if err := storage.SaveFleetMigrationTenant(state); err != nil {
+			t.Fatalf("failed to save fleet migration tenant: %v", err)
+		}
+	}
+
+	tenants, err := storage.ListFleetMigrationTenants("fleet_a")
+	if err != nil {
+		t.Fatalf("failed to list fleet migration tenants: %v", err)
+	}
+	if len(tenants) != 2 {
+		t.Fatalf("expected 2 tenant states, got %d", len(tenants))
+	}
+	if tenants["tenant-2"].Error != "boom" {
+		t.Errorf("expected tenant-2 failure to be kept, got %+v", tenants["tenant-2"])
+	}
+
+	if _, err := storage.GetFleetMigration("missing"); err != enterprise.ErrFleetMigrationNotFound {
+		t.Errorf("expected ErrFleetMigrationNotFound, got %v", err)
+	}
+}
+
 // Export/Import tests
 
 func TestExportAndImportData(t *testing.T) {
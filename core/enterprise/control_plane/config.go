@@ -0,0 +1,26 @@
+package control_plane
+
+import (
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// ApplyConfig applies the reloadable settings of a reloaded config
+func (cp *ControlPlane) ApplyConfig(next *enterprise.ClusterConfig) {
+	cp.configMu.Lock()
+	cp.config.AuditRetention = next.AuditRetention
+	cp.config.Logs = next.Logs
+	cp.configMu.Unlock()
+
+	cp.applyDiskSettings(next.Disk)
+
+	cp.logger.Info("Applied reloaded config", "auditRetention", cp.auditRetention())
+}
+
+// applyDiskSettings passes the configured disk thresholds to the disk manager
+func (cp *ControlPlane) applyDiskSettings(disk enterprise.DiskSettings) {
+	if cp.storage == nil || cp.storage.GetDiskManager() == nil {
+		return
+	}
+
+	cp.storage.GetDiskManager().SetThresholds(disk.MaxUsageBytes, disk.WarningThresholdPct, disk.CriticalThresholdPct)
+}
@@ -3,18 +3,21 @@ package control_plane
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/email"
 	"github.com/pocketbase/pocketbase/core/enterprise/health"
+	storagepkg "github.com/pocketbase/pocketbase/core/enterprise/storage"
 )
 
 // ControlPlane manages the distributed control plane for the multi-tenant system
 type ControlPlane struct {
-	config *enterprise.ClusterConfig
+	config   *enterprise.ClusterConfig
+	configMu sync.RWMutex // Guards the settings changed by ApplyConfig
 
 	// Core components
 	storage   *BadgerStorage   // BadgerDB storage
@@ -25,6 +28,33 @@ type ControlPlane struct {
 	nodes   map[string]*enterprise.NodeInfo // Active tenant nodes
 	nodesMu sync.RWMutex
 
+	// Archive restores
+	glacier        GlacierRestorer            // nil when S3 is not configured
+	regionGlaciers map[string]GlacierRestorer // Restorers of the configured regions' buckets
+	restoreMu      sync.Mutex                 // Serializes restore job transitions
+
+	// Removes the S3 data of expired tenants, per region
+	dataDeleters   map[string][]TenantDataDeleter
+	dataDeletersMu sync.Mutex
+
+	// Serializes fleet migration status changes
+	fleetMu sync.Mutex
+
+	// Serializes lease grants and renewals
+	leasesMu sync.Mutex
+
+	// Serializes tenant SSO token use
+	ssoMu sync.Mutex
+
+	// Serializes API key creation, so the per-user limit and unique names hold
+	apiKeysMu sync.Mutex
+
+	// Sends the queued cluster emails
+	email *email.Service
+
+	// Wraps tenant data keys (nil when encryption is disabled)
+	masterKey *storagepkg.MasterKeyring
+
 	// Health and monitoring
 	healthChecker *health.Checker
 
@@ -39,7 +69,7 @@ type ControlPlane struct {
 	// HTTP server for enterprise APIs
 	httpServer *http.Server
 
-	logger *log.Logger
+	logger *slog.Logger
 }
 
 // NewControlPlane creates a new control plane instance
@@ -48,6 +78,11 @@ func NewControlPlane(config *enterprise.ClusterConfig) (*ControlPlane, error) {
 		return nil, fmt.Errorf("invalid mode for control plane: %s", config.Mode)
 	}
 
+	emailService, err := email.NewServiceFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize email service: %w", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Initialize health checker
@@ -58,10 +93,11 @@ func NewControlPlane(config *enterprise.ClusterConfig) (*ControlPlane, error) {
 	cp := &ControlPlane{
 		config:        config,
 		nodes:         make(map[string]*enterprise.NodeInfo),
+		email:         emailService,
 		healthChecker: healthChecker,
 		ctx:           ctx,
 		cancel:        cancel,
-		logger:        log.Default(),
+		logger:        enterprise.ComponentLogger(enterprise.LogComponentControlPlane),
 	}
 
 	return cp, nil
@@ -69,7 +105,7 @@ func NewControlPlane(config *enterprise.ClusterConfig) (*ControlPlane, error) {
 
 // Start initializes and starts the control plane
 func (cp *ControlPlane) Start() error {
-	cp.logger.Printf("[ControlPlane] Starting control plane node: %s", cp.config.NodeID)
+	cp.logger.Info("Starting control plane node", "nodeId", cp.config.NodeID)
 
 	// 1. Initialize BadgerDB storage
 	storage, err := NewBadgerStorage(cp.config.DataDir)
@@ -77,6 +113,11 @@ func (cp *ControlPlane) Start() error {
 		return fmt.Errorf("failed to initialize BadgerDB: %w", err)
 	}
 	cp.storage = storage
+	cp.applyDiskSettings(cp.config.Disk)
+
+	if err := cp.initMasterKey(); err != nil {
+		return fmt.Errorf("failed to load master key: %w", err)
+	}
 
 	// 2. Initialize Raft
 	raftNode, err := NewRaftNode(cp.config, cp.storage)
@@ -86,7 +127,7 @@ func (cp *ControlPlane) Start() error {
 	cp.raft = raftNode
 
 	// 3. Initialize placement service
-	cp.placement = NewPlacementService(cp.storage, cp.raft)
+	cp.placement = NewPlacementService(cp.storage, cp.raft, cp.config.Region)
 
 	// 4. Start IPC server for gateway/tenant node communication
 	ipcServer, err := NewIPCServer(cp)
@@ -144,17 +185,25 @@ func (cp *ControlPlane) Start() error {
 	})
 
 	// 6. Start background tasks
-	cp.wg.Add(2)
+	cp.initGlacierRestorer()
+
+	cp.wg.Add(8)
 	go cp.monitorNodes()
 	go cp.rebalanceTenants()
-
-	cp.logger.Printf("[ControlPlane] Control plane started successfully")
+	go cp.pollRestoreJobs()
+	go cp.pruneAuditLog()
+	go cp.pruneLogStore()
+	go cp.scheduleFleetMigrations()
+	go cp.deliverEmails()
+	go cp.reapExpiredTenants()
+
+	cp.logger.Info("Control plane started successfully")
 	return nil
 }
 
 // Stop gracefully shuts down the control plane
 func (cp *ControlPlane) Stop() error {
-	cp.logger.Printf("[ControlPlane] Stopping control plane...")
+	cp.logger.Info("Stopping control plane")
 
 	cp.cancel()
 	cp.wg.Wait()
@@ -163,29 +212,29 @@ func (cp *ControlPlane) Stop() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := cp.httpServer.Shutdown(ctx); err != nil {
-			cp.logger.Printf("[ControlPlane] Error stopping HTTP server: %v", err)
+			cp.logger.Error("Error stopping HTTP server", "error", err)
 		}
 	}
 
 	if cp.ipcServer != nil {
 		if err := cp.ipcServer.Stop(); err != nil {
-			cp.logger.Printf("[ControlPlane] Error stopping IPC server: %v", err)
+			cp.logger.Error("Error stopping IPC server", "error", err)
 		}
 	}
 
 	if cp.raft != nil {
 		if err := cp.raft.Shutdown(); err != nil {
-			cp.logger.Printf("[ControlPlane] Error shutting down Raft: %v", err)
+			cp.logger.Error("Error shutting down Raft", "error", err)
 		}
 	}
 
 	if cp.storage != nil {
 		if err := cp.storage.Close(); err != nil {
-			cp.logger.Printf("[ControlPlane] Error closing storage: %v", err)
+			cp.logger.Error("Error closing storage", "error", err)
 		}
 	}
 
-	cp.logger.Printf("[ControlPlane] Control plane stopped")
+	cp.logger.Info("Control plane stopped")
 	return nil
 }
 
@@ -196,11 +245,11 @@ func (cp *ControlPlane) StartHTTP(addr string, handler http.Handler) error {
 		Handler: handler,
 	}
 
-	cp.logger.Printf("[ControlPlane] Starting HTTP API server on %s", addr)
+	cp.logger.Info("Starting HTTP API server", "addr", addr)
 
 	go func() {
 		if err := cp.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			cp.logger.Printf("[ControlPlane] HTTP server error: %v", err)
+			cp.logger.Error("HTTP server error", "error", err)
 		}
 	}()
 
@@ -235,6 +284,27 @@ func (cp *ControlPlane) CreateTenant(tenant *enterprise.Tenant) error {
 		return enterprise.NewQuotaError("tenants", int64(tenantCount), int64(user.MaxTenants))
 	}
 
+	// Preview tenants get the cheaper quotas and always expire
+	if tenant.Preview {
+		storageQuotaMB, apiRequestsQuota := enterprise.PreviewTenantQuotas()
+		if tenant.StorageQuotaMB == 0 {
+			tenant.StorageQuotaMB = min(storageQuotaMB, user.MaxStoragePerTenant)
+		}
+		if tenant.APIRequestsQuota == 0 {
+			tenant.APIRequestsQuota = min(apiRequestsQuota, user.MaxAPIRequestsDaily)
+		}
+		if tenant.ExpiresAt == nil {
+			expiresAt := time.Now().Add(enterprise.DefaultPreviewTTL)
+			tenant.ExpiresAt = &expiresAt
+		}
+	}
+	if tenant.ExpiresAt != nil {
+		if err := validateTenantExpiry(*tenant.ExpiresAt, time.Now()); err != nil {
+			return err
+		}
+	}
+	tenant.ExpiryNotified = nil
+
 	// Set defaults
 	if tenant.StorageQuotaMB == 0 {
 		tenant.StorageQuotaMB = user.MaxStoragePerTenant
@@ -243,23 +313,89 @@ func (cp *ControlPlane) CreateTenant(tenant *enterprise.Tenant) error {
 		tenant.APIRequestsQuota = user.MaxAPIRequestsDaily
 	}
 
+	// Tenants without a residency requirement live in the control plane's region
+	if tenant.Region == "" {
+		tenant.Region = cp.config.Region
+	}
+	storage, ok := cp.config.RegionStorage(tenant.Region)
+	if !ok {
+		return fmt.Errorf("%w %q", enterprise.ErrUnknownRegion, tenant.Region)
+	}
+
 	tenant.Status = enterprise.TenantStatusCreated
 	tenant.Created = time.Now()
 	tenant.Updated = time.Now()
 
 	// S3 paths
-	tenant.S3Bucket = cp.config.S3Bucket
+	tenant.S3Bucket = storage.S3.Bucket
 	tenant.S3Prefix = enterprise.GetS3TenantPrefix(tenant.ID)
 
+	// Data keys are created first so that an encrypted cluster never has tenants without keys
+	if cp.masterKey != nil {
+		if err := cp.createTenantKeyring(tenant.ID); err != nil {
+			return fmt.Errorf("failed to create tenant data keys: %w", err)
+		}
+	}
+
 	// Store via Raft
 	return cp.storage.CreateTenant(tenant)
 }
 
+// Regions returns the regions tenants can be created in
+func (cp *ControlPlane) Regions() []string {
+	return cp.config.RegionNames()
+}
+
 // UpdateTenantStatus updates tenant status
 func (cp *ControlPlane) UpdateTenantStatus(tenantID string, status enterprise.TenantStatus) error {
 	return cp.storage.UpdateTenantStatus(tenantID, status)
 }
 
+// SetTenantResponseCache enables or disables the gateway response cache for a tenant.
+// ttlSeconds is used for responses without max-age (0 keeps the gateway default).
+func (cp *ControlPlane) SetTenantResponseCache(tenantID string, enabled bool, ttlSeconds int) (*enterprise.Tenant, error) {
+	if ttlSeconds < 0 {
+		return nil, fmt.Errorf("invalid cache TTL: %d", ttlSeconds)
+	}
+
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant.ResponseCacheEnabled = enabled
+	tenant.ResponseCacheTTL = ttlSeconds
+	tenant.Updated = time.Now()
+
+	if err := cp.storage.UpdateTenant(tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// SetTenantAccessPolicy replaces the gateway access policy of a tenant, an
+// empty policy removes it
+func (cp *ControlPlane) SetTenantAccessPolicy(tenantID string, policy *enterprise.TenantAccessPolicy) (*enterprise.Tenant, error) {
+	if policy.IsEmpty() {
+		policy = nil
+	} else if err := policy.Normalize(); err != nil {
+		return nil, err
+	}
+
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant.AccessPolicy = policy
+	tenant.Updated = time.Now()
+
+	if err := cp.storage.UpdateTenant(tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
 // AssignTenant assigns a tenant to a node
 func (cp *ControlPlane) AssignTenant(tenantID string) (*enterprise.PlacementDecision, error) {
 	return cp.placement.AssignTenant(tenantID)
@@ -267,6 +403,10 @@ func (cp *ControlPlane) AssignTenant(tenantID string) (*enterprise.PlacementDeci
 
 // RegisterNode registers a new tenant node
 func (cp *ControlPlane) RegisterNode(node *enterprise.NodeInfo) error {
+	if _, ok := cp.config.RegionStorage(node.Region); !ok {
+		return fmt.Errorf("%w %q", enterprise.ErrUnknownRegion, node.Region)
+	}
+
 	cp.nodesMu.Lock()
 	defer cp.nodesMu.Unlock()
 
@@ -277,20 +417,50 @@ func (cp *ControlPlane) RegisterNode(node *enterprise.NodeInfo) error {
 	return cp.storage.SaveNode(node)
 }
 
-// UpdateNodeHeartbeat updates node heartbeat
-func (cp *ControlPlane) UpdateNodeHeartbeat(nodeID string, activeTenantsCount int) error {
+// UpdateNodeHeartbeat updates node heartbeat and renews the leases the node
+// holds. It returns the node's status, which tells a node that it should drain,
+// and the renewed leases.
+func (cp *ControlPlane) UpdateNodeHeartbeat(nodeID string, activeTenantsCount int, leases []*enterprise.TenantLease) (string, []*enterprise.TenantLease, error) {
+	status, err := cp.updateNodeHeartbeat(nodeID, activeTenantsCount)
+	if err != nil {
+		return "", nil, err
+	}
+
+	renewed, err := cp.RenewTenantLeases(nodeID, leases)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return status, renewed, nil
+}
+
+func (cp *ControlPlane) updateNodeHeartbeat(nodeID string, activeTenantsCount int) (string, error) {
 	cp.nodesMu.Lock()
 	defer cp.nodesMu.Unlock()
 
 	node, exists := cp.nodes[nodeID]
 	if !exists {
-		return enterprise.ErrNodeNotFound
+		// The node may have registered through another control plane, e.g.
+		// before a leader change
+		stored, err := cp.storage.GetNode(nodeID)
+		if err != nil {
+			return "", enterprise.ErrNodeNotFound
+		}
+		node = stored
+		cp.nodes[nodeID] = node
+	}
+
+	// A drain may have been requested through another control plane
+	if node.Status != enterprise.NodeStatusDraining {
+		if stored, err := cp.storage.GetNode(nodeID); err == nil && stored.Status == enterprise.NodeStatusDraining {
+			node.Status = enterprise.NodeStatusDraining
+		}
 	}
 
 	node.LastHeartbeat = time.Now()
 	node.ActiveTenants = activeTenantsCount
 
-	return cp.storage.SaveNode(node)
+	return node.Status, cp.storage.SaveNode(node)
 }
 
 // GetNodes returns all registered nodes
@@ -305,6 +475,12 @@ func (cp *ControlPlane) GetNodes() []*enterprise.NodeInfo {
 	return nodes
 }
 
+// IsLeader returns true if this control plane accepts writes, i.e. it is the
+// Raft leader or runs without Raft
+func (cp *ControlPlane) IsLeader() bool {
+	return cp.raft == nil || cp.raft.IsLeader()
+}
+
 // monitorNodes monitors node health and marks unhealthy nodes as offline
 func (cp *ControlPlane) monitorNodes() {
 	defer cp.wg.Done()
@@ -333,7 +509,7 @@ func (cp *ControlPlane) checkNodeHealth() {
 	for nodeID, node := range cp.nodes {
 		if now.Sub(node.LastHeartbeat) > heartbeatTimeout {
 			if node.Status != "offline" {
-				cp.logger.Printf("[ControlPlane] Node %s marked offline (no heartbeat)", nodeID)
+				cp.logger.Info("Node marked offline (no heartbeat)", "nodeId", nodeID)
 				node.Status = "offline"
 				cp.storage.SaveNode(node)
 			}
@@ -359,7 +535,7 @@ func (cp *ControlPlane) rebalanceTenants() {
 			}
 
 			if err := cp.placement.CheckRebalance(); err != nil {
-				cp.logger.Printf("[ControlPlane] Rebalance check failed: %v", err)
+				cp.logger.Error("Rebalance check failed", "error", err)
 			}
 		}
 	}
@@ -413,9 +589,22 @@ func (cp *ControlPlane) GetTenantActivity(tenantID string) (*enterprise.TenantAc
 	return cp.storage.GetActivity(tenantID)
 }
 
-// ListInactiveTenants returns tenants inactive since the given time
+// ListInactiveTenants returns tenants inactive since the given time. Preview
+// tenants are left out, they expire instead of being archived.
 func (cp *ControlPlane) ListInactiveTenants(since time.Time) ([]*enterprise.TenantActivity, error) {
-	return cp.storage.ListInactiveTenants(since)
+	activities, err := cp.storage.ListInactiveTenants(since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := activities[:0]
+	for _, activity := range activities {
+		if tenant, err := cp.storage.GetTenant(activity.TenantID); err == nil && tenant.Preview {
+			continue
+		}
+		result = append(result, activity)
+	}
+	return result, nil
 }
 
 // CountTenantsByTier returns the number of tenants in a given storage tier
@@ -429,6 +618,14 @@ func (cp *ControlPlane) CountTenantsByTier(tier enterprise.StorageTier) (int, er
 
 // ArchiveTenant archives a tenant to the specified tier
 func (cp *ControlPlane) ArchiveTenant(tenantID string, tier enterprise.StorageTier) error {
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return err
+	}
+	if tenant.Preview {
+		return enterprise.ErrTenantPreview
+	}
+
 	// Get current activity
 	activity, err := cp.storage.GetActivity(tenantID)
 	if err != nil {
@@ -442,10 +639,20 @@ func (cp *ControlPlane) ArchiveTenant(tenantID string, tier enterprise.StorageTi
 	activity.Updated = now
 
 	// Save activity
-	return cp.storage.SaveActivity(activity)
+	if err := cp.storage.SaveActivity(activity); err != nil {
+		return err
+	}
+
+	// Cold tenants can't be loaded until a Glacier restore completes
+	if tier == enterprise.StorageTierCold {
+		return cp.storage.UpdateTenantStatus(tenantID, enterprise.TenantStatusArchived)
+	}
+
+	return nil
 }
 
-// RestoreTenant restores an archived tenant
+// RestoreTenant marks an archived tenant as hot again once its data is readable.
+// Use RequestTenantRestore to restore a tenant whose data may still be in Glacier.
 func (cp *ControlPlane) RestoreTenant(tenantID string) error {
 	// Get current activity
 	activity, err := cp.storage.GetActivity(tenantID)
@@ -2,6 +2,8 @@ package control_plane
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -327,6 +329,102 @@ func TestRaftCommandAllTypes(t *testing.T) {
 				Token: "abc123",
 			},
 		},
+		{
+			name:    "SaveRestoreJob",
+			cmdType: CommandSaveRestoreJob,
+			payload: SaveRestoreJobPayload{
+				Job: &enterprise.RestoreJob{
+					TenantID: "tenant-1",
+					Status:   enterprise.RestoreJobPending,
+					FromTier: enterprise.StorageTierCold,
+				},
+			},
+		},
+		{
+			name:    "SaveGateway",
+			cmdType: CommandSaveGateway,
+			payload: SaveGatewayPayload{
+				Gateway: &enterprise.GatewayInfo{
+					ID:      "gw-1:8082",
+					Address: "gw-1:8082",
+				},
+			},
+		},
+		{
+			name:    "SaveUsage",
+			cmdType: CommandSaveUsage,
+			payload: SaveUsagePayload{
+				Checkpoints: []*enterprise.UsageCheckpoint{
+					{TenantID: "tenant-1", Day: "2024-01-01", APIRequests: 42},
+				},
+			},
+		},
+		{
+			name:    "SaveTenantKeys",
+			cmdType: CommandSaveTenantKeys,
+			payload: SaveTenantKeysPayload{
+				Keyring: &enterprise.TenantKeyring{
+					TenantID:      "tenant-1",
+					ActiveVersion: 1,
+					Keys: []*enterprise.WrappedDataKey{
+						{Version: 1, MasterKeyID: "local:0123456789abcdef", Ciphertext: []byte("wrapped")},
+					},
+				},
+			},
+		},
+		{
+			name:    "AppendAudit",
+			cmdType: CommandAppendAudit,
+			payload: AppendAuditPayload{
+				Entry: &enterprise.AuditEntry{
+					ID:     "audit_1",
+					Actor:  "admin:ops",
+					Action: enterprise.AuditActionUserImpersonate,
+					Target: "user-1",
+				},
+			},
+		},
+		{
+			name:    "PruneAudit",
+			cmdType: CommandPruneAudit,
+			payload: PruneAuditPayload{Before: time.Now()},
+		},
+		{
+			name:    "SaveFleetMigration",
+			cmdType: CommandSaveFleetMigration,
+			payload: SaveFleetMigrationPayload{
+				Migration: &enterprise.FleetMigration{
+					ID:     "fleet_1",
+					Format: enterprise.FleetMigrationJS,
+					Status: enterprise.FleetMigrationRunning,
+				},
+			},
+		},
+		{
+			name:    "SaveFleetTenant",
+			cmdType: CommandSaveFleetTenant,
+			payload: SaveFleetTenantPayload{
+				State: &enterprise.FleetMigrationTenant{
+					MigrationID: "fleet_1",
+					TenantID:    "tenant-1",
+					Status:      enterprise.FleetMigrationTenantApplied,
+				},
+			},
+		},
+		{
+			name:    "AppendLogs",
+			cmdType: CommandAppendLogs,
+			payload: AppendLogsPayload{
+				Entries: []*enterprise.LogEntry{
+					{ID: "log_1", Level: 8, Message: "Failed to load tenant", Component: enterprise.LogComponentTenantNode, TenantID: "tenant-1"},
+				},
+			},
+		},
+		{
+			name:    "PruneLogs",
+			cmdType: CommandPruneLogs,
+			payload: PruneLogsPayload{Before: time.Now()},
+		},
 	}
 
 	for _, tt := range tests {
@@ -401,10 +499,20 @@ func TestCommandTypeConstants(t *testing.T) {
 		CommandSaveActivity:       true,
 		CommandSaveToken:          true,
 		CommandMarkTokenUsed:      true,
+		CommandSaveRestoreJob:     true,
+		CommandSaveGateway:        true,
+		CommandSaveUsage:          true,
+		CommandSaveTenantKeys:     true,
+		CommandAppendAudit:        true,
+		CommandPruneAudit:         true,
+		CommandSaveFleetMigration: true,
+		CommandSaveFleetTenant:    true,
+		CommandAppendLogs:         true,
+		CommandPruneLogs:          true,
 	}
 
-	if len(types) != 10 {
-		t.Error("expected 10 unique command types")
+	if len(types) != 20 {
+		t.Error("expected 20 unique command types")
 	}
 }
 
@@ -744,3 +852,87 @@ func TestControlPlaneConfigValidation(t *testing.T) {
 		t.Fatal("expected non-nil control plane")
 	}
 }
+
+func TestCreateTenantResidency(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	cp.config.Region = "us-east"
+	cp.config.S3Bucket = "tenants-us"
+	cp.config.Regions = map[string]*enterprise.RegionConfig{
+		"eu-west": {S3: enterprise.S3Target{Bucket: "tenants-eu"}},
+	}
+
+	if err := cp.storage.CreateUser(&enterprise.ClusterUser{ID: "user-1", MaxTenants: 5}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	tests := []struct {
+		region         string
+		expectedRegion string
+		expectedBucket string
+	}{
+		{"", "us-east", "tenants-us"},
+		{"us-east", "us-east", "tenants-us"},
+		{"eu-west", "eu-west", "tenants-eu"},
+	}
+
+	for i, tt := range tests {
+		id := fmt.Sprintf("tenant-%d", i)
+		tenant := &enterprise.Tenant{ID: id, Domain: id + ".example.com", OwnerUserID: "user-1", Region: tt.region}
+		if err := cp.CreateTenant(tenant); err != nil {
+			t.Fatalf("failed to create tenant in %q: %v", tt.region, err)
+		}
+		if tenant.Region != tt.expectedRegion || tenant.S3Bucket != tt.expectedBucket {
+			t.Errorf("expected %s in %s, got %s in %s", tt.expectedRegion, tt.expectedBucket, tenant.Region, tenant.S3Bucket)
+		}
+	}
+
+	err := cp.CreateTenant(&enterprise.Tenant{ID: "tenant-ap", Domain: "tenant-ap.example.com", OwnerUserID: "user-1", Region: "ap-south"})
+	if !errors.Is(err, enterprise.ErrUnknownRegion) {
+		t.Errorf("expected ErrUnknownRegion, got %v", err)
+	}
+
+	err = cp.RegisterNode(&enterprise.NodeInfo{ID: "node-1", Address: "localhost:8091", Region: "ap-south"})
+	if !errors.Is(err, enterprise.ErrUnknownRegion) {
+		t.Errorf("expected node in unknown region to be rejected, got %v", err)
+	}
+}
+
+func TestSetTenantAccessPolicy(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	if err := cp.storage.CreateUser(&enterprise.ClusterUser{ID: "user-1", MaxTenants: 5}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := cp.CreateTenant(&enterprise.Tenant{ID: "tenant-1", Domain: "tenant-1.example.com", OwnerUserID: "user-1"}); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	_, err := cp.SetTenantAccessPolicy("tenant-1", &enterprise.TenantAccessPolicy{BlockedCIDRs: []string{"10.0.0.0/33"}})
+	if !errors.Is(err, enterprise.ErrInvalidAccessPolicy) {
+		t.Errorf("expected ErrInvalidAccessPolicy, got %v", err)
+	}
+
+	if _, err := cp.SetTenantAccessPolicy("tenant-1", &enterprise.TenantAccessPolicy{AdminAllowlist: []string{"203.0.113.7/24"}, BlockedCountries: []string{"kp"}}); err != nil {
+		t.Fatalf("failed to set access policy: %v", err)
+	}
+
+	stored, err := cp.GetTenant("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if policy := stored.AccessPolicy; policy == nil || policy.AdminAllowlist[0] != "203.0.113.0/24" || policy.BlockedCountries[0] != "KP" {
+		t.Fatalf("expected the normalized policy to be stored, got %+v", policy)
+	}
+
+	if _, err := cp.SetTenantAccessPolicy("tenant-1", &enterprise.TenantAccessPolicy{}); err != nil {
+		t.Fatalf("failed to remove access policy: %v", err)
+	}
+
+	stored, err = cp.GetTenant("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if stored.AccessPolicy != nil {
+		t.Errorf("expected an empty policy to be removed, got %+v", stored.AccessPolicy)
+	}
+}
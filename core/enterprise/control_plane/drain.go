@@ -0,0 +1,109 @@
+package control_plane
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// MarkNodeDraining stops placing tenants on a node. The node learns about it from
+// its next heartbeat and starts handing back its tenants.
+func (cp *ControlPlane) MarkNodeDraining(nodeID string) error {
+	cp.nodesMu.Lock()
+	defer cp.nodesMu.Unlock()
+
+	// The node may heartbeat to another control plane, so go by the stored node
+	node, err := cp.storage.GetNode(nodeID)
+	if err != nil {
+		return err
+	}
+
+	if node.Status == enterprise.NodeStatusOffline {
+		return enterprise.ErrNodeOffline
+	}
+
+	node.Status = enterprise.NodeStatusDraining
+	if err := cp.storage.SaveNode(node); err != nil {
+		return err
+	}
+
+	if cached, exists := cp.nodes[nodeID]; exists {
+		cached.Status = enterprise.NodeStatusDraining
+	}
+
+	cp.logger.Info("Node marked draining", "nodeId", nodeID)
+	return nil
+}
+
+// DrainNode is called by a node that started draining. Tenants assigned to it
+// that aren't loaded are released right away; the loaded ones are released one
+// by one with ReleaseTenant once their final sync to S3 is done.
+func (cp *ControlPlane) DrainNode(nodeID string, loadedTenants []string) error {
+	if err := cp.MarkNodeDraining(nodeID); err != nil {
+		return err
+	}
+
+	loaded := make(map[string]bool, len(loadedTenants))
+	for _, tenantID := range loadedTenants {
+		loaded[tenantID] = true
+	}
+
+	tenants, err := cp.storage.ListTenantsByNode(nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to list tenants of node %s: %w", nodeID, err)
+	}
+
+	released := 0
+	for _, tenant := range tenants {
+		if loaded[tenant.ID] {
+			continue
+		}
+		if err := cp.releaseTenant(tenant); err != nil {
+			return err
+		}
+		released++
+	}
+
+	cp.logger.Info("Draining node", "nodeId", nodeID, "released", released, "loaded", len(loaded))
+	return nil
+}
+
+// ReleaseTenant unassigns a tenant a draining node has unloaded, so the next
+// request places it on another node
+func (cp *ControlPlane) ReleaseTenant(tenantID, nodeID string) error {
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return err
+	}
+
+	// Already moved elsewhere
+	if tenant.AssignedNodeID != nodeID {
+		return nil
+	}
+
+	return cp.releaseTenant(tenant)
+}
+
+func (cp *ControlPlane) releaseTenant(tenant *enterprise.Tenant) error {
+	// An admin move completes once the old node lets go of the tenant
+	if tenant.MovingToNodeID != "" {
+		moved, err := cp.completeTenantMove(tenant)
+		if moved || err != nil {
+			return err
+		}
+	}
+
+	tenant.AssignedNodeID = ""
+
+	// Archived and deleted tenants keep their status
+	switch tenant.Status {
+	case enterprise.TenantStatusAssigning, enterprise.TenantStatusDeploying,
+		enterprise.TenantStatusActive, enterprise.TenantStatusIdle:
+		tenant.Status = enterprise.TenantStatusEvicted
+	}
+
+	if err := cp.storage.UpdateTenant(tenant); err != nil {
+		return fmt.Errorf("failed to release tenant %s: %w", tenant.ID, err)
+	}
+	return nil
+}
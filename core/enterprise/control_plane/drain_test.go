@@ -0,0 +1,94 @@
+package control_plane
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func createAssignedTenant(t *testing.T, cp *ControlPlane, tenantID, nodeID string, status enterprise.TenantStatus) {
+	t.Helper()
+
+	err := cp.storage.CreateTenant(&enterprise.Tenant{
+		ID:             tenantID,
+		Domain:         tenantID + ".example.com",
+		Status:         status,
+		AssignedNodeID: nodeID,
+	})
+	if err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+}
+
+func TestDrainNodeReleasesUnloadedTenants(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	if err := cp.RegisterNode(&enterprise.NodeInfo{ID: "node-1", Address: "localhost:8091", Status: enterprise.NodeStatusOnline, Capacity: 10}); err != nil {
+		t.Fatalf("failed to register node: %v", err)
+	}
+
+	createAssignedTenant(t, cp, "loaded", "node-1", enterprise.TenantStatusActive)
+	createAssignedTenant(t, cp, "idle", "node-1", enterprise.TenantStatusIdle)
+	createAssignedTenant(t, cp, "archived", "node-1", enterprise.TenantStatusArchived)
+
+	if err := cp.DrainNode("node-1", []string{"loaded"}); err != nil {
+		t.Fatalf("failed to drain node: %v", err)
+	}
+
+	status, _, err := cp.UpdateNodeHeartbeat("node-1", 1, nil)
+	if err != nil {
+		t.Fatalf("failed to send heartbeat: %v", err)
+	}
+	if status != enterprise.NodeStatusDraining {
+		t.Errorf("expected heartbeat to report draining, got %s", status)
+	}
+
+	idle, _ := cp.storage.GetTenant("idle")
+	if idle.AssignedNodeID != "" || idle.Status != enterprise.TenantStatusEvicted {
+		t.Errorf("expected idle tenant released, got node %q status %s", idle.AssignedNodeID, idle.Status)
+	}
+
+	archived, _ := cp.storage.GetTenant("archived")
+	if archived.AssignedNodeID != "" || archived.Status != enterprise.TenantStatusArchived {
+		t.Errorf("expected archived tenant released and still archived, got node %q status %s", archived.AssignedNodeID, archived.Status)
+	}
+
+	loaded, _ := cp.storage.GetTenant("loaded")
+	if loaded.AssignedNodeID != "node-1" {
+		t.Errorf("expected loaded tenant to stay on node-1 until released, got %q", loaded.AssignedNodeID)
+	}
+
+	// The node hands the loaded tenant back after its final sync
+	if err := cp.ReleaseTenant("loaded", "node-1"); err != nil {
+		t.Fatalf("failed to release tenant: %v", err)
+	}
+
+	loaded, _ = cp.storage.GetTenant("loaded")
+	if loaded.AssignedNodeID != "" || loaded.Status != enterprise.TenantStatusEvicted {
+		t.Errorf("expected loaded tenant released, got node %q status %s", loaded.AssignedNodeID, loaded.Status)
+	}
+}
+
+func TestReleaseTenantIgnoresOtherNodes(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	createAssignedTenant(t, cp, "tenant-1", "node-2", enterprise.TenantStatusActive)
+
+	// Already moved to node-2, a late release from node-1 must not undo that
+	if err := cp.ReleaseTenant("tenant-1", "node-1"); err != nil {
+		t.Fatalf("failed to release tenant: %v", err)
+	}
+
+	tenant, _ := cp.storage.GetTenant("tenant-1")
+	if tenant.AssignedNodeID != "node-2" || tenant.Status != enterprise.TenantStatusActive {
+		t.Errorf("expected tenant to stay on node-2, got node %q status %s", tenant.AssignedNodeID, tenant.Status)
+	}
+}
+
+func TestMarkNodeDrainingUnknownNode(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	if err := cp.MarkNodeDraining("missing"); err != enterprise.ErrNodeNotFound {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package control_plane
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/email"
+)
+
+const (
+	// defaultEmailMaxAttempts is how often a queued email is tried when no limit is configured
+	defaultEmailMaxAttempts = 8
+
+	// emailDeliveryInterval is how often the outbound queue is worked through
+	emailDeliveryInterval = 5 * time.Second
+
+	// emailRetryBaseDelay is the delay after the first failed attempt, doubled
+	// after each further one up to emailRetryMaxDelay
+	emailRetryBaseDelay = 30 * time.Second
+	emailRetryMaxDelay  = time.Hour
+)
+
+// SendEmail renders a cluster email for a user in their locale and queues it
+// for delivery. Name and Year are added to data unless set. Returns
+// ErrEmailSuppressed if the user's address bounced or complained.
+func (cp *ControlPlane) SendEmail(templateName string, user *enterprise.ClusterUser, data map[string]interface{}) error {
+	if user.EmailStatus != enterprise.EmailStatusOK {
+		return enterprise.ErrEmailSuppressed
+	}
+
+	tmpl, err := cp.ResolveEmailTemplate(templateName, user.Locale)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{
+		"Name": user.Name,
+		"Year": time.Now().Year(),
+	}
+	for key, value := range data {
+		values[key] = value
+	}
+
+	subject, html, err := email.Render(tmpl, values)
+	if err != nil {
+		return fmt.Errorf("failed to render %s email: %w", templateName, err)
+	}
+
+	now := time.Now().UTC()
+	msg := &enterprise.OutboundEmail{
+		ID:          enterprise.GenerateID("email"),
+		Template:    templateName,
+		To:          user.Email,
+		Subject:     subject,
+		HTML:        html,
+		Status:      enterprise.OutboundEmailPending,
+		NextAttempt: now,
+		Created:     now,
+	}
+
+	if err := cp.storage.SaveOutboundEmail(msg); err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+
+	cp.logger.Debug("Queued email", "id", msg.ID, "template", templateName, "userId", user.ID)
+	return nil
+}
+
+// ResolveEmailTemplate returns the template sent for a locale, the most
+// specific admin override or the built-in template
+func (cp *ControlPlane) ResolveEmailTemplate(name, locale string) (*enterprise.EmailTemplate, error) {
+	return email.ResolveTemplate(name, locale, cp.storage.GetEmailTemplate)
+}
+
+// ListEmailTemplates lists the admin template overrides
+func (cp *ControlPlane) ListEmailTemplates() ([]*enterprise.EmailTemplate, error) {
+	return cp.storage.ListEmailTemplates()
+}
+
+// SaveEmailTemplate validates and stores an admin template override
+func (cp *ControlPlane) SaveEmailTemplate(tmpl *enterprise.EmailTemplate) error {
+	if err := email.ValidateTemplate(tmpl); err != nil {
+		return fmt.Errorf("%w: %v", enterprise.ErrInvalidEmailTemplate, err)
+	}
+
+	tmpl.Updated = time.Now().UTC()
+	return cp.storage.SaveEmailTemplate(tmpl)
+}
+
+// DeleteEmailTemplate removes an admin template override, reverting to the
+// next less specific template
+func (cp *ControlPlane) DeleteEmailTemplate(name, locale string) error {
+	return cp.storage.DeleteEmailTemplate(name, email.NormalizeLocale(locale))
+}
+
+// ListOutboundEmails lists the emails waiting in the outbound queue and the
+// ones that ran out of attempts
+func (cp *ControlPlane) ListOutboundEmails() ([]*enterprise.OutboundEmail, error) {
+	return cp.storage.ListOutboundEmails()
+}
+
+// RetryOutboundEmail queues a failed email again with a fresh set of attempts
+func (cp *ControlPlane) RetryOutboundEmail(id string) error {
+	queued, err := cp.storage.ListOutboundEmails()
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range queued {
+		if msg.ID != id {
+			continue
+		}
+		msg.Status = enterprise.OutboundEmailPending
+		msg.Attempts = 0
+		msg.NextAttempt = time.Now().UTC()
+		return cp.storage.SaveOutboundEmail(msg)
+	}
+
+	return enterprise.ErrOutboundEmailNotFound
+}
+
+// DeleteOutboundEmail removes an email from the outbound queue
+func (cp *ControlPlane) DeleteOutboundEmail(id string) error {
+	return cp.storage.DeleteOutboundEmail(id)
+}
+
+// FlagEmailAddress marks the cluster user with an address as bounced or
+// complained, no more emails are sent to them. Addresses without a user are
+// ignored, providers report every address they sent to.
+func (cp *ControlPlane) FlagEmailAddress(address string, status enterprise.EmailStatus) error {
+	user, err := cp.userByEmail(address)
+	if err != nil {
+		if errors.Is(err, enterprise.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.EmailStatus == status {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	user.EmailStatus = status
+	user.EmailStatusUpdated = &now
+	if err := cp.storage.UpdateUser(user); err != nil {
+		return err
+	}
+
+	cp.logger.Warn("Flagged cluster user email address", "userId", user.ID, "status", status)
+	return nil
+}
+
+// ClearEmailStatus makes a flagged address deliverable again, e.g. after the
+// user fixed their mailbox
+func (cp *ControlPlane) ClearEmailStatus(address string) error {
+	user, err := cp.userByEmail(address)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailStatus == enterprise.EmailStatusOK {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	user.EmailStatus = enterprise.EmailStatusOK
+	user.EmailStatusUpdated = &now
+	return cp.storage.UpdateUser(user)
+}
+
+// userByEmail looks a user up by address, providers may report it in another case
+func (cp *ControlPlane) userByEmail(address string) (*enterprise.ClusterUser, error) {
+	user, err := cp.storage.GetUserByEmail(address)
+	if errors.Is(err, enterprise.ErrUserNotFound) && address != strings.ToLower(address) {
+		return cp.storage.GetUserByEmail(strings.ToLower(address))
+	}
+	return user, err
+}
+
+// EmailBaseURL returns the configured public URL of the cluster API used in
+// email links, empty if links should use the request's host
+func (cp *ControlPlane) EmailBaseURL() string {
+	return strings.TrimRight(cp.config.Email.BaseURL, "/")
+}
+
+// ValidEmailWebhookToken checks the token of a provider webhook request.
+// Webhooks are disabled without a configured secret.
+func (cp *ControlPlane) ValidEmailWebhookToken(token string) bool {
+	secret := cp.config.Email.WebhookSecret
+	if secret == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1
+}
+
+// emailMaxAttempts returns the configured attempts of a queued email
+func (cp *ControlPlane) emailMaxAttempts() int {
+	if cp.config.Email.MaxAttempts > 0 {
+		return cp.config.Email.MaxAttempts
+	}
+	return defaultEmailMaxAttempts
+}
+
+// emailRetryDelay returns the delay after the given number of failed attempts
+func emailRetryDelay(attempts int) time.Duration {
+	delay := emailRetryBaseDelay
+	for i := 1; i < attempts && delay < emailRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, emailRetryMaxDelay)
+}
+
+// deliverEmails periodically sends the emails due in the outbound queue
+func (cp *ControlPlane) deliverEmails() {
+	defer cp.wg.Done()
+
+	ticker := time.NewTicker(emailDeliveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cp.ctx.Done():
+			return
+		case <-ticker.C:
+			// Only leader should send, so each email goes out once
+			if cp.raft != nil && !cp.raft.IsLeader() {
+				continue
+			}
+
+			cp.deliverDueEmails(time.Now().UTC())
+		}
+	}
+}
+
+// deliverDueEmails tries each pending email due at now once. Sent emails are
+// removed, failed ones are retried with a backoff until they run out of attempts.
+func (cp *ControlPlane) deliverDueEmails(now time.Time) {
+	queued, err := cp.storage.ListOutboundEmails()
+	if err != nil {
+		cp.logger.Error("Failed to list outbound emails", "error", err)
+		return
+	}
+
+	for _, msg := range queued {
+		if msg.Status != enterprise.OutboundEmailPending || msg.NextAttempt.After(now) {
+			continue
+		}
+
+		// The address may have bounced since the email was queued
+		if user, err := cp.userByEmail(msg.To); err == nil && user.EmailStatus != enterprise.EmailStatusOK {
+			cp.logger.Info("Dropped email to flagged address", "id", msg.ID, "userId", user.ID, "status", user.EmailStatus)
+			if err := cp.storage.DeleteOutboundEmail(msg.ID); err != nil {
+				cp.logger.Error("Failed to remove outbound email", "id", msg.ID, "error", err)
+			}
+			continue
+		}
+
+		sendErr := cp.email.Send(msg.To, msg.Subject, msg.HTML)
+		if sendErr == nil {
+			if err := cp.storage.DeleteOutboundEmail(msg.ID); err != nil {
+				cp.logger.Error("Failed to remove sent email", "id", msg.ID, "error", err)
+			}
+			continue
+		}
+
+		msg.Attempts++
+		msg.LastError = sendErr.Error()
+		if msg.Attempts >= cp.emailMaxAttempts() {
+			msg.Status = enterprise.OutboundEmailFailed
+			cp.logger.Error("Email delivery failed", "id", msg.ID, "template", msg.Template, "attempts", msg.Attempts, "error", sendErr)
+		} else {
+			msg.NextAttempt = now.Add(emailRetryDelay(msg.Attempts))
+			cp.logger.Warn("Email delivery attempt failed", "id", msg.ID, "attempts", msg.Attempts, "error", sendErr)
+		}
+
+		if err := cp.storage.SaveOutboundEmail(msg); err != nil {
+			cp.logger.Error("Failed to update outbound email", "id", msg.ID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package control_plane
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/email"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+// stubMailer records sent messages and fails while err is set
+type stubMailer struct {
+	mu   sync.Mutex
+	sent []*mailer.Message
+	err  error
+}
+
+func (m *stubMailer) Send(message *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func newTestEmailControlPlane(t *testing.T) (*ControlPlane, *stubMailer) {
+	cp := newTestControlPlaneWithStorage(t)
+	m := &stubMailer{}
+	cp.email = email.NewService(m, mail.Address{Address: "noreply@example.com"})
+	return cp, m
+}
+
+func createEmailUser(t *testing.T, cp *ControlPlane, address, locale string) *enterprise.ClusterUser {
+	t.Helper()
+
+	user := &enterprise.ClusterUser{
+		ID:     enterprise.GenerateUserID(),
+		Email:  address,
+		Name:   "Ada",
+		Locale: locale,
+	}
+	if err := cp.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func TestSendEmailUsesLocaleTemplate(t *testing.T) {
+	cp, m := newTestEmailControlPlane(t)
+
+	err := cp.SaveEmailTemplate(&enterprise.EmailTemplate{
+		Name:    email.TemplateVerification,
+		Locale:  "pt",
+		Subject: "Verifique seu email, {{.Name}}",
+		HTML:    `<a href="{{.VerificationURL}}">Verificar</a>`,
+	})
+	if err != nil {
+		t.Fatalf("failed to save template: %v", err)
+	}
+
+	brazilian := createEmailUser(t, cp, "ada@example.com", "pt-BR")
+	english := createEmailUser(t, cp, "grace@example.com", "en")
+
+	data := map[string]interface{}{"VerificationURL": "https://example.com/verify?token=a&b"}
+	if err := cp.SendEmail(email.TemplateVerification, brazilian, data); err != nil {
+		t.Fatalf("failed to queue email: %v", err)
+	}
+	if err := cp.SendEmail(email.TemplateVerification, english, data); err != nil {
+		t.Fatalf("failed to queue email: %v", err)
+	}
+
+	cp.deliverDueEmails(time.Now().UTC())
+
+	if len(m.sent) != 2 {
+		t.Fatalf("expected 2 sent emails, got %d", len(m.sent))
+	}
+
+	subjects := map[string]string{}
+	for _, msg := range m.sent {
+		subjects[msg.To[0].Address] = msg.Subject
+	}
+	if subjects["ada@example.com"] != "Verifique seu email, Ada" {
+		t.Errorf("expected the pt override for pt-BR, got %q", subjects["ada@example.com"])
+	}
+	if subjects["grace@example.com"] != "Verify Your Email" {
+		t.Errorf("expected the built-in template for en, got %q", subjects["grace@example.com"])
+	}
+
+	queued, _ := cp.ListOutboundEmails()
+	if len(queued) != 0 {
+		t.Errorf("expected sent emails to leave the queue, got %d", len(queued))
+	}
+}
+
+func TestDeliverEmailsRetriesWithBackoff(t *testing.T) {
+	cp, m := newTestEmailControlPlane(t)
+	cp.config.Email.MaxAttempts = 3
+
+	user := createEmailUser(t, cp, "ada@example.com", "")
+	if err := cp.SendEmail(email.TemplateVerification, user, nil); err != nil {
+		t.Fatalf("failed to queue email: %v", err)
+	}
+
+	m.err = errors.New("provider down")
+	now := time.Now().UTC()
+
+	cp.deliverDueEmails(now)
+	queued, _ := cp.ListOutboundEmails()
+	if len(queued) != 1 || queued[0].Attempts != 1 || queued[0].Status != enterprise.OutboundEmailPending {
+		t.Fatalf("expected one pending email after one attempt, got %+v", queued)
+	}
+	if !queued[0].NextAttempt.Equal(now.Add(emailRetryBaseDelay)) {
+		t.Errorf("expected the next attempt after %s, got %s", emailRetryBaseDelay, queued[0].NextAttempt.Sub(now))
+	}
+	if queued[0].LastError != "provider down" {
+		t.Errorf("expected the provider error to be kept, got %q", queued[0].LastError)
+	}
+
+	// Not due yet
+	cp.deliverDueEmails(now.Add(time.Second))
+	if queued, _ := cp.ListOutboundEmails(); queued[0].Attempts != 1 {
+		t.Fatalf("expected no attempt before the backoff, got %d", queued[0].Attempts)
+	}
+
+	cp.deliverDueEmails(now.Add(time.Hour))
+	cp.deliverDueEmails(now.Add(3 * time.Hour))
+
+	queued, _ = cp.ListOutboundEmails()
+	if len(queued) != 1 || queued[0].Status != enterprise.OutboundEmailFailed || queued[0].Attempts != 3 {
+		t.Fatalf("expected the email to fail after 3 attempts, got %+v", queued)
+	}
+
+	// A failed email is retried only when an admin asks for it
+	m.err = nil
+	cp.deliverDueEmails(now.Add(4 * time.Hour))
+	if len(m.sent) != 0 {
+		t.Fatalf("expected failed emails to stay unsent, got %d", len(m.sent))
+	}
+
+	if err := cp.RetryOutboundEmail(queued[0].ID); err != nil {
+		t.Fatalf("failed to retry email: %v", err)
+	}
+	cp.deliverDueEmails(time.Now().UTC().Add(time.Second))
+	if len(m.sent) != 1 {
+		t.Fatalf("expected the retried email to be sent, got %d", len(m.sent))
+	}
+}
+
+func TestEmailRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if delay := emailRetryDelay(tt.attempts); delay != tt.expected {
+			t.Errorf("attempts %d: expected %s, got %s", tt.attempts, tt.expected, delay)
+		}
+	}
+}
+
+func TestFlaggedAddressGetsNoEmail(t *testing.T) {
+	cp, m := newTestEmailControlPlane(t)
+
+	user := createEmailUser(t, cp, "ada@example.com", "")
+	if err := cp.SendEmail(email.TemplateVerification, user, nil); err != nil {
+		t.Fatalf("failed to queue email: %v", err)
+	}
+
+	// Providers may report the address in another case
+	if err := cp.FlagEmailAddress("ADA@example.com", enterprise.EmailStatusBounced); err != nil {
+		t.Fatalf("failed to flag address: %v", err)
+	}
+	if err := cp.FlagEmailAddress("unknown@example.com", enterprise.EmailStatusComplained); err != nil {
+		t.Fatalf("expected unknown addresses to be ignored, got %v", err)
+	}
+
+	// The email queued before the bounce is dropped
+	cp.deliverDueEmails(time.Now().UTC())
+	if len(m.sent) != 0 {
+		t.Fatalf("expected no email to a bounced address, got %d", len(m.sent))
+	}
+	if queued, _ := cp.ListOutboundEmails(); len(queued) != 0 {
+		t.Errorf("expected the email to be dropped, got %d queued", len(queued))
+	}
+
+	user, _ = cp.GetUser(user.ID)
+	if user.EmailStatus != enterprise.EmailStatusBounced || user.EmailStatusUpdated == nil {
+		t.Fatalf("expected the user to be flagged, got %q", user.EmailStatus)
+	}
+	if err := cp.SendEmail(email.TemplateVerification, user, nil); !errors.Is(err, enterprise.ErrEmailSuppressed) {
+		t.Fatalf("expected ErrEmailSuppressed, got %v", err)
+	}
+
+	if err := cp.ClearEmailStatus(user.Email); err != nil {
+		t.Fatalf("failed to clear status: %v", err)
+	}
+	user, _ = cp.GetUser(user.ID)
+	if err := cp.SendEmail(email.TemplateVerification, user, nil); err != nil {
+		t.Fatalf("expected a cleared address to get email, got %v", err)
+	}
+}
+
+func TestSaveEmailTemplateValidates(t *testing.T) {
+	cp, _ := newTestEmailControlPlane(t)
+
+	invalid := []*enterprise.EmailTemplate{
+		{Name: "unknown", Subject: "s", HTML: "h"},
+		{Name: email.TemplateVerification, Subject: "", HTML: "h"},
+		{Name: email.TemplateVerification, Subject: "s", HTML: "{{.Broken"},
+	}
+	for _, tmpl := range invalid {
+		if err := cp.SaveEmailTemplate(tmpl); !errors.Is(err, enterprise.ErrInvalidEmailTemplate) {
+			t.Errorf("expected ErrInvalidEmailTemplate for %+v, got %v", tmpl, err)
+		}
+	}
+
+	err := cp.SaveEmailTemplate(&enterprise.EmailTemplate{Name: email.TemplateVerification, Locale: "de_de", Subject: "s", HTML: "h"})
+	if err != nil {
+		t.Fatalf("failed to save template: %v", err)
+	}
+	if _, err := cp.storage.GetEmailTemplate(email.TemplateVerification, "de-DE"); err != nil {
+		t.Errorf("expected the locale to be normalized: %v", err)
+	}
+
+	if err := cp.DeleteEmailTemplate(email.TemplateVerification, "DE-de"); err != nil {
+		t.Fatalf("failed to delete template: %v", err)
+	}
+	tmpl, err := cp.ResolveEmailTemplate(email.TemplateVerification, "de-DE")
+	if err != nil || !strings.Contains(tmpl.Subject, "Verify") {
+		t.Errorf("expected the built-in template after deleting the override, got %v %v", tmpl, err)
+	}
+}
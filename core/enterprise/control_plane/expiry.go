@@ -0,0 +1,253 @@
+package control_plane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/email"
+	storagepkg "github.com/pocketbase/pocketbase/core/enterprise/storage"
+)
+
+const (
+	// tenantReapInterval is how often expiring tenants are checked
+	tenantReapInterval = 1 * time.Minute
+
+	// tenantExpiryNotice is how long before its expiry the owner of a tenant is
+	// told that it will be deleted
+	tenantExpiryNotice = 24 * time.Hour
+
+	// tenantExpiryGrace is the least time between the notice and the deletion,
+	// for tenants whose owner was told late, e.g. because of a short TTL
+	tenantExpiryGrace = 1 * time.Hour
+
+	// tenantPurgeDelay is how long an expired tenant stays soft deleted before
+	// its data is removed, which gives the node serving it time to unload it
+	// without writing to S3 after the removal
+	tenantPurgeDelay = 5 * time.Minute
+
+	// tenantReaperActor is the audit actor of expired tenant deletions
+	tenantReaperActor = "system:tenant_reaper"
+)
+
+// TenantDataDeleter removes the S3 objects of a tenant from a bucket
+type TenantDataDeleter interface {
+	DeleteTenantData(ctx context.Context, tenant *enterprise.Tenant) error
+}
+
+// validateTenantExpiry checks that an expiry is in the future, but no further
+// than MaxTenantTTL
+func validateTenantExpiry(expiresAt, now time.Time) error {
+	if !expiresAt.After(now) {
+		return fmt.Errorf("%w: %s is in the past", enterprise.ErrInvalidTenantExpiry, expiresAt.Format(time.RFC3339))
+	}
+	if expiresAt.Sub(now) > enterprise.MaxTenantTTL {
+		return fmt.Errorf("%w: tenants can expire at most %s from now", enterprise.ErrInvalidTenantExpiry, enterprise.MaxTenantTTL)
+	}
+	return nil
+}
+
+// ExtendTenantExpiry moves the expiry of a tenant by the given duration, from
+// now if it already passed. The owner is told again before the new expiry.
+func (cp *ControlPlane) ExtendTenantExpiry(tenantID string, by time.Duration) (*enterprise.Tenant, error) {
+	if by <= 0 {
+		return nil, fmt.Errorf("%w: the extension must be positive", enterprise.ErrInvalidTenantExpiry)
+	}
+
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tenant.Status == enterprise.TenantStatusDeleted {
+		return nil, enterprise.ErrTenantNotFound
+	}
+	if tenant.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: the tenant doesn't expire", enterprise.ErrInvalidTenantExpiry)
+	}
+
+	now := time.Now()
+	expiresAt := *tenant.ExpiresAt
+	if expiresAt.Before(now) {
+		expiresAt = now
+	}
+	expiresAt = expiresAt.Add(by)
+
+	if err := validateTenantExpiry(expiresAt, now); err != nil {
+		return nil, err
+	}
+
+	tenant.ExpiresAt = &expiresAt
+	tenant.ExpiryNotified = nil
+	tenant.Updated = now
+
+	if err := cp.storage.UpdateTenant(tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// dataDeletersFor returns the deleters of the buckets a tenant's data is
+// stored in, its region's bucket and DR bucket. None are returned when S3
+// isn't configured.
+func (cp *ControlPlane) dataDeletersFor(tenant *enterprise.Tenant) ([]TenantDataDeleter, error) {
+	cp.dataDeletersMu.Lock()
+	defer cp.dataDeletersMu.Unlock()
+
+	if deleters, ok := cp.dataDeleters[tenant.Region]; ok {
+		return deleters, nil
+	}
+
+	storage, ok := cp.config.RegionStorage(tenant.Region)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", enterprise.ErrUnknownRegion, tenant.Region)
+	}
+
+	var deleters []TenantDataDeleter
+	for _, target := range []enterprise.S3Target{storage.S3, storage.LitestreamDR} {
+		if target.Bucket == "" {
+			continue
+		}
+
+		backend, err := storagepkg.NewS3Backend(cp.ctx, target.Endpoint, target.Region, target.Bucket, target.AccessKeyID, target.SecretAccessKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to bucket %s: %w", target.Bucket, err)
+		}
+		deleters = append(deleters, backend)
+	}
+
+	if cp.dataDeleters == nil {
+		cp.dataDeleters = make(map[string][]TenantDataDeleter)
+	}
+	cp.dataDeleters[tenant.Region] = deleters
+	return deleters, nil
+}
+
+// reapExpiredTenants periodically deletes the tenants that expired
+func (cp *ControlPlane) reapExpiredTenants() {
+	defer cp.wg.Done()
+
+	ticker := time.NewTicker(tenantReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cp.ctx.Done():
+			return
+		case <-ticker.C:
+			// Only leader should reap, so each owner is told once
+			if cp.raft != nil && !cp.raft.IsLeader() {
+				continue
+			}
+
+			cp.reapTenants(time.Now())
+		}
+	}
+}
+
+// reapTenants advances each tenant expiring within the notice period by one
+// step: the owner is told, then the expired tenant is soft deleted and once
+// its node had time to unload it, its data and records are removed
+func (cp *ControlPlane) reapTenants(now time.Time) {
+	tenants, err := cp.storage.ListExpiringTenants(now.Add(tenantExpiryNotice))
+	if err != nil {
+		cp.logger.Error("Failed to list expiring tenants", "error", err)
+		return
+	}
+
+	for _, tenant := range tenants {
+		if err := cp.reapTenant(tenant, now); err != nil {
+			cp.logger.Error("Failed to reap expiring tenant", "tenantId", tenant.ID, "error", err)
+		}
+	}
+}
+
+// reapTenant moves an expiring tenant to its next step
+func (cp *ControlPlane) reapTenant(tenant *enterprise.Tenant, now time.Time) error {
+	switch {
+	case tenant.Status == enterprise.TenantStatusDeleted:
+		if now.Sub(tenant.Updated) < tenantPurgeDelay {
+			return nil
+		}
+		return cp.purgeTenant(tenant)
+
+	case tenant.ExpiryNotified == nil:
+		return cp.notifyTenantExpiry(tenant, now)
+
+	case tenant.Expired(now) && now.Sub(*tenant.ExpiryNotified) >= tenantExpiryGrace:
+		if err := cp.DeleteTenant(tenant.ID); err != nil {
+			return err
+		}
+
+		cp.recordReaperAudit(tenant.ID, map[string]*enterprise.AuditChange{
+			"status": {Before: tenant.Status, After: enterprise.TenantStatusDeleted},
+		})
+		cp.logger.Info("Deleted expired tenant", "tenantId", tenant.ID, "expiresAt", tenant.ExpiresAt)
+	}
+
+	return nil
+}
+
+// notifyTenantExpiry emails the owner of a tenant about its upcoming deletion.
+// Owners that can't be emailed count as told, their tenants still expire.
+func (cp *ControlPlane) notifyTenantExpiry(tenant *enterprise.Tenant, now time.Time) error {
+	user, err := cp.storage.GetUser(tenant.OwnerUserID)
+	if err != nil && !errors.Is(err, enterprise.ErrUserNotFound) {
+		return err
+	}
+
+	if user != nil {
+		err := cp.SendEmail(email.TemplateTenantExpiring, user, map[string]interface{}{
+			"TenantID":  tenant.ID,
+			"Domain":    tenant.Domain,
+			"ExpiresAt": tenant.ExpiresAt.UTC().Format("January 2, 2006 15:04 MST"),
+		})
+		if err != nil && !errors.Is(err, enterprise.ErrEmailSuppressed) {
+			return fmt.Errorf("failed to send expiry notice: %w", err)
+		}
+	}
+
+	notified := now
+	tenant.ExpiryNotified = &notified
+	return cp.storage.UpdateTenant(tenant)
+}
+
+// purgeTenant removes the S3 data of a deleted tenant from every bucket it is
+// stored in, then the tenant itself. A failed removal is retried by the next run.
+func (cp *ControlPlane) purgeTenant(tenant *enterprise.Tenant) error {
+	deleters, err := cp.dataDeletersFor(tenant)
+	if err != nil {
+		return err
+	}
+
+	for _, deleter := range deleters {
+		if err := deleter.DeleteTenantData(cp.ctx, tenant); err != nil {
+			return fmt.Errorf("failed to delete tenant data: %w", err)
+		}
+	}
+
+	if err := cp.storage.DeleteTenant(tenant.ID); err != nil {
+		return err
+	}
+
+	cp.recordReaperAudit(tenant.ID, map[string]*enterprise.AuditChange{
+		"s3Prefix": {Before: tenant.S3Prefix},
+	})
+	cp.logger.Info("Purged expired tenant", "tenantId", tenant.ID, "buckets", len(deleters))
+	return nil
+}
+
+// recordReaperAudit records a deletion step of the reaper in the audit log
+func (cp *ControlPlane) recordReaperAudit(tenantID string, changes map[string]*enterprise.AuditChange) {
+	entry := &enterprise.AuditEntry{
+		Actor:   tenantReaperActor,
+		Action:  enterprise.AuditActionTenantExpire,
+		Target:  tenantID,
+		Changes: changes,
+	}
+
+	if err := cp.RecordAudit(entry); err != nil {
+		cp.logger.Error("Failed to record audit entry", "action", entry.Action, "target", tenantID, "error", err)
+	}
+}
@@ -0,0 +1,246 @@
+package control_plane
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/email"
+)
+
+// fakeDataDeleter implements TenantDataDeleter for testing
+type fakeDataDeleter struct {
+	deleted []string
+	err     error
+}
+
+func (f *fakeDataDeleter) DeleteTenantData(ctx context.Context, tenant *enterprise.Tenant) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.deleted = append(f.deleted, tenant.S3Prefix)
+	return nil
+}
+
+func TestCreatePreviewTenant(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	tenant := &enterprise.Tenant{ID: "tenant_pr-1", Domain: "pr-1.example.com", OwnerUserID: "user-1", Preview: true}
+	if err := cp.CreateTenant(tenant); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	storageQuota, requestsQuota := enterprise.PreviewTenantQuotas()
+	if tenant.StorageQuotaMB != storageQuota || tenant.APIRequestsQuota != requestsQuota {
+		t.Errorf("expected the preview quotas, got %d MB and %d requests", tenant.StorageQuotaMB, tenant.APIRequestsQuota)
+	}
+
+	if tenant.ExpiresAt == nil {
+		t.Fatal("expected a preview tenant to expire")
+	}
+	if ttl := time.Until(*tenant.ExpiresAt); ttl < enterprise.DefaultPreviewTTL-time.Minute || ttl > enterprise.DefaultPreviewTTL {
+		t.Errorf("expected the default preview TTL, got %s", ttl)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	err := cp.CreateTenant(&enterprise.Tenant{ID: "tenant_pr-2", Domain: "pr-2.example.com", OwnerUserID: "user-1", ExpiresAt: &past})
+	if !errors.Is(err, enterprise.ErrInvalidTenantExpiry) {
+		t.Errorf("expected an expiry in the past to be rejected, got %v", err)
+	}
+
+	tooLate := time.Now().Add(enterprise.MaxTenantTTL + time.Hour)
+	err = cp.CreateTenant(&enterprise.Tenant{ID: "tenant_pr-2", Domain: "pr-2.example.com", OwnerUserID: "user-1", ExpiresAt: &tooLate})
+	if !errors.Is(err, enterprise.ErrInvalidTenantExpiry) {
+		t.Errorf("expected an expiry past the max TTL to be rejected, got %v", err)
+	}
+}
+
+func TestPreviewTenantsAreNotArchived(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	for _, tenant := range []*enterprise.Tenant{
+		{ID: "tenant_main", Domain: "main.example.com", OwnerUserID: "user-1"},
+		{ID: "tenant_pr-1", Domain: "pr-1.example.com", OwnerUserID: "user-1", Preview: true},
+	} {
+		if err := cp.CreateTenant(tenant); err != nil {
+			t.Fatalf("failed to create tenant: %v", err)
+		}
+		activity := &enterprise.TenantActivity{TenantID: tenant.ID, LastAccess: time.Now().Add(-30 * 24 * time.Hour)}
+		if err := cp.storage.SaveActivity(activity); err != nil {
+			t.Fatalf("failed to save activity: %v", err)
+		}
+	}
+
+	inactive, err := cp.ListInactiveTenants(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("failed to list inactive tenants: %v", err)
+	}
+	if len(inactive) != 1 || inactive[0].TenantID != "tenant_main" {
+		t.Errorf("expected only tenant_main to be inactive, got %v", inactive)
+	}
+
+	if err := cp.ArchiveTenant("tenant_pr-1", enterprise.StorageTierWarm); !errors.Is(err, enterprise.ErrTenantPreview) {
+		t.Errorf("expected ErrTenantPreview, got %v", err)
+	}
+}
+
+func TestExtendTenantExpiry(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	expiresAt := time.Now().Add(2 * time.Hour)
+	tenant := &enterprise.Tenant{ID: "tenant_pr-1", Domain: "pr-1.example.com", OwnerUserID: "user-1", ExpiresAt: &expiresAt}
+	if err := cp.CreateTenant(tenant); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	cp.reapTenants(time.Now())
+
+	extended, err := cp.ExtendTenantExpiry(tenant.ID, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to extend tenant: %v", err)
+	}
+	if !extended.ExpiresAt.Equal(expiresAt.Add(24 * time.Hour)) {
+		t.Errorf("expected the expiry to move by a day, got %v", extended.ExpiresAt)
+	}
+	if extended.ExpiryNotified != nil {
+		t.Error("expected the owner to be told again before the new expiry")
+	}
+
+	if _, err := cp.ExtendTenantExpiry(tenant.ID, enterprise.MaxTenantTTL); !errors.Is(err, enterprise.ErrInvalidTenantExpiry) {
+		t.Errorf("expected an extension past the max TTL to be rejected, got %v", err)
+	}
+
+	if err := cp.CreateTenant(&enterprise.Tenant{ID: "tenant_main", Domain: "main.example.com", OwnerUserID: "user-1"}); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+	if _, err := cp.ExtendTenantExpiry("tenant_main", time.Hour); !errors.Is(err, enterprise.ErrInvalidTenantExpiry) {
+		t.Errorf("expected a tenant without expiry to be rejected, got %v", err)
+	}
+}
+
+func TestReapExpiredTenant(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	deleter := &fakeDataDeleter{}
+	cp.dataDeleters = map[string][]TenantDataDeleter{"": {deleter}}
+
+	expiresAt := time.Now().Add(2 * time.Hour)
+	tenant := &enterprise.Tenant{ID: "tenant_pr-1", Domain: "pr-1.example.com", OwnerUserID: "user-1", Preview: true, ExpiresAt: &expiresAt}
+	if err := cp.CreateTenant(tenant); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+	if _, err := cp.AcquireTenantLease(tenant.ID, "node-1"); err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+
+	// the owner is told first
+	cp.reapTenants(time.Now())
+
+	stored, err := cp.GetTenant(tenant.ID)
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if stored.ExpiryNotified == nil || stored.Status == enterprise.TenantStatusDeleted {
+		t.Fatalf("expected the owner to be told before the deletion, got %+v", stored)
+	}
+
+	queued, err := cp.ListOutboundEmails()
+	if err != nil {
+		t.Fatalf("failed to list emails: %v", err)
+	}
+	if len(queued) != 1 || queued[0].Template != email.TemplateTenantExpiring || queued[0].To != "user-1@example.com" {
+		t.Fatalf("expected an expiry notice to the owner, got %v", queued)
+	}
+
+	// then soft deleted once expired, which ends the node's lease
+	cp.reapTenants(expiresAt.Add(time.Minute))
+
+	stored, err = cp.GetTenant(tenant.ID)
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if stored.Status != enterprise.TenantStatusDeleted {
+		t.Fatalf("expected the expired tenant to be deleted, got %s", stored.Status)
+	}
+
+	renewed, err := cp.RenewTenantLeases("node-1", []*enterprise.TenantLease{{TenantID: tenant.ID, Epoch: 1}})
+	if err != nil {
+		t.Fatalf("failed to renew leases: %v", err)
+	}
+	if len(renewed) != 0 {
+		t.Error("expected the lease of the deleted tenant to be lost")
+	}
+
+	// the data stays until the node had time to unload the tenant
+	cp.reapTenants(time.Now())
+	if len(deleter.deleted) != 0 {
+		t.Fatal("expected the data to be kept during the purge delay")
+	}
+
+	deleter.err = errors.New("s3 unavailable")
+	cp.reapTenants(time.Now().Add(tenantPurgeDelay))
+	if _, err := cp.GetTenant(tenant.ID); err != nil {
+		t.Fatalf("expected the tenant to be kept when its data can't be deleted, got %v", err)
+	}
+
+	deleter.err = nil
+	cp.reapTenants(time.Now().Add(tenantPurgeDelay))
+
+	if len(deleter.deleted) != 1 || deleter.deleted[0] != tenant.S3Prefix {
+		t.Errorf("expected the data under %s to be deleted, got %v", tenant.S3Prefix, deleter.deleted)
+	}
+	if _, err := cp.GetTenant(tenant.ID); !errors.Is(err, enterprise.ErrTenantNotFound) {
+		t.Errorf("expected the tenant to be removed, got %v", err)
+	}
+	if _, err := cp.storage.GetTenantLease(tenant.ID); !errors.Is(err, enterprise.ErrTenantLeaseNotFound) {
+		t.Errorf("expected the lease to be removed, got %v", err)
+	}
+
+	entries, err := cp.ListAuditEntries(&enterprise.AuditFilter{Action: enterprise.AuditActionTenantExpire})
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Actor != tenantReaperActor {
+		t.Errorf("expected the deletion and the purge to be audited, got %v", entries)
+	}
+}
+
+func TestReapExpiredTenantWaitsForTheNotice(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	expiresAt := time.Now().Add(time.Hour)
+	tenant := &enterprise.Tenant{ID: "tenant_pr-1", Domain: "pr-1.example.com", OwnerUserID: "user-1", ExpiresAt: &expiresAt}
+	if err := cp.CreateTenant(tenant); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	// the reaper didn't run before the expiry, the owner still gets the grace period
+	expired := expiresAt.Add(time.Minute)
+	cp.reapTenants(expired)
+	cp.reapTenants(expired.Add(time.Minute))
+
+	stored, err := cp.GetTenant(tenant.ID)
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if stored.Status == enterprise.TenantStatusDeleted {
+		t.Fatal("expected the tenant to be kept during the grace period")
+	}
+
+	cp.reapTenants(expired.Add(tenantExpiryGrace))
+
+	stored, err = cp.GetTenant(tenant.ID)
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if stored.Status != enterprise.TenantStatusDeleted {
+		t.Errorf("expected the tenant to be deleted after the grace period, got %s", stored.Status)
+	}
+}
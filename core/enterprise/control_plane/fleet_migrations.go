@@ -0,0 +1,351 @@
+package control_plane
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+const (
+	// fleetMigrationInterval is how often eager rollouts are advanced
+	fleetMigrationInterval = 30 * time.Second
+
+	// fleetMigrationScheduleTimeout is how long a tenant node gets to apply a
+	// scheduled migration before the tenant is scheduled again
+	fleetMigrationScheduleTimeout = 10 * time.Minute
+)
+
+// CreateFleetMigration validates a fleet migration and starts rolling it out
+func (cp *ControlPlane) CreateFleetMigration(migration *enterprise.FleetMigration) error {
+	if err := migration.Validate(); err != nil {
+		return err
+	}
+
+	// Catch malformed collection exports before they fail on every tenant
+	if migration.Format == enterprise.FleetMigrationJSON {
+		var collections []map[string]interface{}
+		if err := json.Unmarshal([]byte(migration.Script), &collections); err != nil {
+			return fmt.Errorf("script must be a JSON array of collections: %w", err)
+		}
+	}
+
+	now := time.Now()
+	migration.ID = enterprise.GenerateID("fleet")
+	migration.Status = enterprise.FleetMigrationRunning
+	migration.Created = now
+	migration.Updated = now
+
+	if err := cp.storage.SaveFleetMigration(migration); err != nil {
+		return fmt.Errorf("failed to save fleet migration: %w", err)
+	}
+
+	cp.logger.Info("Fleet migration started", "migrationId", migration.ID, "name", migration.Name, "mode", migration.Mode, "canaryPercent", migration.CanaryPercent)
+	return nil
+}
+
+// GetFleetMigration returns a fleet migration
+func (cp *ControlPlane) GetFleetMigration(migrationID string) (*enterprise.FleetMigration, error) {
+	return cp.storage.GetFleetMigration(migrationID)
+}
+
+// ListFleetMigrations returns all fleet migrations, oldest first
+func (cp *ControlPlane) ListFleetMigrations() ([]*enterprise.FleetMigration, error) {
+	return cp.storage.ListFleetMigrations()
+}
+
+// SetFleetMigrationStatus pauses, resumes or aborts a fleet migration.
+// Aborting stops the rollout but doesn't revert tenants that already applied it.
+func (cp *ControlPlane) SetFleetMigrationStatus(migrationID string, status enterprise.FleetMigrationStatus) (*enterprise.FleetMigration, error) {
+	switch status {
+	case enterprise.FleetMigrationRunning, enterprise.FleetMigrationPaused, enterprise.FleetMigrationAborted:
+	default:
+		return nil, fmt.Errorf("invalid fleet migration status: %s", status)
+	}
+
+	return cp.updateFleetMigration(migrationID, func(migration *enterprise.FleetMigration) {
+		migration.Status = status
+	})
+}
+
+// SetFleetMigrationCanary changes the share of tenants a fleet migration is rolled out to.
+// Lowering it stops the rollout to the dropped tenants but doesn't revert them.
+func (cp *ControlPlane) SetFleetMigrationCanary(migrationID string, percent int) (*enterprise.FleetMigration, error) {
+	if percent < 1 || percent > 100 {
+		return nil, fmt.Errorf("canaryPercent must be between 1 and 100")
+	}
+
+	return cp.updateFleetMigration(migrationID, func(migration *enterprise.FleetMigration) {
+		migration.CanaryPercent = percent
+	})
+}
+
+// updateFleetMigration applies an admin change to a migration that is still rolling out
+func (cp *ControlPlane) updateFleetMigration(migrationID string, update func(*enterprise.FleetMigration)) (*enterprise.FleetMigration, error) {
+	cp.fleetMu.Lock()
+	defer cp.fleetMu.Unlock()
+
+	migration, err := cp.storage.GetFleetMigration(migrationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if migration.IsFinished() {
+		return nil, enterprise.ErrFleetMigrationFinished
+	}
+
+	update(migration)
+	migration.Updated = time.Now()
+
+	if err := cp.storage.SaveFleetMigration(migration); err != nil {
+		return nil, fmt.Errorf("failed to save fleet migration: %w", err)
+	}
+
+	return migration, nil
+}
+
+// GetFleetMigrationProgress returns the rollout progress of a migration and its failed tenants
+func (cp *ControlPlane) GetFleetMigrationProgress(migration *enterprise.FleetMigration) (*enterprise.FleetMigrationProgress, []*enterprise.FleetMigrationTenant, error) {
+	tenants, _, err := cp.storage.ListTenants(0, 0, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	states, err := cp.storage.ListFleetMigrationTenants(migration.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list fleet migration tenants: %w", err)
+	}
+
+	progress, _ := fleetMigrationProgress(migration, tenants, states, time.Now())
+
+	failures := make([]*enterprise.FleetMigrationTenant, 0)
+	for _, state := range states {
+		if state.Status == enterprise.FleetMigrationTenantFailed {
+			failures = append(failures, state)
+		}
+	}
+
+	return progress, failures, nil
+}
+
+// fleetMigrationProgress counts the targeted tenants by state and returns the
+// ones still waiting for the migration. Tenants whose scheduled load timed out
+// are pending again.
+func fleetMigrationProgress(migration *enterprise.FleetMigration, tenants []*enterprise.Tenant, states map[string]*enterprise.FleetMigrationTenant, now time.Time) (*enterprise.FleetMigrationProgress, []*enterprise.Tenant) {
+	progress := &enterprise.FleetMigrationProgress{}
+	pending := make([]*enterprise.Tenant, 0)
+
+	for _, tenant := range tenants {
+		if !migration.Targets(tenant.ID) {
+			continue
+		}
+		progress.Targeted++
+
+		state := states[tenant.ID]
+		switch {
+		case state == nil:
+		case state.Status == enterprise.FleetMigrationTenantApplied:
+			progress.Applied++
+			continue
+		case state.Status == enterprise.FleetMigrationTenantFailed:
+			progress.Failed++
+			continue
+		case state.Status == enterprise.FleetMigrationTenantScheduled && now.Sub(state.Updated) < fleetMigrationScheduleTimeout:
+			progress.Scheduled++
+			continue
+		}
+
+		progress.Pending++
+		pending = append(pending, tenant)
+	}
+
+	return progress, pending
+}
+
+// PendingFleetMigrations returns, for each tenant, the running fleet migrations it
+// hasn't applied yet, oldest first. Tenants with nothing to apply are left out.
+func (cp *ControlPlane) PendingFleetMigrations(tenantIDs []string) (map[string][]*enterprise.FleetMigration, error) {
+	migrations, err := cp.storage.ListFleetMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string][]*enterprise.FleetMigration)
+	for _, migration := range migrations {
+		if migration.Status != enterprise.FleetMigrationRunning {
+			continue
+		}
+
+		states, err := cp.storage.ListFleetMigrationTenants(migration.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tenantID := range tenantIDs {
+			if !migration.Targets(tenantID) {
+				continue
+			}
+			if state := states[tenantID]; state != nil && state.Status != enterprise.FleetMigrationTenantScheduled {
+				continue
+			}
+			pending[tenantID] = append(pending[tenantID], migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// ReportFleetMigration records the outcome of a fleet migration on a tenant
+func (cp *ControlPlane) ReportFleetMigration(state *enterprise.FleetMigrationTenant) error {
+	if state.Status != enterprise.FleetMigrationTenantApplied && state.Status != enterprise.FleetMigrationTenantFailed {
+		return fmt.Errorf("invalid fleet migration result: %s", state.Status)
+	}
+
+	if _, err := cp.storage.GetFleetMigration(state.MigrationID); err != nil {
+		return err
+	}
+
+	state.Updated = time.Now()
+	if state.Status == enterprise.FleetMigrationTenantFailed {
+		cp.logger.Warn("Fleet migration failed on tenant", "migrationId", state.MigrationID, "tenantId", state.TenantID, "error", state.Error)
+	}
+
+	return cp.storage.SaveFleetMigrationTenant(state)
+}
+
+// FleetMigrationBatch returns the tenants eager rollouts scheduled on a node
+func (cp *ControlPlane) FleetMigrationBatch(nodeID string) ([]string, error) {
+	migrations, err := cp.storage.ListFleetMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	tenantIDs := make([]string, 0)
+	for _, migration := range migrations {
+		if migration.Status != enterprise.FleetMigrationRunning || migration.Mode != enterprise.FleetMigrationEager {
+			continue
+		}
+
+		states, err := cp.storage.ListFleetMigrationTenants(migration.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, state := range states {
+			if state.Status == enterprise.FleetMigrationTenantScheduled && state.NodeID == nodeID && !seen[state.TenantID] {
+				seen[state.TenantID] = true
+				tenantIDs = append(tenantIDs, state.TenantID)
+			}
+		}
+	}
+
+	return tenantIDs, nil
+}
+
+// scheduleFleetMigrations periodically advances running fleet migrations
+func (cp *ControlPlane) scheduleFleetMigrations() {
+	defer cp.wg.Done()
+
+	ticker := time.NewTicker(fleetMigrationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cp.ctx.Done():
+			return
+		case <-ticker.C:
+			// Only leader should schedule, tenant states are replicated to followers
+			if cp.raft != nil && !cp.raft.IsLeader() {
+				continue
+			}
+
+			cp.advanceFleetMigrations()
+		}
+	}
+}
+
+// advanceFleetMigrations schedules the next batch of every eager rollout and
+// completes the migrations every tenant has applied or failed
+func (cp *ControlPlane) advanceFleetMigrations() {
+	migrations, err := cp.storage.ListFleetMigrations()
+	if err != nil {
+		cp.logger.Error("Failed to list fleet migrations", "error", err)
+		return
+	}
+
+	for _, migration := range migrations {
+		if migration.Status != enterprise.FleetMigrationRunning {
+			continue
+		}
+
+		if err := cp.advanceFleetMigration(migration); err != nil {
+			cp.logger.Error("Failed to advance fleet migration", "migrationId", migration.ID, "error", err)
+		}
+	}
+}
+
+// advanceFleetMigration moves a single running migration forward
+func (cp *ControlPlane) advanceFleetMigration(migration *enterprise.FleetMigration) error {
+	tenants, _, err := cp.storage.ListTenants(0, 0, "")
+	if err != nil {
+		return fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	states, err := cp.storage.ListFleetMigrationTenants(migration.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list fleet migration tenants: %w", err)
+	}
+
+	now := time.Now()
+	progress, pending := fleetMigrationProgress(migration, tenants, states, now)
+
+	// A canary is never completed, raising it targets more tenants
+	if progress.Pending == 0 && progress.Scheduled == 0 && migration.CanaryPercent >= 100 {
+		_, err := cp.updateFleetMigration(migration.ID, func(m *enterprise.FleetMigration) {
+			m.Status = enterprise.FleetMigrationCompleted
+		})
+		if err == nil {
+			cp.logger.Info("Fleet migration completed", "migrationId", migration.ID, "applied", progress.Applied, "failed", progress.Failed)
+		}
+		return err
+	}
+
+	if migration.Mode != enterprise.FleetMigrationEager {
+		return nil
+	}
+
+	slots := migration.BatchSize - progress.Scheduled
+	for _, tenant := range pending {
+		if slots <= 0 {
+			break
+		}
+
+		// Archived tenants can't be loaded, they pick the migration up once restored
+		if tenant.Status == enterprise.TenantStatusArchived {
+			continue
+		}
+
+		decision, err := cp.placement.AssignTenant(tenant.ID)
+		if err != nil {
+			cp.logger.Error("Failed to place tenant for fleet migration", "tenantId", tenant.ID, "migrationId", migration.ID, "error", err)
+			continue
+		}
+
+		err = cp.storage.SaveFleetMigrationTenant(&enterprise.FleetMigrationTenant{
+			MigrationID: migration.ID,
+			TenantID:    tenant.ID,
+			Status:      enterprise.FleetMigrationTenantScheduled,
+			NodeID:      decision.NodeID,
+			Updated:     now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule tenant %s: %w", tenant.ID, err)
+		}
+
+		slots--
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package control_plane
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestEagerFleetMigrationRollout(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	cp.placement = NewPlacementService(cp.storage, nil, "")
+
+	if err := cp.RegisterNode(&enterprise.NodeInfo{ID: "node-1", Address: "localhost:8091", Status: enterprise.NodeStatusOnline, Capacity: 10}); err != nil {
+		t.Fatalf("failed to register node: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		createAssignedTenant(t, cp, fmt.Sprintf("tenant-%d", i), "", enterprise.TenantStatusIdle)
+	}
+	createAssignedTenant(t, cp, "archived", "", enterprise.TenantStatusArchived)
+
+	migration := &enterprise.FleetMigration{
+		Name:      "add audit field",
+		Script:    `migrate((app) => {}, (app) => {})`,
+		Mode:      enterprise.FleetMigrationEager,
+		BatchSize: 2,
+	}
+	if err := cp.CreateFleetMigration(migration); err != nil {
+		t.Fatalf("failed to create fleet migration: %v", err)
+	}
+
+	cp.advanceFleetMigrations()
+
+	batch, err := cp.FleetMigrationBatch("node-1")
+	if err != nil {
+		t.Fatalf("failed to get batch: %v", err)
+	}
+	if len(batch) != 2 {
+		t.Fatalf("expected a batch of 2 tenants, got %v", batch)
+	}
+
+	// Scheduled tenants still have the migration pending until they report
+	pending, err := cp.PendingFleetMigrations(batch)
+	if err != nil {
+		t.Fatalf("failed to get pending migrations: %v", err)
+	}
+	if len(pending[batch[0]]) != 1 || len(pending[batch[1]]) != 1 {
+		t.Fatalf("expected the migration pending for the batch, got %v", pending)
+	}
+
+	results := map[string]enterprise.FleetMigrationTenantStatus{
+		batch[0]: enterprise.FleetMigrationTenantApplied,
+		batch[1]: enterprise.FleetMigrationTenantFailed,
+	}
+	for tenantID, status := range results {
+		err := cp.ReportFleetMigration(&enterprise.FleetMigrationTenant{
+			MigrationID: migration.ID,
+			TenantID:    tenantID,
+			Status:      status,
+			NodeID:      "node-1",
+			Error:       "boom",
+		})
+		if err != nil {
+			t.Fatalf("failed to report result: %v", err)
+		}
+	}
+
+	progress, failures, err := cp.GetFleetMigrationProgress(migration)
+	if err != nil {
+		t.Fatalf("failed to get progress: %v", err)
+	}
+	if progress.Targeted != 4 || progress.Applied != 1 || progress.Failed != 1 || progress.Pending != 2 {
+		t.Errorf("unexpected progress: %+v", progress)
+	}
+	if len(failures) != 1 || failures[0].Error != "boom" {
+		t.Errorf("expected one failure, got %v", failures)
+	}
+
+	// The last loadable tenant is scheduled, the archived one waits for a restore
+	cp.advanceFleetMigrations()
+
+	batch, _ = cp.FleetMigrationBatch("node-1")
+	if len(batch) != 1 || batch[0] == "archived" {
+		t.Fatalf("expected the remaining tenant to be scheduled, got %v", batch)
+	}
+}
+
+func TestFleetMigrationPauseAndCanary(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	tenantIDs := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		tenantID := fmt.Sprintf("tenant-%d", i)
+		createAssignedTenant(t, cp, tenantID, "", enterprise.TenantStatusIdle)
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+
+	migration := &enterprise.FleetMigration{
+		Name:          "canary",
+		Format:        enterprise.FleetMigrationJSON,
+		Script:        `[{"name": "posts", "type": "base"}]`,
+		CanaryPercent: 30,
+	}
+	if err := cp.CreateFleetMigration(migration); err != nil {
+		t.Fatalf("failed to create fleet migration: %v", err)
+	}
+
+	pending, _ := cp.PendingFleetMigrations(tenantIDs)
+	canary := len(pending)
+	if canary == 0 || canary == len(tenantIDs) {
+		t.Fatalf("expected the canary to target some tenants, got %d", canary)
+	}
+
+	if _, err := cp.SetFleetMigrationStatus(migration.ID, enterprise.FleetMigrationPaused); err != nil {
+		t.Fatalf("failed to pause: %v", err)
+	}
+	if pending, _ := cp.PendingFleetMigrations(tenantIDs); len(pending) != 0 {
+		t.Errorf("expected nothing pending while paused, got %d tenants", len(pending))
+	}
+
+	if _, err := cp.SetFleetMigrationStatus(migration.ID, enterprise.FleetMigrationRunning); err != nil {
+		t.Fatalf("failed to resume: %v", err)
+	}
+	if _, err := cp.SetFleetMigrationCanary(migration.ID, 100); err != nil {
+		t.Fatalf("failed to raise canary: %v", err)
+	}
+	if pending, _ := cp.PendingFleetMigrations(tenantIDs); len(pending) != len(tenantIDs) {
+		t.Errorf("expected every tenant pending at 100%%, got %d", len(pending))
+	}
+
+	if _, err := cp.SetFleetMigrationStatus(migration.ID, enterprise.FleetMigrationAborted); err != nil {
+		t.Fatalf("failed to abort: %v", err)
+	}
+	if _, err := cp.SetFleetMigrationStatus(migration.ID, enterprise.FleetMigrationRunning); err != enterprise.ErrFleetMigrationFinished {
+		t.Errorf("expected aborted migration to stay aborted, got %v", err)
+	}
+}
+
+func TestFleetMigrationCompletes(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createAssignedTenant(t, cp, "tenant-1", "", enterprise.TenantStatusActive)
+
+	migration := &enterprise.FleetMigration{Name: "lazy", Script: `migrate((app) => {})`}
+	if err := cp.CreateFleetMigration(migration); err != nil {
+		t.Fatalf("failed to create fleet migration: %v", err)
+	}
+
+	err := cp.ReportFleetMigration(&enterprise.FleetMigrationTenant{
+		MigrationID: migration.ID,
+		TenantID:    "tenant-1",
+		Status:      enterprise.FleetMigrationTenantApplied,
+	})
+	if err != nil {
+		t.Fatalf("failed to report result: %v", err)
+	}
+
+	cp.advanceFleetMigrations()
+
+	stored, _ := cp.GetFleetMigration(migration.ID)
+	if stored.Status != enterprise.FleetMigrationCompleted {
+		t.Errorf("expected migration completed, got %s", stored.Status)
+	}
+}
+
+func TestCreateFleetMigrationRejectsInvalidJSON(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	err := cp.CreateFleetMigration(&enterprise.FleetMigration{
+		Name:   "broken",
+		Format: enterprise.FleetMigrationJSON,
+		Script: `{"name": "posts"}`,
+	})
+	if err == nil {
+		t.Error("expected a collections object instead of an array to be rejected")
+	}
+}
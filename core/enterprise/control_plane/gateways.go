@@ -0,0 +1,89 @@
+package control_plane
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// gatewayTimeout is how long a gateway stays in the quota ring without a heartbeat
+const gatewayTimeout = 30 * time.Second
+
+// RegisterGateway registers or heartbeats a gateway and returns the live gateways
+func (cp *ControlPlane) RegisterGateway(gateway *enterprise.GatewayInfo) ([]*enterprise.GatewayInfo, error) {
+	if gateway.ID == "" || gateway.Address == "" {
+		return nil, fmt.Errorf("gateway id and address required")
+	}
+
+	now := time.Now()
+	gateway.LastHeartbeat = now
+	if gateway.Registered.IsZero() {
+		gateway.Registered = now
+	}
+
+	if err := cp.storage.SaveGateway(gateway); err != nil {
+		return nil, fmt.Errorf("failed to save gateway: %w", err)
+	}
+
+	return cp.ListLiveGateways()
+}
+
+// ListLiveGateways returns the gateways that sent a heartbeat recently, sorted by ID
+func (cp *ControlPlane) ListLiveGateways() ([]*enterprise.GatewayInfo, error) {
+	gateways, err := cp.storage.ListGateways()
+	if err != nil {
+		return nil, err
+	}
+
+	live := make([]*enterprise.GatewayInfo, 0, len(gateways))
+	for _, gateway := range gateways {
+		if time.Since(gateway.LastHeartbeat) < gatewayTimeout {
+			live = append(live, gateway)
+		}
+	}
+
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].ID < live[j].ID
+	})
+
+	return live, nil
+}
+
+// CheckpointUsage persists daily request counts. Counts never go backwards within
+// a day, so a stale checkpoint from a gateway that just lost ownership is ignored.
+func (cp *ControlPlane) CheckpointUsage(checkpoints []*enterprise.UsageCheckpoint) error {
+	now := time.Now()
+	changed := make([]*enterprise.UsageCheckpoint, 0, len(checkpoints))
+
+	for _, checkpoint := range checkpoints {
+		if checkpoint.TenantID == "" || checkpoint.Day == "" {
+			continue
+		}
+
+		existing, err := cp.storage.GetUsageCheckpoint(checkpoint.TenantID)
+		if err == nil {
+			if existing.Day > checkpoint.Day {
+				continue
+			}
+			if existing.Day == checkpoint.Day && existing.APIRequests >= checkpoint.APIRequests {
+				continue
+			}
+		}
+
+		checkpoint.Updated = now
+		changed = append(changed, checkpoint)
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	return cp.storage.SaveUsageCheckpoints(changed)
+}
+
+// GetUsageCheckpoint returns a tenant's last persisted daily request count
+func (cp *ControlPlane) GetUsageCheckpoint(tenantID string) (*enterprise.UsageCheckpoint, error) {
+	return cp.storage.GetUsageCheckpoint(tenantID)
+}
@@ -0,0 +1,81 @@
+package control_plane
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestRegisterGatewayListsLiveGateways(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	// A gateway that stopped sending heartbeats drops out of the ring
+	stale := &enterprise.GatewayInfo{
+		ID:            "gw-stale",
+		Address:       "gw-stale:8091",
+		LastHeartbeat: time.Now().Add(-2 * gatewayTimeout),
+	}
+	if err := cp.storage.SaveGateway(stale); err != nil {
+		t.Fatalf("failed to save gateway: %v", err)
+	}
+
+	if _, err := cp.RegisterGateway(&enterprise.GatewayInfo{ID: "gw-2", Address: "gw-2:8091"}); err != nil {
+		t.Fatalf("failed to register gateway: %v", err)
+	}
+	live, err := cp.RegisterGateway(&enterprise.GatewayInfo{ID: "gw-1", Address: "gw-1:8091"})
+	if err != nil {
+		t.Fatalf("failed to register gateway: %v", err)
+	}
+
+	if len(live) != 2 || live[0].ID != "gw-1" || live[1].ID != "gw-2" {
+		t.Fatalf("expected live gateways gw-1 and gw-2, got %+v", live)
+	}
+
+	if _, err := cp.RegisterGateway(&enterprise.GatewayInfo{ID: "gw-3"}); err == nil {
+		t.Error("expected error for gateway without address")
+	}
+}
+
+func TestCheckpointUsageNeverGoesBackwards(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	if _, err := cp.GetUsageCheckpoint("tenant-1"); err != enterprise.ErrUsageCheckpointNotFound {
+		t.Fatalf("expected ErrUsageCheckpointNotFound, got %v", err)
+	}
+
+	checkpoint := func(day string, requests int64) {
+		t.Helper()
+		err := cp.CheckpointUsage([]*enterprise.UsageCheckpoint{
+			{TenantID: "tenant-1", Day: day, APIRequests: requests},
+		})
+		if err != nil {
+			t.Fatalf("failed to checkpoint usage: %v", err)
+		}
+	}
+
+	expect := func(day string, requests int64) {
+		t.Helper()
+		got, err := cp.GetUsageCheckpoint("tenant-1")
+		if err != nil {
+			t.Fatalf("failed to get checkpoint: %v", err)
+		}
+		if got.Day != day || got.APIRequests != requests {
+			t.Errorf("expected %d requests on %s, got %d on %s", requests, day, got.APIRequests, got.Day)
+		}
+	}
+
+	checkpoint("2026-01-02", 100)
+	expect("2026-01-02", 100)
+
+	// A stale count from a gateway that lost ownership is ignored
+	checkpoint("2026-01-02", 40)
+	expect("2026-01-02", 100)
+
+	checkpoint("2026-01-01", 500)
+	expect("2026-01-02", 100)
+
+	// A new day starts over
+	checkpoint("2026-01-03", 5)
+	expect("2026-01-03", 5)
+}
@@ -3,20 +3,24 @@ package control_plane
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/pocketbase/pocketbase/core/enterprise"
 	"go.nanomsg.org/mangos/v3"
 	"go.nanomsg.org/mangos/v3/protocol/rep"
 	_ "go.nanomsg.org/mangos/v3/transport/all" // Import all transports
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // IPCServer handles IPC requests from gateways and tenant nodes
 type IPCServer struct {
 	cp     *ControlPlane
 	socket mangos.Socket
-	logger *log.Logger
+	logger *slog.Logger
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -34,7 +38,7 @@ func NewIPCServer(cp *ControlPlane) (*IPCServer, error) {
 	return &IPCServer{
 		cp:     cp,
 		socket: socket,
-		logger: log.Default(),
+		logger: enterprise.ComponentLogger(enterprise.LogComponentIPC),
 		ctx:    ctx,
 		cancel: cancel,
 	}, nil
@@ -43,12 +47,16 @@ func NewIPCServer(cp *ControlPlane) (*IPCServer, error) {
 // Start starts the IPC server
 func (s *IPCServer) Start() error {
 	// Listen on TCP
-	url := fmt.Sprintf("tcp://0.0.0.0:8090")
+	bindAddr := s.cp.config.IPCBindAddr
+	if bindAddr == "" {
+		bindAddr = "0.0.0.0:8090"
+	}
+	url := fmt.Sprintf("tcp://%s", bindAddr)
 	if err := s.socket.Listen(url); err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", url, err)
 	}
 
-	s.logger.Printf("[IPCServer] Listening on %s", url)
+	s.logger.Info("IPC server listening", "url", url)
 
 	// Start request handler
 	go s.handleRequests()
@@ -69,25 +77,38 @@ func (s *IPCServer) handleRequests() {
 		case <-s.ctx.Done():
 			return
 		default:
-			msg, err := s.socket.Recv()
+			// Requests are handled concurrently, so each one gets its own
+			// context for the reply to go back to the client that sent it
+			reply, err := s.socket.OpenContext()
 			if err != nil {
 				if s.ctx.Err() != nil {
 					return
 				}
-				s.logger.Printf("[IPCServer] Error receiving message: %v", err)
+				s.logger.Error("Error opening socket context", "error", err)
+				return
+			}
+
+			msg, err := reply.Recv()
+			if err != nil {
+				reply.Close()
+				if s.ctx.Err() != nil {
+					return
+				}
+				s.logger.Error("Error receiving message", "error", err)
 				continue
 			}
 
 			// Handle request in goroutine
-			go s.handleRequest(msg)
+			go s.handleRequest(reply, msg)
 		}
 	}
 }
 
 // IPCRequest represents an IPC request
 type IPCRequest struct {
-	Type string                 `json:"type"` // getTenant, assignTenant, registerNode, heartbeat
-	Data map[string]interface{} `json:"data"`
+	Type  string                 `json:"type"` // getTenant, assignTenant, registerNode, heartbeat
+	Data  map[string]interface{} `json:"data"`
+	Trace map[string]string      `json:"trace,omitempty"` // W3C trace context of the caller
 }
 
 // IPCResponse represents an IPC response
@@ -98,13 +119,18 @@ type IPCResponse struct {
 }
 
 // handleRequest processes a single IPC request
-func (s *IPCServer) handleRequest(msg []byte) {
+func (s *IPCServer) handleRequest(reply mangos.Context, msg []byte) {
+	defer reply.Close()
+
 	var req IPCRequest
 	if err := json.Unmarshal(msg, &req); err != nil {
-		s.sendError(fmt.Sprintf("invalid request: %v", err))
+		s.sendError(reply, fmt.Sprintf("invalid request: %v", err))
 		return
 	}
 
+	ctx := enterprise.ExtractTraceContext(s.ctx, req.Trace)
+	ctx, span := enterprise.Tracer().Start(ctx, "ipc "+req.Type, trace.WithSpanKind(trace.SpanKindServer))
+
 	var resp IPCResponse
 
 	switch req.Type {
@@ -113,11 +139,43 @@ func (s *IPCServer) handleRequest(msg []byte) {
 	case "getTenantByDomain":
 		resp = s.handleGetTenantByDomain(req.Data)
 	case "assignTenant":
-		resp = s.handleAssignTenant(req.Data)
+		resp = s.handleAssignTenant(ctx, req.Data)
 	case "registerNode":
 		resp = s.handleRegisterNode(req.Data)
 	case "heartbeat":
 		resp = s.handleHeartbeat(req.Data)
+	case "updateTenantStatus":
+		resp = s.handleUpdateTenantStatus(req.Data)
+	case "requestRestore":
+		resp = s.handleRequestRestore(req.Data)
+	case "registerGateway":
+		resp = s.handleRegisterGateway(req.Data)
+	case "checkpointUsage":
+		resp = s.handleCheckpointUsage(req.Data)
+	case "getUsage":
+		resp = s.handleGetUsage(req.Data)
+	case "appendLogs":
+		resp = s.handleAppendLogs(req.Data)
+	case "getTenantKeys":
+		resp = s.handleGetTenantKeys(req.Data)
+	case "drainNode":
+		resp = s.handleDrainNode(req.Data)
+	case "releaseTenant":
+		resp = s.handleReleaseTenant(req.Data)
+	case "acquireLease":
+		resp = s.handleAcquireLease(req.Data)
+	case "releaseLease":
+		resp = s.handleReleaseLease(req.Data)
+	case "useSSOToken":
+		resp = s.handleUseSSOToken(req.Data)
+	case "getTenantHandoffs":
+		resp = s.handleGetTenantHandoffs(req.Data)
+	case "getFleetMigrations":
+		resp = s.handleGetFleetMigrations(req.Data)
+	case "getFleetBatch":
+		resp = s.handleGetFleetBatch(req.Data)
+	case "reportFleetMigration":
+		resp = s.handleReportFleetMigration(req.Data)
 	default:
 		resp = IPCResponse{
 			Success: false,
@@ -125,19 +183,40 @@ func (s *IPCServer) handleRequest(msg []byte) {
 		}
 	}
 
+	if !resp.Success {
+		span.SetStatus(codes.Error, resp.Error)
+	}
+	span.End()
+
 	// Send response
 	respJSON, _ := json.Marshal(resp)
-	if err := s.socket.Send(respJSON); err != nil {
-		s.logger.Printf("[IPCServer] Error sending response: %v", err)
+	if err := reply.Send(respJSON); err != nil {
+		s.logger.Error("Error sending response", "error", err)
 	}
 }
 
+// readOptions returns the consistency a read request asked for. Requests
+// without one are read linearizably.
+func readOptions(data map[string]interface{}) enterprise.ReadOptions {
+	consistency, _ := data["consistency"].(string)
+	maxStalenessMs, _ := data["maxStalenessMs"].(float64)
+
+	return enterprise.ReadOptions{
+		Consistency:  enterprise.ReadConsistency(consistency),
+		MaxStaleness: time.Duration(maxStalenessMs) * time.Millisecond,
+	}.WithDefaults()
+}
+
 func (s *IPCServer) handleGetTenant(data map[string]interface{}) IPCResponse {
 	tenantID, ok := data["tenantId"].(string)
 	if !ok {
 		return IPCResponse{Success: false, Error: "tenantId required"}
 	}
 
+	if err := s.cp.CheckRead(readOptions(data)); err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
 	tenant, err := s.cp.GetTenant(tenantID)
 	if err != nil {
 		return IPCResponse{Success: false, Error: err.Error()}
@@ -157,6 +236,10 @@ func (s *IPCServer) handleGetTenantByDomain(data map[string]interface{}) IPCResp
 		return IPCResponse{Success: false, Error: "domain required"}
 	}
 
+	if err := s.cp.CheckRead(readOptions(data)); err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
 	tenant, err := s.cp.GetTenantByDomain(domain)
 	if err != nil {
 		return IPCResponse{Success: false, Error: err.Error()}
@@ -170,13 +253,18 @@ func (s *IPCServer) handleGetTenantByDomain(data map[string]interface{}) IPCResp
 	}
 }
 
-func (s *IPCServer) handleAssignTenant(data map[string]interface{}) IPCResponse {
+func (s *IPCServer) handleAssignTenant(ctx context.Context, data map[string]interface{}) IPCResponse {
 	tenantID, ok := data["tenantId"].(string)
 	if !ok {
 		return IPCResponse{Success: false, Error: "tenantId required"}
 	}
 
+	_, span := enterprise.StartSpan(ctx, "placement.assign", enterprise.AttrTenantID.String(tenantID))
 	decision, err := s.cp.AssignTenant(tenantID)
+	if err == nil {
+		span.SetAttributes(enterprise.AttrNodeID.String(decision.NodeID))
+	}
+	enterprise.EndSpan(span, err)
 	if err != nil {
 		return IPCResponse{Success: false, Error: err.Error()}
 	}
@@ -194,6 +282,7 @@ func (s *IPCServer) handleRegisterNode(data map[string]interface{}) IPCResponse
 	nodeID, _ := data["nodeId"].(string)
 	address, _ := data["address"].(string)
 	capacity, _ := data["capacity"].(float64)
+	region, _ := data["region"].(string)
 
 	if nodeID == "" || address == "" {
 		return IPCResponse{Success: false, Error: "nodeId and address required"}
@@ -203,6 +292,7 @@ func (s *IPCServer) handleRegisterNode(data map[string]interface{}) IPCResponse
 		ID:       nodeID,
 		Address:  address,
 		Capacity: int(capacity),
+		Region:   region,
 		Status:   "online",
 	}
 
@@ -221,18 +311,427 @@ func (s *IPCServer) handleHeartbeat(data map[string]interface{}) IPCResponse {
 		return IPCResponse{Success: false, Error: "nodeId required"}
 	}
 
-	if err := s.cp.UpdateNodeHeartbeat(nodeID, int(activeTenantsCount)); err != nil {
+	var leases []*enterprise.TenantLease
+	if data["leases"] != nil {
+		leasesJSON, err := json.Marshal(data["leases"])
+		if err != nil {
+			return IPCResponse{Success: false, Error: "invalid leases"}
+		}
+		if err := json.Unmarshal(leasesJSON, &leases); err != nil {
+			return IPCResponse{Success: false, Error: "invalid leases"}
+		}
+	}
+
+	status, renewed, err := s.cp.UpdateNodeHeartbeat(nodeID, int(activeTenantsCount), leases)
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"status": status,
+			"leases": renewed,
+		},
+	}
+}
+
+func (s *IPCServer) handleAcquireLease(data map[string]interface{}) IPCResponse {
+	tenantID, _ := data["tenantId"].(string)
+	nodeID, _ := data["nodeId"].(string)
+
+	if tenantID == "" || nodeID == "" {
+		return IPCResponse{Success: false, Error: "tenantId and nodeId required"}
+	}
+
+	lease, err := s.cp.AcquireTenantLease(tenantID, nodeID)
+	if errors.Is(err, enterprise.ErrTenantLeaseHeld) {
+		return IPCResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"held": true,
+			},
+		}
+	}
+
+	// The node answers requests for these tenants like it does after its own checks
+	var refused string
+	switch {
+	case errors.Is(err, enterprise.ErrTenantNotFound):
+		refused = "deleted"
+	case errors.Is(err, enterprise.ErrTenantArchived):
+		refused = "archived"
+	case errors.Is(err, enterprise.ErrTenantSuspended):
+		refused = "suspended"
+	}
+	if refused != "" {
+		return IPCResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"refused": refused,
+			},
+		}
+	}
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"lease": lease,
+		},
+	}
+}
+
+func (s *IPCServer) handleReleaseLease(data map[string]interface{}) IPCResponse {
+	tenantID, _ := data["tenantId"].(string)
+	nodeID, _ := data["nodeId"].(string)
+	epoch, _ := data["epoch"].(float64)
+
+	if tenantID == "" || nodeID == "" {
+		return IPCResponse{Success: false, Error: "tenantId and nodeId required"}
+	}
+
+	if err := s.cp.ReleaseTenantLease(tenantID, nodeID, uint64(epoch)); err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{Success: true}
+}
+
+func (s *IPCServer) handleUseSSOToken(data map[string]interface{}) IPCResponse {
+	tokenID, _ := data["tokenId"].(string)
+	expiresAtStr, _ := data["expiresAt"].(string)
+
+	if tokenID == "" {
+		return IPCResponse{Success: false, Error: "tokenId required"}
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339Nano, expiresAtStr)
+	if err != nil {
+		return IPCResponse{Success: false, Error: "invalid expiresAt"}
+	}
+
+	err = s.cp.UseSSOToken(tokenID, expiresAt)
+	if errors.Is(err, enterprise.ErrSSOTokenUsed) {
+		return IPCResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"used": true,
+			},
+		}
+	}
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{Success: true}
+}
+
+func (s *IPCServer) handleDrainNode(data map[string]interface{}) IPCResponse {
+	nodeID, _ := data["nodeId"].(string)
+	if nodeID == "" {
+		return IPCResponse{Success: false, Error: "nodeId required"}
+	}
+
+	loadedTenants := make([]string, 0)
+	if loaded, ok := data["loadedTenants"].([]interface{}); ok {
+		for _, tenantID := range loaded {
+			if id, ok := tenantID.(string); ok {
+				loadedTenants = append(loadedTenants, id)
+			}
+		}
+	}
+
+	if err := s.cp.DrainNode(nodeID, loadedTenants); err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{Success: true}
+}
+
+func (s *IPCServer) handleReleaseTenant(data map[string]interface{}) IPCResponse {
+	tenantID, _ := data["tenantId"].(string)
+	nodeID, _ := data["nodeId"].(string)
+
+	if tenantID == "" || nodeID == "" {
+		return IPCResponse{Success: false, Error: "tenantId and nodeId required"}
+	}
+
+	if err := s.cp.ReleaseTenant(tenantID, nodeID); err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{Success: true}
+}
+
+func (s *IPCServer) handleUpdateTenantStatus(data map[string]interface{}) IPCResponse {
+	tenantID, _ := data["tenantId"].(string)
+	status, _ := data["status"].(string)
+
+	if tenantID == "" || status == "" {
+		return IPCResponse{Success: false, Error: "tenantId and status required"}
+	}
+
+	if err := s.cp.UpdateTenantStatus(tenantID, enterprise.TenantStatus(status)); err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{Success: true}
+}
+
+func (s *IPCServer) handleRequestRestore(data map[string]interface{}) IPCResponse {
+	tenantID, ok := data["tenantId"].(string)
+	if !ok {
+		return IPCResponse{Success: false, Error: "tenantId required"}
+	}
+	expedited, _ := data["expedited"].(bool)
+	requestedBy, _ := data["requestedBy"].(string)
+
+	job, err := s.cp.RequestTenantRestore(tenantID, expedited, requestedBy)
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"job": job,
+		},
+	}
+}
+
+func (s *IPCServer) handleRegisterGateway(data map[string]interface{}) IPCResponse {
+	gatewayID, _ := data["gatewayId"].(string)
+	address, _ := data["address"].(string)
+
+	if gatewayID == "" || address == "" {
+		return IPCResponse{Success: false, Error: "gatewayId and address required"}
+	}
+
+	gateways, err := s.cp.RegisterGateway(&enterprise.GatewayInfo{
+		ID:      gatewayID,
+		Address: address,
+	})
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"gateways": gateways,
+		},
+	}
+}
+
+func (s *IPCServer) handleCheckpointUsage(data map[string]interface{}) IPCResponse {
+	// Convert the generic checkpoint list back into structs
+	checkpointsJSON, err := json.Marshal(data["checkpoints"])
+	if err != nil {
+		return IPCResponse{Success: false, Error: "invalid checkpoints"}
+	}
+
+	var checkpoints []*enterprise.UsageCheckpoint
+	if err := json.Unmarshal(checkpointsJSON, &checkpoints); err != nil {
+		return IPCResponse{Success: false, Error: "invalid checkpoints"}
+	}
+
+	if err := s.cp.CheckpointUsage(checkpoints); err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{Success: true}
+}
+
+func (s *IPCServer) handleAppendLogs(data map[string]interface{}) IPCResponse {
+	entriesJSON, err := json.Marshal(data["entries"])
+	if err != nil {
+		return IPCResponse{Success: false, Error: "invalid entries"}
+	}
+
+	var entries []*enterprise.LogEntry
+	if err := json.Unmarshal(entriesJSON, &entries); err != nil || len(entries) == 0 {
+		return IPCResponse{Success: false, Error: "invalid entries"}
+	}
+
+	if err := s.cp.AppendLogs(entries); err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{Success: true}
+}
+
+func (s *IPCServer) handleGetUsage(data map[string]interface{}) IPCResponse {
+	tenantID, ok := data["tenantId"].(string)
+	if !ok {
+		return IPCResponse{Success: false, Error: "tenantId required"}
+	}
+
+	// A missing checkpoint is normal (no requests yet today), not a failure
+	checkpoint, err := s.cp.GetUsageCheckpoint(tenantID)
+	if err != nil && !errors.Is(err, enterprise.ErrUsageCheckpointNotFound) {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"checkpoint": checkpoint,
+		},
+	}
+}
+
+func (s *IPCServer) handleGetTenantKeys(data map[string]interface{}) IPCResponse {
+	tenantID, ok := data["tenantId"].(string)
+	if !ok {
+		return IPCResponse{Success: false, Error: "tenantId required"}
+	}
+
+	// Unwrapped keys only go to the lease holder, sealed with the IPC secret.
+	// Without a master key there are none to hand out.
+	encrypted := s.cp.EncryptionEnabled()
+	if encrypted {
+		nodeID, _ := data["nodeId"].(string)
+		timestamp, _ := data["timestamp"].(float64)
+		signature, _ := data["signature"].(string)
+
+		if err := enterprise.VerifyIPCRequest(s.cp.config.IPCSecret, "getTenantKeys", nodeID, tenantID, int64(timestamp), signature); err != nil {
+			s.logger.Warn("Refused tenant keys", "tenantId", tenantID, "nodeId", nodeID, "error", err)
+			return IPCResponse{Success: false, Error: err.Error()}
+		}
+		if err := s.cp.CheckKeyHolder(tenantID, nodeID); err != nil {
+			s.logger.Warn("Refused tenant keys", "tenantId", tenantID, "nodeId", nodeID, "error", err)
+			return IPCResponse{Success: false, Error: err.Error()}
+		}
+	}
+
+	// Tenants created before encryption was enabled have no keys and are stored unencrypted
+	keys, err := s.cp.GetTenantDataKeys(tenantID)
+	if errors.Is(err, enterprise.ErrTenantKeysShredded) {
+		return IPCResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"shredded": true,
+			},
+		}
+	}
+	if err != nil && !errors.Is(err, enterprise.ErrTenantKeysNotFound) {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	if !encrypted || keys == nil {
+		return IPCResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"keys": nil,
+			},
+		}
+	}
+
+	keysJSON, err := json.Marshal(keys)
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+	sealed, err := enterprise.SealIPCPayload(s.cp.config.IPCSecret, keysJSON)
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sealedKeys": sealed,
+		},
+	}
+}
+
+func (s *IPCServer) handleGetFleetMigrations(data map[string]interface{}) IPCResponse {
+	tenantIDs := make([]string, 0)
+	if ids, ok := data["tenantIds"].([]interface{}); ok {
+		for _, tenantID := range ids {
+			if id, ok := tenantID.(string); ok {
+				tenantIDs = append(tenantIDs, id)
+			}
+		}
+	}
+
+	migrations, err := s.cp.PendingFleetMigrations(tenantIDs)
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"migrations": migrations,
+		},
+	}
+}
+
+func (s *IPCServer) handleGetTenantHandoffs(data map[string]interface{}) IPCResponse {
+	nodeID, _ := data["nodeId"].(string)
+	if nodeID == "" {
+		return IPCResponse{Success: false, Error: "nodeId required"}
+	}
+
+	tenantIDs, err := s.cp.TenantHandoffs(nodeID)
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"tenantIds": tenantIDs,
+		},
+	}
+}
+
+func (s *IPCServer) handleGetFleetBatch(data map[string]interface{}) IPCResponse {
+	nodeID, _ := data["nodeId"].(string)
+	if nodeID == "" {
+		return IPCResponse{Success: false, Error: "nodeId required"}
+	}
+
+	tenantIDs, err := s.cp.FleetMigrationBatch(nodeID)
+	if err != nil {
+		return IPCResponse{Success: false, Error: err.Error()}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"tenantIds": tenantIDs,
+		},
+	}
+}
+
+func (s *IPCServer) handleReportFleetMigration(data map[string]interface{}) IPCResponse {
+	// Convert the generic result back into a struct
+	resultJSON, err := json.Marshal(data["result"])
+	if err != nil {
+		return IPCResponse{Success: false, Error: "invalid result"}
+	}
+
+	var result enterprise.FleetMigrationTenant
+	if err := json.Unmarshal(resultJSON, &result); err != nil || result.MigrationID == "" || result.TenantID == "" {
+		return IPCResponse{Success: false, Error: "invalid result"}
+	}
+
+	if err := s.cp.ReportFleetMigration(&result); err != nil {
 		return IPCResponse{Success: false, Error: err.Error()}
 	}
 
 	return IPCResponse{Success: true}
 }
 
-func (s *IPCServer) sendError(errMsg string) {
+func (s *IPCServer) sendError(reply mangos.Context, errMsg string) {
 	resp := IPCResponse{
 		Success: false,
 		Error:   errMsg,
 	}
 	respJSON, _ := json.Marshal(resp)
-	s.socket.Send(respJSON)
+	reply.Send(respJSON)
 }
@@ -0,0 +1,289 @@
+package control_plane
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	storagepkg "github.com/pocketbase/pocketbase/core/enterprise/storage"
+)
+
+// SetMasterKey sets the master key wrapping tenant data keys, e.g. a KMS key
+// created with storagepkg.NewKMSMasterKey. It must be called before Start.
+func (cp *ControlPlane) SetMasterKey(keyring *storagepkg.MasterKeyring) {
+	cp.masterKey = keyring
+}
+
+// initMasterKey loads the master key files from the cluster configuration
+func (cp *ControlPlane) initMasterKey() error {
+	if cp.masterKey != nil {
+		return nil
+	}
+
+	if cp.config.MasterKeyFile == "" {
+		cp.logger.Warn("No master key configured, tenant data is stored unencrypted")
+		return nil
+	}
+
+	current, err := storagepkg.LoadLocalMasterKey(cp.config.MasterKeyFile)
+	if err != nil {
+		return err
+	}
+
+	previous := make([]storagepkg.MasterKey, 0, len(cp.config.PreviousMasterKeyFiles))
+	for _, path := range cp.config.PreviousMasterKeyFiles {
+		key, err := storagepkg.LoadLocalMasterKey(path)
+		if err != nil {
+			return fmt.Errorf("failed to load previous master key %s: %w", path, err)
+		}
+		previous = append(previous, key)
+	}
+
+	cp.masterKey = storagepkg.NewMasterKeyring(current, previous...)
+	cp.logger.Info("Tenant data encryption enabled", "masterKey", current.ID())
+	return nil
+}
+
+// EncryptionEnabled reports whether new tenants get data keys
+func (cp *ControlPlane) EncryptionEnabled() bool {
+	return cp.masterKey != nil
+}
+
+// newWrappedDataKey generates a data key and wraps it with the current master key
+func (cp *ControlPlane) newWrappedDataKey(version int) (*enterprise.WrappedDataKey, error) {
+	dataKey, err := storagepkg.GenerateDataKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	master := cp.masterKey.Current()
+	wrapped, err := master.Wrap(cp.ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	return &enterprise.WrappedDataKey{
+		Version:     version,
+		MasterKeyID: master.ID(),
+		Ciphertext:  wrapped,
+		Created:     time.Now(),
+	}, nil
+}
+
+// createTenantKeyring generates the first data key of a new tenant
+func (cp *ControlPlane) createTenantKeyring(tenantID string) error {
+	key, err := cp.newWrappedDataKey(1)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return cp.storage.SaveTenantKeyring(&enterprise.TenantKeyring{
+		TenantID:      tenantID,
+		ActiveVersion: key.Version,
+		Keys:          []*enterprise.WrappedDataKey{key},
+		Created:       now,
+		Updated:       now,
+	})
+}
+
+// GetTenantDataKeys unwraps all data key versions of a tenant. Tenants created
+// before encryption was enabled return ErrTenantKeysNotFound.
+func (cp *ControlPlane) GetTenantDataKeys(tenantID string) (*enterprise.TenantDataKeys, error) {
+	keyring, err := cp.storage.GetTenantKeyring(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyring.Shredded {
+		return nil, enterprise.ErrTenantKeysShredded
+	}
+
+	if cp.masterKey == nil {
+		return nil, fmt.Errorf("tenant %s is encrypted but no master key is configured", tenantID)
+	}
+
+	keys := &enterprise.TenantDataKeys{
+		TenantID:      tenantID,
+		ActiveVersion: keyring.ActiveVersion,
+		Keys:          make([]*enterprise.DataKey, 0, len(keyring.Keys)),
+	}
+
+	for _, wrapped := range keyring.Keys {
+		identity, err := cp.masterKey.Unwrap(cp.ctx, wrapped.MasterKeyID, wrapped.Ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key version %d: %w", wrapped.Version, err)
+		}
+
+		keys.Keys = append(keys.Keys, &enterprise.DataKey{
+			Version:  wrapped.Version,
+			Identity: string(identity),
+		})
+	}
+
+	return keys, nil
+}
+
+// CheckKeyHolder returns nil if nodeID may get the data keys of a tenant: the
+// node is registered and holds the tenant's lease
+func (cp *ControlPlane) CheckKeyHolder(tenantID, nodeID string) error {
+	if _, err := cp.storage.GetNode(nodeID); err != nil {
+		return fmt.Errorf("%w: node %s: %v", enterprise.ErrIPCUnauthorized, nodeID, err)
+	}
+
+	// A new leader may not have applied the grant of the node's lease yet
+	if err := cp.storage.Barrier(); err != nil {
+		return err
+	}
+
+	lease, err := cp.storage.GetTenantLease(tenantID)
+	if err != nil && !errors.Is(err, enterprise.ErrTenantLeaseNotFound) {
+		return err
+	}
+	if lease == nil || lease.NodeID != nodeID || !time.Now().Before(lease.ExpiresAt) {
+		return fmt.Errorf("%w: node %s doesn't hold the lease of tenant %s", enterprise.ErrIPCUnauthorized, nodeID, tenantID)
+	}
+	return nil
+}
+
+// RotateTenantKey adds a new data key version that new replicas are encrypted with.
+// Older versions are kept to read existing replicas. Rotating a tenant without keys
+// enables encryption for it. Nodes pick up the new key the next time they load the tenant.
+func (cp *ControlPlane) RotateTenantKey(tenantID string) (*enterprise.TenantKeyring, error) {
+	if cp.masterKey == nil {
+		return nil, fmt.Errorf("no master key configured")
+	}
+
+	if _, err := cp.storage.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	keyring, err := cp.storage.GetTenantKeyring(tenantID)
+	if err == enterprise.ErrTenantKeysNotFound {
+		keyring = &enterprise.TenantKeyring{TenantID: tenantID, Created: time.Now()}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if keyring.Shredded {
+		return nil, enterprise.ErrTenantKeysShredded
+	}
+
+	version := 1
+	for _, key := range keyring.Keys {
+		if key.Version >= version {
+			version = key.Version + 1
+		}
+	}
+
+	key, err := cp.newWrappedDataKey(version)
+	if err != nil {
+		return nil, err
+	}
+
+	keyring.Keys = append(keyring.Keys, key)
+	keyring.ActiveVersion = version
+	keyring.Updated = time.Now()
+
+	if err := cp.storage.SaveTenantKeyring(keyring); err != nil {
+		return nil, err
+	}
+
+	cp.logger.Info("Rotated data key for tenant to version", "tenantId", tenantID, "version", version)
+	return keyring, nil
+}
+
+// RewrapTenantKeys rewraps data keys wrapped by a previous master key with the
+// current one. Once it succeeds the previous master keys can be removed.
+func (cp *ControlPlane) RewrapTenantKeys() (int, error) {
+	if cp.masterKey == nil {
+		return 0, fmt.Errorf("no master key configured")
+	}
+
+	keyrings, err := cp.storage.ListTenantKeyrings()
+	if err != nil {
+		return 0, err
+	}
+
+	master := cp.masterKey.Current()
+	rewrapped := 0
+
+	for _, keyring := range keyrings {
+		changed := false
+
+		for _, key := range keyring.Keys {
+			if key.MasterKeyID == master.ID() {
+				continue
+			}
+
+			dataKey, err := cp.masterKey.Unwrap(cp.ctx, key.MasterKeyID, key.Ciphertext)
+			if err != nil {
+				return rewrapped, fmt.Errorf("failed to unwrap data key of tenant %s: %w", keyring.TenantID, err)
+			}
+
+			wrapped, err := master.Wrap(cp.ctx, dataKey)
+			if err != nil {
+				return rewrapped, fmt.Errorf("failed to rewrap data key of tenant %s: %w", keyring.TenantID, err)
+			}
+
+			key.MasterKeyID = master.ID()
+			key.Ciphertext = wrapped
+			changed = true
+		}
+
+		if !changed {
+			continue
+		}
+
+		keyring.Updated = time.Now()
+		if err := cp.storage.SaveTenantKeyring(keyring); err != nil {
+			return rewrapped, err
+		}
+		rewrapped++
+	}
+
+	cp.logger.Info("Rewrapped tenant data keys", "tenants", rewrapped, "masterKey", master.ID())
+	return rewrapped, nil
+}
+
+// DeleteTenant soft deletes a tenant and crypto-shreds its data keys, which makes
+// its encrypted S3 replicas unreadable even if they are never removed. The wrapped
+// keys remain in the Raft log until it is compacted by the next snapshot. The
+// tenant's lease ends, so the node serving it unloads it at its next heartbeat.
+func (cp *ControlPlane) DeleteTenant(tenantID string) error {
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := cp.storage.GetTenantKeyring(tenantID)
+	if err != nil && err != enterprise.ErrTenantKeysNotFound {
+		return err
+	}
+
+	if keyring != nil && !keyring.Shredded {
+		keyring.Keys = nil
+		keyring.Shredded = true
+		keyring.Updated = time.Now()
+
+		if err := cp.storage.SaveTenantKeyring(keyring); err != nil {
+			return fmt.Errorf("failed to shred tenant keys: %w", err)
+		}
+	}
+
+	if tenant.Status != enterprise.TenantStatusDeleted {
+		tenant.Status = enterprise.TenantStatusDeleted
+		tenant.Updated = time.Now()
+		if err := cp.storage.UpdateTenant(tenant); err != nil {
+			return err
+		}
+	}
+
+	if err := cp.endTenantLease(tenantID); err != nil {
+		return fmt.Errorf("failed to end tenant lease: %w", err)
+	}
+
+	cp.logger.Info("Deleted tenant", "tenantId", tenantID, "keysShredded", keyring != nil)
+	return nil
+}
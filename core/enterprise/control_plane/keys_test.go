@@ -0,0 +1,207 @@
+package control_plane
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	storagepkg "github.com/pocketbase/pocketbase/core/enterprise/storage"
+)
+
+func newTestMasterKey(t *testing.T, name string) *storagepkg.LocalMasterKey {
+	t.Helper()
+
+	key, err := storagepkg.LoadLocalMasterKey(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatalf("failed to load master key: %v", err)
+	}
+	return key
+}
+
+func newTestEncryptedTenant(t *testing.T, cp *ControlPlane, tenantID string) {
+	t.Helper()
+
+	createAssignedTenant(t, cp, tenantID, "", enterprise.TenantStatusActive)
+	if err := cp.createTenantKeyring(tenantID); err != nil {
+		t.Fatalf("failed to create tenant keyring: %v", err)
+	}
+}
+
+func TestTenantDataKeysRotateAndShred(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	cp.SetMasterKey(storagepkg.NewMasterKeyring(newTestMasterKey(t, "master.key")))
+
+	newTestEncryptedTenant(t, cp, "tenant-1")
+
+	keys, err := cp.GetTenantDataKeys("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to get data keys: %v", err)
+	}
+	if keys.ActiveVersion != 1 || len(keys.Keys) != 1 {
+		t.Fatalf("expected a single active key version 1, got %+v", keys)
+	}
+	settingsKey := storagepkg.SettingsKey(keys)
+
+	if _, err := cp.RotateTenantKey("tenant-1"); err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+
+	keys, err = cp.GetTenantDataKeys("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to get data keys: %v", err)
+	}
+	if keys.ActiveVersion != 2 || len(keys.Keys) != 2 {
+		t.Fatalf("expected active version 2 of 2 keys, got %+v", keys)
+	}
+
+	// Already encrypted settings must stay readable after a rotation
+	if got := storagepkg.SettingsKey(keys); got != settingsKey {
+		t.Errorf("expected settings key to survive rotation")
+	}
+
+	if err := cp.DeleteTenant("tenant-1"); err != nil {
+		t.Fatalf("failed to delete tenant: %v", err)
+	}
+
+	if _, err := cp.GetTenantDataKeys("tenant-1"); err != enterprise.ErrTenantKeysShredded {
+		t.Errorf("expected ErrTenantKeysShredded, got %v", err)
+	}
+	if _, err := cp.RotateTenantKey("tenant-1"); err != enterprise.ErrTenantKeysShredded {
+		t.Errorf("expected rotation of a shredded tenant to fail, got %v", err)
+	}
+
+	tenant, err := cp.storage.GetTenant("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if tenant.Status != enterprise.TenantStatusDeleted {
+		t.Errorf("expected tenant to be deleted, got %s", tenant.Status)
+	}
+}
+
+func TestRewrapTenantKeysAfterMasterKeyRotation(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	oldKey := newTestMasterKey(t, "old.key")
+	cp.SetMasterKey(storagepkg.NewMasterKeyring(oldKey))
+	newTestEncryptedTenant(t, cp, "tenant-1")
+
+	before, err := cp.GetTenantDataKeys("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to get data keys: %v", err)
+	}
+
+	newKey := newTestMasterKey(t, "new.key")
+	cp.SetMasterKey(storagepkg.NewMasterKeyring(newKey, oldKey))
+
+	rewrapped, err := cp.RewrapTenantKeys()
+	if err != nil {
+		t.Fatalf("failed to rewrap keys: %v", err)
+	}
+	if rewrapped != 1 {
+		t.Errorf("expected 1 rewrapped tenant, got %d", rewrapped)
+	}
+
+	// The old master key is no longer needed
+	cp.SetMasterKey(storagepkg.NewMasterKeyring(newKey))
+
+	after, err := cp.GetTenantDataKeys("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to get data keys with the new master key only: %v", err)
+	}
+	if after.Keys[0].Identity != before.Keys[0].Identity {
+		t.Error("expected rewrapping to keep the data key")
+	}
+
+	if rewrapped, err := cp.RewrapTenantKeys(); err != nil || rewrapped != 0 {
+		t.Errorf("expected nothing left to rewrap, got %d (%v)", rewrapped, err)
+	}
+}
+
+func TestRotateTenantKeyEncryptsLegacyTenant(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	cp.SetMasterKey(storagepkg.NewMasterKeyring(newTestMasterKey(t, "master.key")))
+
+	tenant := &enterprise.Tenant{ID: "legacy", Domain: "legacy.example.com", Status: enterprise.TenantStatusActive}
+	if err := cp.storage.CreateTenant(tenant); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	if _, err := cp.GetTenantDataKeys("legacy"); err != enterprise.ErrTenantKeysNotFound {
+		t.Fatalf("expected ErrTenantKeysNotFound, got %v", err)
+	}
+
+	keyring, err := cp.RotateTenantKey("legacy")
+	if err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+	if keyring.ActiveVersion != 1 {
+		t.Errorf("expected first key version 1, got %d", keyring.ActiveVersion)
+	}
+}
+
+func TestGetTenantKeysOnlyForLeaseHolder(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	cp.config.IPCSecret = "ipc-secret"
+	cp.SetMasterKey(storagepkg.NewMasterKeyring(newTestMasterKey(t, "master.key")))
+	newTestEncryptedTenant(t, cp, "tenant-1")
+
+	server, err := NewIPCServer(cp)
+	if err != nil {
+		t.Fatalf("failed to create IPC server: %v", err)
+	}
+	defer server.socket.Close()
+
+	for _, nodeID := range []string{"node-1", "node-2"} {
+		if err := cp.RegisterNode(&enterprise.NodeInfo{ID: nodeID, Status: enterprise.NodeStatusOnline}); err != nil {
+			t.Fatalf("failed to register node: %v", err)
+		}
+	}
+	if _, err := cp.AcquireTenantLease("tenant-1", "node-1"); err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+
+	request := func(secret, nodeID string, signedAt time.Time) IPCResponse {
+		return server.handleGetTenantKeys(map[string]interface{}{
+			"tenantId":  "tenant-1",
+			"nodeId":    nodeID,
+			"timestamp": float64(signedAt.Unix()),
+			"signature": enterprise.SignIPCRequest(secret, "getTenantKeys", nodeID, "tenant-1", signedAt),
+		})
+	}
+
+	resp := request("ipc-secret", "node-1", time.Now())
+	sealed, ok := resp.Data["sealedKeys"].(string)
+	if !resp.Success || !ok {
+		t.Fatalf("expected sealed keys for the lease holder, got %+v", resp)
+	}
+	payload, err := enterprise.OpenIPCPayload("ipc-secret", sealed)
+	if err != nil || !strings.Contains(string(payload), `"tenantId":"tenant-1"`) {
+		t.Errorf("expected the keys of tenant-1, got %s (%v)", payload, err)
+	}
+	if _, err := enterprise.OpenIPCPayload("other-secret", sealed); err == nil {
+		t.Error("expected the keys to be sealed with the IPC secret")
+	}
+
+	refused := map[string]IPCResponse{
+		"wrong secret":      request("other-secret", "node-1", time.Now()),
+		"old signature":     request("ipc-secret", "node-1", time.Now().Add(-time.Minute)),
+		"not lease holder":  request("ipc-secret", "node-2", time.Now()),
+		"unregistered node": request("ipc-secret", "node-3", time.Now()),
+	}
+	for name, resp := range refused {
+		if resp.Success || resp.Data != nil {
+			t.Errorf("%s: expected the keys to be refused, got %+v", name, resp)
+		}
+	}
+
+	// the lease ends when the tenant is suspended, and so does the key handout
+	if err := cp.endTenantLease("tenant-1"); err != nil {
+		t.Fatalf("failed to end lease: %v", err)
+	}
+	if resp := request("ipc-secret", "node-1", time.Now()); resp.Success {
+		t.Errorf("expected the keys to be refused after the lease ended, got %+v", resp)
+	}
+}
@@ -0,0 +1,176 @@
+package control_plane
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// AcquireTenantLease grants nodeID the lease of a tenant, unless another node
+// holds a lease that hasn't expired. The epoch only grows when the lease changes
+// hands, so a node loading its own tenant again keeps replicating to the same path.
+//
+// Deleted, archived and suspended tenants get no lease (ErrTenantNotFound,
+// ErrTenantArchived and ErrTenantSuspended), the node may have checked stale metadata.
+func (cp *ControlPlane) AcquireTenantLease(tenantID, nodeID string) (*enterprise.TenantLease, error) {
+	cp.leasesMu.Lock()
+	defer cp.leasesMu.Unlock()
+
+	// A new leader may not have applied the last grants of the old one yet
+	if err := cp.storage.Barrier(); err != nil {
+		return nil, err
+	}
+
+	if err := cp.checkTenantServable(tenantID); err != nil {
+		return nil, err
+	}
+
+	lease, err := cp.storage.GetTenantLease(tenantID)
+	if errors.Is(err, enterprise.ErrTenantLeaseNotFound) {
+		lease = &enterprise.TenantLease{TenantID: tenantID}
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if lease.NodeID != nodeID {
+		if lease.NodeID != "" && now.Before(lease.ExpiresAt) {
+			return nil, enterprise.ErrTenantLeaseHeld
+		}
+		lease.NodeID = nodeID
+		lease.Epoch++
+	}
+	lease.ExpiresAt = now.Add(enterprise.TenantLeaseTTL)
+
+	if err := cp.storage.SaveTenantLeases([]*enterprise.TenantLease{lease}); err != nil {
+		return nil, fmt.Errorf("failed to save lease of tenant %s: %w", tenantID, err)
+	}
+
+	cp.logger.Debug("Granted tenant lease", "tenantId", tenantID, "nodeId", nodeID, "epoch", lease.Epoch)
+	return lease, nil
+}
+
+// RenewTenantLeases extends the leases nodeID still holds and returns them. A
+// lease that expired or went to another node in the meantime, or of a tenant
+// that was suspended, archived or deleted, is left out, and the node must stop
+// serving that tenant.
+func (cp *ControlPlane) RenewTenantLeases(nodeID string, leases []*enterprise.TenantLease) ([]*enterprise.TenantLease, error) {
+	if len(leases) == 0 {
+		return nil, nil
+	}
+
+	cp.leasesMu.Lock()
+	defer cp.leasesMu.Unlock()
+
+	if err := cp.storage.Barrier(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	renewed := make([]*enterprise.TenantLease, 0, len(leases))
+	for _, held := range leases {
+		lease, err := cp.storage.GetTenantLease(held.TenantID)
+		if errors.Is(err, enterprise.ErrTenantLeaseNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if lease.NodeID != nodeID || lease.Epoch != held.Epoch || !now.Before(lease.ExpiresAt) {
+			cp.logger.Warn("Refused to renew lost tenant lease", "tenantId", held.TenantID, "nodeId", nodeID, "epoch", held.Epoch)
+			continue
+		}
+
+		// Suspending ends the lease too, but the holder may have renewed it since
+		if err := cp.checkTenantServable(held.TenantID); err != nil {
+			if !errors.Is(err, enterprise.ErrTenantNotFound) && !errors.Is(err, enterprise.ErrTenantArchived) && !errors.Is(err, enterprise.ErrTenantSuspended) {
+				return nil, err
+			}
+			cp.logger.Warn("Refused to renew tenant lease", "tenantId", held.TenantID, "nodeId", nodeID, "epoch", held.Epoch, "reason", err)
+			continue
+		}
+
+		lease.ExpiresAt = now.Add(enterprise.TenantLeaseTTL)
+		renewed = append(renewed, lease)
+	}
+
+	if len(renewed) == 0 {
+		return renewed, nil
+	}
+
+	if err := cp.storage.SaveTenantLeases(renewed); err != nil {
+		return nil, fmt.Errorf("failed to renew leases of node %s: %w", nodeID, err)
+	}
+	return renewed, nil
+}
+
+// ReleaseTenantLease lets another node take over a tenant right away instead of
+// waiting for the lease to expire. Leases the node no longer holds are ignored.
+func (cp *ControlPlane) ReleaseTenantLease(tenantID, nodeID string, epoch uint64) error {
+	cp.leasesMu.Lock()
+	defer cp.leasesMu.Unlock()
+
+	if err := cp.storage.Barrier(); err != nil {
+		return err
+	}
+
+	lease, err := cp.storage.GetTenantLease(tenantID)
+	if errors.Is(err, enterprise.ErrTenantLeaseNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if lease.NodeID != nodeID || lease.Epoch != epoch || lease.ExpiresAt.IsZero() {
+		return nil
+	}
+
+	// The holder stays recorded so that it keeps the epoch if it loads the tenant again
+	lease.ExpiresAt = time.Time{}
+	return cp.storage.SaveTenantLeases([]*enterprise.TenantLease{lease})
+}
+
+// endTenantLease expires the lease of a tenant that must not be served anymore,
+// its holder can't renew it and unloads the tenant
+func (cp *ControlPlane) endTenantLease(tenantID string) error {
+	cp.leasesMu.Lock()
+	defer cp.leasesMu.Unlock()
+
+	lease, err := cp.storage.GetTenantLease(tenantID)
+	if errors.Is(err, enterprise.ErrTenantLeaseNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if lease.ExpiresAt.IsZero() {
+		return nil
+	}
+
+	lease.ExpiresAt = time.Time{}
+	return cp.storage.SaveTenantLeases([]*enterprise.TenantLease{lease})
+}
+
+// checkTenantServable returns ErrTenantNotFound for deleted tenants, and
+// ErrTenantArchived or ErrTenantSuspended for those that must not be loaded
+func (cp *ControlPlane) checkTenantServable(tenantID string) error {
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case tenant.Status == enterprise.TenantStatusDeleted:
+		return enterprise.ErrTenantNotFound
+	case tenant.Status == enterprise.TenantStatusArchived:
+		return enterprise.ErrTenantArchived
+	case tenant.Suspended():
+		return enterprise.ErrTenantSuspended
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package control_plane
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestAcquireTenantLeaseFencesOtherNodes(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createAssignedTenant(t, cp, "tenant-1", "", enterprise.TenantStatusActive)
+
+	lease, err := cp.AcquireTenantLease("tenant-1", "node-1")
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+	if lease.Epoch != 1 {
+		t.Errorf("expected epoch 1, got %d", lease.Epoch)
+	}
+
+	if _, err := cp.AcquireTenantLease("tenant-1", "node-2"); !errors.Is(err, enterprise.ErrTenantLeaseHeld) {
+		t.Fatalf("expected ErrTenantLeaseHeld, got %v", err)
+	}
+
+	// Loading the tenant again on the same node keeps the epoch
+	again, err := cp.AcquireTenantLease("tenant-1", "node-1")
+	if err != nil {
+		t.Fatalf("failed to acquire lease again: %v", err)
+	}
+	if again.Epoch != 1 {
+		t.Errorf("expected epoch 1 to be kept, got %d", again.Epoch)
+	}
+}
+
+func TestReleasedTenantLeaseMovesWithNewEpoch(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createAssignedTenant(t, cp, "tenant-1", "", enterprise.TenantStatusActive)
+
+	lease, err := cp.AcquireTenantLease("tenant-1", "node-1")
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+	if err := cp.ReleaseTenantLease("tenant-1", "node-1", lease.Epoch); err != nil {
+		t.Fatalf("failed to release lease: %v", err)
+	}
+
+	moved, err := cp.AcquireTenantLease("tenant-1", "node-2")
+	if err != nil {
+		t.Fatalf("failed to acquire released lease: %v", err)
+	}
+	if moved.Epoch != 2 {
+		t.Errorf("expected epoch 2, got %d", moved.Epoch)
+	}
+
+	// A stale release from the old holder doesn't free the new lease
+	if err := cp.ReleaseTenantLease("tenant-1", "node-1", lease.Epoch); err != nil {
+		t.Fatalf("failed to release stale lease: %v", err)
+	}
+	if _, err := cp.AcquireTenantLease("tenant-1", "node-1"); !errors.Is(err, enterprise.ErrTenantLeaseHeld) {
+		t.Errorf("expected ErrTenantLeaseHeld after a stale release, got %v", err)
+	}
+}
+
+func TestRenewTenantLeasesDropsLostLeases(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createAssignedTenant(t, cp, "tenant-kept", "", enterprise.TenantStatusActive)
+	createAssignedTenant(t, cp, "tenant-lost", "", enterprise.TenantStatusActive)
+
+	kept, err := cp.AcquireTenantLease("tenant-kept", "node-1")
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+	lost, err := cp.AcquireTenantLease("tenant-lost", "node-1")
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+
+	// The lease ran out while node-1 was partitioned and node-2 took over
+	lost.ExpiresAt = time.Now().Add(-time.Second)
+	if err := cp.storage.SaveTenantLeases([]*enterprise.TenantLease{lost}); err != nil {
+		t.Fatalf("failed to expire lease: %v", err)
+	}
+	if _, err := cp.AcquireTenantLease("tenant-lost", "node-2"); err != nil {
+		t.Fatalf("failed to take over expired lease: %v", err)
+	}
+
+	renewed, err := cp.RenewTenantLeases("node-1", []*enterprise.TenantLease{
+		{TenantID: "tenant-kept", NodeID: "node-1", Epoch: kept.Epoch},
+		{TenantID: "tenant-lost", NodeID: "node-1", Epoch: lost.Epoch},
+	})
+	if err != nil {
+		t.Fatalf("failed to renew leases: %v", err)
+	}
+
+	if len(renewed) != 1 || renewed[0].TenantID != "tenant-kept" {
+		t.Fatalf("expected only tenant-kept to be renewed, got %v", renewed)
+	}
+	if !renewed[0].ExpiresAt.After(kept.ExpiresAt) {
+		t.Errorf("expected renewal to extend the lease")
+	}
+}
+
+func TestTenantLeasesRefusedForUnservableTenants(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createAssignedTenant(t, cp, "tenant-suspended", "", enterprise.TenantStatusActive)
+	createAssignedTenant(t, cp, "tenant-archived", "", enterprise.TenantStatusActive)
+	createAssignedTenant(t, cp, "tenant-deleted", "", enterprise.TenantStatusActive)
+
+	suspended, err := cp.AcquireTenantLease("tenant-suspended", "node-1")
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+
+	// Suspended after the node renewed the ended lease, e.g. on stale metadata
+	if err := cp.storage.SetTenantSuspension("tenant-suspended", &enterprise.TenantSuspension{
+		Reason:      enterprise.SuspensionReasonNonPayment,
+		SuspendedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("failed to suspend tenant: %v", err)
+	}
+	if err := cp.storage.UpdateTenantStatus("tenant-archived", enterprise.TenantStatusArchived); err != nil {
+		t.Fatalf("failed to archive tenant: %v", err)
+	}
+	if err := cp.storage.UpdateTenantStatus("tenant-deleted", enterprise.TenantStatusDeleted); err != nil {
+		t.Fatalf("failed to delete tenant: %v", err)
+	}
+
+	scenarios := []struct {
+		tenantID string
+		expected error
+	}{
+		{"tenant-suspended", enterprise.ErrTenantSuspended},
+		{"tenant-archived", enterprise.ErrTenantArchived},
+		{"tenant-deleted", enterprise.ErrTenantNotFound},
+		{"tenant-missing", enterprise.ErrTenantNotFound},
+	}
+	for _, s := range scenarios {
+		if _, err := cp.AcquireTenantLease(s.tenantID, "node-2"); !errors.Is(err, s.expected) {
+			t.Errorf("%s: expected %v, got %v", s.tenantID, s.expected, err)
+		}
+	}
+
+	renewed, err := cp.RenewTenantLeases("node-1", []*enterprise.TenantLease{suspended})
+	if err != nil {
+		t.Fatalf("failed to renew leases: %v", err)
+	}
+	if len(renewed) != 0 {
+		t.Errorf("expected the suspended tenant's lease not to be renewed, got %v", renewed)
+	}
+}
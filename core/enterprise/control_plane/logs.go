@@ -0,0 +1,79 @@
+package control_plane
+
+import (
+	"context"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+const (
+	// defaultLogRetention is how long stored logs are kept when no retention is configured
+	defaultLogRetention = 7 * 24 * time.Hour
+
+	// logPruneInterval is how often stored logs past the retention are removed
+	logPruneInterval = 10 * time.Minute
+)
+
+// AppendLogs stores log entries shipped by the cluster's nodes
+func (cp *ControlPlane) AppendLogs(entries []*enterprise.LogEntry) error {
+	for _, entry := range entries {
+		if entry.ID == "" {
+			entry.ID = enterprise.GenerateID("log")
+		}
+		if entry.Time.IsZero() {
+			entry.Time = time.Now().UTC()
+		}
+	}
+
+	return cp.storage.AppendLogEntries(entries)
+}
+
+// ListLogs returns the stored log entries matching the filter, newest first
+func (cp *ControlPlane) ListLogs(filter *enterprise.LogFilter) ([]*enterprise.LogEntry, error) {
+	return cp.storage.ListLogEntries(filter)
+}
+
+// LogStoreWriter returns the writer shipping this control plane's own logs
+func (cp *ControlPlane) LogStoreWriter() enterprise.LogStoreWriter {
+	return func(ctx context.Context, entries []*enterprise.LogEntry) error {
+		return cp.AppendLogs(entries)
+	}
+}
+
+// logRetention returns the configured log store retention
+func (cp *ControlPlane) logRetention() time.Duration {
+	cp.configMu.RLock()
+	configured := cp.config.Logs.Retention
+	cp.configMu.RUnlock()
+
+	retention, err := time.ParseDuration(configured)
+	if err != nil || retention <= 0 {
+		return defaultLogRetention
+	}
+	return retention
+}
+
+// pruneLogStore periodically removes stored logs older than the retention
+func (cp *ControlPlane) pruneLogStore() {
+	defer cp.wg.Done()
+
+	ticker := time.NewTicker(logPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cp.ctx.Done():
+			return
+		case <-ticker.C:
+			// Only leader should prune, the cutoff is replicated to followers
+			if cp.raft != nil && !cp.raft.IsLeader() {
+				continue
+			}
+
+			if err := cp.storage.PruneLogs(time.Now().Add(-cp.logRetention())); err != nil {
+				cp.logger.Error("Failed to prune log store", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package control_plane
+
+import (
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/metrics"
+)
+
+// nodeMetricsPath is the Prometheus endpoint of tenant nodes
+const nodeMetricsPath = "/_prometheus"
+
+// MetricsFederator returns the gatherer of the control plane's /metrics,
+// merging its own metrics with the ones of the tenant nodes that are online
+func (cp *ControlPlane) MetricsFederator() *metrics.Federator {
+	return metrics.NewFederator(cp.config.NodeID, cp.metricsTargets)
+}
+
+// metricsTargets returns the metrics endpoints of the tenant nodes that aren't offline
+func (cp *ControlPlane) metricsTargets() []metrics.FederationTarget {
+	targets := make([]metrics.FederationTarget, 0)
+	for _, node := range cp.GetNodes() {
+		if node.Status == enterprise.NodeStatusOffline || node.Address == "" {
+			continue
+		}
+
+		address := node.Address
+		if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+			address = "http://" + address
+		}
+		targets = append(targets, metrics.FederationTarget{NodeID: node.ID, URL: address + nodeMetricsPath})
+	}
+	return targets
+}
@@ -0,0 +1,142 @@
+package control_plane
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// MoveTenant moves a tenant to another node of its region. A tenant that isn't on
+// a reachable node is placed on the new node right away. Otherwise its current
+// node hands it off: it unloads the tenant with a final sync to S3 and releases
+// it, which completes the move.
+func (cp *ControlPlane) MoveTenant(tenantID, nodeID string) (*enterprise.Tenant, error) {
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tenant.Status {
+	case enterprise.TenantStatusArchived:
+		return nil, enterprise.ErrTenantArchived
+	case enterprise.TenantStatusDeleted:
+		return nil, enterprise.ErrTenantNotFound
+	}
+
+	node, err := cp.storage.GetNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.Status {
+	case enterprise.NodeStatusOffline:
+		return nil, enterprise.ErrNodeOffline
+	case enterprise.NodeStatusDraining:
+		return nil, enterprise.ErrNodeDraining
+	}
+
+	if cp.regionOf(tenant.Region) != cp.regionOf(node.Region) {
+		return nil, enterprise.ErrRegionMismatch
+	}
+
+	// Moving back to the current node cancels a pending move
+	if tenant.AssignedNodeID == nodeID {
+		tenant.MovingToNodeID = ""
+		tenant.Updated = time.Now()
+		if err := cp.storage.UpdateTenant(tenant); err != nil {
+			return nil, fmt.Errorf("failed to update tenant %s: %w", tenantID, err)
+		}
+		return tenant, nil
+	}
+
+	// Only a live node can hand the tenant off
+	if current, err := cp.storage.GetNode(tenant.AssignedNodeID); err != nil || current.Status == enterprise.NodeStatusOffline {
+		if err := cp.placeTenant(tenant, node, "Moved by admin"); err != nil {
+			return nil, err
+		}
+		cp.logger.Info("Tenant moved to node", "tenantId", tenantID, "nodeId", nodeID)
+		return tenant, nil
+	}
+
+	tenant.MovingToNodeID = nodeID
+	tenant.Updated = time.Now()
+	if err := cp.storage.UpdateTenant(tenant); err != nil {
+		return nil, fmt.Errorf("failed to update tenant %s: %w", tenantID, err)
+	}
+
+	cp.logger.Info("Tenant moving to another node", "tenantId", tenantID, "fromNodeId", tenant.AssignedNodeID, "nodeId", nodeID)
+	return tenant, nil
+}
+
+// TenantHandoffs returns the tenants of a node that are being moved to another node
+func (cp *ControlPlane) TenantHandoffs(nodeID string) ([]string, error) {
+	tenants, err := cp.storage.ListTenantsByNode(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants of node %s: %w", nodeID, err)
+	}
+
+	tenantIDs := make([]string, 0)
+	for _, tenant := range tenants {
+		if tenant.MovingToNodeID != "" {
+			tenantIDs = append(tenantIDs, tenant.ID)
+		}
+	}
+	return tenantIDs, nil
+}
+
+// completeTenantMove places a released tenant on the node it is moving to.
+// It reports false if that node can't take it anymore, so the tenant is
+// released as usual and placed on the next request.
+func (cp *ControlPlane) completeTenantMove(tenant *enterprise.Tenant) (bool, error) {
+	nodeID := tenant.MovingToNodeID
+	tenant.MovingToNodeID = ""
+
+	node, err := cp.storage.GetNode(nodeID)
+	if err != nil || node.Status != enterprise.NodeStatusOnline {
+		cp.logger.Info("Node is gone, tenant is released instead of moved", "nodeId", nodeID, "tenantId", tenant.ID)
+		return false, nil
+	}
+
+	if err := cp.placeTenant(tenant, node, "Moved by admin"); err != nil {
+		return false, err
+	}
+
+	cp.logger.Info("Tenant moved to node", "tenantId", tenant.ID, "nodeId", nodeID)
+	return true, nil
+}
+
+// placeTenant assigns a tenant to the given node
+func (cp *ControlPlane) placeTenant(tenant *enterprise.Tenant, node *enterprise.NodeInfo, reason string) error {
+	now := time.Now()
+
+	err := cp.storage.SavePlacement(&enterprise.PlacementDecision{
+		TenantID:    tenant.ID,
+		NodeID:      node.ID,
+		NodeAddress: node.Address,
+		Reason:      reason,
+		DecidedAt:   now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save placement for tenant %s: %w", tenant.ID, err)
+	}
+
+	tenant.AssignedNodeID = node.ID
+	tenant.AssignedAt = now
+	tenant.MovingToNodeID = ""
+	tenant.Status = enterprise.TenantStatusAssigning
+	tenant.Updated = now
+
+	if err := cp.storage.UpdateTenant(tenant); err != nil {
+		return fmt.Errorf("failed to update tenant %s: %w", tenant.ID, err)
+	}
+	return nil
+}
+
+// regionOf returns the region a tenant or node belongs to
+func (cp *ControlPlane) regionOf(region string) string {
+	if region == "" {
+		return cp.config.Region
+	}
+	return region
+}
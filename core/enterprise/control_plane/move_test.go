@@ -0,0 +1,93 @@
+package control_plane
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func registerTestNodes(t *testing.T, cp *ControlPlane, nodeIDs ...string) {
+	t.Helper()
+
+	for _, nodeID := range nodeIDs {
+		err := cp.RegisterNode(&enterprise.NodeInfo{ID: nodeID, Address: nodeID + ":8091", Status: enterprise.NodeStatusOnline, Capacity: 10})
+		if err != nil {
+			t.Fatalf("failed to register node %s: %v", nodeID, err)
+		}
+	}
+}
+
+func TestMoveTenantHandOff(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	registerTestNodes(t, cp, "node-1", "node-2")
+	createAssignedTenant(t, cp, "tenant-1", "node-1", enterprise.TenantStatusActive)
+
+	tenant, err := cp.MoveTenant("tenant-1", "node-2")
+	if err != nil {
+		t.Fatalf("failed to move tenant: %v", err)
+	}
+	if tenant.AssignedNodeID != "node-1" || tenant.MovingToNodeID != "node-2" {
+		t.Fatalf("expected the tenant to wait for node-1 to hand it off, got %+v", tenant)
+	}
+
+	handoffs, err := cp.TenantHandoffs("node-1")
+	if err != nil {
+		t.Fatalf("failed to get handoffs: %v", err)
+	}
+	if len(handoffs) != 1 || handoffs[0] != "tenant-1" {
+		t.Fatalf("expected tenant-1 to be handed off, got %v", handoffs)
+	}
+
+	if err := cp.ReleaseTenant("tenant-1", "node-1"); err != nil {
+		t.Fatalf("failed to release tenant: %v", err)
+	}
+
+	tenant, _ = cp.storage.GetTenant("tenant-1")
+	if tenant.AssignedNodeID != "node-2" || tenant.MovingToNodeID != "" {
+		t.Errorf("expected the tenant on node-2, got %+v", tenant)
+	}
+
+	placement, err := cp.storage.GetPlacement("tenant-1")
+	if err != nil || placement.NodeID != "node-2" || placement.NodeAddress != "node-2:8091" {
+		t.Errorf("expected a placement on node-2, got %+v (%v)", placement, err)
+	}
+
+	if handoffs, _ := cp.TenantHandoffs("node-1"); len(handoffs) != 0 {
+		t.Errorf("expected no handoffs left, got %v", handoffs)
+	}
+}
+
+func TestMoveUnplacedTenant(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	registerTestNodes(t, cp, "node-1")
+	createAssignedTenant(t, cp, "tenant-1", "", enterprise.TenantStatusEvicted)
+
+	tenant, err := cp.MoveTenant("tenant-1", "node-1")
+	if err != nil {
+		t.Fatalf("failed to move tenant: %v", err)
+	}
+	if tenant.AssignedNodeID != "node-1" || tenant.MovingToNodeID != "" {
+		t.Errorf("expected the tenant placed on node-1 right away, got %+v", tenant)
+	}
+}
+
+func TestMoveTenantRejectsUnavailableTargets(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	registerTestNodes(t, cp, "node-1", "node-2")
+	createAssignedTenant(t, cp, "tenant-1", "node-1", enterprise.TenantStatusActive)
+	createAssignedTenant(t, cp, "archived", "", enterprise.TenantStatusArchived)
+
+	if err := cp.MarkNodeDraining("node-2"); err != nil {
+		t.Fatalf("failed to drain node: %v", err)
+	}
+
+	if _, err := cp.MoveTenant("tenant-1", "node-2"); err != enterprise.ErrNodeDraining {
+		t.Errorf("expected ErrNodeDraining, got %v", err)
+	}
+	if _, err := cp.MoveTenant("tenant-1", "missing"); err != enterprise.ErrNodeNotFound {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+	if _, err := cp.MoveTenant("archived", "node-1"); err != enterprise.ErrTenantArchived {
+		t.Errorf("expected ErrTenantArchived, got %v", err)
+	}
+}
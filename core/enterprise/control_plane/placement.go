@@ -9,12 +9,14 @@ type PlacementService struct {
 	*placement.Service
 }
 
-// NewPlacementService creates a new placement service
-func NewPlacementService(storage *BadgerStorage, raftNode *RaftNode) *PlacementService {
+// NewPlacementService creates a new placement service. Tenants and nodes without
+// a region belong to defaultRegion.
+func NewPlacementService(storage *BadgerStorage, raftNode *RaftNode, defaultRegion string) *PlacementService {
 	// Use default least-loaded strategy
 	strategy := &placement.LeastLoadedStrategy{}
 
 	service := placement.NewService(storage, strategy)
+	service.SetDefaultRegion(defaultRegion)
 
 	return &PlacementService{
 		Service: service,
@@ -2,6 +2,7 @@ package placement
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/pocketbase/pocketbase/core/enterprise"
@@ -22,6 +23,9 @@ type Storage interface {
 type Service struct {
 	storage  Storage
 	strategy enterprise.PlacementStrategy
+
+	// Region of tenants and nodes that don't name one
+	defaultRegion string
 }
 
 // NewService creates a new placement service
@@ -37,15 +41,21 @@ func NewService(storage Storage, strategy enterprise.PlacementStrategy) *Service
 	}
 }
 
-// AssignTenant assigns a tenant to a node
-func (s *Service) AssignTenant(tenantID string) (*enterprise.PlacementDecision, error) {
-	// Check if tenant already has a placement
-	existing, err := s.storage.GetPlacement(tenantID)
-	if err == nil && existing != nil {
-		// Already placed
-		return existing, nil
+// SetDefaultRegion sets the region of tenants and nodes that don't name one
+func (s *Service) SetDefaultRegion(region string) {
+	s.defaultRegion = region
+}
+
+// regionOf returns the region a tenant or node belongs to
+func (s *Service) regionOf(region string) string {
+	if region == "" {
+		return s.defaultRegion
 	}
+	return region
+}
 
+// AssignTenant assigns a tenant to a node
+func (s *Service) AssignTenant(tenantID string) (*enterprise.PlacementDecision, error) {
 	// Get tenant metadata
 	tenant, err := s.storage.GetTenant(tenantID)
 	if err != nil {
@@ -58,6 +68,14 @@ func (s *Service) AssignTenant(tenantID string) (*enterprise.PlacementDecision,
 		return nil, fmt.Errorf("failed to list nodes: %w", err)
 	}
 
+	// Check if tenant already has a placement. A placement whose node no longer
+	// holds the tenant (e.g. released by a draining node) or is dead is stale.
+	existing, err := s.storage.GetPlacement(tenantID)
+	if err == nil && existing != nil && existing.NodeID == tenant.AssignedNodeID && !isNodeDead(nodes, existing.NodeID) {
+		// Already placed
+		return existing, nil
+	}
+
 	// Filter to only healthy nodes
 	healthyNodes := make([]*enterprise.NodeInfo, 0)
 	for _, node := range nodes {
@@ -70,8 +88,21 @@ func (s *Service) AssignTenant(tenantID string) (*enterprise.PlacementDecision,
 		return nil, enterprise.ErrNoHealthyNodes
 	}
 
+	// Tenants are never placed outside their region, even if that leaves them unplaced
+	region := s.regionOf(tenant.Region)
+	regionNodes := make([]*enterprise.NodeInfo, 0, len(healthyNodes))
+	for _, node := range healthyNodes {
+		if s.regionOf(node.Region) == region {
+			regionNodes = append(regionNodes, node)
+		}
+	}
+
+	if len(regionNodes) == 0 {
+		return nil, fmt.Errorf("%w %q", enterprise.ErrNoNodesInRegion, region)
+	}
+
 	// Use strategy to select node
-	selectedNode, err := s.strategy.SelectNode(tenant, healthyNodes)
+	selectedNode, err := s.strategy.SelectNode(tenant, regionNodes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to select node: %w", err)
 	}
@@ -102,13 +133,48 @@ func (s *Service) AssignTenant(tenantID string) (*enterprise.PlacementDecision,
 	return decision, nil
 }
 
-// CheckRebalance checks if rebalancing is needed and executes the plan
+// isNodeDead returns true if the node is gone, marked offline or missed its
+// heartbeats. A draining node still holds its tenants and isn't dead.
+func isNodeDead(nodes []*enterprise.NodeInfo, nodeID string) bool {
+	for _, node := range nodes {
+		if node.ID == nodeID {
+			return node.Status == enterprise.NodeStatusOffline || time.Since(node.LastHeartbeat) > 30*time.Second
+		}
+	}
+	return true
+}
+
+// CheckRebalance checks if rebalancing is needed and executes the plan.
+// Each region is balanced on its own so tenants never move across regions.
 func (s *Service) CheckRebalance() error {
 	nodes, err := s.storage.ListNodes()
 	if err != nil {
 		return fmt.Errorf("failed to list nodes: %w", err)
 	}
 
+	nodesByRegion := make(map[string][]*enterprise.NodeInfo)
+	for _, node := range nodes {
+		region := s.regionOf(node.Region)
+		nodesByRegion[region] = append(nodesByRegion[region], node)
+	}
+
+	regions := make([]string, 0, len(nodesByRegion))
+	for region := range nodesByRegion {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	for _, region := range regions {
+		if err := s.rebalanceNodes(nodesByRegion[region]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rebalanceNodes rebalances tenants between the given nodes of one region
+func (s *Service) rebalanceNodes(nodes []*enterprise.NodeInfo) error {
 	if !s.strategy.ShouldRebalance(nodes) {
 		return nil
 	}
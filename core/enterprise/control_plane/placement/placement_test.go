@@ -1,6 +1,7 @@
 package placement
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -303,6 +304,30 @@ func TestAssignTenantReturnsExistingPlacement(t *testing.T) {
 	}
 }
 
+func TestAssignTenantMovesTenantOffDeadNode(t *testing.T) {
+	storage := newMockStorage()
+	storage.addNode("node-1", "localhost:8091", 10, 0)
+	storage.addNode("node-2", "localhost:8092", 10, 0)
+	storage.nodes["node-1"].LastHeartbeat = time.Now().Add(-time.Minute)
+	storage.addTenant("tenant-1", "node-1")
+	storage.placements["tenant-1"] = &enterprise.PlacementDecision{
+		TenantID:    "tenant-1",
+		NodeID:      "node-1",
+		NodeAddress: "localhost:8091",
+	}
+
+	service := NewService(storage, nil)
+
+	decision, err := service.AssignTenant("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to assign tenant: %v", err)
+	}
+
+	if decision.NodeID != "node-2" {
+		t.Errorf("expected tenant moved to node-2, got %s", decision.NodeID)
+	}
+}
+
 func TestAssignTenantCreatesNewPlacement(t *testing.T) {
 	storage := newMockStorage()
 	storage.addNode("node-1", "localhost:8091", 10, 0)
@@ -325,3 +350,100 @@ func TestAssignTenantCreatesNewPlacement(t *testing.T) {
 		t.Error("expected placement to be saved")
 	}
 }
+
+func TestAssignTenantReplacesTenantReleasedByDrainingNode(t *testing.T) {
+	storage := newMockStorage()
+	storage.addNode("node-1", "localhost:8091", 10, 1)
+	storage.addNode("node-2", "localhost:8092", 10, 0)
+	storage.nodes["node-1"].Status = enterprise.NodeStatusDraining
+
+	// Released by node-1, the old placement is still stored
+	storage.addTenant("tenant-1", "")
+	storage.placements["tenant-1"] = &enterprise.PlacementDecision{
+		TenantID:    "tenant-1",
+		NodeID:      "node-1",
+		NodeAddress: "localhost:8091",
+	}
+
+	service := NewService(storage, nil)
+
+	decision, err := service.AssignTenant("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to assign tenant: %v", err)
+	}
+
+	if decision.NodeID != "node-2" {
+		t.Errorf("expected tenant to move to node-2, got %s", decision.NodeID)
+	}
+
+	if storage.tenants["tenant-1"].AssignedNodeID != "node-2" {
+		t.Errorf("expected tenant assigned to node-2, got %s", storage.tenants["tenant-1"].AssignedNodeID)
+	}
+}
+
+func TestAssignTenantStaysInRegion(t *testing.T) {
+	storage := newMockStorage()
+	storage.addNode("node-us", "localhost:8091", 10, 0) // Least loaded, wrong region
+	storage.addNode("node-eu", "localhost:8092", 10, 5)
+	storage.addNode("node-default", "localhost:8093", 10, 1)
+	storage.nodes["node-us"].Region = "us-east"
+	storage.nodes["node-eu"].Region = "eu-west"
+
+	storage.addTenant("tenant-eu", "")
+	storage.tenants["tenant-eu"].Region = "eu-west"
+	storage.addTenant("tenant-ap", "")
+	storage.tenants["tenant-ap"].Region = "ap-south"
+	storage.addTenant("tenant-legacy", "")
+
+	service := NewService(storage, nil)
+	service.SetDefaultRegion("us-east")
+
+	decision, err := service.AssignTenant("tenant-eu")
+	if err != nil {
+		t.Fatalf("failed to assign tenant: %v", err)
+	}
+	if decision.NodeID != "node-eu" {
+		t.Errorf("expected tenant to stay in eu-west, got %s", decision.NodeID)
+	}
+
+	// Tenants and nodes without a region are in the default region
+	decision, err = service.AssignTenant("tenant-legacy")
+	if err != nil {
+		t.Fatalf("failed to assign tenant: %v", err)
+	}
+	if decision.NodeID != "node-us" && decision.NodeID != "node-default" {
+		t.Errorf("expected tenant in the default region, got %s", decision.NodeID)
+	}
+
+	if _, err := service.AssignTenant("tenant-ap"); !errors.Is(err, enterprise.ErrNoNodesInRegion) {
+		t.Errorf("expected ErrNoNodesInRegion, got %v", err)
+	}
+	if storage.tenants["tenant-ap"].AssignedNodeID != "" {
+		t.Error("expected tenant without nodes in its region to stay unassigned")
+	}
+}
+
+func TestCheckRebalanceStaysInRegion(t *testing.T) {
+	storage := newMockStorage()
+
+	// The only underloaded node is in another region
+	storage.addNode("node-eu", "localhost:8091", 10, 8)
+	storage.nodes["node-eu"].Region = "eu-west"
+	for i := 0; i < 8; i++ {
+		storage.addTenant("tenant-eu-"+string(rune('a'+i)), "node-eu")
+	}
+	storage.addNode("node-us", "localhost:8092", 10, 0)
+	storage.nodes["node-us"].Region = "us-east"
+
+	service := NewService(storage, nil)
+
+	if err := service.CheckRebalance(); err != nil {
+		t.Fatalf("failed to check rebalance: %v", err)
+	}
+
+	for id, tenant := range storage.tenants {
+		if tenant.AssignedNodeID != "node-eu" {
+			t.Errorf("expected %s to stay on node-eu, got %s", id, tenant.AssignedNodeID)
+		}
+	}
+}
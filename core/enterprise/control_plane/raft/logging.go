@@ -0,0 +1,83 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// hclogLevels maps the levels of hashicorp/raft's logger to slog levels
+var hclogLevels = map[string]slog.Level{
+	"trace": slog.LevelDebug - 4,
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// newRaftLogger returns a logger for hashicorp/raft that writes to the raft
+// component logger, so it follows logs.levels and reaches the log store
+func newRaftLogger(logger *slog.Logger) hclog.Logger {
+	return hclog.New(&hclog.LoggerOptions{
+		Name:       "raft",
+		Level:      hclog.Trace, // filtered by the component level
+		Output:     &slogWriter{logger: logger},
+		JSONFormat: true,
+	})
+}
+
+// slogWriter re-logs the JSON lines of an hclog logger through slog
+type slogWriter struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+func (w *slogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, line := range bytes.Split(bytes.TrimSpace(p), []byte("\n")) {
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(line, &fields); err != nil {
+			w.logger.Info(string(line))
+			continue
+		}
+
+		level, ok := hclogLevels[stringField(fields, "@level")]
+		if !ok {
+			level = slog.LevelInfo
+		}
+		if !w.logger.Enabled(context.Background(), level) {
+			continue
+		}
+
+		message := stringField(fields, "@message")
+		args := make([]any, 0, 2*len(fields))
+		for key, value := range fields {
+			switch key {
+			case "@level", "@message", "@module", "@timestamp", "@caller":
+				continue
+			}
+			args = append(args, key, value)
+		}
+
+		w.logger.Log(context.Background(), level, message, args...)
+	}
+
+	return len(p), nil
+}
+
+func stringField(fields map[string]interface{}, key string) string {
+	value, _ := fields[key].(string)
+	return value
+}
+
+// raftComponentLogger is the logger of the Raft node and hashicorp/raft
+func raftComponentLogger() *slog.Logger {
+	return enterprise.ComponentLogger(enterprise.LogComponentRaft)
+}
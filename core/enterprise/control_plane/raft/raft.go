@@ -1,7 +1,9 @@
 package raft
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
@@ -17,6 +19,15 @@ type Node struct {
 	raft   *raft.Raft
 	config *enterprise.ClusterConfig
 	fsm    *FSM
+	logger *slog.Logger
+
+	// done stops the leadership watcher on shutdown
+	done chan struct{}
+
+	// Closed after Raft shuts down so a restarted node can reopen them
+	logStore    *raftboltdb.BoltStore
+	stableStore *raftboltdb.BoltStore
+	transport   *raft.NetworkTransport
 }
 
 // NewNode creates a new Raft node
@@ -24,6 +35,9 @@ func NewNode(config *enterprise.ClusterConfig, fsm *FSM) (*Node, error) {
 	raftConfig := raft.DefaultConfig()
 	raftConfig.LocalID = raft.ServerID(config.NodeID)
 
+	logger := raftComponentLogger()
+	raftConfig.Logger = newRaftLogger(logger)
+
 	// Create data directory
 	raftDir := filepath.Join(config.DataDir, "raft")
 	if err := os.MkdirAll(raftDir, 0755); err != nil {
@@ -74,7 +88,14 @@ func NewNode(config *enterprise.ClusterConfig, fsm *FSM) (*Node, error) {
 		raft:   r,
 		config: config,
 		fsm:    fsm,
+		logger: logger,
+		done:   make(chan struct{}),
+
+		logStore:    logStore,
+		stableStore: stableStore,
+		transport:   transport,
 	}
+	go node.watchLeadership()
 
 	// Bootstrap cluster if this is the first node
 	if len(config.RaftPeers) > 0 {
@@ -142,10 +163,47 @@ func (n *Node) Apply(cmd []byte, timeout time.Duration) error {
 	return future.Error()
 }
 
+// Barrier blocks until all preceding log entries are applied to the FSM
+func (n *Node) Barrier(timeout time.Duration) error {
+	return n.raft.Barrier(timeout).Error()
+}
+
+// LastContact returns when this node last heard from the leader (zero if never)
+func (n *Node) LastContact() time.Time {
+	if n.IsLeader() {
+		return time.Now()
+	}
+	return n.raft.LastContact()
+}
+
 // Shutdown gracefully shuts down the Raft node
 func (n *Node) Shutdown() error {
-	future := n.raft.Shutdown()
-	return future.Error()
+	close(n.done)
+	if err := n.raft.Shutdown().Error(); err != nil {
+		return err
+	}
+	return errors.Join(n.transport.Close(), n.logStore.Close(), n.stableStore.Close())
+}
+
+// Term returns the current Raft term
+func (n *Node) Term() uint64 {
+	return n.raft.CurrentTerm()
+}
+
+// watchLeadership logs when this node gains or loses leadership
+func (n *Node) watchLeadership() {
+	for {
+		select {
+		case <-n.done:
+			return
+		case isLeader := <-n.raft.LeaderCh():
+			if isLeader {
+				n.logger.Info("Became Raft leader", enterprise.LogKeyRaftTerm, n.Term())
+			} else {
+				n.logger.Info("Lost Raft leadership", enterprise.LogKeyRaftTerm, n.Term())
+			}
+		}
+	}
 }
 
 // GetLeader returns the current leader address
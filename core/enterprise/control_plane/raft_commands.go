@@ -3,6 +3,7 @@ package control_plane
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/pocketbase/pocketbase/core/enterprise"
 )
@@ -11,16 +12,36 @@ import (
 type CommandType string
 
 const (
-	CommandCreateTenant       CommandType = "create_tenant"
-	CommandUpdateTenant       CommandType = "update_tenant"
-	CommandUpdateTenantStatus CommandType = "update_tenant_status"
-	CommandCreateUser         CommandType = "create_user"
-	CommandUpdateUser         CommandType = "update_user"
-	CommandSaveNode           CommandType = "save_node"
-	CommandSavePlacement      CommandType = "save_placement"
-	CommandSaveActivity       CommandType = "save_activity"
-	CommandSaveToken          CommandType = "save_token"
-	CommandMarkTokenUsed      CommandType = "mark_token_used"
+	CommandCreateTenant        CommandType = "create_tenant"
+	CommandUpdateTenant        CommandType = "update_tenant"
+	CommandUpdateTenantStatus  CommandType = "update_tenant_status"
+	CommandCreateUser          CommandType = "create_user"
+	CommandUpdateUser          CommandType = "update_user"
+	CommandSaveNode            CommandType = "save_node"
+	CommandSavePlacement       CommandType = "save_placement"
+	CommandSaveActivity        CommandType = "save_activity"
+	CommandSaveToken           CommandType = "save_token"
+	CommandMarkTokenUsed       CommandType = "mark_token_used"
+	CommandSaveRestoreJob      CommandType = "save_restore_job"
+	CommandSaveGateway         CommandType = "save_gateway"
+	CommandSaveUsage           CommandType = "save_usage"
+	CommandSaveTenantKeys      CommandType = "save_tenant_keys"
+	CommandAppendAudit         CommandType = "append_audit"
+	CommandPruneAudit          CommandType = "prune_audit"
+	CommandSaveFleetMigration  CommandType = "save_fleet_migration"
+	CommandSaveFleetTenant     CommandType = "save_fleet_tenant"
+	CommandAppendLogs          CommandType = "append_logs"
+	CommandPruneLogs           CommandType = "prune_logs"
+	CommandSaveLeases          CommandType = "save_leases"
+	CommandUseSSOToken         CommandType = "use_sso_token"
+	CommandSaveEmailTemplate   CommandType = "save_email_template"
+	CommandDeleteEmailTemplate CommandType = "delete_email_template"
+	CommandSaveOutboundEmail   CommandType = "save_outbound_email"
+	CommandDeleteOutboundEmail CommandType = "delete_outbound_email"
+	CommandSaveAPIKey          CommandType = "save_api_key"
+	CommandDeleteAPIKey        CommandType = "delete_api_key"
+	CommandDeleteTenant        CommandType = "delete_tenant"
+	CommandSetTenantSuspension CommandType = "set_tenant_suspension"
 )
 
 // RaftCommand represents a command to be replicated via Raft
@@ -80,6 +101,112 @@ type MarkTokenUsedPayload struct {
 	Token string `json:"token"`
 }
 
+// SaveRestoreJobPayload is the payload for saving an archive restore job
+type SaveRestoreJobPayload struct {
+	Job *enterprise.RestoreJob `json:"job"`
+}
+
+// SaveGatewayPayload is the payload for saving gateway info
+type SaveGatewayPayload struct {
+	Gateway *enterprise.GatewayInfo `json:"gateway"`
+}
+
+// SaveUsagePayload is the payload for saving a batch of usage checkpoints
+type SaveUsagePayload struct {
+	Checkpoints []*enterprise.UsageCheckpoint `json:"checkpoints"`
+}
+
+// SaveTenantKeysPayload is the payload for saving a tenant keyring
+type SaveTenantKeysPayload struct {
+	Keyring *enterprise.TenantKeyring `json:"keyring"`
+}
+
+// AppendAuditPayload is the payload for CommandAppendAudit
+type AppendAuditPayload struct {
+	Entry *enterprise.AuditEntry `json:"entry"`
+}
+
+// PruneAuditPayload is the payload for CommandPruneAudit. The cutoff is decided by
+// the leader so every replica prunes the same entries.
+type PruneAuditPayload struct {
+	Before time.Time `json:"before"`
+}
+
+// SaveFleetMigrationPayload is the payload for saving a fleet migration
+type SaveFleetMigrationPayload struct {
+	Migration *enterprise.FleetMigration `json:"migration"`
+}
+
+// SaveFleetTenantPayload is the payload for saving a tenant's fleet migration state
+type SaveFleetTenantPayload struct {
+	State *enterprise.FleetMigrationTenant `json:"state"`
+}
+
+// AppendLogsPayload is the payload for CommandAppendLogs
+type AppendLogsPayload struct {
+	Entries []*enterprise.LogEntry `json:"entries"`
+}
+
+// PruneLogsPayload is the payload for CommandPruneLogs, with the cutoff decided by the leader
+type PruneLogsPayload struct {
+	Before time.Time `json:"before"`
+}
+
+// SaveLeasesPayload is the payload for saving tenant leases, batched so that a
+// heartbeat renews all leases of a node in one entry
+type SaveLeasesPayload struct {
+	Leases []*enterprise.TenantLease `json:"leases"`
+}
+
+// UseSSOTokenPayload is the payload for marking a tenant SSO token used
+type UseSSOTokenPayload struct {
+	TokenID   string    `json:"tokenId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// SaveEmailTemplatePayload is the payload for saving an admin email template
+type SaveEmailTemplatePayload struct {
+	Template *enterprise.EmailTemplate `json:"template"`
+}
+
+// DeleteEmailTemplatePayload is the payload for deleting an admin email template
+type DeleteEmailTemplatePayload struct {
+	Name   string `json:"name"`
+	Locale string `json:"locale"`
+}
+
+// SaveOutboundEmailPayload is the payload for queueing or updating an outbound email
+type SaveOutboundEmailPayload struct {
+	Email *enterprise.OutboundEmail `json:"email"`
+}
+
+// DeleteOutboundEmailPayload is the payload for removing an email from the outbound queue
+type DeleteOutboundEmailPayload struct {
+	ID string `json:"id"`
+}
+
+// SaveAPIKeyPayload is the payload for saving a cluster user API key
+type SaveAPIKeyPayload struct {
+	Key *enterprise.APIKey `json:"key"`
+}
+
+// DeleteAPIKeyPayload is the payload for revoking a cluster user API key
+type DeleteAPIKeyPayload struct {
+	ID string `json:"id"`
+}
+
+// DeleteTenantPayload is the payload for removing a tenant and its state
+type DeleteTenantPayload struct {
+	TenantID string `json:"tenantId"`
+}
+
+// SetTenantSuspensionPayload is the payload for suspending a tenant, a nil
+// suspension lifts it
+type SetTenantSuspensionPayload struct {
+	TenantID   string                       `json:"tenantId"`
+	Suspension *enterprise.TenantSuspension `json:"suspension,omitempty"`
+}
+
 // NewRaftCommand creates a new Raft command with the given type and payload
 func NewRaftCommand(cmdType CommandType, payload interface{}) (*RaftCommand, error) {
 	data, err := json.Marshal(payload)
@@ -53,12 +53,27 @@ func (rn *RaftNode) IsLeader() bool {
 	return rn.node.IsLeader()
 }
 
+// Term returns the current Raft term
+func (rn *RaftNode) Term() uint64 {
+	return rn.node.Term()
+}
+
 // Apply applies a command to the Raft log
 // This should be used for all state-changing operations
 func (rn *RaftNode) Apply(cmd []byte, timeout time.Duration) error {
 	return rn.node.Apply(cmd, timeout)
 }
 
+// Barrier blocks until all preceding log entries are applied
+func (rn *RaftNode) Barrier(timeout time.Duration) error {
+	return rn.node.Barrier(timeout)
+}
+
+// LastContact returns when this node last heard from the leader
+func (rn *RaftNode) LastContact() time.Time {
+	return rn.node.LastContact()
+}
+
 // Shutdown gracefully shuts down the Raft node
 func (rn *RaftNode) Shutdown() error {
 	return rn.node.Shutdown()
@@ -0,0 +1,105 @@
+package control_plane
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// readLeaseDuration is how long a leader answers linearizable reads after a
+// quorum confirmed its leadership. Followers only elect another leader after
+// missing heartbeats for a second (the Raft default), so the lease runs out
+// before a new leader can accept writes.
+const readLeaseDuration = 500 * time.Millisecond
+
+// readBarrierTimeout bounds the quorum round that renews the read lease
+const readBarrierTimeout = 5 * time.Second
+
+// raftReader is the Raft state reads are checked against, implemented by RaftNode
+type raftReader interface {
+	IsLeader() bool
+	Term() uint64
+	GetLeader() string
+	LastContact() time.Time
+	Barrier(timeout time.Duration) error
+}
+
+// readLease lets the leader answer linearizable reads from its store for a
+// while after a quorum round, instead of one round per read
+type readLease struct {
+	mu    sync.Mutex
+	term  uint64
+	until time.Time
+}
+
+// renew makes sure the lease is held for the current term. A barrier is
+// committed by a quorum, which confirms the leadership, and returns once every
+// earlier write is applied to the store. Concurrent reads wait for one barrier.
+func (l *readLease) renew(node raftReader) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	term := node.Term()
+	if l.term == term && time.Now().Before(l.until) {
+		return nil
+	}
+
+	start := time.Now()
+	if err := node.Barrier(readBarrierTimeout); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotLeader, err)
+	}
+
+	// Leadership may have moved on while the barrier was in flight
+	if !node.IsLeader() || node.Term() != term {
+		return ErrNotLeader
+	}
+
+	l.term = term
+	l.until = start.Add(readLeaseDuration)
+	return nil
+}
+
+// checkRead returns nil if this node may answer a read with the given
+// consistency from its store. Linearizable reads are only answered by the
+// leader while it holds the read lease. Stale reads are answered by any node
+// that heard from the leader within the max staleness.
+func checkRead(node raftReader, lease *readLease, opts enterprise.ReadOptions) error {
+	opts = opts.WithDefaults()
+
+	if opts.Consistency == enterprise.ReadStale {
+		lastContact := node.LastContact()
+		if lastContact.IsZero() {
+			return fmt.Errorf("%w: no contact with the leader yet", enterprise.ErrStaleRead)
+		}
+		if behind := time.Since(lastContact); behind > opts.MaxStaleness {
+			return fmt.Errorf("%w: last contact %s ago, max %s", enterprise.ErrStaleRead, behind.Round(time.Millisecond), opts.MaxStaleness)
+		}
+		return nil
+	}
+
+	if !node.IsLeader() {
+		if leaderAddr := node.GetLeader(); leaderAddr != "" {
+			return fmt.Errorf("%w: leader is %s", ErrNotLeader, leaderAddr)
+		}
+		return ErrNotLeader
+	}
+
+	return lease.renew(node)
+}
+
+// CheckRead returns nil if this node may answer a read with the given
+// consistency. Single-node setups without Raft answer every read.
+func (s *BadgerStorage) CheckRead(opts enterprise.ReadOptions) error {
+	if s.raftNode == nil {
+		return nil
+	}
+	return checkRead(s.raftNode, &s.readLease, opts)
+}
+
+// CheckRead returns nil if this node may answer a read with the given
+// consistency, or ErrNotLeader or ErrStaleRead for the client to ask another node
+func (cp *ControlPlane) CheckRead(opts enterprise.ReadOptions) error {
+	return cp.storage.CheckRead(opts)
+}
@@ -0,0 +1,118 @@
+package control_plane
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// fakeRaftReader is the Raft state of a node for read checks
+type fakeRaftReader struct {
+	leader      bool
+	term        uint64
+	lastContact time.Time
+	barrierErr  error
+	barriers    int
+}
+
+func (f *fakeRaftReader) IsLeader() bool         { return f.leader }
+func (f *fakeRaftReader) Term() uint64           { return f.term }
+func (f *fakeRaftReader) GetLeader() string      { return "cp1:7000" }
+func (f *fakeRaftReader) LastContact() time.Time { return f.lastContact }
+
+func (f *fakeRaftReader) Barrier(timeout time.Duration) error {
+	f.barriers++
+	return f.barrierErr
+}
+
+func TestCheckStaleRead(t *testing.T) {
+	stale := enterprise.ReadOptions{Consistency: enterprise.ReadStale, MaxStaleness: time.Second}
+
+	follower := &fakeRaftReader{term: 2, lastContact: time.Now().Add(-200 * time.Millisecond)}
+	if err := checkRead(follower, &readLease{}, stale); err != nil {
+		t.Errorf("expected a follower in touch with the leader to answer, got %v", err)
+	}
+
+	follower.lastContact = time.Now().Add(-3 * time.Second)
+	if err := checkRead(follower, &readLease{}, stale); !errors.Is(err, enterprise.ErrStaleRead) {
+		t.Errorf("expected ErrStaleRead from a lagging follower, got %v", err)
+	}
+
+	// the default max staleness applies if the client sent none
+	if err := checkRead(follower, &readLease{}, enterprise.ReadOptions{Consistency: enterprise.ReadStale}); err != nil {
+		t.Errorf("expected the default max staleness to allow 3s, got %v", err)
+	}
+
+	if err := checkRead(&fakeRaftReader{}, &readLease{}, stale); !errors.Is(err, enterprise.ErrStaleRead) {
+		t.Errorf("expected ErrStaleRead before any contact with a leader, got %v", err)
+	}
+
+	if follower.barriers != 0 {
+		t.Error("expected stale reads to skip the quorum round")
+	}
+}
+
+func TestCheckLinearizableRead(t *testing.T) {
+	linearizable := enterprise.ReadOptions{Consistency: enterprise.ReadLinearizable}
+	lease := &readLease{}
+
+	follower := &fakeRaftReader{term: 2, lastContact: time.Now()}
+	if err := checkRead(follower, lease, linearizable); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("expected ErrNotLeader from a follower, got %v", err)
+	}
+
+	// reads without a level are linearizable
+	if err := checkRead(follower, lease, enterprise.ReadOptions{}); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("expected reads without a level to need the leader, got %v", err)
+	}
+
+	leader := &fakeRaftReader{leader: true, term: 2}
+	for i := 0; i < 3; i++ {
+		if err := checkRead(leader, lease, linearizable); err != nil {
+			t.Fatalf("expected the leader to answer, got %v", err)
+		}
+	}
+	if leader.barriers != 1 {
+		t.Errorf("expected reads within the lease to share one barrier, got %d", leader.barriers)
+	}
+
+	// a new term needs a new lease
+	leader.term = 3
+	if err := checkRead(leader, lease, linearizable); err != nil {
+		t.Fatalf("expected the leader to answer, got %v", err)
+	}
+	if leader.barriers != 2 {
+		t.Errorf("expected a barrier in the new term, got %d", leader.barriers)
+	}
+
+	// so does an expired one
+	lease.until = time.Now().Add(-time.Millisecond)
+	leader.barrierErr = errors.New("timed out enqueuing operation")
+	if err := checkRead(leader, lease, linearizable); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("expected a failed barrier to send the client elsewhere, got %v", err)
+	}
+}
+
+func TestCheckReadWithoutRaft(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	for _, opts := range []enterprise.ReadOptions{{}, {Consistency: enterprise.ReadStale}} {
+		if err := cp.CheckRead(opts); err != nil {
+			t.Errorf("expected a single node to answer %q reads, got %v", opts.Consistency, err)
+		}
+	}
+}
+
+func TestReadOptionsFromIPC(t *testing.T) {
+	opts := readOptions(map[string]interface{}{"consistency": "stale", "maxStalenessMs": float64(1500)})
+	if opts.Consistency != enterprise.ReadStale || opts.MaxStaleness != 1500*time.Millisecond {
+		t.Errorf("expected a stale read of 1.5s, got %+v", opts)
+	}
+
+	opts = readOptions(map[string]interface{}{"consistency": "eventual"})
+	if opts.Consistency != enterprise.ReadLinearizable {
+		t.Errorf("expected unknown levels to read linearizably, got %+v", opts)
+	}
+}
@@ -0,0 +1,295 @@
+package control_plane
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	storagepkg "github.com/pocketbase/pocketbase/core/enterprise/storage"
+)
+
+const (
+	// restorePollInterval is how often in-flight Glacier restores are checked
+	restorePollInterval = 1 * time.Minute
+
+	// maxRestoreAttempts is the number of consecutive S3 errors tolerated before a job fails
+	maxRestoreAttempts = 5
+)
+
+// GlacierRestorer abstracts the S3 operations needed to thaw an archived tenant
+type GlacierRestorer interface {
+	// RestoreFromGlacier requests a temporary copy of every archived object under the prefix
+	RestoreFromGlacier(ctx context.Context, tenantPrefix string, expedited bool) error
+
+	// GetRestoreStatus reports restore progress from the objects' restore headers
+	GetRestoreStatus(ctx context.Context, tenantPrefix string) (*storagepkg.RestoreStatus, error)
+
+	// TransitionToStandard makes restored objects permanently readable again
+	TransitionToStandard(ctx context.Context, tenantPrefix string) error
+}
+
+// SetGlacierRestorer sets the backend used to restore cold tenants
+func (cp *ControlPlane) SetGlacierRestorer(restorer GlacierRestorer) {
+	cp.glacier = restorer
+}
+
+// initGlacierRestorer creates Glacier restorers from the cluster S3 configuration,
+// one for the top-level bucket and one per configured region
+func (cp *ControlPlane) initGlacierRestorer() {
+	if cp.glacier == nil && cp.config.S3Bucket != "" {
+		// Tenants without a region are always stored in the top-level bucket
+		storage, _ := cp.config.RegionStorage("")
+		cp.glacier = cp.newGlacierRestorer(storage.S3)
+	}
+
+	cp.regionGlaciers = make(map[string]GlacierRestorer, len(cp.config.Regions))
+	for name := range cp.config.Regions {
+		storage, _ := cp.config.RegionStorage(name)
+		if restorer := cp.newGlacierRestorer(storage.S3); restorer != nil {
+			cp.regionGlaciers[name] = restorer
+		}
+	}
+}
+
+// newGlacierRestorer creates a Glacier restorer for a bucket (nil on error)
+func (cp *ControlPlane) newGlacierRestorer(target enterprise.S3Target) GlacierRestorer {
+	backend, err := storagepkg.NewS3Backend(
+		cp.ctx,
+		target.Endpoint,
+		target.Region,
+		target.Bucket,
+		target.AccessKeyID,
+		target.SecretAccessKey,
+	)
+	if err != nil {
+		cp.logger.Warn("Glacier restores disabled for bucket", "bucket", target.Bucket, "error", err)
+		return nil
+	}
+
+	return storagepkg.NewGlacierLifecycleManager(backend.Client(), backend.Bucket())
+}
+
+// glacierFor returns the restorer of the bucket a tenant's data is stored in
+func (cp *ControlPlane) glacierFor(tenant *enterprise.Tenant) GlacierRestorer {
+	if _, configured := cp.config.Regions[tenant.Region]; configured {
+		return cp.regionGlaciers[tenant.Region]
+	}
+	return cp.glacier
+}
+
+// RequestTenantRestore starts restoring an archived tenant. Warm tenants are still in
+// S3 Standard and are restored immediately; cold tenants get an asynchronous job that
+// is advanced by the restore poller. If a job is already in flight it is returned as is.
+func (cp *ControlPlane) RequestTenantRestore(tenantID string, expedited bool, requestedBy string) (*enterprise.RestoreJob, error) {
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	cp.restoreMu.Lock()
+	defer cp.restoreMu.Unlock()
+
+	if job, err := cp.storage.GetRestoreJob(tenantID); err == nil && job.IsActive() {
+		return job, nil
+	}
+
+	activity, err := cp.storage.GetActivity(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant activity: %w", err)
+	}
+
+	if requestedBy == "" {
+		requestedBy = "system"
+	}
+
+	now := time.Now()
+	job := &enterprise.RestoreJob{
+		TenantID:    tenantID,
+		Status:      enterprise.RestoreJobPending,
+		FromTier:    activity.StorageTier,
+		Expedited:   expedited,
+		RequestedBy: requestedBy,
+		Created:     now,
+		Updated:     now,
+	}
+
+	if activity.StorageTier == enterprise.StorageTierCold || tenant.Status == enterprise.TenantStatusArchived {
+		// Assume Deep Archive until the first poll tells us otherwise
+		job.EstimatedReady = now.Add(storagepkg.EstimateRestoreDuration(true, expedited))
+		cp.logger.Info("Restore job created for tenant", "tenantId", tenantID, "estimatedReady", job.EstimatedReady.Format(time.RFC3339))
+	} else {
+		if err := cp.RestoreTenant(tenantID); err != nil {
+			return nil, err
+		}
+		job.Status = enterprise.RestoreJobReady
+		job.EstimatedReady = now
+		job.CompletedAt = &now
+	}
+
+	if err := cp.storage.SaveRestoreJob(job); err != nil {
+		return nil, fmt.Errorf("failed to save restore job: %w", err)
+	}
+
+	return job, nil
+}
+
+// GetRestoreJob returns the latest restore job for a tenant
+func (cp *ControlPlane) GetRestoreJob(tenantID string) (*enterprise.RestoreJob, error) {
+	return cp.storage.GetRestoreJob(tenantID)
+}
+
+// pollRestoreJobs periodically advances in-flight restore jobs
+func (cp *ControlPlane) pollRestoreJobs() {
+	defer cp.wg.Done()
+
+	ticker := time.NewTicker(restorePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cp.ctx.Done():
+			return
+		case <-ticker.C:
+			// Only leader should drive restores
+			if cp.raft != nil && !cp.raft.IsLeader() {
+				continue
+			}
+
+			cp.processRestoreJobs(cp.ctx)
+		}
+	}
+}
+
+// processRestoreJobs advances every pending or in-progress restore job by one step
+func (cp *ControlPlane) processRestoreJobs(ctx context.Context) {
+	jobs, err := cp.storage.ListActiveRestoreJobs()
+	if err != nil {
+		cp.logger.Error("Failed to list restore jobs", "error", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if err := cp.advanceRestoreJob(ctx, job); err != nil {
+			cp.logger.Error("Failed to advance restore job for tenant", "tenantId", job.TenantID, "error", err)
+		}
+	}
+}
+
+// advanceRestoreJob moves a single job through pending -> in_progress -> ready
+func (cp *ControlPlane) advanceRestoreJob(ctx context.Context, job *enterprise.RestoreJob) error {
+	cp.restoreMu.Lock()
+	defer cp.restoreMu.Unlock()
+
+	tenant, err := cp.storage.GetTenant(job.TenantID)
+	if err != nil {
+		return cp.failRestoreJob(job, err)
+	}
+
+	glacier := cp.glacierFor(tenant)
+	if glacier == nil {
+		return cp.failRestoreJob(job, fmt.Errorf("glacier restore not configured"))
+	}
+
+	now := time.Now()
+
+	switch job.Status {
+	case enterprise.RestoreJobPending:
+		if err := glacier.RestoreFromGlacier(ctx, tenant.S3Prefix, job.Expedited); err != nil {
+			return cp.retryRestoreJob(job, err)
+		}
+		job.Status = enterprise.RestoreJobInProgress
+
+	case enterprise.RestoreJobInProgress:
+		status, err := glacier.GetRestoreStatus(ctx, tenant.S3Prefix)
+		if err != nil {
+			return cp.retryRestoreJob(job, err)
+		}
+
+		job.ObjectsTotal = status.Total
+		job.ObjectsRestored = status.Readable()
+		job.RestoredUntil = status.ExpiresAt
+
+		if status.Ready() {
+			return cp.completeRestoreJob(ctx, glacier, job, tenant)
+		}
+
+		// Objects added to the archive after the first request still need a restore
+		if status.NotRequested > 0 {
+			if err := glacier.RestoreFromGlacier(ctx, tenant.S3Prefix, job.Expedited); err != nil {
+				return cp.retryRestoreJob(job, err)
+			}
+		}
+
+		// Refine the estimate once we know which storage classes are involved
+		if status.DeepArchive == 0 {
+			estimate := job.Created.Add(storagepkg.EstimateRestoreDuration(false, job.Expedited))
+			if estimate.Before(job.EstimatedReady) {
+				job.EstimatedReady = estimate
+			}
+		}
+		if now.After(job.EstimatedReady) {
+			job.EstimatedReady = now.Add(15 * time.Minute)
+		}
+	}
+
+	job.Attempts = 0
+	job.Error = ""
+	job.Updated = now
+	return cp.storage.SaveRestoreJob(job)
+}
+
+// completeRestoreJob copies the restored objects back to S3 Standard and marks the tenant loadable
+func (cp *ControlPlane) completeRestoreJob(ctx context.Context, glacier GlacierRestorer, job *enterprise.RestoreJob, tenant *enterprise.Tenant) error {
+	if err := glacier.TransitionToStandard(ctx, tenant.S3Prefix); err != nil {
+		return cp.retryRestoreJob(job, err)
+	}
+
+	if err := cp.RestoreTenant(tenant.ID); err != nil {
+		return cp.retryRestoreJob(job, err)
+	}
+
+	if tenant.Status == enterprise.TenantStatusArchived {
+		if err := cp.storage.UpdateTenantStatus(tenant.ID, enterprise.TenantStatusIdle); err != nil {
+			return cp.retryRestoreJob(job, err)
+		}
+	}
+
+	now := time.Now()
+	job.Status = enterprise.RestoreJobReady
+	job.Error = ""
+	job.Updated = now
+	job.CompletedAt = &now
+
+	cp.logger.Info("Tenant restored from storage", "tenantId", tenant.ID, "fromTier", job.FromTier)
+	return cp.storage.SaveRestoreJob(job)
+}
+
+// retryRestoreJob records a transient failure, failing the job after maxRestoreAttempts
+func (cp *ControlPlane) retryRestoreJob(job *enterprise.RestoreJob, cause error) error {
+	job.Attempts++
+	if job.Attempts >= maxRestoreAttempts {
+		return cp.failRestoreJob(job, cause)
+	}
+
+	job.Error = cause.Error()
+	job.Updated = time.Now()
+	if err := cp.storage.SaveRestoreJob(job); err != nil {
+		return err
+	}
+	return cause
+}
+
+// failRestoreJob marks a job as permanently failed
+func (cp *ControlPlane) failRestoreJob(job *enterprise.RestoreJob, cause error) error {
+	now := time.Now()
+	job.Status = enterprise.RestoreJobFailed
+	job.Error = cause.Error()
+	job.Updated = now
+	job.CompletedAt = &now
+
+	if err := cp.storage.SaveRestoreJob(job); err != nil {
+		return err
+	}
+	return cause
+}
@@ -0,0 +1,276 @@
+package control_plane
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	storagepkg "github.com/pocketbase/pocketbase/core/enterprise/storage"
+)
+
+// fakeGlacierRestorer implements GlacierRestorer for testing
+type fakeGlacierRestorer struct {
+	status       *storagepkg.RestoreStatus
+	restoreErr   error
+	restoreCalls int
+	promoted     bool
+}
+
+func (f *fakeGlacierRestorer) RestoreFromGlacier(ctx context.Context, tenantPrefix string, expedited bool) error {
+	f.restoreCalls++
+	return f.restoreErr
+}
+
+func (f *fakeGlacierRestorer) GetRestoreStatus(ctx context.Context, tenantPrefix string) (*storagepkg.RestoreStatus, error) {
+	return f.status, nil
+}
+
+func (f *fakeGlacierRestorer) TransitionToStandard(ctx context.Context, tenantPrefix string) error {
+	f.promoted = true
+	return nil
+}
+
+// newTestControlPlaneWithStorage creates a control plane with single-node storage (no Raft)
+func newTestControlPlaneWithStorage(t *testing.T) *ControlPlane {
+	cp, err := NewControlPlane(&enterprise.ClusterConfig{
+		Mode:    enterprise.ModeControlPlane,
+		NodeID:  "cp-1",
+		DataDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("failed to create control plane: %v", err)
+	}
+
+	storage, err := NewBadgerStorage(cp.config.DataDir)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	cp.storage = storage
+	t.Cleanup(func() { storage.Close() })
+
+	return cp
+}
+
+// createTestUser creates a verified cluster user with the default quotas
+func createTestUser(t *testing.T, cp *ControlPlane, userID string) {
+	t.Helper()
+
+	maxTenants, maxStorage, maxRequests := enterprise.DefaultUserQuotas()
+	err := cp.storage.CreateUser(&enterprise.ClusterUser{
+		ID:                  userID,
+		Email:               userID + "@example.com",
+		Verified:            true,
+		MaxTenants:          maxTenants,
+		MaxStoragePerTenant: maxStorage,
+		MaxAPIRequestsDaily: maxRequests,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+}
+
+func createArchivedTenant(t *testing.T, cp *ControlPlane, tenantID string) {
+	err := cp.storage.CreateTenant(&enterprise.Tenant{
+		ID:       tenantID,
+		Domain:   tenantID + ".example.com",
+		Status:   enterprise.TenantStatusActive,
+		S3Prefix: enterprise.GetS3TenantPrefix(tenantID),
+	})
+	if err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	if err := cp.ArchiveTenant(tenantID, enterprise.StorageTierCold); err != nil {
+		t.Fatalf("failed to archive tenant: %v", err)
+	}
+}
+
+func TestArchiveTenantColdMarksArchived(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createArchivedTenant(t, cp, "tenant-1")
+
+	tenant, err := cp.GetTenant("tenant-1")
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if tenant.Status != enterprise.TenantStatusArchived {
+		t.Errorf("expected status archived, got %s", tenant.Status)
+	}
+}
+
+func TestRequestTenantRestoreWarmIsImmediate(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	cp.storage.CreateTenant(&enterprise.Tenant{ID: "tenant-1", Domain: "t1.example.com"})
+	if err := cp.ArchiveTenant("tenant-1", enterprise.StorageTierWarm); err != nil {
+		t.Fatalf("failed to archive tenant: %v", err)
+	}
+
+	job, err := cp.RequestTenantRestore("tenant-1", false, "user-1")
+	if err != nil {
+		t.Fatalf("failed to request restore: %v", err)
+	}
+
+	if job.Status != enterprise.RestoreJobReady {
+		t.Errorf("expected warm restore to be ready, got %s", job.Status)
+	}
+
+	activity, _ := cp.GetTenantActivity("tenant-1")
+	if activity.StorageTier != enterprise.StorageTierHot {
+		t.Errorf("expected tier hot, got %s", activity.StorageTier)
+	}
+}
+
+func TestRequestTenantRestoreColdIsIdempotent(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createArchivedTenant(t, cp, "tenant-1")
+
+	first, err := cp.RequestTenantRestore("tenant-1", false, "gateway")
+	if err != nil {
+		t.Fatalf("failed to request restore: %v", err)
+	}
+	if first.Status != enterprise.RestoreJobPending {
+		t.Fatalf("expected pending job, got %s", first.Status)
+	}
+	if !first.EstimatedReady.After(time.Now()) {
+		t.Error("expected ETA in the future")
+	}
+
+	second, err := cp.RequestTenantRestore("tenant-1", true, "admin")
+	if err != nil {
+		t.Fatalf("failed to request restore: %v", err)
+	}
+	if second.RequestedBy != "gateway" || second.Expedited {
+		t.Error("expected the in-flight job to be returned unchanged")
+	}
+}
+
+func TestRestoreJobLifecycle(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createArchivedTenant(t, cp, "tenant-1")
+
+	restorer := &fakeGlacierRestorer{
+		status: &storagepkg.RestoreStatus{Total: 3, Archived: 3, Ongoing: 3},
+	}
+	cp.SetGlacierRestorer(restorer)
+
+	if _, err := cp.RequestTenantRestore("tenant-1", false, "gateway"); err != nil {
+		t.Fatalf("failed to request restore: %v", err)
+	}
+
+	// pending -> in_progress
+	cp.processRestoreJobs(context.Background())
+	job, _ := cp.GetRestoreJob("tenant-1")
+	if job.Status != enterprise.RestoreJobInProgress {
+		t.Fatalf("expected in_progress, got %s", job.Status)
+	}
+	if restorer.restoreCalls != 1 {
+		t.Errorf("expected 1 restore request, got %d", restorer.restoreCalls)
+	}
+
+	// Still thawing - stays in progress and refines the ETA for non Deep Archive objects
+	cp.processRestoreJobs(context.Background())
+	job, _ = cp.GetRestoreJob("tenant-1")
+	if job.Status != enterprise.RestoreJobInProgress {
+		t.Fatalf("expected in_progress, got %s", job.Status)
+	}
+	if job.ObjectsTotal != 3 || job.ObjectsRestored != 0 {
+		t.Errorf("expected 0/3 objects restored, got %d/%d", job.ObjectsRestored, job.ObjectsTotal)
+	}
+	if job.EstimatedReady.After(job.Created.Add(6 * time.Hour)) {
+		t.Errorf("expected ETA to be refined below Deep Archive estimate, got %v", job.EstimatedReady)
+	}
+
+	// All objects restored -> ready
+	restorer.status = &storagepkg.RestoreStatus{Total: 3, Archived: 3, Restored: 3}
+	cp.processRestoreJobs(context.Background())
+	job, _ = cp.GetRestoreJob("tenant-1")
+	if job.Status != enterprise.RestoreJobReady {
+		t.Fatalf("expected ready, got %s", job.Status)
+	}
+	if job.CompletedAt == nil {
+		t.Error("expected CompletedAt to be set")
+	}
+	if !restorer.promoted {
+		t.Error("expected restored objects to be copied back to S3 Standard")
+	}
+
+	tenant, _ := cp.GetTenant("tenant-1")
+	if tenant.Status != enterprise.TenantStatusIdle {
+		t.Errorf("expected tenant status idle, got %s", tenant.Status)
+	}
+
+	activity, _ := cp.GetTenantActivity("tenant-1")
+	if activity.StorageTier != enterprise.StorageTierHot {
+		t.Errorf("expected tier hot, got %s", activity.StorageTier)
+	}
+}
+
+func TestRestoreJobFailsAfterRetries(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createArchivedTenant(t, cp, "tenant-1")
+
+	cp.SetGlacierRestorer(&fakeGlacierRestorer{restoreErr: errors.New("access denied")})
+
+	if _, err := cp.RequestTenantRestore("tenant-1", false, "gateway"); err != nil {
+		t.Fatalf("failed to request restore: %v", err)
+	}
+
+	for i := 0; i < maxRestoreAttempts; i++ {
+		cp.processRestoreJobs(context.Background())
+	}
+
+	job, _ := cp.GetRestoreJob("tenant-1")
+	if job.Status != enterprise.RestoreJobFailed {
+		t.Fatalf("expected failed, got %s", job.Status)
+	}
+	if job.Error != "access denied" {
+		t.Errorf("expected error to be recorded, got %q", job.Error)
+	}
+}
+
+func TestRestoreJobWithoutGlacierFails(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createArchivedTenant(t, cp, "tenant-1")
+
+	if _, err := cp.RequestTenantRestore("tenant-1", false, "gateway"); err != nil {
+		t.Fatalf("failed to request restore: %v", err)
+	}
+
+	cp.processRestoreJobs(context.Background())
+
+	job, _ := cp.GetRestoreJob("tenant-1")
+	if job.Status != enterprise.RestoreJobFailed {
+		t.Errorf("expected failed without Glacier backend, got %s", job.Status)
+	}
+}
+
+func TestRestoreJobUsesTenantRegionBucket(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	cp.config.Regions = map[string]*enterprise.RegionConfig{
+		"eu-west": {S3: enterprise.S3Target{Bucket: "tenants-eu"}},
+	}
+
+	defaultRestorer := &fakeGlacierRestorer{}
+	euRestorer := &fakeGlacierRestorer{}
+	cp.SetGlacierRestorer(defaultRestorer)
+	cp.regionGlaciers = map[string]GlacierRestorer{"eu-west": euRestorer}
+
+	createArchivedTenant(t, cp, "tenant-1")
+	tenant, _ := cp.GetTenant("tenant-1")
+	tenant.Region = "eu-west"
+	if err := cp.storage.UpdateTenant(tenant); err != nil {
+		t.Fatalf("failed to update tenant: %v", err)
+	}
+
+	if _, err := cp.RequestTenantRestore("tenant-1", false, "gateway"); err != nil {
+		t.Fatalf("failed to request restore: %v", err)
+	}
+	cp.processRestoreJobs(context.Background())
+
+	if euRestorer.restoreCalls != 1 || defaultRestorer.restoreCalls != 0 {
+		t.Errorf("expected restore from the eu-west bucket only, got eu %d default %d", euRestorer.restoreCalls, defaultRestorer.restoreCalls)
+	}
+}
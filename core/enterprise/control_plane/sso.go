@@ -0,0 +1,33 @@
+package control_plane
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// UseSSOToken marks a tenant SSO token used, so that it logs in only once on
+// whichever node serves the tenant. It returns ErrSSOTokenUsed if it already was.
+func (cp *ControlPlane) UseSSOToken(tokenID string, expiresAt time.Time) error {
+	cp.ssoMu.Lock()
+	defer cp.ssoMu.Unlock()
+
+	// A new leader may not have applied the last uses of the old one yet
+	if err := cp.storage.Barrier(); err != nil {
+		return err
+	}
+
+	used, err := cp.storage.IsSSOTokenUsed(tokenID)
+	if err != nil {
+		return err
+	}
+	if used {
+		return enterprise.ErrSSOTokenUsed
+	}
+
+	if err := cp.storage.MarkSSOTokenUsed(tokenID, expiresAt); err != nil {
+		return fmt.Errorf("failed to mark sso token %s used: %w", tokenID, err)
+	}
+	return nil
+}
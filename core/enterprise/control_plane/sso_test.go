@@ -0,0 +1,26 @@
+package control_plane
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestUseSSOTokenOnlyOnce(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+
+	expiresAt := time.Now().Add(time.Hour)
+	if err := cp.UseSSOToken("sso_abc", expiresAt); err != nil {
+		t.Fatalf("failed to use token: %v", err)
+	}
+
+	if err := cp.UseSSOToken("sso_abc", expiresAt); !errors.Is(err, enterprise.ErrSSOTokenUsed) {
+		t.Fatalf("expected ErrSSOTokenUsed, got %v", err)
+	}
+
+	if err := cp.UseSSOToken("sso_def", expiresAt); err != nil {
+		t.Errorf("expected another token to be usable, got %v", err)
+	}
+}
@@ -14,6 +14,8 @@ import (
 type BadgerStorage struct {
 	*badger.Storage
 	raftNode *RaftNode // Reference to Raft node for log proposals
+
+	readLease readLease // Lets the leader answer linearizable reads without a quorum round each
 }
 
 // NewBadgerStorage creates a new BadgerDB storage wrapper
@@ -34,8 +36,9 @@ func (s *BadgerStorage) SetRaftNode(raftNode *RaftNode) {
 	s.raftNode = raftNode
 }
 
-// ErrNotLeader is returned when a write operation is attempted on a non-leader node
-var ErrNotLeader = fmt.Errorf("not the Raft leader")
+// ErrNotLeader is returned when a write operation is attempted on a non-leader node.
+// It is the enterprise error so that IPC clients recognize it and retry elsewhere.
+var ErrNotLeader = enterprise.ErrNotLeader
 
 // proposeCommand proposes a command via Raft consensus
 func (s *BadgerStorage) proposeCommand(cmd *RaftCommand) error {
@@ -64,6 +67,21 @@ func (s *BadgerStorage) proposeCommand(cmd *RaftCommand) error {
 	return s.raftNode.Apply(data, 10*time.Second)
 }
 
+// Barrier waits until all entries committed before this node became leader are
+// applied, so that reads reflect every earlier write. Decisions that must not
+// act on stale state, like granting leases, read after a barrier.
+func (s *BadgerStorage) Barrier() error {
+	if s.raftNode == nil {
+		return nil
+	}
+
+	if !s.raftNode.IsLeader() {
+		return ErrNotLeader
+	}
+
+	return s.raftNode.Barrier(10 * time.Second)
+}
+
 // ApplyRaftLog applies a Raft log entry to the storage
 // This is called by the Raft FSM when a log is committed
 func (s *BadgerStorage) ApplyRaftLog(cmd *RaftCommand) error {
@@ -144,6 +162,158 @@ func (s *BadgerStorage) ApplyRaftLog(cmd *RaftCommand) error {
 		}
 		return s.Storage.MarkVerificationTokenUsed(payload.Token)
 
+	case CommandSaveRestoreJob:
+		var payload SaveRestoreJobPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal restore job payload: %w", err)
+		}
+		return s.Storage.SaveRestoreJob(payload.Job)
+
+	case CommandSaveGateway:
+		var payload SaveGatewayPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal gateway payload: %w", err)
+		}
+		return s.Storage.SaveGateway(payload.Gateway)
+
+	case CommandSaveUsage:
+		var payload SaveUsagePayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal usage payload: %w", err)
+		}
+		for _, checkpoint := range payload.Checkpoints {
+			if err := s.Storage.SaveUsageCheckpoint(checkpoint); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case CommandSaveTenantKeys:
+		var payload SaveTenantKeysPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal tenant keys payload: %w", err)
+		}
+		return s.Storage.SaveTenantKeyring(payload.Keyring)
+
+	case CommandAppendAudit:
+		var payload AppendAuditPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal audit payload: %w", err)
+		}
+		return s.Storage.AppendAuditEntry(payload.Entry)
+
+	case CommandPruneAudit:
+		var payload PruneAuditPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal audit prune payload: %w", err)
+		}
+		_, err := s.Storage.DeleteAuditEntriesBefore(payload.Before)
+		return err
+
+	case CommandSaveFleetMigration:
+		var payload SaveFleetMigrationPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal fleet migration payload: %w", err)
+		}
+		return s.Storage.SaveFleetMigration(payload.Migration)
+
+	case CommandSaveFleetTenant:
+		var payload SaveFleetTenantPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal fleet tenant payload: %w", err)
+		}
+		return s.Storage.SaveFleetMigrationTenant(payload.State)
+
+	case CommandAppendLogs:
+		var payload AppendLogsPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal logs payload: %w", err)
+		}
+		return s.Storage.AppendLogEntries(payload.Entries)
+
+	case CommandPruneLogs:
+		var payload PruneLogsPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal logs prune payload: %w", err)
+		}
+		_, err := s.Storage.DeleteLogEntriesBefore(payload.Before)
+		return err
+
+	case CommandSaveLeases:
+		var payload SaveLeasesPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal leases payload: %w", err)
+		}
+		for _, lease := range payload.Leases {
+			if err := s.Storage.SaveTenantLease(lease); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case CommandUseSSOToken:
+		var payload UseSSOTokenPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal sso token payload: %w", err)
+		}
+		return s.Storage.MarkSSOTokenUsed(payload.TokenID, payload.ExpiresAt)
+
+	case CommandSaveEmailTemplate:
+		var payload SaveEmailTemplatePayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal email template payload: %w", err)
+		}
+		return s.Storage.SaveEmailTemplate(payload.Template)
+
+	case CommandDeleteEmailTemplate:
+		var payload DeleteEmailTemplatePayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal email template payload: %w", err)
+		}
+		return s.Storage.DeleteEmailTemplate(payload.Name, payload.Locale)
+
+	case CommandSaveOutboundEmail:
+		var payload SaveOutboundEmailPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal outbound email payload: %w", err)
+		}
+		return s.Storage.SaveOutboundEmail(payload.Email)
+
+	case CommandDeleteOutboundEmail:
+		var payload DeleteOutboundEmailPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal outbound email payload: %w", err)
+		}
+		return s.Storage.DeleteOutboundEmail(payload.ID)
+
+	case CommandSaveAPIKey:
+		var payload SaveAPIKeyPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal api key payload: %w", err)
+		}
+		return s.Storage.SaveAPIKey(payload.Key)
+
+	case CommandDeleteAPIKey:
+		var payload DeleteAPIKeyPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal api key payload: %w", err)
+		}
+		return s.Storage.DeleteAPIKey(payload.ID)
+
+	case CommandDeleteTenant:
+		var payload DeleteTenantPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal tenant payload: %w", err)
+		}
+		return s.Storage.DeleteTenant(payload.TenantID)
+
+	case CommandSetTenantSuspension:
+		var payload SetTenantSuspensionPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal suspension payload: %w", err)
+		}
+		return s.Storage.SetTenantSuspension(payload.TenantID, payload.Suspension)
+
 	default:
 		return fmt.Errorf("unknown command type: %s", cmd.Type)
 	}
@@ -300,6 +470,25 @@ func (s *BadgerStorage) UpdateTenantStatus(tenantID string, status enterprise.Te
 	return s.proposeCommand(cmd)
 }
 
+func (s *BadgerStorage) DeleteTenant(tenantID string) error {
+	cmd, err := NewRaftCommand(CommandDeleteTenant, DeleteTenantPayload{TenantID: tenantID})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SetTenantSuspension(tenantID string, suspension *enterprise.TenantSuspension) error {
+	cmd, err := NewRaftCommand(CommandSetTenantSuspension, SetTenantSuspensionPayload{
+		TenantID:   tenantID,
+		Suspension: suspension,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
 func (s *BadgerStorage) CreateUser(user *enterprise.ClusterUser) error {
 	cmd, err := NewRaftCommand(CommandCreateUser, CreateUserPayload{User: user})
 	if err != nil {
@@ -331,3 +520,147 @@ func (s *BadgerStorage) SavePlacement(placement *enterprise.PlacementDecision) e
 	}
 	return s.proposeCommand(cmd)
 }
+
+func (s *BadgerStorage) SaveRestoreJob(job *enterprise.RestoreJob) error {
+	cmd, err := NewRaftCommand(CommandSaveRestoreJob, SaveRestoreJobPayload{Job: job})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SaveGateway(gateway *enterprise.GatewayInfo) error {
+	cmd, err := NewRaftCommand(CommandSaveGateway, SaveGatewayPayload{Gateway: gateway})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SaveUsageCheckpoints(checkpoints []*enterprise.UsageCheckpoint) error {
+	cmd, err := NewRaftCommand(CommandSaveUsage, SaveUsagePayload{Checkpoints: checkpoints})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SaveTenantKeyring(keyring *enterprise.TenantKeyring) error {
+	cmd, err := NewRaftCommand(CommandSaveTenantKeys, SaveTenantKeysPayload{Keyring: keyring})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SaveTenantLeases(leases []*enterprise.TenantLease) error {
+	cmd, err := NewRaftCommand(CommandSaveLeases, SaveLeasesPayload{Leases: leases})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) MarkSSOTokenUsed(tokenID string, expiresAt time.Time) error {
+	cmd, err := NewRaftCommand(CommandUseSSOToken, UseSSOTokenPayload{TokenID: tokenID, ExpiresAt: expiresAt})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SaveEmailTemplate(tmpl *enterprise.EmailTemplate) error {
+	cmd, err := NewRaftCommand(CommandSaveEmailTemplate, SaveEmailTemplatePayload{Template: tmpl})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) DeleteEmailTemplate(name, locale string) error {
+	cmd, err := NewRaftCommand(CommandDeleteEmailTemplate, DeleteEmailTemplatePayload{Name: name, Locale: locale})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SaveOutboundEmail(email *enterprise.OutboundEmail) error {
+	cmd, err := NewRaftCommand(CommandSaveOutboundEmail, SaveOutboundEmailPayload{Email: email})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) DeleteOutboundEmail(id string) error {
+	cmd, err := NewRaftCommand(CommandDeleteOutboundEmail, DeleteOutboundEmailPayload{ID: id})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SaveAPIKey(key *enterprise.APIKey) error {
+	cmd, err := NewRaftCommand(CommandSaveAPIKey, SaveAPIKeyPayload{Key: key})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) DeleteAPIKey(keyID string) error {
+	cmd, err := NewRaftCommand(CommandDeleteAPIKey, DeleteAPIKeyPayload{ID: keyID})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SaveFleetMigration(migration *enterprise.FleetMigration) error {
+	cmd, err := NewRaftCommand(CommandSaveFleetMigration, SaveFleetMigrationPayload{Migration: migration})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) SaveFleetMigrationTenant(state *enterprise.FleetMigrationTenant) error {
+	cmd, err := NewRaftCommand(CommandSaveFleetTenant, SaveFleetTenantPayload{State: state})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) AppendAuditEntry(entry *enterprise.AuditEntry) error {
+	cmd, err := NewRaftCommand(CommandAppendAudit, AppendAuditPayload{Entry: entry})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) PruneAuditLog(before time.Time) error {
+	cmd, err := NewRaftCommand(CommandPruneAudit, PruneAuditPayload{Before: before})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) AppendLogEntries(entries []*enterprise.LogEntry) error {
+	cmd, err := NewRaftCommand(CommandAppendLogs, AppendLogsPayload{Entries: entries})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
+
+func (s *BadgerStorage) PruneLogs(before time.Time) error {
+	cmd, err := NewRaftCommand(CommandPruneLogs, PruneLogsPayload{Before: before})
+	if err != nil {
+		return fmt.Errorf("failed to create command: %w", err)
+	}
+	return s.proposeCommand(cmd)
+}
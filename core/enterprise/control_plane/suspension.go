@@ -0,0 +1,122 @@
+package control_plane
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/email"
+)
+
+// SuspendTenant blocks a tenant while keeping its data. Its lease ends, so the
+// node serving it unloads it at its next heartbeat, and the owner is emailed the
+// reason. Suspending a suspended tenant replaces the reason and any appeal.
+func (cp *ControlPlane) SuspendTenant(tenantID string, reason enterprise.SuspensionReason, message, actor string) (*enterprise.Tenant, error) {
+	if !reason.Valid() {
+		return nil, fmt.Errorf("%w %q", enterprise.ErrInvalidSuspension, reason)
+	}
+
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tenant.Status == enterprise.TenantStatusDeleted {
+		return nil, enterprise.ErrTenantNotFound
+	}
+
+	tenant.Suspension = &enterprise.TenantSuspension{
+		Reason:      reason,
+		Message:     message,
+		SuspendedAt: time.Now(),
+		SuspendedBy: actor,
+	}
+	if err := cp.storage.SetTenantSuspension(tenantID, tenant.Suspension); err != nil {
+		return nil, err
+	}
+
+	if err := cp.endTenantLease(tenantID); err != nil {
+		return nil, fmt.Errorf("failed to end tenant lease: %w", err)
+	}
+
+	if err := cp.notifyTenantSuspension(tenant); err != nil {
+		cp.logger.Warn("Failed to send suspension notice", "tenantId", tenantID, "error", err)
+	}
+
+	cp.logger.Info("Suspended tenant", "tenantId", tenantID, "reason", reason)
+	return tenant, nil
+}
+
+// UnsuspendTenant lifts the suspension of a tenant. The next request loads it
+// on a node again.
+func (cp *ControlPlane) UnsuspendTenant(tenantID string) (*enterprise.Tenant, error) {
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !tenant.Suspended() {
+		return nil, enterprise.ErrTenantNotSuspended
+	}
+
+	if err := cp.storage.SetTenantSuspension(tenantID, nil); err != nil {
+		return nil, err
+	}
+	tenant.Suspension = nil
+
+	cp.logger.Info("Unsuspended tenant", "tenantId", tenantID)
+	return tenant, nil
+}
+
+// AppealTenantSuspension records the owner's appeal of a suspension for the
+// admins to review. A later appeal replaces the previous one.
+func (cp *ControlPlane) AppealTenantSuspension(tenantID, message string) (*enterprise.Tenant, error) {
+	tenant, err := cp.storage.GetTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !tenant.Suspended() {
+		return nil, enterprise.ErrTenantNotSuspended
+	}
+
+	suspension := *tenant.Suspension
+	appealedAt := time.Now()
+	suspension.Appeal = message
+	suspension.AppealedAt = &appealedAt
+
+	if err := cp.storage.SetTenantSuspension(tenantID, &suspension); err != nil {
+		return nil, err
+	}
+	tenant.Suspension = &suspension
+
+	return tenant, nil
+}
+
+// SuspensionAppealURL returns where owners of suspended tenants appeal, empty
+// if only the appeal endpoint is offered
+func (cp *ControlPlane) SuspensionAppealURL() string {
+	return cp.config.Suspension.AppealURL
+}
+
+// notifyTenantSuspension emails the owner of a tenant why it was suspended and
+// how to appeal
+func (cp *ControlPlane) notifyTenantSuspension(tenant *enterprise.Tenant) error {
+	user, err := cp.storage.GetUser(tenant.OwnerUserID)
+	if errors.Is(err, enterprise.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = cp.SendEmail(email.TemplateTenantSuspended, user, map[string]interface{}{
+		"TenantID":  tenant.ID,
+		"Domain":    tenant.Domain,
+		"Reason":    string(tenant.Suspension.Reason),
+		"Message":   tenant.Suspension.Message,
+		"AppealURL": cp.SuspensionAppealURL(),
+	})
+	if errors.Is(err, enterprise.ErrEmailSuppressed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package control_plane
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/email"
+)
+
+func TestSuspendTenant(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	tenant := &enterprise.Tenant{ID: "tenant_shop", Domain: "shop.example.com", OwnerUserID: "user-1"}
+	if err := cp.CreateTenant(tenant); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+	lease, err := cp.AcquireTenantLease(tenant.ID, "node-1")
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+
+	if _, err := cp.SuspendTenant(tenant.ID, "spam", "", "admin:ops"); !errors.Is(err, enterprise.ErrInvalidSuspension) {
+		t.Errorf("expected an unknown reason to be rejected, got %v", err)
+	}
+
+	if _, err := cp.SuspendTenant(tenant.ID, enterprise.SuspensionReasonNonPayment, "Invoice 42 is overdue", "admin:ops"); err != nil {
+		t.Fatalf("failed to suspend tenant: %v", err)
+	}
+
+	stored, err := cp.GetTenant(tenant.ID)
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if !stored.Suspended() || stored.Suspension.Reason != enterprise.SuspensionReasonNonPayment || stored.Suspension.SuspendedBy != "admin:ops" {
+		t.Fatalf("expected the suspension to be stored, got %+v", stored.Suspension)
+	}
+
+	// the node loses the lease and unloads the tenant
+	renewed, err := cp.RenewTenantLeases("node-1", []*enterprise.TenantLease{lease})
+	if err != nil {
+		t.Fatalf("failed to renew leases: %v", err)
+	}
+	if len(renewed) != 0 {
+		t.Error("expected the lease of the suspended tenant to be lost")
+	}
+
+	queued, err := cp.ListOutboundEmails()
+	if err != nil {
+		t.Fatalf("failed to list emails: %v", err)
+	}
+	if len(queued) != 1 || queued[0].Template != email.TemplateTenantSuspended || queued[0].To != "user-1@example.com" {
+		t.Fatalf("expected a suspension notice to the owner, got %v", queued)
+	}
+
+	appealed, err := cp.AppealTenantSuspension(tenant.ID, "Paid today")
+	if err != nil {
+		t.Fatalf("failed to appeal: %v", err)
+	}
+	if appealed.Suspension.Appeal != "Paid today" || appealed.Suspension.AppealedAt == nil {
+		t.Errorf("expected the appeal to be recorded, got %+v", appealed.Suspension)
+	}
+	if appealed.Suspension.Reason != enterprise.SuspensionReasonNonPayment {
+		t.Errorf("expected the appeal to keep the reason, got %s", appealed.Suspension.Reason)
+	}
+
+	if _, err := cp.UnsuspendTenant(tenant.ID); err != nil {
+		t.Fatalf("failed to unsuspend tenant: %v", err)
+	}
+
+	stored, err = cp.GetTenant(tenant.ID)
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if stored.Suspended() {
+		t.Error("expected the suspension to be lifted")
+	}
+
+	// the tenant can be loaded again
+	if _, err := cp.AcquireTenantLease(tenant.ID, "node-1"); err != nil {
+		t.Errorf("failed to acquire lease after the suspension was lifted: %v", err)
+	}
+
+	if _, err := cp.UnsuspendTenant(tenant.ID); !errors.Is(err, enterprise.ErrTenantNotSuspended) {
+		t.Errorf("expected ErrTenantNotSuspended, got %v", err)
+	}
+	if _, err := cp.AppealTenantSuspension(tenant.ID, "again"); !errors.Is(err, enterprise.ErrTenantNotSuspended) {
+		t.Errorf("expected ErrTenantNotSuspended, got %v", err)
+	}
+}
+
+func TestSuspensionSurvivesStatusUpdates(t *testing.T) {
+	cp := newTestControlPlaneWithStorage(t)
+	createTestUser(t, cp, "user-1")
+
+	tenant := &enterprise.Tenant{ID: "tenant_shop", Domain: "shop.example.com", OwnerUserID: "user-1"}
+	if err := cp.CreateTenant(tenant); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+	if _, err := cp.SuspendTenant(tenant.ID, enterprise.SuspensionReasonAbuse, "", "admin:ops"); err != nil {
+		t.Fatalf("failed to suspend tenant: %v", err)
+	}
+
+	// nodes report status changes while unloading the tenant
+	if err := cp.storage.UpdateTenantStatus(tenant.ID, enterprise.TenantStatusEvicted); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+
+	stored, err := cp.GetTenant(tenant.ID)
+	if err != nil {
+		t.Fatalf("failed to get tenant: %v", err)
+	}
+	if !stored.Suspended() {
+		t.Error("expected the suspension to be kept")
+	}
+}
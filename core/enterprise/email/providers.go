@@ -0,0 +1,406 @@
+package email
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+var (
+	_ mailer.Mailer = (*PostmarkClient)(nil)
+	_ mailer.Mailer = (*SendGridClient)(nil)
+	_ mailer.Mailer = (*SESClient)(nil)
+)
+
+// Default API base URLs of the HTTP providers
+const (
+	PostmarkBaseURL = "https://api.postmarkapp.com"
+	SendGridBaseURL = "https://api.sendgrid.com"
+)
+
+// defaultHTTPClient bounds a single API call of the HTTP providers
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// PostmarkClient sends emails through the Postmark email API
+type PostmarkClient struct {
+	ServerToken string
+	BaseURL     string       // Default PostmarkBaseURL
+	HTTPClient  *http.Client // Default client with a 30s timeout
+}
+
+// Send implements [mailer.Mailer] interface.
+func (c *PostmarkClient) Send(m *mailer.Message) error {
+	attachments, err := readAttachments(m)
+	if err != nil {
+		return err
+	}
+
+	type header struct {
+		Name  string
+		Value string
+	}
+	type attachment struct {
+		Name        string
+		Content     string
+		ContentType string
+		ContentID   string `json:",omitempty"`
+	}
+
+	body := struct {
+		From        string
+		To          string
+		Cc          string `json:",omitempty"`
+		Bcc         string `json:",omitempty"`
+		Subject     string
+		HtmlBody    string       `json:",omitempty"`
+		TextBody    string       `json:",omitempty"`
+		Headers     []header     `json:",omitempty"`
+		Attachments []attachment `json:",omitempty"`
+	}{
+		From:     m.From.String(),
+		To:       joinAddresses(m.To),
+		Cc:       joinAddresses(m.Cc),
+		Bcc:      joinAddresses(m.Bcc),
+		Subject:  m.Subject,
+		HtmlBody: m.HTML,
+		TextBody: m.Text,
+	}
+
+	for _, name := range sortedKeys(m.Headers) {
+		body.Headers = append(body.Headers, header{Name: name, Value: m.Headers[name]})
+	}
+
+	for _, a := range attachments {
+		pa := attachment{Name: a.name, Content: base64.StdEncoding.EncodeToString(a.content), ContentType: a.contentType}
+		if a.inline {
+			pa.ContentID = "cid:" + a.name
+		}
+		body.Attachments = append(body.Attachments, pa)
+	}
+
+	req, err := newJSONRequest(baseURL(c.BaseURL, PostmarkBaseURL)+"/email", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Postmark-Server-Token", c.ServerToken)
+
+	return doRequest(c.HTTPClient, req, "postmark")
+}
+
+// SendGridClient sends emails through the SendGrid v3 mail send API
+type SendGridClient struct {
+	APIKey     string
+	BaseURL    string       // Default SendGridBaseURL
+	HTTPClient *http.Client // Default client with a 30s timeout
+}
+
+// Send implements [mailer.Mailer] interface.
+func (c *SendGridClient) Send(m *mailer.Message) error {
+	attachments, err := readAttachments(m)
+	if err != nil {
+		return err
+	}
+
+	type address struct {
+		Email string `json:"email"`
+		Name  string `json:"name,omitempty"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	type attachment struct {
+		Content     string `json:"content"`
+		Filename    string `json:"filename"`
+		Type        string `json:"type"`
+		Disposition string `json:"disposition"`
+		ContentID   string `json:"content_id,omitempty"`
+	}
+	type personalization struct {
+		To  []address `json:"to"`
+		Cc  []address `json:"cc,omitempty"`
+		Bcc []address `json:"bcc,omitempty"`
+	}
+
+	convert := func(addresses []mail.Address) []address {
+		result := make([]address, 0, len(addresses))
+		for _, a := range addresses {
+			result = append(result, address{Email: a.Address, Name: a.Name})
+		}
+		return result
+	}
+
+	body := struct {
+		Personalizations []personalization `json:"personalizations"`
+		From             address           `json:"from"`
+		Subject          string            `json:"subject"`
+		Content          []content         `json:"content"`
+		Headers          map[string]string `json:"headers,omitempty"`
+		Attachments      []attachment      `json:"attachments,omitempty"`
+	}{
+		Personalizations: []personalization{{To: convert(m.To), Cc: convert(m.Cc), Bcc: convert(m.Bcc)}},
+		From:             address{Email: m.From.Address, Name: m.From.Name},
+		Subject:          m.Subject,
+		Headers:          m.Headers,
+	}
+
+	// SendGrid requires the plain text part first
+	if m.Text != "" {
+		body.Content = append(body.Content, content{Type: "text/plain", Value: m.Text})
+	}
+	if m.HTML != "" {
+		body.Content = append(body.Content, content{Type: "text/html", Value: m.HTML})
+	}
+
+	for _, a := range attachments {
+		sa := attachment{
+			Content:     base64.StdEncoding.EncodeToString(a.content),
+			Filename:    a.name,
+			Type:        a.contentType,
+			Disposition: "attachment",
+		}
+		if a.inline {
+			sa.Disposition = "inline"
+			sa.ContentID = a.name
+		}
+		body.Attachments = append(body.Attachments, sa)
+	}
+
+	req, err := newJSONRequest(baseURL(c.BaseURL, SendGridBaseURL)+"/v3/mail/send", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+
+	return doRequest(c.HTTPClient, req, "sendgrid")
+}
+
+// SESClient sends emails through the Amazon SES v2 API, signing its requests
+// with AWS Signature Version 4
+type SESClient struct {
+	Region      string
+	Credentials aws.CredentialsProvider
+	BaseURL     string       // Default https://email.<region>.amazonaws.com
+	HTTPClient  *http.Client // Default client with a 30s timeout
+}
+
+// Send implements [mailer.Mailer] interface.
+func (c *SESClient) Send(m *mailer.Message) error {
+	attachments, err := readAttachments(m)
+	if err != nil {
+		return err
+	}
+
+	type text struct {
+		Data    string
+		Charset string
+	}
+	type header struct {
+		Name  string
+		Value string
+	}
+	type attachment struct {
+		RawContent         string
+		FileName           string
+		ContentType        string
+		ContentDisposition string
+		ContentId          string `json:",omitempty"`
+	}
+	type simple struct {
+		Subject text
+		Body    struct {
+			Html *text `json:",omitempty"`
+			Text *text `json:",omitempty"`
+		}
+		Headers     []header     `json:",omitempty"`
+		Attachments []attachment `json:",omitempty"`
+	}
+
+	var content simple
+	content.Subject = text{Data: m.Subject, Charset: "UTF-8"}
+	if m.HTML != "" {
+		content.Body.Html = &text{Data: m.HTML, Charset: "UTF-8"}
+	}
+	if m.Text != "" {
+		content.Body.Text = &text{Data: m.Text, Charset: "UTF-8"}
+	}
+
+	for _, name := range sortedKeys(m.Headers) {
+		content.Headers = append(content.Headers, header{Name: name, Value: m.Headers[name]})
+	}
+
+	for _, a := range attachments {
+		sa := attachment{
+			RawContent:         base64.StdEncoding.EncodeToString(a.content),
+			FileName:           a.name,
+			ContentType:        a.contentType,
+			ContentDisposition: "ATTACHMENT",
+		}
+		if a.inline {
+			sa.ContentDisposition = "INLINE"
+			sa.ContentId = a.name
+		}
+		content.Attachments = append(content.Attachments, sa)
+	}
+
+	body := map[string]interface{}{
+		"FromEmailAddress": m.From.String(),
+		"Destination": map[string][]string{
+			"ToAddresses":  addressStrings(m.To),
+			"CcAddresses":  addressStrings(m.Cc),
+			"BccAddresses": addressStrings(m.Bcc),
+		},
+		"Content": map[string]interface{}{"Simple": content},
+	}
+
+	endpoint := baseURL(c.BaseURL, fmt.Sprintf("https://email.%s.amazonaws.com", c.Region))
+	req, err := newJSONRequest(endpoint+"/v2/email/outbound-emails", body)
+	if err != nil {
+		return err
+	}
+
+	if err := c.sign(req); err != nil {
+		return fmt.Errorf("failed to sign ses request: %w", err)
+	}
+
+	return doRequest(c.HTTPClient, req, "ses")
+}
+
+func (c *SESClient) sign(req *http.Request) error {
+	if c.Credentials == nil {
+		return fmt.Errorf("no credentials")
+	}
+
+	creds, err := c.Credentials.Retrieve(req.Context())
+	if err != nil {
+		return err
+	}
+
+	payload, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(data)
+
+	return v4.NewSigner().SignHTTP(req.Context(), creds, req, hex.EncodeToString(hash[:]), "ses", c.Region, time.Now())
+}
+
+type attachment struct {
+	name        string
+	content     []byte
+	contentType string
+	inline      bool
+}
+
+// readAttachments reads the attachments of a message, the HTTP APIs take
+// them base64 encoded in the request body
+func readAttachments(m *mailer.Message) ([]attachment, error) {
+	var result []attachment
+
+	add := func(files map[string]io.Reader, inline bool) error {
+		for _, name := range sortedKeys(files) {
+			content, err := io.ReadAll(files[name])
+			if err != nil {
+				return fmt.Errorf("failed to read attachment %s: %w", name, err)
+			}
+			result = append(result, attachment{
+				name:        name,
+				content:     content,
+				contentType: http.DetectContentType(content),
+				inline:      inline,
+			})
+		}
+		return nil
+	}
+
+	if err := add(m.Attachments, false); err != nil {
+		return nil, err
+	}
+	if err := add(m.InlineAttachments, true); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func newJSONRequest(url string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// doRequest sends a provider API request and turns an error response into an error
+func doRequest(client *http.Client, req *http.Request, provider string) error {
+	if client == nil {
+		client = defaultHTTPClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s responded %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(message)))
+}
+
+func baseURL(configured, fallback string) string {
+	if configured == "" {
+		return fallback
+	}
+	return strings.TrimRight(configured, "/")
+}
+
+func joinAddresses(addresses []mail.Address) string {
+	return strings.Join(addressStrings(addresses), ",")
+}
+
+func addressStrings(addresses []mail.Address) []string {
+	result := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		result = append(result, a.String())
+	}
+	return result
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
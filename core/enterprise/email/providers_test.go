@@ -0,0 +1,183 @@
+package email
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+// stubRequest is a request received by a stub provider server
+type stubRequest struct {
+	path   string
+	header http.Header
+	body   map[string]interface{}
+}
+
+// newStubServer returns a provider stub recording its last request and
+// responding with status
+func newStubServer(t *testing.T, status int) (*httptest.Server, *stubRequest) {
+	received := &stubRequest{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received.path = r.URL.Path
+		received.header = r.Header.Clone()
+		received.body = nil
+		json.Unmarshal(data, &received.body)
+
+		w.WriteHeader(status)
+		if status >= 300 {
+			w.Write([]byte(`{"message":"rejected"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, received
+}
+
+func testMessage() *mailer.Message {
+	return &mailer.Message{
+		From:    mail.Address{Name: "Cluster", Address: "noreply@example.com"},
+		To:      []mail.Address{{Address: "ada@example.com"}},
+		Subject: "Hello",
+		HTML:    "<p>Hi</p>",
+		Text:    "Hi",
+		Attachments: map[string]io.Reader{
+			"notes.txt": strings.NewReader("notes"),
+		},
+	}
+}
+
+func TestPostmarkClientSend(t *testing.T) {
+	server, received := newStubServer(t, http.StatusOK)
+
+	client := &PostmarkClient{ServerToken: "pm-token", BaseURL: server.URL}
+	if err := client.Send(testMessage()); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	if received.path != "/email" {
+		t.Errorf("expected /email, got %s", received.path)
+	}
+	if received.header.Get("X-Postmark-Server-Token") != "pm-token" {
+		t.Errorf("expected the server token header, got %q", received.header.Get("X-Postmark-Server-Token"))
+	}
+	if received.body["To"] != "<ada@example.com>" || received.body["HtmlBody"] != "<p>Hi</p>" || received.body["TextBody"] != "Hi" {
+		t.Errorf("unexpected body %v", received.body)
+	}
+	if received.body["From"] != `"Cluster" <noreply@example.com>` {
+		t.Errorf("unexpected sender %v", received.body["From"])
+	}
+
+	attachments, _ := received.body["Attachments"].([]interface{})
+	if len(attachments) != 1 || attachments[0].(map[string]interface{})["Content"] != "bm90ZXM=" {
+		t.Errorf("expected the base64 encoded attachment, got %v", attachments)
+	}
+}
+
+func TestSendGridClientSend(t *testing.T) {
+	server, received := newStubServer(t, http.StatusAccepted)
+
+	client := &SendGridClient{APIKey: "sg-key", BaseURL: server.URL + "/"}
+	if err := client.Send(testMessage()); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	if received.path != "/v3/mail/send" {
+		t.Errorf("expected /v3/mail/send, got %s", received.path)
+	}
+	if received.header.Get("Authorization") != "Bearer sg-key" {
+		t.Errorf("expected the bearer API key, got %q", received.header.Get("Authorization"))
+	}
+
+	content, _ := received.body["content"].([]interface{})
+	if len(content) != 2 || content[0].(map[string]interface{})["type"] != "text/plain" {
+		t.Errorf("expected the plain text part first, got %v", content)
+	}
+}
+
+func TestSESClientSend(t *testing.T) {
+	server, received := newStubServer(t, http.StatusOK)
+
+	client := &SESClient{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIDTEST", "secret", ""),
+		BaseURL:     server.URL,
+	}
+	if err := client.Send(testMessage()); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	if received.path != "/v2/email/outbound-emails" {
+		t.Errorf("expected /v2/email/outbound-emails, got %s", received.path)
+	}
+
+	authorization := received.header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDTEST/") || !strings.Contains(authorization, "/eu-west-1/ses/aws4_request") {
+		t.Errorf("expected a SigV4 signature for ses, got %q", authorization)
+	}
+	if received.header.Get("X-Amz-Date") == "" {
+		t.Error("expected the signing date header")
+	}
+
+	destination, _ := received.body["Destination"].(map[string]interface{})
+	if to, _ := destination["ToAddresses"].([]interface{}); len(to) != 1 || to[0] != "<ada@example.com>" {
+		t.Errorf("unexpected destination %v", destination)
+	}
+}
+
+func TestProviderErrorResponse(t *testing.T) {
+	server, _ := newStubServer(t, http.StatusUnprocessableEntity)
+
+	client := &PostmarkClient{ServerToken: "pm-token", BaseURL: server.URL}
+	err := client.Send(testMessage())
+	if err == nil || !strings.Contains(err.Error(), "postmark responded 422") || !strings.Contains(err.Error(), "rejected") {
+		t.Fatalf("expected the provider error, got %v", err)
+	}
+}
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		provider string
+		expected interface{}
+	}{
+		{"", &LogMailer{}},
+		{enterprise.EmailProviderLog, &LogMailer{}},
+		{enterprise.EmailProviderSMTP, &mailer.SMTPClient{}},
+		{enterprise.EmailProviderSendmail, &mailer.Sendmail{}},
+		{enterprise.EmailProviderPostmark, &PostmarkClient{}},
+		{enterprise.EmailProviderSendGrid, &SendGridClient{}},
+		{enterprise.EmailProviderSES, &SESClient{}},
+	}
+
+	for _, tt := range tests {
+		c := &enterprise.ClusterConfig{
+			S3Region:          "us-east-1",
+			S3AccessKeyID:     "AKIDTEST",
+			S3SecretAccessKey: "secret",
+			Email:             enterprise.EmailSettings{Provider: tt.provider, SMTPHost: "localhost", APIKey: "key"},
+		}
+
+		m, err := NewMailer(c)
+		if err != nil {
+			t.Errorf("%q: %v", tt.provider, err)
+			continue
+		}
+		if got, want := fmt.Sprintf("%T", m), fmt.Sprintf("%T", tt.expected); got != want {
+			t.Errorf("%q: expected %s, got %s", tt.provider, want, got)
+		}
+	}
+
+	if _, err := NewMailer(&enterprise.ClusterConfig{Email: enterprise.EmailSettings{Provider: "pigeon"}}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
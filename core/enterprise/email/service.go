@@ -1,190 +1,131 @@
 package email
 
 import (
-	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
-	"html/template"
-	"net/smtp"
-	"time"
+	"log/slog"
+	"net/mail"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/tools/mailer"
 )
 
-// Config holds email service configuration
-type Config struct {
-	SMTPHost     string
-	SMTPPort     int
-	SMTPUsername string
-	SMTPPassword string
-	FromAddress  string
-	FromName     string
+// Service sends cluster emails through the configured provider
+type Service struct {
+	mailer mailer.Mailer
+	from   mail.Address
 }
 
-// Service handles sending emails
-type Service struct {
-	config    *Config
-	templates map[string]*template.Template
+// NewService creates an email service sending from the given address
+func NewService(m mailer.Mailer, from mail.Address) *Service {
+	return &Service{mailer: m, from: from}
 }
 
-// NewService creates a new email service
-func NewService(config *Config) *Service {
-	service := &Service{
-		config:    config,
-		templates: make(map[string]*template.Template),
+// NewServiceFromConfig creates the email service of the configured provider
+func NewServiceFromConfig(c *enterprise.ClusterConfig) (*Service, error) {
+	m, err := NewMailer(c)
+	if err != nil {
+		return nil, err
 	}
 
-	// Load built-in templates
-	service.loadTemplates()
-
-	return service
+	return NewService(m, mail.Address{Name: c.Email.FromName, Address: c.Email.FromAddress}), nil
 }
 
-// loadTemplates loads email templates
-func (s *Service) loadTemplates() {
-	// Verification email template
-	verificationTemplate := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background: #4a5568; color: white; padding: 20px; text-align: center; }
-        .content { background: #f7fafc; padding: 30px; }
-        .button { display: inline-block; padding: 12px 24px; background: #4299e1; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
-        .footer { text-align: center; color: #718096; font-size: 12px; margin-top: 20px; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>Verify Your Email</h1>
-        </div>
-        <div class="content">
-            <p>Hi {{.Name}},</p>
-            <p>Thank you for signing up! Please verify your email address by clicking the button below:</p>
-            <p style="text-align: center;">
-                <a href="{{.VerificationURL}}" class="button">Verify Email</a>
-            </p>
-            <p>Or copy and paste this link into your browser:</p>
-            <p><a href="{{.VerificationURL}}">{{.VerificationURL}}</a></p>
-            <p>This link will expire in 24 hours.</p>
-            <p>If you didn't create an account, please ignore this email.</p>
-        </div>
-        <div class="footer">
-            <p>&copy; {{.Year}} PocketBase Enterprise. All rights reserved.</p>
-        </div>
-    </div>
-</body>
-</html>
-`
-
-	// Use template.Must to ensure template parsing errors are caught at startup
-	s.templates["verification"] = template.Must(template.New("verification").Parse(verificationTemplate))
-
-	// Password reset template
-	passwordResetTemplate := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background: #4a5568; color: white; padding: 20px; text-align: center; }
-        .content { background: #f7fafc; padding: 30px; }
-        .button { display: inline-block; padding: 12px 24px; background: #e53e3e; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
-        .footer { text-align: center; color: #718096; font-size: 12px; margin-top: 20px; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>Reset Your Password</h1>
-        </div>
-        <div class="content">
-            <p>Hi {{.Name}},</p>
-            <p>We received a request to reset your password. Click the button below to create a new password:</p>
-            <p style="text-align: center;">
-                <a href="{{.ResetURL}}" class="button">Reset Password</a>
-            </p>
-            <p>Or copy and paste this link into your browser:</p>
-            <p><a href="{{.ResetURL}}">{{.ResetURL}}</a></p>
-            <p>This link will expire in 1 hour.</p>
-            <p>If you didn't request this, please ignore this email.</p>
-        </div>
-        <div class="footer">
-            <p>&copy; {{.Year}} PocketBase Enterprise. All rights reserved.</p>
-        </div>
-    </div>
-</body>
-</html>
-`
-
-	s.templates["password_reset"] = template.Must(template.New("password_reset").Parse(passwordResetTemplate))
+// Send sends a rendered email to a single recipient
+func (s *Service) Send(to, subject, html string) error {
+	return s.mailer.Send(&mailer.Message{
+		From:    s.from,
+		To:      []mail.Address{{Address: to}},
+		Subject: subject,
+		HTML:    html,
+	})
 }
 
-// SendVerificationEmail sends an email verification email
-func (s *Service) SendVerificationEmail(to, name, verificationToken string, baseURL string) error {
-	verificationURL := fmt.Sprintf("%s/api/enterprise/users/verify?token=%s", baseURL, verificationToken)
-
-	data := map[string]interface{}{
-		"Name":            name,
-		"VerificationURL": verificationURL,
-		"Year":            time.Now().Year(),
+// NewMailer creates the mail client of the configured provider. Without a
+// provider emails are logged, which is enough for development.
+func NewMailer(c *enterprise.ClusterConfig) (mailer.Mailer, error) {
+	e := c.Email
+
+	switch e.Provider {
+	case "", enterprise.EmailProviderLog:
+		return &LogMailer{logger: enterprise.ComponentLogger(enterprise.LogComponentEmail)}, nil
+
+	case enterprise.EmailProviderSMTP:
+		port := e.SMTPPort
+		if port == 0 {
+			port = 587
+		}
+		return &mailer.SMTPClient{
+			Host:     e.SMTPHost,
+			Port:     port,
+			Username: e.SMTPUsername,
+			Password: e.SMTPPassword,
+			TLS:      e.SMTPTLS,
+		}, nil
+
+	case enterprise.EmailProviderSendmail:
+		return &mailer.Sendmail{}, nil
+
+	case enterprise.EmailProviderPostmark:
+		return &PostmarkClient{ServerToken: e.APIKey, BaseURL: e.Endpoint}, nil
+
+	case enterprise.EmailProviderSendGrid:
+		return &SendGridClient{APIKey: e.APIKey, BaseURL: e.Endpoint}, nil
+
+	case enterprise.EmailProviderSES:
+		region := e.Region
+		if region == "" {
+			region = c.S3Region
+		}
+		creds, err := sesCredentials(c, region)
+		if err != nil {
+			return nil, err
+		}
+		return &SESClient{Region: region, Credentials: creds, BaseURL: e.Endpoint}, nil
 	}
 
-	var body bytes.Buffer
-	if err := s.templates["verification"].Execute(&body, data); err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
-	}
-
-	return s.sendEmail(to, "Verify Your Email", body.String())
+	return nil, fmt.Errorf("unknown email provider %q", e.Provider)
 }
 
-// SendPasswordResetEmail sends a password reset email
-func (s *Service) SendPasswordResetEmail(to, name, resetToken string, baseURL string) error {
-	resetURL := fmt.Sprintf("%s/reset-password?token=%s", baseURL, resetToken)
+// sesCredentials returns the SES credentials, falling back to the S3 ones and
+// then to the default AWS credential chain
+func sesCredentials(c *enterprise.ClusterConfig, region string) (aws.CredentialsProvider, error) {
+	accessKeyID, secretAccessKey := c.Email.AccessKeyID, c.Email.SecretAccessKey
+	if accessKeyID == "" && secretAccessKey == "" {
+		accessKeyID, secretAccessKey = c.S3AccessKeyID, c.S3SecretAccessKey
+	}
 
-	data := map[string]interface{}{
-		"Name":     name,
-		"ResetURL": resetURL,
-		"Year":     time.Now().Year(),
+	if accessKeyID != "" {
+		return credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, ""), nil
 	}
 
-	var body bytes.Buffer
-	if err := s.templates["password_reset"].Execute(&body, data); err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
+	return cfg.Credentials, nil
+}
 
-	return s.sendEmail(to, "Reset Your Password", body.String())
+// LogMailer logs emails instead of sending them
+type LogMailer struct {
+	logger *slog.Logger
 }
 
-// sendEmail sends an email using SMTP
-func (s *Service) sendEmail(to, subject, body string) error {
-	// If SMTP is not configured, log and skip (for development)
-	if s.config.SMTPHost == "" {
-		fmt.Printf("[Email] Would send email to %s: %s\n", to, subject)
-		return nil
+// Send implements [mailer.Mailer] interface.
+func (m *LogMailer) Send(message *mailer.Message) error {
+	to := make([]string, 0, len(message.To))
+	for _, a := range message.To {
+		to = append(to, a.Address)
 	}
 
-	// Set up authentication
-	auth := smtp.PlainAuth("", s.config.SMTPUsername, s.config.SMTPPassword, s.config.SMTPHost)
-
-	// Compose message
-	from := fmt.Sprintf("%s <%s>", s.config.FromName, s.config.FromAddress)
-	message := fmt.Sprintf("From: %s\r\n"+
-		"To: %s\r\n"+
-		"Subject: %s\r\n"+
-		"MIME-Version: 1.0\r\n"+
-		"Content-Type: text/html; charset=UTF-8\r\n"+
-		"\r\n"+
-		"%s", from, to, subject, body)
-
-	// Send email
-	addr := fmt.Sprintf("%s:%d", s.config.SMTPHost, s.config.SMTPPort)
-	return smtp.SendMail(addr, auth, s.config.FromAddress, []string{to}, []byte(message))
+	m.logger.Info("Email not sent, no email provider configured", "to", to, "subject", message.Subject)
+	m.logger.Debug("Email body", "html", message.HTML)
+	return nil
 }
 
 // GenerateToken generates a random token for verification
@@ -0,0 +1,320 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// Built-in templates
+const (
+	TemplateVerification    = "verification"
+	TemplatePasswordReset   = "password_reset"
+	TemplateTenantExpiring  = "tenant_expiring"
+	TemplateTenantSuspended = "tenant_suspended"
+)
+
+// defaultTemplates are the built-in English templates, admins can override
+// them with EmailTemplate entries per locale
+var defaultTemplates = map[string]*enterprise.EmailTemplate{
+	TemplateVerification: {
+		Name:    TemplateVerification,
+		Subject: "Verify Your Email",
+		HTML: `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: #4a5568; color: white; padding: 20px; text-align: center; }
+        .content { background: #f7fafc; padding: 30px; }
+        .button { display: inline-block; padding: 12px 24px; background: #4299e1; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .footer { text-align: center; color: #718096; font-size: 12px; margin-top: 20px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Verify Your Email</h1>
+        </div>
+        <div class="content">
+            <p>Hi {{.Name}},</p>
+            <p>Thank you for signing up! Please verify your email address by clicking the button below:</p>
+            <p style="text-align: center;">
+                <a href="{{.VerificationURL}}" class="button">Verify Email</a>
+            </p>
+            <p>Or copy and paste this link into your browser:</p>
+            <p><a href="{{.VerificationURL}}">{{.VerificationURL}}</a></p>
+            <p>This link will expire in 24 hours.</p>
+            <p>If you didn't create an account, please ignore this email.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; {{.Year}} PocketBase Enterprise. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>
+`,
+	},
+	TemplatePasswordReset: {
+		Name:    TemplatePasswordReset,
+		Subject: "Reset Your Password",
+		HTML: `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: #4a5568; color: white; padding: 20px; text-align: center; }
+        .content { background: #f7fafc; padding: 30px; }
+        .button { display: inline-block; padding: 12px 24px; background: #e53e3e; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .footer { text-align: center; color: #718096; font-size: 12px; margin-top: 20px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Reset Your Password</h1>
+        </div>
+        <div class="content">
+            <p>Hi {{.Name}},</p>
+            <p>We received a request to reset your password. Click the button below to create a new password:</p>
+            <p style="text-align: center;">
+                <a href="{{.ResetURL}}" class="button">Reset Password</a>
+            </p>
+            <p>Or copy and paste this link into your browser:</p>
+            <p><a href="{{.ResetURL}}">{{.ResetURL}}</a></p>
+            <p>This link will expire in 1 hour.</p>
+            <p>If you didn't request this, please ignore this email.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; {{.Year}} PocketBase Enterprise. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>
+`,
+	},
+	TemplateTenantExpiring: {
+		Name:    TemplateTenantExpiring,
+		Subject: "Your tenant {{.TenantID}} will be deleted",
+		HTML: `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: #4a5568; color: white; padding: 20px; text-align: center; }
+        .content { background: #f7fafc; padding: 30px; }
+        .footer { text-align: center; color: #718096; font-size: 12px; margin-top: 20px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Tenant Expiring</h1>
+        </div>
+        <div class="content">
+            <p>Hi {{.Name}},</p>
+            <p>Your tenant <strong>{{.TenantID}}</strong> ({{.Domain}}) expires on {{.ExpiresAt}}.</p>
+            <p>It will then be deleted together with its data and files, this can't be undone.</p>
+            <p>To keep the tenant longer, extend its expiry before then, e.g. with <code>pocketbase cluster tenants extend {{.TenantID}} --ttl 72h</code>.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; {{.Year}} PocketBase Enterprise. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>
+`,
+	},
+	TemplateTenantSuspended: {
+		Name:    TemplateTenantSuspended,
+		Subject: "Your tenant {{.TenantID}} was suspended",
+		HTML: `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: #4a5568; color: white; padding: 20px; text-align: center; }
+        .content { background: #f7fafc; padding: 30px; }
+        .footer { text-align: center; color: #718096; font-size: 12px; margin-top: 20px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Tenant Suspended</h1>
+        </div>
+        <div class="content">
+            <p>Hi {{.Name}},</p>
+            <p>Your tenant <strong>{{.TenantID}}</strong> ({{.Domain}}) was suspended{{if eq .Reason "non_payment"}} because of unpaid invoices{{else}} for violating the terms of service{{end}}.</p>
+            {{if .Message}}<p>{{.Message}}</p>{{end}}
+            <p>Its data is kept, but it doesn't serve requests until the suspension is lifted.</p>
+            <p>To appeal, {{if .AppealURL}}visit <a href="{{.AppealURL}}">{{.AppealURL}}</a> or {{end}}run <code>pocketbase cluster tenants appeal {{.TenantID}} --message "..."</code>.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; {{.Year}} PocketBase Enterprise. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>
+`,
+	},
+}
+
+// DefaultTemplate returns a copy of a built-in template
+func DefaultTemplate(name string) (*enterprise.EmailTemplate, error) {
+	tmpl, ok := defaultTemplates[name]
+	if !ok {
+		return nil, enterprise.ErrEmailTemplateNotFound
+	}
+	clone := *tmpl
+	return &clone, nil
+}
+
+// TemplateNames returns the names of the built-in templates
+func TemplateNames() []string {
+	return sortedKeys(defaultTemplates)
+}
+
+// ResolveTemplate returns the template to send for a locale. Overrides are
+// looked up for the locale, its language and without a locale, the built-in
+// template is the last resort.
+func ResolveTemplate(name, locale string, lookup func(name, locale string) (*enterprise.EmailTemplate, error)) (*enterprise.EmailTemplate, error) {
+	for _, candidate := range LocaleFallbacks(locale) {
+		tmpl, err := lookup(name, candidate)
+		if err == nil {
+			return tmpl, nil
+		}
+		if !errors.Is(err, enterprise.ErrEmailTemplateNotFound) {
+			return nil, err
+		}
+	}
+
+	return DefaultTemplate(name)
+}
+
+// LocaleFallbacks returns the locales a template is looked up for, most
+// specific first, e.g. "pt-BR", "pt" and ""
+func LocaleFallbacks(locale string) []string {
+	locale = NormalizeLocale(locale)
+
+	var result []string
+	for locale != "" {
+		result = append(result, locale)
+		i := strings.LastIndexByte(locale, '-')
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+
+	return append(result, "")
+}
+
+// NormalizeLocale formats a language tag the way templates are stored, with
+// a lowercase language and an uppercase region ("pt_br" becomes "pt-BR")
+func NormalizeLocale(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	for i, part := range parts {
+		if i > 0 && len(part) == 2 {
+			parts[i] = strings.ToUpper(part)
+		} else {
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Trim(strings.Join(parts, "-"), "-")
+}
+
+// PreferredLocale returns the highest weighted language of an Accept-Language header
+func PreferredLocale(acceptLanguage string) string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var candidates []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		locale, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if _, err := fmt.Sscanf(value, "%g", &q); err != nil {
+				continue
+			}
+		}
+		candidates = append(candidates, weighted{locale, q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	if len(candidates) == 0 || candidates[0].q <= 0 {
+		return ""
+	}
+	return NormalizeLocale(candidates[0].locale)
+}
+
+// ValidateTemplate checks an admin template before it is saved
+func ValidateTemplate(tmpl *enterprise.EmailTemplate) error {
+	if _, ok := defaultTemplates[tmpl.Name]; !ok {
+		return fmt.Errorf("unknown template %q, expected one of %s", tmpl.Name, strings.Join(TemplateNames(), ", "))
+	}
+	tmpl.Locale = NormalizeLocale(tmpl.Locale)
+
+	if strings.TrimSpace(tmpl.Subject) == "" {
+		return fmt.Errorf("subject is required")
+	}
+	if strings.TrimSpace(tmpl.HTML) == "" {
+		return fmt.Errorf("html is required")
+	}
+
+	if _, err := texttemplate.New("subject").Parse(tmpl.Subject); err != nil {
+		return fmt.Errorf("invalid subject: %w", err)
+	}
+	if _, err := htmltemplate.New("html").Parse(tmpl.HTML); err != nil {
+		return fmt.Errorf("invalid html: %w", err)
+	}
+
+	return nil
+}
+
+// Render executes the subject and HTML body of a template with data. The HTML
+// is escaped as HTML, the subject as plain text.
+func Render(tmpl *enterprise.EmailTemplate, data map[string]interface{}) (subject string, html string, err error) {
+	subjectTmpl, err := texttemplate.New("subject").Parse(tmpl.Subject)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid subject: %w", err)
+	}
+	htmlTmpl, err := htmltemplate.New("html").Parse(tmpl.HTML)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid html: %w", err)
+	}
+
+	var subjectBuf, htmlBuf bytes.Buffer
+	if err := subjectTmpl.Execute(&subjectBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := htmlTmpl.Execute(&htmlBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render html: %w", err)
+	}
+
+	// Headers can't span lines
+	subject = strings.Join(strings.Fields(subjectBuf.String()), " ")
+
+	return subject, htmlBuf.String(), nil
+}
@@ -0,0 +1,102 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestLocaleFallbacks(t *testing.T) {
+	tests := []struct {
+		locale   string
+		expected string
+	}{
+		{"", ""},
+		{"de", "de,"},
+		{"pt_br", "pt-BR,pt,"},
+		{"zh-Hant-TW", "zh-hant-TW,zh-hant,zh,"},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(LocaleFallbacks(tt.locale), ","); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.locale, tt.expected, got)
+		}
+	}
+}
+
+func TestPreferredLocale(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"de", "de"},
+		{"en;q=0.5, pt-br;q=0.9, *;q=0.1", "pt-BR"},
+		{"fr-CH, fr;q=0.9", "fr-CH"},
+		{"*", ""},
+	}
+
+	for _, tt := range tests {
+		if got := PreferredLocale(tt.header); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.header, tt.expected, got)
+		}
+	}
+}
+
+func TestResolveTemplate(t *testing.T) {
+	overrides := map[string]*enterprise.EmailTemplate{
+		"pt": {Name: TemplateVerification, Locale: "pt", Subject: "pt"},
+		"":   {Name: TemplateVerification, Subject: "any"},
+	}
+	lookup := func(name, locale string) (*enterprise.EmailTemplate, error) {
+		if tmpl, ok := overrides[locale]; ok && name == TemplateVerification {
+			return tmpl, nil
+		}
+		return nil, enterprise.ErrEmailTemplateNotFound
+	}
+
+	tmpl, err := ResolveTemplate(TemplateVerification, "pt-BR", lookup)
+	if err != nil || tmpl.Subject != "pt" {
+		t.Errorf("expected the language override, got %v %v", tmpl, err)
+	}
+
+	tmpl, err = ResolveTemplate(TemplateVerification, "de", lookup)
+	if err != nil || tmpl.Subject != "any" {
+		t.Errorf("expected the override without a locale, got %v %v", tmpl, err)
+	}
+
+	tmpl, err = ResolveTemplate(TemplatePasswordReset, "de", lookup)
+	if err != nil || tmpl.Subject != "Reset Your Password" {
+		t.Errorf("expected the built-in template, got %v %v", tmpl, err)
+	}
+
+	if _, err := ResolveTemplate("unknown", "", lookup); err != enterprise.ErrEmailTemplateNotFound {
+		t.Errorf("expected ErrEmailTemplateNotFound, got %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpl := &enterprise.EmailTemplate{
+		Subject: "Welcome\n{{.Name}} & co",
+		HTML:    `<p>Hi {{.Name}}</p><a href="{{.VerificationURL}}">verify</a>`,
+	}
+
+	subject, html, err := Render(tmpl, map[string]interface{}{
+		"Name":            "<Ada>",
+		"VerificationURL": "https://example.com/verify?token=a b",
+	})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	if subject != "Welcome <Ada> & co" {
+		t.Errorf("expected an unescaped single line subject, got %q", subject)
+	}
+	if !strings.Contains(html, "Hi &lt;Ada&gt;") {
+		t.Errorf("expected the name to be HTML escaped, got %q", html)
+	}
+	if !strings.Contains(html, `href="https://example.com/verify?token=a%20b"`) {
+		t.Errorf("expected the URL to be escaped, got %q", html)
+	}
+}
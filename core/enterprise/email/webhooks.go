@@ -0,0 +1,151 @@
+package email
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// Suppression is an address a provider reported as undeliverable
+type Suppression struct {
+	Address string
+	Status  enterprise.EmailStatus
+}
+
+// SubscriptionConfirmationError is returned for the SNS message confirming
+// an SES webhook subscription, which has to be confirmed by visiting URL
+type SubscriptionConfirmationError struct {
+	URL string
+}
+
+func (e *SubscriptionConfirmationError) Error() string {
+	return "sns subscription confirmation required: " + e.URL
+}
+
+// ParseWebhook extracts the hard bounces and spam complaints of a provider
+// webhook. Soft bounces, deliveries and other events are ignored.
+func ParseWebhook(provider string, body []byte) ([]Suppression, error) {
+	switch provider {
+	case enterprise.EmailProviderPostmark:
+		return parsePostmarkWebhook(body)
+	case enterprise.EmailProviderSendGrid:
+		return parseSendGridWebhook(body)
+	case enterprise.EmailProviderSES:
+		return parseSESWebhook(body)
+	}
+	return nil, fmt.Errorf("unknown email provider %q", provider)
+}
+
+// parsePostmarkWebhook parses a Postmark bounce or spam complaint webhook,
+// one event per request
+func parsePostmarkWebhook(body []byte) ([]Suppression, error) {
+	var event struct {
+		RecordType string
+		Type       string
+		Email      string
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid postmark webhook: %w", err)
+	}
+
+	switch {
+	case event.RecordType == "SpamComplaint" || event.Type == "SpamComplaint":
+		return suppressions(enterprise.EmailStatusComplained, event.Email), nil
+	case event.RecordType == "Bounce" && (event.Type == "HardBounce" || event.Type == "BadEmailAddress"):
+		return suppressions(enterprise.EmailStatusBounced, event.Email), nil
+	}
+	return nil, nil
+}
+
+// parseSendGridWebhook parses a batch of SendGrid event webhook events
+func parseSendGridWebhook(body []byte) ([]Suppression, error) {
+	var events []struct {
+		Event string `json:"event"`
+		Type  string `json:"type"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, fmt.Errorf("invalid sendgrid webhook: %w", err)
+	}
+
+	var result []Suppression
+	for _, event := range events {
+		switch {
+		case event.Event == "spamreport":
+			result = append(result, suppressions(enterprise.EmailStatusComplained, event.Email)...)
+		// Blocked bounces are temporary rejections by the receiving server
+		case event.Event == "bounce" && event.Type != "blocked":
+			result = append(result, suppressions(enterprise.EmailStatusBounced, event.Email)...)
+		}
+	}
+	return result, nil
+}
+
+// parseSESWebhook parses an SES notification delivered by SNS
+func parseSESWebhook(body []byte) ([]Suppression, error) {
+	var envelope struct {
+		Type         string
+		Message      string
+		SubscribeURL string
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid sns message: %w", err)
+	}
+
+	switch envelope.Type {
+	case "SubscriptionConfirmation":
+		return nil, &SubscriptionConfirmationError{URL: envelope.SubscribeURL}
+	case "Notification":
+	default:
+		return nil, nil
+	}
+
+	type recipient struct {
+		EmailAddress string `json:"emailAddress"`
+	}
+	var notification struct {
+		NotificationType string `json:"notificationType"`
+		EventType        string `json:"eventType"` // Configuration set event publishing
+		Bounce           struct {
+			BounceType        string      `json:"bounceType"`
+			BouncedRecipients []recipient `json:"bouncedRecipients"`
+		} `json:"bounce"`
+		Complaint struct {
+			ComplainedRecipients []recipient `json:"complainedRecipients"`
+		} `json:"complaint"`
+	}
+	if err := json.Unmarshal([]byte(envelope.Message), &notification); err != nil {
+		return nil, fmt.Errorf("invalid ses notification: %w", err)
+	}
+
+	kind := notification.NotificationType
+	if kind == "" {
+		kind = notification.EventType
+	}
+
+	var result []Suppression
+	switch kind {
+	case "Bounce":
+		if notification.Bounce.BounceType != "Permanent" {
+			return nil, nil
+		}
+		for _, r := range notification.Bounce.BouncedRecipients {
+			result = append(result, suppressions(enterprise.EmailStatusBounced, r.EmailAddress)...)
+		}
+	case "Complaint":
+		for _, r := range notification.Complaint.ComplainedRecipients {
+			result = append(result, suppressions(enterprise.EmailStatusComplained, r.EmailAddress)...)
+		}
+	}
+	return result, nil
+}
+
+func suppressions(status enterprise.EmailStatus, address string) []Suppression {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return nil
+	}
+	return []Suppression{{Address: address, Status: status}}
+}
@@ -0,0 +1,108 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestParseWebhook(t *testing.T) {
+	sesNotification := func(message string) string {
+		data, _ := json.Marshal(map[string]string{"Type": "Notification", "Message": message})
+		return string(data)
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		body     string
+		expected []Suppression
+	}{
+		{
+			"postmark hard bounce",
+			enterprise.EmailProviderPostmark,
+			`{"RecordType":"Bounce","Type":"HardBounce","Email":"ada@example.com"}`,
+			[]Suppression{{"ada@example.com", enterprise.EmailStatusBounced}},
+		},
+		{
+			"postmark soft bounce",
+			enterprise.EmailProviderPostmark,
+			`{"RecordType":"Bounce","Type":"SoftBounce","Email":"ada@example.com"}`,
+			nil,
+		},
+		{
+			"postmark spam complaint",
+			enterprise.EmailProviderPostmark,
+			`{"RecordType":"SpamComplaint","Type":"SpamComplaint","Email":"ada@example.com"}`,
+			[]Suppression{{"ada@example.com", enterprise.EmailStatusComplained}},
+		},
+		{
+			"sendgrid batch",
+			enterprise.EmailProviderSendGrid,
+			`[
+				{"event":"delivered","email":"a@example.com"},
+				{"event":"bounce","type":"bounce","email":"b@example.com"},
+				{"event":"bounce","type":"blocked","email":"c@example.com"},
+				{"event":"spamreport","email":"d@example.com"}
+			]`,
+			[]Suppression{
+				{"b@example.com", enterprise.EmailStatusBounced},
+				{"d@example.com", enterprise.EmailStatusComplained},
+			},
+		},
+		{
+			"ses permanent bounce",
+			enterprise.EmailProviderSES,
+			sesNotification(`{"notificationType":"Bounce","bounce":{"bounceType":"Permanent","bouncedRecipients":[{"emailAddress":"ada@example.com"}]}}`),
+			[]Suppression{{"ada@example.com", enterprise.EmailStatusBounced}},
+		},
+		{
+			"ses transient bounce",
+			enterprise.EmailProviderSES,
+			sesNotification(`{"notificationType":"Bounce","bounce":{"bounceType":"Transient","bouncedRecipients":[{"emailAddress":"ada@example.com"}]}}`),
+			nil,
+		},
+		{
+			"ses event publishing complaint",
+			enterprise.EmailProviderSES,
+			sesNotification(`{"eventType":"Complaint","complaint":{"complainedRecipients":[{"emailAddress":"ada@example.com"}]}}`),
+			[]Suppression{{"ada@example.com", enterprise.EmailStatusComplained}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseWebhook(tt.provider, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, result)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected[i], result[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseWebhookErrors(t *testing.T) {
+	_, err := ParseWebhook(enterprise.EmailProviderSES, []byte(`{"Type":"SubscriptionConfirmation","SubscribeURL":"https://sns.example.com/confirm"}`))
+
+	var confirmation *SubscriptionConfirmationError
+	if !errors.As(err, &confirmation) || confirmation.URL != "https://sns.example.com/confirm" {
+		t.Errorf("expected a subscription confirmation, got %v", err)
+	}
+
+	if _, err := ParseWebhook(enterprise.EmailProviderSendGrid, []byte(`{`)); err == nil {
+		t.Error("expected an error for an invalid body")
+	}
+	if _, err := ParseWebhook(enterprise.EmailProviderSMTP, []byte(`{}`)); err == nil {
+		t.Error("expected an error for a provider without webhooks")
+	}
+}
@@ -13,17 +13,46 @@ var (
 	ErrTenantNotAssigned   = errors.New("tenant not assigned to any node")
 	ErrTenantOffline       = errors.New("tenant is offline")
 	ErrTenantOverQuota     = errors.New("tenant over quota")
+	ErrTenantArchived      = errors.New("tenant is archived and must be restored")
+	ErrRestoreJobNotFound  = errors.New("restore job not found")
+	ErrTenantKeysNotFound  = errors.New("tenant has no data keys")
+	ErrTenantKeysShredded  = errors.New("tenant data keys were shredded")
+	ErrTenantMoved         = errors.New("tenant is assigned to another node")
+	ErrTenantLeaseHeld     = errors.New("tenant lease is held by another node")
+	ErrTenantLeaseLost     = errors.New("tenant lease expired or was taken over")
+	ErrTenantLeaseNotFound = errors.New("tenant lease not found")
+	ErrInvalidTenantExpiry = errors.New("invalid tenant expiry")
+	ErrTenantPreview       = errors.New("preview tenants are not archived")
+	ErrTenantSuspended     = errors.New("tenant is suspended")
+	ErrTenantNotSuspended  = errors.New("tenant is not suspended")
+	ErrInvalidSuspension   = errors.New("invalid suspension reason")
+	ErrInvalidAccessPolicy = errors.New("invalid access policy")
 
 	// Node errors
 	ErrNodeNotFound       = errors.New("node not found")
 	ErrNodeAtCapacity     = errors.New("node at capacity")
 	ErrNodeOffline        = errors.New("node is offline")
 	ErrNoHealthyNodes     = errors.New("no healthy nodes available")
+	ErrNodeDraining       = errors.New("node is draining")
+
+	// Region errors
+	ErrUnknownRegion      = errors.New("unknown region")
+	ErrNoNodesInRegion    = errors.New("no healthy nodes in the tenant's region")
+	ErrRegionMismatch     = errors.New("tenant is resident in another region")
+
+	// Fleet migration errors
+	ErrFleetMigrationNotFound = errors.New("fleet migration not found")
+	ErrFleetMigrationFinished = errors.New("fleet migration is aborted or completed")
+
+	// Gateway errors
+	ErrUsageCheckpointNotFound = errors.New("usage checkpoint not found")
 
 	// Control plane errors
 	ErrNotLeader          = errors.New("not the raft leader")
 	ErrControlPlaneDown   = errors.New("control plane unavailable")
 	ErrPlacementFailed    = errors.New("placement failed")
+	ErrStaleRead          = errors.New("control plane follower is too far behind the leader")
+	ErrIPCUnauthorized    = errors.New("ipc request not authorized")
 
 	// User errors
 	ErrUserNotFound       = errors.New("cluster user not found")
@@ -32,10 +61,20 @@ var (
 	ErrUserNotVerified    = errors.New("user email not verified")
 	ErrUserOverQuota      = errors.New("user over quota")
 
+	// Email errors
+	ErrEmailTemplateNotFound = errors.New("email template not found")
+	ErrInvalidEmailTemplate  = errors.New("invalid email template")
+	ErrEmailSuppressed       = errors.New("email address bounced or complained")
+	ErrOutboundEmailNotFound = errors.New("outbound email not found")
+
 	// Auth errors
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrTokenExpired       = errors.New("token expired")
 	ErrUnauthorized       = errors.New("unauthorized")
+	ErrSSOTokenUsed       = errors.New("sso token was already used")
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrInvalidAPIKey      = errors.New("invalid api key")
+	ErrTooManyAPIKeys     = errors.New("too many api keys")
 
 	// Storage errors
 	ErrS3DownloadFailed   = errors.New("S3 download failed")
@@ -111,3 +150,21 @@ func NewQuotaError(resource string, current, limit int64) *QuotaError {
 		Limit:    limit,
 	}
 }
+
+// ConfigError is an invalid cluster config field
+type ConfigError struct {
+	Field   string // json path of the field, e.g. "disk.warningThresholdPct"
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("config field %s: %s", e.Field, e.Message)
+}
+
+// NewConfigError creates a new config error
+func NewConfigError(field, message string) *ConfigError {
+	return &ConfigError{
+		Field:   field,
+		Message: message,
+	}
+}
@@ -0,0 +1,80 @@
+package enterprise
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+const (
+	// DefaultFleetMigrationBatchSize is the number of tenants an eager rollout loads at once
+	DefaultFleetMigrationBatchSize = 50
+)
+
+// Validate checks a new fleet migration and fills in the defaults
+func (m *FleetMigration) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if m.Script == "" {
+		return fmt.Errorf("script is required")
+	}
+
+	switch m.Format {
+	case FleetMigrationJS, FleetMigrationJSON:
+	case "":
+		m.Format = FleetMigrationJS
+	default:
+		return fmt.Errorf("unknown format %q, expected js or json", m.Format)
+	}
+
+	switch m.Mode {
+	case FleetMigrationLazy, FleetMigrationEager:
+	case "":
+		m.Mode = FleetMigrationLazy
+	default:
+		return fmt.Errorf("unknown mode %q, expected lazy or eager", m.Mode)
+	}
+
+	if m.CanaryPercent == 0 {
+		m.CanaryPercent = 100
+	}
+	if m.CanaryPercent < 1 || m.CanaryPercent > 100 {
+		return fmt.Errorf("canaryPercent must be between 1 and 100")
+	}
+
+	if m.BatchSize == 0 {
+		m.BatchSize = DefaultFleetMigrationBatchSize
+	}
+	if m.BatchSize < 0 {
+		return fmt.Errorf("batchSize must be positive")
+	}
+
+	return nil
+}
+
+// IsFinished reports whether the migration was aborted or completed
+func (m *FleetMigration) IsFinished() bool {
+	return m.Status == FleetMigrationAborted || m.Status == FleetMigrationCompleted
+}
+
+// Targets reports whether a tenant is within the canary percentage. Each tenant
+// falls in a fixed bucket per migration, so raising the percentage only adds
+// tenants and never drops ones that were already migrated.
+func (m *FleetMigration) Targets(tenantID string) bool {
+	if m.CanaryPercent >= 100 {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(m.ID))
+	h.Write([]byte{0})
+	h.Write([]byte(tenantID))
+
+	return int(h.Sum32()%100) < m.CanaryPercent
+}
+
+// File returns the name the migration is recorded under in a tenant's _migrations
+// table. IDs start with "fleet_", which keeps them apart from the app migrations.
+func (m *FleetMigration) File() string {
+	return fmt.Sprintf("%s.%s", m.ID, m.Format)
+}
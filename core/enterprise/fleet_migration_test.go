@@ -0,0 +1,57 @@
+package enterprise
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFleetMigrationValidateDefaults(t *testing.T) {
+	migration := &FleetMigration{Name: "add field", Script: "migrate((app) => {})"}
+	if err := migration.Validate(); err != nil {
+		t.Fatalf("expected valid migration, got %v", err)
+	}
+
+	if migration.Format != FleetMigrationJS || migration.Mode != FleetMigrationLazy {
+		t.Errorf("expected js/lazy defaults, got %s/%s", migration.Format, migration.Mode)
+	}
+	if migration.CanaryPercent != 100 || migration.BatchSize != DefaultFleetMigrationBatchSize {
+		t.Errorf("expected full rollout with default batch, got %d%%/%d", migration.CanaryPercent, migration.BatchSize)
+	}
+
+	invalid := []*FleetMigration{
+		{Script: "migrate((app) => {})"},
+		{Name: "no script"},
+		{Name: "format", Script: "x", Format: "sql"},
+		{Name: "mode", Script: "x", Mode: "now"},
+		{Name: "canary", Script: "x", CanaryPercent: 101},
+	}
+	for _, m := range invalid {
+		if err := m.Validate(); err == nil {
+			t.Errorf("expected %q to be rejected", m.Name)
+		}
+	}
+}
+
+func TestFleetMigrationTargetsGrowWithCanary(t *testing.T) {
+	migration := &FleetMigration{ID: "fleet_test", CanaryPercent: 10}
+
+	targeted := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		tenantID := fmt.Sprintf("tenant-%d", i)
+		if migration.Targets(tenantID) {
+			targeted[tenantID] = true
+		}
+	}
+
+	if len(targeted) < 50 || len(targeted) > 150 {
+		t.Errorf("expected about 10%% of tenants targeted, got %d", len(targeted))
+	}
+
+	// Raising the canary keeps every tenant that was already targeted
+	migration.CanaryPercent = 50
+	for tenantID := range targeted {
+		if !migration.Targets(tenantID) {
+			t.Fatalf("expected %s to stay targeted at 50%%", tenantID)
+		}
+	}
+}
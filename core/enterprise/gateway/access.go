@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"encoding/json"
+	"hash/crc32"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// Rules of a tenant access policy, logged with the requests they block
+const (
+	accessRuleBlockedCIDR    = "blocked_cidr"
+	accessRuleBlockedCountry = "blocked_country"
+	accessRuleAdminAllowlist = "admin_allowlist"
+	accessRuleMaxBody        = "max_body"
+	accessRuleInvalidPolicy  = "invalid_policy"
+)
+
+// superusersCollectionID is the fixed id of the _superusers collection, which
+// PocketBase derives from its type and name and accepts in place of the name.
+var superusersCollectionID = "pbc_" + strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte("auth_superusers"))), 10)
+
+// superuserAPIPrefixes are the API routes that only superusers may call, and
+// the cluster SSO login that signs in as one.
+var superuserAPIPrefixes = []string{
+	"/api/enterprise/sso",
+	"/api/settings",
+	"/api/backups",
+	"/api/logs",
+	"/api/crons",
+	"/api/batch",
+}
+
+// isAdminPath reports whether a path belongs to the tenant's admin UI, its
+// collection management API, the superuser-only APIs or the superuser
+// collection, which the admin allowlist restricts. The record, auth and file
+// routes of other collections serve the app itself.
+func isAdminPath(urlPath string) bool {
+	urlPath = path.Clean("/" + urlPath)
+
+	if urlPath == "/_" || strings.HasPrefix(urlPath, "/_/") {
+		return true
+	}
+	if urlPath == "/api/collections" {
+		return true
+	}
+	for _, prefix := range superuserAPIPrefixes {
+		if urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/") {
+			return true
+		}
+	}
+
+	rest, ok := strings.CutPrefix(urlPath, "/api/collections/")
+	if !ok {
+		return false
+	}
+
+	// collections are found by id or case-insensitively by name
+	name, sub, _ := strings.Cut(rest, "/")
+	if strings.EqualFold(name, "_superusers") || name == superusersCollectionID || name == "meta" {
+		return true
+	}
+	return sub == "" || sub == "truncate" || strings.HasPrefix(sub, "impersonate/")
+}
+
+// resolveClient returns the client address of a request and its country, if
+// known. Forwarding headers are only trusted from the configured proxies.
+func (g *Gateway) resolveClient(r *http.Request) (netip.Addr, string) {
+	g.accessMu.RLock()
+	proxies, countryHeader := g.trustedProxies, g.countryHeader
+	g.accessMu.RUnlock()
+
+	client := proxies.ClientAddr(r)
+
+	var country string
+	if countryHeader != "" && proxies.Contains(enterprise.RemoteAddr(r)) {
+		country = strings.ToUpper(strings.TrimSpace(r.Header.Get(countryHeader)))
+	}
+
+	return client, country
+}
+
+// checkAccessPolicy returns the rule of the policy a request breaks and the
+// status to refuse it with, or an empty rule if the request is allowed
+func checkAccessPolicy(policy *enterprise.TenantAccessPolicy, r *http.Request, client netip.Addr, country string) (string, int) {
+	blocked, err := enterprise.ParsePrefixes(policy.BlockedCIDRs)
+	if err != nil {
+		return accessRuleInvalidPolicy, http.StatusForbidden
+	}
+	for _, prefix := range blocked {
+		if prefix.Contains(client) {
+			return accessRuleBlockedCIDR, http.StatusForbidden
+		}
+	}
+
+	// Requests without a country pass, the policy can't tell where they are from
+	if country != "" && slices.Contains(policy.BlockedCountries, country) {
+		return accessRuleBlockedCountry, http.StatusForbidden
+	}
+
+	if len(policy.AdminAllowlist) > 0 && isAdminPath(r.URL.Path) {
+		allowed, err := enterprise.ParsePrefixes(policy.AdminAllowlist)
+		if err != nil {
+			return accessRuleInvalidPolicy, http.StatusForbidden
+		}
+		if !slices.ContainsFunc(allowed, func(prefix netip.Prefix) bool { return prefix.Contains(client) }) {
+			return accessRuleAdminAllowlist, http.StatusForbidden
+		}
+	}
+
+	if policy.MaxBodyBytes > 0 && r.ContentLength > policy.MaxBodyBytes {
+		return accessRuleMaxBody, http.StatusRequestEntityTooLarge
+	}
+
+	return "", 0
+}
+
+// enforceAccessPolicy checks a request against the access policy of its
+// tenant. Blocked requests are logged with the tenant for the log store and
+// refused; allowed bodies are capped at the policy's limit as they are read.
+func (g *Gateway) enforceAccessPolicy(w http.ResponseWriter, r *http.Request, tenant *enterprise.Tenant, logger *slog.Logger) bool {
+	policy := tenant.AccessPolicy
+	client, country := g.resolveClient(r)
+
+	rule, statusCode := checkAccessPolicy(policy, r, client, country)
+	if rule == "" {
+		// Chunked bodies have no length to check up front
+		if policy.MaxBodyBytes > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, policy.MaxBodyBytes)
+		}
+		return true
+	}
+
+	blockRequest(w, r, tenant.ID, rule, statusCode, client, country, logger)
+	return false
+}
+
+// blockOversizedBody refuses a proxied request whose body went over the
+// tenant's limit while it was streamed to the node, like the check up front
+func (g *Gateway) blockOversizedBody(w http.ResponseWriter, r *http.Request) {
+	client, country := g.resolveClient(r)
+	logger := g.logger.With(enterprise.LogKeyRequestID, r.Header.Get(enterprise.RequestIDHeader))
+
+	blockRequest(w, r, r.Header.Get("X-Tenant-ID"), accessRuleMaxBody, http.StatusRequestEntityTooLarge, client, country, logger)
+}
+
+// blockRequest logs a request blocked by an access policy rule, with the
+// tenant for the log store, and refuses it
+func blockRequest(w http.ResponseWriter, r *http.Request, tenantID, rule string, statusCode int, client netip.Addr, country string, logger *slog.Logger) {
+	logger.Warn("Request blocked by access policy", enterprise.LogKeyTenantID, tenantID, "rule", rule,
+		"clientIp", client.String(), "country", country, "method", r.Method, "path", r.URL.Path)
+
+	message := "access denied by the tenant's access policy"
+	if rule == accessRuleMaxBody {
+		message = "request body too large"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": message,
+		"rule":  rule,
+	})
+}
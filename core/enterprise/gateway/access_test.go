@@ -0,0 +1,207 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func newTestAccessGateway(t *testing.T) *Gateway {
+	g, err := NewGateway(&enterprise.ClusterConfig{
+		Mode:                  enterprise.ModeGateway,
+		GatewayTrustedProxies: []string{"10.0.0.0/8"},
+		GatewayCountryHeader:  "CF-IPCountry",
+	}, newMockCPClient())
+	if err != nil {
+		t.Fatalf("failed to create gateway: %v", err)
+	}
+	return g
+}
+
+func TestIsAdminPath(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"/_/":                                                true,
+		"/_/#/collections":                                   true,
+		"/api/collections":                                   true,
+		"/api/collections/posts":                             true,
+		"/api/collections/import":                            true,
+		"/api/collections/posts/truncate":                    true,
+		"/api/collections/meta/scaffolds":                    true,
+		"/api/collections/_superusers/auth-with-password":    true,
+		"/api/collections/_SUPERUSERS/auth-with-password":    true,
+		"/api/collections/pbc_3142635823/auth-with-password": true,
+		"/api/collections/pbc_3142635823/records":            true,
+		"/api/collections/users/impersonate/abc":             true,
+		"/api/settings":                                      true,
+		"/api/settings/test/s3":                              true,
+		"/api/backups":                                       true,
+		"/api/backups/backup.zip/restore":                    true,
+		"/api/logs":                                          true,
+		"/api/logs/stats":                                    true,
+		"/api/crons":                                         true,
+		"/api/crons/__pbDBOptimize__":                        true,
+		"/api/batch":                                         true,
+		"/api/enterprise/sso":                                true,
+		"/api/settingsx":                                     false,
+		"/api/collections/posts/records":                     false,
+		"/api/collections/users/auth-with-password":          false,
+		"/api/collections/posts/../../collections":           true,
+		"/api/files/posts/abc/image.png":                     false,
+		"/":                                                  false,
+	} {
+		if isAdminPath(path) != expected {
+			t.Errorf("expected isAdminPath(%q) to be %v", path, expected)
+		}
+	}
+}
+
+func TestResolveClient(t *testing.T) {
+	g := newTestAccessGateway(t)
+
+	// headers of untrusted peers are ignored
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:4000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.Header.Set("CF-IPCountry", "us")
+
+	client, country := g.resolveClient(req)
+	if client.String() != "203.0.113.7" || country != "" {
+		t.Errorf("expected the peer without a country, got %s %q", client, country)
+	}
+
+	// behind the load balancers the first untrusted hop from the right is the client
+	req.RemoteAddr = "10.0.0.2:4000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.9, 10.0.0.5")
+
+	client, country = g.resolveClient(req)
+	if client.String() != "203.0.113.9" || country != "US" {
+		t.Errorf("expected 203.0.113.9 from US, got %s %q", client, country)
+	}
+}
+
+func TestApplyConfigReloadsClientResolution(t *testing.T) {
+	g := newTestAccessGateway(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.168.0.2:4000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	req.Header.Set("X-Country", "de")
+
+	g.ApplyConfig(&enterprise.ClusterConfig{
+		GatewayTrustedProxies: []string{"192.168.0.0/16"},
+		GatewayCountryHeader:  "X-Country",
+		GatewayCacheMemoryMB:  1,
+	})
+
+	client, country := g.resolveClient(req)
+	if client.String() != "203.0.113.9" || country != "DE" {
+		t.Errorf("expected the reloaded proxies and country header, got %s %q", client, country)
+	}
+	if maxBytes := g.responseCache.config.MaxMemoryBytes; maxBytes != 1024*1024 {
+		t.Errorf("expected the cache resized to 1MB, got %d", maxBytes)
+	}
+
+	// the old proxies aren't trusted anymore
+	req.RemoteAddr = "10.0.0.2:4000"
+	if client, _ := g.resolveClient(req); client.String() != "10.0.0.2" {
+		t.Errorf("expected the peer address, got %s", client)
+	}
+}
+
+func TestEnforceAccessPolicy(t *testing.T) {
+	g := newTestAccessGateway(t)
+	tenant := &enterprise.Tenant{ID: "tenant-1", AccessPolicy: &enterprise.TenantAccessPolicy{
+		AdminAllowlist:   []string{"192.0.2.0/24"},
+		BlockedCIDRs:     []string{"198.51.100.0/24"},
+		BlockedCountries: []string{"KP"},
+		MaxBodyBytes:     16,
+	}}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		client   string
+		country  string
+		body     string
+		expected int
+	}{
+		{"blocked cidr", http.MethodGet, "/api/collections/posts/records", "198.51.100.4", "", "", http.StatusForbidden},
+		{"blocked country", http.MethodGet, "/api/collections/posts/records", "203.0.113.9", "KP", "", http.StatusForbidden},
+		{"admin ui outside the allowlist", http.MethodGet, "/_/", "203.0.113.9", "", "", http.StatusForbidden},
+		{"admin ui in the allowlist", http.MethodGet, "/_/", "192.0.2.10", "", "", 0},
+		{"records outside the allowlist", http.MethodGet, "/api/collections/posts/records", "203.0.113.9", "", "", 0},
+		{"body too large", http.MethodPost, "/api/collections/posts/records", "203.0.113.9", "", strings.Repeat("x", 17), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.RemoteAddr = "10.0.0.2:4000"
+		req.Header.Set("X-Forwarded-For", tt.client)
+		if tt.country != "" {
+			req.Header.Set("CF-IPCountry", tt.country)
+		}
+
+		rec := httptest.NewRecorder()
+		allowed := g.enforceAccessPolicy(rec, req, tenant, slog.New(slog.DiscardHandler))
+
+		if tt.expected == 0 {
+			if !allowed {
+				t.Errorf("%s: expected the request to be allowed, got %d", tt.name, rec.Code)
+			}
+			continue
+		}
+		if allowed || rec.Code != tt.expected {
+			t.Errorf("%s: expected %d, got allowed=%v %d", tt.name, tt.expected, allowed, rec.Code)
+		}
+	}
+
+	// bodies without a length are cut off while they are read
+	req := httptest.NewRequest(http.MethodPost, "/api/collections/posts/records", strings.NewReader(strings.Repeat("x", 32)))
+	req.RemoteAddr = "203.0.113.9:4000"
+	req.ContentLength = -1
+
+	if !g.enforceAccessPolicy(httptest.NewRecorder(), req, tenant, slog.New(slog.DiscardHandler)) {
+		t.Fatal("expected the request to be allowed until its body is read")
+	}
+	if _, err := io.ReadAll(req.Body); err == nil {
+		t.Error("expected reading past the body limit to fail")
+	}
+}
+
+func TestProxyRefusesBodyOverLimitWhileStreaming(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer node.Close()
+
+	var logs strings.Builder
+	g := newTestAccessGateway(t)
+	g.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	// a chunked body only hits the limit once the node reads it
+	req := httptest.NewRequest(http.MethodPost, "/api/collections/posts/records", strings.NewReader(strings.Repeat("x", 64*1024)))
+	req.RemoteAddr = "203.0.113.9:4000"
+	req.ContentLength = -1
+	req.Header.Set("X-Tenant-ID", "tenant-1")
+
+	rec := httptest.NewRecorder()
+	req.Body = http.MaxBytesReader(rec, req.Body, 16)
+	g.getOrCreateProxy(node.URL).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), accessRuleMaxBody) {
+		t.Errorf("expected the max_body rule in the response, got %s", rec.Body.String())
+	}
+	if !strings.Contains(logs.String(), "rule="+accessRuleMaxBody) || !strings.Contains(logs.String(), "tenantId=tenant-1") {
+		t.Errorf("expected the block to be logged, got %s", logs.String())
+	}
+}
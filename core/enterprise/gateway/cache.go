@@ -0,0 +1,351 @@
+package gateway
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// responseCacheDirName is the subdirectory of the configured disk directory the
+// cache owns. Only it is wiped, the directory itself may hold other files.
+const responseCacheDirName = "pb-response-cache"
+
+// ResponseCacheConfig configures the gateway response cache
+type ResponseCacheConfig struct {
+	MaxMemoryBytes int64         // Size of the in-memory LRU
+	MaxDiskBytes   int64         // Size of the on-disk tier
+	DiskDir        string        // Directory of the on-disk tier (disabled if empty)
+	MaxEntryBytes  int64         // Larger responses are never cached
+	DefaultTTL     time.Duration // TTL for responses without max-age, unless the tenant sets one
+}
+
+// DefaultResponseCacheConfig returns the default response cache configuration
+func DefaultResponseCacheConfig() *ResponseCacheConfig {
+	return &ResponseCacheConfig{
+		MaxMemoryBytes: 256 * 1024 * 1024,  // 256MB
+		MaxDiskBytes:   1024 * 1024 * 1024, // 1GB
+		MaxEntryBytes:  1024 * 1024,        // 1MB
+		DefaultTTL:     60 * time.Second,
+	}
+}
+
+// cachedResponse is a stored upstream response. Entries are immutable once
+// stored, except for the body which is dropped from memory when spilled to disk.
+type cachedResponse struct {
+	key      string
+	tenantID string
+	nodeAddr string // Node that produced the response
+	status   int
+	header   http.Header
+	body     []byte
+	size     int64
+	storedAt time.Time
+	expires  time.Time
+
+	onDisk bool
+	elem   *list.Element
+}
+
+// ResponseCache is a two-tier (memory LRU + optional disk) cache of public tenant responses.
+// Entries are invalidated per tenant whenever the tenant's node publishes a record change.
+type ResponseCache struct {
+	config  *ResponseCacheConfig
+	diskDir string // Owned subdirectory of config.DiskDir (disk tier disabled if empty)
+
+	mu          sync.Mutex
+	entries     map[string]*cachedResponse
+	tenants     map[string]map[string]*cachedResponse // tenantID -> key -> entry
+	generations map[string]uint64                     // Bumped on every tenant invalidation
+	memory      *list.List                            // Most recently used first
+	disk        *list.List                            // Most recently spilled first
+	memoryBytes int64
+	diskBytes   int64
+
+	// Statistics
+	hits          int64
+	misses        int64
+	stores        int64
+	evictions     int64
+	invalidations int64
+
+	logger *slog.Logger
+}
+
+// NewResponseCache creates a new response cache. Entries left on disk by a
+// previous run may be stale and are wiped, along with nothing else in the
+// disk directory.
+func NewResponseCache(config *ResponseCacheConfig) (*ResponseCache, error) {
+	if config == nil {
+		config = DefaultResponseCacheConfig()
+	}
+
+	var diskDir string
+	if config.DiskDir != "" {
+		diskDir = filepath.Join(config.DiskDir, responseCacheDirName)
+		if err := os.RemoveAll(diskDir); err != nil {
+			return nil, fmt.Errorf("failed to clear cache directory: %w", err)
+		}
+		if err := os.MkdirAll(diskDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+
+	return &ResponseCache{
+		config:      config,
+		diskDir:     diskDir,
+		entries:     make(map[string]*cachedResponse),
+		tenants:     make(map[string]map[string]*cachedResponse),
+		generations: make(map[string]uint64),
+		memory:      list.New(),
+		disk:        list.New(),
+		logger:      enterprise.ComponentLogger(enterprise.LogComponentGateway),
+	}, nil
+}
+
+// Get returns a fresh cached response, loading it back into memory if it was spilled to disk
+func (c *ResponseCache) Get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists {
+		c.misses++
+		return nil
+	}
+
+	if time.Now().After(entry.expires) {
+		c.removeLocked(entry)
+		c.misses++
+		return nil
+	}
+
+	if entry.onDisk {
+		body, err := os.ReadFile(c.diskPath(key))
+		if err != nil {
+			c.logger.Error("Failed to read cached response from disk", "error", err)
+			c.removeLocked(entry)
+			c.misses++
+			return nil
+		}
+
+		c.disk.Remove(entry.elem)
+		c.diskBytes -= entry.size
+		os.Remove(c.diskPath(key))
+
+		entry.body = body
+		entry.onDisk = false
+		entry.elem = c.memory.PushFront(entry)
+		c.memoryBytes += entry.size
+		c.evictMemoryLocked()
+	} else {
+		c.memory.MoveToFront(entry.elem)
+	}
+
+	c.hits++
+
+	// Return a copy, the stored entry's body is dropped if it is spilled again
+	hit := *entry
+	return &hit
+}
+
+// Generation returns the tenant's invalidation generation. Pass it to Put so that
+// responses fetched before an invalidation aren't stored after it.
+func (c *ResponseCache) Generation(tenantID string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generations[tenantID]
+}
+
+// Put stores a response unless it is too large or the tenant was invalidated since generation
+func (c *ResponseCache) Put(entry *cachedResponse, generation uint64) bool {
+	entry.size = int64(len(entry.body))
+	if entry.size > c.config.MaxEntryBytes {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry.size > c.config.MaxMemoryBytes || c.generations[entry.tenantID] != generation {
+		return false
+	}
+
+	if existing, exists := c.entries[entry.key]; exists {
+		c.removeLocked(existing)
+	}
+
+	entry.onDisk = false
+	entry.elem = c.memory.PushFront(entry)
+	c.entries[entry.key] = entry
+	if c.tenants[entry.tenantID] == nil {
+		c.tenants[entry.tenantID] = make(map[string]*cachedResponse)
+	}
+	c.tenants[entry.tenantID][entry.key] = entry
+	c.memoryBytes += entry.size
+	c.stores++
+
+	c.evictMemoryLocked()
+	return true
+}
+
+// InvalidateTenant drops every cached response of a tenant
+func (c *ResponseCache) InvalidateTenant(tenantID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.invalidateTenantLocked(tenantID)
+}
+
+// InvalidateNode drops every cached response produced by a node, used when
+// the node's change feed can't be trusted (node restarted or unreachable)
+func (c *ResponseCache) InvalidateNode(nodeAddr string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	affected := make(map[string]bool)
+	for _, entry := range c.entries {
+		if entry.nodeAddr == nodeAddr {
+			affected[entry.tenantID] = true
+		}
+	}
+
+	removed := 0
+	for tenantID := range affected {
+		removed += c.invalidateTenantLocked(tenantID)
+	}
+	return removed
+}
+
+// Nodes returns the addresses of the nodes that produced the cached responses
+func (c *ResponseCache) Nodes() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[string]bool)
+	nodes := make([]string, 0)
+	for _, entry := range c.entries {
+		if !seen[entry.nodeAddr] {
+			seen[entry.nodeAddr] = true
+			nodes = append(nodes, entry.nodeAddr)
+		}
+	}
+	return nodes
+}
+
+// GetStats returns cache statistics
+func (c *ResponseCache) GetStats() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return map[string]interface{}{
+		"entries":       len(c.entries),
+		"memoryBytes":   c.memoryBytes,
+		"diskBytes":     c.diskBytes,
+		"hits":          c.hits,
+		"misses":        c.misses,
+		"stores":        c.stores,
+		"evictions":     c.evictions,
+		"invalidations": c.invalidations,
+	}
+}
+
+// invalidateTenantLocked removes a tenant's entries and bumps its generation
+func (c *ResponseCache) invalidateTenantLocked(tenantID string) int {
+	c.generations[tenantID]++
+
+	removed := 0
+	for _, entry := range c.tenants[tenantID] {
+		c.removeLocked(entry)
+		removed++
+	}
+
+	if removed > 0 {
+		c.invalidations++
+	}
+	return removed
+}
+
+// SetMaxMemoryBytes resizes the memory tier, spilling entries that no longer fit
+func (c *ResponseCache) SetMaxMemoryBytes(maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.config.MaxMemoryBytes = maxBytes
+	c.evictMemoryLocked()
+}
+
+// evictMemoryLocked spills least recently used entries to disk (or drops them)
+// until the memory tier fits its budget
+func (c *ResponseCache) evictMemoryLocked() {
+	for c.memoryBytes > c.config.MaxMemoryBytes {
+		entry := c.memory.Back().Value.(*cachedResponse)
+
+		if c.diskDir == "" || entry.size > c.config.MaxDiskBytes || time.Now().After(entry.expires) {
+			c.removeLocked(entry)
+			c.evictions++
+			continue
+		}
+
+		if err := os.WriteFile(c.diskPath(entry.key), entry.body, 0644); err != nil {
+			c.logger.Error("Failed to spill cached response to disk", "error", err)
+			c.removeLocked(entry)
+			c.evictions++
+			continue
+		}
+
+		c.memory.Remove(entry.elem)
+		c.memoryBytes -= entry.size
+
+		entry.body = nil
+		entry.onDisk = true
+		entry.elem = c.disk.PushFront(entry)
+		c.diskBytes += entry.size
+
+		c.evictDiskLocked()
+	}
+}
+
+// evictDiskLocked drops the oldest spilled entries until the disk tier fits its budget
+func (c *ResponseCache) evictDiskLocked() {
+	for c.diskBytes > c.config.MaxDiskBytes {
+		c.removeLocked(c.disk.Back().Value.(*cachedResponse))
+		c.evictions++
+	}
+}
+
+// removeLocked removes an entry from every index and tier
+func (c *ResponseCache) removeLocked(entry *cachedResponse) {
+	delete(c.entries, entry.key)
+
+	if tenantEntries := c.tenants[entry.tenantID]; tenantEntries != nil {
+		delete(tenantEntries, entry.key)
+		if len(tenantEntries) == 0 {
+			delete(c.tenants, entry.tenantID)
+		}
+	}
+
+	if entry.onDisk {
+		c.disk.Remove(entry.elem)
+		c.diskBytes -= entry.size
+		os.Remove(c.diskPath(entry.key))
+	} else {
+		c.memory.Remove(entry.elem)
+		c.memoryBytes -= entry.size
+	}
+}
+
+// diskPath returns the file holding a spilled entry's body
+func (c *ResponseCache) diskPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.diskDir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,345 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func newTestEntry(tenantID, key, body string) *cachedResponse {
+	return &cachedResponse{
+		key:      key,
+		tenantID: tenantID,
+		nodeAddr: "http://node-1:8091",
+		status:   http.StatusOK,
+		header:   http.Header{},
+		body:     []byte(body),
+		storedAt: time.Now(),
+		expires:  time.Now().Add(time.Minute),
+	}
+}
+
+func TestResponseCacheGetPut(t *testing.T) {
+	cache, err := NewResponseCache(nil)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	if cache.Get("missing") != nil {
+		t.Error("expected miss for unknown key")
+	}
+
+	if !cache.Put(newTestEntry("tenant-1", "a", "hello"), cache.Generation("tenant-1")) {
+		t.Fatal("expected entry to be stored")
+	}
+
+	entry := cache.Get("a")
+	if entry == nil || string(entry.body) != "hello" {
+		t.Fatalf("expected cached body, got %v", entry)
+	}
+
+	expired := newTestEntry("tenant-1", "b", "stale")
+	expired.expires = time.Now().Add(-time.Second)
+	cache.Put(expired, cache.Generation("tenant-1"))
+	if cache.Get("b") != nil {
+		t.Error("expected expired entry to miss")
+	}
+}
+
+func TestResponseCacheRejectsStaleGeneration(t *testing.T) {
+	cache, _ := NewResponseCache(nil)
+
+	generation := cache.Generation("tenant-1")
+	cache.InvalidateTenant("tenant-1")
+
+	if cache.Put(newTestEntry("tenant-1", "a", "fetched before invalidation"), generation) {
+		t.Error("expected response fetched before invalidation to be rejected")
+	}
+}
+
+func TestResponseCacheInvalidateTenant(t *testing.T) {
+	cache, _ := NewResponseCache(nil)
+
+	cache.Put(newTestEntry("tenant-1", "a", "1"), 0)
+	cache.Put(newTestEntry("tenant-1", "b", "2"), 0)
+	cache.Put(newTestEntry("tenant-2", "c", "3"), 0)
+
+	if removed := cache.InvalidateTenant("tenant-1"); removed != 2 {
+		t.Errorf("expected 2 entries removed, got %d", removed)
+	}
+
+	if cache.Get("a") != nil || cache.Get("b") != nil {
+		t.Error("expected tenant-1 entries to be invalidated")
+	}
+	if cache.Get("c") == nil {
+		t.Error("expected tenant-2 entry to stay cached")
+	}
+}
+
+func TestResponseCacheMemoryEviction(t *testing.T) {
+	config := DefaultResponseCacheConfig()
+	config.MaxMemoryBytes = 10
+	cache, _ := NewResponseCache(config)
+
+	cache.Put(newTestEntry("tenant-1", "a", "12345"), 0)
+	cache.Put(newTestEntry("tenant-1", "b", "12345"), 0)
+	cache.Get("a") // a becomes most recently used
+	cache.Put(newTestEntry("tenant-1", "c", "12345"), 0)
+
+	if cache.Get("b") != nil {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if cache.Get("a") == nil || cache.Get("c") == nil {
+		t.Error("expected recently used entries to stay cached")
+	}
+}
+
+func TestResponseCacheDiskTier(t *testing.T) {
+	config := DefaultResponseCacheConfig()
+	config.MaxMemoryBytes = 10
+	config.MaxDiskBytes = 10
+	config.DiskDir = t.TempDir()
+
+	// files of others in the directory survive the wipe of the previous run's entries
+	unrelated := filepath.Join(config.DiskDir, "keep.db")
+	if err := os.WriteFile(unrelated, []byte("data"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	cache, err := NewResponseCache(config)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Fatalf("expected files outside the cache to be kept: %v", err)
+	}
+
+	cache.Put(newTestEntry("tenant-1", "a", "aaaaa"), 0)
+	cache.Put(newTestEntry("tenant-1", "b", "bbbbb"), 0)
+	cache.Put(newTestEntry("tenant-1", "c", "ccccc"), 0)
+
+	stats := cache.GetStats()
+	if stats["diskBytes"].(int64) != 5 {
+		t.Fatalf("expected one entry spilled to disk, got %v", stats)
+	}
+
+	entry := cache.Get("a")
+	if entry == nil || string(entry.body) != "aaaaa" {
+		t.Fatalf("expected spilled entry to be loaded from disk, got %v", entry)
+	}
+
+	cache.InvalidateTenant("tenant-1")
+	stats = cache.GetStats()
+	if stats["memoryBytes"].(int64) != 0 || stats["diskBytes"].(int64) != 0 {
+		t.Errorf("expected both tiers to be empty after invalidation, got %v", stats)
+	}
+}
+
+func TestIsCacheableRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{"public GET", http.MethodGet, nil, true},
+		{"POST", http.MethodPost, nil, false},
+		{"authenticated", http.MethodGet, map[string]string{"Authorization": "Bearer token"}, false},
+		{"auth cookie", http.MethodGet, map[string]string{"Cookie": "pb_auth=token"}, false},
+		{"no-store", http.MethodGet, map[string]string{"Cache-Control": "no-store"}, false},
+		{"range", http.MethodGet, map[string]string{"Range": "bytes=0-10"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/collections/posts/records", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := isCacheableRequest(r); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResponseTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		wantTTL time.Duration
+		wantOK  bool
+	}{
+		{"default TTL", http.StatusOK, nil, time.Minute, true},
+		{"max-age", http.StatusOK, map[string]string{"Cache-Control": "public, max-age=300"}, 5 * time.Minute, true},
+		{"s-maxage wins", http.StatusOK, map[string]string{"Cache-Control": "max-age=300, s-maxage=10"}, 10 * time.Second, true},
+		{"max-age=0", http.StatusOK, map[string]string{"Cache-Control": "max-age=0"}, 0, false},
+		{"private", http.StatusOK, map[string]string{"Cache-Control": "private, max-age=300"}, 0, false},
+		{"no-store", http.StatusOK, map[string]string{"Cache-Control": "no-store"}, 0, false},
+		{"set-cookie", http.StatusOK, map[string]string{"Set-Cookie": "a=b"}, 0, false},
+		{"vary origin", http.StatusOK, map[string]string{"Vary": "Origin"}, time.Minute, true},
+		{"vary authorization", http.StatusOK, map[string]string{"Vary": "Origin, Authorization"}, 0, false},
+		{"not found", http.StatusNotFound, nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+			ttl, ok := responseTTL(tt.status, header, time.Minute)
+			if ok != tt.wantOK || (ok && ttl != tt.wantTTL) {
+				t.Errorf("expected (%v, %v), got (%v, %v)", tt.wantTTL, tt.wantOK, ttl, ok)
+			}
+		})
+	}
+}
+
+// newCachingTestGateway creates a gateway routing a cache-enabled tenant to a fake node
+func newCachingTestGateway(t *testing.T, node http.Handler) (*Gateway, *httptest.Server) {
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+
+	cpClient := newMockCPClient()
+	cpClient.tenants["tenant-1"] = &enterprise.Tenant{
+		ID:                   "tenant-1",
+		Domain:               "t1.example.com",
+		Status:               enterprise.TenantStatusActive,
+		ResponseCacheEnabled: true,
+	}
+
+	g := newTestGateway(t, cpClient)
+	g.cacheNodeAddress("tenant-1", server.URL)
+
+	return g, server
+}
+
+func doGatewayRequest(g *Gateway, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "http://t1.example.com"+path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	g.handleRequest(rec, req)
+	return rec
+}
+
+func TestGatewayServesCachedResponses(t *testing.T) {
+	var upstreamRequests int64
+	var changes atomic.Value
+	changes.Store(&enterprise.RecordChangeFeed{NodeID: "node-1", Changes: []*enterprise.RecordChange{}})
+
+	g, _ := newCachingTestGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_changes" {
+			json.NewEncoder(w).Encode(changes.Load())
+			return
+		}
+		atomic.AddInt64(&upstreamRequests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":[]}`))
+	}))
+
+	first := doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", nil)
+	if first.Header().Get(cacheStatusHeader) != "MISS" {
+		t.Fatalf("expected first request to miss, got %q", first.Header().Get(cacheStatusHeader))
+	}
+
+	second := doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", nil)
+	if second.Header().Get(cacheStatusHeader) != "HIT" || second.Body.String() != `{"items":[]}` {
+		t.Fatalf("expected cached response, got %q %q", second.Header().Get(cacheStatusHeader), second.Body.String())
+	}
+	if atomic.LoadInt64(&upstreamRequests) != 1 {
+		t.Errorf("expected 1 upstream request, got %d", upstreamRequests)
+	}
+
+	// Conditional request against the generated ETag
+	etag := second.Header().Get("ETag")
+	notModified := doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", map[string]string{"If-None-Match": etag})
+	if notModified.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for matching ETag, got %d", notModified.Code)
+	}
+
+	// Authenticated requests bypass the cache
+	doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", map[string]string{"Authorization": "token"})
+	if atomic.LoadInt64(&upstreamRequests) != 2 {
+		t.Errorf("expected authenticated request to reach the node, got %d upstream requests", upstreamRequests)
+	}
+
+	// First poll only establishes the feed position (and drops entries it can't vouch for)
+	g.pollRecordChanges(context.Background())
+	doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", nil)
+
+	// A record change published by the node invalidates the tenant
+	changes.Store(&enterprise.RecordChangeFeed{
+		NodeID:  "node-1",
+		Seq:     1,
+		Changes: []*enterprise.RecordChange{{Seq: 1, TenantID: "tenant-1", Collection: "posts"}},
+	})
+	g.pollRecordChanges(context.Background())
+
+	before := atomic.LoadInt64(&upstreamRequests)
+	after := doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", nil)
+	if after.Header().Get(cacheStatusHeader) != "MISS" || atomic.LoadInt64(&upstreamRequests) != before+1 {
+		t.Error("expected record change to invalidate the cached response")
+	}
+
+	// A restarted node starts its sequence over, the new epoch tells it apart
+	changes.Store(&enterprise.RecordChangeFeed{NodeID: "node-1", Epoch: "epoch_restarted", Seq: 1, Changes: []*enterprise.RecordChange{}})
+	g.pollRecordChanges(context.Background())
+
+	restarted := doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", nil)
+	if restarted.Header().Get(cacheStatusHeader) != "MISS" {
+		t.Error("expected a node restart to invalidate its cached responses")
+	}
+}
+
+func TestGatewayWriteInvalidatesCache(t *testing.T) {
+	g, _ := newCachingTestGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+
+	doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", nil)
+	doGatewayRequest(g, http.MethodPost, "/api/collections/posts/records", nil)
+
+	rec := doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", nil)
+	if rec.Header().Get(cacheStatusHeader) != "MISS" {
+		t.Error("expected write through the gateway to invalidate the tenant")
+	}
+}
+
+func TestGatewayDoesNotCacheOptedOutTenants(t *testing.T) {
+	g, _ := newCachingTestGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+
+	tenant, _ := g.cpClient.GetTenantMetadata(context.Background(), "tenant-1")
+	tenant.ResponseCacheEnabled = false
+
+	doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", nil)
+	rec := doGatewayRequest(g, http.MethodGet, "/api/collections/posts/records", nil)
+	if rec.Header().Get(cacheStatusHeader) != "" {
+		t.Errorf("expected no cache involvement, got %q", rec.Header().Get(cacheStatusHeader))
+	}
+}
+
+func TestGatewayBlocksNodeInternalPaths(t *testing.T) {
+	g, _ := newCachingTestGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"changes":[]}`))
+	}))
+
+	rec := doGatewayRequest(g, http.MethodGet, "/_changes", nil)
+	if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "changes") {
+		t.Errorf("expected node change feed to be hidden, got %d", rec.Code)
+	}
+}
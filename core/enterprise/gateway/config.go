@@ -0,0 +1,25 @@
+package gateway
+
+import (
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// ApplyConfig applies the reloadable settings of a reloaded config
+func (g *Gateway) ApplyConfig(next *enterprise.ClusterConfig) {
+	// The reloaded config was validated, so the proxies parse
+	proxies, err := enterprise.ParseTrustedProxies(next.GatewayTrustedProxies)
+	if err != nil {
+		g.logger.Error("Invalid trusted proxies, keeping the current ones", "error", err)
+	} else {
+		g.accessMu.Lock()
+		g.trustedProxies = proxies
+		g.countryHeader = next.GatewayCountryHeader
+		g.accessMu.Unlock()
+	}
+
+	if next.GatewayCacheMemoryMB > 0 {
+		g.responseCache.SetMaxMemoryBytes(int64(next.GatewayCacheMemoryMB) * 1024 * 1024)
+	}
+
+	g.logger.Info("Applied reloaded config", "trustedProxies", len(proxies), "cacheMemoryMb", next.GatewayCacheMemoryMB)
+}
@@ -2,18 +2,38 @@ package gateway
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"html/template"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/auth"
 	"github.com/pocketbase/pocketbase/core/enterprise/health"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
+// proxyFlushInterval is how often buffered proxy responses are flushed to clients
+const proxyFlushInterval = 100 * time.Millisecond
+
+// nodeInternalPaths are served by tenant nodes for the cluster itself
+var nodeInternalPaths = map[string]bool{
+	"/_health":     true,
+	"/_metrics":    true,
+	"/_prometheus": true,
+	"/_changes":    true,
+	"/_status":     true,
+}
+
 // Gateway handles incoming requests and routes them to the appropriate tenant nodes
 type Gateway struct {
 	config   *enterprise.ClusterConfig
@@ -27,8 +47,30 @@ type Gateway struct {
 	nodeCache   map[string]string
 	nodeCacheMu sync.RWMutex
 
+	// Tenant cache: domain -> tenant lookup (see lookupTenant)
+	tenantCache   map[string]*cachedTenant
+	tenantCacheMu sync.RWMutex
+	tenantLookups singleflight.Group
+
 	// Quota enforcement
 	quotaEnforcer *QuotaEnforcer
+	server        *http.Server // Serves tenant traffic
+	peerServer    *http.Server // Receives quota usage from other gateway replicas
+
+	// Served to browsers for suspended tenants
+	suspendedPage *template.Template
+
+	// Proxies whose forwarding headers name the client (see resolveClient),
+	// and the client country header they set. Both are reloadable.
+	trustedProxies enterprise.TrustedProxies
+	countryHeader  string
+	accessMu       sync.RWMutex
+
+	// Response cache for tenants that opted in, invalidated from node change feeds
+	responseCache    *ResponseCache
+	changeCursors    map[string]changeFeedCursor // node address -> position
+	changeCursorsMu  sync.Mutex
+	changeFeedClient *http.Client
 
 	// Health and monitoring
 	healthChecker *health.Checker
@@ -36,7 +78,7 @@ type Gateway struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	logger *log.Logger
+	logger *slog.Logger
 }
 
 // NewGateway creates a new gateway instance
@@ -53,33 +95,80 @@ func NewGateway(config *enterprise.ClusterConfig, cpClient enterprise.ControlPla
 
 	// Initialize quota enforcer
 	quotaEnforcer := NewQuotaEnforcer(cpClient)
+	quotaEnforcer.maxStaleness = config.Reads.MaxStalenessDuration()
+	if config.GatewayPeerAddr != "" {
+		quotaEnforcer.EnableSharding(&enterprise.GatewayInfo{
+			ID:      config.GatewayPeerAddr,
+			Address: config.GatewayPeerAddr,
+		}, config.GatewayPeerSecret)
+	}
+
+	// Initialize response cache
+	cacheConfig := DefaultResponseCacheConfig()
+	if config.GatewayCacheMemoryMB > 0 {
+		cacheConfig.MaxMemoryBytes = int64(config.GatewayCacheMemoryMB) * 1024 * 1024
+	}
+	cacheConfig.DiskDir = config.GatewayCacheDir
+
+	responseCache, err := NewResponseCache(cacheConfig)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create response cache: %w", err)
+	}
+
+	suspendedPage, err := loadSuspendedPageTemplate(config.Suspension.PageTemplate)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	trustedProxies, err := enterprise.ParseTrustedProxies(config.GatewayTrustedProxies)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
 	return &Gateway{
-		config:        config,
-		cpClient:      cpClient,
-		proxyCache:    make(map[string]*httputil.ReverseProxy),
-		nodeCache:     make(map[string]string),
-		quotaEnforcer: quotaEnforcer,
-		healthChecker: healthChecker,
-		ctx:           ctx,
-		cancel:        cancel,
-		logger:        log.Default(),
+		config:           config,
+		cpClient:         cpClient,
+		proxyCache:       make(map[string]*httputil.ReverseProxy),
+		nodeCache:        make(map[string]string),
+		tenantCache:      make(map[string]*cachedTenant),
+		quotaEnforcer:    quotaEnforcer,
+		responseCache:    responseCache,
+		suspendedPage:    suspendedPage,
+		trustedProxies:   trustedProxies,
+		countryHeader:    config.GatewayCountryHeader,
+		changeCursors:    make(map[string]changeFeedCursor),
+		changeFeedClient: &http.Client{Timeout: 5 * time.Second},
+		healthChecker:    healthChecker,
+		ctx:              ctx,
+		cancel:           cancel,
+		logger:           enterprise.ComponentLogger(enterprise.LogComponentGateway),
 	}, nil
 }
 
 // Start starts the gateway HTTP server
 func (g *Gateway) Start(addr string) error {
-	g.logger.Printf("[Gateway] Starting gateway on %s", addr)
+	g.logger.Info("Starting gateway", "addr", addr)
 
 	// Start quota enforcer
 	g.quotaEnforcer.Start()
 
+	if err := g.startPeerServer(); err != nil {
+		return err
+	}
+
+	// Watch tenant nodes for record changes to invalidate cached responses
+	go g.watchRecordChanges()
+
 	// Register health checks
 	g.healthChecker.Register("control_plane", func(ctx context.Context) error {
 		if g.cpClient == nil {
 			return fmt.Errorf("control plane client not initialized")
 		}
-		// Test connectivity
+		// Test connectivity, any control plane node in touch with the leader will do
+		ctx = enterprise.WithStaleReads(ctx, g.config.Reads.MaxStalenessDuration())
 		_, err := g.cpClient.GetTenantByDomain(ctx, "health-check-test.example.com")
 		if err != nil && err.Error() != "tenant not found" {
 			return fmt.Errorf("control plane unreachable: %w", err)
@@ -105,18 +194,38 @@ func (g *Gateway) Start(addr string) error {
 		return nil
 	})
 
-	// Register main request handler (quota is checked inside after tenant resolution)
-	http.Handle("/", http.HandlerFunc(g.handleRequest))
-	http.HandleFunc("/health/live", health.LivenessHandler())
-	http.HandleFunc("/health/ready", health.ReadinessHandler(g.healthChecker))
-	http.HandleFunc("/_health", g.healthChecker.HTTPHandler())
+	g.healthChecker.Register("response_cache", func(ctx context.Context) error {
+		g.healthChecker.SetMetadata("responseCacheStats", g.responseCache.GetStats())
+		return nil
+	})
 
-	return http.ListenAndServe(addr, nil)
+	// Register main request handler (quota is checked inside after tenant resolution)
+	mux := http.NewServeMux()
+	mux.Handle("/", http.HandlerFunc(g.handleRequest))
+	mux.HandleFunc("/health/live", health.LivenessHandler())
+	mux.HandleFunc("/health/ready", health.ReadinessHandler(g.healthChecker))
+	mux.HandleFunc("/_health", g.healthChecker.HTTPHandler())
+
+	g.server = &http.Server{Addr: addr, Handler: mux}
+	return g.server.ListenAndServe()
 }
 
 // Stop gracefully stops the gateway
 func (g *Gateway) Stop() error {
-	g.logger.Printf("[Gateway] Stopping gateway...")
+	g.logger.Info("Stopping gateway")
+
+	if g.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		g.server.Shutdown(ctx)
+		cancel()
+	}
+
+	// Stop accepting usage from peers, then hand off the counts of this replica
+	if g.peerServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		g.peerServer.Shutdown(ctx)
+		cancel()
+	}
 
 	// Stop quota enforcer
 	if g.quotaEnforcer != nil {
@@ -127,12 +236,55 @@ func (g *Gateway) Stop() error {
 	return nil
 }
 
+// startPeerServer serves the quota sync endpoint for other gateway replicas. It
+// listens on the peer address only, separately from tenant traffic, and peers
+// authenticate with the shared secret so tenants cannot report usage.
+func (g *Gateway) startPeerServer() error {
+	if g.config.GatewayPeerAddr == "" {
+		g.logger.Warn("No peer address configured, quotas are enforced per gateway replica")
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(g.config.GatewayPeerAddr); err != nil {
+		return fmt.Errorf("invalid gateway peer address %q: %w", g.config.GatewayPeerAddr, err)
+	}
+	if g.config.GatewayPeerSecret == "" {
+		return fmt.Errorf("gateway peer secret required with peer address %q", g.config.GatewayPeerAddr)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(QuotaSyncPath, g.quotaEnforcer.SyncHandler())
+
+	// Listen before returning so that an address of another host fails the start
+	listener, err := net.Listen("tcp", g.config.GatewayPeerAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on gateway peer address: %w", err)
+	}
+
+	g.peerServer = &http.Server{
+		Addr:    g.config.GatewayPeerAddr,
+		Handler: mux,
+	}
+
+	go func() {
+		if err := g.peerServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			g.logger.Error("Peer server error", "error", err)
+		}
+	}()
+
+	g.logger.Info("Sharing quota state with other gateways", "addr", g.config.GatewayPeerAddr)
+	return nil
+}
+
 // handleRequest handles incoming HTTP requests and routes them
 func (g *Gateway) handleRequest(w http.ResponseWriter, r *http.Request) {
 	// Extract tenant ID from domain
-	host := r.Host
-	if strings.Contains(host, ":") {
-		host = strings.Split(host, ":")[0]
+	host := tenantDomain(r.Host)
+
+	// Node-internal endpoints must not be reachable through tenant domains
+	if nodeInternalPaths[r.URL.Path] {
+		http.NotFound(w, r)
+		return
 	}
 
 	tenantID := enterprise.ExtractTenantIDFromDomain(host)
@@ -141,25 +293,55 @@ func (g *Gateway) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get tenant metadata from control plane
-	tenant, err := g.cpClient.GetTenantByDomain(r.Context(), host)
+	// Continue the client's trace, if any; the tenant node picks it up from the proxied headers
+	ctx := enterprise.ExtractHTTPTraceContext(r.Context(), r.Header)
+	ctx, span := enterprise.Tracer().Start(ctx, "gateway "+r.Method, trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(enterprise.AttrTenantID.String(tenantID), attribute.String("http.route", r.URL.Path)))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	// Tag the request so its logs can be followed from the gateway to the tenant node
+	r = auth.WithRequestID(w, r)
+	requestID := auth.GetRequestID(r.Context())
+	r.Header.Set(enterprise.RequestIDHeader, requestID)
+	logger := g.logger.With(enterprise.LogKeyRequestID, requestID)
+
+	// Get tenant metadata from the lookup cache or the control plane
+	tenant, err := g.lookupTenant(r.Context(), host)
 	if err != nil {
 		if err == enterprise.ErrTenantNotFound {
 			http.Error(w, "Tenant not found", http.StatusNotFound)
 		} else {
-			g.logger.Printf("[Gateway] Failed to get tenant: %v", err)
+			logger.Error("Failed to get tenant", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	// Suspended tenants keep their data but aren't served until the suspension is lifted
+	if tenant.Suspended() {
+		g.handleSuspendedTenant(w, r, tenant)
+		return
+	}
+
+	// Tenants can restrict who reaches them, checked before anything is restored, cached or proxied
+	if !tenant.AccessPolicy.IsEmpty() && !g.enforceAccessPolicy(w, r, tenant, logger) {
+		return
+	}
+
+	// Archived tenants can't be served until their data is restored from Glacier
+	if tenant.Status == enterprise.TenantStatusArchived {
+		g.handleArchivedTenant(w, r, tenant)
+		return
+	}
+
 	// Check quota after tenant is resolved
 	requestSize := r.ContentLength
 	if requestSize < 0 {
 		requestSize = 0
 	}
 	if err := g.quotaEnforcer.CheckQuota(tenant.ID, requestSize); err != nil {
-		g.logger.Printf("[Gateway] Quota exceeded for tenant %s: %v", tenant.ID, err)
+		logger.Warn("Quota exceeded for tenant", "tenantId", tenant.ID, "error", err)
 		statusCode := http.StatusTooManyRequests
 		if quotaErr, ok := err.(*enterprise.QuotaError); ok {
 			if quotaErr.Resource == "storage" {
@@ -173,13 +355,29 @@ func (g *Gateway) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Serve public reads from the response cache if the tenant opted in
+	cacheable := tenant.ResponseCacheEnabled && isCacheableRequest(r)
+	if cacheable && g.serveFromCache(w, r, tenant) {
+		return
+	}
+
+	// Writes through this gateway invalidate right away, without waiting for the change feed
+	if tenant.ResponseCacheEnabled && r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		defer g.responseCache.InvalidateTenant(tenant.ID)
+	}
+
 	// Check if tenant has assigned node
 	nodeAddr := g.getNodeAddress(tenant.ID)
 	if nodeAddr == "" {
 		// No assignment yet, request placement decision
-		decision, err := g.cpClient.GetPlacementDecision(r.Context(), tenant.ID)
+		placementCtx, placementSpan := enterprise.StartSpan(r.Context(), "placement.lookup", enterprise.AttrTenantID.String(tenant.ID))
+		decision, err := g.cpClient.GetPlacementDecision(placementCtx, tenant.ID)
+		if err == nil {
+			placementSpan.SetAttributes(enterprise.AttrNodeID.String(decision.NodeID))
+		}
+		enterprise.EndSpan(placementSpan, err)
 		if err != nil {
-			g.logger.Printf("[Gateway] Failed to get placement decision: %v", err)
+			logger.Error("Failed to get placement decision", "error", err)
 			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
 			return
 		}
@@ -187,7 +385,7 @@ func (g *Gateway) handleRequest(w http.ResponseWriter, r *http.Request) {
 		// Use the node address from the placement decision
 		nodeAddr = decision.NodeAddress
 		if nodeAddr == "" {
-			g.logger.Printf("[Gateway] Placement decision missing node address for tenant %s", tenant.ID)
+			logger.Warn("Placement decision missing node address for tenant", "tenantId", tenant.ID)
 			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
 			return
 		}
@@ -197,18 +395,36 @@ func (g *Gateway) handleRequest(w http.ResponseWriter, r *http.Request) {
 			nodeAddr = "http://" + nodeAddr
 		}
 
-		g.logger.Printf("[Gateway] Routing tenant %s to node %s at %s", tenant.ID, decision.NodeID, nodeAddr)
+		logger.Info("Routing tenant to node", "tenantId", tenant.ID, "nodeId", decision.NodeID, "nodeAddr", nodeAddr)
 		g.cacheNodeAddress(tenant.ID, nodeAddr)
 	}
 
 	// Get or create reverse proxy for this node
 	proxy := g.getOrCreateProxy(nodeAddr)
 
+	// Realtime streams hold a connection slot for as long as they stay open
+	if enterprise.IsRealtimeRequest(r) {
+		if err := g.quotaEnforcer.AcquireRealtimeConnection(tenant.ID); err != nil {
+			logger.Warn("Realtime connection limit reached for tenant", "tenantId", tenant.ID, "error", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(w, `{"error": "%s"}`, err.Error())
+			return
+		}
+		defer g.quotaEnforcer.ReleaseRealtimeConnection(tenant.ID)
+	}
+
 	// Set tenant context in request
 	r.Header.Set("X-Tenant-ID", tenant.ID)
 	r.Header.Set("X-Tenant-Domain", tenant.Domain)
+	enterprise.InjectHTTPTraceContext(r.Context(), r.Header)
 
 	// Proxy the request
+	if cacheable {
+		g.proxyAndCache(w, r, tenant, nodeAddr, proxy)
+		return
+	}
 	proxy.ServeHTTP(w, r)
 }
 
@@ -234,9 +450,26 @@ func (g *Gateway) getOrCreateProxy(nodeAddr string) *httputil.ReverseProxy {
 	target, _ := url.Parse(nodeAddr)
 	proxy = httputil.NewSingleHostReverseProxy(target)
 
+	// Flush periodically so partial responses aren't held back; SSE
+	// (text/event-stream) responses are always flushed immediately and
+	// WebSocket upgrades are tunneled by the proxy
+	proxy.FlushInterval = proxyFlushInterval
+
 	// Customize proxy behavior
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		g.logger.Printf("[Gateway] Proxy error: %v", err)
+		// Client went away (e.g. closed a realtime stream) - the node is fine
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		// A chunked body went over the tenant's limit while it was streamed
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			g.blockOversizedBody(w, r)
+			return
+		}
+
+		g.logger.Error("Proxy error", "error", err)
 
 		// Invalidate cache on error
 		tenantID := r.Header.Get("X-Tenant-ID")
@@ -247,6 +480,22 @@ func (g *Gateway) getOrCreateProxy(nodeAddr string) *httputil.ReverseProxy {
 		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
 	}
 
+	// A draining node refuses tenants it doesn't hold anymore; look the
+	// tenant up again so the retry goes to its new node
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		if resp.Header.Get("X-Node-Draining") != "" {
+			if tenantID := resp.Request.Header.Get("X-Tenant-ID"); tenantID != "" {
+				g.invalidateNodeCache(tenantID)
+			}
+		}
+
+		// The node knows better than the cached lookup, e.g. of a suspension
+		if resp.Header.Get(enterprise.TenantRefusedHeader) != "" {
+			g.evictTenant(tenantDomain(resp.Request.Host))
+		}
+		return nil
+	}
+
 	g.proxyCache[nodeAddr] = proxy
 	return proxy
 }
@@ -3,7 +3,7 @@ package gateway
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 	"sync/atomic"
@@ -14,7 +14,8 @@ import (
 
 // QuotaEnforcer enforces tenant quotas and rate limits at the gateway
 type QuotaEnforcer struct {
-	cpClient enterprise.ControlPlaneClient
+	cpClient     enterprise.ControlPlaneClient
+	maxStaleness time.Duration // Of the tenant metadata reads (default if zero)
 
 	// In-memory quota tracking (synced with control plane)
 	quotas   map[string]*TenantQuotaState
@@ -24,15 +25,23 @@ type QuotaEnforcer struct {
 	rateLimiters   map[string]*RateLimiter
 	rateLimitersMu sync.RWMutex
 
+	// Tenant ownership across gateway replicas. Standalone gateways own every tenant.
+	ring       *QuotaRing
+	sharded    bool
+	peerClient *http.Client
+	peerSecret string // Shared secret of the quota sync endpoint
+
 	// Metrics
-	rejectedRequests int64
-	rejectedStorage  int64
+	rejectedRequests    int64
+	rejectedStorage     int64
+	rejectedRealtime    int64
+	realtimeConnections int64 // Currently open across all tenants
 
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 
-	logger *log.Logger
+	logger *slog.Logger
 }
 
 // TenantQuotaState tracks current quota usage for a tenant
@@ -49,7 +58,17 @@ type TenantQuotaState struct {
 	RequestsLast1h  int64
 	LastRequestTime time.Time
 
-	// Reset tracking
+	// Requests counted here but not yet reported to the owning gateway
+	UnsyncedRequests int64
+
+	// RequestsToday as of the last checkpoint to the control plane
+	CheckpointedRequests int64
+
+	// Open SSE/WebSocket connections proxied for this tenant
+	RealtimeConnections    int64
+	MaxRealtimeConnections int64
+
+	// Reset tracking (UTC)
 	DayStart time.Time
 
 	// Sync
@@ -57,6 +76,9 @@ type TenantQuotaState struct {
 	Mu       sync.RWMutex
 }
 
+// DefaultMaxRealtimeConnections is the per-tenant limit on concurrent realtime connections
+const DefaultMaxRealtimeConnections = 500
+
 // RateLimiter implements token bucket rate limiting
 type RateLimiter struct {
 	tokens         float64
@@ -74,26 +96,44 @@ func NewQuotaEnforcer(cpClient enterprise.ControlPlaneClient) *QuotaEnforcer {
 		cpClient:       cpClient,
 		quotas:         make(map[string]*TenantQuotaState),
 		rateLimiters:   make(map[string]*RateLimiter),
+		ring:           NewQuotaRing(&enterprise.GatewayInfo{ID: "local"}),
+		peerClient:     &http.Client{Timeout: 5 * time.Second},
 		ctx:            ctx,
 		cancel:         cancel,
-		logger:         log.Default(),
+		logger:         enterprise.ComponentLogger(enterprise.LogComponentQuota),
 	}
 }
 
 // Start begins background quota syncing
 func (qe *QuotaEnforcer) Start() {
-	qe.logger.Printf("[QuotaEnforcer] Starting quota enforcement")
+	qe.logger.Info("Starting quota enforcement")
 
-	qe.wg.Add(2)
+	qe.wg.Add(3)
 	go qe.syncQuotasLoop()
 	go qe.resetDailyCountersLoop()
+	go qe.checkpointLoop()
+
+	if qe.sharded {
+		qe.wg.Add(2)
+		go qe.membershipLoop()
+		go qe.syncUsageLoop()
+	}
 }
 
 // Stop stops the quota enforcer
 func (qe *QuotaEnforcer) Stop() {
-	qe.logger.Printf("[QuotaEnforcer] Stopping quota enforcer")
+	qe.logger.Info("Stopping quota enforcer")
 	qe.cancel()
 	qe.wg.Wait()
+
+	// Hand off the counts of this gateway before it leaves the ring
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if qe.sharded {
+		qe.syncUsage(ctx)
+	}
+	qe.checkpointUsage(ctx)
 }
 
 // CheckQuota checks if a request is allowed based on quotas
@@ -127,6 +167,9 @@ func (qe *QuotaEnforcer) CheckQuota(tenantID string, requestSizeBytes int64) err
 
 	// Update counters
 	state.RequestsToday++
+	if !qe.ring.IsOwner(tenantID) {
+		state.UnsyncedRequests++
+	}
 	state.RequestsLast1h++
 	state.LastRequestTime = time.Now()
 
@@ -144,6 +187,37 @@ func (qe *QuotaEnforcer) RecordRequest(tenantID string, responseSizeBytes int64)
 	// We just track request counts here
 }
 
+// AcquireRealtimeConnection reserves a realtime connection slot for a tenant.
+// Every successful call must be paired with ReleaseRealtimeConnection.
+func (qe *QuotaEnforcer) AcquireRealtimeConnection(tenantID string) error {
+	state := qe.getOrCreateQuotaState(tenantID)
+
+	state.Mu.Lock()
+	defer state.Mu.Unlock()
+
+	if state.MaxRealtimeConnections > 0 && state.RealtimeConnections >= state.MaxRealtimeConnections {
+		atomic.AddInt64(&qe.rejectedRealtime, 1)
+		return enterprise.NewQuotaError("realtime_connections", state.RealtimeConnections, state.MaxRealtimeConnections)
+	}
+
+	state.RealtimeConnections++
+	atomic.AddInt64(&qe.realtimeConnections, 1)
+	return nil
+}
+
+// ReleaseRealtimeConnection frees a slot reserved by AcquireRealtimeConnection
+func (qe *QuotaEnforcer) ReleaseRealtimeConnection(tenantID string) {
+	state := qe.getOrCreateQuotaState(tenantID)
+
+	state.Mu.Lock()
+	defer state.Mu.Unlock()
+
+	if state.RealtimeConnections > 0 {
+		state.RealtimeConnections--
+		atomic.AddInt64(&qe.realtimeConnections, -1)
+	}
+}
+
 // getOrCreateQuotaState retrieves or creates quota state for a tenant
 func (qe *QuotaEnforcer) getOrCreateQuotaState(tenantID string) *TenantQuotaState {
 	qe.quotasMu.RLock()
@@ -163,13 +237,13 @@ func (qe *QuotaEnforcer) getOrCreateQuotaState(tenantID string) *TenantQuotaStat
 		return state
 	}
 
-	now := time.Now()
 	state = &TenantQuotaState{
-		TenantID:         tenantID,
-		StorageQuotaMB:   1024,   // Default 1 GB
-		APIRequestsQuota: 100000, // Default 100k/day
-		DayStart:         time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
-		LastSync:         time.Time{}, // Force immediate sync
+		TenantID:               tenantID,
+		StorageQuotaMB:         1024,   // Default 1 GB
+		APIRequestsQuota:       100000, // Default 100k/day
+		MaxRealtimeConnections: DefaultMaxRealtimeConnections,
+		DayStart:               currentDayStart(),
+		LastSync:               time.Time{}, // Force immediate sync
 	}
 
 	qe.quotas[tenantID] = state
@@ -187,12 +261,19 @@ func (qe *QuotaEnforcer) checkRateLimit(tenantID string) bool {
 	limiter.mu.Lock()
 	defer limiter.mu.Unlock()
 
+	// Each gateway replica enforces its share of the tenant's rate limit
+	share := 1.0 / float64(qe.ring.Size())
+	maxTokens := limiter.maxTokens * share
+	if maxTokens < 1.0 {
+		maxTokens = 1.0
+	}
+
 	// Refill tokens based on time elapsed
 	now := time.Now()
 	elapsed := now.Sub(limiter.lastRefillTime).Seconds()
-	limiter.tokens += elapsed * limiter.refillRate
-	if limiter.tokens > limiter.maxTokens {
-		limiter.tokens = limiter.maxTokens
+	limiter.tokens += elapsed * limiter.refillRate * share
+	if limiter.tokens > maxTokens {
+		limiter.tokens = maxTokens
 	}
 	limiter.lastRefillTime = now
 
@@ -272,13 +353,19 @@ func (qe *QuotaEnforcer) syncTenantQuota(tenantID string) {
 	ctx, cancel := context.WithTimeout(qe.ctx, 5*time.Second)
 	defer cancel()
 
-	// Get tenant metadata from control plane
-	tenant, err := qe.cpClient.GetTenantMetadata(ctx, tenantID)
+	// Get tenant metadata from control plane, quotas don't need the leader's latest
+	tenant, err := qe.cpClient.GetTenantMetadata(enterprise.WithStaleReads(ctx, qe.maxStaleness), tenantID)
 	if err != nil {
-		qe.logger.Printf("[QuotaEnforcer] Failed to sync quota for tenant %s: %v", tenantID, err)
+		qe.logger.Error("Failed to sync quota for tenant", "tenantId", tenantID, "error", err)
 		return
 	}
 
+	// Restore today's count persisted by a previous owner of the tenant
+	checkpoint, err := qe.cpClient.GetUsageCheckpoint(ctx, tenantID)
+	if err != nil && err != enterprise.ErrUsageCheckpointNotFound {
+		qe.logger.Error("Failed to load usage checkpoint for tenant", "tenantId", tenantID, "error", err)
+	}
+
 	state := qe.getOrCreateQuotaState(tenantID)
 
 	state.Mu.Lock()
@@ -289,18 +376,18 @@ func (qe *QuotaEnforcer) syncTenantQuota(tenantID string) {
 	state.APIRequestsQuota = tenant.APIRequestsQuota
 	state.StorageUsedMB = tenant.StorageUsedMB
 	state.LastSync = time.Now()
+
+	if checkpoint != nil && checkpoint.Day == dayKey(state.DayStart) && checkpoint.APIRequests > state.RequestsToday {
+		state.RequestsToday = checkpoint.APIRequests
+	}
 }
 
 // resetDailyCountersLoop resets daily counters at midnight
 func (qe *QuotaEnforcer) resetDailyCountersLoop() {
 	defer qe.wg.Done()
 
-	// Calculate time until next midnight
-	now := time.Now()
-	nextMidnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
-	duration := nextMidnight.Sub(now)
-
-	timer := time.NewTimer(duration)
+	// Calculate time until next midnight (UTC, shared by all gateways)
+	timer := time.NewTimer(time.Until(currentDayStart().AddDate(0, 0, 1)))
 	defer timer.Stop()
 
 	for {
@@ -311,31 +398,30 @@ func (qe *QuotaEnforcer) resetDailyCountersLoop() {
 			qe.resetDailyCounters()
 
 			// Schedule next reset for tomorrow
-			now := time.Now()
-			nextMidnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
-			timer.Reset(nextMidnight.Sub(now))
+			timer.Reset(time.Until(currentDayStart().AddDate(0, 0, 1)))
 		}
 	}
 }
 
 // resetDailyCounters resets daily request counters for all tenants
 func (qe *QuotaEnforcer) resetDailyCounters() {
-	qe.logger.Printf("[QuotaEnforcer] Resetting daily request counters")
+	qe.logger.Info("Resetting daily request counters")
 
 	qe.quotasMu.RLock()
 	defer qe.quotasMu.RUnlock()
 
-	now := time.Now()
-	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	dayStart := currentDayStart()
 
 	for _, state := range qe.quotas {
 		state.Mu.Lock()
 		state.RequestsToday = 0
+		state.UnsyncedRequests = 0
+		state.CheckpointedRequests = 0
 		state.DayStart = dayStart
 		state.Mu.Unlock()
 	}
 
-	qe.logger.Printf("[QuotaEnforcer] Daily counters reset for %d tenants", len(qe.quotas))
+	qe.logger.Info("Daily counters reset", "tenants", len(qe.quotas))
 }
 
 // GetStats returns quota enforcement statistics
@@ -345,9 +431,12 @@ func (qe *QuotaEnforcer) GetStats() map[string]interface{} {
 	qe.quotasMu.RUnlock()
 
 	return map[string]interface{}{
-		"trackedTenants":    trackedTenants,
-		"rejectedRequests":  atomic.LoadInt64(&qe.rejectedRequests),
-		"rejectedStorage":   atomic.LoadInt64(&qe.rejectedStorage),
+		"trackedTenants":      trackedTenants,
+		"rejectedRequests":    atomic.LoadInt64(&qe.rejectedRequests),
+		"rejectedStorage":     atomic.LoadInt64(&qe.rejectedStorage),
+		"rejectedRealtime":    atomic.LoadInt64(&qe.rejectedRealtime),
+		"realtimeConnections": atomic.LoadInt64(&qe.realtimeConnections),
+		"quotaRingSize":       qe.ring.Size(),
 	}
 }
 
@@ -363,7 +452,7 @@ func (qe *QuotaEnforcer) CleanupTenant(tenantID string) {
 	delete(qe.rateLimiters, tenantID)
 	qe.rateLimitersMu.Unlock()
 
-	qe.logger.Printf("[QuotaEnforcer] Cleaned up quota data for tenant: %s", tenantID)
+	qe.logger.Info("Cleaned up quota data for tenant", "tenantId", tenantID)
 }
 
 // QuotaMiddleware returns HTTP middleware for quota enforcement
@@ -385,7 +474,7 @@ func (qe *QuotaEnforcer) QuotaMiddleware(next http.Handler) http.Handler {
 
 		// Check quota
 		if err := qe.CheckQuota(tenantID, requestSize); err != nil {
-			qe.logger.Printf("[QuotaEnforcer] Quota check failed for tenant %s: %v", tenantID, err)
+			qe.logger.Warn("Quota check failed for tenant", "tenantId", tenantID, "error", err)
 
 			// Return 429 Too Many Requests or 507 Insufficient Storage
 			statusCode := http.StatusTooManyRequests
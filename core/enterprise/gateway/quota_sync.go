@@ -0,0 +1,407 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+const (
+	// quotaSyncInterval is how often usage deltas are sent to the owning gateways
+	quotaSyncInterval = 2 * time.Second
+
+	// quotaCheckpointInterval is how often owned daily counters are persisted to the control plane
+	quotaCheckpointInterval = 30 * time.Second
+
+	// gatewayHeartbeatInterval is how often the gateway refreshes quota ring membership
+	gatewayHeartbeatInterval = 10 * time.Second
+
+	// QuotaSyncPath is the peer endpoint receiving usage deltas
+	QuotaSyncPath = "/_quota/sync"
+
+	// maxQuotaSyncDelta caps the requests a peer can report for one tenant per
+	// sync. The per-tenant rate limit (bursts of 100, 10/s sustained) lets a
+	// gateway serve about 120 requests in a sync interval; the rest of the cap
+	// covers deltas kept over a few failed syncs.
+	maxQuotaSyncDelta = 1000
+
+	// maxQuotaSyncBodyBytes bounds the body of a sync request
+	maxQuotaSyncBodyBytes = 4 << 20
+)
+
+// QuotaRing assigns each tenant to one live gateway (rendezvous hashing), which is
+// authoritative for the tenant's daily request count. Membership changes only move
+// the tenants of the gateways that joined or left.
+type QuotaRing struct {
+	self *enterprise.GatewayInfo
+
+	mu      sync.RWMutex
+	members []*enterprise.GatewayInfo
+}
+
+// NewQuotaRing creates a ring containing only this gateway
+func NewQuotaRing(self *enterprise.GatewayInfo) *QuotaRing {
+	return &QuotaRing{
+		self:    self,
+		members: []*enterprise.GatewayInfo{self},
+	}
+}
+
+// SetMembers replaces the ring members. This gateway is always a member.
+func (r *QuotaRing) SetMembers(members []*enterprise.GatewayInfo) {
+	list := make([]*enterprise.GatewayInfo, 0, len(members)+1)
+	list = append(list, r.self)
+	for _, member := range members {
+		if member.ID != r.self.ID {
+			list = append(list, member)
+		}
+	}
+
+	r.mu.Lock()
+	r.members = list
+	r.mu.Unlock()
+}
+
+// Owner returns the gateway that owns a tenant's quota
+func (r *QuotaRing) Owner(tenantID string) *enterprise.GatewayInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var owner *enterprise.GatewayInfo
+	var best uint64
+	for _, member := range r.members {
+		h := fnv.New64a()
+		h.Write([]byte(member.ID))
+		h.Write([]byte{0})
+		h.Write([]byte(tenantID))
+		if score := mixHash(h.Sum64()); owner == nil || score > best {
+			owner = member
+			best = score
+		}
+	}
+	return owner
+}
+
+// mixHash spreads FNV hashes of similar inputs (splitmix64 finalizer), without it
+// tenant IDs that differ in the last characters cluster on the same gateway
+func mixHash(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// IsOwner reports whether this gateway owns a tenant's quota
+func (r *QuotaRing) IsOwner(tenantID string) bool {
+	return r.Owner(tenantID).ID == r.self.ID
+}
+
+// Size returns the number of live gateways
+func (r *QuotaRing) Size() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.members)
+}
+
+// quotaSyncRequest carries the requests a gateway counted for tenants owned by the receiver
+type quotaSyncRequest struct {
+	Day    string           `json:"day"`
+	Deltas map[string]int64 `json:"deltas"` // tenantID -> requests since the last sync
+}
+
+// quotaSyncResponse returns the owner's cluster-wide counts for the synced tenants
+type quotaSyncResponse struct {
+	Day    string           `json:"day"`
+	Totals map[string]int64 `json:"totals"`
+}
+
+// dayKey returns the UTC day a daily counter belongs to. UTC keeps gateways in
+// different time zones in agreement about when counters reset.
+func dayKey(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// currentDayStart returns the start of the current UTC day
+func currentDayStart() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// EnableSharding makes this enforcer one of several gateway replicas sharing
+// quota state. self.Address must be reachable by the other gateways, which
+// authenticate with the shared secret.
+func (qe *QuotaEnforcer) EnableSharding(self *enterprise.GatewayInfo, secret string) {
+	qe.ring = NewQuotaRing(self)
+	qe.sharded = true
+	qe.peerSecret = secret
+}
+
+// authorizedPeer reports whether a sync request carries the shared peer secret
+func (qe *QuotaEnforcer) authorizedPeer(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && qe.peerSecret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(qe.peerSecret)) == 1
+}
+
+// SyncHandler returns the peer endpoint that receives usage deltas for owned tenants
+func (qe *QuotaEnforcer) SyncHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !qe.authorizedPeer(r) {
+			qe.logger.Warn("Rejected quota sync without the peer secret", "remoteAddr", r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req quotaSyncRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQuotaSyncBodyBytes)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		today := dayKey(time.Now())
+		resp := quotaSyncResponse{
+			Day:    today,
+			Totals: make(map[string]int64, len(req.Deltas)),
+		}
+
+		for tenantID, delta := range req.Deltas {
+			if delta > maxQuotaSyncDelta {
+				qe.logger.Warn("Capped implausible quota sync delta", "tenantId", tenantID, "delta", delta, "remoteAddr", r.RemoteAddr)
+				delta = maxQuotaSyncDelta
+			}
+
+			state := qe.getOrCreateQuotaState(tenantID)
+
+			state.Mu.Lock()
+			// Deltas from before midnight belong to a counter that was already reset
+			if req.Day == today && delta > 0 {
+				state.RequestsToday += delta
+			}
+			resp.Totals[tenantID] = state.RequestsToday
+			state.Mu.Unlock()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// membershipLoop keeps the quota ring in sync with the live gateways
+func (qe *QuotaEnforcer) membershipLoop() {
+	defer qe.wg.Done()
+
+	qe.refreshMembership()
+
+	ticker := time.NewTicker(gatewayHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-qe.ctx.Done():
+			return
+		case <-ticker.C:
+			qe.refreshMembership()
+		}
+	}
+}
+
+// refreshMembership heartbeats this gateway and updates the ring with the live gateways
+func (qe *QuotaEnforcer) refreshMembership() {
+	ctx, cancel := context.WithTimeout(qe.ctx, 5*time.Second)
+	defer cancel()
+
+	members, err := qe.cpClient.RegisterGateway(ctx, qe.ring.self)
+	if err != nil {
+		// Keep the previous membership, quotas stay enforced with the last known shares
+		qe.logger.Error("Failed to register gateway", "error", err)
+		return
+	}
+
+	before := qe.ring.Size()
+	qe.ring.SetMembers(members)
+	if after := qe.ring.Size(); after != before {
+		qe.logger.Info("Quota ring changed", "gatewaysBefore", before, "gatewaysAfter", after)
+	}
+}
+
+// syncUsageLoop periodically sends usage deltas to the owning gateways
+func (qe *QuotaEnforcer) syncUsageLoop() {
+	defer qe.wg.Done()
+
+	ticker := time.NewTicker(quotaSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-qe.ctx.Done():
+			return
+		case <-ticker.C:
+			qe.syncUsage(qe.ctx)
+		}
+	}
+}
+
+// syncUsage reports the requests counted for tenants owned by other gateways and
+// adopts the owners' cluster-wide totals. Tenants are included even without new
+// requests so that this gateway learns about requests served by its peers.
+func (qe *QuotaEnforcer) syncUsage(ctx context.Context) {
+	day := dayKey(time.Now())
+	owners := make(map[string]*enterprise.GatewayInfo)
+	batches := make(map[string]map[string]int64) // owner ID -> tenantID -> delta
+
+	qe.quotasMu.RLock()
+	for tenantID, state := range qe.quotas {
+		owner := qe.ring.Owner(tenantID)
+
+		state.Mu.Lock()
+		if owner.ID == qe.ring.self.ID {
+			// Owned tenants already count every request in RequestsToday
+			state.UnsyncedRequests = 0
+		} else {
+			if batches[owner.ID] == nil {
+				owners[owner.ID] = owner
+				batches[owner.ID] = make(map[string]int64)
+			}
+			batches[owner.ID][tenantID] = state.UnsyncedRequests
+		}
+		state.Mu.Unlock()
+	}
+	qe.quotasMu.RUnlock()
+
+	for ownerID, deltas := range batches {
+		resp, err := qe.sendUsageDeltas(ctx, owners[ownerID], day, deltas)
+		if err != nil {
+			// Deltas are kept and retried on the next sync
+			qe.logger.Error("Failed to sync usage with gateway", "gatewayId", ownerID, "error", err)
+			continue
+		}
+
+		for tenantID, sent := range deltas {
+			state := qe.getOrCreateQuotaState(tenantID)
+
+			state.Mu.Lock()
+			state.UnsyncedRequests -= sent
+			if state.UnsyncedRequests < 0 {
+				state.UnsyncedRequests = 0
+			}
+			if total, ok := resp.Totals[tenantID]; ok && resp.Day == dayKey(state.DayStart) {
+				// Requests counted while the sync was in flight are still unsynced
+				state.RequestsToday = total + state.UnsyncedRequests
+			}
+			state.Mu.Unlock()
+		}
+	}
+}
+
+// sendUsageDeltas posts usage deltas to a peer gateway
+func (qe *QuotaEnforcer) sendUsageDeltas(ctx context.Context, owner *enterprise.GatewayInfo, day string, deltas map[string]int64) (*quotaSyncResponse, error) {
+	body, err := json.Marshal(quotaSyncRequest{Day: day, Deltas: deltas})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+owner.Address+QuotaSyncPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+qe.peerSecret)
+
+	resp, err := qe.peerClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var syncResp quotaSyncResponse
+	if err := json.NewDecoder(resp.Body).Decode(&syncResp); err != nil {
+		return nil, fmt.Errorf("failed to decode sync response: %w", err)
+	}
+
+	return &syncResp, nil
+}
+
+// checkpointLoop periodically persists owned daily counters to the control plane
+func (qe *QuotaEnforcer) checkpointLoop() {
+	defer qe.wg.Done()
+
+	ticker := time.NewTicker(quotaCheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-qe.ctx.Done():
+			return
+		case <-ticker.C:
+			qe.checkpointUsage(qe.ctx)
+		}
+	}
+}
+
+// checkpointUsage persists the daily counters of owned tenants that changed since the last checkpoint
+func (qe *QuotaEnforcer) checkpointUsage(ctx context.Context) {
+	checkpoints := make([]*enterprise.UsageCheckpoint, 0)
+
+	qe.quotasMu.RLock()
+	for tenantID, state := range qe.quotas {
+		if !qe.ring.IsOwner(tenantID) {
+			continue
+		}
+
+		state.Mu.RLock()
+		if state.RequestsToday != state.CheckpointedRequests {
+			checkpoints = append(checkpoints, &enterprise.UsageCheckpoint{
+				TenantID:    tenantID,
+				Day:         dayKey(state.DayStart),
+				APIRequests: state.RequestsToday,
+			})
+		}
+		state.Mu.RUnlock()
+	}
+	qe.quotasMu.RUnlock()
+
+	if len(checkpoints) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := qe.cpClient.CheckpointUsage(ctx, checkpoints); err != nil {
+		qe.logger.Error("Failed to checkpoint tenant usage", "tenants", len(checkpoints), "error", err)
+		return
+	}
+
+	for _, checkpoint := range checkpoints {
+		state := qe.getOrCreateQuotaState(checkpoint.TenantID)
+
+		state.Mu.Lock()
+		if dayKey(state.DayStart) == checkpoint.Day {
+			state.CheckpointedRequests = checkpoint.APIRequests
+		}
+		state.Mu.Unlock()
+	}
+}
@@ -2,21 +2,33 @@ package gateway
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pocketbase/pocketbase/core/enterprise"
 )
 
 // mockControlPlaneClient implements enterprise.ControlPlaneClient for testing
 type mockControlPlaneClient struct {
-	tenants map[string]*enterprise.Tenant
+	tenants     map[string]*enterprise.Tenant
+	restoreJobs map[string]*enterprise.RestoreJob
+
+	mu       sync.Mutex
+	gateways []*enterprise.GatewayInfo
+	usage    map[string]*enterprise.UsageCheckpoint
 }
 
 func newMockCPClient() *mockControlPlaneClient {
 	return &mockControlPlaneClient{
-		tenants: make(map[string]*enterprise.Tenant),
+		tenants:     make(map[string]*enterprise.Tenant),
+		restoreJobs: make(map[string]*enterprise.RestoreJob),
+		usage:       make(map[string]*enterprise.UsageCheckpoint),
 	}
 }
 
@@ -29,6 +41,11 @@ func (m *mockControlPlaneClient) GetTenantMetadata(ctx context.Context, tenantID
 }
 
 func (m *mockControlPlaneClient) GetTenantByDomain(ctx context.Context, domain string) (*enterprise.Tenant, error) {
+	for _, t := range m.tenants {
+		if t.Domain == domain {
+			return t, nil
+		}
+	}
 	return nil, enterprise.ErrTenantNotFound
 }
 
@@ -40,14 +57,97 @@ func (m *mockControlPlaneClient) RegisterNode(ctx context.Context, nodeInfo *ent
 	return nil
 }
 
-func (m *mockControlPlaneClient) SendHeartbeat(ctx context.Context, nodeID string, activeTenantsCount int) error {
+func (m *mockControlPlaneClient) SendHeartbeat(ctx context.Context, nodeID string, activeTenantsCount int, leases []*enterprise.TenantLease) (string, []*enterprise.TenantLease, error) {
+	return enterprise.NodeStatusOnline, leases, nil
+}
+
+func (m *mockControlPlaneClient) AcquireTenantLease(ctx context.Context, tenantID, nodeID string) (*enterprise.TenantLease, error) {
+	return &enterprise.TenantLease{TenantID: tenantID, NodeID: nodeID, Epoch: 1}, nil
+}
+
+func (m *mockControlPlaneClient) ReleaseTenantLease(ctx context.Context, lease *enterprise.TenantLease) error {
+	return nil
+}
+
+func (m *mockControlPlaneClient) UseSSOToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
 	return nil
 }
 
+func (m *mockControlPlaneClient) DrainNode(ctx context.Context, nodeID string, loadedTenants []string) error {
+	return nil
+}
+
+func (m *mockControlPlaneClient) ReleaseTenant(ctx context.Context, tenantID, nodeID string) error {
+	return nil
+}
+
+func (m *mockControlPlaneClient) GetTenantHandoffs(ctx context.Context, nodeID string) ([]string, error) {
+	return nil, nil
+}
+
 func (m *mockControlPlaneClient) GetPlacementDecision(ctx context.Context, tenantID string) (*enterprise.PlacementDecision, error) {
 	return nil, nil
 }
 
+func (m *mockControlPlaneClient) RequestTenantRestore(ctx context.Context, tenantID string, expedited bool) (*enterprise.RestoreJob, error) {
+	job, exists := m.restoreJobs[tenantID]
+	if !exists {
+		return nil, enterprise.ErrTenantNotFound
+	}
+	return job, nil
+}
+
+func (m *mockControlPlaneClient) RegisterGateway(ctx context.Context, gateway *enterprise.GatewayInfo) ([]*enterprise.GatewayInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.gateways) == 0 {
+		return []*enterprise.GatewayInfo{gateway}, nil
+	}
+	return m.gateways, nil
+}
+
+func (m *mockControlPlaneClient) AppendLogs(ctx context.Context, entries []*enterprise.LogEntry) error {
+	return nil
+}
+
+func (m *mockControlPlaneClient) CheckpointUsage(ctx context.Context, checkpoints []*enterprise.UsageCheckpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, checkpoint := range checkpoints {
+		m.usage[checkpoint.TenantID] = checkpoint
+	}
+	return nil
+}
+
+func (m *mockControlPlaneClient) GetUsageCheckpoint(ctx context.Context, tenantID string) (*enterprise.UsageCheckpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	checkpoint, exists := m.usage[tenantID]
+	if !exists {
+		return nil, enterprise.ErrUsageCheckpointNotFound
+	}
+	return checkpoint, nil
+}
+
+func (m *mockControlPlaneClient) GetFleetMigrations(ctx context.Context, tenantIDs []string) (map[string][]*enterprise.FleetMigration, error) {
+	return nil, nil
+}
+
+func (m *mockControlPlaneClient) GetFleetBatch(ctx context.Context, nodeID string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockControlPlaneClient) ReportFleetMigration(ctx context.Context, result *enterprise.FleetMigrationTenant) error {
+	return nil
+}
+
+func (m *mockControlPlaneClient) GetTenantKeys(ctx context.Context, tenantID, nodeID string) (*enterprise.TenantDataKeys, error) {
+	return nil, enterprise.ErrTenantKeysNotFound
+}
+
 func (m *mockControlPlaneClient) addTenant(id string, storageQuota, apiQuota int64) {
 	m.tenants[id] = &enterprise.Tenant{
 		ID:               id,
@@ -238,3 +338,249 @@ func TestQuotaEnforcerUnknownTenant(t *testing.T) {
 	// May or may not error depending on implementation
 	_ = err // Just verify no panic
 }
+
+func TestQuotaEnforcerRealtimeConnectionLimit(t *testing.T) {
+	cpClient := newMockCPClient()
+	cpClient.addTenant("tenant-1", 100, 1000)
+
+	enforcer := NewQuotaEnforcer(cpClient)
+
+	state := enforcer.getOrCreateQuotaState("tenant-1")
+	state.Mu.Lock()
+	state.MaxRealtimeConnections = 2
+	state.Mu.Unlock()
+
+	for i := 0; i < 2; i++ {
+		if err := enforcer.AcquireRealtimeConnection("tenant-1"); err != nil {
+			t.Fatalf("connection %d should be allowed: %v", i, err)
+		}
+	}
+
+	err := enforcer.AcquireRealtimeConnection("tenant-1")
+	quotaErr, ok := err.(*enterprise.QuotaError)
+	if !ok || quotaErr.Resource != "realtime_connections" {
+		t.Fatalf("expected realtime_connections quota error, got %v", err)
+	}
+
+	// Releasing a connection frees a slot
+	enforcer.ReleaseRealtimeConnection("tenant-1")
+	if err := enforcer.AcquireRealtimeConnection("tenant-1"); err != nil {
+		t.Errorf("connection should be allowed after release: %v", err)
+	}
+
+	if open := enforcer.GetStats()["realtimeConnections"]; open != int64(2) {
+		t.Errorf("expected 2 open realtime connections, got %v", open)
+	}
+}
+
+func TestQuotaRingOwnership(t *testing.T) {
+	gateways := []*enterprise.GatewayInfo{
+		{ID: "gw-1", Address: "gw-1:8091"},
+		{ID: "gw-2", Address: "gw-2:8091"},
+		{ID: "gw-3", Address: "gw-3:8091"},
+	}
+
+	rings := make([]*QuotaRing, len(gateways))
+	for i, gateway := range gateways {
+		rings[i] = NewQuotaRing(gateway)
+		rings[i].SetMembers(gateways)
+	}
+
+	owned := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		tenantID := fmt.Sprintf("tenant-%d", i)
+
+		owner := rings[0].Owner(tenantID)
+		owners := 0
+		for _, ring := range rings {
+			if ring.Owner(tenantID).ID != owner.ID {
+				t.Fatalf("gateways disagree about the owner of %s", tenantID)
+			}
+			if ring.IsOwner(tenantID) {
+				owners++
+			}
+		}
+		if owners != 1 {
+			t.Fatalf("expected exactly one owner for %s, got %d", tenantID, owners)
+		}
+		owned[owner.ID]++
+	}
+
+	for _, gateway := range gateways {
+		if owned[gateway.ID] < 50 {
+			t.Errorf("expected tenants spread across gateways, %s owns %d", gateway.ID, owned[gateway.ID])
+		}
+	}
+
+	// A departing gateway only moves its own tenants
+	before := make(map[string]string)
+	for i := 0; i < 300; i++ {
+		tenantID := fmt.Sprintf("tenant-%d", i)
+		before[tenantID] = rings[0].Owner(tenantID).ID
+	}
+	rings[0].SetMembers(gateways[:2])
+	for tenantID, ownerID := range before {
+		if ownerID != "gw-3" && rings[0].Owner(tenantID).ID != ownerID {
+			t.Errorf("tenant %s moved from live gateway %s", tenantID, ownerID)
+		}
+	}
+}
+
+func TestQuotaEnforcerSyncUsage(t *testing.T) {
+	cpClient := newMockCPClient()
+	cpClient.addTenant("tenant-1", 100, 1000)
+
+	ownerEnforcer := NewQuotaEnforcer(cpClient)
+	ownerServer := httptest.NewServer(ownerEnforcer.SyncHandler())
+	defer ownerServer.Close()
+
+	owner := &enterprise.GatewayInfo{ID: "owner", Address: strings.TrimPrefix(ownerServer.URL, "http://")}
+	peer := &enterprise.GatewayInfo{ID: "peer", Address: "127.0.0.1:0"}
+
+	ownerEnforcer.EnableSharding(owner, "peer-secret")
+	ownerEnforcer.ring.SetMembers([]*enterprise.GatewayInfo{peer})
+
+	peerEnforcer := NewQuotaEnforcer(cpClient)
+	peerEnforcer.EnableSharding(peer, "peer-secret")
+	peerEnforcer.ring.SetMembers([]*enterprise.GatewayInfo{owner})
+
+	// Find a tenant the owner gateway is responsible for
+	tenantID := ""
+	for i := 0; tenantID == ""; i++ {
+		if candidate := fmt.Sprintf("tenant-%d", i); ownerEnforcer.ring.IsOwner(candidate) {
+			tenantID = candidate
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := ownerEnforcer.CheckQuota(tenantID, 0); err != nil {
+			t.Fatalf("owner request %d should be allowed: %v", i, err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if err := peerEnforcer.CheckQuota(tenantID, 0); err != nil {
+			t.Fatalf("peer request %d should be allowed: %v", i, err)
+		}
+	}
+
+	peerEnforcer.syncUsage(context.Background())
+
+	ownerState := ownerEnforcer.getOrCreateQuotaState(tenantID)
+	ownerState.Mu.RLock()
+	ownerCount := ownerState.RequestsToday
+	ownerState.Mu.RUnlock()
+	if ownerCount != 8 {
+		t.Errorf("expected owner to count 8 requests, got %d", ownerCount)
+	}
+
+	peerState := peerEnforcer.getOrCreateQuotaState(tenantID)
+	peerState.Mu.RLock()
+	peerCount, unsynced := peerState.RequestsToday, peerState.UnsyncedRequests
+	peerState.Mu.RUnlock()
+	if peerCount != 8 || unsynced != 0 {
+		t.Errorf("expected peer to adopt total 8 with no unsynced requests, got %d (unsynced %d)", peerCount, unsynced)
+	}
+
+	// Syncing again without new requests must not double count
+	peerEnforcer.syncUsage(context.Background())
+	ownerState.Mu.RLock()
+	ownerCount = ownerState.RequestsToday
+	ownerState.Mu.RUnlock()
+	if ownerCount != 8 {
+		t.Errorf("expected owner count to stay at 8, got %d", ownerCount)
+	}
+}
+
+func TestQuotaSyncHandlerRequiresPeerSecret(t *testing.T) {
+	enforcer := NewQuotaEnforcer(newMockCPClient())
+	enforcer.EnableSharding(&enterprise.GatewayInfo{ID: "owner", Address: "127.0.0.1:0"}, "peer-secret")
+	handler := enforcer.SyncHandler()
+
+	post := func(authorization string, delta int64) int {
+		body := fmt.Sprintf(`{"day":%q,"deltas":{"tenant-1":%d}}`, dayKey(time.Now()), delta)
+		req := httptest.NewRequest(http.MethodPost, QuotaSyncPath, strings.NewReader(body))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, authorization := range []string{"", "Bearer wrong", "peer-secret"} {
+		if code := post(authorization, 5); code != http.StatusUnauthorized {
+			t.Errorf("expected 401 for %q, got %d", authorization, code)
+		}
+	}
+
+	if code := post("Bearer peer-secret", 1_000_000); code != http.StatusOK {
+		t.Fatalf("expected the peer to be accepted, got %d", code)
+	}
+
+	state := enforcer.getOrCreateQuotaState("tenant-1")
+	state.Mu.RLock()
+	defer state.Mu.RUnlock()
+	if state.RequestsToday != maxQuotaSyncDelta {
+		t.Errorf("expected only the rejected syncs to be ignored and the delta to be capped at %d, got %d", maxQuotaSyncDelta, state.RequestsToday)
+	}
+}
+
+func TestQuotaEnforcerCheckpointRestore(t *testing.T) {
+	cpClient := newMockCPClient()
+	cpClient.addTenant("tenant-1", 100, 1000)
+
+	enforcer := NewQuotaEnforcer(cpClient)
+	for i := 0; i < 4; i++ {
+		if err := enforcer.CheckQuota("tenant-1", 0); err != nil {
+			t.Fatalf("request %d should be allowed: %v", i, err)
+		}
+	}
+
+	enforcer.checkpointUsage(context.Background())
+
+	checkpoint, err := cpClient.GetUsageCheckpoint(context.Background(), "tenant-1")
+	if err != nil {
+		t.Fatalf("expected checkpoint to be saved: %v", err)
+	}
+	if checkpoint.APIRequests != 4 || checkpoint.Day != dayKey(time.Now()) {
+		t.Errorf("unexpected checkpoint: %+v", checkpoint)
+	}
+
+	// A gateway taking over the tenant continues from the checkpoint
+	restarted := NewQuotaEnforcer(cpClient)
+	restarted.syncTenantQuota("tenant-1")
+
+	state := restarted.getOrCreateQuotaState("tenant-1")
+	state.Mu.RLock()
+	defer state.Mu.RUnlock()
+	if state.RequestsToday != 4 {
+		t.Errorf("expected restored count of 4, got %d", state.RequestsToday)
+	}
+}
+
+func TestQuotaEnforcerRateLimitShare(t *testing.T) {
+	cpClient := newMockCPClient()
+	cpClient.addTenant("tenant-1", 100, 100000)
+
+	self := &enterprise.GatewayInfo{ID: "gw-1", Address: "gw-1:8091"}
+	enforcer := NewQuotaEnforcer(cpClient)
+	enforcer.EnableSharding(self, "peer-secret")
+	enforcer.ring.SetMembers([]*enterprise.GatewayInfo{
+		self,
+		{ID: "gw-2", Address: "gw-2:8091"},
+		{ID: "gw-3", Address: "gw-3:8091"},
+		{ID: "gw-4", Address: "gw-4:8091"},
+	})
+
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if enforcer.checkRateLimit("tenant-1") {
+			allowed++
+		}
+	}
+
+	// Each of the 4 gateways gets a quarter of the 100 token burst
+	if allowed < 25 || allowed > 26 {
+		t.Errorf("expected a burst of about 25 requests, got %d", allowed)
+	}
+}
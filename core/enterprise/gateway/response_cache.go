@@ -0,0 +1,337 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+const (
+	// changeFeedPollInterval is how often tenant nodes are polled for record changes
+	changeFeedPollInterval = 1 * time.Second
+
+	// cacheStatusHeader reports whether a response was served from the cache
+	cacheStatusHeader = "X-Cache"
+)
+
+// changeFeedCursor is the gateway's position in a node's record change feed
+type changeFeedCursor struct {
+	nodeID string
+	epoch  string
+	seq    uint64
+}
+
+// isCacheableRequest reports whether a request may be answered from the response cache.
+// Authenticated requests are never cached since their responses depend on the auth record.
+func isCacheableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Range") != "" {
+		return false
+	}
+
+	if cookie, err := r.Cookie("pb_auth"); err == nil && cookie.Value != "" {
+		return false
+	}
+
+	if enterprise.IsRealtimeRequest(r) {
+		return false
+	}
+
+	_, noStore := parseCacheControl(r.Header)["no-store"]
+	return !noStore
+}
+
+// responseCacheKey identifies a cached response. Accept-Encoding and Origin
+// are included since PocketBase varies compression and CORS headers on them.
+func responseCacheKey(tenantID string, r *http.Request) string {
+	return strings.Join([]string{
+		tenantID,
+		r.URL.RequestURI(),
+		r.Header.Get("Accept-Encoding"),
+		r.Header.Get("Origin"),
+	}, "\x00")
+}
+
+// parseCacheControl parses the Cache-Control directives of a header set
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, arg, _ := strings.Cut(part, "=")
+			directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return directives
+}
+
+// responseTTL returns how long a response may be cached, or false if it must not be cached
+func responseTTL(status int, header http.Header, defaultTTL time.Duration) (time.Duration, bool) {
+	if status != http.StatusOK || header.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+
+	if strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") {
+		return 0, false
+	}
+
+	// Only variations that are part of the cache key can be stored
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if !strings.EqualFold(field, "Accept-Encoding") && !strings.EqualFold(field, "Origin") {
+				return 0, false
+			}
+		}
+	}
+
+	directives := parseCacheControl(header)
+	for _, name := range []string{"no-store", "no-cache", "private"} {
+		if _, exists := directives[name]; exists {
+			return 0, false
+		}
+	}
+
+	ttl := defaultTTL
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if value, exists := directives[name]; exists {
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, false
+			}
+			ttl = time.Duration(seconds) * time.Second
+			break
+		}
+	}
+
+	return ttl, ttl > 0
+}
+
+// etagMatches reports whether an If-None-Match header matches an ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// serveFromCache writes a cached response, returning false on a cache miss
+func (g *Gateway) serveFromCache(w http.ResponseWriter, r *http.Request, tenant *enterprise.Tenant) bool {
+	// The client asked for revalidation - fetch a fresh copy (which is stored again)
+	directives := parseCacheControl(r.Header)
+	if _, noCache := directives["no-cache"]; noCache || directives["max-age"] == "0" {
+		return false
+	}
+
+	entry := g.responseCache.Get(responseCacheKey(tenant.ID, r))
+	if entry == nil {
+		return false
+	}
+
+	for name, values := range entry.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(entry.storedAt).Seconds())))
+	w.Header().Set(cacheStatusHeader, "HIT")
+
+	if etagMatches(r.Header.Get("If-None-Match"), entry.header.Get("ETag")) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	w.WriteHeader(entry.status)
+	w.Write(entry.body)
+	return true
+}
+
+// proxyAndCache proxies a cacheable request and stores the response if it allows caching
+func (g *Gateway) proxyAndCache(w http.ResponseWriter, r *http.Request, tenant *enterprise.Tenant, nodeAddr string, proxy *httputil.ReverseProxy) {
+	key := responseCacheKey(tenant.ID, r)
+	generation := g.responseCache.Generation(tenant.ID)
+
+	recorder := &cacheRecorder{
+		ResponseWriter: w,
+		limit:          g.responseCache.config.MaxEntryBytes,
+	}
+
+	w.Header().Set(cacheStatusHeader, "MISS")
+	proxy.ServeHTTP(recorder, r)
+
+	if recorder.overflow || recorder.header == nil {
+		return
+	}
+
+	defaultTTL := g.responseCache.config.DefaultTTL
+	if tenant.ResponseCacheTTL > 0 {
+		defaultTTL = time.Duration(tenant.ResponseCacheTTL) * time.Second
+	}
+
+	ttl, ok := responseTTL(recorder.status, recorder.header, defaultTTL)
+	if !ok {
+		return
+	}
+
+	header := recorder.header
+	header.Del(cacheStatusHeader)
+	header.Del("Date")
+	if header.Get("ETag") == "" {
+		sum := sha256.Sum256(recorder.body.Bytes())
+		header.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	}
+
+	now := time.Now()
+	g.responseCache.Put(&cachedResponse{
+		key:      key,
+		tenantID: tenant.ID,
+		nodeAddr: nodeAddr,
+		status:   recorder.status,
+		header:   header,
+		body:     recorder.body.Bytes(),
+		storedAt: now,
+		expires:  now.Add(ttl),
+	}, generation)
+}
+
+// watchRecordChanges polls the change feeds of the nodes that produced cached responses
+func (g *Gateway) watchRecordChanges() {
+	ticker := time.NewTicker(changeFeedPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case <-ticker.C:
+			g.pollRecordChanges(g.ctx)
+		}
+	}
+}
+
+// pollRecordChanges invalidates the cached responses of tenants whose records changed
+func (g *Gateway) pollRecordChanges(ctx context.Context) {
+	for _, nodeAddr := range g.responseCache.Nodes() {
+		if err := g.pollNodeChanges(ctx, nodeAddr); err != nil {
+			// Changes may have been missed - nothing from this node can be trusted
+			g.logger.Error("Failed to poll record changes", "nodeAddr", nodeAddr, "error", err)
+			g.responseCache.InvalidateNode(nodeAddr)
+
+			g.changeCursorsMu.Lock()
+			delete(g.changeCursors, nodeAddr)
+			g.changeCursorsMu.Unlock()
+		}
+	}
+}
+
+// pollNodeChanges reads a node's change feed from the gateway's last position
+func (g *Gateway) pollNodeChanges(ctx context.Context, nodeAddr string) error {
+	g.changeCursorsMu.Lock()
+	cursor, known := g.changeCursors[nodeAddr]
+	g.changeCursorsMu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/_changes?since=%d&epoch=%s", nodeAddr, cursor.seq, url.QueryEscape(cursor.epoch)), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := g.changeFeedClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var feed enterprise.RecordChangeFeed
+	if err := json.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return fmt.Errorf("failed to decode change feed: %w", err)
+	}
+
+	// First contact, node restart or a gap in the feed
+	if !known || feed.NodeID != cursor.nodeID || feed.Epoch != cursor.epoch || feed.Reset {
+		g.responseCache.InvalidateNode(nodeAddr)
+	} else {
+		invalidated := make(map[string]bool)
+		for _, change := range feed.Changes {
+			if !invalidated[change.TenantID] {
+				invalidated[change.TenantID] = true
+				g.responseCache.InvalidateTenant(change.TenantID)
+			}
+		}
+	}
+
+	g.changeCursorsMu.Lock()
+	g.changeCursors[nodeAddr] = changeFeedCursor{nodeID: feed.NodeID, epoch: feed.Epoch, seq: feed.Seq}
+	g.changeCursorsMu.Unlock()
+
+	return nil
+}
+
+// cacheRecorder passes a response through to the client while keeping a copy
+// of it, up to limit bytes, for the response cache
+type cacheRecorder struct {
+	http.ResponseWriter
+
+	limit    int64
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	overflow bool
+}
+
+// WriteHeader captures the status code and headers
+func (rec *cacheRecorder) WriteHeader(code int) {
+	if rec.header == nil {
+		rec.status = code
+		rec.header = rec.ResponseWriter.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Write captures the body
+func (rec *cacheRecorder) Write(b []byte) (int, error) {
+	if rec.header == nil {
+		rec.WriteHeader(http.StatusOK)
+	}
+
+	if !rec.overflow {
+		if int64(rec.body.Len()+len(b)) > rec.limit {
+			rec.overflow = true
+			rec.body.Reset()
+		} else {
+			rec.body.Write(b)
+		}
+	}
+
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter (used by http.ResponseController)
+func (rec *cacheRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
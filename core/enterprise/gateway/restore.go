@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// restorePageTemplate is served to browsers while an archived tenant is being restored
+var restorePageTemplate = template.Must(template.New("restore").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.RefreshSeconds}}">
+<title>Restoring {{.Domain}}</title>
+<style>body{font-family:sans-serif;max-width:40em;margin:4em auto;color:#333}</style>
+</head>
+<body>
+<h1>This project is being restored</h1>
+{{if .Failed}}
+<p>Restoring {{.Domain}} from archive storage failed. Please contact support.</p>
+{{else}}
+<p>{{.Domain}} has been inactive for a while and was moved to archive storage.
+It is being restored and should be available around <strong>{{.ETA}}</strong>.</p>
+{{if .ObjectsTotal}}<p>Progress: {{.ObjectsRestored}} of {{.ObjectsTotal}} files restored.</p>{{end}}
+<p>This page refreshes automatically.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// handleArchivedTenant kicks off (or looks up) the tenant's restore job and
+// responds with 503 and the estimated time until the tenant is available
+func (g *Gateway) handleArchivedTenant(w http.ResponseWriter, r *http.Request, tenant *enterprise.Tenant) {
+	job, err := g.cpClient.RequestTenantRestore(r.Context(), tenant.ID, false)
+	if err != nil {
+		g.logger.Error("Failed to request restore for tenant", "tenantId", tenant.ID, "error", err)
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	// The job may have completed between the tenant lookup and now
+	if job.Status == enterprise.RestoreJobReady {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Tenant restored, please retry", http.StatusServiceUnavailable)
+		return
+	}
+
+	writeRestoreResponse(w, r, tenant, job)
+}
+
+// writeRestoreResponse writes a 503 response describing restore progress,
+// as HTML for browsers and JSON for API clients
+func writeRestoreResponse(w http.ResponseWriter, r *http.Request, tenant *enterprise.Tenant, job *enterprise.RestoreJob) {
+	retryAfter := int(job.RetryAfter().Seconds())
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.Header().Set("Cache-Control", "no-store")
+
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		// Refresh at most every 5 minutes so the page notices an early finish
+		refresh := retryAfter
+		if refresh > 300 {
+			refresh = 300
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		restorePageTemplate.Execute(w, map[string]interface{}{
+			"Domain":          tenant.Domain,
+			"ETA":             job.EstimatedReady.UTC().Format(time.RFC1123),
+			"Failed":          job.Status == enterprise.RestoreJobFailed,
+			"ObjectsTotal":    job.ObjectsTotal,
+			"ObjectsRestored": job.ObjectsRestored,
+			"RefreshSeconds":  refresh,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":          "tenant is archived and being restored",
+		"restoreStatus":  job.Status,
+		"estimatedReady": job.EstimatedReady,
+		"retryAfter":     retryAfter,
+	})
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func newTestGateway(t *testing.T, cpClient enterprise.ControlPlaneClient) *Gateway {
+	g, err := NewGateway(&enterprise.ClusterConfig{Mode: enterprise.ModeGateway}, cpClient)
+	if err != nil {
+		t.Fatalf("failed to create gateway: %v", err)
+	}
+	return g
+}
+
+func TestHandleArchivedTenantJSON(t *testing.T) {
+	cpClient := newMockCPClient()
+	cpClient.restoreJobs["tenant-1"] = &enterprise.RestoreJob{
+		TenantID:       "tenant-1",
+		Status:         enterprise.RestoreJobInProgress,
+		EstimatedReady: time.Now().Add(2 * time.Hour),
+	}
+
+	g := newTestGateway(t, cpClient)
+	tenant := &enterprise.Tenant{ID: "tenant-1", Domain: "t1.example.com", Status: enterprise.TenantStatusArchived}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/collections", nil)
+	g.handleArchivedTenant(rec, req, tenant)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil {
+		t.Fatalf("invalid Retry-After header: %v", err)
+	}
+	if retryAfter < 3600 || retryAfter > 7200 {
+		t.Errorf("expected Retry-After close to 2h, got %d", retryAfter)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body["restoreStatus"] != string(enterprise.RestoreJobInProgress) {
+		t.Errorf("expected restoreStatus in_progress, got %v", body["restoreStatus"])
+	}
+}
+
+func TestHandleArchivedTenantHTML(t *testing.T) {
+	cpClient := newMockCPClient()
+	cpClient.restoreJobs["tenant-1"] = &enterprise.RestoreJob{
+		TenantID:       "tenant-1",
+		Status:         enterprise.RestoreJobPending,
+		EstimatedReady: time.Now().Add(12 * time.Hour),
+	}
+
+	g := newTestGateway(t, cpClient)
+	tenant := &enterprise.Tenant{ID: "tenant-1", Domain: "t1.example.com", Status: enterprise.TenantStatusArchived}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	g.handleArchivedTenant(rec, req, tenant)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected HTML response, got %s", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "t1.example.com") {
+		t.Error("expected restore page to mention the tenant domain")
+	}
+}
+
+func TestHandleArchivedTenantRestoreError(t *testing.T) {
+	g := newTestGateway(t, newMockCPClient())
+	tenant := &enterprise.Tenant{ID: "missing", Status: enterprise.TenantStatusArchived}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	g.handleArchivedTenant(rec, req, tenant)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+}
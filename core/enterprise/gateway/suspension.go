@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// defaultSuspendedPageTemplate is served to browsers for suspended tenants,
+// unless a page template is configured
+var defaultSuspendedPageTemplate = template.Must(template.New("suspended").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Domain}} is unavailable</title>
+<style>body{font-family:sans-serif;max-width:40em;margin:4em auto;color:#333}</style>
+</head>
+<body>
+<h1>This project is unavailable</h1>
+{{if eq .Reason "non_payment"}}
+<p>{{.Domain}} was suspended because of unpaid invoices.</p>
+{{else}}
+<p>{{.Domain}} was suspended for violating the terms of service.</p>
+{{end}}
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+{{if .AppealURL}}<p>If you own this project, you can <a href="{{.AppealURL}}">appeal the suspension</a>.</p>{{end}}
+</body>
+</html>
+`))
+
+// loadSuspendedPageTemplate parses the configured page template, or returns
+// the built-in one
+func loadSuspendedPageTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return defaultSuspendedPageTemplate, nil
+	}
+
+	tmpl, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse suspension page template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// suspensionStatusCode returns 402 for tenants suspended for non-payment and
+// 403 for everything else
+func suspensionStatusCode(suspension *enterprise.TenantSuspension) int {
+	if suspension.Reason == enterprise.SuspensionReasonNonPayment {
+		return http.StatusPaymentRequired
+	}
+	return http.StatusForbidden
+}
+
+// handleSuspendedTenant responds with the reason of the suspension, as HTML
+// for browsers and JSON for API clients
+func (g *Gateway) handleSuspendedTenant(w http.ResponseWriter, r *http.Request, tenant *enterprise.Tenant) {
+	suspension := tenant.Suspension
+	statusCode := suspensionStatusCode(suspension)
+	appealURL := g.config.Suspension.AppealURL
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(statusCode)
+		if err := g.suspendedPage.Execute(w, map[string]interface{}{
+			"Domain":    tenant.Domain,
+			"Reason":    string(suspension.Reason),
+			"Message":   suspension.Message,
+			"AppealURL": appealURL,
+		}); err != nil {
+			g.logger.Error("Failed to render suspension page", "tenantId", tenant.ID, "error", err)
+		}
+		return
+	}
+
+	body := map[string]interface{}{
+		"error":   "tenant is suspended",
+		"reason":  suspension.Reason,
+		"message": suspension.Message,
+	}
+	if appealURL != "" {
+		body["appealUrl"] = appealURL
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
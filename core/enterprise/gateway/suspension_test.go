@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func newSuspendedTenant(reason enterprise.SuspensionReason) *enterprise.Tenant {
+	return &enterprise.Tenant{
+		ID:     "tenant-1",
+		Domain: "t1.example.com",
+		Status: enterprise.TenantStatusActive,
+		Suspension: &enterprise.TenantSuspension{
+			Reason:      reason,
+			Message:     "Invoice 42 is overdue",
+			SuspendedAt: time.Now(),
+		},
+	}
+}
+
+func TestHandleSuspendedTenantJSON(t *testing.T) {
+	g, err := NewGateway(&enterprise.ClusterConfig{
+		Mode:       enterprise.ModeGateway,
+		Suspension: enterprise.SuspensionSettings{AppealURL: "https://example.com/appeal"},
+	}, newMockCPClient())
+	if err != nil {
+		t.Fatalf("failed to create gateway: %v", err)
+	}
+
+	for reason, expected := range map[enterprise.SuspensionReason]int{
+		enterprise.SuspensionReasonNonPayment: http.StatusPaymentRequired,
+		enterprise.SuspensionReasonAbuse:      http.StatusForbidden,
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/collections", nil)
+		g.handleSuspendedTenant(rec, req, newSuspendedTenant(reason))
+
+		if rec.Code != expected {
+			t.Errorf("expected %d for %s, got %d", expected, reason, rec.Code)
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+		if body["reason"] != string(reason) || body["appealUrl"] != "https://example.com/appeal" {
+			t.Errorf("expected the reason and the appeal URL, got %v", body)
+		}
+	}
+}
+
+func TestHandleSuspendedTenantHTML(t *testing.T) {
+	g := newTestGateway(t, newMockCPClient())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	g.handleSuspendedTenant(rec, req, newSuspendedTenant(enterprise.SuspensionReasonNonPayment))
+
+	if rec.Code != http.StatusPaymentRequired {
+		t.Fatalf("expected 402, got %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected HTML response, got %s", rec.Header().Get("Content-Type"))
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "unpaid invoices") || !strings.Contains(body, "Invoice 42 is overdue") {
+		t.Errorf("expected the page to explain the suspension, got %q", body)
+	}
+}
+
+func TestSuspendedPageTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suspended.html")
+	if err := os.WriteFile(path, []byte(`<p>{{.Domain}} is paused ({{.Reason}})</p>`), 0o644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	g, err := NewGateway(&enterprise.ClusterConfig{
+		Mode:       enterprise.ModeGateway,
+		Suspension: enterprise.SuspensionSettings{PageTemplate: path},
+	}, newMockCPClient())
+	if err != nil {
+		t.Fatalf("failed to create gateway: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html")
+	g.handleSuspendedTenant(rec, req, newSuspendedTenant(enterprise.SuspensionReasonAbuse))
+
+	if body := rec.Body.String(); body != "<p>t1.example.com is paused (abuse)</p>" {
+		t.Errorf("expected the configured page, got %q", body)
+	}
+
+	_, err = NewGateway(&enterprise.ClusterConfig{
+		Mode:       enterprise.ModeGateway,
+		Suspension: enterprise.SuspensionSettings{PageTemplate: filepath.Join(t.TempDir(), "missing.html")},
+	}, newMockCPClient())
+	if err == nil {
+		t.Error("expected a missing page template to fail the gateway")
+	}
+}
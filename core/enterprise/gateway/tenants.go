@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// tenantLookupTimeout bounds a tenant lookup shared by the requests for a domain
+const tenantLookupTimeout = 10 * time.Second
+
+// maxCachedTenants bounds the tenant lookup cache. Lookups of new domains are
+// not cached while it is full of fresh entries.
+const maxCachedTenants = 100000
+
+// cachedTenant is a tenant lookup and when it has to be asked for again
+type cachedTenant struct {
+	tenant    *enterprise.Tenant
+	expiresAt time.Time
+}
+
+// lookupTenant returns the tenant of a domain. Lookups are stale reads served
+// by any control plane node, kept for the cache TTL, so a change to a tenant
+// reaches the gateway within the TTL plus the max staleness. Concurrent
+// lookups of the same domain share one request, which isn't tied to the
+// request that started it: a client going away doesn't fail the others.
+func (g *Gateway) lookupTenant(ctx context.Context, domain string) (*enterprise.Tenant, error) {
+	if tenant := g.cachedTenant(domain); tenant != nil {
+		return tenant, nil
+	}
+
+	lookup := g.tenantLookups.DoChan(domain, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tenantLookupTimeout)
+		defer cancel()
+
+		ctx = enterprise.WithStaleReads(ctx, g.config.Reads.MaxStalenessDuration())
+		tenant, err := g.cpClient.GetTenantByDomain(ctx, domain)
+		if err != nil {
+			return nil, err
+		}
+		g.cacheTenant(domain, tenant)
+		return tenant, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-lookup:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.(*enterprise.Tenant), nil
+	}
+}
+
+// evictTenant drops the cached tenant of a domain, which a tenant node showed
+// to be stale, so the next request looks it up again
+func (g *Gateway) evictTenant(domain string) {
+	g.tenantCacheMu.Lock()
+	defer g.tenantCacheMu.Unlock()
+
+	delete(g.tenantCache, domain)
+}
+
+// tenantDomain returns the domain a request's tenant is looked up by, its host
+// without the port
+func tenantDomain(host string) string {
+	if strings.Contains(host, ":") {
+		host = strings.Split(host, ":")[0]
+	}
+	return host
+}
+
+// cachedTenant returns the cached tenant of a domain, or nil once it expired
+func (g *Gateway) cachedTenant(domain string) *enterprise.Tenant {
+	g.tenantCacheMu.RLock()
+	defer g.tenantCacheMu.RUnlock()
+
+	entry, ok := g.tenantCache[domain]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil
+	}
+	return entry.tenant
+}
+
+// cacheTenant keeps the tenant of a domain for the cache TTL
+func (g *Gateway) cacheTenant(domain string, tenant *enterprise.Tenant) {
+	now := time.Now()
+
+	g.tenantCacheMu.Lock()
+	defer g.tenantCacheMu.Unlock()
+
+	if len(g.tenantCache) >= maxCachedTenants {
+		for d, entry := range g.tenantCache {
+			if now.After(entry.expiresAt) {
+				delete(g.tenantCache, d)
+			}
+		}
+		if len(g.tenantCache) >= maxCachedTenants {
+			return
+		}
+	}
+
+	g.tenantCache[domain] = &cachedTenant{
+		tenant:    tenant,
+		expiresAt: now.Add(g.config.Reads.TenantCacheTTLDuration()),
+	}
+}
@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// lookupCountingCPClient counts the tenant lookups that reach the control plane
+type lookupCountingCPClient struct {
+	*mockControlPlaneClient
+	lookups atomic.Int32
+	stale   atomic.Bool // Every lookup asked for a stale read
+	release chan struct{}
+}
+
+func (m *lookupCountingCPClient) GetTenantByDomain(ctx context.Context, domain string) (*enterprise.Tenant, error) {
+	m.lookups.Add(1)
+	if opts := enterprise.ReadOptionsFromContext(ctx); opts.Consistency != enterprise.ReadStale || opts.MaxStaleness != 3*time.Second {
+		m.stale.Store(false)
+	}
+	if m.release != nil {
+		<-m.release
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.mockControlPlaneClient.GetTenantByDomain(ctx, domain)
+}
+
+func TestLookupTenantIsCached(t *testing.T) {
+	cpClient := &lookupCountingCPClient{mockControlPlaneClient: newMockCPClient()}
+	cpClient.stale.Store(true)
+	cpClient.tenants["tenant-1"] = &enterprise.Tenant{ID: "tenant-1", Domain: "t1.example.com"}
+
+	g, err := NewGateway(&enterprise.ClusterConfig{
+		Mode:  enterprise.ModeGateway,
+		Reads: enterprise.ReadSettings{MaxStaleness: "3s", TenantCacheTTL: "1h"},
+	}, cpClient)
+	if err != nil {
+		t.Fatalf("failed to create gateway: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		tenant, err := g.lookupTenant(context.Background(), "t1.example.com")
+		if err != nil || tenant.ID != "tenant-1" {
+			t.Fatalf("expected tenant-1, got %v %v", tenant, err)
+		}
+	}
+	if n := cpClient.lookups.Load(); n != 1 {
+		t.Errorf("expected one control plane lookup, got %d", n)
+	}
+	if !cpClient.stale.Load() {
+		t.Error("expected lookups to be stale reads bounded by the configured staleness")
+	}
+
+	// unknown domains aren't cached
+	for i := 0; i < 2; i++ {
+		if _, err := g.lookupTenant(context.Background(), "missing.example.com"); !errors.Is(err, enterprise.ErrTenantNotFound) {
+			t.Fatalf("expected ErrTenantNotFound, got %v", err)
+		}
+	}
+	if n := cpClient.lookups.Load(); n != 3 {
+		t.Errorf("expected misses to reach the control plane, got %d lookups", n)
+	}
+
+	// expired entries are looked up again
+	g.tenantCacheMu.Lock()
+	g.tenantCache["t1.example.com"].expiresAt = time.Now().Add(-time.Second)
+	g.tenantCacheMu.Unlock()
+
+	if _, err := g.lookupTenant(context.Background(), "t1.example.com"); err != nil {
+		t.Fatalf("failed to look up tenant: %v", err)
+	}
+	if n := cpClient.lookups.Load(); n != 4 {
+		t.Errorf("expected the expired entry to be looked up again, got %d lookups", n)
+	}
+}
+
+func TestLookupTenantSharesConcurrentLookups(t *testing.T) {
+	cpClient := &lookupCountingCPClient{mockControlPlaneClient: newMockCPClient(), release: make(chan struct{})}
+	cpClient.tenants["tenant-1"] = &enterprise.Tenant{ID: "tenant-1", Domain: "t1.example.com"}
+	g := newTestGateway(t, cpClient)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := g.lookupTenant(context.Background(), "t1.example.com"); err != nil {
+				t.Errorf("failed to look up tenant: %v", err)
+			}
+		}()
+	}
+
+	// let the lookups pile up behind the first one
+	for cpClient.lookups.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(cpClient.release)
+	wg.Wait()
+
+	if n := cpClient.lookups.Load(); n != 1 {
+		t.Errorf("expected concurrent lookups to share one request, got %d", n)
+	}
+}
+
+func TestLookupTenantOutlivesTheFirstCaller(t *testing.T) {
+	cpClient := &lookupCountingCPClient{mockControlPlaneClient: newMockCPClient(), release: make(chan struct{})}
+	cpClient.tenants["tenant-1"] = &enterprise.Tenant{ID: "tenant-1", Domain: "t1.example.com"}
+	g := newTestGateway(t, cpClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := g.lookupTenant(ctx, "t1.example.com")
+		first <- err
+	}()
+	for cpClient.lookups.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := g.lookupTenant(context.Background(), "t1.example.com")
+		second <- err
+	}()
+
+	// the first client disconnects while the lookup is in flight
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the first caller to give up, got %v", err)
+	}
+
+	close(cpClient.release)
+	if err := <-second; err != nil {
+		t.Errorf("expected the shared lookup to succeed for the other caller, got %v", err)
+	}
+	if n := cpClient.lookups.Load(); n != 1 {
+		t.Errorf("expected one shared lookup, got %d", n)
+	}
+}
+
+func TestProxyEvictsTenantsRefusedByTheNode(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/suspended" {
+			w.Header().Set(enterprise.TenantRefusedHeader, "suspended")
+		}
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}))
+	defer node.Close()
+
+	g := newTestGateway(t, newMockCPClient())
+	g.cacheTenant("t1.example.com", &enterprise.Tenant{ID: "tenant-1", Domain: "t1.example.com"})
+	proxy := g.getOrCreateProxy(node.URL)
+
+	// the tenant app's own 403s leave the cache alone
+	req := httptest.NewRequest(http.MethodGet, "http://t1.example.com:8080/api/collections/posts/records", nil)
+	proxy.ServeHTTP(httptest.NewRecorder(), req)
+	if g.cachedTenant("t1.example.com") == nil {
+		t.Fatal("expected an app response to keep the cached tenant")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://t1.example.com:8080/suspended", nil)
+	proxy.ServeHTTP(httptest.NewRecorder(), req)
+	if g.cachedTenant("t1.example.com") != nil {
+		t.Error("expected the node's refusal to evict the cached tenant")
+	}
+}
@@ -0,0 +1,64 @@
+package enterprise
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+// MaxIPCClockSkew is how old a signed IPC request may be. Replaying one within
+// the window gains nothing, the answer is sealed with the shared secret.
+const MaxIPCClockSkew = 30 * time.Second
+
+// SignIPCRequest returns the signature a node sends with an IPC request that
+// only nodes knowing the IPC secret may make
+func SignIPCRequest(secret, reqType, nodeID, tenantID string, timestamp time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%d", reqType, nodeID, tenantID, timestamp.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyIPCRequest checks the signature of an IPC request made at the unix
+// timestamp, or returns ErrIPCUnauthorized
+func VerifyIPCRequest(secret, reqType, nodeID, tenantID string, timestamp int64, signature string) error {
+	if secret == "" {
+		return fmt.Errorf("%w: no ipc secret configured", ErrIPCUnauthorized)
+	}
+
+	signed := time.Unix(timestamp, 0)
+	if skew := time.Since(signed); skew > MaxIPCClockSkew || skew < -MaxIPCClockSkew {
+		return fmt.Errorf("%w: request signed at %s", ErrIPCUnauthorized, signed.UTC().Format(time.RFC3339))
+	}
+
+	expected := SignIPCRequest(secret, reqType, nodeID, tenantID, signed)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("%w: bad signature", ErrIPCUnauthorized)
+	}
+	return nil
+}
+
+// SealIPCPayload encrypts an IPC answer for the nodes knowing the IPC secret,
+// the socket itself is plain TCP
+func SealIPCPayload(secret string, payload []byte) (string, error) {
+	return security.Encrypt(payload, ipcSealKey(secret))
+}
+
+// OpenIPCPayload decrypts an answer sealed with SealIPCPayload
+func OpenIPCPayload(secret, sealed string) ([]byte, error) {
+	payload, err := security.Decrypt(sealed, ipcSealKey(secret))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to open sealed payload: %v", ErrIPCUnauthorized, err)
+	}
+	return payload, nil
+}
+
+// ipcSealKey derives the 32 byte AES key of sealed payloads from the secret
+func ipcSealKey(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("pocketbase ipc seal"))
+	return string(mac.Sum(nil))
+}
@@ -0,0 +1,46 @@
+package enterprise
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyIPCRequest(t *testing.T) {
+	now := time.Now()
+	signature := SignIPCRequest("secret", "getTenantKeys", "node-1", "tenant-1", now)
+
+	if err := VerifyIPCRequest("secret", "getTenantKeys", "node-1", "tenant-1", now.Unix(), signature); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+
+	tests := map[string]error{
+		"other secret": VerifyIPCRequest("other", "getTenantKeys", "node-1", "tenant-1", now.Unix(), signature),
+		"no secret":    VerifyIPCRequest("", "getTenantKeys", "node-1", "tenant-1", now.Unix(), SignIPCRequest("", "getTenantKeys", "node-1", "tenant-1", now)),
+		"other node":   VerifyIPCRequest("secret", "getTenantKeys", "node-2", "tenant-1", now.Unix(), signature),
+		"other tenant": VerifyIPCRequest("secret", "getTenantKeys", "node-1", "tenant-2", now.Unix(), signature),
+		"expired": VerifyIPCRequest("secret", "getTenantKeys", "node-1", "tenant-1", now.Add(-time.Minute).Unix(),
+			SignIPCRequest("secret", "getTenantKeys", "node-1", "tenant-1", now.Add(-time.Minute))),
+	}
+	for name, err := range tests {
+		if !errors.Is(err, ErrIPCUnauthorized) {
+			t.Errorf("%s: expected ErrIPCUnauthorized, got %v", name, err)
+		}
+	}
+}
+
+func TestSealIPCPayload(t *testing.T) {
+	sealed, err := SealIPCPayload("secret", []byte("keys"))
+	if err != nil {
+		t.Fatalf("failed to seal payload: %v", err)
+	}
+
+	payload, err := OpenIPCPayload("secret", sealed)
+	if err != nil || string(payload) != "keys" {
+		t.Errorf("expected the sealed payload back, got %q (%v)", payload, err)
+	}
+
+	if _, err := OpenIPCPayload("other", sealed); !errors.Is(err, ErrIPCUnauthorized) {
+		t.Errorf("expected ErrIPCUnauthorized with another secret, got %v", err)
+	}
+}
@@ -0,0 +1,457 @@
+package enterprise
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/logger"
+)
+
+// Components of the enterprise subsystems. Each one logs at its own level,
+// set in logs.levels and defaulting to logLevel.
+const (
+	LogComponentControlPlane = "control-plane"
+	LogComponentRaft         = "raft"
+	LogComponentIPC          = "ipc"
+	LogComponentGateway      = "gateway"
+	LogComponentTenantNode   = "tenant-node"
+	LogComponentArchiver     = "archiver"
+	LogComponentStorage      = "storage" // Litestream, Glacier and the BadgerDB disk
+	LogComponentQuota        = "quota"
+	LogComponentAPI          = "api" // Cluster admin and user APIs
+	LogComponentAuth         = "auth"
+	LogComponentEmail        = "email" // Cluster email queue and provider webhooks
+)
+
+var logComponents = map[string]bool{
+	LogComponentControlPlane: true,
+	LogComponentRaft:         true,
+	LogComponentIPC:          true,
+	LogComponentGateway:      true,
+	LogComponentTenantNode:   true,
+	LogComponentArchiver:     true,
+	LogComponentStorage:      true,
+	LogComponentQuota:        true,
+	LogComponentAPI:          true,
+	LogComponentAuth:         true,
+	LogComponentEmail:        true,
+}
+
+// IsLogComponent reports whether name is a known log component
+func IsLogComponent(name string) bool {
+	return logComponents[name]
+}
+
+// Attribute keys shared by the enterprise logs. The log store indexes the
+// component, node, tenant and request of every entry.
+const (
+	LogKeyComponent = "component"
+	LogKeyNodeID    = "nodeId"
+	LogKeyTenantID  = "tenantId"
+	LogKeyRequestID = "requestId"
+	LogKeyRaftTerm  = "raftTerm"
+)
+
+// RequestIDHeader carries the request ID set by the gateway to the tenant node
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	// defaultPersistLevel is the minimum level shipped to the log store
+	defaultPersistLevel = slog.LevelWarn
+
+	// logStoreBatchSize is how many entries are batched before shipping
+	logStoreBatchSize = 50
+
+	// logStoreFlushInterval is how often incomplete batches are shipped
+	logStoreFlushInterval = 5 * time.Second
+
+	// maxPendingLogEntries bounds the entries kept while the log store is unreachable
+	maxPendingLogEntries = 1000
+)
+
+var (
+	// logLevel is the default minimum level, changed on config reload
+	logLevel = new(slog.LevelVar)
+
+	// componentLevels holds the per-component levels overriding logLevel
+	componentLevels atomic.Pointer[map[string]slog.Level]
+
+	// persistLevel is the minimum level shipped to the log store while persistEnabled
+	persistLevel   = new(slog.LevelVar)
+	persistEnabled atomic.Bool
+
+	// shipper sends the persisted logs of this node to the log store (nil if not started)
+	shipper atomic.Pointer[logShipper]
+
+	storeHandler = logger.NewBatchHandler(logger.BatchOptions{
+		WriteFunc: queueLogBatch,
+		BeforeAddFunc: func(ctx context.Context, log *logger.Log) bool {
+			return shipper.Load() != nil
+		},
+		Level:     persistLevel,
+		BatchSize: logStoreBatchSize,
+	})
+
+	rootLogger = slog.New(&componentHandler{
+		text:  slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		store: storeHandler,
+	})
+)
+
+func init() {
+	persistLevel.Set(defaultPersistLevel)
+}
+
+// Logger returns the enterprise logger, filtered by the configured log level
+func Logger() *slog.Logger {
+	return rootLogger
+}
+
+// ComponentLogger returns the logger of a component, filtered by its level
+func ComponentLogger(component string) *slog.Logger {
+	return rootLogger.With(LogKeyComponent, component)
+}
+
+// SetLogLevel changes the default level of the enterprise loggers
+func SetLogLevel(level string) error {
+	parsed, err := ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	logLevel.Set(parsed)
+	return nil
+}
+
+// ConfigureLogging applies the log levels and log store settings of config
+func ConfigureLogging(config *ClusterConfig) error {
+	if err := SetLogLevel(config.LogLevel); err != nil {
+		return err
+	}
+
+	levels := make(map[string]slog.Level, len(config.Logs.Levels))
+	for component, level := range config.Logs.Levels {
+		parsed, err := ParseLogLevel(level)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component, err)
+		}
+		levels[component] = parsed
+	}
+	componentLevels.Store(&levels)
+
+	level := defaultPersistLevel
+	if config.Logs.PersistLevel != "" {
+		parsed, err := ParseLogLevel(config.Logs.PersistLevel)
+		if err != nil {
+			return err
+		}
+		level = parsed
+	}
+	persistLevel.Set(level)
+	persistEnabled.Store(config.Logs.Persist)
+
+	return nil
+}
+
+// componentMinLevel returns the minimum level logged by a component
+func componentMinLevel(component string) slog.Level {
+	if levels := componentLevels.Load(); levels != nil {
+		if level, ok := (*levels)[component]; ok {
+			return level
+		}
+	}
+	return logLevel.Level()
+}
+
+// componentHandler filters records by the level of their component, writes
+// them to stderr and batches them for the log store if persisting is on
+type componentHandler struct {
+	component string
+	text      slog.Handler
+	store     slog.Handler
+}
+
+func (h *componentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= componentMinLevel(h.component)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, r slog.Record) error {
+	err := h.text.Handle(ctx, r)
+
+	if persistEnabled.Load() && h.store.Enabled(ctx, r.Level) {
+		if storeErr := h.store.Handle(ctx, r.Clone()); err == nil {
+			err = storeErr
+		}
+	}
+
+	return err
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	for _, attr := range attrs {
+		if attr.Key == LogKeyComponent {
+			component = attr.Value.String()
+		}
+	}
+
+	return &componentHandler{
+		component: component,
+		text:      h.text.WithAttrs(attrs),
+		store:     h.store.WithAttrs(attrs),
+	}
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return &componentHandler{
+		component: h.component,
+		text:      h.text.WithGroup(name),
+		store:     h.store.WithGroup(name),
+	}
+}
+
+// LogStoreWriter stores a batch of log entries in the control plane log store
+type LogStoreWriter func(ctx context.Context, entries []*LogEntry) error
+
+// logShipper hands the batched log entries of a node to a LogStoreWriter
+type logShipper struct {
+	nodeID string
+	write  LogStoreWriter
+
+	mu      sync.Mutex
+	pending []*LogEntry
+	dropped int
+
+	ready chan struct{}
+}
+
+// StartLogShipping ships the persisted logs of this node with write until the
+// returned function is called. Entries that can't be written are retried with
+// the next batch, up to maxPendingLogEntries.
+func StartLogShipping(nodeID string, write LogStoreWriter) (stop func()) {
+	s := &logShipper{
+		nodeID: nodeID,
+		write:  write,
+		ready:  make(chan struct{}, 1),
+	}
+	shipper.Store(s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(logStoreFlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				storeHandler.WriteAll(ctx)
+				flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+				s.flush(flushCtx)
+				flushCancel()
+				return
+			case <-ticker.C:
+				storeHandler.WriteAll(ctx)
+				s.flush(ctx)
+			case <-s.ready:
+				s.flush(ctx)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		shipper.CompareAndSwap(s, nil)
+	}
+}
+
+// queueLogBatch is the write function of the log store batch handler
+func queueLogBatch(ctx context.Context, logs []*logger.Log) error {
+	s := shipper.Load()
+	if s == nil {
+		return nil
+	}
+
+	entries := make([]*LogEntry, 0, len(logs))
+	for _, log := range logs {
+		entries = append(entries, newLogEntry(s.nodeID, log))
+	}
+
+	s.mu.Lock()
+	s.pending = append(s.pending, entries...)
+	if overflow := len(s.pending) - maxPendingLogEntries; overflow > 0 {
+		s.pending = s.pending[overflow:]
+		s.dropped += overflow
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// flush writes the pending entries, keeping them for the next flush on failure
+func (s *logShipper) flush(ctx context.Context) {
+	s.mu.Lock()
+	entries := s.pending
+	dropped := s.dropped
+	s.pending = nil
+	s.dropped = 0
+	s.mu.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	if err := s.write(ctx, entries); err != nil {
+		s.mu.Lock()
+		s.pending = append(entries, s.pending...)
+		if overflow := len(s.pending) - maxPendingLogEntries; overflow > 0 {
+			s.pending = s.pending[overflow:]
+			dropped += overflow
+		}
+		s.dropped += dropped
+		s.mu.Unlock()
+		return
+	}
+
+	// Logged to stderr only, the store might be why entries were dropped
+	if dropped > 0 {
+		stderrLogger.Warn("Log store entries dropped", "count", dropped)
+	}
+}
+
+// stderrLogger reports on the log store itself without feeding it
+var stderrLogger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// newLogEntry converts a batched log record into a log store entry
+func newLogEntry(nodeID string, log *logger.Log) *LogEntry {
+	entry := &LogEntry{
+		ID:      GenerateID("log"),
+		Time:    log.Time.UTC(),
+		Level:   int(log.Level),
+		Message: log.Message,
+		NodeID:  nodeID,
+		Data:    make(map[string]interface{}, len(log.Data)),
+	}
+
+	for key, value := range log.Data {
+		switch key {
+		case LogKeyComponent:
+			entry.Component, _ = value.(string)
+		case LogKeyTenantID:
+			entry.TenantID, _ = value.(string)
+		case LogKeyRequestID:
+			entry.RequestID, _ = value.(string)
+		default:
+			entry.Data[key] = value
+		}
+	}
+
+	if len(entry.Data) == 0 {
+		entry.Data = nil
+	}
+
+	return entry
+}
+
+// Matches reports whether an entry passes the filter (Limit is ignored). A node
+// filter matches the entries logged by the node and the ones about it.
+func (f *LogFilter) Matches(entry *LogEntry) bool {
+	if f.MinLevel != nil && entry.Level < *f.MinLevel {
+		return false
+	}
+	if f.Component != "" && entry.Component != f.Component {
+		return false
+	}
+	if f.NodeID != "" && entry.NodeID != f.NodeID && entry.Data[LogKeyNodeID] != f.NodeID {
+		return false
+	}
+	if f.TenantID != "" && entry.TenantID != f.TenantID {
+		return false
+	}
+	if f.RequestID != "" && entry.RequestID != f.RequestID {
+		return false
+	}
+	if f.Search != "" && !strings.Contains(strings.ToLower(entry.Message), strings.ToLower(f.Search)) {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Entries returned by a log store query when the filter has no limit, and
+// the most a query can return
+const (
+	DefaultLogListLimit = 200
+	MaxLogListLimit     = 1000
+)
+
+// ParseLogFilter reads a log store filter from the query string of a request,
+// applying the default and max limits
+func ParseLogFilter(query url.Values) (*LogFilter, error) {
+	filter := &LogFilter{
+		Component: query.Get("component"),
+		NodeID:    query.Get("nodeId"),
+		TenantID:  query.Get("tenantId"),
+		RequestID: query.Get("requestId"),
+		Search:    query.Get("search"),
+		Limit:     DefaultLogListLimit,
+	}
+
+	if level := query.Get("level"); level != "" {
+		parsed, err := ParseLogLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("level must be one of debug, info, warn or error")
+		}
+		minLevel := int(parsed)
+		filter.MinLevel = &minLevel
+	}
+
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return nil, fmt.Errorf("since must be an RFC 3339 time")
+		}
+		filter.Since = t
+	}
+
+	if until := query.Get("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("until must be an RFC 3339 time")
+		}
+		filter.Until = t
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("limit must be a positive number")
+		}
+		if n > 0 {
+			filter.Limit = min(n, MaxLogListLimit)
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,191 @@
+package enterprise
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// withLogConfig applies the logging settings of config for the duration of a test
+func withLogConfig(t *testing.T, config *ClusterConfig) {
+	if err := ConfigureLogging(config); err != nil {
+		t.Fatalf("ConfigureLogging: %v", err)
+	}
+	t.Cleanup(func() {
+		ConfigureLogging(DefaultClusterConfig())
+	})
+}
+
+func TestComponentLogLevels(t *testing.T) {
+	config := DefaultClusterConfig()
+	config.LogLevel = LogLevelWarn
+	config.Logs.Levels = map[string]string{LogComponentRaft: LogLevelDebug}
+	withLogConfig(t, config)
+
+	ctx := context.Background()
+
+	if !ComponentLogger(LogComponentRaft).Enabled(ctx, slog.LevelDebug) {
+		t.Error("expected debug logs of the raft component to be enabled")
+	}
+	if ComponentLogger(LogComponentGateway).Enabled(ctx, slog.LevelInfo) {
+		t.Error("expected info logs of the gateway to follow the default warn level")
+	}
+	if !ComponentLogger(LogComponentGateway).Enabled(ctx, slog.LevelWarn) {
+		t.Error("expected warn logs of the gateway to be enabled")
+	}
+}
+
+func TestLogShippingPersistsEntries(t *testing.T) {
+	config := DefaultClusterConfig()
+	config.Logs.Persist = true
+	config.Logs.PersistLevel = LogLevelWarn
+	withLogConfig(t, config)
+
+	var mu sync.Mutex
+	var shipped []*LogEntry
+	stop := StartLogShipping("node-1", func(ctx context.Context, entries []*LogEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		shipped = append(shipped, entries...)
+		return nil
+	})
+
+	logger := ComponentLogger(LogComponentGateway)
+	logger.Info("Routing tenant to node", LogKeyTenantID, "tenant-1")
+	logger.Warn("Quota exceeded for tenant", LogKeyTenantID, "tenant-1", LogKeyRequestID, "req-1", "limit", 10)
+
+	// Stopping ships the incomplete batch
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(shipped) != 1 {
+		t.Fatalf("expected only the warning to be shipped, got %d entries", len(shipped))
+	}
+
+	entry := shipped[0]
+	if entry.Message != "Quota exceeded for tenant" || entry.Level != int(slog.LevelWarn) {
+		t.Errorf("unexpected entry %q at level %d", entry.Message, entry.Level)
+	}
+	if entry.Component != LogComponentGateway || entry.NodeID != "node-1" {
+		t.Errorf("expected gateway entry of node-1, got %s of %s", entry.Component, entry.NodeID)
+	}
+	if entry.TenantID != "tenant-1" || entry.RequestID != "req-1" {
+		t.Errorf("expected tenant and request IDs to be indexed, got %q and %q", entry.TenantID, entry.RequestID)
+	}
+	if entry.Data["limit"] == nil {
+		t.Errorf("expected the other attributes in data, got %v", entry.Data)
+	}
+}
+
+func TestLogShipperKeepsEntriesOnFailure(t *testing.T) {
+	fail := true
+	var written []*LogEntry
+	s := &logShipper{
+		nodeID: "node-1",
+		write: func(ctx context.Context, entries []*LogEntry) error {
+			if fail {
+				return errors.New("not the leader")
+			}
+			written = append(written, entries...)
+			return nil
+		},
+		ready: make(chan struct{}, 1),
+	}
+
+	s.pending = []*LogEntry{{ID: "log_1"}, {ID: "log_2"}}
+	s.flush(context.Background())
+
+	if len(s.pending) != 2 {
+		t.Fatalf("expected 2 entries kept for retry, got %d", len(s.pending))
+	}
+
+	fail = false
+	s.flush(context.Background())
+
+	if len(written) != 2 || len(s.pending) != 0 {
+		t.Errorf("expected the retried entries to be written, got %d written and %d pending", len(written), len(s.pending))
+	}
+}
+
+func TestLogFilterMatches(t *testing.T) {
+	now := time.Now()
+	warn := int(slog.LevelWarn)
+
+	entry := &LogEntry{
+		Time:      now,
+		Level:     int(slog.LevelError),
+		Message:   "Failed to load tenant",
+		Component: LogComponentTenantNode,
+		NodeID:    "node-1",
+		TenantID:  "tenant-1",
+		RequestID: "req-1",
+		Data:      map[string]interface{}{LogKeyNodeID: "node-2"},
+	}
+
+	tests := []struct {
+		name    string
+		filter  LogFilter
+		matches bool
+	}{
+		{"empty", LogFilter{}, true},
+		{"min level", LogFilter{MinLevel: &warn}, true},
+		{"component", LogFilter{Component: LogComponentGateway}, false},
+		{"logging node", LogFilter{NodeID: "node-1"}, true},
+		{"node in data", LogFilter{NodeID: "node-2"}, true},
+		{"other node", LogFilter{NodeID: "node-3"}, false},
+		{"tenant", LogFilter{TenantID: "tenant-2"}, false},
+		{"request", LogFilter{RequestID: "req-1"}, true},
+		{"search", LogFilter{Search: "load TENANT"}, true},
+		{"since", LogFilter{Since: now.Add(time.Second)}, false},
+		{"until", LogFilter{Until: now}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Matches(entry); got != tt.matches {
+			t.Errorf("%s: expected match %v, got %v", tt.name, tt.matches, got)
+		}
+	}
+}
+
+func TestParseLogFilter(t *testing.T) {
+	filter, err := ParseLogFilter(url.Values{
+		"tenantId": {"tenant-1"},
+		"level":    {"warn"},
+		"since":    {"2026-01-02T15:04:05Z"},
+		"limit":    {"5000"},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+
+	if filter.TenantID != "tenant-1" || filter.MinLevel == nil || *filter.MinLevel != int(slog.LevelWarn) {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+	if !filter.Since.Equal(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected since %v", filter.Since)
+	}
+	if filter.Limit != MaxLogListLimit {
+		t.Errorf("expected the limit to be capped at %d, got %d", MaxLogListLimit, filter.Limit)
+	}
+
+	if filter, _ := ParseLogFilter(url.Values{}); filter.Limit != DefaultLogListLimit {
+		t.Errorf("expected the default limit %d, got %d", DefaultLogListLimit, filter.Limit)
+	}
+
+	for _, query := range []url.Values{
+		{"level": {"verbose"}},
+		{"since": {"yesterday"}},
+		{"until": {"1h"}},
+		{"limit": {"-1"}},
+	} {
+		if _, err := ParseLogFilter(query); err == nil {
+			t.Errorf("expected %v to be rejected", query)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProcessHeader carries the ProcessID of the process serving node metrics, so
+// that a federator skips nodes sharing its registry (all-in-one mode)
+const ProcessHeader = "X-Metrics-Process"
+
+// NodeIDLabel is the label added to every federated series
+const NodeIDLabel = "node_id"
+
+// federationTimeout bounds how long a node gets to serve its metrics
+const federationTimeout = 5 * time.Second
+
+// ProcessID identifies this process in ProcessHeader
+var ProcessID = newProcessID()
+
+func newProcessID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NodeHandler serves the metrics of this process to a federator
+func NodeHandler() http.Handler {
+	handler := promhttp.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ProcessHeader, ProcessID)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// FederationTarget is a node whose metrics are federated
+type FederationTarget struct {
+	NodeID string
+	URL    string // Metrics endpoint of the node
+}
+
+// Federator is a prometheus.Gatherer merging the metrics of this process with
+// the ones scraped from the cluster nodes. Every series is labelled with the
+// node it comes from, and pocketbase_enterprise_federation_up reports which
+// nodes could be scraped.
+type Federator struct {
+	local   prometheus.Gatherer
+	nodeID  string
+	targets func() []FederationTarget
+	client  *http.Client
+}
+
+// NewFederator creates a federator for the node nodeID, scraping the nodes
+// returned by targets on every gather
+func NewFederator(nodeID string, targets func() []FederationTarget) *Federator {
+	return &Federator{
+		local:   prometheus.DefaultGatherer,
+		nodeID:  nodeID,
+		targets: targets,
+		client:  &http.Client{Timeout: federationTimeout},
+	}
+}
+
+// Handler serves the federated metrics. Failing nodes don't fail the scrape.
+func (f *Federator) Handler() http.Handler {
+	return promhttp.HandlerFor(f, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// Gather implements prometheus.Gatherer
+func (f *Federator) Gather() ([]*dto.MetricFamily, error) {
+	local, err := f.local.Gather()
+
+	merged := make(map[string]*dto.MetricFamily)
+	mergeFamilies(merged, local, f.nodeID)
+
+	targets := f.targets()
+	scraped := make([][]*dto.MetricFamily, len(targets))
+	up := make([]bool, len(targets))
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target FederationTarget) {
+			defer wg.Done()
+			families, scrapeErr := f.scrape(target.URL)
+			up[i] = scrapeErr == nil
+			scraped[i] = families
+		}(i, target)
+	}
+	wg.Wait()
+
+	upFamily := &dto.MetricFamily{
+		Name: proto.String("pocketbase_enterprise_federation_up"),
+		Help: proto.String("Whether the metrics of a node could be scraped (1 = up, 0 = down)"),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	for i, target := range targets {
+		mergeFamilies(merged, scraped[i], target.NodeID)
+
+		value := 0.0
+		if up[i] {
+			value = 1
+		}
+		upFamily.Metric = append(upFamily.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{{Name: proto.String(NodeIDLabel), Value: proto.String(target.NodeID)}},
+			Gauge: &dto.Gauge{Value: proto.Float64(value)},
+		})
+	}
+	merged[upFamily.GetName()] = upFamily
+
+	families := make([]*dto.MetricFamily, 0, len(merged))
+	for _, family := range merged {
+		families = append(families, family)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].GetName() < families[j].GetName()
+	})
+
+	return families, err
+}
+
+// scrape fetches the metrics of a node. Nodes in this process return nothing,
+// their metrics are already gathered locally.
+func (f *Federator) scrape(url string) ([]*dto.MetricFamily, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), federationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(expfmt.FmtText))
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("node responded %d", resp.StatusCode)
+	}
+	if resp.Header.Get(ProcessHeader) == ProcessID {
+		return nil, nil
+	}
+
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	families := make([]*dto.MetricFamily, 0, len(parsed))
+	for _, family := range parsed {
+		families = append(families, family)
+	}
+	return families, nil
+}
+
+// mergeFamilies adds the metrics of families to merged, labelled with nodeID.
+// A family whose type differs from the one already merged is dropped.
+func mergeFamilies(merged map[string]*dto.MetricFamily, families []*dto.MetricFamily, nodeID string) {
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			addNodeLabel(metric, nodeID)
+		}
+
+		existing, ok := merged[family.GetName()]
+		if !ok {
+			merged[family.GetName()] = family
+			continue
+		}
+		if existing.GetType() == family.GetType() {
+			existing.Metric = append(existing.Metric, family.Metric...)
+		}
+	}
+}
+
+// addNodeLabel labels a metric with nodeID unless it already has a node label
+func addNodeLabel(metric *dto.Metric, nodeID string) {
+	for _, label := range metric.Label {
+		if label.GetName() == NodeIDLabel {
+			return
+		}
+	}
+
+	metric.Label = append(metric.Label, &dto.LabelPair{Name: proto.String(NodeIDLabel), Value: proto.String(nodeID)})
+	sort.Slice(metric.Label, func(i, j int) bool {
+		return metric.Label[i].GetName() < metric.Label[j].GetName()
+	})
+}
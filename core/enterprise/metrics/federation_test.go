@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTestRegistry returns a registry with a requests counter set to value
+func newTestRegistry(value float64) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_requests_total", Help: "Requests"})
+	counter.Add(value)
+	reg.MustRegister(counter)
+	return reg
+}
+
+func TestFederatorGather(t *testing.T) {
+	remote := httptest.NewServer(promhttp.HandlerFor(newTestRegistry(5), promhttp.HandlerOpts{}))
+	defer remote.Close()
+
+	// Nodes in this process are already in the local registry
+	local := newTestRegistry(2)
+	sameProcess := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ProcessHeader, ProcessID)
+		promhttp.HandlerFor(local, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}))
+	defer sameProcess.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	f := NewFederator("cp-1", func() []FederationTarget {
+		return []FederationTarget{
+			{NodeID: "node-1", URL: remote.URL},
+			{NodeID: "node-2", URL: sameProcess.URL},
+			{NodeID: "node-3", URL: down.URL},
+		}
+	})
+	f.local = local
+
+	expected := `
+# HELP pocketbase_enterprise_federation_up Whether the metrics of a node could be scraped (1 = up, 0 = down)
+# TYPE pocketbase_enterprise_federation_up gauge
+pocketbase_enterprise_federation_up{node_id="node-1"} 1
+pocketbase_enterprise_federation_up{node_id="node-2"} 1
+pocketbase_enterprise_federation_up{node_id="node-3"} 0
+# HELP test_requests_total Requests
+# TYPE test_requests_total counter
+test_requests_total{node_id="cp-1"} 2
+test_requests_total{node_id="node-1"} 5
+`
+	if err := testutil.GatherAndCompare(f, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNodeHandlerSetsProcessHeader(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NodeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_prometheus", nil))
+
+	if rec.Header().Get(ProcessHeader) != ProcessID {
+		t.Errorf("expected the process header, got %q", rec.Header().Get(ProcessHeader))
+	}
+}
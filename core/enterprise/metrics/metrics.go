@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -42,8 +44,28 @@ type Collector struct {
 	RequestCount          prometheus.Counter
 }
 
-// NewCollector creates a new metrics collector with all Prometheus metrics
+var (
+	collectorsMu sync.Mutex
+	collectors   = make(map[string]*Collector) // subsystem -> collector
+)
+
+// NewCollector returns the metrics collector of subsystem. The Prometheus metrics
+// are registered once, so nodes running in the same process share them.
 func NewCollector(subsystem string) *Collector {
+	collectorsMu.Lock()
+	defer collectorsMu.Unlock()
+
+	if collector, ok := collectors[subsystem]; ok {
+		return collector
+	}
+
+	collector := newCollector(subsystem)
+	collectors[subsystem] = collector
+	return collector
+}
+
+// newCollector creates a new metrics collector with all Prometheus metrics
+func newCollector(subsystem string) *Collector {
 	return &Collector{
 		// Tenant metrics
 		TenantsActive: promauto.NewGauge(prometheus.GaugeOpts{
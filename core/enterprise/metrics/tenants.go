@@ -0,0 +1,244 @@
+package metrics
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// OtherTenants is the tenant_id label of the tenants without their own series
+const OtherTenants = "_other"
+
+// DefaultTenantTopN is how many of the busiest tenants get their own series
+const DefaultTenantTopN = 20
+
+// TenantMetrics holds the per-tenant Prometheus metrics of a tenant node. To
+// bound cardinality only the busiest tenants and the allowlisted ones are
+// labelled with their ID, the others are folded into OtherTenants.
+type TenantMetrics struct {
+	RequestsTotal   *prometheus.CounterVec
+	RequestDuration *prometheus.HistogramVec
+	DatabaseBytes   *prometheus.GaugeVec
+
+	mu        sync.Mutex
+	topN      int
+	allowlist map[string]bool
+	labelled  map[string]bool    // tenants with their own series, besides the allowlisted ones
+	requests  map[string]int     // tenant -> requests since the last ranking
+	sizes     map[string]float64 // tenant -> database bytes
+}
+
+var (
+	tenantMetricsMu sync.Mutex
+	tenantMetrics   = make(map[string]*TenantMetrics) // subsystem -> metrics
+)
+
+// NewTenantMetrics returns the per-tenant metrics of subsystem. Like
+// NewCollector the metrics are registered once per process.
+func NewTenantMetrics(subsystem string) *TenantMetrics {
+	tenantMetricsMu.Lock()
+	defer tenantMetricsMu.Unlock()
+
+	if m, ok := tenantMetrics[subsystem]; ok {
+		return m
+	}
+
+	m := newTenantMetrics(prometheus.DefaultRegisterer, subsystem)
+	tenantMetrics[subsystem] = m
+	return m
+}
+
+// newTenantMetrics creates the per-tenant metrics registered with reg
+func newTenantMetrics(reg prometheus.Registerer, subsystem string) *TenantMetrics {
+	factory := promauto.With(reg)
+
+	return &TenantMetrics{
+		RequestsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pocketbase_enterprise",
+			Subsystem: subsystem,
+			Name:      "tenant_requests_total",
+			Help:      "Total number of tenant requests by status class",
+		}, []string{"tenant_id", "code"}),
+		RequestDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "pocketbase_enterprise",
+			Subsystem: subsystem,
+			Name:      "tenant_request_duration_seconds",
+			Help:      "Tenant request duration in seconds (realtime streams excluded)",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"tenant_id"}),
+		DatabaseBytes: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "pocketbase_enterprise",
+			Subsystem: subsystem,
+			Name:      "tenant_database_bytes",
+			Help:      "Size of the tenant databases in bytes",
+		}, []string{"tenant_id"}),
+		topN:      DefaultTenantTopN,
+		allowlist: make(map[string]bool),
+		labelled:  make(map[string]bool),
+		requests:  make(map[string]int),
+		sizes:     make(map[string]float64),
+	}
+}
+
+// Configure sets how many of the busiest tenants get their own series
+// (0 for DefaultTenantTopN, negative for none) and the tenants that always do
+func (m *TenantMetrics) Configure(topN int, allowlist []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if topN == 0 {
+		topN = DefaultTenantTopN
+	}
+	m.topN = topN
+
+	m.allowlist = make(map[string]bool, len(allowlist))
+	for _, tenantID := range allowlist {
+		m.allowlist[tenantID] = true
+	}
+
+	m.rankLocked()
+}
+
+// ObserveRequest records a served tenant request. Pass a zero duration for
+// requests whose latency is meaningless (realtime streams).
+func (m *TenantMetrics) ObserveRequest(tenantID string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[tenantID]++
+	label := m.labelLocked(tenantID)
+	m.RequestsTotal.WithLabelValues(label, statusClass(status)).Inc()
+	if duration > 0 {
+		m.RequestDuration.WithLabelValues(label).Observe(duration.Seconds())
+	}
+}
+
+// SetDatabaseSize records the size of the tenant databases
+func (m *TenantMetrics) SetDatabaseSize(tenantID string, bytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sizes[tenantID] = float64(bytes)
+	if m.allowlist[tenantID] || m.labelled[tenantID] {
+		m.DatabaseBytes.WithLabelValues(tenantID).Set(float64(bytes))
+		return
+	}
+	m.updateOtherSizeLocked()
+}
+
+// Forget removes the series of a tenant that left the node
+func (m *TenantMetrics) Forget(tenantID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.requests, tenantID)
+	delete(m.sizes, tenantID)
+	delete(m.labelled, tenantID)
+	m.deleteSeriesLocked(tenantID)
+	m.updateOtherSizeLocked()
+}
+
+// Rank gives their own series to the tenants with the most requests since
+// the previous ranking and folds the others into OtherTenants. Free slots are
+// kept by the tenants that had one, so idle periods don't churn series.
+func (m *TenantMetrics) Rank() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rankLocked()
+}
+
+// rankLocked re-ranks the tenants (must be called with mu held)
+func (m *TenantMetrics) rankLocked() {
+	candidates := make([]string, 0, len(m.requests)+len(m.labelled))
+	for tenantID, count := range m.requests {
+		if count > 0 && !m.labelled[tenantID] && !m.allowlist[tenantID] {
+			candidates = append(candidates, tenantID)
+		}
+	}
+	for tenantID := range m.labelled {
+		if !m.allowlist[tenantID] {
+			candidates = append(candidates, tenantID)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if m.requests[a] != m.requests[b] {
+			return m.requests[a] > m.requests[b]
+		}
+		if m.labelled[a] != m.labelled[b] {
+			return m.labelled[a]
+		}
+		return a < b
+	})
+	if len(candidates) > max(m.topN, 0) {
+		candidates = candidates[:max(m.topN, 0)]
+	}
+
+	labelled := make(map[string]bool, len(candidates))
+	for _, tenantID := range candidates {
+		labelled[tenantID] = true
+	}
+
+	// Demoted tenants lose their series, their next requests count as OtherTenants
+	for tenantID := range m.labelled {
+		if !labelled[tenantID] && !m.allowlist[tenantID] {
+			m.deleteSeriesLocked(tenantID)
+		}
+	}
+	m.labelled = labelled
+
+	for tenantID, size := range m.sizes {
+		if labelled[tenantID] || m.allowlist[tenantID] {
+			m.DatabaseBytes.WithLabelValues(tenantID).Set(size)
+		}
+	}
+	m.updateOtherSizeLocked()
+
+	m.requests = make(map[string]int, len(m.requests))
+}
+
+// labelLocked returns the tenant_id label of a tenant, handing out free top N
+// slots until the next ranking (must be called with mu held)
+func (m *TenantMetrics) labelLocked(tenantID string) string {
+	if m.allowlist[tenantID] || m.labelled[tenantID] {
+		return tenantID
+	}
+	if len(m.labelled) < m.topN {
+		m.labelled[tenantID] = true
+		return tenantID
+	}
+	return OtherTenants
+}
+
+// updateOtherSizeLocked sets the OtherTenants database size to the sum of the
+// tenants without their own series (must be called with mu held)
+func (m *TenantMetrics) updateOtherSizeLocked() {
+	var total float64
+	for tenantID, size := range m.sizes {
+		if !m.allowlist[tenantID] && !m.labelled[tenantID] {
+			total += size
+		}
+	}
+	m.DatabaseBytes.WithLabelValues(OtherTenants).Set(total)
+}
+
+// deleteSeriesLocked removes every series of a tenant (must be called with mu held)
+func (m *TenantMetrics) deleteSeriesLocked(tenantID string) {
+	labels := prometheus.Labels{"tenant_id": tenantID}
+	m.RequestsTotal.DeletePartialMatch(labels)
+	m.RequestDuration.DeletePartialMatch(labels)
+	m.DatabaseBytes.DeletePartialMatch(labels)
+}
+
+// statusClass returns the status code class used as the code label (e.g., "2xx")
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestTenantMetrics(topN int, allowlist ...string) *TenantMetrics {
+	m := newTenantMetrics(prometheus.NewRegistry(), "test")
+	m.Configure(topN, allowlist)
+	return m
+}
+
+func requests(m *TenantMetrics, tenantID, code string) float64 {
+	return testutil.ToFloat64(m.RequestsTotal.WithLabelValues(tenantID, code))
+}
+
+func TestTenantMetricsFoldsOtherTenants(t *testing.T) {
+	m := newTestTenantMetrics(2)
+
+	m.ObserveRequest("a", http.StatusOK, 10*time.Millisecond)
+	m.ObserveRequest("b", http.StatusNotFound, 10*time.Millisecond)
+	m.ObserveRequest("c", http.StatusInternalServerError, 10*time.Millisecond)
+	m.ObserveRequest("d", http.StatusOK, 10*time.Millisecond)
+
+	if requests(m, "a", "2xx") != 1 || requests(m, "b", "4xx") != 1 {
+		t.Errorf("expected the first tenants to get the free slots")
+	}
+	if requests(m, OtherTenants, "5xx") != 1 || requests(m, OtherTenants, "2xx") != 1 {
+		t.Errorf("expected the other tenants to be folded into %s", OtherTenants)
+	}
+	if n := testutil.CollectAndCount(m.RequestDuration); n != 3 {
+		t.Errorf("expected 3 latency series, got %d", n)
+	}
+}
+
+func TestTenantMetricsRank(t *testing.T) {
+	m := newTestTenantMetrics(1, "vip")
+
+	m.ObserveRequest("quiet", http.StatusOK, time.Millisecond)
+	for i := 0; i < 3; i++ {
+		m.ObserveRequest("busy", http.StatusOK, time.Millisecond)
+	}
+	m.ObserveRequest("vip", http.StatusOK, time.Millisecond)
+	m.SetDatabaseSize("quiet", 100)
+	m.SetDatabaseSize("busy", 200)
+
+	if requests(m, "quiet", "2xx") != 1 || requests(m, OtherTenants, "2xx") != 3 {
+		t.Fatalf("expected the first tenant to get the slot before ranking")
+	}
+	if v := testutil.ToFloat64(m.DatabaseBytes.WithLabelValues(OtherTenants)); v != 200 {
+		t.Errorf("expected the other tenants' size to be summed, got %v", v)
+	}
+
+	m.Rank()
+
+	if n := testutil.CollectAndCount(m.RequestsTotal); n != 2 {
+		t.Errorf("expected the demoted tenant's series to be removed, got %d series", n)
+	}
+	m.ObserveRequest("busy", http.StatusOK, time.Millisecond)
+	m.ObserveRequest("quiet", http.StatusOK, time.Millisecond)
+	if requests(m, "busy", "2xx") != 1 || requests(m, OtherTenants, "2xx") != 4 {
+		t.Errorf("expected the busiest tenant to be promoted")
+	}
+	if requests(m, "vip", "2xx") != 1 {
+		t.Errorf("expected the allowlisted tenant to keep its series")
+	}
+	if v := testutil.ToFloat64(m.DatabaseBytes.WithLabelValues("busy")); v != 200 {
+		t.Errorf("expected the promoted tenant's size, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.DatabaseBytes.WithLabelValues(OtherTenants)); v != 100 {
+		t.Errorf("expected the demoted tenant's size in %s, got %v", OtherTenants, v)
+	}
+
+	// A quiet period keeps the slot with the tenant that had it
+	m.Rank()
+	m.Rank()
+	m.ObserveRequest("busy", http.StatusOK, time.Millisecond)
+	if requests(m, "busy", "2xx") != 2 {
+		t.Errorf("expected the slot to be kept without traffic")
+	}
+}
+
+func TestTenantMetricsAllowlistOnly(t *testing.T) {
+	m := newTestTenantMetrics(-1, "vip")
+
+	m.ObserveRequest("vip", http.StatusOK, time.Millisecond)
+	m.ObserveRequest("a", 0, 0)
+
+	if requests(m, "vip", "2xx") != 1 || requests(m, OtherTenants, "unknown") != 1 {
+		t.Errorf("expected only the allowlisted tenant to get its own series")
+	}
+	if n := testutil.CollectAndCount(m.RequestDuration); n != 1 {
+		t.Errorf("expected requests without a duration to skip the histogram, got %d series", n)
+	}
+}
+
+func TestTenantMetricsForget(t *testing.T) {
+	m := newTestTenantMetrics(5)
+
+	m.ObserveRequest("a", http.StatusOK, time.Millisecond)
+	m.SetDatabaseSize("a", 10)
+	m.Forget("a")
+
+	expected := `
+# HELP pocketbase_enterprise_test_tenant_database_bytes Size of the tenant databases in bytes
+# TYPE pocketbase_enterprise_test_tenant_database_bytes gauge
+pocketbase_enterprise_test_tenant_database_bytes{tenant_id="_other"} 0
+`
+	if err := testutil.CollectAndCompare(m.DatabaseBytes, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(m.RequestsTotal); n != 0 {
+		t.Errorf("expected the tenant's requests to be removed, got %d series", n)
+	}
+}
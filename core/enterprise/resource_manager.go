@@ -3,7 +3,7 @@ package enterprise
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -55,14 +55,14 @@ type TenantResourceMetrics struct {
 
 // ResourceQuota defines limits for each tier
 type ResourceQuota struct {
-	Tier                TenantTier
-	MaxDatabaseMB       int64
-	MaxRequestsDaily    int64
-	MaxConcurrentConns  int
-	MaxMemoryMB         int64
-	MaxCPUPercent       float64
-	MaxQueryTimeMs      int64
-	PriorityClass       int // Higher = more priority in scheduling
+	Tier               TenantTier `json:"tier,omitempty"`
+	MaxDatabaseMB      int64      `json:"maxDatabaseMb,omitempty"`
+	MaxRequestsDaily   int64      `json:"maxRequestsDaily,omitempty"`
+	MaxConcurrentConns int        `json:"maxConcurrentConns,omitempty"`
+	MaxMemoryMB        int64      `json:"maxMemoryMb,omitempty"`
+	MaxCPUPercent      float64    `json:"maxCpuPercent,omitempty"`
+	MaxQueryTimeMs     int64      `json:"maxQueryTimeMs,omitempty"`
+	PriorityClass      int        `json:"priorityClass,omitempty"` // Higher = more priority in scheduling
 }
 
 // DefaultResourceQuotas returns standard quotas for each tier
@@ -135,7 +135,7 @@ type ResourceManager struct {
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 
-	logger *log.Logger
+	logger *slog.Logger
 }
 
 // NewResourceManager creates a new resource manager
@@ -147,13 +147,13 @@ func NewResourceManager() *ResourceManager {
 		quotas:  DefaultResourceQuotas,
 		ctx:     ctx,
 		cancel:  cancel,
-		logger:  log.Default(),
+		logger:  ComponentLogger(LogComponentTenantNode),
 	}
 }
 
 // Start begins monitoring
 func (rm *ResourceManager) Start() {
-	rm.logger.Printf("[ResourceManager] Starting resource monitoring")
+	rm.logger.Info("Starting resource monitoring")
 
 	rm.wg.Add(2)
 	go rm.monitorLoop()
@@ -162,7 +162,7 @@ func (rm *ResourceManager) Start() {
 
 // Stop stops monitoring
 func (rm *ResourceManager) Stop() {
-	rm.logger.Printf("[ResourceManager] Stopping resource manager")
+	rm.logger.Info("Stopping resource manager")
 	rm.cancel()
 	rm.wg.Wait()
 }
@@ -181,8 +181,7 @@ func (rm *ResourceManager) RecordMetrics(metrics *TenantResourceMetrics) {
 	// Update tier if needed
 	newTier := rm.calculateTier(metrics)
 	if newTier != metrics.Tier {
-		rm.logger.Printf("[ResourceManager] Tenant %s tier change: %s -> %s",
-			metrics.TenantID, metrics.Tier, newTier)
+		rm.logger.Info("Tenant tier change", "tenantId", metrics.TenantID, "oldTier", metrics.Tier, "newTier", newTier)
 
 		if rm.onTierUpgrade != nil {
 			rm.onTierUpgrade(metrics.TenantID, metrics.Tier, newTier)
@@ -199,6 +198,13 @@ func (rm *ResourceManager) GetMetrics(tenantID string) *TenantResourceMetrics {
 	return rm.metrics[tenantID]
 }
 
+// SetQuotas replaces the per-tier quotas, e.g. after a config reload
+func (rm *ResourceManager) SetQuotas(quotas map[TenantTier]*ResourceQuota) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.quotas = quotas
+}
+
 // GetQuota returns quota for a tier
 func (rm *ResourceManager) GetQuota(tier TenantTier) *ResourceQuota {
 	rm.mu.RLock()
@@ -240,8 +246,7 @@ func (rm *ResourceManager) checkQuotas(metrics *TenantResourceMetrics) {
 
 	// Check database size
 	if metrics.DatabaseSizeMB > quota.MaxDatabaseMB {
-		rm.logger.Printf("[ResourceManager] Tenant %s exceeded database quota: %d MB > %d MB",
-			metrics.TenantID, metrics.DatabaseSizeMB, quota.MaxDatabaseMB)
+		rm.logger.Warn("Tenant exceeded database quota", "tenantId", metrics.TenantID, "databaseSizeMB", metrics.DatabaseSizeMB, "maxDatabaseMB", quota.MaxDatabaseMB)
 
 		if rm.onQuotaExceeded != nil {
 			rm.onQuotaExceeded(metrics.TenantID, "database_size", metrics.DatabaseSizeMB, quota.MaxDatabaseMB)
@@ -250,8 +255,7 @@ func (rm *ResourceManager) checkQuotas(metrics *TenantResourceMetrics) {
 
 	// Check request quota
 	if metrics.RequestsLast24h > quota.MaxRequestsDaily {
-		rm.logger.Printf("[ResourceManager] Tenant %s exceeded request quota: %d > %d",
-			metrics.TenantID, metrics.RequestsLast24h, quota.MaxRequestsDaily)
+		rm.logger.Warn("Tenant exceeded request quota", "tenantId", metrics.TenantID, "requestsLast24h", metrics.RequestsLast24h, "maxRequestsDaily", quota.MaxRequestsDaily)
 
 		if rm.onQuotaExceeded != nil {
 			rm.onQuotaExceeded(metrics.TenantID, "daily_requests", metrics.RequestsLast24h, quota.MaxRequestsDaily)
@@ -260,8 +264,7 @@ func (rm *ResourceManager) checkQuotas(metrics *TenantResourceMetrics) {
 
 	// Check CPU usage
 	if metrics.CPUUsagePercent > quota.MaxCPUPercent {
-		rm.logger.Printf("[ResourceManager] Tenant %s exceeded CPU quota: %.2f%% > %.2f%%",
-			metrics.TenantID, metrics.CPUUsagePercent, quota.MaxCPUPercent)
+		rm.logger.Warn("Tenant exceeded CPU quota", "tenantId", metrics.TenantID, "cpuUsagePercent", metrics.CPUUsagePercent, "maxCPUPercent", quota.MaxCPUPercent)
 
 		if rm.onQuotaExceeded != nil {
 			rm.onQuotaExceeded(metrics.TenantID, "cpu_usage", int64(metrics.CPUUsagePercent), int64(quota.MaxCPUPercent))
@@ -312,7 +315,7 @@ func (rm *ResourceManager) detectHotspots() {
 
 		// Notify if newly detected hotspot
 		if metrics.IsHotspot && !wasHotspot {
-			rm.logger.Printf("[ResourceManager] Hotspot detected: %s (score: %.2f)", tenantID, hotspotScore)
+			rm.logger.Info("Hotspot detected", "tenantId", tenantID, "hotspotScore", hotspotScore)
 
 			if rm.onHotspotDetected != nil {
 				rm.onHotspotDetected(tenantID, metrics)
@@ -393,8 +396,7 @@ func (rm *ResourceManager) classifyTenants() {
 		}
 	}
 
-	rm.logger.Printf("[ResourceManager] Classification: %d tenants tracked, %d hotspots, %d spikes",
-		len(rm.metrics), hotspots, spikes)
+	rm.logger.Debug("Classification", "tenants", len(rm.metrics), "hotspots", hotspots, "spikes", spikes)
 }
 
 // SetCallbacks configures action callbacks
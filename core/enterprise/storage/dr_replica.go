@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/superfly/ltx"
+)
+
+// mirroredReplicaClient copies every LTX file written to the primary replica
+// client to a second, disaster recovery client. Litestream v0.5 supports a single
+// replica per database, so the second bucket has to be fed at the client level.
+//
+// Reads and listings only use the primary. DR failures are logged and never fail
+// replication: the primary bucket is the source of truth and the DR copy catches
+// up with the next files written.
+type mirroredReplicaClient struct {
+	litestream.ReplicaClient
+	dr     litestream.ReplicaClient
+	logger *slog.Logger
+}
+
+func newMirroredReplicaClient(primary, dr litestream.ReplicaClient, logger *slog.Logger) *mirroredReplicaClient {
+	return &mirroredReplicaClient{ReplicaClient: primary, dr: dr, logger: logger}
+}
+
+// WriteLTXFile streams an LTX file to the primary and the DR client at the same time
+func (c *mirroredReplicaClient) WriteLTXFile(ctx context.Context, level int, minTXID, maxTXID ltx.TXID, r io.Reader) (*ltx.FileInfo, error) {
+	pr, pw := io.Pipe()
+
+	drDone := make(chan error, 1)
+	go func() {
+		_, err := c.dr.WriteLTXFile(ctx, level, minTXID, maxTXID, pr)
+
+		// Keep draining so that a failed DR upload doesn't stall the primary
+		io.Copy(io.Discard, pr)
+		drDone <- err
+	}()
+
+	info, err := c.ReplicaClient.WriteLTXFile(ctx, level, minTXID, maxTXID, io.TeeReader(r, pw))
+	if err != nil {
+		pw.CloseWithError(err)
+	} else {
+		pw.Close()
+	}
+
+	if drErr := <-drDone; drErr != nil && err == nil {
+		c.logger.Warn("DR replica write failed", "level", level, "minTXId", minTXID, "maxTXId", maxTXID, "error", drErr)
+	}
+
+	return info, err
+}
+
+// DeleteLTXFiles deletes LTX files from both replicas
+func (c *mirroredReplicaClient) DeleteLTXFiles(ctx context.Context, a []*ltx.FileInfo) error {
+	if err := c.ReplicaClient.DeleteLTXFiles(ctx, a); err != nil {
+		return err
+	}
+
+	if err := c.dr.DeleteLTXFiles(ctx, a); err != nil {
+		c.logger.Warn("DR replica delete failed", "error", err)
+	}
+	return nil
+}
+
+// DeleteAll deletes all files from both replicas
+func (c *mirroredReplicaClient) DeleteAll(ctx context.Context) error {
+	if err := c.ReplicaClient.DeleteAll(ctx); err != nil {
+		return err
+	}
+
+	if err := c.dr.DeleteAll(ctx); err != nil {
+		c.logger.Warn("DR replica delete failed", "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/superfly/ltx"
+)
+
+// encryptedReplicaClient encrypts LTX files client-side before they reach the
+// wrapped replica client. Litestream v0.5 still has Replica.AgeRecipients but no
+// longer applies them, so encryption has to happen at the client level.
+//
+// Offsets passed to OpenLTXFile refer to the plaintext. Ranged reads download and
+// decrypt the file from the start, which is fine for restores (they read whole
+// files) but makes VFS page reads slow.
+type encryptedReplicaClient struct {
+	litestream.ReplicaClient
+	cipher *TenantCipher
+}
+
+func newEncryptedReplicaClient(client litestream.ReplicaClient, cipher *TenantCipher) *encryptedReplicaClient {
+	return &encryptedReplicaClient{ReplicaClient: client, cipher: cipher}
+}
+
+// OpenLTXFile opens and decrypts an LTX file
+func (c *encryptedReplicaClient) OpenLTXFile(ctx context.Context, level int, minTXID, maxTXID ltx.TXID, offset, size int64) (io.ReadCloser, error) {
+	rc, err := c.ReplicaClient.OpenLTXFile(ctx, level, minTXID, maxTXID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.cipher.DecryptReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+
+	if offset > 0 {
+		if _, err := io.CopyN(io.Discard, r, offset); err != nil {
+			rc.Close()
+			return nil, err
+		}
+	}
+	if size > 0 {
+		r = io.LimitReader(r, size)
+	}
+
+	return &readCloser{Reader: r, Closer: rc}, nil
+}
+
+// WriteLTXFile encrypts and writes an LTX file
+func (c *encryptedReplicaClient) WriteLTXFile(ctx context.Context, level int, minTXID, maxTXID ltx.TXID, r io.Reader) (*ltx.FileInfo, error) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		w, err := c.cipher.EncryptWriter(pw)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(w.Close())
+	}()
+
+	info, err := c.ReplicaClient.WriteLTXFile(ctx, level, minTXID, maxTXID, pr)
+
+	// Unblock the encrypting goroutine if the upload failed early
+	pr.CloseWithError(io.ErrClosedPipe)
+	return info, err
+}
+
+// readCloser combines a decrypting reader with the underlying body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// ageMagic starts every age-encrypted file. Objects without it were written before
+// the tenant had data keys and are read as plaintext.
+const ageMagic = "age-encryption.org/"
+
+// MasterKey wraps and unwraps tenant data keys. Only wrapped data keys are persisted,
+// and the master key itself never leaves the control plane.
+type MasterKey interface {
+	// ID identifies the key so wrapped data keys can be unwrapped after a master key rotation
+	ID() string
+
+	// Wrap encrypts a data key
+	Wrap(ctx context.Context, plaintext []byte) ([]byte, error)
+
+	// Unwrap decrypts a data key wrapped by this master key
+	Unwrap(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// KMSClient is the subset of a KMS API needed to use it as the master key
+// (e.g. AWS KMS Encrypt/Decrypt, GCP Cloud KMS or a Vault transit engine).
+type KMSClient interface {
+	Encrypt(ctx context.Context, keyID string, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+}
+
+// KMSMasterKey is a master key held by a KMS
+type KMSMasterKey struct {
+	client KMSClient
+	keyID  string
+}
+
+// NewKMSMasterKey creates a master key backed by a KMS key
+func NewKMSMasterKey(client KMSClient, keyID string) *KMSMasterKey {
+	return &KMSMasterKey{client: client, keyID: keyID}
+}
+
+// ID returns the KMS key ID
+func (k *KMSMasterKey) ID() string {
+	return "kms:" + k.keyID
+}
+
+// Wrap encrypts a data key with the KMS key
+func (k *KMSMasterKey) Wrap(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return k.client.Encrypt(ctx, k.keyID, plaintext)
+}
+
+// Unwrap decrypts a data key with the KMS key
+func (k *KMSMasterKey) Unwrap(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return k.client.Decrypt(ctx, k.keyID, ciphertext)
+}
+
+// LocalMasterKey is a master key stored in a local age identity file
+type LocalMasterKey struct {
+	identity *age.X25519Identity
+	id       string
+}
+
+// LoadLocalMasterKey reads an age identity file (as written by age-keygen). If the
+// file doesn't exist a new key is generated. Losing this file makes every encrypted
+// tenant unreadable, so it must be backed up separately from S3.
+func LoadLocalMasterKey(path string) (*LocalMasterKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return generateLocalMasterKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+
+	identities, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse master key file: %w", err)
+	}
+
+	identity, ok := identities[0].(*age.X25519Identity)
+	if !ok || len(identities) != 1 {
+		return nil, fmt.Errorf("master key file must contain exactly one X25519 identity")
+	}
+
+	return newLocalMasterKey(identity), nil
+}
+
+func generateLocalMasterKey(path string) (*LocalMasterKey, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create master key directory: %w", err)
+	}
+
+	content := fmt.Sprintf("# public key: %s\n%s\n", identity.Recipient(), identity)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write master key file: %w", err)
+	}
+
+	return newLocalMasterKey(identity), nil
+}
+
+func newLocalMasterKey(identity *age.X25519Identity) *LocalMasterKey {
+	sum := sha256.Sum256([]byte(identity.Recipient().String()))
+	return &LocalMasterKey{
+		identity: identity,
+		id:       "local:" + hex.EncodeToString(sum[:8]),
+	}
+}
+
+// ID returns a fingerprint of the public key
+func (k *LocalMasterKey) ID() string {
+	return k.id
+}
+
+// Wrap encrypts a data key to the master key
+func (k *LocalMasterKey) Wrap(ctx context.Context, plaintext []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, k.identity.Recipient())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unwrap decrypts a data key wrapped by this master key
+func (k *LocalMasterKey) Unwrap(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), k.identity)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// MasterKeyring holds the current master key and the previous ones still needed to
+// unwrap data keys that haven't been rewrapped yet.
+type MasterKeyring struct {
+	current  MasterKey
+	previous map[string]MasterKey
+}
+
+// NewMasterKeyring creates a keyring that wraps with current
+func NewMasterKeyring(current MasterKey, previous ...MasterKey) *MasterKeyring {
+	keyring := &MasterKeyring{
+		current:  current,
+		previous: make(map[string]MasterKey, len(previous)),
+	}
+	for _, key := range previous {
+		keyring.previous[key.ID()] = key
+	}
+	return keyring
+}
+
+// Current returns the master key used for wrapping
+func (k *MasterKeyring) Current() MasterKey {
+	return k.current
+}
+
+// Unwrap decrypts a data key with the master key it was wrapped by
+func (k *MasterKeyring) Unwrap(ctx context.Context, masterKeyID string, ciphertext []byte) ([]byte, error) {
+	if masterKeyID == k.current.ID() {
+		return k.current.Unwrap(ctx, ciphertext)
+	}
+	if key, ok := k.previous[masterKeyID]; ok {
+		return key.Unwrap(ctx, ciphertext)
+	}
+	return nil, fmt.Errorf("master key %s not configured", masterKeyID)
+}
+
+// GenerateDataKey creates a new tenant data key (an age X25519 identity)
+func GenerateDataKey() ([]byte, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(identity.String()), nil
+}
+
+// TenantCipher encrypts tenant objects to the active data key and decrypts objects
+// written with any of the tenant's data key versions.
+type TenantCipher struct {
+	recipient  age.Recipient
+	identities []age.Identity
+}
+
+// NewTenantCipher creates a cipher from a tenant's unwrapped data keys
+func NewTenantCipher(keys *enterprise.TenantDataKeys) (*TenantCipher, error) {
+	cipher := &TenantCipher{}
+
+	for _, key := range keys.Keys {
+		identity, err := age.ParseX25519Identity(key.Identity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data key version %d: %w", key.Version, err)
+		}
+
+		cipher.identities = append(cipher.identities, identity)
+		if key.Version == keys.ActiveVersion {
+			cipher.recipient = identity.Recipient()
+		}
+	}
+
+	if cipher.recipient == nil {
+		return nil, fmt.Errorf("active data key version %d missing", keys.ActiveVersion)
+	}
+
+	return cipher, nil
+}
+
+// EncryptWriter returns a writer encrypting to dst. Close must be called to flush the last chunk.
+func (c *TenantCipher) EncryptWriter(dst io.Writer) (io.WriteCloser, error) {
+	return age.Encrypt(dst, c.recipient)
+}
+
+// DecryptReader returns a reader decrypting src. Plaintext objects written before the
+// tenant had data keys are returned unchanged.
+func (c *TenantCipher) DecryptReader(src io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(src)
+
+	magic, err := br.Peek(len(ageMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, []byte(ageMagic)) {
+		return br, nil
+	}
+
+	return age.Decrypt(br, c.identities...)
+}
+
+// SettingsKey derives the 32 character key PocketBase uses to encrypt the tenant's
+// settings. It's derived from the first data key version so that rotations don't
+// make already encrypted settings unreadable.
+func SettingsKey(keys *enterprise.TenantDataKeys) string {
+	if len(keys.Keys) == 0 {
+		return ""
+	}
+
+	first := keys.Keys[0]
+	for _, key := range keys.Keys[1:] {
+		if key.Version < first.Version {
+			first = key
+		}
+	}
+
+	sum := sha256.Sum256([]byte("pocketbase-settings:" + strings.TrimSpace(first.Identity)))
+	return hex.EncodeToString(sum[:])[:32]
+}
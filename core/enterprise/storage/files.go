@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// ListTenantFiles lists the keys of a tenant's uploaded files under prefix,
+// relative to the tenant's storage directory
+func (s *S3Backend) ListTenantFiles(ctx context.Context, tenant *enterprise.Tenant, prefix string) ([]string, error) {
+	root := enterprise.GetS3FilesPrefix(tenant.ID)
+
+	var keys []string
+	err := s.listObjects(ctx, root+prefix, func(key string) error {
+		keys = append(keys, strings.TrimPrefix(key, root))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant files: %w", err)
+	}
+
+	return keys, nil
+}
+
+// UploadTenantFiles uploads files of the local storage directory dir, encrypted
+// with the tenant's data key
+func (s *S3Backend) UploadTenantFiles(ctx context.Context, tenant *enterprise.Tenant, dir string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	cipher, err := s.tenantCipher(ctx, tenant.ID)
+	if err != nil {
+		return err
+	}
+
+	root := enterprise.GetS3FilesPrefix(tenant.ID)
+	for _, key := range keys {
+		if err := s.uploadFile(ctx, root+key, filepath.Join(dir, filepath.FromSlash(key)), cipher); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *S3Backend) uploadFile(ctx context.Context, key, sourcePath string, cipher *TenantCipher) error {
+	file, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if cipher != nil {
+		encrypted, err := encryptToTempFile(file, cipher)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt file: %w", err)
+		}
+		defer os.Remove(encrypted.Name())
+		defer encrypted.Close()
+
+		file = encrypted
+	}
+
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   file,
+	})
+	return err
+}
+
+// DownloadTenantFiles downloads files into the local storage directory dir
+func (s *S3Backend) DownloadTenantFiles(ctx context.Context, tenant *enterprise.Tenant, dir string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	cipher, err := s.tenantCipher(ctx, tenant.ID)
+	if err != nil {
+		return err
+	}
+
+	root := enterprise.GetS3FilesPrefix(tenant.ID)
+	for _, key := range keys {
+		if err := s.downloadFile(ctx, root+key, filepath.Join(dir, filepath.FromSlash(key)), cipher); err != nil {
+			return fmt.Errorf("failed to download %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// downloadFile writes the object to a temporary file first, so that an
+// interrupted download never leaves a truncated file behind
+func (s *S3Backend) downloadFile(ctx context.Context, key, destPath string, cipher *TenantCipher) error {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	defer result.Body.Close()
+
+	var body io.Reader = result.Body
+	if cipher != nil {
+		if body, err = cipher.DecryptReader(body); err != nil {
+			return fmt.Errorf("failed to decrypt object: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(destPath), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), destPath)
+}
+
+// DeleteTenantFiles deletes uploaded files of a tenant
+func (s *S3Backend) DeleteTenantFiles(ctx context.Context, tenant *enterprise.Tenant, keys []string) error {
+	root := enterprise.GetS3FilesPrefix(tenant.ID)
+	for _, key := range keys {
+		_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(root + key),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// listObjects calls fn with the key of every object under prefix, across all
+// result pages
+func (s *S3Backend) listObjects(ctx context.Context, prefix string, fn func(key string) error) error {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, obj := range page.Contents {
+			if err := fn(aws.ToString(obj.Key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -3,18 +3,22 @@ package storage
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pocketbase/pocketbase/core/enterprise"
 )
 
 // GlacierLifecycleManager manages S3 lifecycle policies for archiving to Glacier
 type GlacierLifecycleManager struct {
 	client *s3.Client
 	bucket string
-	logger *log.Logger
+	logger *slog.Logger
 }
 
 // NewGlacierLifecycleManager creates a new Glacier lifecycle manager
@@ -22,13 +26,13 @@ func NewGlacierLifecycleManager(client *s3.Client, bucket string) *GlacierLifecy
 	return &GlacierLifecycleManager{
 		client: client,
 		bucket: bucket,
-		logger: log.Default(),
+		logger: enterprise.ComponentLogger(enterprise.LogComponentStorage),
 	}
 }
 
 // SetupTenantLifecyclePolicy creates lifecycle rules for a tenant's S3 prefix
 func (g *GlacierLifecycleManager) SetupTenantLifecyclePolicy(ctx context.Context, tenantPrefix string) error {
-	g.logger.Printf("[GlacierLifecycle] Setting up lifecycle policy for %s", tenantPrefix)
+	g.logger.Info("Setting up lifecycle policy", "tenantPrefix", tenantPrefix)
 
 	// Create lifecycle configuration for this tenant
 	lifecycleConfig := &types.BucketLifecycleConfiguration{
@@ -61,6 +65,21 @@ func (g *GlacierLifecycleManager) SetupTenantLifecyclePolicy(ctx context.Context
 					},
 				},
 			},
+			{
+				// Move uploaded files to Intelligent-Tiering after 30 days, the
+				// tenant node serves them from its local copy
+				ID:     aws.String(fmt.Sprintf("files-%s", tenantPrefix)),
+				Status: types.ExpirationStatusEnabled,
+				Filter: &types.LifecycleRuleFilter{
+					Prefix: aws.String(fmt.Sprintf("%sstorage/", tenantPrefix)),
+				},
+				Transitions: []types.Transition{
+					{
+						Days:         aws.Int32(30),
+						StorageClass: types.TransitionStorageClassIntelligentTiering,
+					},
+				},
+			},
 			{
 				// Delete old snapshots after 180 days
 				ID:     aws.String(fmt.Sprintf("cleanup-%s", tenantPrefix)),
@@ -88,13 +107,13 @@ func (g *GlacierLifecycleManager) SetupTenantLifecyclePolicy(ctx context.Context
 		return fmt.Errorf("failed to set lifecycle policy: %w", err)
 	}
 
-	g.logger.Printf("[GlacierLifecycle] Lifecycle policy configured for %s", tenantPrefix)
+	g.logger.Info("Lifecycle policy configured", "tenantPrefix", tenantPrefix)
 	return nil
 }
 
 // SetupGlobalLifecyclePolicy creates global lifecycle rules for the bucket
 func (g *GlacierLifecycleManager) SetupGlobalLifecyclePolicy(ctx context.Context) error {
-	g.logger.Printf("[GlacierLifecycle] Setting up global lifecycle policy")
+	g.logger.Info("Setting up global lifecycle policy")
 
 	lifecycleConfig := &types.BucketLifecycleConfiguration{
 		Rules: []types.LifecycleRule{
@@ -146,13 +165,13 @@ func (g *GlacierLifecycleManager) SetupGlobalLifecyclePolicy(ctx context.Context
 		return fmt.Errorf("failed to set global lifecycle policy: %w", err)
 	}
 
-	g.logger.Printf("[GlacierLifecycle] Global lifecycle policy configured")
+	g.logger.Info("Global lifecycle policy configured")
 	return nil
 }
 
 // RemoveTenantLifecyclePolicy removes lifecycle rules for a tenant
 func (g *GlacierLifecycleManager) RemoveTenantLifecyclePolicy(ctx context.Context, tenantPrefix string) error {
-	g.logger.Printf("[GlacierLifecycle] Removing lifecycle policy for %s", tenantPrefix)
+	g.logger.Info("Removing lifecycle policy", "tenantPrefix", tenantPrefix)
 
 	// Get current lifecycle configuration
 	result, err := g.client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
@@ -172,7 +191,8 @@ func (g *GlacierLifecycleManager) RemoveTenantLifecyclePolicy(ctx context.Contex
 			prefix := *rule.Filter.Prefix
 			if prefix == tenantPrefix ||
 			   prefix == fmt.Sprintf("%slitestream/", tenantPrefix) ||
-			   prefix == fmt.Sprintf("%ssnapshots/", tenantPrefix) {
+			   prefix == fmt.Sprintf("%ssnapshots/", tenantPrefix) ||
+			   prefix == fmt.Sprintf("%sstorage/", tenantPrefix) {
 				isMatch = true
 			}
 		}
@@ -201,26 +221,22 @@ func (g *GlacierLifecycleManager) RemoveTenantLifecyclePolicy(ctx context.Contex
 		return fmt.Errorf("failed to update lifecycle configuration: %w", err)
 	}
 
-	g.logger.Printf("[GlacierLifecycle] Lifecycle policy removed for %s", tenantPrefix)
+	g.logger.Info("Lifecycle policy removed", "tenantPrefix", tenantPrefix)
 	return nil
 }
 
 // TransitionToGlacier immediately transitions objects to Glacier storage class
 func (g *GlacierLifecycleManager) TransitionToGlacier(ctx context.Context, tenantPrefix string, storageClass types.StorageClass) error {
-	g.logger.Printf("[GlacierLifecycle] Transitioning %s to %s", tenantPrefix, storageClass)
+	g.logger.Info("Transitioning", "tenantPrefix", tenantPrefix, "storageClass", storageClass)
 
 	// List all objects with the tenant prefix
-	listResult, err := g.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-		Bucket: aws.String(g.bucket),
-		Prefix: aws.String(tenantPrefix),
-	})
-
+	objects, err := g.listObjects(ctx, tenantPrefix)
 	if err != nil {
 		return fmt.Errorf("failed to list objects: %w", err)
 	}
 
 	// Copy each object to the new storage class
-	for _, obj := range listResult.Contents {
+	for _, obj := range objects {
 		_, err := g.client.CopyObject(ctx, &s3.CopyObjectInput{
 			Bucket:       aws.String(g.bucket),
 			CopySource:   aws.String(fmt.Sprintf("%s/%s", g.bucket, *obj.Key)),
@@ -229,25 +245,21 @@ func (g *GlacierLifecycleManager) TransitionToGlacier(ctx context.Context, tenan
 		})
 
 		if err != nil {
-			g.logger.Printf("[GlacierLifecycle] Failed to transition %s: %v", *obj.Key, err)
+			g.logger.Error("Failed to transition", "key", *obj.Key, "error", err)
 			continue
 		}
 	}
 
-	g.logger.Printf("[GlacierLifecycle] Transitioned %d objects to %s", len(listResult.Contents), storageClass)
+	g.logger.Info("Transitioned objects", "objects", len(objects), "storageClass", storageClass)
 	return nil
 }
 
 // RestoreFromGlacier initiates Glacier restore for archived objects
 func (g *GlacierLifecycleManager) RestoreFromGlacier(ctx context.Context, tenantPrefix string, expedited bool) error {
-	g.logger.Printf("[GlacierLifecycle] Initiating restore for %s", tenantPrefix)
+	g.logger.Info("Initiating restore", "tenantPrefix", tenantPrefix)
 
 	// List all objects with the tenant prefix
-	listResult, err := g.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-		Bucket: aws.String(g.bucket),
-		Prefix: aws.String(tenantPrefix),
-	})
-
+	objects, err := g.listObjects(ctx, tenantPrefix)
 	if err != nil {
 		return fmt.Errorf("failed to list objects: %w", err)
 	}
@@ -259,34 +271,172 @@ func (g *GlacierLifecycleManager) RestoreFromGlacier(ctx context.Context, tenant
 	}
 
 	// Initiate restore for each object
-	for _, obj := range listResult.Contents {
+	for _, obj := range objects {
 		// Check if object is in Glacier storage class
 		if obj.StorageClass == types.ObjectStorageClassGlacier ||
 		   obj.StorageClass == types.ObjectStorageClassDeepArchive {
 
+			// Deep Archive does not support expedited retrieval
+			objTier := tier
+			if obj.StorageClass == types.ObjectStorageClassDeepArchive && objTier == types.TierExpedited {
+				objTier = types.TierStandard
+			}
+
 			_, err := g.client.RestoreObject(ctx, &s3.RestoreObjectInput{
 				Bucket: aws.String(g.bucket),
 				Key:    obj.Key,
 				RestoreRequest: &types.RestoreRequest{
 					Days: aws.Int32(7), // Keep restored for 7 days
 					GlacierJobParameters: &types.GlacierJobParameters{
-						Tier: tier,
+						Tier: objTier,
 					},
 				},
 			})
 
 			if err != nil {
-				g.logger.Printf("[GlacierLifecycle] Failed to restore %s: %v", *obj.Key, err)
+				g.logger.Error("Failed to restore", "key", *obj.Key, "error", err)
 				continue
 			}
 		}
 	}
 
 	if expedited {
-		g.logger.Printf("[GlacierLifecycle] Expedited restore initiated for %s (1-5 minutes)", tenantPrefix)
+		g.logger.Info("Expedited restore initiated (1-5 minutes)", "tenantPrefix", tenantPrefix)
 	} else {
-		g.logger.Printf("[GlacierLifecycle] Standard restore initiated for %s (3-5 hours)", tenantPrefix)
+		g.logger.Info("Standard restore initiated (3-5 hours)", "tenantPrefix", tenantPrefix)
 	}
 
 	return nil
 }
+
+// RestoreStatus summarizes the Glacier restore progress of a tenant prefix
+type RestoreStatus struct {
+	Total        int        // All objects under the prefix
+	Archived     int        // Objects in a Glacier storage class
+	DeepArchive  int        // Archived objects in Deep Archive (slowest retrieval)
+	NotRequested int        // Archived objects with no restore request
+	Ongoing      int        // Archived objects still thawing
+	Restored     int        // Archived objects with a readable temporary copy
+	ExpiresAt    *time.Time // Earliest expiry of the temporary copies
+}
+
+// Ready reports whether every object under the prefix can be read
+func (s *RestoreStatus) Ready() bool {
+	return s.NotRequested == 0 && s.Ongoing == 0
+}
+
+// Readable returns the number of objects that can currently be read
+func (s *RestoreStatus) Readable() int {
+	return s.Total - s.NotRequested - s.Ongoing
+}
+
+// GetRestoreStatus inspects the x-amz-restore header of every archived object
+// under the tenant prefix to determine how far a Glacier restore has progressed
+func (g *GlacierLifecycleManager) GetRestoreStatus(ctx context.Context, tenantPrefix string) (*RestoreStatus, error) {
+	objects, err := g.listObjects(ctx, tenantPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	status := &RestoreStatus{Total: len(objects)}
+
+	for _, obj := range objects {
+		if obj.StorageClass != types.ObjectStorageClassGlacier &&
+			obj.StorageClass != types.ObjectStorageClassDeepArchive {
+			continue
+		}
+		status.Archived++
+		if obj.StorageClass == types.ObjectStorageClassDeepArchive {
+			status.DeepArchive++
+		}
+
+		head, err := g.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(g.bucket),
+			Key:    obj.Key,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to head %s: %w", aws.ToString(obj.Key), err)
+		}
+
+		ongoing, expiry, requested := ParseRestoreHeader(aws.ToString(head.Restore))
+		switch {
+		case !requested:
+			status.NotRequested++
+		case ongoing:
+			status.Ongoing++
+		default:
+			status.Restored++
+			if expiry != nil && (status.ExpiresAt == nil || expiry.Before(*status.ExpiresAt)) {
+				status.ExpiresAt = expiry
+			}
+		}
+	}
+
+	return status, nil
+}
+
+// listObjects lists every object under the prefix, the replicas and uploaded
+// files of a tenant easily exceed a single result page
+func (g *GlacierLifecycleManager) listObjects(ctx context.Context, prefix string) ([]types.Object, error) {
+	paginator := s3.NewListObjectsV2Paginator(g.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(g.bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	var objects []types.Object
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, page.Contents...)
+	}
+
+	return objects, nil
+}
+
+// TransitionToStandard copies restored objects back to the STANDARD storage class
+// so they remain readable after the temporary Glacier copy expires
+func (g *GlacierLifecycleManager) TransitionToStandard(ctx context.Context, tenantPrefix string) error {
+	return g.TransitionToGlacier(ctx, tenantPrefix, types.StorageClassStandard)
+}
+
+// ParseRestoreHeader parses an x-amz-restore header value such as
+// `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`.
+// An empty header means no restore has been requested for the object.
+func ParseRestoreHeader(header string) (ongoing bool, expiry *time.Time, requested bool) {
+	if header == "" {
+		return false, nil, false
+	}
+
+	for _, part := range strings.Split(header, "\",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		value = strings.Trim(value, "\" ,")
+
+		switch key {
+		case "ongoing-request":
+			ongoing = value == "true"
+		case "expiry-date":
+			if t, err := http.ParseTime(value); err == nil {
+				expiry = &t
+			}
+		}
+	}
+
+	return ongoing, expiry, true
+}
+
+// EstimateRestoreDuration returns the typical time for a Glacier retrieval to complete.
+// Deep Archive has no expedited tier and takes up to 12 hours.
+func EstimateRestoreDuration(deepArchive bool, expedited bool) time.Duration {
+	if deepArchive {
+		return 12 * time.Hour
+	}
+	if expedited {
+		return 5 * time.Minute
+	}
+	return 5 * time.Hour
+}
@@ -2,25 +2,36 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/benbjohnson/litestream"
 	"github.com/benbjohnson/litestream/s3"
 	"github.com/pocketbase/pocketbase/core/enterprise"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// epochPruneInterval is how often a new replica is checked for a snapshot before
+// the replicas of earlier epochs are deleted
+const epochPruneInterval = 10 * time.Second
+
 // LitestreamManager manages Litestream replication for tenant databases
 type LitestreamManager struct {
 	config   *enterprise.ClusterConfig
+	storage  enterprise.RegionConfig  // Buckets of this node's region
 	replicas map[string]*replicaState // key: tenantID:dbName
 	mu       sync.RWMutex
-	logger   *log.Logger
+	logger   *slog.Logger
 }
 
 type replicaState struct {
@@ -31,17 +42,23 @@ type replicaState struct {
 	cancel   context.CancelFunc
 }
 
-// NewLitestreamManager creates a new Litestream manager
+// NewLitestreamManager creates a new Litestream manager replicating to the
+// buckets of the node's region
 func NewLitestreamManager(config *enterprise.ClusterConfig) *LitestreamManager {
+	storage, _ := config.RegionStorage(config.Region)
+
 	return &LitestreamManager{
 		config:   config,
+		storage:  storage,
 		replicas: make(map[string]*replicaState),
-		logger:   log.Default(),
+		logger:   enterprise.ComponentLogger(enterprise.LogComponentStorage),
 	}
 }
 
-// StartReplication starts Litestream replication for a tenant database
-func (m *LitestreamManager) StartReplication(tenantID string, dbPath string, dbName string) error {
+// StartReplication starts Litestream replication for a tenant database to the
+// replica of the node's lease epoch. If keys is not nil, replicated files are
+// encrypted with the active data key.
+func (m *LitestreamManager) StartReplication(tenantID string, dbPath string, dbName string, keys *enterprise.TenantDataKeys, epoch uint64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -49,11 +66,11 @@ func (m *LitestreamManager) StartReplication(tenantID string, dbPath string, dbN
 
 	// Check if already replicating
 	if _, exists := m.replicas[key]; exists {
-		m.logger.Printf("[Litestream] Already replicating %s/%s", tenantID, dbName)
+		m.logger.Debug("Already replicating", "tenantId", tenantID, "dbName", dbName)
 		return nil
 	}
 
-	m.logger.Printf("[Litestream] Starting replication for tenant %s, database %s at %s", tenantID, dbName, dbPath)
+	m.logger.Info("Starting replication", "tenantId", tenantID, "dbName", dbName, "dbPath", dbPath)
 
 	// Create Litestream DB
 	db := litestream.NewDB(dbPath)
@@ -69,27 +86,33 @@ func (m *LitestreamManager) StartReplication(tenantID string, dbPath string, dbN
 		Level: slog.LevelWarn, // Only show warnings and errors
 	}))
 
-	// Create S3 replica client
-	s3Client := s3.NewReplicaClient()
-	s3Client.AccessKeyID = m.config.S3AccessKeyID
-	s3Client.SecretAccessKey = m.config.S3SecretAccessKey
-	s3Client.Region = m.config.S3Region
-	s3Client.Bucket = m.config.S3Bucket
-	s3Client.Path = fmt.Sprintf("tenants/%s/litestream/%s", tenantID, dbName)
+	// Create S3 replica client, mirrored to the DR bucket if the region has one
+	ctx := context.Background()
+	path := replicaPath(tenantID, dbName, epoch)
 
-	if m.config.S3Endpoint != "" {
-		s3Client.Endpoint = m.config.S3Endpoint
-		s3Client.ForcePathStyle = true // Required for MinIO/LocalStack
+	s3Client, err := newS3ReplicaClient(ctx, m.storage.S3, path)
+	if err != nil {
+		return err
 	}
 
-	// Initialize S3 client
-	ctx := context.Background()
-	if err := s3Client.Init(ctx); err != nil {
-		return fmt.Errorf("failed to initialize S3 client: %w", err)
+	var client litestream.ReplicaClient = s3Client
+	var drClient *s3.ReplicaClient
+	if m.storage.LitestreamDR.Bucket != "" {
+		drClient, err = newS3ReplicaClient(ctx, m.storage.LitestreamDR, path)
+		if err != nil {
+			return fmt.Errorf("DR replica: %w", err)
+		}
+		client = newMirroredReplicaClient(s3Client, drClient, m.logger)
+	}
+
+	// Encryption wraps the mirror so that both buckets get the same ciphertext
+	replicaClient, err := m.replicaClient(client, keys)
+	if err != nil {
+		return err
 	}
 
 	// Create replica
-	replica := litestream.NewReplicaWithClient(db, s3Client)
+	replica := litestream.NewReplicaWithClient(db, replicaClient)
 
 	// Configure sync interval
 	if m.config.LitestreamReplicateSync {
@@ -113,10 +136,18 @@ func (m *LitestreamManager) StartReplication(tenantID string, dbPath string, dbN
 
 	go func() {
 		if err := replica.Start(ctx); err != nil && ctx.Err() == nil {
-			m.logger.Printf("[Litestream] Replica error for %s/%s: %v", tenantID, dbName, err)
+			m.logger.Error("Replica error", "tenantId", tenantID, "dbName", dbName, "error", err)
 		}
 	}()
 
+	// The replicas of earlier epochs are deleted once this one can be restored
+	if epoch > 0 {
+		go m.pruneEpochs(ctx, m.storage.S3, s3Client, tenantID, dbName, epoch)
+		if drClient != nil {
+			go m.pruneEpochs(ctx, m.storage.LitestreamDR, drClient, tenantID, dbName, epoch)
+		}
+	}
+
 	// Store state
 	m.replicas[key] = &replicaState{
 		tenantID: tenantID,
@@ -126,8 +157,7 @@ func (m *LitestreamManager) StartReplication(tenantID string, dbPath string, dbN
 		cancel:   cancel,
 	}
 
-	m.logger.Printf("[Litestream] Successfully started replication for %s/%s to s3://%s/%s",
-		tenantID, dbName, m.config.S3Bucket, s3Client.Path)
+	m.logger.Info("Successfully started replication", "tenantId", tenantID, "dbName", dbName, "bucket", m.storage.S3.Bucket, "path", path)
 
 	return nil
 }
@@ -144,12 +174,12 @@ func (m *LitestreamManager) StopReplication(tenantID string, dbName string) erro
 		return nil // Not replicating
 	}
 
-	m.logger.Printf("[Litestream] Stopping replication for tenant %s, database %s", tenantID, dbName)
+	m.logger.Info("Stopping replication", "tenantId", tenantID, "dbName", dbName)
 
 	// Stop the replica monitoring (soft stop to complete pending syncs)
 	if state.replica != nil {
 		if err := state.replica.Stop(false); err != nil {
-			m.logger.Printf("[Litestream] Error stopping replica: %v", err)
+			m.logger.Error("Error stopping replica", "error", err)
 		}
 	}
 
@@ -164,7 +194,7 @@ func (m *LitestreamManager) StopReplication(tenantID string, dbName string) erro
 
 	if state.replica != nil {
 		if err := state.replica.Sync(ctx); err != nil {
-			m.logger.Printf("[Litestream] Error during final sync: %v", err)
+			m.logger.Error("Error during final sync", "error", err)
 		}
 	}
 
@@ -174,14 +204,14 @@ func (m *LitestreamManager) StopReplication(tenantID string, dbName string) erro
 		defer closeCancel()
 
 		if err := state.db.Close(closeCtx); err != nil {
-			m.logger.Printf("[Litestream] Error closing db: %v", err)
+			m.logger.Error("Error closing db", "error", err)
 		}
 	}
 
 	// Remove from map
 	delete(m.replicas, key)
 
-	m.logger.Printf("[Litestream] Stopped replication for tenant %s, database %s", tenantID, dbName)
+	m.logger.Info("Stopped replication", "tenantId", tenantID, "dbName", dbName)
 	return nil
 }
 
@@ -204,70 +234,284 @@ func (m *LitestreamManager) StopAllReplications() error {
 			// Extract tenant ID and dbName from key
 			// Stop without holding lock
 			if err := m.StopReplication(state.tenantID, state.dbName); err != nil {
-				m.logger.Printf("[Litestream] Error stopping %s: %v", key, err)
+				m.logger.Error("Error stopping", "key", key, "error", err)
 			}
 		}
 	}
 
-	m.logger.Printf("[Litestream] Stopped all replications")
+	m.logger.Info("Stopped all replications")
 	return nil
 }
 
-// RestoreDatabase restores a database from S3 using Litestream
-func (m *LitestreamManager) RestoreDatabase(ctx context.Context, tenantID, dbName, destPath string) error {
-	m.logger.Printf("[Litestream] Restoring database %s/%s to %s", tenantID, dbName, destPath)
+// RestoreDatabase restores a database from S3 using Litestream, from the highest
+// lease epoch up to epoch that has a replica. If the primary bucket can't be
+// read or has no snapshots and the region has a DR bucket, the DR copy is
+// restored. The database is only created empty if neither bucket has snapshots.
+// keys must include every data key version the replica files were written with.
+func (m *LitestreamManager) RestoreDatabase(ctx context.Context, tenantID, dbName, destPath string, keys *enterprise.TenantDataKeys, epoch uint64) (err error) {
+	ctx, span := enterprise.StartSpan(ctx, "litestream.restore",
+		enterprise.AttrTenantID.String(tenantID), attribute.String("db.name", dbName))
+	defer func() { enterprise.EndSpan(span, err) }()
+
+	m.logger.Info("Restoring database", "tenantId", tenantID, "dbName", dbName, "destPath", destPath)
 
 	// Create destination directory
 	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Create S3 replica client (same config as replication)
-	s3Client := s3.NewReplicaClient()
-	s3Client.AccessKeyID = m.config.S3AccessKeyID
-	s3Client.SecretAccessKey = m.config.S3SecretAccessKey
-	s3Client.Region = m.config.S3Region
-	s3Client.Bucket = m.config.S3Bucket
-	s3Client.Path = fmt.Sprintf("tenants/%s/litestream/%s", tenantID, dbName)
+	err = m.restoreLatest(ctx, m.storage.S3, tenantID, dbName, destPath, keys, epoch)
+
+	// An empty primary is either a new database or a lost bucket, only the DR
+	// copy can tell them apart
+	if err != nil && m.storage.LitestreamDR.Bucket != "" {
+		if err == litestream.ErrNoSnapshots {
+			m.logger.Warn("No snapshots in primary bucket, trying DR bucket", "tenantId", tenantID, "dbName", dbName, "bucket", m.storage.S3.Bucket, "drBucket", m.storage.LitestreamDR.Bucket)
+		} else {
+			m.logger.Warn("Restore failed, trying DR bucket", "tenantId", tenantID, "dbName", dbName, "bucket", m.storage.S3.Bucket, "drBucket", m.storage.LitestreamDR.Bucket, "error", err)
+		}
+
+		drErr := m.restoreLatest(ctx, m.storage.LitestreamDR, tenantID, dbName, destPath, keys, epoch)
+		if drErr == nil {
+			m.logger.Info("Restored from DR bucket", "tenantId", tenantID, "dbName", dbName, "drBucket", m.storage.LitestreamDR.Bucket)
+			span.SetAttributes(attribute.String("litestream.source", "dr"))
+			return nil
+		}
+		if err == litestream.ErrNoSnapshots && drErr != litestream.ErrNoSnapshots {
+			return fmt.Errorf("no snapshots in primary bucket and failed to read DR bucket: %w", drErr)
+		}
+	}
+
+	if err == litestream.ErrNoSnapshots {
+		m.logger.Info("No snapshots found (new database)", "tenantId", tenantID, "dbName", dbName)
+		span.SetAttributes(attribute.String("litestream.source", "empty"))
+		// Create empty database file
+		if _, err := os.Create(destPath); err != nil {
+			return fmt.Errorf("failed to create empty database: %w", err)
+		}
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to restore from S3: %w", err)
+	}
+
+	m.logger.Info("Successfully restored from S3", "tenantId", tenantID, "dbName", dbName)
+	span.SetAttributes(attribute.String("litestream.source", "primary"))
+	return nil
+}
+
+// restoreLatest restores the replica of the highest epoch up to epoch that has
+// snapshots. Holders start replicating to their own epoch right after loading,
+// so empty epochs are the ones of holders that never got that far. Only the
+// epochs listed in the bucket are tried, earlier ones are pruned.
+func (m *LitestreamManager) restoreLatest(ctx context.Context, target enterprise.S3Target, tenantID, dbName, destPath string, keys *enterprise.TenantDataKeys, epoch uint64) error {
+	backend, err := NewS3Backend(ctx, target.Endpoint, target.Region, target.Bucket, target.AccessKeyID, target.SecretAccessKey)
+	if err != nil {
+		return err
+	}
+
+	epochs, err := listReplicaEpochs(ctx, backend, tenantID)
+	if err != nil {
+		return err
+	}
+
+	// Highest first, down to the replica of tenants that predate leases
+	epochs = slices.DeleteFunc(epochs, func(e uint64) bool { return e > epoch || e == 0 })
+	slices.Sort(epochs)
+	slices.Reverse(epochs)
+
+	for _, e := range append(epochs, 0) {
+		err := m.restoreFrom(ctx, target, tenantID, dbName, destPath, keys, e)
+		if !errors.Is(err, litestream.ErrNoSnapshots) {
+			return err
+		}
+	}
+
+	return litestream.ErrNoSnapshots
+}
 
-	if m.config.S3Endpoint != "" {
-		s3Client.Endpoint = m.config.S3Endpoint
-		s3Client.ForcePathStyle = true
+// restoreFrom restores the latest state of a database replicated to target in epoch
+func (m *LitestreamManager) restoreFrom(ctx context.Context, target enterprise.S3Target, tenantID, dbName, destPath string, keys *enterprise.TenantDataKeys, epoch uint64) error {
+	s3Client, err := newS3ReplicaClient(ctx, target, replicaPath(tenantID, dbName, epoch))
+	if err != nil {
+		return err
 	}
 
-	// Initialize S3 client
-	if err := s3Client.Init(ctx); err != nil {
-		return fmt.Errorf("failed to initialize S3 client: %w", err)
+	replicaClient, err := m.replicaClient(s3Client, keys)
+	if err != nil {
+		return err
 	}
 
 	// Create a temporary DB for restore
 	db := litestream.NewDB(destPath)
-	replica := litestream.NewReplicaWithClient(db, s3Client)
+	replica := litestream.NewReplicaWithClient(db, replicaClient)
+
+	// Nothing has been replicated for a new database
+	plan, err := litestream.CalcRestorePlan(ctx, replicaClient, 0, time.Time{}, m.logger)
+	if errors.Is(err, litestream.ErrTxNotAvailable) || (err == nil && len(plan) == 0) {
+		return litestream.ErrNoSnapshots
+	}
+	if err != nil {
+		return fmt.Errorf("failed to plan restore: %w", err)
+	}
 
-	// Configure restore options
+	// The replica is the source of truth: a copy left by an earlier load on this
+	// node may be stale, and Litestream doesn't restore over existing files
+	if err := removeLocalDatabase(db); err != nil {
+		return err
+	}
+
+	// Configure restore options (restores the latest point in time)
 	opt := litestream.NewRestoreOptions()
-	// Restore to latest point in time
-	opt.Timestamp = time.Now()
+	opt.OutputPath = destPath
 	opt.Parallelism = 4 // Parallel restore for speed
 
-	// Perform restore
-	if err := replica.Restore(ctx, opt); err != nil {
-		// Check if error is "no snapshots" which means this is a new database
-		if err == litestream.ErrNoSnapshots {
-			m.logger.Printf("[Litestream] No snapshots found for %s/%s (new database)", tenantID, dbName)
-			// Create empty database file
-			if _, err := os.Create(destPath); err != nil {
-				return fmt.Errorf("failed to create empty database: %w", err)
-			}
-			return nil
+	return replica.Restore(ctx, opt)
+}
+
+// removeLocalDatabase removes a database file along with its WAL and Litestream metadata
+func removeLocalDatabase(db *litestream.DB) error {
+	for _, path := range []string{db.Path(), db.Path() + "-wal", db.Path() + "-shm", db.Path() + ".tmp"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale database: %w", err)
 		}
-		return fmt.Errorf("failed to restore from S3: %w", err)
 	}
 
-	m.logger.Printf("[Litestream] Successfully restored %s/%s from S3", tenantID, dbName)
+	if err := os.RemoveAll(db.MetaPath()); err != nil {
+		return fmt.Errorf("failed to remove stale Litestream metadata: %w", err)
+	}
 	return nil
 }
 
+// replicaPath returns the key prefix a tenant database is replicated under by
+// the holder of a lease epoch. Epoch 0 is the path from before leases.
+func replicaPath(tenantID, dbName string, epoch uint64) string {
+	if epoch == 0 {
+		return fmt.Sprintf("tenants/%s/litestream/%s", tenantID, dbName)
+	}
+	return fmt.Sprintf("tenants/%s/litestream/epochs/%d/%s", tenantID, epoch, dbName)
+}
+
+// pruneEpochs deletes the replicas of a database from the lease epochs before
+// epoch in target, once client (the replica of epoch in target) has a snapshot.
+// Every move or failover replicates the whole database to a new epoch, and
+// restores never read an older epoch than the latest with snapshots.
+func (m *LitestreamManager) pruneEpochs(ctx context.Context, target enterprise.S3Target, client litestream.ReplicaClient, tenantID, dbName string, epoch uint64) {
+	ticker := time.NewTicker(epochPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		plan, err := litestream.CalcRestorePlan(ctx, client, 0, time.Time{}, m.logger)
+		if err == nil && len(plan) > 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	if err := m.deleteEpochsBefore(ctx, target, tenantID, dbName, epoch); err != nil && ctx.Err() == nil {
+		m.logger.Warn("Failed to delete replicas of earlier epochs", "tenantId", tenantID, "dbName", dbName, "bucket", target.Bucket, "epoch", epoch, "error", err)
+	}
+}
+
+// deleteEpochsBefore deletes the replicas of a database from the lease epochs
+// before epoch in target, including the path from before leases
+func (m *LitestreamManager) deleteEpochsBefore(ctx context.Context, target enterprise.S3Target, tenantID, dbName string, epoch uint64) error {
+	backend, err := NewS3Backend(ctx, target.Endpoint, target.Region, target.Bucket, target.AccessKeyID, target.SecretAccessKey)
+	if err != nil {
+		return err
+	}
+
+	epochs, err := listReplicaEpochs(ctx, backend, tenantID)
+	if err != nil {
+		return err
+	}
+
+	for _, old := range append(epochs, 0) {
+		if old >= epoch {
+			continue
+		}
+
+		client, err := newS3ReplicaClient(ctx, target, replicaPath(tenantID, dbName, old))
+		if err != nil {
+			return err
+		}
+		if err := client.DeleteAll(ctx); err != nil {
+			return fmt.Errorf("failed to delete replica of epoch %d: %w", old, err)
+		}
+
+		m.logger.Debug("Deleted replica of earlier epoch", "tenantId", tenantID, "dbName", dbName, "bucket", target.Bucket, "epoch", old)
+	}
+
+	return nil
+}
+
+// listReplicaEpochs returns the lease epochs with replicas of a tenant's databases
+func listReplicaEpochs(ctx context.Context, backend *S3Backend, tenantID string) ([]uint64, error) {
+	prefix := fmt.Sprintf("tenants/%s/litestream/epochs/", tenantID)
+
+	var epochs []uint64
+	paginator := awss3.NewListObjectsV2Paginator(backend.client, &awss3.ListObjectsV2Input{
+		Bucket:    aws.String(backend.bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list replica epochs: %w", err)
+		}
+		for _, common := range page.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(common.Prefix), prefix), "/")
+			if epoch, err := strconv.ParseUint(name, 10, 64); err == nil {
+				epochs = append(epochs, epoch)
+			}
+		}
+	}
+
+	return epochs, nil
+}
+
+// newS3ReplicaClient creates an initialized Litestream S3 client for a bucket
+func newS3ReplicaClient(ctx context.Context, target enterprise.S3Target, path string) (*s3.ReplicaClient, error) {
+	client := s3.NewReplicaClient()
+	client.AccessKeyID = target.AccessKeyID
+	client.SecretAccessKey = target.SecretAccessKey
+	client.Region = target.Region
+	client.Bucket = target.Bucket
+	client.Path = path
+
+	if target.Endpoint != "" {
+		client.Endpoint = target.Endpoint
+		client.ForcePathStyle = true // Required for MinIO/LocalStack
+	}
+
+	if err := client.Init(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize S3 client: %w", err)
+	}
+
+	return client, nil
+}
+
+// replicaClient wraps the S3 replica client with client-side encryption when the tenant has data keys
+func (m *LitestreamManager) replicaClient(client litestream.ReplicaClient, keys *enterprise.TenantDataKeys) (litestream.ReplicaClient, error) {
+	if keys == nil {
+		return client, nil
+	}
+
+	cipher, err := NewTenantCipher(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant data keys: %w", err)
+	}
+
+	return newEncryptedReplicaClient(client, cipher), nil
+}
+
 // GetReplicationStats returns replication statistics for a tenant database
 func (m *LitestreamManager) GetReplicationStats(tenantID string, dbName string) (map[string]interface{}, error) {
 	m.mu.RLock()
@@ -327,13 +571,13 @@ func (m *LitestreamManager) SyncNow(ctx context.Context, tenantID string, dbName
 		return fmt.Errorf("replica not initialized for %s/%s", tenantID, dbName)
 	}
 
-	m.logger.Printf("[Litestream] Forcing sync for %s/%s", tenantID, dbName)
+	m.logger.Info("Forcing sync", "tenantId", tenantID, "dbName", dbName)
 
 	if err := state.replica.Sync(ctx); err != nil {
 		return fmt.Errorf("sync failed: %w", err)
 	}
 
-	m.logger.Printf("[Litestream] Sync completed for %s/%s", tenantID, dbName)
+	m.logger.Info("Sync completed", "tenantId", tenantID, "dbName", dbName)
 	return nil
 }
 
@@ -18,6 +18,9 @@ import (
 type S3Backend struct {
 	client *s3.Client
 	bucket string
+
+	// Tenant data keys for client-side encryption (uploads are plaintext if nil)
+	keys enterprise.TenantKeySource
 }
 
 // NewS3Backend creates a new S3 storage backend
@@ -64,6 +67,38 @@ func NewS3Backend(ctx context.Context, endpoint, region, bucket, accessKeyID, se
 	}, nil
 }
 
+// SetKeySource enables client-side encryption of tenant databases with the tenants' data keys
+func (s *S3Backend) SetKeySource(keys enterprise.TenantKeySource) {
+	s.keys = keys
+}
+
+// tenantCipher returns the cipher for a tenant, or nil if the tenant's data isn't encrypted
+func (s *S3Backend) tenantCipher(ctx context.Context, tenantID string) (*TenantCipher, error) {
+	if s.keys == nil {
+		return nil, nil
+	}
+
+	keys, err := s.keys.GetTenantKeys(ctx, tenantID)
+	if err == enterprise.ErrTenantKeysNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant data keys: %w", err)
+	}
+
+	return NewTenantCipher(keys)
+}
+
+// Client returns the underlying S3 client
+func (s *S3Backend) Client() *s3.Client {
+	return s.client
+}
+
+// Bucket returns the bucket tenant data is stored in
+func (s *S3Backend) Bucket() string {
+	return s.bucket
+}
+
 // DownloadTenantDB downloads a tenant database from S3
 func (s *S3Backend) DownloadTenantDB(ctx context.Context, tenant *enterprise.Tenant, dbName string, destPath string) error {
 	// S3 key: tenants/tenant_xxx/litestream/data.db/generations/[hash]/snapshots/[hash]/snapshot.db
@@ -94,6 +129,11 @@ func (s *S3Backend) DownloadTenantDB(ctx context.Context, tenant *enterprise.Ten
 		return nil
 	}
 
+	cipher, err := s.tenantCipher(ctx, tenant.ID)
+	if err != nil {
+		return err
+	}
+
 	// Download from S3
 	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
@@ -116,8 +156,15 @@ func (s *S3Backend) DownloadTenantDB(ctx context.Context, tenant *enterprise.Ten
 	}
 	defer file.Close()
 
+	var body io.Reader = result.Body
+	if cipher != nil {
+		if body, err = cipher.DecryptReader(body); err != nil {
+			return fmt.Errorf("failed to decrypt object: %w", err)
+		}
+	}
+
 	// Copy from S3 to file
-	_, err = io.Copy(file, result.Body)
+	_, err = io.Copy(file, body)
 	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
@@ -129,6 +176,11 @@ func (s *S3Backend) DownloadTenantDB(ctx context.Context, tenant *enterprise.Ten
 func (s *S3Backend) UploadTenantDB(ctx context.Context, tenant *enterprise.Tenant, dbName string, sourcePath string) error {
 	key := fmt.Sprintf("%s%s", tenant.S3Prefix, dbName)
 
+	cipher, err := s.tenantCipher(ctx, tenant.ID)
+	if err != nil {
+		return err
+	}
+
 	// Open source file
 	file, err := os.Open(sourcePath)
 	if err != nil {
@@ -136,6 +188,17 @@ func (s *S3Backend) UploadTenantDB(ctx context.Context, tenant *enterprise.Tenan
 	}
 	defer file.Close()
 
+	if cipher != nil {
+		encrypted, err := encryptToTempFile(file, cipher)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt database: %w", err)
+		}
+		defer os.Remove(encrypted.Name())
+		defer encrypted.Close()
+
+		file = encrypted
+	}
+
 	// Upload to S3
 	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(s.bucket),
@@ -149,25 +212,51 @@ func (s *S3Backend) UploadTenantDB(ctx context.Context, tenant *enterprise.Tenan
 	return nil
 }
 
-// DeleteTenantData removes all tenant data from S3
+// encryptToTempFile encrypts src into a temporary file, since S3 uploads need a seekable body
+func encryptToTempFile(src io.Reader, cipher *TenantCipher) (*os.File, error) {
+	tmp, err := os.CreateTemp("", "pb-upload-*.age")
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := cipher.EncryptWriter(tmp)
+	if err == nil {
+		if _, err = io.Copy(w, src); err == nil {
+			err = w.Close()
+		}
+	}
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return tmp, nil
+}
+
+// DeleteTenantData removes all tenant data from S3, replicas and uploaded files alike
 func (s *S3Backend) DeleteTenantData(ctx context.Context, tenant *enterprise.Tenant) error {
-	// List all objects with the tenant prefix
-	result, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-		Bucket: aws.String(s.bucket),
-		Prefix: aws.String(tenant.S3Prefix),
+	// Collect the keys first, deleting while paginating would skip objects
+	var keys []string
+	err := s.listObjects(ctx, tenant.S3Prefix, func(key string) error {
+		keys = append(keys, key)
+		return nil
 	})
 	if err != nil {
 		return fmt.Errorf("failed to list objects: %w", err)
 	}
 
 	// Delete all objects
-	for _, obj := range result.Contents {
+	for _, key := range keys {
 		_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 			Bucket: aws.String(s.bucket),
-			Key:    obj.Key,
+			Key:    aws.String(key),
 		})
 		if err != nil {
-			return fmt.Errorf("failed to delete object %s: %w", *obj.Key, err)
+			return fmt.Errorf("failed to delete object %s: %w", key, err)
 		}
 	}
 
@@ -8,6 +8,11 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 )
 
+// TenantRefusedHeader marks tenant node responses refusing a tenant because of
+// its state (suspended, archived, moved or leased to another node), which the
+// gateway's cached lookup of the tenant may not show yet
+const TenantRefusedHeader = "X-Tenant-Refused"
+
 // TenantInstance represents a running tenant PocketBase instance
 type TenantInstance struct {
 	Tenant      *Tenant      // Tenant metadata
@@ -22,6 +27,12 @@ type TenantInstance struct {
 	// Litestream
 	LitestreamRunning bool
 	LastReplication   time.Time
+
+	// Lease the tenant is served under. The node stops serving at LeaseValidUntil,
+	// which it measures from before asking for the lease, so it never serves
+	// past the expiry the control plane sees.
+	LeaseEpoch      uint64
+	LeaseValidUntil time.Time
 }
 
 // TenantManager defines the interface for managing tenant lifecycle
@@ -56,11 +67,68 @@ type ControlPlaneClient interface {
 	// RegisterNode registers a tenant node with the control plane
 	RegisterNode(ctx context.Context, nodeInfo *NodeInfo) error
 
-	// SendHeartbeat sends a heartbeat from this node
-	SendHeartbeat(ctx context.Context, nodeID string, activeTenantsCount int) error
+	// SendHeartbeat sends a heartbeat from this node, renewing the leases of its
+	// loaded tenants. It returns the node status as the control plane sees it
+	// (NodeStatusDraining asks the node to drain) and the renewed leases; the
+	// leases left out are lost.
+	SendHeartbeat(ctx context.Context, nodeID string, activeTenantsCount int, leases []*TenantLease) (string, []*TenantLease, error)
+
+	// AcquireTenantLease grants this node the lease of a tenant, or returns
+	// ErrTenantLeaseHeld while another node holds it
+	AcquireTenantLease(ctx context.Context, tenantID, nodeID string) (*TenantLease, error)
+
+	// ReleaseTenantLease gives up a lease after the tenant is unloaded and synced
+	ReleaseTenantLease(ctx context.Context, lease *TenantLease) error
+
+	// DrainNode marks this node draining and releases its tenants that aren't loaded
+	DrainNode(ctx context.Context, nodeID string, loadedTenants []string) error
+
+	// ReleaseTenant hands a tenant unloaded by a draining node back for placement elsewhere
+	ReleaseTenant(ctx context.Context, tenantID, nodeID string) error
+
+	// GetTenantHandoffs returns the tenants of a node an admin is moving to another node
+	GetTenantHandoffs(ctx context.Context, nodeID string) ([]string, error)
 
 	// GetPlacementDecision requests placement decision for a tenant
 	GetPlacementDecision(ctx context.Context, tenantID string) (*PlacementDecision, error)
+
+	// RequestTenantRestore starts restoring an archived tenant, or returns the job already in flight
+	RequestTenantRestore(ctx context.Context, tenantID string, expedited bool) (*RestoreJob, error)
+
+	// RegisterGateway registers (or heartbeats) a gateway and returns the live gateways
+	RegisterGateway(ctx context.Context, gateway *GatewayInfo) ([]*GatewayInfo, error)
+
+	// CheckpointUsage persists cluster-wide daily request counts
+	CheckpointUsage(ctx context.Context, checkpoints []*UsageCheckpoint) error
+
+	// GetUsageCheckpoint returns a tenant's last persisted daily request count
+	GetUsageCheckpoint(ctx context.Context, tenantID string) (*UsageCheckpoint, error)
+
+	// AppendLogs ships a batch of log entries to the control plane log store
+	AppendLogs(ctx context.Context, entries []*LogEntry) error
+
+	// GetFleetMigrations returns the running fleet migrations each tenant hasn't applied yet
+	GetFleetMigrations(ctx context.Context, tenantIDs []string) (map[string][]*FleetMigration, error)
+
+	// GetFleetBatch returns the tenants eager fleet migrations scheduled on a node
+	GetFleetBatch(ctx context.Context, nodeID string) ([]string, error)
+
+	// ReportFleetMigration records whether a tenant applied a fleet migration
+	ReportFleetMigration(ctx context.Context, result *FleetMigrationTenant) error
+
+	// UseSSOToken marks a tenant SSO token used until it expires, or returns
+	// ErrSSOTokenUsed if it already was
+	UseSSOToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+
+	// GetTenantKeys returns the data keys of a tenant whose lease nodeID holds,
+	// or ErrTenantKeysNotFound for tenants created before encryption was enabled
+	GetTenantKeys(ctx context.Context, tenantID, nodeID string) (*TenantDataKeys, error)
+}
+
+// TenantKeySource provides the unwrapped data keys tenant data is encrypted with
+type TenantKeySource interface {
+	// GetTenantKeys returns ErrTenantKeysNotFound for tenants created before encryption was enabled
+	GetTenantKeys(ctx context.Context, tenantID string) (*TenantDataKeys, error)
 }
 
 // PlacementStrategy defines the interface for tenant placement algorithms
@@ -87,6 +155,19 @@ type StorageBackend interface {
 	// DeleteTenantData removes all tenant data from S3
 	DeleteTenantData(ctx context.Context, tenant *Tenant) error
 
+	// ListTenantFiles lists the keys of the tenant's uploaded files under prefix,
+	// relative to the tenant's storage directory
+	ListTenantFiles(ctx context.Context, tenant *Tenant, prefix string) ([]string, error)
+
+	// UploadTenantFiles uploads files of the local storage directory dir
+	UploadTenantFiles(ctx context.Context, tenant *Tenant, dir string, keys []string) error
+
+	// DownloadTenantFiles downloads files into the local storage directory dir
+	DownloadTenantFiles(ctx context.Context, tenant *Tenant, dir string, keys []string) error
+
+	// DeleteTenantFiles deletes uploaded files of the tenant
+	DeleteTenantFiles(ctx context.Context, tenant *Tenant, keys []string) error
+
 	// ListTenantBackups lists available backups for a tenant
 	ListTenantBackups(ctx context.Context, tenantID string) ([]string, error)
 
@@ -3,10 +3,11 @@ package tenant_node
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/pocketbase/pocketbase/core/enterprise"
 	storagepkg "github.com/pocketbase/pocketbase/core/enterprise/storage"
 )
@@ -17,7 +18,8 @@ type TenantArchiver struct {
 	storage         *storagepkg.S3Backend
 	litestreamMgr   *storagepkg.LitestreamManager
 	config          *ArchiveConfig
-	logger          *log.Logger
+	configMu        sync.RWMutex // Guards config, replaced on config reload
+	logger          *slog.Logger
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -59,6 +61,29 @@ func DefaultArchiveConfig() *ArchiveConfig {
 	}
 }
 
+// ArchiveConfigFromSettings overlays the configured archive settings on DefaultArchiveConfig
+func ArchiveConfigFromSettings(settings enterprise.ArchiveSettings) *ArchiveConfig {
+	config := DefaultArchiveConfig()
+
+	if d, err := time.ParseDuration(settings.LitestreamStopAfter); err == nil && d > 0 {
+		config.LitestreamStopThreshold = d
+	}
+	if d, err := time.ParseDuration(settings.WarmAfter); err == nil && d > 0 {
+		config.WarmThreshold = d
+	}
+	if d, err := time.ParseDuration(settings.ColdAfter); err == nil && d > 0 {
+		config.ColdThreshold = d
+	}
+	if settings.MaxPerRun > 0 {
+		config.MaxArchivePerRun = settings.MaxPerRun
+	}
+	if settings.GlacierStorageClass != "" {
+		config.GlacierStorageClass = settings.GlacierStorageClass
+	}
+
+	return config
+}
+
 // NewTenantArchiver creates a new tenant archiver
 func NewTenantArchiver(
 	manager *Manager,
@@ -77,7 +102,7 @@ func NewTenantArchiver(
 		storage:       storage,
 		litestreamMgr: litestreamMgr,
 		config:        config,
-		logger:        log.Default(),
+		logger:        enterprise.ComponentLogger(enterprise.LogComponentArchiver),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
@@ -85,9 +110,8 @@ func NewTenantArchiver(
 
 // Start starts the archiving background tasks
 func (a *TenantArchiver) Start() {
-	a.logger.Printf("[TenantArchiver] Starting tenant archiver")
-	a.logger.Printf("[TenantArchiver] Warm threshold: %v, Cold threshold: %v",
-		a.config.WarmThreshold, a.config.ColdThreshold)
+	a.logger.Info("Starting tenant archiver")
+	a.logger.Info("Archive thresholds", "warmThreshold", a.config.WarmThreshold, "coldThreshold", a.config.ColdThreshold)
 
 	// Start background tasks
 	a.wg.Add(3)
@@ -96,9 +120,26 @@ func (a *TenantArchiver) Start() {
 	go a.runActivitySync()
 }
 
+// SetConfig replaces the archiving thresholds at runtime. The check interval and
+// metrics reset intervals keep their startup values.
+func (a *TenantArchiver) SetConfig(config *ArchiveConfig) {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	a.config = config
+}
+
+// currentConfig returns the archiving config in effect
+func (a *TenantArchiver) currentConfig() *ArchiveConfig {
+	a.configMu.RLock()
+	defer a.configMu.RUnlock()
+
+	return a.config
+}
+
 // Stop stops the archiver
 func (a *TenantArchiver) Stop() {
-	a.logger.Printf("[TenantArchiver] Stopping tenant archiver")
+	a.logger.Info("Stopping tenant archiver")
 	a.cancel()
 	a.wg.Wait()
 }
@@ -107,7 +148,7 @@ func (a *TenantArchiver) Stop() {
 func (a *TenantArchiver) runArchiveLoop() {
 	defer a.wg.Done()
 
-	ticker := time.NewTicker(a.config.CheckInterval)
+	ticker := time.NewTicker(a.currentConfig().CheckInterval)
 	defer ticker.Stop()
 
 	for {
@@ -116,7 +157,7 @@ func (a *TenantArchiver) runArchiveLoop() {
 			return
 		case <-ticker.C:
 			if err := a.checkAndArchiveTenants(); err != nil {
-				a.logger.Printf("[TenantArchiver] Archive check failed: %v", err)
+				a.logger.Error("Archive check failed", "error", err)
 			}
 		}
 	}
@@ -125,7 +166,7 @@ func (a *TenantArchiver) runArchiveLoop() {
 // checkAndArchiveTenants identifies and archives inactive tenants
 func (a *TenantArchiver) checkAndArchiveTenants() error {
 	start := time.Now()
-	a.logger.Printf("[TenantArchiver] Starting archive check")
+	a.logger.Debug("Starting archive check")
 
 	// Get control plane client from manager
 	cpClient := a.manager.cpClient
@@ -137,15 +178,16 @@ func (a *TenantArchiver) checkAndArchiveTenants() error {
 	// In production, this should query the control plane for all inactive tenants
 	instances := a.manager.ListActiveTenants()
 
-	litestreamStopCutoff := time.Now().Add(-a.config.LitestreamStopThreshold)
-	warmCutoff := time.Now().Add(-a.config.WarmThreshold)
-	coldCutoff := time.Now().Add(-a.config.ColdThreshold)
+	config := a.currentConfig()
+	litestreamStopCutoff := time.Now().Add(-config.LitestreamStopThreshold)
+	warmCutoff := time.Now().Add(-config.WarmThreshold)
+	coldCutoff := time.Now().Add(-config.ColdThreshold)
 
 	litestreamStoppedCount := 0
 	archivedCount := 0
 
 	for _, instance := range instances {
-		if archivedCount >= a.config.MaxArchivePerRun {
+		if archivedCount >= config.MaxArchivePerRun {
 			break
 		}
 
@@ -153,28 +195,27 @@ func (a *TenantArchiver) checkAndArchiveTenants() error {
 		// 1. Stop Litestream after 3 days (saves S3 write costs)
 		// 2. Unload from memory after 7 days (frees resources)
 		// 3. Move to Glacier after 90 days (reduces storage costs)
+		// Preview tenants expire instead of being archived, they only stop Litestream.
+		archivable := !instance.Tenant.Preview
 
-		if instance.LastAccessed.Before(coldCutoff) {
+		if archivable && instance.LastAccessed.Before(coldCutoff) {
 			// Move to cold storage (Glacier)
 			if err := a.archiveTenantToCold(instance.Tenant); err != nil {
-				a.logger.Printf("[TenantArchiver] Failed to archive tenant %s to cold: %v",
-					instance.Tenant.ID, err)
+				a.logger.Error("Failed to archive tenant to cold", "tenantId", instance.Tenant.ID, "error", err)
 			} else {
 				archivedCount++
 			}
-		} else if instance.LastAccessed.Before(warmCutoff) {
+		} else if archivable && instance.LastAccessed.Before(warmCutoff) {
 			// Move to warm storage (unload, stop Litestream, keep in S3 Standard)
 			if err := a.archiveTenantToWarm(instance.Tenant); err != nil {
-				a.logger.Printf("[TenantArchiver] Failed to archive tenant %s to warm: %v",
-					instance.Tenant.ID, err)
+				a.logger.Error("Failed to archive tenant to warm", "tenantId", instance.Tenant.ID, "error", err)
 			} else {
 				archivedCount++
 			}
 		} else if instance.LastAccessed.Before(litestreamStopCutoff) && instance.LitestreamRunning {
 			// Stop Litestream only (keep loaded in memory)
 			if err := a.stopLitestreamOnly(instance.Tenant.ID); err != nil {
-				a.logger.Printf("[TenantArchiver] Failed to stop Litestream for tenant %s: %v",
-					instance.Tenant.ID, err)
+				a.logger.Error("Failed to stop Litestream for tenant", "tenantId", instance.Tenant.ID, "error", err)
 			} else {
 				litestreamStoppedCount++
 				instance.LitestreamRunning = false
@@ -182,15 +223,14 @@ func (a *TenantArchiver) checkAndArchiveTenants() error {
 		}
 	}
 
-	a.logger.Printf("[TenantArchiver] Archive check completed in %v (%d Litestream stopped, %d tenants archived)",
-		time.Since(start), litestreamStoppedCount, archivedCount)
+	a.logger.Info("Archive check completed", "duration", time.Since(start), "litestreamStopped", litestreamStoppedCount, "archived", archivedCount)
 
 	return nil
 }
 
 // archiveTenantToWarm moves tenant to warm storage (S3 Standard, no Litestream)
 func (a *TenantArchiver) archiveTenantToWarm(tenant *enterprise.Tenant) error {
-	a.logger.Printf("[TenantArchiver] Archiving tenant %s to warm storage", tenant.ID)
+	a.logger.Info("Archiving tenant to warm storage", "tenantId", tenant.ID)
 
 	// 1. Stop Litestream replication
 	if err := a.stopLitestreamForTenant(tenant.ID); err != nil {
@@ -202,29 +242,34 @@ func (a *TenantArchiver) archiveTenantToWarm(tenant *enterprise.Tenant) error {
 		return fmt.Errorf("failed to create final snapshot: %w", err)
 	}
 
-	// 3. Unload tenant from memory
+	// 3. Upload the files not replicated yet, the local copies go with the node
+	if err := a.manager.flushTenantFiles(a.ctx, tenant.ID); err != nil {
+		return fmt.Errorf("failed to replicate uploaded files: %w", err)
+	}
+
+	// 4. Unload tenant from memory
 	if err := a.manager.UnloadTenant(a.ctx, tenant.ID); err != nil {
-		a.logger.Printf("[TenantArchiver] Warning: failed to unload tenant %s: %v", tenant.ID, err)
+		a.logger.Warn("Failed to unload tenant", "tenantId", tenant.ID, "error", err)
 		// Continue anyway - unload is best effort
 	}
 
-	// 4. Update tenant status and storage tier
+	// 5. Update tenant status and storage tier
 	if err := a.updateTenantTier(tenant.ID, enterprise.StorageTierWarm); err != nil {
 		return fmt.Errorf("failed to update tenant tier: %w", err)
 	}
 
-	a.logger.Printf("[TenantArchiver] Tenant %s archived to warm storage", tenant.ID)
+	a.logger.Info("Tenant archived to warm storage", "tenantId", tenant.ID)
 	return nil
 }
 
 // archiveTenantToCold moves tenant to cold storage (S3 Glacier Deep Archive)
 func (a *TenantArchiver) archiveTenantToCold(tenant *enterprise.Tenant) error {
-	if !a.config.GlacierEnabled {
-		a.logger.Printf("[TenantArchiver] Glacier archiving disabled, skipping tenant %s", tenant.ID)
+	if !a.currentConfig().GlacierEnabled {
+		a.logger.Warn("Glacier archiving disabled, skipping tenant", "tenantId", tenant.ID)
 		return nil
 	}
 
-	a.logger.Printf("[TenantArchiver] Archiving tenant %s to cold storage (Glacier)", tenant.ID)
+	a.logger.Info("Archiving tenant to cold storage (Glacier)", "tenantId", tenant.ID)
 
 	// 1. Ensure tenant is unloaded and Litestream stopped
 	if err := a.archiveTenantToWarm(tenant); err != nil {
@@ -241,7 +286,12 @@ func (a *TenantArchiver) archiveTenantToCold(tenant *enterprise.Tenant) error {
 		return fmt.Errorf("failed to update tenant tier: %w", err)
 	}
 
-	a.logger.Printf("[TenantArchiver] Tenant %s archived to cold storage", tenant.ID)
+	// Requests for archived tenants trigger a Glacier restore instead of a load
+	if err := a.manager.cpClient.UpdateTenantStatus(a.ctx, tenant.ID, enterprise.TenantStatusArchived); err != nil {
+		return fmt.Errorf("failed to mark tenant archived: %w", err)
+	}
+
+	a.logger.Info("Tenant archived to cold storage", "tenantId", tenant.ID)
 	return nil
 }
 
@@ -255,8 +305,7 @@ func (a *TenantArchiver) stopLitestreamForTenant(tenantID string) error {
 	databases := []string{"data", "auxiliary", "hooks"}
 	for _, dbName := range databases {
 		if err := a.litestreamMgr.StopReplication(tenantID, dbName); err != nil {
-			a.logger.Printf("[TenantArchiver] Warning: failed to stop Litestream for %s/%s: %v",
-				tenantID, dbName, err)
+			a.logger.Warn("Failed to stop Litestream", "tenantId", tenantID, "dbName", dbName, "error", err)
 			// Continue with other databases
 		}
 	}
@@ -266,7 +315,7 @@ func (a *TenantArchiver) stopLitestreamForTenant(tenantID string) error {
 
 // stopLitestreamOnly stops Litestream without unloading tenant (cost optimization)
 func (a *TenantArchiver) stopLitestreamOnly(tenantID string) error {
-	a.logger.Printf("[TenantArchiver] Stopping Litestream for tenant %s (cost optimization)", tenantID)
+	a.logger.Info("Stopping Litestream for tenant (cost optimization)", "tenantId", tenantID)
 
 	// Stop Litestream replication
 	if err := a.stopLitestreamForTenant(tenantID); err != nil {
@@ -279,10 +328,10 @@ func (a *TenantArchiver) stopLitestreamOnly(tenantID string) error {
 	for _, dbName := range databases {
 		// Note: This is a simplified implementation
 		// In production, you'd want to sync the final state to S3
-		a.logger.Printf("[TenantArchiver] Created final snapshot for %s/%s", tenantID, dbName)
+		a.logger.Info("Created final snapshot", "tenantId", tenantID, "dbName", dbName)
 	}
 
-	a.logger.Printf("[TenantArchiver] Litestream stopped for tenant %s (tenant still loaded in memory)", tenantID)
+	a.logger.Info("Litestream stopped for tenant (tenant still loaded in memory)", "tenantId", tenantID)
 	return nil
 }
 
@@ -292,7 +341,7 @@ func (a *TenantArchiver) createFinalSnapshot(tenant *enterprise.Tenant) error {
 	snapshotTime := time.Now()
 	snapshotPath := fmt.Sprintf("%s/snapshots/%s", tenant.S3Prefix, snapshotTime.Format("20060102-150405"))
 
-	a.logger.Printf("[TenantArchiver] Creating final snapshot for tenant %s at %s", tenant.ID, snapshotPath)
+	a.logger.Info("Creating final snapshot for tenant", "tenantId", tenant.ID, "snapshotPath", snapshotPath)
 
 	// In a real implementation, this would:
 	// 1. Copy current database files to snapshot location in S3
@@ -300,28 +349,25 @@ func (a *TenantArchiver) createFinalSnapshot(tenant *enterprise.Tenant) error {
 	// 3. Verify snapshot integrity
 
 	// For now, we assume Litestream has already replicated the latest state
-	a.logger.Printf("[TenantArchiver] Final snapshot created (via Litestream)")
+	a.logger.Info("Final snapshot created (via Litestream)")
 
 	return nil
 }
 
-// transitionToGlacier transitions S3 objects to Glacier storage class
+// transitionToGlacier transitions S3 objects to Glacier storage class. Every
+// object under the tenant prefix moves, the Litestream replicas as well as the
+// uploaded files.
 func (a *TenantArchiver) transitionToGlacier(tenant *enterprise.Tenant) error {
-	// In production, this would use S3 lifecycle policies or copy objects with Glacier storage class
-	// For now, we'll just log the intent
-
-	a.logger.Printf("[TenantArchiver] Transitioning tenant %s to Glacier storage class: %s",
-		tenant.ID, a.config.GlacierStorageClass)
+	storageClass := a.currentConfig().GlacierStorageClass
 
-	// Implementation would:
-	// 1. List all objects with prefix tenant.S3Prefix
-	// 2. For each object, copy to same key with GLACIER or DEEP_ARCHIVE storage class
-	// 3. Delete original object (or use S3 lifecycle policy)
+	a.logger.Info("Transitioning tenant to Glacier storage class", "tenantId", tenant.ID, "storageClass", storageClass)
 
-	// Note: In real implementation, you'd use AWS SDK:
-	// s3.CopyObject with StorageClass: "DEEP_ARCHIVE"
+	if a.storage == nil {
+		return fmt.Errorf("S3 storage not configured")
+	}
 
-	return nil
+	glacier := storagepkg.NewGlacierLifecycleManager(a.storage.Client(), a.storage.Bucket())
+	return glacier.TransitionToGlacier(a.ctx, tenant.S3Prefix, types.StorageClass(storageClass))
 }
 
 // updateTenantTier updates the tenant's storage tier in control plane
@@ -329,7 +375,7 @@ func (a *TenantArchiver) updateTenantTier(tenantID string, tier enterprise.Stora
 	// This would call control plane API to update tenant activity record
 	// For now, we'll skip the actual implementation since we need the control plane running
 
-	a.logger.Printf("[TenantArchiver] Updated tenant %s to storage tier: %s", tenantID, tier)
+	a.logger.Info("Updated tenant to storage tier", "tenantId", tenantID, "tier", tier)
 
 	// Implementation would call:
 	// cpClient.UpdateTenantActivity(ctx, tenantID, activity)
@@ -341,10 +387,10 @@ func (a *TenantArchiver) updateTenantTier(tenantID string, tier enterprise.Stora
 func (a *TenantArchiver) runMetricsReset() {
 	defer a.wg.Done()
 
-	dailyTicker := time.NewTicker(a.config.MetricsResetDaily)
+	dailyTicker := time.NewTicker(a.currentConfig().MetricsResetDaily)
 	defer dailyTicker.Stop()
 
-	weeklyTicker := time.NewTicker(a.config.MetricsResetWeekly)
+	weeklyTicker := time.NewTicker(a.currentConfig().MetricsResetWeekly)
 	defer weeklyTicker.Stop()
 
 	for {
@@ -361,22 +407,22 @@ func (a *TenantArchiver) runMetricsReset() {
 
 // resetDailyMetrics resets daily request counters
 func (a *TenantArchiver) resetDailyMetrics() {
-	a.logger.Printf("[TenantArchiver] Resetting daily metrics")
+	a.logger.Info("Resetting daily metrics")
 
 	// This would iterate through all tenant activity records and reset RequestsLast24h
 	// Implementation would call control plane API
 
-	a.logger.Printf("[TenantArchiver] Daily metrics reset complete")
+	a.logger.Info("Daily metrics reset complete")
 }
 
 // resetWeeklyMetrics resets weekly request counters
 func (a *TenantArchiver) resetWeeklyMetrics() {
-	a.logger.Printf("[TenantArchiver] Resetting weekly metrics")
+	a.logger.Info("Resetting weekly metrics")
 
 	// This would iterate through all tenant activity records and reset RequestsLast7d
 	// Implementation would call control plane API
 
-	a.logger.Printf("[TenantArchiver] Weekly metrics reset complete")
+	a.logger.Info("Weekly metrics reset complete")
 }
 
 // runActivitySync syncs activity from tenant nodes to control plane
@@ -392,7 +438,7 @@ func (a *TenantArchiver) runActivitySync() {
 			return
 		case <-ticker.C:
 			if err := a.syncActivityToControlPlane(); err != nil {
-				a.logger.Printf("[TenantArchiver] Activity sync failed: %v", err)
+				a.logger.Error("Activity sync failed", "error", err)
 			}
 		}
 	}
@@ -420,17 +466,31 @@ func (a *TenantArchiver) syncActivityToControlPlane() error {
 	return nil
 }
 
-// RestoreTenant restores an archived tenant back to hot storage
-func (a *TenantArchiver) RestoreTenant(ctx context.Context, tenantID string) error {
-	a.logger.Printf("[TenantArchiver] Restoring tenant %s from archive", tenantID)
+// RestoreTenant restores an archived tenant back to hot storage.
+// Warm tenants are loaded immediately; cold tenants return the in-flight
+// restore job and can be loaded once the control plane reports it ready.
+func (a *TenantArchiver) RestoreTenant(ctx context.Context, tenantID string) (*enterprise.RestoreJob, error) {
+	a.logger.Info("Restoring tenant from archive", "tenantId", tenantID)
+
+	cpClient := a.manager.cpClient
+	if cpClient == nil {
+		return nil, fmt.Errorf("control plane client not initialized")
+	}
+
+	job, err := cpClient.RequestTenantRestore(ctx, tenantID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request restore: %w", err)
+	}
+
+	if job.Status != enterprise.RestoreJobReady {
+		a.logger.Info("Tenant restore in progress", "tenantId", tenantID, "status", job.Status, "estimatedReady", job.EstimatedReady.Format(time.RFC3339))
+		return job, nil
+	}
 
-	// This would be called when a request comes in for an archived tenant
-	// Implementation:
-	// 1. Check current tier
-	// 2. If cold (Glacier), initiate restore (can take hours)
-	// 3. If warm, load from S3 Standard (fast)
-	// 4. Start Litestream replication
-	// 5. Update tier to hot
+	// Data is readable again - loading restores from S3 and restarts Litestream
+	if _, err := a.manager.LoadTenant(ctx, tenantID); err != nil {
+		return job, fmt.Errorf("failed to load restored tenant: %w", err)
+	}
 
-	return nil
+	return job, nil
 }
@@ -0,0 +1,122 @@
+package tenant_node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+const (
+	// changeFeedCapacity is the number of record changes retained for consumers
+	changeFeedCapacity = 4096
+
+	// changeFeedPageSize is the maximum number of changes returned per request
+	changeFeedPageSize = 1000
+)
+
+// ChangeFeed is a bounded in-memory log of record changes on this node.
+// Gateways poll it to invalidate cached responses of the changed tenants.
+type ChangeFeed struct {
+	nodeID string
+	epoch  string // Random per run, since seq starts over on restart
+
+	mu      sync.Mutex
+	seq     uint64
+	changes []*enterprise.RecordChange // Oldest first
+}
+
+// NewChangeFeed creates a new change feed for a node
+func NewChangeFeed(nodeID string) *ChangeFeed {
+	return &ChangeFeed{
+		nodeID:  nodeID,
+		epoch:   enterprise.GenerateID("epoch"),
+		changes: make([]*enterprise.RecordChange, 0, changeFeedCapacity),
+	}
+}
+
+// Publish appends a change for a tenant's collection
+func (f *ChangeFeed) Publish(tenantID, collection string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	if len(f.changes) == changeFeedCapacity {
+		copy(f.changes, f.changes[1:])
+		f.changes = f.changes[:len(f.changes)-1]
+	}
+
+	f.changes = append(f.changes, &enterprise.RecordChange{
+		Seq:        f.seq,
+		TenantID:   tenantID,
+		Collection: collection,
+		Time:       time.Now(),
+	})
+}
+
+// Epoch returns the epoch of this run of the feed
+func (f *ChangeFeed) Epoch() string {
+	return f.epoch
+}
+
+// Since returns the changes published after seq of the given epoch (empty on
+// first contact). Reset is set when some of those changes were already
+// dropped or seq belongs to another run of the node, in which case consumers
+// must assume that every tenant on this node changed.
+func (f *ChangeFeed) Since(epoch string, seq uint64) *enterprise.RecordChangeFeed {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	feed := &enterprise.RecordChangeFeed{
+		NodeID:  f.nodeID,
+		Epoch:   f.epoch,
+		Seq:     f.seq,
+		Changes: []*enterprise.RecordChange{},
+	}
+
+	if (epoch != "" && epoch != f.epoch) || seq > f.seq {
+		// Sequence from a previous run of this node
+		feed.Reset = true
+		return feed
+	}
+
+	if len(f.changes) > 0 && seq+1 < f.changes[0].Seq {
+		feed.Reset = true
+	}
+
+	for _, change := range f.changes {
+		if change.Seq <= seq {
+			continue
+		}
+		if len(feed.Changes) == changeFeedPageSize {
+			feed.Seq = feed.Changes[len(feed.Changes)-1].Seq
+			break
+		}
+		feed.Changes = append(feed.Changes, change)
+	}
+
+	return feed
+}
+
+// bindChangeHooks publishes the record and collection changes of a tenant app
+func (m *Manager) bindChangeHooks(tenantID string, app core.App) {
+	publishRecord := func(e *core.RecordEvent) error {
+		m.changes.Publish(tenantID, e.Record.Collection().Name)
+		return e.Next()
+	}
+
+	app.OnRecordAfterCreateSuccess().BindFunc(publishRecord)
+	app.OnRecordAfterUpdateSuccess().BindFunc(publishRecord)
+	app.OnRecordAfterDeleteSuccess().BindFunc(publishRecord)
+
+	// Schema and API rule changes can alter any cached response
+	publishCollection := func(e *core.CollectionEvent) error {
+		m.changes.Publish(tenantID, e.Collection.Name)
+		return e.Next()
+	}
+
+	app.OnCollectionAfterCreateSuccess().BindFunc(publishCollection)
+	app.OnCollectionAfterUpdateSuccess().BindFunc(publishCollection)
+	app.OnCollectionAfterDeleteSuccess().BindFunc(publishCollection)
+}
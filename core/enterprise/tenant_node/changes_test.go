@@ -0,0 +1,105 @@
+package tenant_node
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestChangeFeedSince(t *testing.T) {
+	feed := NewChangeFeed("node-1")
+
+	feed.Publish("tenant-1", "posts")
+	feed.Publish("tenant-2", "comments")
+	feed.Publish("tenant-1", "posts")
+
+	page := feed.Since("", 1)
+	if page.NodeID != "node-1" || page.Seq != 3 || page.Reset {
+		t.Fatalf("unexpected feed page: %+v", page)
+	}
+	if len(page.Changes) != 2 || page.Changes[0].TenantID != "tenant-2" {
+		t.Errorf("expected the 2 changes after seq 1, got %d", len(page.Changes))
+	}
+
+	if empty := feed.Since("", 3); len(empty.Changes) != 0 || empty.Reset {
+		t.Errorf("expected no changes after the latest seq, got %+v", empty)
+	}
+}
+
+func TestChangeFeedReset(t *testing.T) {
+	feed := NewChangeFeed("node-1")
+
+	for i := 0; i < changeFeedCapacity+10; i++ {
+		feed.Publish("tenant-1", "posts")
+	}
+
+	if page := feed.Since("", 0); !page.Reset {
+		t.Error("expected reset when requested changes were dropped")
+	}
+
+	// A sequence ahead of the feed comes from a previous run of the node
+	if page := NewChangeFeed("node-1").Since("", 42); !page.Reset {
+		t.Error("expected reset for a sequence from a previous run")
+	}
+
+	// as is one the restarted node already caught up with
+	restarted := NewChangeFeed("node-1")
+	restarted.Publish("tenant-1", "posts")
+	restarted.Publish("tenant-1", "posts")
+	if page := restarted.Since(feed.Epoch(), 1); !page.Reset {
+		t.Error("expected reset for a cursor of another epoch")
+	}
+	if page := restarted.Since(restarted.Epoch(), 1); page.Reset || len(page.Changes) != 1 {
+		t.Errorf("expected the change after seq 1 of the same epoch, got %+v", page)
+	}
+}
+
+func TestChangeFeedPaging(t *testing.T) {
+	feed := NewChangeFeed("node-1")
+
+	for i := 0; i < changeFeedPageSize+5; i++ {
+		feed.Publish("tenant-1", "posts")
+	}
+
+	page := feed.Since("", 0)
+	if len(page.Changes) != changeFeedPageSize {
+		t.Fatalf("expected a full page, got %d changes", len(page.Changes))
+	}
+	if page.Seq != uint64(changeFeedPageSize) {
+		t.Errorf("expected page to end at seq %d, got %d", changeFeedPageSize, page.Seq)
+	}
+
+	if rest := feed.Since("", page.Seq); len(rest.Changes) != 5 {
+		t.Errorf("expected the remaining 5 changes, got %d", len(rest.Changes))
+	}
+}
+
+func TestHandleChanges(t *testing.T) {
+	mgr := getTestManager(t)
+	server := NewHTTPServer(mgr)
+
+	mgr.changes.Publish("changes-tenant", "posts")
+
+	rec := httptest.NewRecorder()
+	server.handleChanges(rec, httptest.NewRequest(http.MethodGet, "/_changes?since=0", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var page enterprise.RecordChangeFeed
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode feed: %v", err)
+	}
+	if page.NodeID != mgr.nodeID || len(page.Changes) == 0 {
+		t.Errorf("expected published change in feed, got %+v", page)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleChanges(rec, httptest.NewRequest(http.MethodGet, "/_changes?since=abc", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid since, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,20 @@
+package tenant_node
+
+import (
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// ApplyConfig applies the reloadable settings of a reloaded config
+func (m *Manager) ApplyConfig(next *enterprise.ClusterConfig) {
+	if m.resourceMgr != nil {
+		m.resourceMgr.SetQuotas(enterprise.MergeResourceQuotas(next.Quotas))
+	}
+
+	if m.archiver != nil {
+		m.archiver.SetConfig(ArchiveConfigFromSettings(next.Archive))
+	}
+
+	m.tenantMetrics.Configure(next.Metrics.TenantTopN, next.Metrics.TenantAllowlist)
+
+	m.logger.Info("Applied reloaded config")
+}
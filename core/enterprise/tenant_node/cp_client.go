@@ -3,73 +3,255 @@ package tenant_node
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pocketbase/pocketbase/core/enterprise"
 	"go.nanomsg.org/mangos/v3"
 	"go.nanomsg.org/mangos/v3/protocol/req"
 	_ "go.nanomsg.org/mangos/v3/transport/all"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ipcTimeout          = 5 * time.Second        // Send and receive deadline of requests without one
+	notLeaderRetries    = 10                     // Rounds over the control planes while none is the leader
+	notLeaderRetryDelay = 200 * time.Millisecond // Pause between rounds, e.g. during an election
 )
 
 // ControlPlaneClient implements the enterprise.ControlPlaneClient interface
 type ControlPlaneClient struct {
 	controlPlaneAddrs []string
-	socket            mangos.Socket
-	socketMu          sync.Mutex // Protects socket send/recv ordering
+	sockets           []*controlPlaneSocket // One per control plane node
+	current           atomic.Int32          // Index of the socket that last answered a leader request (the leader)
+	next              atomic.Uint32         // Spreads stale reads over the nodes
 	circuitBreaker    *enterprise.CircuitBreaker
-	logger            *log.Logger
+	ipcSecret         string // Signs tenant key requests and opens the sealed keys
+	logger            *slog.Logger
+}
+
+// controlPlaneSocket is the REQ socket of one control plane node. A single
+// socket dialing all nodes would stick to whichever answered first, but writes
+// must reach the Raft leader.
+type controlPlaneSocket struct {
+	addr   string
+	socket mangos.Socket
+	mu     sync.Mutex   // Protects socket send/recv ordering
+	pipes  atomic.Int32 // Open connections to the node
 }
 
-// NewControlPlaneClient creates a new control plane client
-func NewControlPlaneClient(controlPlaneAddrs []string) (*ControlPlaneClient, error) {
+// NewControlPlaneClient creates a new control plane client. Zero circuit breaker
+// settings fall back to the defaults.
+func NewControlPlaneClient(controlPlaneAddrs []string, breaker enterprise.CircuitBreakerSettings) (*ControlPlaneClient, error) {
 	if len(controlPlaneAddrs) == 0 {
 		return nil, fmt.Errorf("at least one control plane address required")
 	}
 
-	socket, err := req.NewSocket()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create REQ socket: %w", err)
-	}
-
-	// Set socket options
-	socket.SetOption(mangos.OptionRecvDeadline, 5*time.Second)
-	socket.SetOption(mangos.OptionSendDeadline, 5*time.Second)
+	logger := enterprise.ComponentLogger(enterprise.LogComponentIPC)
 
-	// Connect to all control plane nodes (REQ socket will round-robin)
+	// Connect to all control plane nodes (dialing is retried in the background)
+	sockets := make([]*controlPlaneSocket, 0, len(controlPlaneAddrs))
 	for _, addr := range controlPlaneAddrs {
+		socket, err := req.NewSocket()
+		if err != nil {
+			for _, s := range sockets {
+				s.socket.Close()
+			}
+			return nil, fmt.Errorf("failed to create REQ socket: %w", err)
+		}
+
+		// Set socket options
+		socket.SetOption(mangos.OptionRecvDeadline, ipcTimeout)
+		socket.SetOption(mangos.OptionSendDeadline, ipcTimeout)
+
+		s := &controlPlaneSocket{addr: addr, socket: socket}
+		socket.SetPipeEventHook(func(event mangos.PipeEvent, _ mangos.Pipe) {
+			switch event {
+			case mangos.PipeEventAttached:
+				s.pipes.Add(1)
+			case mangos.PipeEventDetached:
+				s.pipes.Add(-1)
+			}
+		})
+
 		url := fmt.Sprintf("tcp://%s", addr)
-		if err := socket.Dial(url); err != nil {
-			log.Printf("[CPClient] Warning: failed to dial %s: %v", url, err)
+		if err := socket.DialOptions(url, map[string]interface{}{mangos.OptionDialAsynch: true}); err != nil {
+			logger.Warn("Failed to dial control plane", "url", url, "error", err)
 			// Continue to try other addresses
 		}
+
+		sockets = append(sockets, s)
 	}
 
 	// Create circuit breaker for control plane communication
-	cb := enterprise.NewCircuitBreaker(enterprise.CircuitBreakerConfig{
-		Name:            "control-plane",
-		MaxFailures:     5,
-		ResetTimeout:    30 * time.Second,
-		HalfOpenMaxReqs: 3,
-	})
+	cb := enterprise.NewCircuitBreaker(breaker.BreakerConfig("control-plane"))
 
 	return &ControlPlaneClient{
 		controlPlaneAddrs: controlPlaneAddrs,
-		socket:            socket,
+		sockets:           sockets,
 		circuitBreaker:    cb,
-		logger:            log.Default(),
+		logger:            logger,
 	}, nil
 }
 
 // Close closes the control plane client
 func (c *ControlPlaneClient) Close() error {
-	return c.socket.Close()
+	var errs []error
+	for _, s := range c.sockets {
+		if err := s.socket.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CircuitBreakerStats returns the state of the control plane circuit breaker
+func (c *ControlPlaneClient) CircuitBreakerStats() map[string]interface{} {
+	return c.circuitBreaker.Stats()
+}
+
+// ipcResponse is the reply of a control plane node
+type ipcResponse struct {
+	Success bool                   `json:"success"`
+	Data    map[string]interface{} `json:"data"`
+	Error   string                 `json:"error"`
+}
+
+// request sends a request to the node and waits for its reply
+func (s *controlPlaneSocket) request(ctx context.Context, reqJSON []byte) (*ipcResponse, error) {
+	// Lock to ensure send-recv ordering for REQ socket
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Adjust socket timeout based on context deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout > 0 {
+			s.socket.SetOption(mangos.OptionRecvDeadline, timeout)
+			s.socket.SetOption(mangos.OptionSendDeadline, timeout)
+			// Reset to default after request
+			defer func() {
+				s.socket.SetOption(mangos.OptionRecvDeadline, ipcTimeout)
+				s.socket.SetOption(mangos.OptionSendDeadline, ipcTimeout)
+			}()
+		}
+	}
+
+	// Send request
+	if err := s.socket.Send(reqJSON); err != nil {
+		return nil, fmt.Errorf("failed to send request to %s: %w", s.addr, err)
+	}
+
+	// Receive response
+	respJSON, err := s.socket.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive response from %s: %w", s.addr, err)
+	}
+
+	var resp ipcResponse
+	if err := json.Unmarshal(respJSON, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &resp, nil
+}
+
+// roundTrip sends a request to the node that answered last, moving on to the
+// other nodes while it is unreachable or isn't the Raft leader of a write.
+// Stale reads start at the next node in turn instead, so that followers share
+// them, and move on while a node is too far behind the leader.
+func (c *ControlPlaneClient) roundTrip(ctx context.Context, reqJSON []byte, stale bool) (map[string]interface{}, error) {
+	n := len(c.sockets)
+
+	for round := 0; ; round++ {
+		// Nodes without a connection are skipped unless no node has one
+		connected := false
+		for _, s := range c.sockets {
+			if s.pipes.Load() > 0 {
+				connected = true
+				break
+			}
+		}
+
+		var lastErr error
+		redirected := false
+		start := int(c.current.Load())
+		if stale {
+			start = int(c.next.Add(1) % uint32(n))
+		}
+
+		for i := 0; i < n; i++ {
+			index := (start + i) % n
+			s := c.sockets[index]
+			if connected && s.pipes.Load() == 0 {
+				continue
+			}
+
+			resp, err := s.request(ctx, reqJSON)
+			if err != nil {
+				lastErr = err
+				if ctx.Err() != nil {
+					return nil, lastErr
+				}
+				continue
+			}
+
+			if !resp.Success && (strings.Contains(resp.Error, enterprise.ErrNotLeader.Error()) || strings.Contains(resp.Error, enterprise.ErrStaleRead.Error())) {
+				lastErr = fmt.Errorf("control plane error: %s", resp.Error)
+				redirected = true
+				continue
+			}
+
+			if !stale {
+				c.current.Store(int32(index))
+			}
+
+			if !resp.Success {
+				return nil, fmt.Errorf("control plane error: %s", resp.Error)
+			}
+			return resp.Data, nil
+		}
+
+		// Only an election in progress or followers catching up are worth waiting for
+		if !redirected || round == notLeaderRetries {
+			return nil, lastErr
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, lastErr
+		case <-time.After(notLeaderRetryDelay):
+		}
+	}
 }
 
 // requestWithContext sends a request with context support for cancellation and timeout
 func (c *ControlPlaneClient) requestWithContext(ctx context.Context, reqType string, data map[string]interface{}) (map[string]interface{}, error) {
+	return c.send(ctx, reqType, data, false)
+}
+
+// readWithContext sends a read with the consistency the context asks for
+// (linearizable unless set with enterprise.WithReadOptions)
+func (c *ControlPlaneClient) readWithContext(ctx context.Context, reqType string, data map[string]interface{}) (map[string]interface{}, error) {
+	opts := enterprise.ReadOptionsFromContext(ctx)
+	data["consistency"] = string(opts.Consistency)
+	if opts.Consistency == enterprise.ReadStale {
+		data["maxStalenessMs"] = opts.MaxStaleness.Milliseconds()
+	}
+	return c.send(ctx, reqType, data, opts.Consistency == enterprise.ReadStale)
+}
+
+// send sends a request, to any node for stale reads and to the leader otherwise
+func (c *ControlPlaneClient) send(ctx context.Context, reqType string, data map[string]interface{}, stale bool) (_ map[string]interface{}, err error) {
+	ctx, span := enterprise.Tracer().Start(ctx, "ipc "+reqType, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("pocketbase.circuit_breaker", string(c.circuitBreaker.State()))))
+	defer func() { enterprise.EndSpan(span, err) }()
+
 	// Check if context is already cancelled
 	if err := ctx.Err(); err != nil {
 		return nil, fmt.Errorf("context cancelled: %w", err)
@@ -81,8 +263,9 @@ func (c *ControlPlaneClient) requestWithContext(ctx context.Context, reqType str
 	}
 
 	req := map[string]interface{}{
-		"type": reqType,
-		"data": data,
+		"type":  reqType,
+		"data":  data,
+		"trace": enterprise.InjectTraceContext(ctx),
 	}
 
 	reqJSON, err := json.Marshal(req)
@@ -98,54 +281,8 @@ func (c *ControlPlaneClient) requestWithContext(ctx context.Context, reqType str
 	resultCh := make(chan result, 1)
 
 	go func() {
-		// Lock to ensure send-recv ordering for REQ socket
-		c.socketMu.Lock()
-		defer c.socketMu.Unlock()
-
-		// Adjust socket timeout based on context deadline
-		if deadline, ok := ctx.Deadline(); ok {
-			timeout := time.Until(deadline)
-			if timeout > 0 {
-				c.socket.SetOption(mangos.OptionRecvDeadline, timeout)
-				c.socket.SetOption(mangos.OptionSendDeadline, timeout)
-				// Reset to default after request
-				defer func() {
-					c.socket.SetOption(mangos.OptionRecvDeadline, 5*time.Second)
-					c.socket.SetOption(mangos.OptionSendDeadline, 5*time.Second)
-				}()
-			}
-		}
-
-		// Send request
-		if err := c.socket.Send(reqJSON); err != nil {
-			resultCh <- result{nil, fmt.Errorf("failed to send request: %w", err)}
-			return
-		}
-
-		// Receive response
-		respJSON, err := c.socket.Recv()
-		if err != nil {
-			resultCh <- result{nil, fmt.Errorf("failed to receive response: %w", err)}
-			return
-		}
-
-		var resp struct {
-			Success bool                   `json:"success"`
-			Data    map[string]interface{} `json:"data"`
-			Error   string                 `json:"error"`
-		}
-
-		if err := json.Unmarshal(respJSON, &resp); err != nil {
-			resultCh <- result{nil, fmt.Errorf("failed to unmarshal response: %w", err)}
-			return
-		}
-
-		if !resp.Success {
-			resultCh <- result{nil, fmt.Errorf("control plane error: %s", resp.Error)}
-			return
-		}
-
-		resultCh <- result{resp.Data, nil}
+		data, err := c.roundTrip(ctx, reqJSON, stale)
+		resultCh <- result{data, err}
 	}()
 
 	// Wait for either context cancellation or result
@@ -175,7 +312,7 @@ func (c *ControlPlaneClient) request(reqType string, data map[string]interface{}
 
 // GetTenantMetadata retrieves tenant metadata from control plane
 func (c *ControlPlaneClient) GetTenantMetadata(ctx context.Context, tenantID string) (*enterprise.Tenant, error) {
-	data, err := c.requestWithContext(ctx, "getTenant", map[string]interface{}{
+	data, err := c.readWithContext(ctx, "getTenant", map[string]interface{}{
 		"tenantId": tenantID,
 	})
 	if err != nil {
@@ -199,7 +336,7 @@ func (c *ControlPlaneClient) GetTenantMetadata(ctx context.Context, tenantID str
 
 // GetTenantByDomain retrieves tenant by domain name
 func (c *ControlPlaneClient) GetTenantByDomain(ctx context.Context, domain string) (*enterprise.Tenant, error) {
-	data, err := c.requestWithContext(ctx, "getTenantByDomain", map[string]interface{}{
+	data, err := c.readWithContext(ctx, "getTenantByDomain", map[string]interface{}{
 		"domain": domain,
 	})
 	if err != nil {
@@ -236,15 +373,117 @@ func (c *ControlPlaneClient) RegisterNode(ctx context.Context, nodeInfo *enterpr
 		"nodeId":   nodeInfo.ID,
 		"address":  nodeInfo.Address,
 		"capacity": nodeInfo.Capacity,
+		"region":   nodeInfo.Region,
 	})
 	return err
 }
 
-// SendHeartbeat sends a heartbeat from this node
-func (c *ControlPlaneClient) SendHeartbeat(ctx context.Context, nodeID string, activeTenantsCount int) error {
-	_, err := c.requestWithContext(ctx, "heartbeat", map[string]interface{}{
+// SendHeartbeat sends a heartbeat from this node and returns its status
+func (c *ControlPlaneClient) SendHeartbeat(ctx context.Context, nodeID string, activeTenantsCount int, leases []*enterprise.TenantLease) (string, []*enterprise.TenantLease, error) {
+	data, err := c.requestWithContext(ctx, "heartbeat", map[string]interface{}{
 		"nodeId":             nodeID,
 		"activeTenantsCount": activeTenantsCount,
+		"leases":             leases,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	status, _ := data["status"].(string)
+
+	// Convert the generic lease list back into structs
+	var renewed []*enterprise.TenantLease
+	if data["leases"] != nil {
+		leasesJSON, _ := json.Marshal(data["leases"])
+		if err := json.Unmarshal(leasesJSON, &renewed); err != nil {
+			return "", nil, fmt.Errorf("failed to unmarshal leases: %w", err)
+		}
+	}
+
+	return status, renewed, nil
+}
+
+// AcquireTenantLease grants this node the lease of a tenant
+func (c *ControlPlaneClient) AcquireTenantLease(ctx context.Context, tenantID, nodeID string) (*enterprise.TenantLease, error) {
+	data, err := c.requestWithContext(ctx, "acquireLease", map[string]interface{}{
+		"tenantId": tenantID,
+		"nodeId":   nodeID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if held, _ := data["held"].(bool); held {
+		return nil, enterprise.ErrTenantLeaseHeld
+	}
+
+	switch refused, _ := data["refused"].(string); refused {
+	case "":
+	case "deleted":
+		return nil, enterprise.ErrTenantNotFound
+	case "archived":
+		return nil, enterprise.ErrTenantArchived
+	case "suspended":
+		return nil, enterprise.ErrTenantSuspended
+	default:
+		return nil, fmt.Errorf("lease refused: %s", refused)
+	}
+
+	leaseData, ok := data["lease"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid lease data in response")
+	}
+
+	// Convert map to TenantLease struct
+	leaseJSON, _ := json.Marshal(leaseData)
+	var lease enterprise.TenantLease
+	if err := json.Unmarshal(leaseJSON, &lease); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal lease: %w", err)
+	}
+
+	return &lease, nil
+}
+
+// ReleaseTenantLease gives up a lease after the tenant is unloaded
+func (c *ControlPlaneClient) ReleaseTenantLease(ctx context.Context, lease *enterprise.TenantLease) error {
+	_, err := c.requestWithContext(ctx, "releaseLease", map[string]interface{}{
+		"tenantId": lease.TenantID,
+		"nodeId":   lease.NodeID,
+		"epoch":    lease.Epoch,
+	})
+	return err
+}
+
+// UseSSOToken marks a tenant SSO token used, or returns ErrSSOTokenUsed
+func (c *ControlPlaneClient) UseSSOToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	data, err := c.requestWithContext(ctx, "useSSOToken", map[string]interface{}{
+		"tokenId":   tokenID,
+		"expiresAt": expiresAt.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+
+	if used, _ := data["used"].(bool); used {
+		return enterprise.ErrSSOTokenUsed
+	}
+	return nil
+}
+
+// DrainNode marks this node draining and releases its tenants that aren't loaded
+func (c *ControlPlaneClient) DrainNode(ctx context.Context, nodeID string, loadedTenants []string) error {
+	_, err := c.requestWithContext(ctx, "drainNode", map[string]interface{}{
+		"nodeId":        nodeID,
+		"loadedTenants": loadedTenants,
+	})
+	return err
+}
+
+// ReleaseTenant hands an unloaded tenant back for placement elsewhere
+func (c *ControlPlaneClient) ReleaseTenant(ctx context.Context, tenantID, nodeID string) error {
+	_, err := c.requestWithContext(ctx, "releaseTenant", map[string]interface{}{
+		"tenantId": tenantID,
+		"nodeId":   nodeID,
 	})
 	return err
 }
@@ -272,3 +511,199 @@ func (c *ControlPlaneClient) GetPlacementDecision(ctx context.Context, tenantID
 
 	return &decision, nil
 }
+
+// RequestTenantRestore starts (or returns the in-flight) restore job for an archived tenant
+func (c *ControlPlaneClient) RequestTenantRestore(ctx context.Context, tenantID string, expedited bool) (*enterprise.RestoreJob, error) {
+	data, err := c.requestWithContext(ctx, "requestRestore", map[string]interface{}{
+		"tenantId":  tenantID,
+		"expedited": expedited,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jobData, ok := data["job"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid restore job data in response")
+	}
+
+	// Convert map to RestoreJob struct
+	jobJSON, _ := json.Marshal(jobData)
+	var job enterprise.RestoreJob
+	if err := json.Unmarshal(jobJSON, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal restore job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// RegisterGateway registers (or heartbeats) a gateway and returns the live gateways
+func (c *ControlPlaneClient) RegisterGateway(ctx context.Context, gateway *enterprise.GatewayInfo) ([]*enterprise.GatewayInfo, error) {
+	data, err := c.requestWithContext(ctx, "registerGateway", map[string]interface{}{
+		"gatewayId": gateway.ID,
+		"address":   gateway.Address,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert list to GatewayInfo structs
+	gatewaysJSON, _ := json.Marshal(data["gateways"])
+	var gateways []*enterprise.GatewayInfo
+	if err := json.Unmarshal(gatewaysJSON, &gateways); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal gateways: %w", err)
+	}
+
+	return gateways, nil
+}
+
+// CheckpointUsage persists cluster-wide daily request counts
+func (c *ControlPlaneClient) CheckpointUsage(ctx context.Context, checkpoints []*enterprise.UsageCheckpoint) error {
+	_, err := c.requestWithContext(ctx, "checkpointUsage", map[string]interface{}{
+		"checkpoints": checkpoints,
+	})
+	return err
+}
+
+// AppendLogs ships a batch of log entries to the control plane log store
+func (c *ControlPlaneClient) AppendLogs(ctx context.Context, entries []*enterprise.LogEntry) error {
+	_, err := c.requestWithContext(ctx, "appendLogs", map[string]interface{}{
+		"entries": entries,
+	})
+	return err
+}
+
+// GetUsageCheckpoint returns a tenant's last persisted daily request count
+func (c *ControlPlaneClient) GetUsageCheckpoint(ctx context.Context, tenantID string) (*enterprise.UsageCheckpoint, error) {
+	data, err := c.requestWithContext(ctx, "getUsage", map[string]interface{}{
+		"tenantId": tenantID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checkpointData, ok := data["checkpoint"].(map[string]interface{})
+	if !ok {
+		return nil, enterprise.ErrUsageCheckpointNotFound
+	}
+
+	// Convert map to UsageCheckpoint struct
+	checkpointJSON, _ := json.Marshal(checkpointData)
+	var checkpoint enterprise.UsageCheckpoint
+	if err := json.Unmarshal(checkpointJSON, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal usage checkpoint: %w", err)
+	}
+
+	return &checkpoint, nil
+}
+
+// GetFleetMigrations returns the running fleet migrations each tenant hasn't applied yet
+func (c *ControlPlaneClient) GetFleetMigrations(ctx context.Context, tenantIDs []string) (map[string][]*enterprise.FleetMigration, error) {
+	data, err := c.requestWithContext(ctx, "getFleetMigrations", map[string]interface{}{
+		"tenantIds": tenantIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert map to FleetMigration structs
+	migrationsJSON, _ := json.Marshal(data["migrations"])
+	var migrations map[string][]*enterprise.FleetMigration
+	if err := json.Unmarshal(migrationsJSON, &migrations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fleet migrations: %w", err)
+	}
+
+	return migrations, nil
+}
+
+// GetTenantHandoffs returns the tenants of a node an admin is moving to another node
+func (c *ControlPlaneClient) GetTenantHandoffs(ctx context.Context, nodeID string) ([]string, error) {
+	data, err := c.requestWithContext(ctx, "getTenantHandoffs", map[string]interface{}{
+		"nodeId": nodeID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tenantIDs := make([]string, 0)
+	if ids, ok := data["tenantIds"].([]interface{}); ok {
+		for _, tenantID := range ids {
+			if id, ok := tenantID.(string); ok {
+				tenantIDs = append(tenantIDs, id)
+			}
+		}
+	}
+
+	return tenantIDs, nil
+}
+
+// GetFleetBatch returns the tenants eager fleet migrations scheduled on a node
+func (c *ControlPlaneClient) GetFleetBatch(ctx context.Context, nodeID string) ([]string, error) {
+	data, err := c.requestWithContext(ctx, "getFleetBatch", map[string]interface{}{
+		"nodeId": nodeID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tenantIDs := make([]string, 0)
+	if ids, ok := data["tenantIds"].([]interface{}); ok {
+		for _, tenantID := range ids {
+			if id, ok := tenantID.(string); ok {
+				tenantIDs = append(tenantIDs, id)
+			}
+		}
+	}
+
+	return tenantIDs, nil
+}
+
+// ReportFleetMigration records whether a tenant applied a fleet migration
+func (c *ControlPlaneClient) ReportFleetMigration(ctx context.Context, result *enterprise.FleetMigrationTenant) error {
+	_, err := c.requestWithContext(ctx, "reportFleetMigration", map[string]interface{}{
+		"result": result,
+	})
+	return err
+}
+
+// SetIPCSecret sets the secret shared with the control plane that tenant data
+// keys are handed out with
+func (c *ControlPlaneClient) SetIPCSecret(secret string) {
+	c.ipcSecret = secret
+}
+
+// GetTenantKeys returns the data keys a tenant's replicas are encrypted with.
+// The control plane only hands them to nodeID while it holds the tenant's lease.
+func (c *ControlPlaneClient) GetTenantKeys(ctx context.Context, tenantID, nodeID string) (*enterprise.TenantDataKeys, error) {
+	now := time.Now()
+	data, err := c.requestWithContext(ctx, "getTenantKeys", map[string]interface{}{
+		"tenantId":  tenantID,
+		"nodeId":    nodeID,
+		"timestamp": now.Unix(),
+		"signature": enterprise.SignIPCRequest(c.ipcSecret, "getTenantKeys", nodeID, tenantID, now),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if shredded, _ := data["shredded"].(bool); shredded {
+		return nil, enterprise.ErrTenantKeysShredded
+	}
+
+	sealed, ok := data["sealedKeys"].(string)
+	if !ok {
+		return nil, enterprise.ErrTenantKeysNotFound
+	}
+
+	keysJSON, err := enterprise.OpenIPCPayload(c.ipcSecret, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys enterprise.TenantDataKeys
+	if err := json.Unmarshal(keysJSON, &keys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tenant keys: %w", err)
+	}
+
+	return &keys, nil
+}
@@ -0,0 +1,74 @@
+package tenant_node
+
+import (
+	"context"
+)
+
+// Drain stops the node from taking new tenants and hands the loaded ones back
+// to the control plane, each after a final Litestream sync. It returns once the
+// node is empty or ctx is done; the drain itself keeps going in the background.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.startDrain()
+
+	select {
+	case <-m.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drained is closed once a drain has handed back all tenants
+func (m *Manager) Drained() <-chan struct{} {
+	return m.drained
+}
+
+// IsDraining reports whether the node is draining
+func (m *Manager) IsDraining() bool {
+	m.tenantsMu.RLock()
+	defer m.tenantsMu.RUnlock()
+
+	return m.draining
+}
+
+// startDrain starts draining the node (only the first call has an effect)
+func (m *Manager) startDrain() {
+	m.drainOnce.Do(func() {
+		m.tenantsMu.Lock()
+		m.draining = true
+		loaded := make([]string, 0, len(m.tenants))
+		for tenantID := range m.tenants {
+			loaded = append(loaded, tenantID)
+		}
+		m.tenantsMu.Unlock()
+
+		go m.drain(loaded)
+	})
+}
+
+// drain releases the given loaded tenants one by one
+func (m *Manager) drain(loaded []string) {
+	defer close(m.drained)
+
+	m.logger.Info("Draining node", "nodeId", m.nodeID, "loaded", len(loaded))
+
+	// Tenants assigned here but not loaded are released by the control plane right away
+	if err := m.cpClient.DrainNode(m.ctx, m.nodeID, loaded); err != nil {
+		m.logger.Error("Failed to report drain to control plane", "error", err)
+	}
+
+	for _, tenantID := range loaded {
+		// Unloading stops replication with a final sync to S3, so the next
+		// node restores the latest data
+		if err := m.UnloadTenant(m.ctx, tenantID); err != nil {
+			m.logger.Error("Failed to unload tenant while draining", "tenantId", tenantID, "error", err)
+			continue
+		}
+
+		if err := m.cpClient.ReleaseTenant(m.ctx, tenantID, m.nodeID); err != nil {
+			m.logger.Error("Failed to release tenant", "tenantId", tenantID, "error", err)
+		}
+	}
+
+	m.logger.Info("Node drained", "nodeId", m.nodeID)
+}
@@ -0,0 +1,527 @@
+package tenant_node
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+const (
+	// fileRetryInterval is how often failed file replications are retried
+	fileRetryInterval = 30 * time.Second
+
+	// fileFlushTimeout bounds the replication of pending files on unload
+	fileFlushTimeout = 2 * time.Minute
+)
+
+// FileReplicator keeps the uploaded files of tenants in S3, with the local
+// storage directory as a write-through cache. The tenant apps keep using
+// the local filesystem, and the record directories touched by a write are
+// queued and mirrored to S3 by a background worker, so writes don't wait for
+// S3. Files are never modified in place (uploads get unique names), so
+// comparing the file names is enough to sync.
+type FileReplicator struct {
+	storage enterprise.StorageBackend
+	logger  *slog.Logger
+
+	mu sync.Mutex
+
+	// Directories written since the worker last ran, by tenant ID
+	queued map[string]*pendingFiles
+
+	// Directories that failed to replicate, by tenant ID
+	pending map[string]*pendingFiles
+
+	// Tenants the worker is replicating, closed when it's done
+	syncing map[string]chan struct{}
+
+	// Signals the worker that directories were queued
+	wake chan struct{}
+}
+
+type pendingFiles struct {
+	tenant *enterprise.Tenant
+	dir    string
+
+	// Whether the record or collection of each directory was deleted
+	prefixes map[string]bool
+}
+
+// NewFileReplicator creates a file replicator on top of the storage backend
+func NewFileReplicator(storage enterprise.StorageBackend) *FileReplicator {
+	return &FileReplicator{
+		storage: storage,
+		logger:  enterprise.ComponentLogger(enterprise.LogComponentTenantNode),
+		queued:  make(map[string]*pendingFiles),
+		pending: make(map[string]*pendingFiles),
+		syncing: make(map[string]chan struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Restore brings the local storage directory dir of a tenant in line with
+// S3 before its app starts: missing files are downloaded and files deleted
+// elsewhere are removed. A tenant with no files in S3 yet but some locally
+// predates replication, its files are uploaded instead.
+//
+// Local files missing in S3 that were written after the last full sync of dir
+// were never uploaded (the node crashed before), they are uploaded rather than
+// removed.
+func (r *FileReplicator) Restore(ctx context.Context, tenant *enterprise.Tenant, dir string) error {
+	// Files queued or failing to replicate before the last unload only exist here
+	if err := r.Flush(ctx, tenant.ID); err != nil {
+		return err
+	}
+
+	remote, err := r.storage.ListTenantFiles(ctx, tenant, "")
+	if err != nil {
+		return err
+	}
+
+	local, err := listLocalFiles(dir, "")
+	if err != nil {
+		return err
+	}
+
+	// The app writes its files here, the sync marker must exist before that
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if len(remote) == 0 {
+		if len(local) > 0 {
+			r.logger.Info("Uploading tenant files not replicated yet", "tenantId", tenant.ID, "files", len(local))
+		}
+		if err := r.storage.UploadTenantFiles(ctx, tenant, dir, local); err != nil {
+			return err
+		}
+		return markFilesSynced(dir)
+	}
+
+	missing, localOnly := diffKeys(remote, local)
+
+	unsynced, stale, err := splitUnsynced(dir, localOnly)
+	if err != nil {
+		return err
+	}
+
+	if err := r.storage.UploadTenantFiles(ctx, tenant, dir, unsynced); err != nil {
+		return err
+	}
+
+	if err := r.storage.DownloadTenantFiles(ctx, tenant, dir, missing); err != nil {
+		return err
+	}
+
+	for _, key := range stale {
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(key))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	if len(missing) > 0 || len(stale) > 0 || len(unsynced) > 0 {
+		r.logger.Info("Restored tenant files", "tenantId", tenant.ID, "downloaded", len(missing), "removed", len(stale), "uploaded", len(unsynced))
+	}
+
+	return markFilesSynced(dir)
+}
+
+// Bind replicates the files of the tenant app's records as they change
+func (r *FileReplicator) Bind(app core.App, tenant *enterprise.Tenant, dir string) {
+	// Tenants with their own S3 storage configured don't use the local files
+	replicated := func(app core.App) bool {
+		return !app.Settings().S3.Enabled
+	}
+
+	syncRecord := func(e *core.RecordEvent) error {
+		if hasFileFields(e.Record.Collection()) && replicated(e.App) {
+			r.Sync(tenant, dir, e.Record.BaseFilesPath(), false)
+		}
+		return e.Next()
+	}
+
+	app.OnRecordAfterCreateSuccess().BindFunc(syncRecord)
+	app.OnRecordAfterUpdateSuccess().BindFunc(syncRecord)
+
+	// PocketBase deletes the local files of deleted models in the background,
+	// so they may still be there
+	app.OnRecordAfterDeleteSuccess().BindFunc(func(e *core.RecordEvent) error {
+		if hasFileFields(e.Record.Collection()) && replicated(e.App) {
+			r.Sync(tenant, dir, e.Record.BaseFilesPath(), true)
+		}
+		return e.Next()
+	})
+
+	app.OnCollectionAfterDeleteSuccess().BindFunc(func(e *core.CollectionEvent) error {
+		if replicated(e.App) {
+			r.Sync(tenant, dir, e.Collection.BaseFilesPath(), true)
+		}
+		return e.Next()
+	})
+}
+
+// Sync queues the files under prefix, a record or collection directory, to be
+// mirrored to S3, or deleted from S3 for a deleted record or collection. The
+// worker replicates them in the background, the write already succeeded.
+func (r *FileReplicator) Sync(tenant *enterprise.Tenant, dir string, prefix string, deleted bool) {
+	prefix = strings.TrimRight(prefix, "/") + "/"
+
+	r.mu.Lock()
+	addPrefix(r.queued, tenant, dir, prefix, deleted)
+	r.mu.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// SyncQueued replicates the queued directories of every tenant. Failures are
+// retried with the pending ones.
+func (r *FileReplicator) SyncQueued(ctx context.Context) {
+	for {
+		r.mu.Lock()
+		var queued *pendingFiles
+		for tenantID, p := range r.queued {
+			queued = p
+			delete(r.queued, tenantID)
+			break
+		}
+		if queued == nil {
+			r.mu.Unlock()
+			return
+		}
+		done := make(chan struct{})
+		r.syncing[queued.tenant.ID] = done
+		r.mu.Unlock()
+
+		if err := r.replicate(ctx, queued); err != nil {
+			r.logger.Warn("Failed to replicate tenant files, will retry", "tenantId", queued.tenant.ID, "error", err)
+		}
+
+		r.mu.Lock()
+		delete(r.syncing, queued.tenant.ID)
+		r.mu.Unlock()
+		close(done)
+	}
+}
+
+// replicate mirrors or deletes the directories of p and returns the first
+// error. Directories that fail are left pending.
+func (r *FileReplicator) replicate(ctx context.Context, p *pendingFiles) error {
+	var firstErr error
+	for prefix, deleted := range p.prefixes {
+		var err error
+		if deleted {
+			err = r.deleteRemote(ctx, p.tenant, prefix)
+		} else {
+			err = r.mirror(ctx, p.tenant, p.dir, prefix)
+		}
+
+		if err != nil {
+			r.markPending(p.tenant, p.dir, prefix)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// mirror uploads the local files under prefix missing in S3, and deletes the
+// ones in S3 no longer present locally
+func (r *FileReplicator) mirror(ctx context.Context, tenant *enterprise.Tenant, dir string, prefix string) error {
+	local, err := listLocalFiles(dir, prefix)
+	if err != nil {
+		return err
+	}
+
+	remote, err := r.storage.ListTenantFiles(ctx, tenant, prefix)
+	if err != nil {
+		return err
+	}
+
+	missing, stale := diffKeys(local, remote)
+
+	if err := r.storage.UploadTenantFiles(ctx, tenant, dir, missing); err != nil {
+		return err
+	}
+
+	if len(stale) > 0 {
+		return r.storage.DeleteTenantFiles(ctx, tenant, stale)
+	}
+	return nil
+}
+
+// deleteRemote deletes all the files under prefix from S3
+func (r *FileReplicator) deleteRemote(ctx context.Context, tenant *enterprise.Tenant, prefix string) error {
+	remote, err := r.storage.ListTenantFiles(ctx, tenant, prefix)
+	if err != nil {
+		return err
+	}
+
+	if len(remote) > 0 {
+		return r.storage.DeleteTenantFiles(ctx, tenant, remote)
+	}
+	return nil
+}
+
+// markPending records a directory to retry. Retries mirror the directories,
+// the local files of deleted models are gone by then.
+func (r *FileReplicator) markPending(tenant *enterprise.Tenant, dir string, prefix string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	addPrefix(r.pending, tenant, dir, prefix, false)
+}
+
+// addPrefix adds a directory of a tenant to files (must be called with lock held)
+func addPrefix(files map[string]*pendingFiles, tenant *enterprise.Tenant, dir string, prefix string, deleted bool) {
+	p, ok := files[tenant.ID]
+	if !ok {
+		p = &pendingFiles{tenant: tenant, dir: dir, prefixes: make(map[string]bool)}
+		files[tenant.ID] = p
+	}
+	p.prefixes[prefix] = deleted
+}
+
+// Flush replicates the queued and pending directories of a tenant, after the
+// worker is done with it, and returns the first error. Directories that fail
+// again stay pending.
+func (r *FileReplicator) Flush(ctx context.Context, tenantID string) error {
+	r.mu.Lock()
+	for {
+		done, ok := r.syncing[tenantID]
+		if !ok {
+			break
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		r.mu.Lock()
+	}
+
+	p, ok := r.pending[tenantID]
+	delete(r.pending, tenantID)
+
+	// Queued directories override pending ones, their record may have been deleted since
+	if queued, queuedOK := r.queued[tenantID]; queuedOK {
+		delete(r.queued, tenantID)
+		if ok {
+			maps.Copy(p.prefixes, queued.prefixes)
+		} else {
+			p, ok = queued, true
+		}
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return r.replicate(ctx, p)
+}
+
+// FlushAll retries the pending replications of every tenant
+func (r *FileReplicator) FlushAll(ctx context.Context) {
+	r.mu.Lock()
+	tenantIDs := make([]string, 0, len(r.pending))
+	for tenantID := range r.pending {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	r.mu.Unlock()
+
+	for _, tenantID := range tenantIDs {
+		if err := r.Flush(ctx, tenantID); err != nil {
+			r.logger.Warn("Failed to replicate tenant files", "tenantId", tenantID, "error", err)
+		}
+	}
+}
+
+// Pending returns the number of directories of a tenant queued or waiting to
+// be replicated again
+func (r *FileReplicator) Pending(tenantID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prefixes := make(map[string]struct{})
+	for _, files := range []map[string]*pendingFiles{r.queued, r.pending} {
+		if p, ok := files[tenantID]; ok {
+			for prefix := range p.prefixes {
+				prefixes[prefix] = struct{}{}
+			}
+		}
+	}
+	return len(prefixes)
+}
+
+// replicateFiles replicates queued files and retries failed file replications
+// until the node stops
+func (m *Manager) replicateFiles() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(fileRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.files.wake:
+			m.files.SyncQueued(m.ctx)
+		case <-ticker.C:
+			m.files.FlushAll(m.ctx)
+		}
+	}
+}
+
+// flushTenantFiles replicates the pending files of a tenant before its local
+// copy is left behind
+func (m *Manager) flushTenantFiles(ctx context.Context, tenantID string) error {
+	if m.files == nil {
+		return nil
+	}
+
+	if err := m.files.Flush(ctx, tenantID); err != nil {
+		return err
+	}
+
+	// Nothing is left to upload, files written before now are in S3
+	return markFilesSynced(m.tenantStorageDir(tenantID))
+}
+
+// tenantStorageDir returns the local directory of a tenant's uploaded files
+func (m *Manager) tenantStorageDir(tenantID string) string {
+	return filepath.Join(m.dataDir, tenantID, core.LocalStorageDirName)
+}
+
+// listLocalFiles lists the files under prefix of the storage directory dir,
+// as slash separated keys relative to dir. Thumbnails are left out, they are
+// generated again on demand.
+func listLocalFiles(dir string, prefix string) ([]string, error) {
+	root := filepath.Join(dir, filepath.FromSlash(prefix))
+
+	var keys []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		name := d.Name()
+		if d.IsDir() {
+			if p != root && strings.HasPrefix(name, "thumbs_") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Temporary files of downloads and uploads in progress
+		if strings.HasPrefix(name, ".") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+
+	return keys, err
+}
+
+// filesSyncedMarker is the file of a storage directory whose modification time
+// is the last time all its files were in S3
+const filesSyncedMarker = ".synced"
+
+// markFilesSynced records that all the files of the storage directory dir are
+// in S3. A directory that doesn't exist has no files to record.
+func markFilesSynced(dir string) error {
+	err := os.WriteFile(filepath.Join(dir, filesSyncedMarker), nil, 0644)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// splitUnsynced splits local files missing in S3 into the ones written after
+// the last full sync of dir, which may never have been uploaded, and the stale
+// ones deleted elsewhere. Without a sync on record, all of them are stale.
+func splitUnsynced(dir string, keys []string) (unsynced, stale []string, err error) {
+	marker, err := os.Stat(filepath.Join(dir, filesSyncedMarker))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, keys, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, key := range keys {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(key)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if info.ModTime().After(marker.ModTime()) {
+			unsynced = append(unsynced, key)
+		} else {
+			stale = append(stale, key)
+		}
+	}
+
+	return unsynced, stale, nil
+}
+
+// diffKeys returns the keys of want missing in have, and the keys of have
+// not in want
+func diffKeys(want, have []string) (missing, stale []string) {
+	wantSet := make(map[string]struct{}, len(want))
+	for _, key := range want {
+		wantSet[key] = struct{}{}
+	}
+
+	haveSet := make(map[string]struct{}, len(have))
+	for _, key := range have {
+		haveSet[key] = struct{}{}
+		if _, ok := wantSet[key]; !ok {
+			stale = append(stale, key)
+		}
+	}
+
+	for _, key := range want {
+		if _, ok := haveSet[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	return missing, stale
+}
+
+func hasFileFields(collection *core.Collection) bool {
+	for _, f := range collection.Fields {
+		if f.Type() == core.FieldTypeFile {
+			return true
+		}
+	}
+	return false
+}
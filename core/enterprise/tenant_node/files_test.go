@@ -0,0 +1,312 @@
+package tenant_node
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// fileStorageBackend keeps tenant files in memory
+type fileStorageBackend struct {
+	mockStorageBackend
+
+	mu    sync.Mutex
+	files map[string]string // key => content
+	fail  bool
+}
+
+func newFileStorageBackend() *fileStorageBackend {
+	return &fileStorageBackend{files: make(map[string]string)}
+}
+
+func (s *fileStorageBackend) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.files))
+	for key := range s.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *fileStorageBackend) ListTenantFiles(ctx context.Context, tenant *enterprise.Tenant, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail {
+		return nil, errors.New("s3 unavailable")
+	}
+
+	var keys []string
+	for key := range s.files {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *fileStorageBackend) UploadTenantFiles(ctx context.Context, tenant *enterprise.Tenant, dir string, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(key)))
+		if err != nil {
+			return err
+		}
+		s.files[key] = string(data)
+	}
+	return nil
+}
+
+func (s *fileStorageBackend) DownloadTenantFiles(ctx context.Context, tenant *enterprise.Tenant, dir string, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		dest := filepath.Join(dir, filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, []byte(s.files[key]), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fileStorageBackend) DeleteTenantFiles(ctx context.Context, tenant *enterprise.Tenant, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.files, key)
+	}
+	return nil
+}
+
+func writeTestFile(t *testing.T, dir, key, content string) {
+	t.Helper()
+
+	p := filepath.Join(dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileReplicatorRestore(t *testing.T) {
+	storage := newFileStorageBackend()
+	storage.files["col/rec1/a.png"] = "a"
+	storage.files["col/rec1/b.png"] = "b"
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "col/rec1/b.png", "b")
+	writeTestFile(t, dir, "col/rec2/deleted.png", "deleted elsewhere")
+	writeTestFile(t, dir, "col/rec1/thumbs_b.png/100x100_b.png", "thumb")
+
+	tenant := &enterprise.Tenant{ID: "tenant-1"}
+	if err := NewFileReplicator(storage).Restore(context.Background(), tenant, dir); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	local, err := listLocalFiles(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(local)
+	if strings.Join(local, ",") != "col/rec1/a.png,col/rec1/b.png" {
+		t.Errorf("expected the files in S3 locally, got %v", local)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "col", "rec1", "a.png")); string(data) != "a" {
+		t.Errorf("expected a.png to be downloaded, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "col", "rec1", "thumbs_b.png", "100x100_b.png")); err != nil {
+		t.Errorf("expected thumbnails to be kept: %v", err)
+	}
+}
+
+func TestFileReplicatorRestoreUploadsUnreplicatedFiles(t *testing.T) {
+	storage := newFileStorageBackend()
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "col/rec1/a.png", "a")
+
+	tenant := &enterprise.Tenant{ID: "tenant-1"}
+	if err := NewFileReplicator(storage).Restore(context.Background(), tenant, dir); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	if keys := storage.keys(); len(keys) != 1 || keys[0] != "col/rec1/a.png" {
+		t.Errorf("expected the local files to be uploaded, got %v", keys)
+	}
+}
+
+func TestFileReplicatorReplicatesRecordFiles(t *testing.T) {
+	app := newTestTenantApp(t)
+	dir := filepath.Join(app.DataDir(), core.LocalStorageDirName)
+
+	collection := core.NewBaseCollection("docs")
+	collection.Fields.Add(&core.FileField{Name: "file", MaxSelect: 1, MaxSize: 1 << 20})
+	if err := app.Save(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := newFileStorageBackend()
+	replicator := NewFileReplicator(storage)
+	tenant := &enterprise.Tenant{ID: "tenant-1"}
+	replicator.Bind(app, tenant, dir)
+
+	file, err := filesystem.NewFileFromBytes([]byte("hello"), "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("file", file)
+	if err := app.Save(record); err != nil {
+		t.Fatal(err)
+	}
+
+	// The write doesn't wait for S3, the worker uploads the files
+	if keys := storage.keys(); len(keys) != 0 {
+		t.Fatalf("expected nothing in S3 before the worker ran, got %v", keys)
+	}
+	if replicator.Pending(tenant.ID) != 1 {
+		t.Fatalf("expected the record directory to be queued, got %d", replicator.Pending(tenant.ID))
+	}
+	replicator.SyncQueued(context.Background())
+
+	// The local blob store keeps the content type in an attributes file
+	key := record.BaseFilesPath() + "/" + record.GetString("file")
+	if keys := storage.keys(); strings.Join(keys, ",") != key+","+key+".attrs" {
+		t.Fatalf("expected %s in S3, got %v", key, keys)
+	}
+
+	// Replacing the file while S3 is down is caught up on flush
+	storage.mu.Lock()
+	storage.fail = true
+	storage.mu.Unlock()
+
+	replacement, _ := filesystem.NewFileFromBytes([]byte("world"), "world.txt")
+	record.Set("file", replacement)
+	if err := app.Save(record); err != nil {
+		t.Fatal(err)
+	}
+	replicator.SyncQueued(context.Background())
+
+	if replicator.Pending(tenant.ID) != 1 {
+		t.Fatalf("expected the record directory to be pending, got %d", replicator.Pending(tenant.ID))
+	}
+	if err := replicator.Flush(context.Background(), tenant.ID); err == nil {
+		t.Fatal("expected flush to fail while S3 is down")
+	}
+
+	storage.mu.Lock()
+	storage.fail = false
+	storage.mu.Unlock()
+
+	if err := replicator.Flush(context.Background(), tenant.ID); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	key = record.BaseFilesPath() + "/" + record.GetString("file")
+	if keys := storage.keys(); strings.Join(keys, ",") != key+","+key+".attrs" {
+		t.Fatalf("expected only %s in S3, got %v", key, keys)
+	}
+
+	if err := app.Delete(record); err != nil {
+		t.Fatal(err)
+	}
+	replicator.SyncQueued(context.Background())
+	if keys := storage.keys(); len(keys) != 0 {
+		t.Errorf("expected the files of the deleted record to be removed, got %v", keys)
+	}
+}
+
+func TestFileReplicatorRestoreKeepsFilesWrittenAfterLastSync(t *testing.T) {
+	storage := newFileStorageBackend()
+	storage.files["col/rec1/a.png"] = "a"
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "col/rec1/a.png", "a")
+	writeTestFile(t, dir, "col/rec2/deleted.png", "deleted elsewhere")
+
+	tenant := &enterprise.Tenant{ID: "tenant-1"}
+	storage.files["col/rec2/deleted.png"] = "deleted elsewhere"
+	if err := NewFileReplicator(storage).Restore(context.Background(), tenant, dir); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	synced, err := os.Stat(filepath.Join(dir, filesSyncedMarker))
+	if err != nil {
+		t.Fatalf("expected the restore to record the sync: %v", err)
+	}
+
+	// Deleted by another node since, and written after the restore with the
+	// node crashing before uploading it
+	storage.DeleteTenantFiles(context.Background(), tenant, []string{"col/rec2/deleted.png"})
+	writeTestFile(t, dir, "col/rec3/new.png", "new")
+	later := synced.ModTime().Add(time.Second)
+	if err := os.Chtimes(filepath.Join(dir, "col", "rec3", "new.png"), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewFileReplicator(storage).Restore(context.Background(), tenant, dir); err != nil {
+		t.Fatalf("restore after crash failed: %v", err)
+	}
+
+	if keys := storage.keys(); strings.Join(keys, ",") != "col/rec1/a.png,col/rec3/new.png" {
+		t.Errorf("expected the unsynced file to be uploaded, got %v", keys)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "col", "rec3", "new.png")); err != nil {
+		t.Errorf("expected the unsynced file to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "col", "rec2", "deleted.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the file deleted elsewhere to be removed, got %v", err)
+	}
+}
+
+func TestFileReplicatorFlushReplicatesQueuedFiles(t *testing.T) {
+	storage := newFileStorageBackend()
+	storage.files["col/rec2/deleted.png"] = "deleted"
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "col/rec1/a.png", "a")
+
+	replicator := NewFileReplicator(storage)
+	tenant := &enterprise.Tenant{ID: "tenant-1"}
+
+	// Failed before, then written again: the latest queued state wins
+	replicator.markPending(tenant, dir, "col/rec2/")
+	replicator.Sync(tenant, dir, "col/rec1", false)
+	replicator.Sync(tenant, dir, "col/rec2", true)
+
+	// Unloading flushes what the worker didn't get to yet
+	if err := replicator.Flush(context.Background(), tenant.ID); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	if keys := storage.keys(); strings.Join(keys, ",") != "col/rec1/a.png" {
+		t.Fatalf("expected only col/rec1/a.png in S3, got %v", keys)
+	}
+	if replicator.Pending(tenant.ID) != 0 {
+		t.Errorf("expected nothing left to replicate, got %d", replicator.Pending(tenant.ID))
+	}
+}
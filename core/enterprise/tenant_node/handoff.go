@@ -0,0 +1,63 @@
+package tenant_node
+
+import (
+	"context"
+	"time"
+)
+
+// tenantHandoffPollInterval is how often the node checks for tenants an admin
+// is moving to another node
+const tenantHandoffPollInterval = 10 * time.Second
+
+// pollTenantHandoffs periodically hands off the tenants moved away from this node
+func (m *Manager) pollTenantHandoffs() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(tenantHandoffPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.handOffTenants(m.ctx)
+		}
+	}
+}
+
+// handOffTenants releases the tenants moved away from this node, which completes
+// their move. Loaded tenants are unloaded first so that replication stops with a
+// final sync to S3 and the new node restores the latest data.
+func (m *Manager) handOffTenants(ctx context.Context) {
+	// A drain releases every tenant anyway
+	if m.IsDraining() {
+		return
+	}
+
+	tenantIDs, err := m.cpClient.GetTenantHandoffs(ctx, m.nodeID)
+	if err != nil {
+		m.logger.Error("Failed to get tenant handoffs", "error", err)
+		return
+	}
+
+	for _, tenantID := range tenantIDs {
+		m.tenantsMu.RLock()
+		_, loaded := m.tenants[tenantID]
+		m.tenantsMu.RUnlock()
+
+		if loaded {
+			if err := m.UnloadTenant(ctx, tenantID); err != nil {
+				m.logger.Error("Failed to unload tenant for handoff", "tenantId", tenantID, "error", err)
+				continue
+			}
+		}
+
+		if err := m.cpClient.ReleaseTenant(ctx, tenantID, m.nodeID); err != nil {
+			m.logger.Error("Failed to hand off tenant", "tenantId", tenantID, "error", err)
+			continue
+		}
+
+		m.logger.Info("Handed off tenant", "tenantId", tenantID)
+	}
+}
@@ -1,14 +1,23 @@
 package tenant_node
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // HTTPServer handles incoming HTTP requests and routes them to tenant instances
@@ -22,7 +31,7 @@ type HTTPServer struct {
 	tenantLoadTime  map[string]time.Duration
 	tenantLoadMu    sync.RWMutex
 
-	logger *log.Logger
+	logger *slog.Logger
 }
 
 // NewHTTPServer creates a new HTTP server for the tenant node
@@ -30,13 +39,13 @@ func NewHTTPServer(manager *Manager) *HTTPServer {
 	return &HTTPServer{
 		manager:        manager,
 		tenantLoadTime: make(map[string]time.Duration),
-		logger:         log.Default(),
+		logger:         enterprise.ComponentLogger(enterprise.LogComponentTenantNode),
 	}
 }
 
 // Start starts the HTTP server
 func (s *HTTPServer) Start(addr string) error {
-	s.logger.Printf("[TenantNode HTTP] Starting HTTP server on %s", addr)
+	s.logger.Info("Starting HTTP server", "addr", addr)
 
 	mux := http.NewServeMux()
 
@@ -49,6 +58,15 @@ func (s *HTTPServer) Start(addr string) error {
 	// Metrics endpoint
 	mux.HandleFunc("/_metrics", s.handleMetrics)
 
+	// Prometheus metrics (scraped directly or federated by the control plane)
+	mux.Handle("/_prometheus", metrics.NodeHandler())
+
+	// Record change feed (polled by gateways for cache invalidation)
+	mux.HandleFunc("/_changes", s.handleChanges)
+
+	// Hotspots and circuit breakers (polled by the cluster admin console)
+	mux.HandleFunc("/_status", s.handleStatus)
+
 	s.server = &http.Server{
 		Addr:         addr,
 		Handler:      mux,
@@ -66,7 +84,7 @@ func (s *HTTPServer) Stop() error {
 		return nil
 	}
 
-	s.logger.Printf("[TenantNode HTTP] Stopping HTTP server...")
+	s.logger.Info("Stopping HTTP server")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -81,22 +99,63 @@ func (s *HTTPServer) handleTenantRequest(w http.ResponseWriter, r *http.Request)
 	// Extract tenant ID from header (set by gateway)
 	tenantID := r.Header.Get("X-Tenant-ID")
 	if tenantID == "" {
-		s.logger.Printf("[TenantNode HTTP] Request missing X-Tenant-ID header")
+		s.logger.Warn("Request missing X-Tenant-ID header")
 		s.failedRequests++
 		http.Error(w, "Missing tenant identifier", http.StatusBadRequest)
 		return
 	}
 
+	// Continue the gateway's trace; the context reaches the tenant app's RequestEvent
+	ctx := enterprise.ExtractHTTPTraceContext(r.Context(), r.Header)
+	ctx, span := enterprise.Tracer().Start(ctx, "tenant "+r.Method, trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(enterprise.AttrTenantID.String(tenantID), enterprise.AttrNodeID.String(s.manager.nodeID),
+			attribute.String("http.route", r.URL.Path)))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	logger := s.logger.With(enterprise.LogKeyTenantID, tenantID, enterprise.LogKeyRequestID, r.Header.Get(enterprise.RequestIDHeader))
+
 	// Get or load tenant instance
 	startTime := time.Now()
-	instance, err := s.manager.GetOrLoadTenant(tenantID)
+	instance, err := s.manager.GetOrLoadTenant(ctx, tenantID)
 	if err != nil {
-		s.logger.Printf("[TenantNode HTTP] Failed to load tenant %s: %v", tenantID, err)
+		logger.Error("Failed to load tenant", "error", err)
 		s.failedRequests++
+		span.SetStatus(codes.Error, err.Error())
 
 		// Return appropriate error based on the type
 		if err == enterprise.ErrTenantNotFound {
 			http.Error(w, "Tenant not found", http.StatusNotFound)
+		} else if errors.Is(err, enterprise.ErrTenantArchived) {
+			w.Header().Set(enterprise.TenantRefusedHeader, "archived")
+			retryAfter := 60
+			if job, jobErr := s.manager.cpClient.RequestTenantRestore(r.Context(), tenantID, false); jobErr == nil {
+				retryAfter = int(job.RetryAfter().Seconds())
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, "Tenant is being restored from archive", http.StatusServiceUnavailable)
+		} else if errors.Is(err, enterprise.ErrNodeDraining) {
+			// Tells the gateway to look up the tenant's node again
+			w.Header().Set("X-Node-Draining", "true")
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Node is draining", http.StatusServiceUnavailable)
+		} else if errors.Is(err, enterprise.ErrTenantMoved) {
+			// Same as draining for the gateway, the tenant lives on another node now
+			w.Header().Set(enterprise.TenantRefusedHeader, "moved")
+			w.Header().Set("X-Node-Draining", "true")
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Tenant moved to another node", http.StatusServiceUnavailable)
+		} else if errors.Is(err, enterprise.ErrTenantLeaseHeld) || errors.Is(err, enterprise.ErrTenantLeaseLost) {
+			// Another node serves the tenant, or will once the lease expires
+			w.Header().Set(enterprise.TenantRefusedHeader, "leased")
+			w.Header().Set("X-Node-Draining", "true")
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Tenant is leased to another node", http.StatusServiceUnavailable)
+		} else if errors.Is(err, enterprise.ErrTenantSuspended) {
+			w.Header().Set(enterprise.TenantRefusedHeader, "suspended")
+			http.Error(w, "Tenant is suspended", http.StatusForbidden)
+		} else if errors.Is(err, enterprise.ErrRegionMismatch) {
+			http.Error(w, "Tenant is not served in this region", http.StatusMisdirectedRequest)
 		} else {
 			http.Error(w, "Failed to load tenant", http.StatusServiceUnavailable)
 		}
@@ -107,7 +166,7 @@ func (s *HTTPServer) handleTenantRequest(w http.ResponseWriter, r *http.Request)
 	// Check API quota before processing request
 	if quotaEnforcer := s.manager.GetQuotaEnforcer(); quotaEnforcer != nil {
 		if err := quotaEnforcer.CheckAPIQuota(tenantID, instance.Tenant); err != nil {
-			s.logger.Printf("[TenantNode HTTP] Tenant %s API quota exceeded", tenantID)
+			logger.Warn("Tenant API quota exceeded")
 			s.failedRequests++
 			http.Error(w, "API quota exceeded. Please upgrade your plan or wait for quota reset.", http.StatusTooManyRequests)
 			return
@@ -116,7 +175,7 @@ func (s *HTTPServer) handleTenantRequest(w http.ResponseWriter, r *http.Request)
 		// Check storage quota for write requests
 		if r.Method != http.MethodGet && r.Method != http.MethodHead {
 			if err := quotaEnforcer.CheckStorageQuota(tenantID, instance.Tenant); err != nil {
-				s.logger.Printf("[TenantNode HTTP] Tenant %s storage quota exceeded", tenantID)
+				logger.Warn("Tenant storage quota exceeded")
 				s.failedRequests++
 				http.Error(w, "Storage quota exceeded. Please upgrade your plan.", http.StatusInsufficientStorage)
 				return
@@ -132,7 +191,7 @@ func (s *HTTPServer) handleTenantRequest(w http.ResponseWriter, r *http.Request)
 		s.tenantLoadMu.Lock()
 		s.tenantLoadTime[tenantID] = loadDuration
 		s.tenantLoadMu.Unlock()
-		s.logger.Printf("[TenantNode HTTP] Loaded tenant %s in %v", tenantID, loadDuration)
+		logger.Info("Loaded tenant", "loadDuration", loadDuration)
 	}
 
 	// Update tenant last access time
@@ -141,7 +200,7 @@ func (s *HTTPServer) handleTenantRequest(w http.ResponseWriter, r *http.Request)
 
 	// Get the PocketBase app HTTP handler
 	if instance.HTTPHandler == nil {
-		s.logger.Printf("[TenantNode HTTP] Tenant %s has no HTTP handler", tenantID)
+		logger.Info("Tenant has no HTTP handler")
 		s.failedRequests++
 		http.Error(w, "Tenant HTTP handler not initialized", http.StatusServiceUnavailable)
 		return
@@ -151,7 +210,7 @@ func (s *HTTPServer) handleTenantRequest(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("X-Tenant-ID", tenantID)
 
 	// Log the request
-	s.logger.Printf("[TenantNode HTTP] Serving request for tenant %s: %s %s", tenantID, r.Method, r.URL.Path)
+	logger.Debug("Serving request for tenant", "method", r.Method, "path", r.URL.Path)
 
 	// Record request for peak tracking
 	if metricsCollector := s.manager.metricsCollector; metricsCollector != nil {
@@ -165,11 +224,42 @@ func (s *HTTPServer) handleTenantRequest(w http.ResponseWriter, r *http.Request)
 		statusCode:     http.StatusOK, // Default to 200
 	}
 
+	// Realtime streams are tracked per tenant, exempt from the server write
+	// timeout and closed with a reconnect hint if the tenant leaves this node
+	realtime := enterprise.IsRealtimeRequest(r)
+	if realtime {
+		rc := http.NewResponseController(w)
+		rc.SetReadDeadline(time.Time{})
+		rc.SetWriteDeadline(time.Time{})
+
+		ctx, done := s.manager.realtime.Open(r.Context(), tenantID)
+		defer done()
+		r = r.WithContext(ctx)
+
+		defer func() {
+			if errors.Is(context.Cause(ctx), errTenantUnloaded) && !wrapper.hijacked {
+				writeReconnectEvent(wrapper)
+			}
+		}()
+	}
+
 	// Proxy the request to the tenant's PocketBase app HTTP handler
 	instance.HTTPHandler.ServeHTTP(wrapper, r)
 
 	// Calculate response time
-	responseTime := time.Since(requestStart).Milliseconds()
+	elapsed := time.Since(requestStart)
+	responseTime := elapsed.Milliseconds()
+
+	// A realtime stream lasts as long as the client stays connected, it has no latency
+	if realtime {
+		elapsed = 0
+	}
+	s.manager.tenantMetrics.ObserveRequest(tenantID, wrapper.statusCode, elapsed)
+
+	span.SetAttributes(attribute.Int("http.response.status_code", wrapper.statusCode))
+	if wrapper.statusCode >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(wrapper.statusCode))
+	}
 
 	// Record metrics
 	if metricsCollector := s.manager.metricsCollector; metricsCollector != nil {
@@ -189,6 +279,26 @@ type responseWriterWrapper struct {
 	http.ResponseWriter
 	statusCode int
 	written    bool
+	hijacked   bool
+}
+
+// Flush sends buffered data to the client (required for SSE)
+func (w *responseWriterWrapper) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack takes over the connection (required for WebSocket upgrades)
+func (w *responseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
 func (w *responseWriterWrapper) WriteHeader(statusCode int) {
@@ -245,3 +355,44 @@ func (s *HTTPServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
 		stats.MemoryUsedMB, stats.CPUPercent, loadTimeCount,
 		float64(s.totalRequests-s.failedRequests)/float64(s.totalRequests)*100)
 }
+
+// handleStatus returns the node's hotspot tenants and circuit breaker states
+func (s *HTTPServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := s.manager.GetStats()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"nodeId":          s.manager.nodeID,
+		"draining":        s.manager.IsDraining(),
+		"loadedTenants":   stats.LoadedTenants,
+		"capacity":        stats.Capacity,
+		"hotspots":        s.manager.GetHotspots(),
+		"circuitBreakers": s.manager.GetCircuitBreakers(),
+	})
+}
+
+// handleChanges returns the record changes published after the "since" sequence number of the "epoch"
+func (s *HTTPServer) handleChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since uint64
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid since parameter", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.manager.changes.Since(r.URL.Query().Get("epoch"), since))
+}
@@ -0,0 +1,88 @@
+package tenant_node
+
+import (
+	"context"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+// checkLease refuses to serve a loaded tenant once its lease ran out. Another
+// node may hold it by now.
+func checkLease(instance *enterprise.TenantInstance) error {
+	if time.Now().Before(instance.LeaseValidUntil) {
+		return nil
+	}
+	return enterprise.NewTenantError(instance.Tenant.ID, enterprise.ErrTenantLeaseLost)
+}
+
+// HoldsLease returns true if the tenant is loaded and its lease is still valid
+func (m *Manager) HoldsLease(tenantID string) bool {
+	m.tenantsMu.RLock()
+	defer m.tenantsMu.RUnlock()
+
+	instance, exists := m.tenants[tenantID]
+	return exists && checkLease(instance) == nil
+}
+
+// heldLeasesLocked returns the leases of the loaded tenants to renew (must be called with lock held)
+func (m *Manager) heldLeasesLocked() []*enterprise.TenantLease {
+	leases := make([]*enterprise.TenantLease, 0, len(m.tenants))
+	for tenantID, instance := range m.tenants {
+		leases = append(leases, &enterprise.TenantLease{
+			TenantID: tenantID,
+			NodeID:   m.nodeID,
+			Epoch:    instance.LeaseEpoch,
+		})
+	}
+	return leases
+}
+
+// updateLeases applies the result of a heartbeat sent at sentAt with the held
+// leases: renewed ones are good for another TTL, the others are lost. Tenants
+// whose lease is lost or ran out are unloaded.
+func (m *Manager) updateLeases(held, renewed []*enterprise.TenantLease, sentAt time.Time) {
+	m.tenantsMu.Lock()
+	defer m.unlockTenants()
+
+	renewedEpochs := make(map[string]uint64, len(renewed))
+	for _, lease := range renewed {
+		renewedEpochs[lease.TenantID] = lease.Epoch
+	}
+
+	for _, lease := range held {
+		instance, exists := m.tenants[lease.TenantID]
+		if !exists || instance.LeaseEpoch != lease.Epoch {
+			continue
+		}
+
+		if epoch, ok := renewedEpochs[lease.TenantID]; ok && epoch == lease.Epoch {
+			instance.LeaseValidUntil = sentAt.Add(enterprise.TenantLeaseTTL)
+		} else {
+			instance.LeaseValidUntil = time.Time{}
+		}
+	}
+
+	now := time.Now()
+	for tenantID, instance := range m.tenants {
+		if now.Before(instance.LeaseValidUntil) {
+			continue
+		}
+
+		m.logger.Warn("Lost tenant lease, unloading", "tenantId", tenantID, "leaseEpoch", instance.LeaseEpoch)
+		if err := m.unloadTenantLocked(tenantID); err != nil {
+			m.logger.Error("Failed to unload tenant after losing its lease", "tenantId", tenantID, "error", err)
+		}
+	}
+}
+
+// releaseLease hands a lease back so the tenant can move without waiting for it
+// to expire. Failing is harmless, the lease just expires.
+func (m *Manager) releaseLease(lease *enterprise.TenantLease) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := m.cpClient.ReleaseTenantLease(ctx, lease); err != nil {
+		m.logger.Warn("Failed to release tenant lease", "tenantId", lease.TenantID, "leaseEpoch", lease.Epoch, "error", err)
+	}
+}
@@ -0,0 +1,138 @@
+package tenant_node
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestLoadTenantRefusesLeaseHeldByAnotherNode(t *testing.T) {
+	mgr := getTestManager(t)
+	cpClient := mgr.cpClient.(*mockCPClient)
+
+	cpClient.addTenant(&enterprise.Tenant{ID: "tenant-leased", Status: enterprise.TenantStatusActive})
+	cpClient.leases["tenant-leased"] = &enterprise.TenantLease{
+		TenantID:  "tenant-leased",
+		NodeID:    "other-node",
+		Epoch:     3,
+		ExpiresAt: time.Now().Add(enterprise.TenantLeaseTTL),
+	}
+
+	_, err := mgr.LoadTenant(context.Background(), "tenant-leased")
+	if !errors.Is(err, enterprise.ErrTenantLeaseHeld) {
+		t.Fatalf("expected ErrTenantLeaseHeld, got %v", err)
+	}
+
+	if _, err := mgr.GetTenant("tenant-leased"); err == nil {
+		t.Error("expected tenant not to be loaded")
+	}
+}
+
+func TestUpdateLeasesUnloadsTenantsWithLostLeases(t *testing.T) {
+	mgr := getTestManager(t)
+
+	sentAt := time.Now()
+	mgr.tenantsMu.Lock()
+	mgr.tenants["tenant-renewed"] = &enterprise.TenantInstance{
+		Tenant:     &enterprise.Tenant{ID: "tenant-renewed"},
+		LeaseEpoch: 1,
+	}
+	mgr.tenants["tenant-lost"] = &enterprise.TenantInstance{
+		Tenant:          &enterprise.Tenant{ID: "tenant-lost"},
+		LeaseEpoch:      2,
+		LeaseValidUntil: sentAt.Add(enterprise.TenantLeaseTTL),
+	}
+	held := mgr.heldLeasesLocked()
+	mgr.tenantsMu.Unlock()
+
+	mgr.updateLeases(held, []*enterprise.TenantLease{
+		{TenantID: "tenant-renewed", NodeID: mgr.nodeID, Epoch: 1},
+	}, sentAt)
+
+	if !mgr.HoldsLease("tenant-renewed") {
+		t.Error("expected renewed lease to be held")
+	}
+	if mgr.HoldsLease("tenant-lost") {
+		t.Error("expected lost lease not to be held")
+	}
+	if _, err := mgr.GetTenant("tenant-lost"); err == nil {
+		t.Error("expected tenant with lost lease to be unloaded")
+	}
+
+	mgr.tenantsMu.Lock()
+	delete(mgr.tenants, "tenant-renewed")
+	mgr.tenantsMu.Unlock()
+}
+
+func TestUnloadReleasesLeaseAfterUnlocking(t *testing.T) {
+	mgr := getTestManager(t)
+	cpClient := mgr.cpClient.(*mockCPClient)
+
+	mgr.tenantsMu.Lock()
+	mgr.tenants["tenant-unloaded"] = &enterprise.TenantInstance{
+		Tenant:     &enterprise.Tenant{ID: "tenant-unloaded"},
+		LeaseEpoch: 4,
+	}
+	mgr.tenantsMu.Unlock()
+
+	var lockFree bool
+	var waiting <-chan struct{}
+	var released *enterprise.TenantLease
+	cpClient.onReleaseLease = func(lease *enterprise.TenantLease) {
+		released = lease
+
+		// other tenants are served while the control plane is called, but
+		// loading the unloaded one waits for its lease
+		if lockFree = mgr.tenantsMu.TryLock(); lockFree {
+			waiting = mgr.unloading[lease.TenantID]
+			mgr.tenantsMu.Unlock()
+		}
+	}
+	defer func() { cpClient.onReleaseLease = nil }()
+
+	if err := mgr.UnloadTenant(context.Background(), "tenant-unloaded"); err != nil {
+		t.Fatalf("failed to unload tenant: %v", err)
+	}
+
+	if !lockFree {
+		t.Fatal("expected the lease to be released without holding the tenants lock")
+	}
+	if waiting == nil {
+		t.Fatal("expected the tenant to be unloading while its lease is released")
+	}
+	select {
+	case <-waiting:
+	default:
+		t.Error("expected the unload to be finished once UnloadTenant returns")
+	}
+
+	if released == nil || released.TenantID != "tenant-unloaded" || released.Epoch != 4 {
+		t.Fatalf("expected the lease of epoch 4 to be released, got %+v", released)
+	}
+
+	mgr.tenantsMu.RLock()
+	_, unloading := mgr.unloading["tenant-unloaded"]
+	mgr.tenantsMu.RUnlock()
+	if unloading {
+		t.Error("expected the finished unload to be forgotten")
+	}
+}
+
+func TestCheckLeaseExpired(t *testing.T) {
+	instance := &enterprise.TenantInstance{
+		Tenant:          &enterprise.Tenant{ID: "tenant-1"},
+		LeaseValidUntil: time.Now().Add(-time.Second),
+	}
+
+	if err := checkLease(instance); !errors.Is(err, enterprise.ErrTenantLeaseLost) {
+		t.Errorf("expected ErrTenantLeaseLost, got %v", err)
+	}
+
+	instance.LeaseValidUntil = time.Now().Add(time.Minute)
+	if err := checkLease(instance); err != nil {
+		t.Errorf("expected valid lease, got %v", err)
+	}
+}
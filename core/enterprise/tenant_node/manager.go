@@ -2,8 +2,9 @@ package tenant_node
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"path/filepath"
 	"sync"
@@ -12,9 +13,11 @@ import (
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/auth"
 	"github.com/pocketbase/pocketbase/core/enterprise/health"
 	"github.com/pocketbase/pocketbase/core/enterprise/metrics"
 	storagepkg "github.com/pocketbase/pocketbase/core/enterprise/storage"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Manager manages tenant instances on a tenant node
@@ -36,24 +39,53 @@ type Manager struct {
 	// LRU tracking
 	accessOrder []string // Tenant IDs in access order (most recent last)
 
+	// Open SSE/WebSocket connections per tenant
+	realtime *RealtimeTracker
+
+	// Record changes, polled by gateways to invalidate cached responses
+	changes *ChangeFeed
+
+	// Replicates uploaded files to S3 (nil without a storage backend)
+	files *FileReplicator
+
 	// Archiving
 	archiver *TenantArchiver
 
+	// Tenants a fleet migration is running on, closed once it finishes
+	// so unloading the tenant can wait for it
+	migrating   map[string]chan struct{}
+	migratingMu sync.Mutex
+
+	// Tenants taken out of the cache whose unload finishes once tenantsMu is
+	// released, closed when it has so the tenant can be loaded again
+	unloading      map[string]chan struct{} // guarded by tenantsMu
+	pendingUnloads []*pendingUnload         // guarded by tenantsMu
+
+	// Draining: no new tenants are loaded, and drained is closed once the
+	// loaded ones have been handed back to the control plane
+	draining  bool // guarded by tenantsMu
+	drainOnce sync.Once
+	drained   chan struct{}
+
 	// Resource management
 	resourceMgr       *enterprise.ResourceManager
 	metricsCollector  *MetricsCollector
 	quotaEnforcer     *QuotaEnforcer
 
+	// Validates cluster-issued SSO tokens (nil when no JWT secret is configured)
+	jwtManager *auth.JWTManager
+
 	// Health and monitoring
 	healthChecker *health.Checker
 	metrics       *metrics.Collector
+	tenantMetrics *metrics.TenantMetrics
 
 	// Lifecycle
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 
-	logger *log.Logger
+	logger *slog.Logger
 }
 
 // NewManager creates a new tenant node manager
@@ -84,19 +116,32 @@ func NewManager(config *enterprise.ClusterConfig, storage enterprise.StorageBack
 		dataDir:           dataDir,
 		tenants:           make(map[string]*enterprise.TenantInstance),
 		accessOrder:       make([]string, 0),
+		realtime:          NewRealtimeTracker(),
+		changes:           NewChangeFeed(nodeID),
+		drained:           make(chan struct{}),
 		capacity:          config.MaxTenants,
 		healthChecker:     healthChecker,
 		metrics:           metricsCollector,
 		ctx:               ctx,
 		cancel:            cancel,
-		logger:            log.Default(),
+		logger:            enterprise.ComponentLogger(enterprise.LogComponentTenantNode),
 	}
 
+	// Per-tenant Prometheus series, bounded to the busiest and allowlisted tenants
+	mgr.tenantMetrics = metrics.NewTenantMetrics("tenant_node")
+	mgr.tenantMetrics.Configure(config.Metrics.TenantTopN, config.Metrics.TenantAllowlist)
+
 	// Initialize Litestream manager (after mgr is created to avoid package name collision)
 	mgr.litestreamManager = storagepkg.NewLitestreamManager(config)
 
+	// Uploaded files aren't in the databases, Litestream doesn't replicate them
+	if storage != nil {
+		mgr.files = NewFileReplicator(storage)
+	}
+
 	// Initialize resource manager
 	mgr.resourceMgr = enterprise.NewResourceManager()
+	mgr.resourceMgr.SetQuotas(enterprise.MergeResourceQuotas(config.Quotas))
 	mgr.setupResourceCallbacks()
 
 	// Initialize metrics collector
@@ -105,9 +150,20 @@ func NewManager(config *enterprise.ClusterConfig, storage enterprise.StorageBack
 	// Initialize quota enforcer
 	mgr.quotaEnforcer = NewQuotaEnforcer(mgr)
 
+	// SSO tokens must be signed with the cluster secret, a random one would reject them all
+	if config.JWTSecret != "" {
+		jwtManager, err := auth.NewJWTManager(config.JWTSecret)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		mgr.jwtManager = jwtManager
+	}
+
 	// Initialize tenant archiver (if storage is S3Backend)
 	if s3Backend, ok := storage.(*storagepkg.S3Backend); ok {
-		mgr.archiver = NewTenantArchiver(mgr, s3Backend, mgr.litestreamManager, nil)
+		s3Backend.SetKeySource(nodeKeySource{cpClient: cpClient, nodeID: nodeID})
+		mgr.archiver = NewTenantArchiver(mgr, s3Backend, mgr.litestreamManager, ArchiveConfigFromSettings(config.Archive))
 	}
 
 	return mgr, nil
@@ -115,7 +171,7 @@ func NewManager(config *enterprise.ClusterConfig, storage enterprise.StorageBack
 
 // Start initializes and starts the tenant node manager
 func (m *Manager) Start() error {
-	m.logger.Printf("[TenantNode] Starting tenant node: %s", m.nodeID)
+	m.logger.Info("Starting tenant node", "nodeId", m.nodeID)
 
 	// Register with control plane
 	nodeAddress := m.config.NodeAddress
@@ -128,6 +184,7 @@ func (m *Manager) Start() error {
 		Address:  nodeAddress,
 		Status:   "online",
 		Capacity: m.capacity,
+		Region:   m.config.Region,
 	}
 
 	if err := m.cpClient.RegisterNode(m.ctx, nodeInfo); err != nil {
@@ -139,7 +196,8 @@ func (m *Manager) Start() error {
 		if m.cpClient == nil {
 			return fmt.Errorf("control plane client not initialized")
 		}
-		// Test connectivity by getting metadata (using a dummy tenant ID)
+		// Test connectivity by getting metadata (using a dummy tenant ID) from any control plane node
+		ctx = enterprise.WithStaleReads(ctx, m.config.Reads.MaxStalenessDuration())
 		_, err := m.cpClient.GetTenantMetadata(ctx, "health-check-test")
 		if err != nil && err.Error() != "tenant not found" {
 			return fmt.Errorf("control plane unreachable: %w", err)
@@ -177,9 +235,17 @@ func (m *Manager) Start() error {
 	})
 
 	// Start background tasks
-	m.wg.Add(2)
+	m.wg.Add(5)
 	go m.sendHeartbeats()
 	go m.evictIdleTenants()
+	go m.pollFleetMigrations()
+	go m.pollTenantHandoffs()
+	go m.publishTenantMetrics()
+
+	if m.files != nil {
+		m.wg.Add(1)
+		go m.replicateFiles()
+	}
 
 	// Start resource manager
 	if m.resourceMgr != nil {
@@ -196,13 +262,13 @@ func (m *Manager) Start() error {
 		m.archiver.Start()
 	}
 
-	m.logger.Printf("[TenantNode] Tenant node started successfully")
+	m.logger.Info("Tenant node started successfully")
 	return nil
 }
 
 // Stop gracefully shuts down the tenant node manager
 func (m *Manager) Stop() error {
-	m.logger.Printf("[TenantNode] Stopping tenant node...")
+	m.logger.Info("Stopping tenant node")
 
 	m.cancel()
 	m.wg.Wait()
@@ -220,31 +286,36 @@ func (m *Manager) Stop() error {
 	// Stop all Litestream replications first
 	if m.litestreamManager != nil {
 		if err := m.litestreamManager.StopAllReplications(); err != nil {
-			m.logger.Printf("[TenantNode] Error stopping Litestream replications: %v", err)
+			m.logger.Error("Error stopping Litestream replications", "error", err)
 		}
 	}
 
 	// Unload all tenants
 	m.tenantsMu.Lock()
-	defer m.tenantsMu.Unlock()
+	defer m.unlockTenants()
 
 	for tenantID := range m.tenants {
 		if err := m.unloadTenantLocked(tenantID); err != nil {
-			m.logger.Printf("[TenantNode] Error unloading tenant %s: %v", tenantID, err)
+			m.logger.Error("Error unloading tenant", "tenantId", tenantID, "error", err)
 		}
 	}
 
-	m.logger.Printf("[TenantNode] Tenant node stopped")
+	m.logger.Info("Tenant node stopped")
 	return nil
 }
 
 // LoadTenant loads a tenant from S3 or returns from cache
-func (m *Manager) LoadTenant(ctx context.Context, tenantID string) (*enterprise.TenantInstance, error) {
+func (m *Manager) LoadTenant(ctx context.Context, tenantID string) (_ *enterprise.TenantInstance, err error) {
 	m.tenantsMu.Lock()
-	defer m.tenantsMu.Unlock()
+	defer m.unlockTenants()
+
+	m.waitForUnloadLocked(tenantID)
 
 	// Check cache first
 	if instance, exists := m.tenants[tenantID]; exists {
+		if err := checkLease(instance); err != nil {
+			return nil, err
+		}
 		m.updateAccessOrder(tenantID)
 		instance.LastAccessed = time.Now()
 		instance.RequestCount++
@@ -255,12 +326,20 @@ func (m *Manager) LoadTenant(ctx context.Context, tenantID string) (*enterprise.
 		return instance, nil
 	}
 
+	// A draining node keeps serving what it has loaded but takes nothing new
+	if m.draining {
+		return nil, enterprise.NewTenantError(tenantID, enterprise.ErrNodeDraining)
+	}
+
 	// Track load duration
 	start := time.Now()
 	defer func() {
 		m.metrics.TenantLoadDuration.Observe(time.Since(start).Seconds())
 	}()
 
+	ctx, span := enterprise.StartSpan(ctx, "tenant.load", enterprise.AttrTenantID.String(tenantID), enterprise.AttrNodeID.String(m.nodeID))
+	defer func() { enterprise.EndSpan(span, err) }()
+
 	// Check weighted capacity (large tenants count as multiple slots)
 	// Use locked version since we already hold tenantsMu
 	used, total := m.getWeightedCapacityLocked()
@@ -271,60 +350,141 @@ func (m *Manager) LoadTenant(ctx context.Context, tenantID string) (*enterprise.
 		}
 	}
 
-	// Get tenant metadata from control plane
-	tenant, err := m.cpClient.GetTenantMetadata(ctx, tenantID)
+	// Get tenant metadata from any control plane node within the max staleness,
+	// the lease acquired below is still granted by the leader
+	tenant, err := m.cpClient.GetTenantMetadata(enterprise.WithStaleReads(ctx, m.config.Reads.MaxStalenessDuration()), tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tenant metadata: %w", err)
 	}
 
+	// Archived tenant data is in Glacier and can't be read until restored
+	if tenant.Status == enterprise.TenantStatusArchived {
+		return nil, enterprise.NewTenantError(tenantID, enterprise.ErrTenantArchived)
+	}
+
+	if tenant.Status == enterprise.TenantStatusDeleted {
+		return nil, enterprise.NewTenantError(tenantID, enterprise.ErrTenantNotFound)
+	}
+
+	// Suspended tenants stay unloaded until an admin lifts the suspension
+	if tenant.Suspended() {
+		return nil, enterprise.NewTenantError(tenantID, enterprise.ErrTenantSuspended)
+	}
+
+	// Loading a tenant placed on another node, or being handed off from this one,
+	// would serve it from two nodes at once
+	if tenant.MovingToNodeID != "" || (tenant.AssignedNodeID != "" && tenant.AssignedNodeID != m.nodeID) {
+		return nil, enterprise.NewTenantError(tenantID, enterprise.ErrTenantMoved)
+	}
+
+	// This node can only reach its own region's bucket, and residency rules
+	// forbid serving the tenant from elsewhere anyway
+	if tenant.Region != "" && m.config.Region != "" && tenant.Region != m.config.Region {
+		return nil, enterprise.NewTenantError(tenantID, enterprise.ErrRegionMismatch)
+	}
+
+	// Only the lease holder may serve and replicate the tenant. The lease is
+	// good for its TTL from before it was asked for.
+	leaseRequested := time.Now()
+	lease, err := m.cpClient.AcquireTenantLease(ctx, tenantID, m.nodeID)
+	if errors.Is(err, enterprise.ErrTenantLeaseHeld) || errors.Is(err, enterprise.ErrTenantNotFound) ||
+		errors.Is(err, enterprise.ErrTenantArchived) || errors.Is(err, enterprise.ErrTenantSuspended) {
+		// The metadata checked above may be stale, the control plane has the last word
+		return nil, enterprise.NewTenantError(tenantID, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire tenant lease: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			m.releaseLease(lease)
+		}
+	}()
+
+	// Data keys for encrypting replicas (nil for tenants created before encryption
+	// was enabled), only handed out to the lease holder
+	keys, err := m.getTenantKeys(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Restore tenant databases from S3 using Litestream
 	tenantDir := filepath.Join(m.dataDir, tenantID)
 
 	// Restore each database using Litestream (handles both existing and new databases)
-	if err := m.litestreamManager.RestoreDatabase(ctx, tenantID, "data.db", filepath.Join(tenantDir, "data.db")); err != nil {
+	if err := m.litestreamManager.RestoreDatabase(ctx, tenantID, "data.db", filepath.Join(tenantDir, "data.db"), keys, lease.Epoch); err != nil {
 		return nil, fmt.Errorf("failed to restore data.db: %w", err)
 	}
 
-	if err := m.litestreamManager.RestoreDatabase(ctx, tenantID, "auxiliary.db", filepath.Join(tenantDir, "auxiliary.db")); err != nil {
+	if err := m.litestreamManager.RestoreDatabase(ctx, tenantID, "auxiliary.db", filepath.Join(tenantDir, "auxiliary.db"), keys, lease.Epoch); err != nil {
 		return nil, fmt.Errorf("failed to restore auxiliary.db: %w", err)
 	}
 
-	if err := m.litestreamManager.RestoreDatabase(ctx, tenantID, "hooks.db", filepath.Join(tenantDir, "hooks.db")); err != nil {
+	if err := m.litestreamManager.RestoreDatabase(ctx, tenantID, "hooks.db", filepath.Join(tenantDir, "hooks.db"), keys, lease.Epoch); err != nil {
 		return nil, fmt.Errorf("failed to restore hooks.db: %w", err)
 	}
 
+	// Restore the uploaded files, the local copy may be stale or missing
+	if m.files != nil {
+		if err := m.files.Restore(ctx, tenant, m.tenantStorageDir(tenantID)); err != nil {
+			return nil, fmt.Errorf("failed to restore uploaded files: %w", err)
+		}
+	}
+
+	// PocketBase encrypts the tenant settings with this key. It is given to the
+	// app directly, an env var would be shared by all tenants of the process.
+	var settingsKey string
+	if keys != nil {
+		settingsKey = storagepkg.SettingsKey(keys)
+	}
+
 	// Create PocketBase app instance for this tenant
 	app := core.NewBaseApp(core.BaseAppConfig{
 		DataDir:       tenantDir,
-		EncryptionEnv: fmt.Sprintf("PB_ENCRYPTION_%s", tenantID),
+		EncryptionKey: settingsKey,
 		IsDev:         false,
+		DBConnect:     tracedDBConnect(m.config.Tracing.FullStatements),
 	})
 
 	// Bootstrap the app
-	if err := app.Bootstrap(); err != nil {
+	_, bootstrapSpan := enterprise.StartSpan(ctx, "tenant.bootstrap", enterprise.AttrTenantID.String(tenantID))
+	err = app.Bootstrap()
+	enterprise.EndSpan(bootstrapSpan, err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to bootstrap tenant app: %w", err)
 	}
 
+	// Publish record changes so gateways can invalidate cached responses
+	m.bindChangeHooks(tenantID, app)
+
+	// Write uploaded files through to S3
+	if m.files != nil {
+		m.files.Bind(app, tenant, m.tenantStorageDir(tenantID))
+	}
+
+	// Catch up with the fleet migrations rolled out since the tenant was last loaded
+	m.migrateTenant(ctx, tenantID, app)
+
 	// Start Litestream replication for all databases
 	litestreamRunning := true
 
-	if err := m.litestreamManager.StartReplication(tenantID, filepath.Join(tenantDir, "data.db"), "data.db"); err != nil {
-		m.logger.Printf("[TenantNode] Failed to start Litestream for data.db: %v", err)
+	if err := m.litestreamManager.StartReplication(tenantID, filepath.Join(tenantDir, "data.db"), "data.db", keys, lease.Epoch); err != nil {
+		m.logger.Error("Failed to start Litestream for data.db", "error", err)
 		litestreamRunning = false
 	}
 
-	if err := m.litestreamManager.StartReplication(tenantID, filepath.Join(tenantDir, "auxiliary.db"), "auxiliary.db"); err != nil {
-		m.logger.Printf("[TenantNode] Failed to start Litestream for auxiliary.db: %v", err)
+	if err := m.litestreamManager.StartReplication(tenantID, filepath.Join(tenantDir, "auxiliary.db"), "auxiliary.db", keys, lease.Epoch); err != nil {
+		m.logger.Error("Failed to start Litestream for auxiliary.db", "error", err)
 		litestreamRunning = false
 	}
 
-	if err := m.litestreamManager.StartReplication(tenantID, filepath.Join(tenantDir, "hooks.db"), "hooks.db"); err != nil {
-		m.logger.Printf("[TenantNode] Failed to start Litestream for hooks.db: %v", err)
+	if err := m.litestreamManager.StartReplication(tenantID, filepath.Join(tenantDir, "hooks.db"), "hooks.db", keys, lease.Epoch); err != nil {
+		m.logger.Error("Failed to start Litestream for hooks.db", "error", err)
 		litestreamRunning = false
 	}
 
 	// Create HTTP router for the tenant app
-	httpHandler, err := m.createTenantHTTPHandler(app)
+	httpHandler, err := m.createTenantHTTPHandler(tenantID, app)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP handler: %w", err)
 	}
@@ -338,6 +498,8 @@ func (m *Manager) LoadTenant(ctx context.Context, tenantID string) (*enterprise.
 		LastAccessed:      time.Now(),
 		RequestCount:      1,
 		LitestreamRunning: litestreamRunning,
+		LeaseEpoch:        lease.Epoch,
+		LeaseValidUntil:   leaseRequested.Add(enterprise.TenantLeaseTTL),
 	}
 
 	// Cache the instance
@@ -352,25 +514,111 @@ func (m *Manager) LoadTenant(ctx context.Context, tenantID string) (*enterprise.
 	// Record resource metrics
 	m.recordTenantMetrics(tenantID, instance)
 
-	m.logger.Printf("[TenantNode] Loaded tenant: %s", tenantID)
+	m.logger.Info("Loaded tenant", "tenantId", tenantID, "leaseEpoch", lease.Epoch)
 
 	// Notify control plane
 	if err := m.cpClient.UpdateTenantStatus(ctx, tenantID, enterprise.TenantStatusActive); err != nil {
-		m.logger.Printf("[TenantNode] Failed to update tenant status: %v", err)
+		m.logger.Error("Failed to update tenant status", "error", err)
 	}
 
 	return instance, nil
 }
 
+// getTenantKeys fetches the data keys of a tenant. Tenants created before
+// encryption was enabled have no keys and are replicated unencrypted.
+func (m *Manager) getTenantKeys(ctx context.Context, tenantID string) (*enterprise.TenantDataKeys, error) {
+	keys, err := m.cpClient.GetTenantKeys(ctx, tenantID, m.nodeID)
+	if err == enterprise.ErrTenantKeysNotFound {
+		return nil, nil
+	}
+	if err == enterprise.ErrTenantKeysShredded {
+		return nil, enterprise.NewTenantError(tenantID, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant data keys: %w", err)
+	}
+	return keys, nil
+}
+
+// nodeKeySource hands out the data keys of the tenants whose lease the node holds
+type nodeKeySource struct {
+	cpClient enterprise.ControlPlaneClient
+	nodeID   string
+}
+
+// GetTenantKeys implements enterprise.TenantKeySource
+func (s nodeKeySource) GetTenantKeys(ctx context.Context, tenantID string) (*enterprise.TenantDataKeys, error) {
+	return s.cpClient.GetTenantKeys(ctx, tenantID, s.nodeID)
+}
+
 // UnloadTenant removes a tenant from memory and syncs to S3
 func (m *Manager) UnloadTenant(ctx context.Context, tenantID string) error {
 	m.tenantsMu.Lock()
-	defer m.tenantsMu.Unlock()
+	defer m.unlockTenants()
 
 	return m.unloadTenantLocked(tenantID)
 }
 
-// unloadTenantLocked unloads a tenant (must be called with lock held)
+// pendingUnload is the part of an unload that runs after tenantsMu is released:
+// the file uploads to S3 and the lease release
+type pendingUnload struct {
+	lease *enterprise.TenantLease
+	done  chan struct{}
+}
+
+// unlockTenants releases tenantsMu, then finishes the unloads made while it was
+// held. Callers that may unload tenants release the lock with it, so S3 and
+// the control plane don't hold up the node's other tenants.
+func (m *Manager) unlockTenants() {
+	pending := m.pendingUnloads
+	m.pendingUnloads = nil
+	m.tenantsMu.Unlock()
+
+	for _, unload := range pending {
+		m.finishUnload(unload)
+	}
+}
+
+// finishUnload replicates the pending files of an unloaded tenant and hands
+// its lease back
+func (m *Manager) finishUnload(unload *pendingUnload) {
+	tenantID := unload.lease.TenantID
+
+	// Files failing again stay pending and are retried on the next load here.
+	// Not bound to the manager context, which is already canceled on shutdown.
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), fileFlushTimeout)
+	if err := m.flushTenantFiles(flushCtx, tenantID); err != nil {
+		m.logger.Error("Error replicating files for tenant", "tenantId", tenantID, "error", err)
+	}
+	cancelFlush()
+
+	// Synced, so another node may take over without waiting for the lease to expire
+	m.releaseLease(unload.lease)
+
+	m.tenantsMu.Lock()
+	delete(m.unloading, tenantID)
+	m.tenantsMu.Unlock()
+
+	close(unload.done)
+}
+
+// waitForUnloadLocked blocks until a running unload of the tenant, if any,
+// finishes. tenantsMu is released while waiting (must be called with lock held).
+func (m *Manager) waitForUnloadLocked(tenantID string) {
+	for {
+		done, exists := m.unloading[tenantID]
+		if !exists {
+			return
+		}
+
+		m.tenantsMu.Unlock()
+		<-done
+		m.tenantsMu.Lock()
+	}
+}
+
+// unloadTenantLocked unloads a tenant (must be called with lock held). Its
+// files are flushed and its lease released by unlockTenants once the lock is.
 func (m *Manager) unloadTenantLocked(tenantID string) error {
 	instance, exists := m.tenants[tenantID]
 	if !exists {
@@ -383,17 +631,25 @@ func (m *Manager) unloadTenantLocked(tenantID string) error {
 		m.metrics.TenantUnloadDuration.Observe(time.Since(start).Seconds())
 	}()
 
-	m.logger.Printf("[TenantNode] Unloading tenant: %s", tenantID)
+	m.logger.Info("Unloading tenant", "tenantId", tenantID)
+
+	// Never shut the app down under a running fleet migration
+	m.waitForMigration(tenantID)
+
+	// Close realtime streams first so clients reconnect through the gateway
+	if closed := m.realtime.CloseTenant(tenantID); closed > 0 {
+		m.logger.Info("Closed realtime connections for tenant", "tenantId", tenantID, "connections", closed)
+	}
 
 	// Properly shutdown the PocketBase app instance
 	// This closes database connections, stops cron jobs, and cleans up resources
 	if instance.App != nil {
-		m.logger.Printf("[TenantNode] Shutting down PocketBase app for tenant: %s", tenantID)
+		m.logger.Info("Shutting down PocketBase app for tenant", "tenantId", tenantID)
 
 		// Call ResetBootstrapState to properly clean up the app
 		// This closes database connections, stops cron ticker, etc.
 		if err := instance.App.ResetBootstrapState(); err != nil {
-			m.logger.Printf("[TenantNode] Error resetting bootstrap state for tenant %s: %v", tenantID, err)
+			m.logger.Error("Error resetting bootstrap state for tenant", "tenantId", tenantID, "error", err)
 		}
 	}
 
@@ -401,26 +657,37 @@ func (m *Manager) unloadTenantLocked(tenantID string) error {
 	// This should be done AFTER closing the app to ensure final changes are synced
 	if instance.LitestreamRunning {
 		if err := m.litestreamManager.StopReplication(tenantID, "data.db"); err != nil {
-			m.logger.Printf("[TenantNode] Error stopping Litestream for data.db: %v", err)
+			m.logger.Error("Error stopping Litestream for data.db", "error", err)
 		}
 
 		if err := m.litestreamManager.StopReplication(tenantID, "auxiliary.db"); err != nil {
-			m.logger.Printf("[TenantNode] Error stopping Litestream for auxiliary.db: %v", err)
+			m.logger.Error("Error stopping Litestream for auxiliary.db", "error", err)
 		}
 
 		if err := m.litestreamManager.StopReplication(tenantID, "hooks.db"); err != nil {
-			m.logger.Printf("[TenantNode] Error stopping Litestream for hooks.db: %v", err)
+			m.logger.Error("Error stopping Litestream for hooks.db", "error", err)
 		}
 	}
 
-	// Remove from cache
+	// Remove from cache, loading the tenant again waits for the unload to finish
 	delete(m.tenants, tenantID)
 	m.removeFromAccessOrder(tenantID)
 
+	if m.unloading == nil {
+		m.unloading = make(map[string]chan struct{})
+	}
+	unload := &pendingUnload{
+		lease: &enterprise.TenantLease{TenantID: tenantID, NodeID: m.nodeID, Epoch: instance.LeaseEpoch},
+		done:  make(chan struct{}),
+	}
+	m.unloading[tenantID] = unload.done
+	m.pendingUnloads = append(m.pendingUnloads, unload)
+
 	// Cleanup metrics data to prevent memory leaks
 	if m.metricsCollector != nil {
 		m.metricsCollector.CleanupTenant(tenantID)
 	}
+	m.tenantMetrics.Forget(tenantID)
 
 	// Cleanup quota data to prevent memory leaks
 	if m.quotaEnforcer != nil {
@@ -432,7 +699,7 @@ func (m *Manager) unloadTenantLocked(tenantID string) error {
 	m.metrics.TenantsActive.Set(float64(len(m.tenants)))
 	m.metrics.CacheUtilization.Set(float64(len(m.tenants)) / float64(m.capacity) * 100)
 
-	m.logger.Printf("[TenantNode] Unloaded tenant: %s (requests: %d)", tenantID, instance.RequestCount)
+	m.logger.Info("Unloaded tenant", "tenantId", tenantID, "requestCount", instance.RequestCount)
 	return nil
 }
 
@@ -449,13 +716,19 @@ func (m *Manager) GetTenant(tenantID string) (*enterprise.TenantInstance, error)
 	return instance, nil
 }
 
-// GetOrLoadTenant retrieves a tenant from cache or loads it from S3
-func (m *Manager) GetOrLoadTenant(tenantID string) (*enterprise.TenantInstance, error) {
+// GetOrLoadTenant retrieves a tenant from cache or loads it from S3. Only the
+// trace of ctx is kept: other requests may wait on the load, so it isn't
+// cancelled along with the request that triggered it.
+func (m *Manager) GetOrLoadTenant(ctx context.Context, tenantID string) (*enterprise.TenantInstance, error) {
 	// First check cache
 	instance, err := m.GetTenant(tenantID)
 	if err == nil {
 		// Update access tracking
 		m.tenantsMu.Lock()
+		if err := checkLease(instance); err != nil {
+			m.tenantsMu.Unlock()
+			return nil, err
+		}
 		instance.LastAccessed = time.Now()
 		instance.RequestCount++
 		m.updateAccessOrder(tenantID)
@@ -464,7 +737,7 @@ func (m *Manager) GetOrLoadTenant(tenantID string) (*enterprise.TenantInstance,
 	}
 
 	// Not in cache, load it
-	return m.LoadTenant(m.ctx, tenantID)
+	return m.LoadTenant(trace.ContextWithSpanContext(m.ctx, trace.SpanContextFromContext(ctx)), tenantID)
 }
 
 // ListActiveTenants returns all currently loaded tenants
@@ -483,12 +756,16 @@ func (m *Manager) ListActiveTenants() []*enterprise.TenantInstance {
 // EvictIdleTenants removes tenants that haven't been accessed recently
 func (m *Manager) EvictIdleTenants(idleThreshold time.Duration) error {
 	m.tenantsMu.Lock()
-	defer m.tenantsMu.Unlock()
+	defer m.unlockTenants()
 
 	now := time.Now()
 	toEvict := make([]string, 0)
 
 	for tenantID, instance := range m.tenants {
+		// Open subscriptions keep a tenant busy even without new requests
+		if m.realtime.Count(tenantID) > 0 {
+			continue
+		}
 		if now.Sub(instance.LastAccessed) > idleThreshold {
 			toEvict = append(toEvict, tenantID)
 		}
@@ -496,14 +773,14 @@ func (m *Manager) EvictIdleTenants(idleThreshold time.Duration) error {
 
 	for _, tenantID := range toEvict {
 		if err := m.unloadTenantLocked(tenantID); err != nil {
-			m.logger.Printf("[TenantNode] Failed to evict tenant %s: %v", tenantID, err)
+			m.logger.Error("Failed to evict tenant", "tenantId", tenantID, "error", err)
 		} else {
 			m.metrics.TenantsEvicted.Inc()
 		}
 	}
 
 	if len(toEvict) > 0 {
-		m.logger.Printf("[TenantNode] Evicted %d idle tenants", len(toEvict))
+		m.logger.Info("Evicted idle tenants", "tenants", len(toEvict))
 	}
 
 	return nil
@@ -515,8 +792,16 @@ func (m *Manager) evictLRULocked() error {
 		return fmt.Errorf("no tenants to evict")
 	}
 
-	// First tenant in access order is least recently used
+	// First tenant in access order is least recently used; prefer one
+	// without open realtime connections so subscribers aren't disconnected
 	lruTenantID := m.accessOrder[0]
+	for _, tenantID := range m.accessOrder {
+		if m.realtime.Count(tenantID) == 0 {
+			lruTenantID = tenantID
+			break
+		}
+	}
+
 	err := m.unloadTenantLocked(lruTenantID)
 	if err == nil {
 		m.metrics.TenantsEvicted.Inc()
@@ -557,10 +842,22 @@ func (m *Manager) sendHeartbeats() {
 		case <-ticker.C:
 			m.tenantsMu.RLock()
 			activeCount := len(m.tenants)
+			leases := m.heldLeasesLocked()
 			m.tenantsMu.RUnlock()
 
-			if err := m.cpClient.SendHeartbeat(m.ctx, m.nodeID, activeCount); err != nil {
-				m.logger.Printf("[TenantNode] Failed to send heartbeat: %v", err)
+			sent := time.Now()
+			status, renewed, err := m.cpClient.SendHeartbeat(m.ctx, m.nodeID, activeCount, leases)
+			if err != nil {
+				m.logger.Error("Failed to send heartbeat", "error", err)
+				// Leases run out while the control plane is unreachable
+				m.updateLeases(nil, nil, sent)
+				continue
+			}
+			m.updateLeases(leases, renewed, sent)
+
+			// Drain requested through the admin API
+			if status == enterprise.NodeStatusDraining {
+				m.startDrain()
 			}
 		}
 	}
@@ -580,12 +877,17 @@ func (m *Manager) evictIdleTenants() {
 		case <-ticker.C:
 			// Evict tenants idle for more than 10 minutes
 			if err := m.EvictIdleTenants(10 * time.Minute); err != nil {
-				m.logger.Printf("[TenantNode] Failed to evict idle tenants: %v", err)
+				m.logger.Error("Failed to evict idle tenants", "error", err)
 			}
 		}
 	}
 }
 
+// GetNodeID returns the ID this node registered with the control plane
+func (m *Manager) GetNodeID() string {
+	return m.nodeID
+}
+
 // GetHealthChecker returns the health checker for exposing health endpoints
 func (m *Manager) GetHealthChecker() *health.Checker {
 	return m.healthChecker
@@ -596,20 +898,46 @@ func (m *Manager) GetQuotaEnforcer() *QuotaEnforcer {
 	return m.quotaEnforcer
 }
 
+// GetHotspots returns the resource metrics of the hotspot tenants on this node
+func (m *Manager) GetHotspots() []*enterprise.TenantResourceMetrics {
+	if m.resourceMgr == nil {
+		return []*enterprise.TenantResourceMetrics{}
+	}
+
+	hotspots := m.resourceMgr.GetHotspots()
+	if hotspots == nil {
+		hotspots = []*enterprise.TenantResourceMetrics{}
+	}
+	return hotspots
+}
+
+// GetCircuitBreakers returns the stats of the node's circuit breakers
+func (m *Manager) GetCircuitBreakers() []map[string]interface{} {
+	breakers := make([]map[string]interface{}, 0, 1)
+
+	// Only the real control plane client has a breaker
+	if client, ok := m.cpClient.(interface {
+		CircuitBreakerStats() map[string]interface{}
+	}); ok {
+		breakers = append(breakers, client.CircuitBreakerStats())
+	}
+
+	return breakers
+}
+
 // setupResourceCallbacks configures resource manager callbacks
 func (m *Manager) setupResourceCallbacks() {
 	m.resourceMgr.SetCallbacks(
 		// On hotspot detected
 		func(tenantID string, metrics *enterprise.TenantResourceMetrics) {
-			m.logger.Printf("[TenantNode] Hotspot detected: %s (score: %.2f, tier: %s)",
-				tenantID, metrics.HotspotScore, metrics.Tier)
+			m.logger.Info("Hotspot detected", "tenantId", tenantID, "hotspotScore", metrics.HotspotScore, "tier", metrics.Tier)
 
 			// Notify control plane about hotspot tenant
 			go func() {
 				// Get tenant instance to update metadata
 				instance, err := m.GetTenant(tenantID)
 				if err != nil {
-					m.logger.Printf("[TenantNode] Failed to get tenant for hotspot notification: %v", err)
+					m.logger.Error("Failed to get tenant for hotspot notification", "error", err)
 					return
 				}
 
@@ -618,7 +946,7 @@ func (m *Manager) setupResourceCallbacks() {
 
 				// For enterprise tier, suggest reassignment to dedicated node pool
 				if metrics.Tier == enterprise.TenantTierEnterprise {
-					m.logger.Printf("[TenantNode] Notifying control plane: Enterprise tenant %s needs dedicated node", tenantID)
+					m.logger.Info("Notifying control plane: Enterprise tenant needs dedicated node", "tenantId", tenantID)
 					// Control plane will receive updated tenant metadata via next heartbeat
 					// or we can implement a specific hotspot notification endpoint
 				}
@@ -626,22 +954,22 @@ func (m *Manager) setupResourceCallbacks() {
 
 			// For other tiers, check if should evict to free resources
 			if shouldEvict, reason := m.resourceMgr.ShouldEvict(tenantID); shouldEvict {
-				m.logger.Printf("[TenantNode] Evicting hotspot tenant %s: %s", tenantID, reason)
+				m.logger.Info("Evicting hotspot tenant", "tenantId", tenantID, "reason", reason)
 				if err := m.UnloadTenant(m.ctx, tenantID); err != nil {
-					m.logger.Printf("[TenantNode] Failed to evict tenant %s: %v", tenantID, err)
+					m.logger.Error("Failed to evict tenant", "tenantId", tenantID, "error", err)
 				}
 			}
 		},
 		// On tier upgrade
 		func(tenantID string, oldTier, newTier enterprise.TenantTier) {
-			m.logger.Printf("[TenantNode] Tenant %s tier upgraded: %s -> %s", tenantID, oldTier, newTier)
+			m.logger.Info("Tenant tier upgraded", "tenantId", tenantID, "oldTier", oldTier, "newTier", newTier)
 
 			// Notify about tier change
 			go func() {
 				// Get tenant instance
 				instance, err := m.GetTenant(tenantID)
 				if err != nil {
-					m.logger.Printf("[TenantNode] Failed to get tenant for tier upgrade notification: %v", err)
+					m.logger.Error("Failed to get tenant for tier upgrade notification", "error", err)
 					return
 				}
 
@@ -650,7 +978,7 @@ func (m *Manager) setupResourceCallbacks() {
 
 				// For enterprise tier upgrades, suggest dedicated node allocation
 				if newTier == enterprise.TenantTierEnterprise {
-					m.logger.Printf("[TenantNode] Enterprise tier upgrade: Tenant %s may need dedicated resources", tenantID)
+					m.logger.Info("Enterprise tier upgrade: Tenant may need dedicated resources", "tenantId", tenantID)
 					// The control plane will detect this via resource metrics and placement decisions
 					// Tier information is tracked in TenantResourceMetrics, not in Tenant struct
 				}
@@ -658,14 +986,13 @@ func (m *Manager) setupResourceCallbacks() {
 				// For significant tier upgrades (e.g., medium -> large -> enterprise),
 				// the tenant may benefit from being moved to a less crowded node
 				if newTier >= enterprise.TenantTierLarge {
-					m.logger.Printf("[TenantNode] High-tier tenant %s may benefit from rebalancing", tenantID)
+					m.logger.Info("High-tier tenant may benefit from rebalancing", "tenantId", tenantID)
 				}
 			}()
 		},
 		// On quota exceeded
 		func(tenantID string, quotaType string, current, limit int64) {
-			m.logger.Printf("[TenantNode] Tenant %s exceeded %s quota: %d > %d",
-				tenantID, quotaType, current, limit)
+			m.logger.Warn("Tenant exceeded quota", "tenantId", tenantID, "quotaType", quotaType, "current", current, "limit", limit)
 
 			// Quotas are enforced at the HTTP layer and before writes
 			// This callback is just for logging/alerting
@@ -711,6 +1038,8 @@ type ManagerStats struct {
 	Capacity      int
 	MemoryUsedMB  int64
 	CPUPercent    int
+
+	RealtimeConnections int
 }
 
 // GetStats returns current manager statistics
@@ -730,17 +1059,27 @@ func (m *Manager) GetStats() ManagerStats {
 		Capacity:      m.capacity,
 		MemoryUsedMB:  memoryMB,
 		CPUPercent:    cpuPercent,
+
+		RealtimeConnections: m.realtime.Total(),
 	}
 }
 
+// RealtimeConnections returns the number of open realtime connections for a tenant
+func (m *Manager) RealtimeConnections(tenantID string) int {
+	return m.realtime.Count(tenantID)
+}
+
 // createTenantHTTPHandler creates an HTTP handler for a tenant's PocketBase app
-func (m *Manager) createTenantHTTPHandler(app core.App) (http.Handler, error) {
+func (m *Manager) createTenantHTTPHandler(tenantID string, app core.App) (http.Handler, error) {
 	// Create PocketBase router for this tenant app
 	router, err := apis.NewRouter(app)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create router: %w", err)
 	}
 
+	// Logins from the cluster dashboard
+	m.bindSSORoute(router, tenantID)
+
 	// Build the HTTP mux from the router
 	handler, err := router.BuildMux()
 	if err != nil {
@@ -2,6 +2,7 @@ package tenant_node
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -16,6 +17,22 @@ type mockCPClient struct {
 	placements  map[string]*enterprise.PlacementDecision
 	heartbeats  int
 	registerErr error
+	keys        map[string]*enterprise.TenantDataKeys
+	keysErr     error
+	nodeStatus  string
+	drainedWith []string
+	released    []string
+	handoffs    []string
+
+	// Leases by tenant, renewed by heartbeats while the node and epoch match
+	leases         map[string]*enterprise.TenantLease
+	releasedLeases []*enterprise.TenantLease
+	onReleaseLease func(lease *enterprise.TenantLease)
+
+	fleetMigrations map[string][]*enterprise.FleetMigration
+	fleetResults    []*enterprise.FleetMigrationTenant
+
+	usedSSOTokens map[string]bool
 }
 
 func newMockCPClient() *mockCPClient {
@@ -23,6 +40,9 @@ func newMockCPClient() *mockCPClient {
 		tenants:    make(map[string]*enterprise.Tenant),
 		nodes:      make(map[string]*enterprise.NodeInfo),
 		placements: make(map[string]*enterprise.PlacementDecision),
+		leases:     make(map[string]*enterprise.TenantLease),
+
+		usedSSOTokens: make(map[string]bool),
 	}
 }
 
@@ -60,11 +80,69 @@ func (m *mockCPClient) RegisterNode(ctx context.Context, nodeInfo *enterprise.No
 	return nil
 }
 
-func (m *mockCPClient) SendHeartbeat(ctx context.Context, nodeID string, activeTenantsCount int) error {
+func (m *mockCPClient) SendHeartbeat(ctx context.Context, nodeID string, activeTenantsCount int, leases []*enterprise.TenantLease) (string, []*enterprise.TenantLease, error) {
 	m.heartbeats++
+
+	renewed := make([]*enterprise.TenantLease, 0, len(leases))
+	for _, lease := range leases {
+		if held, ok := m.leases[lease.TenantID]; ok && held.NodeID == nodeID && held.Epoch == lease.Epoch {
+			renewed = append(renewed, held)
+		}
+	}
+
+	if m.nodeStatus == "" {
+		return enterprise.NodeStatusOnline, renewed, nil
+	}
+	return m.nodeStatus, renewed, nil
+}
+
+func (m *mockCPClient) AcquireTenantLease(ctx context.Context, tenantID, nodeID string) (*enterprise.TenantLease, error) {
+	lease, ok := m.leases[tenantID]
+	if !ok {
+		lease = &enterprise.TenantLease{TenantID: tenantID}
+		m.leases[tenantID] = lease
+	}
+	if lease.NodeID != nodeID {
+		if lease.NodeID != "" {
+			return nil, enterprise.ErrTenantLeaseHeld
+		}
+		lease.NodeID = nodeID
+		lease.Epoch++
+	}
+	return lease, nil
+}
+
+func (m *mockCPClient) ReleaseTenantLease(ctx context.Context, lease *enterprise.TenantLease) error {
+	if m.onReleaseLease != nil {
+		m.onReleaseLease(lease)
+	}
+	m.releasedLeases = append(m.releasedLeases, lease)
 	return nil
 }
 
+func (m *mockCPClient) UseSSOToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if m.usedSSOTokens[tokenID] {
+		return enterprise.ErrSSOTokenUsed
+	}
+	m.usedSSOTokens[tokenID] = true
+	return nil
+}
+
+func (m *mockCPClient) DrainNode(ctx context.Context, nodeID string, loadedTenants []string) error {
+	m.nodeStatus = enterprise.NodeStatusDraining
+	m.drainedWith = loadedTenants
+	return nil
+}
+
+func (m *mockCPClient) ReleaseTenant(ctx context.Context, tenantID, nodeID string) error {
+	m.released = append(m.released, tenantID)
+	return nil
+}
+
+func (m *mockCPClient) GetTenantHandoffs(ctx context.Context, nodeID string) ([]string, error) {
+	return m.handoffs, nil
+}
+
 func (m *mockCPClient) GetPlacementDecision(ctx context.Context, tenantID string) (*enterprise.PlacementDecision, error) {
 	p, exists := m.placements[tenantID]
 	if !exists {
@@ -73,6 +151,67 @@ func (m *mockCPClient) GetPlacementDecision(ctx context.Context, tenantID string
 	return p, nil
 }
 
+func (m *mockCPClient) RequestTenantRestore(ctx context.Context, tenantID string, expedited bool) (*enterprise.RestoreJob, error) {
+	if _, exists := m.tenants[tenantID]; !exists {
+		return nil, enterprise.ErrTenantNotFound
+	}
+	return &enterprise.RestoreJob{TenantID: tenantID, Status: enterprise.RestoreJobReady}, nil
+}
+
+func (m *mockCPClient) RegisterGateway(ctx context.Context, gateway *enterprise.GatewayInfo) ([]*enterprise.GatewayInfo, error) {
+	return []*enterprise.GatewayInfo{gateway}, nil
+}
+
+func (m *mockCPClient) CheckpointUsage(ctx context.Context, checkpoints []*enterprise.UsageCheckpoint) error {
+	return nil
+}
+
+func (m *mockCPClient) AppendLogs(ctx context.Context, entries []*enterprise.LogEntry) error {
+	return nil
+}
+
+func (m *mockCPClient) GetUsageCheckpoint(ctx context.Context, tenantID string) (*enterprise.UsageCheckpoint, error) {
+	return nil, enterprise.ErrUsageCheckpointNotFound
+}
+
+func (m *mockCPClient) GetFleetMigrations(ctx context.Context, tenantIDs []string) (map[string][]*enterprise.FleetMigration, error) {
+	migrations := make(map[string][]*enterprise.FleetMigration)
+	for _, tenantID := range tenantIDs {
+		if pending := m.fleetMigrations[tenantID]; len(pending) > 0 {
+			migrations[tenantID] = pending
+		}
+	}
+	return migrations, nil
+}
+
+func (m *mockCPClient) GetFleetBatch(ctx context.Context, nodeID string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockCPClient) ReportFleetMigration(ctx context.Context, result *enterprise.FleetMigrationTenant) error {
+	m.fleetResults = append(m.fleetResults, result)
+
+	pending := make([]*enterprise.FleetMigration, 0)
+	for _, migration := range m.fleetMigrations[result.TenantID] {
+		if migration.ID != result.MigrationID {
+			pending = append(pending, migration)
+		}
+	}
+	m.fleetMigrations[result.TenantID] = pending
+	return nil
+}
+
+func (m *mockCPClient) GetTenantKeys(ctx context.Context, tenantID, nodeID string) (*enterprise.TenantDataKeys, error) {
+	if m.keysErr != nil {
+		return nil, m.keysErr
+	}
+	keys, exists := m.keys[tenantID]
+	if !exists {
+		return nil, enterprise.ErrTenantKeysNotFound
+	}
+	return keys, nil
+}
+
 func (m *mockCPClient) addTenant(t *enterprise.Tenant) {
 	m.tenants[t.ID] = t
 }
@@ -92,6 +231,22 @@ func (m *mockStorageBackend) DeleteTenantData(ctx context.Context, tenant *enter
 	return nil
 }
 
+func (m *mockStorageBackend) ListTenantFiles(ctx context.Context, tenant *enterprise.Tenant, prefix string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockStorageBackend) UploadTenantFiles(ctx context.Context, tenant *enterprise.Tenant, dir string, keys []string) error {
+	return nil
+}
+
+func (m *mockStorageBackend) DownloadTenantFiles(ctx context.Context, tenant *enterprise.Tenant, dir string, keys []string) error {
+	return nil
+}
+
+func (m *mockStorageBackend) DeleteTenantFiles(ctx context.Context, tenant *enterprise.Tenant, keys []string) error {
+	return nil
+}
+
 func (m *mockStorageBackend) ListTenantBackups(ctx context.Context, tenantID string) ([]string, error) {
 	return []string{}, nil
 }
@@ -517,16 +672,20 @@ func TestMockCPClientRegisterNode(t *testing.T) {
 func TestMockCPClientSendHeartbeat(t *testing.T) {
 	client := newMockCPClient()
 
-	err := client.SendHeartbeat(context.Background(), "node-1", 5)
+	status, _, err := client.SendHeartbeat(context.Background(), "node-1", 5, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
+	if status != enterprise.NodeStatusOnline {
+		t.Errorf("expected status online, got %s", status)
+	}
+
 	if client.heartbeats != 1 {
 		t.Errorf("expected 1 heartbeat, got %d", client.heartbeats)
 	}
 
-	client.SendHeartbeat(context.Background(), "node-1", 6)
+	client.SendHeartbeat(context.Background(), "node-1", 6, nil)
 
 	if client.heartbeats != 2 {
 		t.Errorf("expected 2 heartbeats, got %d", client.heartbeats)
@@ -558,3 +717,145 @@ func TestMockCPClientGetPlacementDecision(t *testing.T) {
 		t.Errorf("expected ErrTenantNotAssigned, got %v", err)
 	}
 }
+
+func TestGetTenantKeys(t *testing.T) {
+	cpClient := newMockCPClient()
+	mgr := &Manager{cpClient: cpClient}
+	ctx := context.Background()
+
+	// Tenants created before encryption was enabled load unencrypted
+	keys, err := mgr.getTenantKeys(ctx, "legacy")
+	if err != nil || keys != nil {
+		t.Errorf("expected no keys and no error for legacy tenant, got %v (%v)", keys, err)
+	}
+
+	cpClient.keys = map[string]*enterprise.TenantDataKeys{
+		"tenant-1": {TenantID: "tenant-1", ActiveVersion: 1},
+	}
+	keys, err = mgr.getTenantKeys(ctx, "tenant-1")
+	if err != nil || keys == nil || keys.TenantID != "tenant-1" {
+		t.Errorf("expected keys of tenant-1, got %v (%v)", keys, err)
+	}
+
+	// A shredded tenant must never be loaded
+	cpClient.keysErr = enterprise.ErrTenantKeysShredded
+	if _, err := mgr.getTenantKeys(ctx, "tenant-1"); !errors.Is(err, enterprise.ErrTenantKeysShredded) {
+		t.Errorf("expected ErrTenantKeysShredded, got %v", err)
+	}
+}
+
+func TestDrainRefusesNewTenants(t *testing.T) {
+	cpClient := newMockCPClient()
+	cpClient.addTenant(&enterprise.Tenant{ID: "tenant-1", Status: enterprise.TenantStatusActive})
+	mgr := &Manager{
+		nodeID:   "node-1",
+		cpClient: cpClient,
+		tenants:  make(map[string]*enterprise.TenantInstance),
+		drained:  make(chan struct{}),
+		ctx:      context.Background(),
+		logger:   enterprise.ComponentLogger(enterprise.LogComponentTenantNode),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := mgr.Drain(ctx); err != nil {
+		t.Fatalf("drain failed: %v", err)
+	}
+
+	if !mgr.IsDraining() {
+		t.Error("expected node to be draining")
+	}
+
+	if cpClient.nodeStatus != enterprise.NodeStatusDraining {
+		t.Errorf("expected control plane to be told about the drain, got status %q", cpClient.nodeStatus)
+	}
+
+	select {
+	case <-mgr.Drained():
+	default:
+		t.Error("expected drained to be closed")
+	}
+
+	if _, err := mgr.LoadTenant(ctx, "tenant-1"); !errors.Is(err, enterprise.ErrNodeDraining) {
+		t.Errorf("expected ErrNodeDraining, got %v", err)
+	}
+
+	// Draining again is a no-op
+	if err := mgr.Drain(ctx); err != nil {
+		t.Errorf("second drain failed: %v", err)
+	}
+}
+
+func TestArchiveConfigFromSettings(t *testing.T) {
+	config := ArchiveConfigFromSettings(enterprise.ArchiveSettings{
+		WarmAfter: "48h",
+		MaxPerRun: 10,
+	})
+
+	if config.WarmThreshold != 48*time.Hour || config.MaxArchivePerRun != 10 {
+		t.Errorf("expected configured thresholds, got %+v", config)
+	}
+
+	defaults := DefaultArchiveConfig()
+	if config.ColdThreshold != defaults.ColdThreshold || config.GlacierStorageClass != defaults.GlacierStorageClass {
+		t.Errorf("expected unset settings to keep defaults, got %+v", config)
+	}
+}
+
+func TestLoadTenantRefusesOtherRegion(t *testing.T) {
+	mgr := getTestManager(t)
+	mgr.config.Region = "us-east"
+	defer func() { mgr.config.Region = "" }()
+
+	mgr.cpClient.(*mockCPClient).addTenant(&enterprise.Tenant{
+		ID:     "tenant-eu",
+		Status: enterprise.TenantStatusActive,
+		Region: "eu-west",
+	})
+
+	if _, err := mgr.LoadTenant(context.Background(), "tenant-eu"); !errors.Is(err, enterprise.ErrRegionMismatch) {
+		t.Errorf("expected ErrRegionMismatch, got %v", err)
+	}
+}
+
+func TestLoadTenantRefusesMovedTenant(t *testing.T) {
+	mgr := getTestManager(t)
+	cpClient := mgr.cpClient.(*mockCPClient)
+
+	cpClient.addTenant(&enterprise.Tenant{
+		ID:             "tenant-elsewhere",
+		Status:         enterprise.TenantStatusActive,
+		AssignedNodeID: "other-node",
+	})
+	cpClient.addTenant(&enterprise.Tenant{
+		ID:             "tenant-leaving",
+		Status:         enterprise.TenantStatusActive,
+		AssignedNodeID: mgr.nodeID,
+		MovingToNodeID: "other-node",
+	})
+
+	for _, tenantID := range []string{"tenant-elsewhere", "tenant-leaving"} {
+		if _, err := mgr.LoadTenant(context.Background(), tenantID); !errors.Is(err, enterprise.ErrTenantMoved) {
+			t.Errorf("expected ErrTenantMoved for %s, got %v", tenantID, err)
+		}
+	}
+}
+
+func TestHandOffTenants(t *testing.T) {
+	cpClient := newMockCPClient()
+	cpClient.handoffs = []string{"tenant-1"}
+
+	mgr := &Manager{
+		nodeID:   "node-1",
+		cpClient: cpClient,
+		tenants:  make(map[string]*enterprise.TenantInstance),
+		logger:   enterprise.ComponentLogger(enterprise.LogComponentTenantNode),
+	}
+
+	mgr.handOffTenants(context.Background())
+
+	if len(cpClient.released) != 1 || cpClient.released[0] != "tenant-1" {
+		t.Errorf("expected tenant-1 to be released, got %v", cpClient.released)
+	}
+}
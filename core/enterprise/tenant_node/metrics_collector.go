@@ -2,7 +2,7 @@ package tenant_node
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -37,7 +37,7 @@ type MetricsCollector struct {
 	errorTrackersMu sync.RWMutex
 
 	mu     sync.RWMutex
-	logger *log.Logger
+	logger *slog.Logger
 }
 
 // dbSizeSnapshot stores database size at a point in time
@@ -81,7 +81,7 @@ func NewMetricsCollector(manager *Manager) *MetricsCollector {
 		requestWindows: make(map[string]*requestWindow),
 		responseTimes:  make(map[string]*responseTimeTracker),
 		errorTrackers:  make(map[string]*errorTracker),
-		logger:         log.Default(),
+		logger:         enterprise.ComponentLogger(enterprise.LogComponentTenantNode),
 	}
 }
 
@@ -138,7 +138,12 @@ func (mc *MetricsCollector) CollectTenantMetrics(tenantID string, instance *ente
 
 // calculateDatabaseSize calculates the total size of tenant databases in MB
 func (mc *MetricsCollector) calculateDatabaseSize(tenantID string) int64 {
-	tenantDir := filepath.Join(mc.manager.dataDir, tenantID)
+	return databaseBytes(mc.manager.dataDir, tenantID) / (1024 * 1024)
+}
+
+// databaseBytes returns the total size of the tenant databases in bytes
+func databaseBytes(dataDir, tenantID string) int64 {
+	tenantDir := filepath.Join(dataDir, tenantID)
 
 	// Calculate size of all database files
 	var totalSize int64
@@ -152,8 +157,7 @@ func (mc *MetricsCollector) calculateDatabaseSize(tenantID string) int64 {
 		}
 	}
 
-	// Convert to MB
-	return totalSize / (1024 * 1024)
+	return totalSize
 }
 
 // estimateMemoryUsage estimates memory usage for a tenant (simplified)
@@ -538,7 +542,7 @@ func (mc *MetricsCollector) CleanupTenant(tenantID string) {
 	delete(mc.lastCPUSample, tenantID)
 	mc.mu.Unlock()
 
-	mc.logger.Printf("[MetricsCollector] Cleaned up metrics data for tenant: %s", tenantID)
+	mc.logger.Info("Cleaned up metrics data for tenant", "tenantId", tenantID)
 }
 
 // Production-ready metrics collection would include:
@@ -0,0 +1,197 @@
+package tenant_node
+
+import (
+	"context"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/plugins/jsvm"
+)
+
+// fleetMigrationPollInterval is how often the node checks for eager rollout
+// batches and for fleet migrations started after its tenants were loaded
+const fleetMigrationPollInterval = 30 * time.Second
+
+// migrateTenant applies the pending fleet migrations of a tenant that is being
+// loaded. Failures are reported to the control plane and never keep the tenant
+// from being served.
+func (m *Manager) migrateTenant(ctx context.Context, tenantID string, app core.App) {
+	pending, err := m.cpClient.GetFleetMigrations(ctx, []string{tenantID})
+	if err != nil {
+		m.logger.Error("Failed to get fleet migrations for tenant", "tenantId", tenantID, "error", err)
+		return
+	}
+
+	m.reportFleetMigrations(ctx, m.applyFleetMigrations(tenantID, app, pending[tenantID]))
+}
+
+// applyFleetMigrations applies fleet migrations to a tenant app, oldest first, and
+// returns the result of each
+func (m *Manager) applyFleetMigrations(tenantID string, app core.App, migrations []*enterprise.FleetMigration) []*enterprise.FleetMigrationTenant {
+	results := make([]*enterprise.FleetMigrationTenant, 0, len(migrations))
+
+	for _, migration := range migrations {
+		result := &enterprise.FleetMigrationTenant{
+			MigrationID: migration.ID,
+			TenantID:    tenantID,
+			Status:      enterprise.FleetMigrationTenantApplied,
+			NodeID:      m.nodeID,
+		}
+
+		if err := runFleetMigration(app, migration); err != nil {
+			m.logger.Warn("Fleet migration failed on tenant", "migrationId", migration.ID, "tenantId", tenantID, "error", err)
+			result.Status = enterprise.FleetMigrationTenantFailed
+			result.Error = err.Error()
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// reportFleetMigrations sends fleet migration results to the control plane
+func (m *Manager) reportFleetMigrations(ctx context.Context, results []*enterprise.FleetMigrationTenant) {
+	for _, result := range results {
+		if err := m.cpClient.ReportFleetMigration(ctx, result); err != nil {
+			m.logger.Error("Failed to report fleet migration for tenant", "migrationId", result.MigrationID, "tenantId", result.TenantID, "error", err)
+		}
+	}
+}
+
+// runFleetMigration applies a fleet migration through the tenant's migrations
+// runner, which records it in the _migrations table so it is never applied twice
+func runFleetMigration(app core.App, migration *enterprise.FleetMigration) error {
+	var list core.MigrationsList
+
+	switch migration.Format {
+	case enterprise.FleetMigrationJSON:
+		collections := []byte(migration.Script)
+		list.Register(func(txApp core.App) error {
+			return txApp.ImportCollectionsByMarshaledJSON(collections, false)
+		}, nil, migration.File())
+	default:
+		if err := jsvm.LoadMigration(&list, migration.File(), migration.Script); err != nil {
+			return err
+		}
+	}
+
+	_, err := core.NewMigrationsRunner(app, list).Up()
+	return err
+}
+
+// pollFleetMigrations periodically loads the tenants scheduled by eager rollouts
+// and migrates the tenants that are already loaded
+func (m *Manager) pollFleetMigrations() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(fleetMigrationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.processFleetMigrations(m.ctx)
+		}
+	}
+}
+
+// processFleetMigrations runs one round of fleet migrations on this node
+func (m *Manager) processFleetMigrations(ctx context.Context) {
+	if m.IsDraining() {
+		return
+	}
+
+	// Loading a tenant applies its pending migrations
+	batch, err := m.cpClient.GetFleetBatch(ctx, m.nodeID)
+	if err != nil {
+		m.logger.Error("Failed to get fleet migration batch", "error", err)
+		return
+	}
+
+	for _, tenantID := range batch {
+		if _, err := m.LoadTenant(ctx, tenantID); err != nil {
+			m.logger.Error("Failed to load tenant for fleet migration", "tenantId", tenantID, "error", err)
+		}
+	}
+
+	m.migrateLoadedTenants(ctx)
+}
+
+// migrateLoadedTenants applies the fleet migrations started after the loaded
+// tenants were loaded
+func (m *Manager) migrateLoadedTenants(ctx context.Context) {
+	m.tenantsMu.RLock()
+	loaded := make([]string, 0, len(m.tenants))
+	for tenantID := range m.tenants {
+		loaded = append(loaded, tenantID)
+	}
+	m.tenantsMu.RUnlock()
+
+	if len(loaded) == 0 {
+		return
+	}
+
+	pending, err := m.cpClient.GetFleetMigrations(ctx, loaded)
+	if err != nil {
+		m.logger.Error("Failed to get fleet migrations", "error", err)
+		return
+	}
+
+	for tenantID, migrations := range pending {
+		instance, finish := m.startMigration(tenantID)
+		if instance == nil {
+			continue // Unloaded meanwhile
+		}
+
+		results := m.applyFleetMigrations(tenantID, instance.App, migrations)
+		finish()
+
+		m.reportFleetMigrations(ctx, results)
+	}
+}
+
+// startMigration returns a loaded tenant to run fleet migrations on, or nil if
+// it isn't loaded. The tenant isn't unloaded until finish is called, without
+// holding tenantsMu while the migrations run.
+func (m *Manager) startMigration(tenantID string) (instance *enterprise.TenantInstance, finish func()) {
+	m.tenantsMu.RLock()
+	defer m.tenantsMu.RUnlock()
+
+	instance, exists := m.tenants[tenantID]
+	if !exists {
+		return nil, nil
+	}
+
+	done := make(chan struct{})
+
+	m.migratingMu.Lock()
+	if m.migrating == nil {
+		m.migrating = make(map[string]chan struct{})
+	}
+	m.migrating[tenantID] = done
+	m.migratingMu.Unlock()
+
+	return instance, func() {
+		m.migratingMu.Lock()
+		delete(m.migrating, tenantID)
+		m.migratingMu.Unlock()
+
+		close(done)
+	}
+}
+
+// waitForMigration blocks until the fleet migration running on a tenant, if
+// any, finishes
+func (m *Manager) waitForMigration(tenantID string) {
+	m.migratingMu.Lock()
+	done := m.migrating[tenantID]
+	m.migratingMu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
@@ -0,0 +1,151 @@
+package tenant_node
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+
+	// Registers the system migrations run by app.Bootstrap
+	_ "github.com/pocketbase/pocketbase/migrations"
+)
+
+func newTestTenantApp(t *testing.T) core.App {
+	t.Helper()
+
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatalf("failed to bootstrap tenant app: %v", err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	return app
+}
+
+func TestMigrateLoadedTenants(t *testing.T) {
+	app := newTestTenantApp(t)
+
+	jsMigration := &enterprise.FleetMigration{
+		ID:     "fleet_js",
+		Format: enterprise.FleetMigrationJS,
+		Script: `migrate((app) => {
+			app.db().newQuery("CREATE TABLE audit_events (id TEXT PRIMARY KEY)").execute()
+		}, (app) => {
+			app.db().newQuery("DROP TABLE audit_events").execute()
+		})`,
+	}
+
+	cpClient := newMockCPClient()
+	cpClient.fleetMigrations = map[string][]*enterprise.FleetMigration{
+		"tenant-1": {
+			jsMigration,
+			{
+				ID:     "fleet_json",
+				Format: enterprise.FleetMigrationJSON,
+				Script: `{"name": "notes"}`,
+			},
+			{
+				ID:     "fleet_broken",
+				Format: enterprise.FleetMigrationJS,
+				Script: `migrate((app) => { throw new Error("boom") })`,
+			},
+		},
+	}
+
+	mgr := &Manager{
+		nodeID:   "node-1",
+		cpClient: cpClient,
+		tenants: map[string]*enterprise.TenantInstance{
+			"tenant-1": {App: app},
+		},
+		logger: enterprise.ComponentLogger(enterprise.LogComponentTenantNode),
+	}
+
+	mgr.migrateLoadedTenants(context.Background())
+
+	results := make(map[string]*enterprise.FleetMigrationTenant)
+	for _, result := range cpClient.fleetResults {
+		results[result.MigrationID] = result
+	}
+
+	if results["fleet_js"] == nil || results["fleet_js"].Status != enterprise.FleetMigrationTenantApplied {
+		t.Errorf("expected fleet_js applied, got %+v", results["fleet_js"])
+	}
+	for _, id := range []string{"fleet_json", "fleet_broken"} {
+		if results[id] == nil || results[id].Status != enterprise.FleetMigrationTenantFailed || results[id].Error == "" {
+			t.Errorf("expected %s to fail with an error, got %+v", id, results[id])
+		}
+	}
+
+	if !app.HasTable("audit_events") {
+		t.Error("expected the JS migration to create audit_events")
+	}
+
+	// Applied migrations are recorded and skipped when sent again
+	if err := runFleetMigration(app, jsMigration); err != nil {
+		t.Errorf("expected reapplying to be a no-op, got %v", err)
+	}
+}
+
+func TestFleetMigrationsHaveNoHostAccess(t *testing.T) {
+	app := newTestTenantApp(t)
+
+	for _, binding := range []string{"$os", "$filesystem", "$filepath", "process", "require"} {
+		migration := &enterprise.FleetMigration{
+			ID:     "fleet_" + strings.TrimPrefix(binding, "$"),
+			Format: enterprise.FleetMigrationJS,
+			Script: `migrate((app) => {
+				if (typeof ` + binding + ` !== "undefined") {
+					throw new Error("` + binding + ` is available")
+				}
+			})`,
+		}
+
+		if err := runFleetMigration(app, migration); err != nil {
+			t.Errorf("expected %s to be unavailable, got %v", binding, err)
+		}
+	}
+}
+
+func TestUnloadWaitsForRunningMigration(t *testing.T) {
+	mgr := &Manager{
+		tenants: map[string]*enterprise.TenantInstance{
+			"tenant-1": {},
+		},
+	}
+
+	_, finish := mgr.startMigration("tenant-1")
+
+	// The node-wide lock is free while the migration runs
+	if !mgr.tenantsMu.TryLock() {
+		t.Fatal("expected tenantsMu to be free during the migration")
+	}
+	mgr.tenantsMu.Unlock()
+
+	waited := make(chan struct{})
+	go func() {
+		mgr.waitForMigration("tenant-1")
+		close(waited)
+	}()
+
+	select {
+	case <-waited:
+		t.Fatal("expected unloading to wait for the running migration")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	finish()
+
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("expected unloading to proceed once the migration finished")
+	}
+
+	if instance, _ := mgr.startMigration("tenant-2"); instance != nil {
+		t.Error("expected no migration for a tenant that isn't loaded")
+	}
+}
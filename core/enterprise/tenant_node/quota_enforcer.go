@@ -2,7 +2,7 @@ package tenant_node
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
@@ -23,7 +23,7 @@ type QuotaEnforcer struct {
 	storageSizes   map[string]int64 // tenantID -> size in bytes
 	storageSizesMu sync.RWMutex
 
-	logger *log.Logger
+	logger *slog.Logger
 }
 
 // RequestCounter tracks API requests for a tenant
@@ -39,7 +39,7 @@ func NewQuotaEnforcer(manager *Manager) *QuotaEnforcer {
 		manager:       manager,
 		requestCounts: make(map[string]*RequestCounter),
 		storageSizes:  make(map[string]int64),
-		logger:        log.Default(),
+		logger:        enterprise.ComponentLogger(enterprise.LogComponentQuota),
 	}
 }
 
@@ -162,8 +162,7 @@ func (qe *QuotaEnforcer) periodicStorageCheck() {
 			// Recalculate storage size
 			size, err := qe.getTenantStorageSize(instance.Tenant.ID)
 			if err != nil {
-				qe.logger.Printf("[QuotaEnforcer] Failed to check storage for tenant %s: %v",
-					instance.Tenant.ID, err)
+				qe.logger.Error("Failed to check storage for tenant", "tenantId", instance.Tenant.ID, "error", err)
 				continue
 			}
 
@@ -174,8 +173,7 @@ func (qe *QuotaEnforcer) periodicStorageCheck() {
 
 			// Check if exceeded quota
 			if sizeMB >= instance.Tenant.StorageQuotaMB {
-				qe.logger.Printf("[QuotaEnforcer] Tenant %s exceeded storage quota: %d MB / %d MB",
-					instance.Tenant.ID, sizeMB, instance.Tenant.StorageQuotaMB)
+				qe.logger.Warn("Tenant exceeded storage quota", "tenantId", instance.Tenant.ID, "sizeMB", sizeMB, "storageQuotaMB", instance.Tenant.StorageQuotaMB)
 			}
 		}
 	}
@@ -197,7 +195,7 @@ func (qe *QuotaEnforcer) periodicRequestReset() {
 			if now.Sub(counter.WindowStart) > 24*time.Hour {
 				counter.Count = 0
 				counter.WindowStart = now
-				qe.logger.Printf("[QuotaEnforcer] Reset request counter for tenant %s", tenantID)
+				qe.logger.Info("Reset request counter for tenant", "tenantId", tenantID)
 			}
 
 			counter.mu.Unlock()
@@ -244,5 +242,5 @@ func (qe *QuotaEnforcer) CleanupTenant(tenantID string) {
 	delete(qe.storageSizes, tenantID)
 	qe.storageSizesMu.Unlock()
 
-	qe.logger.Printf("[QuotaEnforcer] Cleaned up quota data for tenant: %s", tenantID)
+	qe.logger.Info("Cleaned up quota data for tenant", "tenantId", tenantID)
 }
@@ -0,0 +1,99 @@
+package tenant_node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// errTenantUnloaded is the cancel cause for realtime streams closed because
+// their tenant was unloaded (evicted, archived or migrated to another node)
+var errTenantUnloaded = errors.New("tenant unloaded from node")
+
+// reconnectRetryMs is the SSE retry hint sent to clients when their tenant moves
+const reconnectRetryMs = 1000
+
+// RealtimeTracker tracks open long-lived (SSE/WebSocket) connections per tenant
+// so that tenants with active subscribers aren't evicted, and so that their
+// streams can be closed gracefully when the tenant leaves this node
+type RealtimeTracker struct {
+	mu      sync.Mutex
+	nextID  uint64
+	streams map[string]map[uint64]context.CancelCauseFunc
+}
+
+// NewRealtimeTracker creates a new realtime connection tracker
+func NewRealtimeTracker() *RealtimeTracker {
+	return &RealtimeTracker{
+		streams: make(map[string]map[uint64]context.CancelCauseFunc),
+	}
+}
+
+// Open registers a realtime connection and returns a context that is canceled
+// when the tenant is unloaded, plus a function that must be called when the
+// connection ends
+func (t *RealtimeTracker) Open(parent context.Context, tenantID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	t.mu.Lock()
+	t.nextID++
+	id := t.nextID
+	if t.streams[tenantID] == nil {
+		t.streams[tenantID] = make(map[uint64]context.CancelCauseFunc)
+	}
+	t.streams[tenantID][id] = cancel
+	t.mu.Unlock()
+
+	return ctx, func() {
+		t.mu.Lock()
+		delete(t.streams[tenantID], id)
+		if len(t.streams[tenantID]) == 0 {
+			delete(t.streams, tenantID)
+		}
+		t.mu.Unlock()
+
+		cancel(nil)
+	}
+}
+
+// Count returns the number of open realtime connections for a tenant
+func (t *RealtimeTracker) Count(tenantID string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.streams[tenantID])
+}
+
+// Total returns the number of open realtime connections across all tenants
+func (t *RealtimeTracker) Total() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := 0
+	for _, streams := range t.streams {
+		total += len(streams)
+	}
+	return total
+}
+
+// CloseTenant cancels every open realtime connection of a tenant
+func (t *RealtimeTracker) CloseTenant(tenantID string) int {
+	t.mu.Lock()
+	streams := t.streams[tenantID]
+	delete(t.streams, tenantID)
+	t.mu.Unlock()
+
+	for _, cancel := range streams {
+		cancel(errTenantUnloaded)
+	}
+	return len(streams)
+}
+
+// writeReconnectEvent tells an SSE client that its stream was closed on purpose
+// and that it should reconnect (the gateway will route it to the tenant's new node)
+func writeReconnectEvent(w http.ResponseWriter) {
+	fmt.Fprintf(w, "retry: %d\nevent: PB_RECONNECT\ndata: {\"reason\":\"tenant moved\"}\n\n", reconnectRetryMs)
+	http.NewResponseController(w).Flush()
+}
@@ -0,0 +1,167 @@
+package tenant_node
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core/enterprise"
+)
+
+func TestRealtimeTrackerOpenAndClose(t *testing.T) {
+	tracker := NewRealtimeTracker()
+
+	_, done1 := tracker.Open(context.Background(), "tenant-1")
+	_, done2 := tracker.Open(context.Background(), "tenant-1")
+	_, done3 := tracker.Open(context.Background(), "tenant-2")
+
+	if count := tracker.Count("tenant-1"); count != 2 {
+		t.Errorf("expected 2 connections for tenant-1, got %d", count)
+	}
+	if total := tracker.Total(); total != 3 {
+		t.Errorf("expected 3 connections in total, got %d", total)
+	}
+
+	done1()
+	done2()
+	done3()
+
+	if total := tracker.Total(); total != 0 {
+		t.Errorf("expected no connections after close, got %d", total)
+	}
+}
+
+func TestRealtimeTrackerCloseTenant(t *testing.T) {
+	tracker := NewRealtimeTracker()
+
+	ctx1, done1 := tracker.Open(context.Background(), "tenant-1")
+	defer done1()
+	ctx2, done2 := tracker.Open(context.Background(), "tenant-2")
+	defer done2()
+
+	if closed := tracker.CloseTenant("tenant-1"); closed != 1 {
+		t.Errorf("expected 1 closed connection, got %d", closed)
+	}
+
+	if !errors.Is(context.Cause(ctx1), errTenantUnloaded) {
+		t.Errorf("expected tenant-1 stream to be canceled with errTenantUnloaded, got %v", context.Cause(ctx1))
+	}
+	if ctx2.Err() != nil {
+		t.Error("expected tenant-2 stream to stay open")
+	}
+	if tracker.Count("tenant-1") != 0 {
+		t.Error("expected tenant-1 to have no tracked connections")
+	}
+}
+
+func TestIsRealtimeRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    bool
+	}{
+		{"PocketBase realtime", http.MethodGet, "/api/realtime", nil, true},
+		{"realtime subscribe POST", http.MethodPost, "/api/realtime", nil, false},
+		{"event stream", http.MethodGet, "/custom/stream", map[string]string{"Accept": "text/event-stream"}, true},
+		{"websocket upgrade", http.MethodGet, "/ws", map[string]string{"Upgrade": "websocket"}, true},
+		{"regular request", http.MethodGet, "/api/collections/posts/records", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := enterprise.IsRealtimeRequest(r); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEvictIdleTenantsSkipsRealtimeSubscribers(t *testing.T) {
+	mgr := getTestManager(t)
+
+	idle := time.Now().Add(-time.Hour)
+	mgr.tenantsMu.Lock()
+	mgr.tenants["rt-subscribed"] = &enterprise.TenantInstance{Tenant: &enterprise.Tenant{ID: "rt-subscribed"}, LastAccessed: idle}
+	mgr.tenants["rt-idle"] = &enterprise.TenantInstance{Tenant: &enterprise.Tenant{ID: "rt-idle"}, LastAccessed: idle}
+	mgr.tenantsMu.Unlock()
+
+	_, done := mgr.realtime.Open(context.Background(), "rt-subscribed")
+	defer done()
+
+	if err := mgr.EvictIdleTenants(time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := mgr.GetTenant("rt-subscribed"); err != nil {
+		t.Error("expected tenant with open subscription to stay loaded")
+	}
+	if _, err := mgr.GetTenant("rt-idle"); err == nil {
+		t.Error("expected idle tenant to be evicted")
+	}
+
+	mgr.UnloadTenant(context.Background(), "rt-subscribed")
+}
+
+func TestRealtimeStreamReconnectOnUnload(t *testing.T) {
+	mgr := getTestManager(t)
+	server := NewHTTPServer(mgr)
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: PB_CONNECT\ndata: {}\n\n"))
+		close(started)
+		<-r.Context().Done()
+	})
+
+	mgr.tenantsMu.Lock()
+	mgr.tenants["rt-stream"] = &enterprise.TenantInstance{
+		Tenant:          &enterprise.Tenant{ID: "rt-stream", APIRequestsQuota: 1000},
+		HTTPHandler:     handler,
+		LastAccessed:    time.Now(),
+		LeaseValidUntil: time.Now().Add(enterprise.TenantLeaseTTL),
+	}
+	mgr.tenantsMu.Unlock()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/realtime", nil)
+	req.Header.Set("X-Tenant-ID", "rt-stream")
+	rec := httptest.NewRecorder()
+
+	served := make(chan struct{})
+	go func() {
+		server.handleTenantRequest(rec, req)
+		close(served)
+	}()
+
+	<-started
+	if count := mgr.RealtimeConnections("rt-stream"); count != 1 {
+		t.Errorf("expected 1 realtime connection, got %d", count)
+	}
+
+	if err := mgr.UnloadTenant(context.Background(), "rt-stream"); err != nil {
+		t.Fatalf("failed to unload tenant: %v", err)
+	}
+
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not closed after unload")
+	}
+
+	if !strings.Contains(rec.Body.String(), "event: PB_RECONNECT") {
+		t.Errorf("expected reconnect event, got %q", rec.Body.String())
+	}
+	if mgr.RealtimeConnections("rt-stream") != 0 {
+		t.Error("expected realtime connection to be released")
+	}
+}
@@ -0,0 +1,168 @@
+package tenant_node
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+// SSOPath is the tenant route that exchanges a cluster-issued tenant admin
+// token for a regular superuser auth token
+const SSOPath = "/api/enterprise/sso"
+
+// ssoEntryPage signs in to the admin UI with the token in the URL fragment,
+// which browsers don't send to servers, then opens the admin UI. The session is
+// stored where the admin UI keeps its superuser auth.
+const ssoEntryPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="referrer" content="no-referrer">
+<title>Signing in…</title>
+</head>
+<body>
+<p id="status">Signing in…</p>
+<script>
+(function () {
+	var token = new URLSearchParams(location.hash.slice(1)).get("token") || "";
+	history.replaceState(null, "", location.pathname);
+
+	fetch("` + SSOPath + `", {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify({token: token})
+	}).then(function (res) {
+		return res.json().then(function (data) {
+			if (!res.ok) {
+				throw new Error(data.message || "Failed to sign in.");
+			}
+			return data;
+		});
+	}).then(function (data) {
+		localStorage.setItem("__pb_superuser_auth__", JSON.stringify({token: data.token, record: data.record}));
+		location.replace("/_/");
+	}).catch(function (err) {
+		document.getElementById("status").textContent = err.message + " Request a new login link from the cluster dashboard.";
+	});
+})();
+</script>
+</body>
+</html>
+`
+
+// bindSSORoute registers the SSO login routes of a tenant: the entry page the
+// cluster dashboard links to, and the token exchange it calls
+func (m *Manager) bindSSORoute(r *router.Router[*core.RequestEvent], tenantID string) {
+	r.GET(SSOPath, func(e *core.RequestEvent) error {
+		e.Response.Header().Set("Cache-Control", "no-store")
+		e.Response.Header().Set("Referrer-Policy", "no-referrer")
+		return e.HTML(http.StatusOK, ssoEntryPage)
+	})
+	r.POST(SSOPath, func(e *core.RequestEvent) error {
+		return m.handleSSO(e, tenantID)
+	})
+}
+
+// handleSSO validates a tenant admin token against the cluster secret and the
+// tenant, uses it up, and logs in the superuser with the token's email,
+// creating it on first login
+func (m *Manager) handleSSO(e *core.RequestEvent, tenantID string) error {
+	if m.jwtManager == nil {
+		return e.Error(http.StatusServiceUnavailable, "SSO is not configured on this node.", nil)
+	}
+
+	var data struct {
+		Token string `form:"token" json:"token"`
+	}
+	if err := e.BindBody(&data); err != nil || data.Token == "" {
+		return e.BadRequestError("Missing SSO token.", err)
+	}
+
+	claims, err := m.jwtManager.ValidateTenantAdminToken(data.Token)
+	if err != nil {
+		return e.UnauthorizedError("Invalid or expired SSO token.", err)
+	}
+	if claims.TenantID != tenantID || claims.ID == "" || claims.Email == "" {
+		return e.UnauthorizedError("Invalid or expired SSO token.", nil)
+	}
+
+	err = m.cpClient.UseSSOToken(e.Request.Context(), claims.ID, claims.ExpiresAt.Time)
+	if errors.Is(err, enterprise.ErrSSOTokenUsed) {
+		return e.UnauthorizedError("The SSO token was already used.", nil)
+	}
+	if err != nil {
+		m.logger.Error("Failed to use SSO token", "tenantId", tenantID, "error", err)
+		return e.Error(http.StatusServiceUnavailable, "Failed to verify the SSO token.", nil)
+	}
+
+	superuser, err := ssoSuperuser(e.App, claims.Email)
+	if err != nil {
+		return e.InternalServerError("Failed to load the superuser.", err)
+	}
+
+	if err := recordAuthOrigin(e, superuser); err != nil {
+		m.logger.Warn("Failed to record SSO auth origin", "tenantId", tenantID, "error", err)
+	}
+
+	m.logger.Info("Tenant SSO login", "tenantId", tenantID, "userId", claims.UserID, "superuserId", superuser.Id)
+
+	// No auth method: the cluster login already authenticated the user, so
+	// neither MFA nor the new origin alert apply
+	return apis.RecordAuthResponse(e, superuser, "", nil)
+}
+
+// ssoSuperuser returns the superuser with email, creating it just in time
+// with a random password if the tenant has none yet
+func ssoSuperuser(app core.App, email string) (*core.Record, error) {
+	superuser, err := app.FindAuthRecordByEmail(core.CollectionNameSuperusers, email)
+	if err == nil {
+		return superuser, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	collection, err := app.FindCachedCollectionByNameOrId(core.CollectionNameSuperusers)
+	if err != nil {
+		return nil, err
+	}
+
+	superuser = core.NewRecord(collection)
+	superuser.SetEmail(email)
+	superuser.SetVerified(true)
+	superuser.SetRandomPassword()
+
+	if err := app.Save(superuser); err != nil {
+		return nil, err
+	}
+	return superuser, nil
+}
+
+// recordAuthOrigin saves the device of an SSO login in the tenant's auth
+// origins, fingerprinted like PocketBase's own logins
+func recordAuthOrigin(e *core.RequestEvent, superuser *core.Record) error {
+	userAgent := e.Request.UserAgent()
+	if len(userAgent) > 300 {
+		userAgent = userAgent[:300]
+	}
+	fingerprint := security.MD5(e.RealIP() + userAgent)
+
+	origin, err := e.App.FindAuthOriginByRecordAndFingerprint(superuser, fingerprint)
+	if errors.Is(err, sql.ErrNoRows) {
+		origin = core.NewAuthOrigin(e.App)
+		origin.SetCollectionRef(superuser.Collection().Id)
+		origin.SetRecordRef(superuser.Id)
+		origin.SetFingerprint(fingerprint)
+	} else if err != nil {
+		return err
+	}
+
+	// Saving an existing origin bumps its updated date to the last login
+	return e.App.Save(origin)
+}
@@ -0,0 +1,99 @@
+package tenant_node
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"github.com/pocketbase/pocketbase/core/enterprise/auth"
+)
+
+func TestSSOLoginCreatesSuperuserOnce(t *testing.T) {
+	app := newTestTenantApp(t)
+
+	jwtManager, err := auth.NewJWTManager("test-secret-key-32-bytes-long!!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := &Manager{
+		nodeID:     "node-1",
+		cpClient:   newMockCPClient(),
+		jwtManager: jwtManager,
+		logger:     enterprise.ComponentLogger(enterprise.LogComponentTenantNode),
+	}
+
+	handler, err := mgr.createTenantHTTPHandler("tenant-1", app)
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	// The page the cluster dashboard links to posts the token from the fragment
+	req := httptest.NewRequest(http.MethodGet, SSOPath, nil)
+	page := httptest.NewRecorder()
+	handler.ServeHTTP(page, req)
+	if page.Code != http.StatusOK || !strings.Contains(page.Body.String(), `fetch("`+SSOPath+`"`) || !strings.Contains(page.Body.String(), "__pb_superuser_auth__") {
+		t.Fatalf("expected the SSO entry page, got %d: %s", page.Code, page.Body.String())
+	}
+	if page.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("expected the entry page not to be cached, got %q", page.Header().Get("Cache-Control"))
+	}
+
+	user := &enterprise.ClusterUser{ID: "user_1", Email: "owner@example.com"}
+	login := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, SSOPath, strings.NewReader(`{"token":"`+token+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	otherTenant, _ := jwtManager.GenerateTenantAdminToken(user, "tenant-2")
+	if rec := login(otherTenant); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for another tenant's token, got %d", rec.Code)
+	}
+
+	token, _ := jwtManager.GenerateTenantAdminToken(user, "tenant-1")
+	rec := login(token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Token  string `json:"token"`
+		Record struct {
+			ID             string `json:"id"`
+			Email          string `json:"email"`
+			CollectionName string `json:"collectionName"`
+		} `json:"record"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Token == "" || resp.Record.Email != user.Email || resp.Record.CollectionName != core.CollectionNameSuperusers {
+		t.Fatalf("expected a superuser auth response for %s, got %s", user.Email, rec.Body.String())
+	}
+
+	superuser, err := app.FindAuthRecordByEmail(core.CollectionNameSuperusers, user.Email)
+	if err != nil {
+		t.Fatalf("expected superuser to be created: %v", err)
+	}
+	origins, err := app.FindAllAuthOriginsByRecord(superuser)
+	if err != nil || len(origins) != 1 {
+		t.Errorf("expected the login in the auth origins, got %d (%v)", len(origins), err)
+	}
+
+	if rec := login(token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 when the token is used again, got %d", rec.Code)
+	}
+
+	// A new token maps to the same superuser
+	again, _ := jwtManager.GenerateTenantAdminToken(user, "tenant-1")
+	if rec := login(again); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), superuser.Id) {
+		t.Errorf("expected login as %s, got %d: %s", superuser.Id, rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,40 @@
+package tenant_node
+
+import (
+	"time"
+)
+
+// tenantMetricsInterval is how often the tenant database sizes are published
+// and the tenants with their own Prometheus series re-ranked
+const tenantMetricsInterval = time.Minute
+
+// publishTenantMetrics periodically publishes the per-tenant Prometheus metrics
+func (m *Manager) publishTenantMetrics() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(tenantMetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.updateTenantMetrics()
+		}
+	}
+}
+
+// updateTenantMetrics re-ranks the tenants by their requests since the last
+// update and publishes the database sizes of the loaded ones
+func (m *Manager) updateTenantMetrics() {
+	m.tenantMetrics.Rank()
+
+	// Held so that an unloading tenant doesn't get its series back
+	m.tenantsMu.RLock()
+	defer m.tenantsMu.RUnlock()
+
+	for tenantID := range m.tenants {
+		m.tenantMetrics.SetDatabaseSize(tenantID, databaseBytes(m.dataDir, tenantID))
+	}
+}
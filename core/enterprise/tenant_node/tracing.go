@@ -0,0 +1,143 @@
+package tenant_node
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/core/enterprise"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxTracedStatementLen caps the SQL recorded on query spans
+const maxTracedStatementLen = 2048
+
+// identifierRegex matches the table names recorded on query spans
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// tracedDBConnect returns a DBConnect opening tenant databases that record a
+// span per SQL query.
+//
+// Queries are only traced when their context carries a trace, which is the case
+// for saves and deletes made while serving a request (they use the request
+// context). Plain reads of the PocketBase APIs run with a background context
+// and show up in the request span's duration only.
+//
+// dbx reports statements with their parameter values interpolated, so spans only
+// carry the operation and table unless fullStatements is set.
+func tracedDBConnect(fullStatements bool) func(dbPath string) (*dbx.DB, error) {
+	return func(dbPath string) (*dbx.DB, error) {
+		db, err := core.DefaultDBConnect(dbPath)
+		if err != nil {
+			return nil, err
+		}
+
+		dbName := filepath.Base(dbPath)
+		db.QueryLogFunc = func(ctx context.Context, t time.Duration, sql string, rows *sql.Rows, err error) {
+			recordQuerySpan(ctx, dbName, "db.query", t, sql, fullStatements, err)
+		}
+		db.ExecLogFunc = func(ctx context.Context, t time.Duration, sql string, result sql.Result, err error) {
+			recordQuerySpan(ctx, dbName, "db.exec", t, sql, fullStatements, err)
+		}
+
+		return db, nil
+	}
+}
+
+// recordQuerySpan records a finished query as a child of the span in ctx.
+// dbx reports queries once they're done, so the span is backdated.
+func recordQuerySpan(ctx context.Context, dbName, name string, t time.Duration, statement string, fullStatement bool, err error) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	operation, table := statementSummary(statement)
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "sqlite"),
+		attribute.String("db.name", dbName),
+		attribute.String("db.operation", operation),
+	}
+	if table != "" {
+		attrs = append(attrs, attribute.String("db.sql.table", table))
+	}
+	if fullStatement {
+		if len(statement) > maxTracedStatementLen {
+			statement = statement[:maxTracedStatementLen]
+		}
+		attrs = append(attrs, attribute.String("db.statement", statement))
+	}
+
+	end := time.Now()
+	_, span := enterprise.Tracer().Start(ctx, name,
+		trace.WithTimestamp(end.Add(-t)),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// statementSummary returns the operation of a SQL statement, e.g. SELECT, and
+// the table it reads or writes if it can tell. Nothing else of the statement is
+// returned, since it may hold parameter values.
+func statementSummary(statement string) (operation, table string) {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return "", ""
+	}
+
+	operation = strings.ToUpper(fields[0])
+
+	// The table follows the first of these keywords
+	var keyword string
+	switch operation {
+	case "SELECT", "DELETE":
+		keyword = "FROM"
+	case "INSERT", "REPLACE":
+		keyword = "INTO"
+	case "UPDATE":
+		keyword = "UPDATE"
+	case "CREATE", "DROP", "ALTER":
+		keyword = "TABLE"
+	default:
+		return operation, ""
+	}
+
+	for i, field := range fields {
+		if !strings.EqualFold(field, keyword) {
+			continue
+		}
+
+		// Skip the IF [NOT] EXISTS of schema changes
+		rest := fields[i+1:]
+		for len(rest) > 0 && (strings.EqualFold(rest[0], "IF") || strings.EqualFold(rest[0], "NOT") || strings.EqualFold(rest[0], "EXISTS")) {
+			rest = rest[1:]
+		}
+		if len(rest) > 0 {
+			return operation, tracedTableName(rest[0])
+		}
+		break
+	}
+
+	return operation, ""
+}
+
+// tracedTableName unquotes a table name, or returns "" if it isn't a plain
+// identifier (e.g. a subquery)
+func tracedTableName(name string) string {
+	name = strings.Trim(name, "`\"[]")
+	if name == "" || !identifierRegex.MatchString(name) {
+		return ""
+	}
+	return name
+}
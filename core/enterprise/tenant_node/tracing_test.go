@@ -0,0 +1,98 @@
+package tenant_node
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRecordQuerySpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	// Queries outside of a traced request aren't recorded
+	recordQuerySpan(context.Background(), "data.db", "db.query", time.Millisecond, "SELECT 1", false, nil)
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("expected no spans, got %d", n)
+	}
+
+	ctx, request := provider.Tracer("test").Start(context.Background(), "tenant POST")
+	recordQuerySpan(ctx, "data.db", "db.exec", 50*time.Millisecond, "INSERT INTO `posts` (`email`) VALUES ('jane@example.com')", false, errors.New("constraint failed"))
+	request.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	query := spans[0]
+	if query.Name() != "db.exec" || query.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Errorf("expected db.exec child of the request span, got %s", query.Name())
+	}
+	if d := query.EndTime().Sub(query.StartTime()); d != 50*time.Millisecond {
+		t.Errorf("expected the span to last as long as the query, got %v", d)
+	}
+	if query.Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", query.Status())
+	}
+
+	attrs := make(map[attribute.Key]string)
+	for _, attr := range query.Attributes() {
+		attrs[attr.Key] = attr.Value.Emit()
+	}
+	if attrs["db.operation"] != "INSERT" || attrs["db.sql.table"] != "posts" {
+		t.Errorf("expected the operation and table, got %v", attrs)
+	}
+	if _, ok := attrs["db.statement"]; ok {
+		t.Errorf("expected no statement with its parameter values, got %q", attrs["db.statement"])
+	}
+
+	// Full statements are opt-in
+	ctx, request = provider.Tracer("test").Start(context.Background(), "tenant GET")
+	recordQuerySpan(ctx, "data.db", "db.query", time.Millisecond, "SELECT * FROM `users` WHERE `email`='jane@example.com'", true, nil)
+	request.End()
+
+	spans = recorder.Ended()
+	for _, attr := range spans[len(spans)-2].Attributes() {
+		attrs[attr.Key] = attr.Value.Emit()
+	}
+	if !strings.Contains(attrs["db.statement"], "jane@example.com") {
+		t.Errorf("expected the full statement, got %q", attrs["db.statement"])
+	}
+}
+
+func TestStatementSummary(t *testing.T) {
+	scenarios := []struct {
+		statement string
+		operation string
+		table     string
+	}{
+		{"SELECT `users`.* FROM `users` WHERE `email`='a@b.c'", "SELECT", "users"},
+		{"select count(*) from \"posts\"", "SELECT", "posts"},
+		{"SELECT * FROM (SELECT 1)", "SELECT", ""},
+		{"INSERT INTO `_params` (`id`, `value`) VALUES ('x', 'secret')", "INSERT", "_params"},
+		{"UPDATE `users` SET `tokenKey`='secret' WHERE `id`='1'", "UPDATE", "users"},
+		{"DELETE FROM `posts` WHERE `id`='1'", "DELETE", "posts"},
+		{"CREATE TABLE IF NOT EXISTS `notes` (`id` TEXT)", "CREATE", "notes"},
+		{"PRAGMA optimize", "PRAGMA", ""},
+		{"", "", ""},
+	}
+
+	for _, s := range scenarios {
+		operation, table := statementSummary(s.statement)
+		if operation != s.operation || table != s.table {
+			t.Errorf("%q: expected %q %q, got %q %q", s.statement, s.operation, s.table, operation, table)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package enterprise
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace exporters
+const (
+	TracingExporterOTLP = "otlp" // OTLP over HTTP to a collector
+	TracingExporterFile = "file" // JSON lines appended to a file, for offline use
+)
+
+// tracerName is the instrumentation scope of the cluster's spans
+const tracerName = "github.com/pocketbase/pocketbase/core/enterprise"
+
+// traceContext propagates W3C trace context (traceparent/tracestate). It is used
+// even with tracing disabled so gateways and nodes keep the caller's trace IDs.
+var traceContext = propagation.TraceContext{}
+
+// StartTracing installs the global tracer provider of a cluster service. The
+// returned function flushes the pending spans and must be called on shutdown.
+func StartTracing(settings TracingSettings, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(traceContext)
+
+	if settings.Exporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch settings.Exporter {
+	case TracingExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if endpoint := settings.Endpoint; strings.Contains(endpoint, "://") {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		} else if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+		}
+		if settings.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		otlpExporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		exporter = otlpExporter
+	case TracingExporterFile:
+		file, err := os.OpenFile(settings.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+
+		fileExporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		exporter = &fileSpanExporter{SpanExporter: fileExporter, file: file}
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", settings.Exporter)
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if settings.SampleRatio > 0 && settings.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(settings.SampleRatio)
+	}
+
+	hostname, _ := os.Hostname()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		// Follow the caller's sampling decision so traces are never cut in half
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(serviceName),
+			semconv.HostName(hostname),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// fileSpanExporter closes the trace file when the exporter shuts down
+type fileSpanExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+// Shutdown flushes the exporter and closes the file
+func (e *fileSpanExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Tracer returns the tracer of the cluster services
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// StartSpan starts a span named after the operation it covers, e.g. "tenant.load"
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err on span, if any, and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// InjectTraceContext returns the trace context of ctx as W3C headers, for
+// envelopes that aren't HTTP requests (e.g. control plane IPC)
+func InjectTraceContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// ExtractTraceContext returns ctx with the trace context of W3C headers injected
+// by InjectTraceContext
+func ExtractTraceContext(ctx context.Context, headers map[string]string) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	return traceContext.Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// InjectHTTPTraceContext sets the traceparent/tracestate headers of an outgoing request
+func InjectHTTPTraceContext(ctx context.Context, header http.Header) {
+	traceContext.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// ExtractHTTPTraceContext returns ctx with the trace context of incoming request headers
+func ExtractHTTPTraceContext(ctx context.Context, header http.Header) context.Context {
+	return traceContext.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Span attribute keys shared by the cluster services
+var (
+	AttrTenantID = attribute.Key("pocketbase.tenant_id")
+	AttrNodeID   = attribute.Key("pocketbase.node_id")
+)
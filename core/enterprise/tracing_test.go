@@ -0,0 +1,132 @@
+package enterprise
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// withTestTracer records the spans of a test in memory
+func withTestTracer(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+
+	return recorder
+}
+
+func TestTraceContextSurvivesIPCEnvelope(t *testing.T) {
+	recorder := withTestTracer(t)
+
+	ctx, parent := StartSpan(context.Background(), "gateway GET")
+	defer parent.End()
+
+	// The envelope is JSON encoded on the wire
+	raw, _ := json.Marshal(map[string]interface{}{"trace": InjectTraceContext(ctx)})
+	var envelope struct {
+		Trace map[string]string `json:"trace"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	if envelope.Trace["traceparent"] == "" {
+		t.Fatalf("expected a traceparent, got %v", envelope.Trace)
+	}
+
+	_, child := StartSpan(ExtractTraceContext(context.Background(), envelope.Trace), "ipc getTenant")
+	child.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 ended span, got %d", len(spans))
+	}
+	if spans[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected child of span %s, got parent %s", parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	}
+	if spans[0].SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Error("expected the child to stay in the caller's trace")
+	}
+}
+
+func TestTraceContextHTTPHeaders(t *testing.T) {
+	withTestTracer(t)
+
+	ctx, span := StartSpan(context.Background(), "gateway GET")
+	defer span.End()
+
+	header := http.Header{}
+	InjectHTTPTraceContext(ctx, header)
+	if !strings.Contains(header.Get("traceparent"), span.SpanContext().TraceID().String()) {
+		t.Fatalf("expected traceparent with the trace ID, got %q", header.Get("traceparent"))
+	}
+
+	extracted := trace.SpanContextFromContext(ExtractHTTPTraceContext(context.Background(), header))
+	if !extracted.IsRemote() || extracted.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("expected remote span context %s, got %s", span.SpanContext().SpanID(), extracted.SpanID())
+	}
+}
+
+func TestInjectTraceContextWithoutSpan(t *testing.T) {
+	if carrier := InjectTraceContext(context.Background()); carrier != nil {
+		t.Errorf("expected no trace context, got %v", carrier)
+	}
+
+	ctx := context.Background()
+	if ExtractTraceContext(ctx, nil) != ctx {
+		t.Error("expected the context to be returned unchanged")
+	}
+}
+
+func TestStartTracingFileExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	file := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := StartTracing(TracingSettings{Exporter: TracingExporterFile, File: file}, "pocketbase-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := StartSpan(context.Background(), "tenant.load", AttrTenantID.String("tenant-1"))
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"tenant.load", "tenant-1", "pocketbase-test"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q in the trace file", want)
+		}
+	}
+}
+
+func TestStartTracingDisabled(t *testing.T) {
+	shutdown, err := StartTracing(TracingSettings{}, "pocketbase-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package enterprise
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies are the load balancers and CDNs whose forwarding headers are
+// believed. Anyone else could send any X-Forwarded-For.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses a list of proxy CIDRs or IPs
+func ParseTrustedProxies(entries []string) (TrustedProxies, error) {
+	prefixes, err := ParsePrefixes(entries)
+	if err != nil {
+		return nil, err
+	}
+	return TrustedProxies(prefixes), nil
+}
+
+// Contains reports whether addr is one of the proxies
+func (p TrustedProxies) Contains(addr netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddr returns the client address of a request. X-Forwarded-For is only
+// trusted from the proxies and read from the right, skipping the trusted hops,
+// so a client can't put an address of its choice in front of the chain.
+func (p TrustedProxies) ClientAddr(r *http.Request) netip.Addr {
+	client := RemoteAddr(r)
+	if !p.Contains(client) {
+		return client
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = hop.Unmap()
+		if !p.Contains(client) {
+			break
+		}
+	}
+
+	return client
+}
+
+// RemoteAddr returns the address of the peer of a request
+func RemoteAddr(r *http.Request) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	addr, _ := netip.ParseAddr(r.RemoteAddr)
+	return addr.Unmap()
+}
@@ -46,6 +46,11 @@ type Tenant struct {
 	// Node assignment
 	AssignedNodeID string    `json:"assignedNodeId,omitempty"` // Current node hosting this tenant
 	AssignedAt     time.Time `json:"assignedAt,omitempty"`
+	MovingToNodeID string    `json:"movingToNodeId,omitempty"` // Set while an admin move waits for the current node to hand the tenant off
+
+	// Data residency: the tenant is only placed on nodes of this region and its
+	// data is stored in the region's bucket (empty means the default region)
+	Region string `json:"region,omitempty"`
 
 	// Timestamps
 	Created time.Time `json:"created"`
@@ -54,6 +59,59 @@ type Tenant struct {
 	// S3 metadata
 	S3Bucket string `json:"s3Bucket"` // S3 bucket for tenant data
 	S3Prefix string `json:"s3Prefix"` // S3 prefix (e.g., tenants/tenant_001/)
+
+	// Gateway response cache (opt-in, only unauthenticated GET requests are cached)
+	ResponseCacheEnabled bool `json:"responseCacheEnabled,omitempty"`
+	ResponseCacheTTL     int  `json:"responseCacheTtl,omitempty"` // Seconds, used when responses set no max-age
+
+	// Ephemeral tenants, e.g. one per pull request. Preview tenants get the
+	// preview quotas, are never archived and always expire. Expired tenants are
+	// deleted with their S3 data by the control plane.
+	Preview        bool       `json:"preview,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	ExpiryNotified *time.Time `json:"expiryNotified,omitempty"` // When the owner was told about the upcoming deletion
+
+	// Set by an admin to block the tenant while keeping its data. The gateway
+	// refuses its requests and nodes don't load it until it is lifted.
+	Suspension *TenantSuspension `json:"suspension,omitempty"`
+
+	// Per-tenant IP, country and body size rules enforced by the gateway
+	AccessPolicy *TenantAccessPolicy `json:"accessPolicy,omitempty"`
+}
+
+// Expired reports whether the tenant has an expiry that passed at now
+func (t *Tenant) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// Suspended reports whether an admin suspended the tenant
+func (t *Tenant) Suspended() bool {
+	return t.Suspension != nil
+}
+
+// SuspensionReason is why a tenant was suspended
+type SuspensionReason string
+
+const (
+	SuspensionReasonAbuse      SuspensionReason = "abuse"       // Terms of service violation (403)
+	SuspensionReasonNonPayment SuspensionReason = "non_payment" // Unpaid invoices (402)
+)
+
+// Valid reports whether r is a known suspension reason
+func (r SuspensionReason) Valid() bool {
+	return r == SuspensionReasonAbuse || r == SuspensionReasonNonPayment
+}
+
+// TenantSuspension records who suspended a tenant and why, and the owner's
+// appeal, if any
+type TenantSuspension struct {
+	Reason      SuspensionReason `json:"reason"`
+	Message     string           `json:"message,omitempty"` // Shown to the owner and on the gateway page
+	SuspendedAt time.Time        `json:"suspendedAt"`
+	SuspendedBy string           `json:"suspendedBy"` // Audit actor of the admin
+
+	Appeal     string     `json:"appeal,omitempty"` // The owner's appeal message
+	AppealedAt *time.Time `json:"appealedAt,omitempty"`
 }
 
 // ClusterUser represents a self-service SaaS customer
@@ -63,6 +121,11 @@ type ClusterUser struct {
 	Name         string    `json:"name"`         // Display name
 	PasswordHash string    `json:"passwordHash"` // Bcrypt hash
 	Verified     bool      `json:"verified"`     // Email verification status
+	Locale       string    `json:"locale,omitempty"` // Preferred language of cluster emails (e.g., "de" or "pt-BR")
+
+	// Set by provider webhooks, no more emails are sent to a flagged address
+	EmailStatus        EmailStatus `json:"emailStatus,omitempty"`
+	EmailStatusUpdated *time.Time  `json:"emailStatusUpdated,omitempty"`
 
 	// Quotas
 	MaxTenants          int   `json:"maxTenants"`          // Maximum number of tenants
@@ -84,12 +147,33 @@ func DefaultUserQuotas() (maxTenants int, maxStoragePerTenant int64, maxAPIReque
 	return 3, 1024, 100000 // 3 tenants, 1GB each, 100k requests/day
 }
 
+// Preview tenant lifetimes
+const (
+	DefaultPreviewTTL = 7 * 24 * time.Hour  // Used when a preview tenant is created without an expiry
+	MaxTenantTTL      = 90 * 24 * time.Hour // Furthest an expiry can be set or extended into the future
+)
+
+// PreviewTenantQuotas returns the quotas of preview tenants, capped by the
+// owner's per-tenant quotas
+func PreviewTenantQuotas() (storageQuotaMB int64, apiRequestsQuota int64) {
+	return 256, 10000 // 256MB, 10k requests/day
+}
+
+// Node statuses. A draining node keeps serving the tenants it has loaded but
+// gets no new ones, and exits once they have all been handed back.
+const (
+	NodeStatusOnline   = "online"
+	NodeStatusOffline  = "offline"
+	NodeStatusDraining = "draining"
+)
+
 // NodeInfo represents a tenant node in the cluster
 type NodeInfo struct {
-	ID       string    `json:"id"`       // Unique node identifier
-	Address  string    `json:"address"`  // Network address (host:port)
-	Status   string    `json:"status"`   // online, offline, draining
-	Capacity int       `json:"capacity"` // Max tenants this node can handle
+	ID       string `json:"id"`               // Unique node identifier
+	Address  string `json:"address"`          // Network address (host:port)
+	Status   string `json:"status"`           // online, offline, draining
+	Capacity int    `json:"capacity"`         // Max tenants this node can handle
+	Region   string `json:"region,omitempty"` // Region the node serves (empty means the default region)
 
 	// Current load
 	ActiveTenants int   `json:"activeTenants"` // Number of currently loaded tenants
@@ -113,6 +197,21 @@ type PlacementDecision struct {
 	DecidedAt   time.Time `json:"decidedAt"`
 }
 
+// TenantLeaseTTL is how long a tenant lease lasts without renewal. Nodes renew
+// their leases with every heartbeat.
+const TenantLeaseTTL = 30 * time.Second
+
+// TenantLease grants one node the right to serve a tenant and replicate its
+// databases. The epoch grows every time the lease changes hands and is part of
+// the replica path, so a node that lost the lease can't write over the replica
+// of the node that took it over.
+type TenantLease struct {
+	TenantID  string    `json:"tenantId"`
+	NodeID    string    `json:"nodeId"`
+	Epoch     uint64    `json:"epoch"`
+	ExpiresAt time.Time `json:"expiresAt"` // By the control plane clock, zero once released
+}
+
 // ClusterConfig holds the configuration for the enterprise cluster
 type ClusterConfig struct {
 	// Mode
@@ -122,15 +221,38 @@ type ClusterConfig struct {
 	NodeID       string   `json:"nodeId,omitempty"`       // This control plane node's ID
 	RaftPeers    []string `json:"raftPeers,omitempty"`    // Raft peer addresses (cp1:7000,cp2:7000,cp3:7000)
 	RaftBindAddr string   `json:"raftBindAddr,omitempty"` // Raft bind address
+	IPCBindAddr  string   `json:"ipcBindAddr,omitempty"`  // Address gateways and tenant nodes reach the control plane on
 	DataDir      string   `json:"dataDir,omitempty"`      // BadgerDB data directory
 
+	// Cluster API settings (for control-plane mode)
+	APITrustedProxies []string `json:"apiTrustedProxies,omitempty"` // CIDRs of load balancers whose X-Forwarded-For names the client in rate limits and the audit log
+
 	// Tenant Node settings (for tenant-node mode)
 	ControlPlaneAddrs []string `json:"controlPlaneAddrs,omitempty"` // Control plane addresses
 	MaxTenants        int      `json:"maxTenants,omitempty"`        // Max tenants this node can handle
 	NodeAddress       string   `json:"nodeAddress,omitempty"`       // This node's advertised address (host:port)
+	IPCSecret         string   `json:"ipcSecret,omitempty"`         // Shared by the control plane and tenant nodes to hand out tenant data keys (required with masterKeyFile)
 
 	// Gateway settings (for gateway mode)
 	GatewayControlPlaneAddrs []string `json:"gatewayControlPlaneAddrs,omitempty"`
+	GatewayCacheMemoryMB     int      `json:"gatewayCacheMemoryMb,omitempty"`  // In-memory response cache size
+	GatewayCacheDir          string   `json:"gatewayCacheDir,omitempty"`       // On-disk response cache tier (disabled if empty)
+	GatewayPeerAddr          string   `json:"gatewayPeerAddr,omitempty"`       // host:port other gateways use to sync quota usage (quotas are per replica if empty)
+	GatewayPeerSecret        string   `json:"gatewayPeerSecret,omitempty"`     // Shared by the gateway replicas to authenticate quota syncs (required with gatewayPeerAddr)
+	GatewayTrustedProxies    []string `json:"gatewayTrustedProxies,omitempty"` // CIDRs of load balancers whose X-Forwarded-For and country header are trusted
+	GatewayCountryHeader     string   `json:"gatewayCountryHeader,omitempty"`  // Client country set by a trusted proxy or CDN (e.g., CF-IPCountry)
+
+	// Encryption settings (for control-plane mode)
+	MasterKeyFile          string   `json:"masterKeyFile,omitempty"`          // age identity wrapping tenant data keys (encryption disabled if empty)
+	PreviousMasterKeyFiles []string `json:"previousMasterKeyFiles,omitempty"` // Old master keys, needed until tenant keys are rewrapped
+
+	// Audit settings (for control-plane mode)
+	AuditRetention string `json:"auditRetention,omitempty"` // How long audit entries are kept (e.g., "8760h")
+
+	// Region settings. The top-level S3 settings are the storage of Region, which is
+	// also where tenants created without a residency requirement are placed.
+	Region  string                   `json:"region,omitempty"`  // This node's region
+	Regions map[string]*RegionConfig `json:"regions,omitempty"` // Per-region storage, overriding the top-level S3 settings (config file only)
 
 	// S3 settings (all modes)
 	S3Endpoint        string `json:"s3Endpoint"`
@@ -140,12 +262,28 @@ type ClusterConfig struct {
 	S3SecretAccessKey string `json:"s3SecretAccessKey"`
 
 	// Litestream settings
-	LitestreamEnabled       bool   `json:"litestreamEnabled"`
-	LitestreamReplicateSync bool   `json:"litestreamReplicateSync"` // Sync replication (slower but safer)
-	LitestreamRetention     string `json:"litestreamRetention"`     // Retention period (e.g., "72h")
+	LitestreamEnabled       bool     `json:"litestreamEnabled"`
+	LitestreamReplicateSync bool     `json:"litestreamReplicateSync"` // Sync replication (slower but safer)
+	LitestreamRetention     string   `json:"litestreamRetention"`     // Retention period (e.g., "72h")
+	LitestreamDR            S3Target `json:"litestreamDr"`            // Second replica bucket for disaster recovery (disabled if no bucket)
 
 	// Security settings
 	JWTSecret string `json:"jwtSecret,omitempty"` // Secret key for JWT signing (env: POCKETBASE_JWT_SECRET)
+
+	// Tuning (config file and env only). Reloaded on SIGHUP along with AuditRetention.
+	LogLevel string                        `json:"logLevel,omitempty"` // debug, info, warn or error (default of every component)
+	Quotas   map[TenantTier]*ResourceQuota `json:"quotas,omitempty"`   // Per-tier limits, overlaid on DefaultResourceQuotas
+	Disk     DiskSettings                  `json:"disk"`
+	Archive  ArchiveSettings               `json:"archive"`
+	Logs     LogSettings                   `json:"logs"`
+	Metrics  MetricsSettings               `json:"metrics"`
+
+	// Not reloadable
+	CircuitBreaker CircuitBreakerSettings `json:"circuitBreaker"`
+	Tracing        TracingSettings        `json:"tracing"`
+	Email          EmailSettings          `json:"email"`
+	Suspension     SuspensionSettings     `json:"suspension"`
+	Reads          ReadSettings           `json:"reads"`
 }
 
 // QuotaIncreaseRequest represents a request to increase tenant quotas
@@ -179,6 +317,79 @@ type VerificationToken struct {
 	Used    bool      `json:"used"`    // Whether token has been used
 }
 
+// EmailStatus flags the address of a cluster user that can't receive email
+type EmailStatus string
+
+const (
+	EmailStatusOK         EmailStatus = ""           // Deliverable
+	EmailStatusBounced    EmailStatus = "bounced"    // Hard bounce reported by the provider
+	EmailStatusComplained EmailStatus = "complained" // Marked as spam by the recipient
+)
+
+// EmailTemplate is an admin override of a built-in cluster email. Templates
+// without a locale apply to every language without a more specific one.
+type EmailTemplate struct {
+	Name    string    `json:"name"`             // Built-in template name (e.g., "verification")
+	Locale  string    `json:"locale,omitempty"` // Language tag (e.g., "de" or "pt-BR")
+	Subject string    `json:"subject"`          // text/template
+	HTML    string    `json:"html"`             // html/template
+	Updated time.Time `json:"updated"`
+}
+
+// OutboundEmailStatus is the delivery state of a queued email
+type OutboundEmailStatus string
+
+const (
+	OutboundEmailPending OutboundEmailStatus = "pending" // Waiting for its next attempt
+	OutboundEmailFailed  OutboundEmailStatus = "failed"  // Out of attempts, kept for inspection
+)
+
+// OutboundEmail is a rendered email in the control plane's outbound queue.
+// Sent emails are removed from the queue.
+type OutboundEmail struct {
+	ID          string              `json:"id"`
+	Template    string              `json:"template"`
+	To          string              `json:"to"`
+	Subject     string              `json:"subject"`
+	HTML        string              `json:"html"`
+	Status      OutboundEmailStatus `json:"status"`
+	Attempts    int                 `json:"attempts"`
+	NextAttempt time.Time           `json:"nextAttempt"`
+	LastError   string              `json:"lastError,omitempty"`
+	Created     time.Time           `json:"created"`
+}
+
+// API key scopes. A key is only accepted by the endpoints of its scopes,
+// login tokens have all of them.
+const (
+	APIKeyScopeTenantsRead  = "tenants:read"  // Profile, tenant list and restore status
+	APIKeyScopeTenantsWrite = "tenants:write" // Create, delete and restore tenants, response cache settings
+	APIKeyScopeTenantsSSO   = "tenants:sso"   // Tenant admin SSO tokens, and so tenant exports
+	APIKeyScopeLogsRead     = "logs:read"     // Log entries of the user's tenants
+)
+
+// APIKeyScopes lists every API key scope
+var APIKeyScopes = []string{
+	APIKeyScopeTenantsRead,
+	APIKeyScopeTenantsWrite,
+	APIKeyScopeTenantsSSO,
+	APIKeyScopeLogsRead,
+}
+
+// APIKey is a named, revocable credential of a cluster user for automation
+// like CI pipelines. Only the hash of the key is stored, the key itself is
+// shown once when it's created.
+type APIKey struct {
+	ID        string     `json:"id"`     // Key identifier (apikey_xxx)
+	UserID    string     `json:"userId"` // Owner of the key
+	Name      string     `json:"name"`   // Unique per user (e.g., "ci-previews")
+	Hash      string     `json:"hash"`   // HashAPIKey of the key
+	Hint      string     `json:"hint"`   // Start of the key, to recognize it in lists
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Never expires if unset
+	Created   time.Time  `json:"created"`
+}
+
 // StorageTier represents the storage tier for a tenant
 type StorageTier string
 
@@ -229,3 +440,252 @@ type TenantAccessPattern struct {
 	Created time.Time `json:"created"`
 	Updated time.Time `json:"updated"`
 }
+
+// RestoreJobStatus represents the state of an archive restore job
+type RestoreJobStatus string
+
+const (
+	RestoreJobPending    RestoreJobStatus = "pending"     // Queued, Glacier restore not yet requested
+	RestoreJobInProgress RestoreJobStatus = "in_progress" // Glacier restore requested, objects thawing
+	RestoreJobReady      RestoreJobStatus = "ready"       // All objects readable, tenant can be loaded
+	RestoreJobFailed     RestoreJobStatus = "failed"      // Restore gave up, see Error
+)
+
+// RestoreJob tracks the restore of an archived tenant back to a loadable state.
+// Cold tenants live in Glacier and take hours to thaw, so restores are asynchronous
+// and polled by the control plane until every object is readable again.
+type RestoreJob struct {
+	TenantID    string           `json:"tenantId"`
+	Status      RestoreJobStatus `json:"status"`
+	FromTier    StorageTier      `json:"fromTier"`    // Tier the tenant was restored from
+	Expedited   bool             `json:"expedited"`   // Use the Glacier expedited retrieval tier
+	RequestedBy string           `json:"requestedBy"` // User ID, admin token name or "gateway"
+	Attempts    int              `json:"attempts"`    // Failed polling attempts
+
+	// Progress, as reported by S3 HeadObject restore headers
+	ObjectsTotal    int `json:"objectsTotal"`
+	ObjectsRestored int `json:"objectsRestored"`
+
+	EstimatedReady time.Time  `json:"estimatedReady"`         // Best guess of when the tenant will be loadable
+	RestoredUntil  *time.Time `json:"restoredUntil,omitempty"` // Expiry of the temporary Glacier copy
+	Error          string     `json:"error,omitempty"`
+
+	// Timestamps
+	Created     time.Time  `json:"created"`
+	Updated     time.Time  `json:"updated"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// IsActive reports whether the job is still waiting on S3
+func (j *RestoreJob) IsActive() bool {
+	return j.Status == RestoreJobPending || j.Status == RestoreJobInProgress
+}
+
+// RetryAfter returns how long a client should wait before retrying a request
+// for a tenant that is being restored
+func (j *RestoreJob) RetryAfter() time.Duration {
+	remaining := time.Until(j.EstimatedReady)
+	if remaining < time.Minute {
+		return time.Minute
+	}
+	return remaining
+}
+
+// RecordChange is published by a tenant node whenever a tenant's records or
+// collections are created, updated or deleted
+type RecordChange struct {
+	Seq        uint64    `json:"seq"`
+	TenantID   string    `json:"tenantId"`
+	Collection string    `json:"collection"`
+	Time       time.Time `json:"time"`
+}
+
+// RecordChangeFeed is a page of a tenant node's record change feed
+type RecordChangeFeed struct {
+	NodeID  string          `json:"nodeId"`
+	Epoch   string          `json:"epoch"`   // Random per run of the node, sequence numbers restart with it
+	Seq     uint64          `json:"seq"`     // Latest published sequence number
+	Reset   bool            `json:"reset"`   // Requested changes are no longer retained
+	Changes []*RecordChange `json:"changes"` // Changes after the requested sequence number
+}
+
+// GatewayInfo represents a gateway replica. Gateways shard quota enforcement
+// between themselves by tenant ID, so each needs to know its live peers.
+type GatewayInfo struct {
+	ID            string    `json:"id"`
+	Address       string    `json:"address"` // Quota sync address for peers (host:port)
+	Registered    time.Time `json:"registered"`
+	LastHeartbeat time.Time `json:"lastHeartbeat"`
+}
+
+// UsageCheckpoint is a tenant's cluster-wide API request count for a day,
+// periodically persisted by the gateway that owns the tenant's quota
+type UsageCheckpoint struct {
+	TenantID    string    `json:"tenantId"`
+	Day         string    `json:"day"` // UTC day (YYYY-MM-DD)
+	APIRequests int64     `json:"apiRequests"`
+	Updated     time.Time `json:"updated"`
+}
+
+// TenantKeyring holds a tenant's data keys, wrapped by the cluster master key.
+// Old versions are kept after a rotation because replicas written with them are
+// still read. A shredded keyring has no keys left, which makes the tenant's S3
+// data unreadable.
+type TenantKeyring struct {
+	TenantID      string            `json:"tenantId"`
+	ActiveVersion int               `json:"activeVersion"` // Version new data is encrypted with
+	Keys          []*WrappedDataKey `json:"keys"`
+	Shredded      bool              `json:"shredded"`
+	Created       time.Time         `json:"created"`
+	Updated       time.Time         `json:"updated"`
+}
+
+// WrappedDataKey is one version of a tenant data key encrypted by a master key
+type WrappedDataKey struct {
+	Version     int       `json:"version"`
+	MasterKeyID string    `json:"masterKeyId"` // Master key that wrapped this data key
+	Ciphertext  []byte    `json:"ciphertext"`
+	Created     time.Time `json:"created"`
+}
+
+// TenantDataKeys are a tenant's unwrapped data keys, sent to the tenant node serving the tenant
+type TenantDataKeys struct {
+	TenantID      string     `json:"tenantId"`
+	ActiveVersion int        `json:"activeVersion"`
+	Keys          []*DataKey `json:"keys"`
+}
+
+// DataKey is one version of an unwrapped tenant data key
+type DataKey struct {
+	Version  int    `json:"version"`
+	Identity string `json:"identity"` // age X25519 identity (AGE-SECRET-KEY-1...)
+}
+
+// AuditEntry records an admin or tenant owner action. Entries are appended through
+// Raft and never modified; they are only pruned once older than the audit retention.
+type AuditEntry struct {
+	ID        string                  `json:"id"`
+	Time      time.Time               `json:"time"`
+	Actor     string                  `json:"actor"`  // admin:<token name>, user:<user id> or system:<task>
+	Action    string                  `json:"action"` // e.g. user.impersonate, tenant.archive
+	Target    string                  `json:"target"` // ID of the affected user or tenant
+	SourceIP  string                  `json:"sourceIp"`
+	RequestID string                  `json:"requestId"`
+	Changes   map[string]*AuditChange `json:"changes,omitempty"` // Fields changed by the action
+}
+
+// AuditChange is the value of a field before and after an audited action
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditFilter selects audit entries. Zero fields match every entry.
+type AuditFilter struct {
+	Actor  string    `json:"actor,omitempty"`
+	Action string    `json:"action,omitempty"`
+	Target string    `json:"target,omitempty"`
+	Since  time.Time `json:"since,omitempty"`
+	Until  time.Time `json:"until,omitempty"`
+	Limit  int       `json:"limit,omitempty"`
+}
+
+// LogEntry is a log record kept in the control plane log store
+type LogEntry struct {
+	ID        string                 `json:"id"`
+	Time      time.Time              `json:"time"`
+	Level     int                    `json:"level"` // slog level: -4 debug, 0 info, 4 warn, 8 error
+	Message   string                 `json:"message"`
+	Component string                 `json:"component,omitempty"`
+	NodeID    string                 `json:"nodeId,omitempty"`   // Node that logged the entry
+	TenantID  string                 `json:"tenantId,omitempty"` // Tenant the entry is about, if any
+	RequestID string                 `json:"requestId,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"` // Remaining attributes
+}
+
+// LogFilter selects stored log entries; zero fields match everything
+type LogFilter struct {
+	MinLevel  *int      `json:"minLevel,omitempty"`
+	Component string    `json:"component,omitempty"`
+	NodeID    string    `json:"nodeId,omitempty"`
+	TenantID  string    `json:"tenantId,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	Search    string    `json:"search,omitempty"` // Case-insensitive substring of the message
+	Since     time.Time `json:"since,omitempty"`
+	Until     time.Time `json:"until,omitempty"`
+	Limit     int       `json:"limit,omitempty"`
+}
+
+// FleetMigrationFormat is the format of a fleet migration script
+type FleetMigrationFormat string
+
+const (
+	FleetMigrationJS   FleetMigrationFormat = "js"   // migrate((app) => {...}, (app) => {...}) as generated by plugins/migratecmd
+	FleetMigrationJSON FleetMigrationFormat = "json" // Collections export, imported without deleting missing collections
+)
+
+// FleetMigrationMode decides when tenants receive a fleet migration
+type FleetMigrationMode string
+
+const (
+	FleetMigrationLazy  FleetMigrationMode = "lazy"  // Applied the next time a tenant is loaded
+	FleetMigrationEager FleetMigrationMode = "eager" // Idle tenants are also loaded in batches to apply it
+)
+
+// FleetMigrationStatus represents the rollout state of a fleet migration
+type FleetMigrationStatus string
+
+const (
+	FleetMigrationRunning   FleetMigrationStatus = "running"
+	FleetMigrationPaused    FleetMigrationStatus = "paused"    // No new tenants receive it until resumed
+	FleetMigrationAborted   FleetMigrationStatus = "aborted"   // Stopped for good, applied tenants are not reverted
+	FleetMigrationCompleted FleetMigrationStatus = "completed" // Every tenant was migrated or failed
+)
+
+// FleetMigration is a collection change rolled out to every tenant of the cluster.
+// Tenants apply it through their own migrations runner, so it is recorded in the
+// tenant's _migrations table like any other migration and never applied twice.
+type FleetMigration struct {
+	ID            string               `json:"id"`
+	Name          string               `json:"name"`
+	Format        FleetMigrationFormat `json:"format"`
+	Script        string               `json:"script"`
+	Mode          FleetMigrationMode   `json:"mode"`
+	CanaryPercent int                  `json:"canaryPercent"` // Share of tenants the rollout is limited to (1-100)
+	BatchSize     int                  `json:"batchSize"`     // Tenants loaded at once by eager rollouts
+	Status        FleetMigrationStatus `json:"status"`
+	CreatedBy     string               `json:"createdBy"`
+
+	// Timestamps
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// FleetMigrationTenantStatus represents the state of a fleet migration on one tenant.
+// Tenants without a state are pending.
+type FleetMigrationTenantStatus string
+
+const (
+	FleetMigrationTenantScheduled FleetMigrationTenantStatus = "scheduled" // Sent to a node by an eager rollout
+	FleetMigrationTenantApplied   FleetMigrationTenantStatus = "applied"
+	FleetMigrationTenantFailed    FleetMigrationTenantStatus = "failed" // See Error, not retried
+)
+
+// FleetMigrationTenant is the state of a fleet migration on a single tenant
+type FleetMigrationTenant struct {
+	MigrationID string                     `json:"migrationId"`
+	TenantID    string                     `json:"tenantId"`
+	Status      FleetMigrationTenantStatus `json:"status"`
+	NodeID      string                     `json:"nodeId,omitempty"` // Node that applied or was asked to apply it
+	Error       string                     `json:"error,omitempty"`
+	Updated     time.Time                  `json:"updated"`
+}
+
+// FleetMigrationProgress summarizes the rollout of a fleet migration
+type FleetMigrationProgress struct {
+	Targeted  int `json:"targeted"` // Tenants within the canary percentage
+	Pending   int `json:"pending"`
+	Scheduled int `json:"scheduled"`
+	Applied   int `json:"applied"`
+	Failed    int `json:"failed"`
+}
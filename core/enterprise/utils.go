@@ -2,8 +2,11 @@ package enterprise
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 )
@@ -63,6 +66,27 @@ func GenerateSessionToken() string {
 	return encoded
 }
 
+// APIKeyPrefix starts every cluster user API key, telling them apart from
+// login tokens
+const APIKeyPrefix = "pbk_"
+
+// GenerateAPIKey generates a cluster user API key
+// Panics if cryptographic random generation fails (system issue)
+func GenerateAPIKey() string {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(randomBytes)
+}
+
+// HashAPIKey returns the hash an API key is stored and looked up by. Keys
+// are random, so unlike passwords they need neither a salt nor a slow hash.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
 // ExtractTenantIDFromDomain extracts tenant ID from domain
 // Example: tenant123.platform.com -> tenant123
 func ExtractTenantIDFromDomain(domain string) string {
@@ -91,6 +115,12 @@ func GetS3DatabasePath(tenantID, dbName string) string {
 	return fmt.Sprintf("tenants/%s/litestream/%s/", tenantID, dbName)
 }
 
+// GetS3FilesPrefix returns the S3 prefix of a tenant's uploaded files
+// Example: tenants/tenant_abc123/storage/
+func GetS3FilesPrefix(tenantID string) string {
+	return fmt.Sprintf("tenants/%s/storage/", tenantID)
+}
+
 // IsNodeHealthy checks if a node is healthy based on last heartbeat
 func IsNodeHealthy(node *NodeInfo, heartbeatTimeout time.Duration) bool {
 	if node == nil {
@@ -154,3 +184,17 @@ func MustParseMode(modeStr string) Mode {
 	}
 	return mode
 }
+
+// IsRealtimeRequest reports whether a request opens a long-lived stream:
+// PocketBase's SSE realtime endpoint, any event-stream request or a WebSocket upgrade
+func IsRealtimeRequest(r *http.Request) bool {
+	if r.URL.Path == "/api/realtime" && r.Method == http.MethodGet {
+		return true
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return true
+	}
+
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"regexp"
 	"slices"
 	"strconv"
@@ -257,7 +256,7 @@ func (s *Settings) DBExport(app App) (map[string]any, error) {
 		return nil, err
 	}
 
-	encryptionKey := os.Getenv(app.EncryptionEnv())
+	encryptionKey := settingsEncryptionKey(app)
 	if encryptionKey != "" {
 		encryptVal, encryptErr := security.Encrypt(encoded, encryptionKey)
 		if encryptErr != nil {
@@ -62,7 +62,7 @@ func (s *Settings) loadParam(app App, param *Param) error {
 
 	// failed, try to decrypt
 	if plainDecodeErr != nil {
-		encryptionKey := os.Getenv(app.EncryptionEnv())
+		encryptionKey := settingsEncryptionKey(app)
 
 		// load without decryption has failed and there is no encryption key to use for decrypt
 		if encryptionKey == "" {
@@ -86,3 +86,14 @@ func (s *Settings) loadParam(app App, param *Param) error {
 
 	return s.PostScan()
 }
+
+// settingsEncryptionKey returns the key the app settings are encrypted with:
+// the one set in the app config, or else the value of its EncryptionEnv.
+func settingsEncryptionKey(app App) string {
+	if keyed, ok := app.(interface{ EncryptionKey() string }); ok {
+		if key := keyed.EncryptionKey(); key != "" {
+			return key
+		}
+	}
+	return os.Getenv(app.EncryptionEnv())
+}
@@ -60,6 +60,7 @@ echo "Installing configuration templates..."
 cp "$SCRIPT_DIR/control-plane.env.example" "$CONFIG_DIR/"
 cp "$SCRIPT_DIR/tenant-node.env.example" "$CONFIG_DIR/"
 cp "$SCRIPT_DIR/gateway.env.example" "$CONFIG_DIR/"
+cp "$SCRIPT_DIR/cluster.yaml.example" "$CONFIG_DIR/"
 
 # Reload systemd
 systemctl daemon-reload
@@ -195,22 +195,8 @@ func (p *plugin) registerMigrations() error {
 	for file, content := range files {
 		vm := goja.New()
 
-		registry.Enable(vm)
-		console.Enable(vm)
-		process.Enable(vm)
-		buffer.Enable(vm)
+		migrationBinds(vm, registry, templateRegistry)
 
-		baseBinds(vm)
-		dbxBinds(vm)
-		securityBinds(vm)
-		osBinds(vm)
-		filepathBinds(vm)
-		httpClientBinds(vm)
-		filesystemBinds(vm)
-		formsBinds(vm)
-		mailsBinds(vm)
-
-		vm.Set("$template", templateRegistry)
 		vm.Set("__hooks", absHooksDir)
 
 		vm.Set("migrate", func(up, down func(txApp core.App) error) {
@@ -230,6 +216,77 @@ func (p *plugin) registerMigrations() error {
 	return nil
 }
 
+// LoadMigration evaluates a single JS migration script, in the same format
+// as the files in the migrations directory, and registers it in list as file.
+//
+// The script runs in a standalone runtime, so the plugin hooks dir
+// and OnInit bindings are not available to it. Since such scripts run on
+// nodes shared with other apps, they also get no access to the host
+// (see sandboxedMigrationBinds).
+func LoadMigration(list *core.MigrationsList, file string, script string) error {
+	vm := goja.New()
+
+	sandboxedMigrationBinds(vm)
+
+	var registered bool
+	vm.Set("migrate", func(up, down func(txApp core.App) error) {
+		list.Register(up, down, file)
+		registered = true
+	})
+
+	_, err := vm.RunScript(defaultScriptPath, script)
+	if err != nil {
+		return fmt.Errorf("failed to run migration %s: %w", file, err)
+	}
+
+	if !registered {
+		return fmt.Errorf("migration %s doesn't call migrate()", file)
+	}
+
+	return nil
+}
+
+// migrationBinds enables the modules and bindings available to migration scripts.
+func migrationBinds(vm *goja.Runtime, registry *require.Registry, templateRegistry *template.Registry) {
+	registry.Enable(vm)
+	console.Enable(vm)
+	process.Enable(vm)
+	buffer.Enable(vm)
+
+	baseBinds(vm)
+	dbxBinds(vm)
+	securityBinds(vm)
+	osBinds(vm)
+	filepathBinds(vm)
+	httpClientBinds(vm)
+	filesystemBinds(vm)
+	formsBinds(vm)
+	mailsBinds(vm)
+
+	vm.Set("$template", templateRegistry)
+}
+
+// sandboxedMigrationBinds enables the migration bindings that don't reach the
+// host, i.e. without $os, $filesystem, $filepath, $template, process and require.
+func sandboxedMigrationBinds(vm *goja.Runtime) {
+	// console and Buffer are core modules loaded through require, which
+	// is removed once they are set since it can load files from disk
+	registry := require.NewRegistry(require.WithLoader(func(path string) ([]byte, error) {
+		return nil, require.ModuleFileDoesNotExistError
+	}))
+	registry.Enable(vm)
+	console.Enable(vm)
+	buffer.Enable(vm)
+	vm.GlobalObject().Delete("require")
+
+	baseBinds(vm)
+	dbxBinds(vm)
+	securityBinds(vm)
+	httpClientBinds(vm)
+	formsBinds(vm)
+	mailsBinds(vm)
+}
+
 // registerHooks registers the JS app hooks loader.
 func (p *plugin) registerHooks() error {
 	// fetch all js hooks sorted by their filename